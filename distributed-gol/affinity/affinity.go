@@ -0,0 +1,119 @@
+// Package affinity pins the goroutines computing a worker's slices to CPUs of the worker's choosing, so on a NUMA
+// machine each chunk of a large board is computed by a core next to the memory its rows were last read into,
+// rather than wherever the scheduler happens to move it. A spec such as
+//
+//	0-7,16-23
+//
+// lists the CPUs in turn: the first chunk of each slice runs on CPU 0, the second on CPU 1, and so on, starting
+// again from the first once every CPU has one. Only Linux can pin threads; elsewhere Check reports it can't.
+package affinity
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxCPUs is the most CPUs a mask has room for, as with the kernel's default cpu_set_t.
+const maxCPUs = 1024
+
+// mask is a set of CPUs, CPU n in bit n%64 of word n/64, laid out as sched_setaffinity expects.
+type mask [maxCPUs / 64]uint64
+
+func (m *mask) set(cpu int) {
+	m[cpu/64] |= 1 << uint(cpu%64)
+}
+
+// errUnsupported is returned for pinning away from Linux.
+var errUnsupported = errors.New("pinning goroutines to CPUs is only supported on Linux")
+
+// CPUs are the CPUs goroutines are pinned to, in turn. Nil CPUs pin nothing, so they can be used unconditionally.
+type CPUs []int
+
+// Parse reads a comma-separated list of CPUs and ranges of them, e.g. 0-3,8,10-11. An empty spec is nil CPUs.
+func Parse(spec string) (CPUs, error) {
+	var cpus CPUs
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := parseCPU(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = parseCPU(bounds[1]); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("CPU range %q runs backwards", part)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// parseCPU reads the number of a CPU.
+func parseCPU(value string) (int, error) {
+	cpu, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || cpu < 0 || cpu >= maxCPUs {
+		return 0, fmt.Errorf("%q isn't a CPU from 0 to %d", value, maxCPUs-1)
+	}
+	return cpu, nil
+}
+
+func (c CPUs) String() string {
+	parts := make([]string, len(c))
+	for i, cpu := range c {
+		parts[i] = strconv.Itoa(cpu)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Check pins a thread to each of the CPUs in turn, returning an error for the first it can't be pinned to, e.g.
+// one the machine doesn't have or the process isn't allowed on, so a bad spec is found at startup rather than by
+// Run quietly leaving goroutines unpinned.
+func (c CPUs) Check() error {
+	for i, cpu := range c {
+		var err error
+		c.Run(i, func(pinned error) { err = pinned })
+		if err != nil {
+			return fmt.Errorf("can't pin to CPU %d: %v", cpu, err)
+		}
+	}
+	return nil
+}
+
+// Run calls fn on an OS thread pinned to the i-th of the CPUs, counting round from the first again past the last,
+// passing it the error if the thread couldn't be pinned, in which case fn runs wherever the thread already was.
+// Nil CPUs call fn straight away with no error. Afterwards the thread is allowed back on the CPUs it was allowed on
+// before, so it can go on to run other goroutines; if that fails, the thread is left locked, and exits with the
+// goroutine rather than carrying the pinning on to others.
+func (c CPUs) Run(i int, fn func(pinned error)) {
+	if len(c) == 0 {
+		fn(nil)
+		return
+	}
+	runtime.LockOSThread()
+	before, err := getAffinity()
+	if err != nil {
+		defer runtime.UnlockOSThread()
+		fn(err)
+		return
+	}
+	var pinned mask
+	pinned.set(c[i%len(c)])
+	err = setAffinity(&pinned)
+	fn(err)
+	if err == nil && setAffinity(&before) != nil {
+		return
+	}
+	runtime.UnlockOSThread()
+}
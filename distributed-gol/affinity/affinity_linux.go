@@ -0,0 +1,25 @@
+package affinity
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// getAffinity returns the CPUs the calling thread is allowed on.
+func getAffinity() (mask, error) {
+	var m mask
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, 0, unsafe.Sizeof(m), uintptr(unsafe.Pointer(&m)))
+	if errno != 0 {
+		return mask{}, errno
+	}
+	return m, nil
+}
+
+// setAffinity allows the calling thread on just the CPUs of m.
+func setAffinity(m *mask) error {
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(*m), uintptr(unsafe.Pointer(m)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package affinity
+
+// getAffinity reports that threads can't be pinned away from Linux.
+func getAffinity() (mask, error) {
+	return mask{}, errUnsupported
+}
+
+// setAffinity reports that threads can't be pinned away from Linux.
+func setAffinity(m *mask) error {
+	return errUnsupported
+}
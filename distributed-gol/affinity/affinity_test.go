@@ -0,0 +1,72 @@
+package affinity
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// TestParse checks lists and ranges of CPUs are read in order, and bad ones refused.
+func TestParse(t *testing.T) {
+	for spec, expected := range map[string]string{"": "[]", "3": "[3]", "0-3,8, 10-11": "[0 1 2 3 8 10 11]", "5-5": "[5]"} {
+		cpus, err := Parse(spec)
+		if err != nil || fmt.Sprint([]int(cpus)) != expected {
+			t.Errorf("Parse(%q) = %v, %v, expected %v", spec, cpus, err, expected)
+		}
+	}
+	for _, spec := range []string{"x", "-1", "3-1", "0-", "1024"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, expected an error", spec)
+		}
+	}
+}
+
+// TestRun checks fn runs pinned to each CPU the process may run on in turn, and that the thread is allowed back on
+// all of them afterwards.
+func TestRun(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		if err := (CPUs{0}).Check(); err == nil {
+			t.Error("pinning succeeded away from Linux")
+		}
+		t.Skip("only Linux can pin threads")
+	}
+	// Stay on one thread, so it can be checked after Run.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	before, err := getAffinity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cpus CPUs
+	for cpu := 0; cpu < maxCPUs; cpu++ {
+		if before[cpu/64]>>uint(cpu%64)&1 != 0 {
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	for i := 0; i < 2*len(cpus); i++ {
+		cpus.Run(i, func(pinned error) {
+			if pinned != nil {
+				t.Fatal(pinned)
+			}
+			var expected mask
+			expected.set(cpus[i%len(cpus)])
+			if got, err := getAffinity(); err != nil || got != expected {
+				t.Errorf("chunk %d ran on CPUs %x, expected %x", i, got[0], expected[0])
+			}
+		})
+		if after, err := getAffinity(); err != nil || after != before {
+			t.Fatalf("after chunk %d the thread is allowed on CPUs %x, expected %x", i, after[0], before[0])
+		}
+	}
+}
+
+// TestNilRun checks nil CPUs call fn without pinning it.
+func TestNilRun(t *testing.T) {
+	called := false
+	var cpus CPUs
+	cpus.Run(3, func(pinned error) { called = pinned == nil })
+	if !called || cpus.Check() != nil {
+		t.Error("nil CPUs didn't call fn with no error")
+	}
+}
@@ -0,0 +1,261 @@
+// Package audit keeps a hash chain over the turns of a run, so a published final state can be proved afterwards to
+// have come from the claimed starting state and number of turns. Each entry folds the SHA-256 of the world at its
+// turn into the previous entry's sum, so changing, dropping or reordering any turn changes every sum after it.
+//
+// The broker appends an entry per turn to its -audit log as a line of text:
+//
+//	start <turn> <width>x<height> <rule> <edge> <seed> <world> <sum>   a run starts, or is continued from elsewhere
+//	turn  <turn> <world> <sum>                                          a turn is complete
+//	rule  <turn> <rule> <edge> <sum>                                    the rule or edges change before the next turn
+//	edit  <turn> <world> <sum>                                          cells are set by hand while paused
+//
+// with the world and sum in hex. Replaying the run from its starting state with the replay subcommand and -audit
+// recomputes every entry and reports the first that differs.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+)
+
+// Sum is a SHA-256 hash, of a world or of the chain up to an entry.
+type Sum [sha256.Size]byte
+
+func (s Sum) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// WorldSum returns the SHA-256 of a world's size and cells, row by row.
+func WorldSum(world slab.World) Sum {
+	h := sha256.New()
+	var size [8]byte
+	binary.BigEndian.PutUint32(size[:4], uint32(world.Width))
+	binary.BigEndian.PutUint32(size[4:], uint32(world.Height))
+	h.Write(size[:])
+	for y := 0; y < world.Height; y++ {
+		h.Write(world.Row(y))
+	}
+	var sum Sum
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// Entry is a line of an audit log. Which fields are set depends on Kind: "start", "turn", "rule" or "edit".
+type Entry struct {
+	Kind          string
+	Turn          int // Turn the world is at once the entry is applied.
+	Width, Height int // Size of the world, for a start.
+	Rule, Edge    string
+	Seed          int64 // Seed of a stochastic rule's chances, for a start.
+	World         Sum   // WorldSum of the world, for all but a change of rule.
+	Sum           Sum   // The chain up to and including this entry.
+}
+
+func (e Entry) String() string {
+	switch e.Kind {
+	case "start":
+		return fmt.Sprintf("start %d %dx%d %s %s %d %v %v", e.Turn, e.Width, e.Height, e.Rule, e.Edge, e.Seed, e.World, e.Sum)
+	case "rule":
+		return fmt.Sprintf("rule %d %s %s %v", e.Turn, e.Rule, e.Edge, e.Sum)
+	}
+	return fmt.Sprintf("%s %d %v %v", e.Kind, e.Turn, e.World, e.Sum)
+}
+
+// Start returns the entry that begins a chain at a run's starting world.
+func Start(world slab.World, turn int, rule, edge string, seed int64) Entry {
+	e := Entry{Kind: "start", Turn: turn, Width: world.Width, Height: world.Height, Rule: rule, Edge: edge, Seed: seed,
+		World: WorldSum(world)}
+	e.Sum = fold(Sum{}, e.Kind, e.Turn, []byte(fmt.Sprintf("%dx%d %s %s %d", e.Width, e.Height, rule, edge, seed)), e.World[:])
+	return e
+}
+
+// Next returns the entry after last for world, the next turn of the run.
+func Next(last Entry, world slab.World) Entry {
+	e := Entry{Kind: "turn", Turn: last.Turn + 1, World: WorldSum(world)}
+	e.Sum = fold(last.Sum, e.Kind, e.Turn, e.World[:])
+	return e
+}
+
+// Rule returns the entry after last for a change of rule or edges before the next turn.
+func Rule(last Entry, rule, edge string) Entry {
+	e := Entry{Kind: "rule", Turn: last.Turn, Rule: rule, Edge: edge}
+	e.Sum = fold(last.Sum, e.Kind, e.Turn, []byte(rule+" "+edge))
+	return e
+}
+
+// Edit returns the entry after last for world edited by hand at the same turn.
+func Edit(last Entry, world slab.World) Entry {
+	e := Entry{Kind: "edit", Turn: last.Turn, World: WorldSum(world)}
+	e.Sum = fold(last.Sum, e.Kind, e.Turn, e.World[:])
+	return e
+}
+
+// fold hashes the previous sum with an entry's kind, turn and fields.
+func fold(previous Sum, kind string, turn int, fields ...[]byte) Sum {
+	h := sha256.New()
+	h.Write(previous[:])
+	h.Write([]byte(kind))
+	var t [8]byte
+	binary.BigEndian.PutUint64(t[:], uint64(turn))
+	h.Write(t[:])
+	for _, field := range fields {
+		h.Write(field)
+	}
+	var sum Sum
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// Read parses the entries of an audit log.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		e, err := parse(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// parse reads an entry written by Entry.String.
+func parse(line string) (Entry, error) {
+	fields := strings.Fields(line)
+	lengths := map[string]int{"start": 8, "turn": 4, "rule": 5, "edit": 4}
+	if len(fields) == 0 || lengths[fields[0]] != len(fields) {
+		return Entry{}, fmt.Errorf("%q isn't an audit entry", line)
+	}
+	e := Entry{Kind: fields[0]}
+	var err error
+	if e.Turn, err = strconv.Atoi(fields[1]); err != nil {
+		return Entry{}, fmt.Errorf("%q has a bad turn", line)
+	}
+	var sums []string
+	switch e.Kind {
+	case "start":
+		if _, err = fmt.Sscanf(fields[2], "%dx%d", &e.Width, &e.Height); err == nil {
+			e.Seed, err = strconv.ParseInt(fields[5], 10, 64)
+		}
+		e.Rule, e.Edge = fields[3], fields[4]
+		sums = fields[6:]
+	case "rule":
+		e.Rule, e.Edge = fields[2], fields[3]
+		sums = fields[4:]
+	default:
+		sums = fields[2:]
+	}
+	targets := []*Sum{&e.World, &e.Sum}[2-len(sums):]
+	for i, s := range sums {
+		if err == nil {
+			err = parseSum(s, targets[i])
+		}
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("%q: %v", line, err)
+	}
+	return e, nil
+}
+
+func parseSum(s string, sum *Sum) error {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(sum) {
+		return fmt.Errorf("%q isn't a SHA-256 in hex", s)
+	}
+	copy(sum[:], b)
+	return nil
+}
+
+// Log appends the entries of the runs it is told about to a writer, chaining each to the one before. A nil Log
+// does nothing, so it can be called unconditionally. Its methods may be called from several goroutines at once, and
+// return an error only for the first write that fails, after which nothing more is written.
+type Log struct {
+	mu      sync.Mutex
+	out     io.Writer
+	last    Entry
+	world   Sum    // WorldSum of the world the chain ends at, which a change of rule leaves as it was.
+	rule    string // Rule and edges the chain ends under.
+	edge    string
+	started bool
+	err     error // First error writing, after which nothing more is written.
+}
+
+// NewLog returns a log that appends its entries to out.
+func NewLog(out io.Writer) *Log {
+	return &Log{out: out}
+}
+
+// Begin starts a chain at the world a run starts from. A run continuing from the world the chain already ends at,
+// e.g. after a quit, carries on the same chain rather than starting another, with a change of rule if it continues
+// under a different one.
+func (l *Log) Begin(world slab.World, turn int, rule, edge string, seed int64) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	start := Start(world, turn, rule, edge, seed)
+	if !l.started || l.last.Turn != turn || l.world != start.World {
+		l.started = true
+		return l.write(start)
+	}
+	if rule != l.rule || edge != l.edge {
+		return l.write(Rule(l.last, rule, edge))
+	}
+	return nil
+}
+
+// Turn records the world at the next turn.
+func (l *Log) Turn(world slab.World) error {
+	return l.append(func(last Entry) Entry { return Next(last, world) })
+}
+
+// Rule records a change of rule or edges before the next turn.
+func (l *Log) Rule(rule, edge string) error {
+	return l.append(func(last Entry) Entry { return Rule(last, rule, edge) })
+}
+
+// Edit records the world edited by hand at the current turn.
+func (l *Log) Edit(world slab.World) error {
+	return l.append(func(last Entry) Entry { return Edit(last, world) })
+}
+
+// append writes the entry next makes from the last one, once a chain has begun.
+func (l *Log) append(next func(last Entry) Entry) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.started {
+		return nil
+	}
+	return l.write(next(l.last))
+}
+
+// write appends e to the log and makes it the last entry, returning the first error writing only the once, so it
+// is reported without repeating it every turn. The caller must hold mu.
+func (l *Log) write(e Entry) (err error) {
+	if l.err == nil {
+		_, err = fmt.Fprintln(l.out, e)
+		l.err = err
+	}
+	if e.Kind != "rule" {
+		l.world = e.World
+	}
+	if e.Kind == "start" || e.Kind == "rule" {
+		l.rule, l.edge = e.Rule, e.Edge
+	}
+	l.last = e
+	return
+}
@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestChain checks every entry depends on the ones before it, so changing any turn changes every sum after it.
+func TestChain(t *testing.T) {
+	world := slab.New(4, 4)
+	start := Start(world, 0, "B3/S23", "torus", 0)
+	first := Next(start, world)
+	second := Next(first, world)
+	if first.Turn != 1 || second.Turn != 2 || first.World != second.World || first.Sum == second.Sum {
+		t.Errorf("turns %d and %d of the same world have sums %v and %v", first.Turn, second.Turn, first.Sum, second.Sum)
+	}
+
+	alive := world.Clone()
+	alive.Set(1, 1, util.Alive)
+	if changed := Next(Next(start, alive), world); changed.Sum == second.Sum {
+		t.Error("changing turn 1 left the sum of turn 2 the same")
+	}
+	if seeded := Start(world, 0, "B3/S23", "torus", 1); seeded.Sum == start.Sum {
+		t.Error("starting with another seed left the sum the same")
+	}
+	if ruled := Rule(first, "B36/S23", "torus"); ruled.Turn != 1 || Next(ruled, world).Sum == second.Sum {
+		t.Error("changing the rule left the sum of the next turn the same")
+	}
+}
+
+// TestLog checks a run continued from where the chain ends carries it on, under a change of rule if it has one,
+// and that what is written reads back as it was.
+func TestLog(t *testing.T) {
+	var out bytes.Buffer
+	l := NewLog(&out)
+	world := slab.New(3, 3)
+	world.Set(0, 1, util.Alive)
+	l.Turn(world) // Nothing has started, so there is nothing to chain it to.
+	l.Begin(world, 0, "B3/S23,B3=0.5", "dead", 42)
+	l.Turn(world)
+	l.Begin(world, 1, "B3/S23,B3=0.5", "dead", 42)
+	l.Begin(world, 1, "B36/S23", "dead", 42)
+	l.Edit(slab.New(3, 3))
+	l.Begin(world, 0, "B3/S23", "torus", 0)
+
+	kinds := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		kinds = append(kinds, strings.Fields(line)[0])
+	}
+	if expected := []string{"start", "turn", "rule", "edit", "start"}; !reflect.DeepEqual(kinds, expected) {
+		t.Fatalf("wrote %q, expected %q", kinds, expected)
+	}
+
+	entries, err := Read(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := Start(world, 0, "B3/S23,B3=0.5", "dead", 42)
+	turn := Next(start, world)
+	rule := Rule(turn, "B36/S23", "dead")
+	expected := []Entry{start, turn, rule, Edit(rule, slab.New(3, 3)), Start(world, 0, "B3/S23", "torus", 0)}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("read back %+v, expected %+v", entries, expected)
+	}
+
+	var nothing *Log
+	if err := nothing.Turn(world); err != nil {
+		t.Errorf("a nil log returned %v", err)
+	}
+	if _, err := Read(strings.NewReader("turn 1 00\n")); err == nil {
+		t.Error("read a turn with a truncated sum")
+	}
+}
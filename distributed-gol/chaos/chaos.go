@@ -0,0 +1,186 @@
+// Package chaos injects faults into the connections between the broker and its workers, so the broker's retries,
+// redialling and computing of failed slices itself can be exercised on one machine, the same way every time, rather
+// than by pulling cables. A spec such as
+//
+//	seed=7,latency=20ms,drop=0.01,crash=0.001
+//
+// delays each write by up to 20ms, breaks the connection instead of writing one time in a hundred, and crashes a
+// worker on one slice in a thousand. Every decision is drawn from a source seeded by the seed, the process's name
+// and the connection's, so each connection fails at the same points on every run with the same seed, whatever
+// order the connections happen to be used in.
+package chaos
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config says which faults are injected, and how often.
+type Config struct {
+	Seed    int64
+	Latency time.Duration // Most delay added to each write, chosen evenly from 0 up to it.
+	Drop    float64       // Chance each write breaks its connection instead.
+	Crash   float64       // Chance a worker crashes when asked for each slice.
+}
+
+// Parse reads a spec of comma-separated settings, e.g. seed=7,latency=20ms,drop=0.01,crash=0.001. Anything not
+// given is 0, so injects nothing.
+func Parse(spec string) (Config, error) {
+	var c Config
+	for _, setting := range strings.Split(spec, ",") {
+		if setting = strings.TrimSpace(setting); setting == "" {
+			continue
+		}
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 {
+			return Config{}, fmt.Errorf("chaos setting %q isn't name=value", setting)
+		}
+		var err error
+		switch name, value := parts[0], parts[1]; name {
+		case "seed":
+			c.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "latency":
+			if c.Latency, err = time.ParseDuration(value); err == nil && c.Latency < 0 {
+				err = fmt.Errorf("latency is negative")
+			}
+		case "drop":
+			c.Drop, err = parseChance(value)
+		case "crash":
+			c.Crash, err = parseChance(value)
+		default:
+			return Config{}, fmt.Errorf("unknown chaos setting %q: use seed, latency, drop or crash", name)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("chaos setting %q: %v", setting, err)
+		}
+	}
+	return c, nil
+}
+
+// parseChance reads a probability from 0 to 1.
+func parseChance(value string) (float64, error) {
+	p, err := strconv.ParseFloat(value, 64)
+	if err == nil && (p < 0 || p > 1) {
+		err = fmt.Errorf("%v isn't a chance from 0 to 1", p)
+	}
+	return p, err
+}
+
+func (c Config) String() string {
+	return fmt.Sprintf("seed=%d,latency=%v,drop=%v,crash=%v", c.Seed, c.Latency, c.Drop, c.Crash)
+}
+
+// Faults injects the faults of a Config into the connections of one process. A nil Faults injects nothing, so it
+// can be used unconditionally.
+type Faults struct {
+	config Config
+	name   string // The process's name, e.g. its port, so processes sharing a seed fail at different points.
+
+	mu      sync.Mutex
+	wrapped map[string]int // Number of connections wrapped so far for each peer.
+	crashes *source
+}
+
+// New returns the faults of config for the process named name.
+func New(config Config, name string) *Faults {
+	return &Faults{config: config, name: name, wrapped: make(map[string]int), crashes: newSource(config.Seed, name, "crash")}
+}
+
+// Wrap returns conn, a connection to peer, with its writes delayed or dropped. Each connection to the same peer,
+// e.g. after redialling one that was dropped, draws its faults from a source of its own, so it doesn't fail at
+// exactly the same point as the one before.
+func (f *Faults) Wrap(conn net.Conn, peer string) net.Conn {
+	if f == nil || f.config.Latency <= 0 && f.config.Drop <= 0 {
+		return conn
+	}
+	f.mu.Lock()
+	n := f.wrapped[peer]
+	f.wrapped[peer]++
+	f.mu.Unlock()
+	return &faultyConn{Conn: conn, config: f.config, source: newSource(f.config.Seed, f.name, peer, strconv.Itoa(n))}
+}
+
+// Listen returns l with every connection it accepts wrapped, named by the order they were accepted in.
+func (f *Faults) Listen(l net.Listener) net.Listener {
+	if f == nil {
+		return l
+	}
+	return &faultyListener{Listener: l, faults: f}
+}
+
+// Crash reports whether a worker should crash rather than compute the slice it has just been asked for.
+func (f *Faults) Crash() bool {
+	if f == nil || f.config.Crash <= 0 {
+		return false
+	}
+	return f.crashes.float() < f.config.Crash
+}
+
+// source is a random source seeded from the names of what it decides for. It may be used from several
+// goroutines at once.
+type source struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newSource(seed int64, names ...string) *source {
+	h := fnv.New64a()
+	fmt.Fprint(h, seed)
+	for _, name := range names {
+		fmt.Fprint(h, "/", name)
+	}
+	return &source{rand: rand.New(rand.NewSource(int64(h.Sum64())))}
+}
+
+func (s *source) float() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.Float64()
+}
+
+// dropped is the error a write that was dropped fails with. It is a net.Error, as a connection reset would be,
+// so callers treat the connection as broken.
+type dropped struct{}
+
+func (dropped) Error() string   { return "chaos: connection dropped" }
+func (dropped) Timeout() bool   { return false }
+func (dropped) Temporary() bool { return false }
+
+// faultyConn is a connection whose writes are delayed or dropped.
+type faultyConn struct {
+	net.Conn
+	config Config
+	source *source
+}
+
+// Write waits for the write's latency, then either writes b or closes the connection.
+func (c *faultyConn) Write(b []byte) (int, error) {
+	// Both are drawn for every write, so the drops don't depend on whether latency is injected too.
+	delay, drop := c.source.float(), c.source.float()
+	time.Sleep(time.Duration(delay * float64(c.config.Latency)))
+	if drop < c.config.Drop {
+		c.Conn.Close()
+		return 0, dropped{}
+	}
+	return c.Conn.Write(b)
+}
+
+// faultyListener wraps each connection it accepts with its faults.
+type faultyListener struct {
+	net.Listener
+	faults *Faults
+}
+
+func (l *faultyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.faults.Wrap(conn, "accepted"), nil
+}
@@ -0,0 +1,117 @@
+package chaos
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// TestParse checks every setting is read, and bad ones are refused.
+func TestParse(t *testing.T) {
+	c, err := Parse("seed=7, latency=20ms,drop=0.01,crash=0.001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Config{Seed: 7, Latency: 20 * time.Millisecond, Drop: 0.01, Crash: 0.001}); c != expected {
+		t.Errorf("parsed %v, expected %v", c, expected)
+	}
+	for _, bad := range []string{"drop", "drop=2", "latency=-1s", "seed=x", "jitter=1ms"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("%q was accepted", bad)
+		}
+	}
+}
+
+// writes counts the writes made to a connection.
+type writes struct {
+	net.Conn
+	n int
+}
+
+func (w *writes) Write(b []byte) (int, error) {
+	w.n++
+	return len(b), nil
+}
+
+func (w *writes) Close() error { return nil }
+
+// drops returns which of 200 writes to a new connection to peer are dropped.
+func drops(t *testing.T, f *Faults, peer string) []int {
+	t.Helper()
+	conn := f.Wrap(&writes{}, peer)
+	var dropped []int
+	for i := 0; i < 200; i++ {
+		if _, err := conn.Write([]byte{1}); err != nil {
+			if _, ok := err.(net.Error); !ok {
+				t.Fatalf("a dropped write failed with %v, expected a net.Error", err)
+			}
+			dropped = append(dropped, i)
+		}
+	}
+	return dropped
+}
+
+// TestDrops checks the same seed drops the same writes every time, and that another seed, process, peer or
+// connection to the same peer drops others.
+func TestDrops(t *testing.T) {
+	config := Config{Seed: 1, Drop: 0.1}
+	first := drops(t, New(config, "broker"), "worker")
+	if len(first) < 5 || len(first) > 40 {
+		t.Fatalf("dropped %d of 200 writes at a chance of 0.1", len(first))
+	}
+	if again := drops(t, New(config, "broker"), "worker"); !equal(again, first) {
+		t.Errorf("the same seed dropped writes %v, then %v", first, again)
+	}
+
+	redialled := New(config, "broker")
+	drops(t, redialled, "worker")
+	others := map[string][]int{
+		"another seed":    drops(t, New(Config{Seed: 2, Drop: 0.1}, "broker"), "worker"),
+		"another process": drops(t, New(config, "other"), "worker"),
+		"another peer":    drops(t, New(config, "broker"), "other"),
+		"a redial":        drops(t, redialled, "worker"),
+	}
+	for name, dropped := range others {
+		if equal(dropped, first) {
+			t.Errorf("%s dropped the same writes", name)
+		}
+	}
+
+	var none *Faults
+	conn := &writes{}
+	if none.Wrap(conn, "worker") != conn || none.Crash() {
+		t.Error("a nil Faults injected faults")
+	}
+}
+
+// TestCrash checks workers crash about as often as asked, at points decided by the seed.
+func TestCrash(t *testing.T) {
+	crashes := func(f *Faults) (n int) {
+		for i := 0; i < 1000; i++ {
+			if f.Crash() {
+				n++
+			}
+		}
+		return
+	}
+	config := Config{Seed: 3, Crash: 0.05}
+	n := crashes(New(config, "worker 8040"))
+	if n < 25 || n > 80 {
+		t.Errorf("crashed on %d of 1000 slices at a chance of 0.05", n)
+	}
+	if again := crashes(New(config, "worker 8040")); again != n {
+		t.Errorf("the same seed crashed %d times, then %d", n, again)
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestCompare checks comparing the byte-wise and bit-sliced kernels runs both in lockstep without the broker, finds
+// no divergence, and ends with the expected board.
+func TestCompare(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 4}
+	expectedAlive := readAliveCells(
+		"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+		p.ImageWidth,
+		p.ImageHeight,
+	)
+
+	events := make(chan gol.Event)
+	go gol.RunCompare(context.Background(), p, [2]kernel.Algorithm{kernel.ByteWise, kernel.BitSliced}, events, nil)
+	turns := 0
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CompareTurn:
+			if e.CompletedTurns != turns {
+				t.Fatalf("got turn %d, expected turn %d", e.CompletedTurns, turns)
+			}
+			if e.Differ != 0 {
+				t.Errorf("turn %d: %d cells differ", e.CompletedTurns, e.Differ)
+			}
+			turns++
+		case gol.ErrorEvent:
+			t.Errorf("unexpected %v", e)
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	if turns != p.Turns+1 {
+		t.Errorf("got %d CompareTurn events, expected one for the input and one for each of %d turns", turns, p.Turns)
+	}
+	assertEqualBoard(t, cells, expectedAlive, p)
+}
+
+// TestCompareUnsupported checks a compare run refuses settings it or its kernels can't honour with an
+// UnsupportedFeature naming the engines that can, rather than ignoring them.
+func TestCompareUnsupported(t *testing.T) {
+	tests := []struct {
+		p        gol.Params
+		engines  [2]kernel.Algorithm
+		expected gol.UnsupportedFeature
+	}{
+		{gol.Params{HardPause: true}, [2]kernel.Algorithm{kernel.ByteWise, kernel.BitSliced},
+			gol.UnsupportedFeature{Engine: gol.CompareEngine, Feature: "hard pausing", Setting: "-hardPause",
+				Supported: []string{gol.BrokerEngine}}},
+		{gol.Params{Rule: "B3/S23,S2=0.95"}, [2]kernel.Algorithm{kernel.ByteWise, kernel.Wide},
+			gol.UnsupportedFeature{Engine: "the wide kernel", Feature: "stochastic rules", Setting: "-rule B3/S23,S2=0.95",
+				Supported: []string{"the bytes kernel"}}},
+	}
+	for _, test := range tests {
+		test.p.ImageWidth, test.p.ImageHeight, test.p.Turns, test.p.Threads = 16, 16, 1, 1
+		if err := gol.CheckFeatures(test.p, gol.BrokerEngine); err != nil {
+			t.Errorf("the broker refused %+v: %v", test.p, err)
+		}
+		events := make(chan gol.Event)
+		go gol.RunCompare(context.Background(), test.p, test.engines, events, nil)
+		var got []gol.ErrorEvent
+		for event := range events {
+			if e, ok := event.(gol.ErrorEvent); ok {
+				got = append(got, e)
+			}
+		}
+		if len(got) != 1 || got[0].Recoverable || got[0].Component != "params" || got[0].Message != test.expected.Error() {
+			t.Errorf("comparing %v with %+v sent %v, expected %q", test.engines, test.p, got, test.expected.Error())
+		}
+	}
+}
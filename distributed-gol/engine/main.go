@@ -0,0 +1,12 @@
+// Command engine is the broker of the distributed Game of Life, run on its own machine.
+package main
+
+import (
+	"os"
+
+	"uk.ac.bris.cs/gameoflife/golbroker"
+)
+
+func main() {
+	golbroker.Main("engine", os.Args[1:])
+}
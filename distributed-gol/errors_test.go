@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestErrorEvents checks that a run which can't start reports why with an ErrorEvent and closes the events
+// channel, rather than exiting the whole client. Neither case gets as far as contacting the broker.
+func TestErrorEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		p         gol.Params
+		component string
+	}{
+		{"missing image", gol.Params{ImageWidth: 17, ImageHeight: 17, Threads: 1}, "io"},
+		{"no threads", gol.Params{ImageWidth: 16, ImageHeight: 16, Threads: 0}, "params"},
+		{"negative turns", gol.Params{ImageWidth: 16, ImageHeight: 16, Threads: 1, Turns: -1}, "params"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events := make(chan gol.Event)
+			go gol.Run(test.p, events, nil)
+
+			var received []gol.Event
+			timeout := time.After(5 * time.Second)
+			for open := true; open; {
+				select {
+				case event, ok := <-events:
+					if ok {
+						received = append(received, event)
+					}
+					open = ok
+				case <-timeout:
+					t.Fatalf("events channel wasn't closed, received %v", received)
+				}
+			}
+
+			if len(received) != 2 {
+				t.Fatalf("expected an ErrorEvent and a StateChange, received %v", received)
+			}
+			e, ok := received[0].(gol.ErrorEvent)
+			if !ok || e.Component != test.component || e.Recoverable || e.Severity != gol.Error {
+				t.Errorf("expected an unrecoverable %q ErrorEvent, received %#v", test.component, received[0])
+			}
+			if s, ok := received[1].(gol.StateChange); !ok || s.NewState != gol.Quitting {
+				t.Errorf("expected StateChange{Quitting}, received %#v", received[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package gol
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// RunCompare evolves the input image with two engines on the client, without the broker, a turn of each at a time
+// in lockstep, sending a CompareTurn event after every turn so the two can be shown side by side. The first turn
+// they disagree on is reported with a recoverable ErrorEvent from the "compare" component, and the run carries on
+// so the divergence can be watched. q quits, p pauses, and the run also quits once ctx is done.
+func RunCompare(ctx context.Context, p Params, engines [2]kernel.Algorithm, events chan<- Event, keyPresses <-chan rune) {
+	c := startChannels(p, events, keyPresses)
+	defer close(c.events) // The only place the events channel is closed, once nothing is left to send.
+	err := validateParams(p)
+	if err == nil {
+		err = CheckFeatures(p, CompareEngine, engines[:]...)
+	}
+	if err != nil {
+		stop(c, 0, "params", err)
+		return
+	}
+	world, err := readWorld(p, c)
+	if err != nil {
+		stop(c, 0, "io", err)
+		return
+	}
+
+	var opts [2]kernel.Options
+	for i, engine := range engines {
+		opts[i], _ = kernel.ParseOptions(p.Rule, p.Edge)
+		opts[i].Algorithm, opts[i].Seed = engine, p.Seed
+	}
+	chunk := (p.ImageHeight + p.Threads - 1) / p.Threads
+
+	worlds := [2][][]byte{world, world}
+	var took, total [2]time.Duration
+	c.events <- CompareTurn{0, engines, worlds, took, 0, time.Now()}
+	diverged := -1
+
+	turn := 0
+	for quit := false; turn < p.Turns && !quit; {
+		for i := range worlds {
+			opts[i].Turn = turn
+			start := time.Now()
+			worlds[i] = kernel.NextStateWith(worlds[i], p.ImageWidth, p.ImageHeight, 0, p.ImageHeight, chunk, opts[i])
+			took[i] = time.Since(start)
+			total[i] += took[i]
+		}
+		turn++
+
+		differ, first := compareCells(worlds[0], worlds[1])
+		c.events <- CompareTurn{turn, engines, worlds, took, differ, time.Now()}
+		if differ > 0 && diverged < 0 {
+			diverged = turn
+			message := fmt.Sprintf("%v and %v diverged at turn %d: %d cells differ, the first at (%d, %d)",
+				engines[0], engines[1], turn, differ, first.X, first.Y)
+			c.events <- ErrorEvent{turn, Warning, "compare", message, true, time.Now()}
+		}
+
+		select {
+		case <-ctx.Done():
+			quit = true
+		case key := <-c.keyPresses:
+			switch key {
+			case 'q':
+				quit = true
+			case 'p':
+				c.events <- StateChange{turn, Paused, time.Now()}
+				quit = !waitForKey(ctx, c.keyPresses, 'p')
+				if !quit {
+					c.events <- StateChange{turn, Executing, time.Now()}
+				}
+			}
+		default:
+		}
+	}
+
+	if turn > 0 {
+		fmt.Printf("Compared %d turns: %v took %v a turn, %v took %v a turn", turn,
+			engines[0], (total[0] / time.Duration(turn)).Round(time.Microsecond),
+			engines[1], (total[1] / time.Duration(turn)).Round(time.Microsecond))
+		if diverged < 0 {
+			fmt.Println(", and they never diverged")
+		} else {
+			fmt.Printf(", and they diverged at turn %d\n", diverged)
+		}
+	}
+	c.events <- FinalTurnComplete{turn, aliveCellsOf(worlds[0]), time.Now()}
+	c.events <- StateChange{turn, Quitting, time.Now()}
+}
+
+// waitForKey blocks until want is pressed, reporting true, or q is pressed or ctx is done, reporting false.
+func waitForKey(ctx context.Context, keyPresses <-chan rune, want rune) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case key := <-keyPresses:
+			switch key {
+			case want:
+				return true
+			case 'q':
+				return false
+			}
+		}
+	}
+}
+
+// compareCells counts the cells two worlds of the same size disagree on, and returns the first of them in row order.
+func compareCells(a, b [][]byte) (int, util.Cell) {
+	differ, first := 0, util.Cell{}
+	for y := range a {
+		for x := range a[y] {
+			if a[y][x] != b[y][x] {
+				if differ == 0 {
+					first = util.Cell{X: x, Y: y}
+				}
+				differ++
+			}
+		}
+	}
+	return differ, first
+}
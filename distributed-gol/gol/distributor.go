@@ -1,11 +1,20 @@
 package gol
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/rpc"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+	"uk.ac.bris.cs/gameoflife/golclient"
+	"uk.ac.bris.cs/gameoflife/golsnap"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/pattern"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/storage"
 	"uk.ac.bris.cs/gameoflife/stubs"
 	"uk.ac.bris.cs/gameoflife/util"
 )
@@ -19,24 +28,120 @@ type distributorChannels struct {
 	ioFilename chan<- string    // Channel to send filenames to the IO goroutine.
 	ioOutput   chan<- uint8     // Channel to send output data to the IO goroutine.
 	ioInput    <-chan uint8     // Channel to receive input data from the IO goroutine.
+	ioErrors   <-chan error     // Channel to receive the result of each input or output from the IO goroutine.
 	keyPresses <-chan rune      // Channel to receive key presses.
 	mu         sync.Mutex       // Mutex to protect shared resources.
 }
 
 // race struct allows goroutines to access shared variables safely, avoiding data races.
 type race struct {
-	turn   int         // Current turn number.
-	client *rpc.Client // RPC client to communicate with the server.
-	mu     sync.Mutex  // Mutex to protect shared resources.
+	turn   int           // Current turn number. Protected by mu, as the goroutines read it while the live view sets it.
+	client *brokerClient // RPC client to communicate with the server.
+	mu     sync.Mutex    // Mutex to protect shared resources.
+	cycle  bool          // Whether a CycleDetected event has already been sent for this run.
+	owners int           // Version of the worker assignments last sent as a WorkerOwnership event.
+	// RPCs that failed last time they were called. A call failing every tick is only reported once, and again
+	// if it fails after having recovered.
+	failing map[string]bool
 }
 
-// distributor divides the work between workers and interacts with other goroutines.
-func distributor(p Params, c *distributorChannels) {
+// setTurn records the turn the run is known to have reached.
+func (r *race) setTurn(turn int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.turn = turn
+}
+
+// currentTurn returns the turn the run is known to have reached.
+func (r *race) currentTurn() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.turn
+}
+
+// newFailure records the result of calling an RPC handler and reports whether it is a failure that hasn't
+// been reported yet.
+func (r *race) newFailure(handler string, err error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		delete(r.failing, handler)
+		return false
+	}
+	if r.failing[handler] {
+		return false
+	}
+	if r.failing == nil {
+		r.failing = make(map[string]bool)
+	}
+	r.failing[handler] = true
+	return true
+}
+
+// warn sends a recoverable ErrorEvent if an RPC to the broker failed and the failure hasn't been reported yet.
+// what describes what the call was for. The caller must hold the DistributorChannels mutex.
+func warn(c *distributorChannels, r *race, handler, what string, err error) {
+	if r.newFailure(handler, err) {
+		c.events <- ErrorEvent{r.currentTurn(), Warning, "broker", fmt.Sprintf("couldn't %s: %v", what, err), true, time.Now()}
+	}
+}
+
+// reportSave sends an ErrorEvent if saving an image failed. The run carries on either way.
+// The caller must hold the DistributorChannels mutex.
+func reportSave(c *distributorChannels, turn int, err error) {
+	if err != nil {
+		c.events <- ErrorEvent{turn, Error, "io", err.Error(), true, time.Now()}
+	}
+}
+
+// stop reports an error the run can't recover from, for the caller to end the run by returning. Only the
+// distributor calls it, and only while no other goroutine may send events.
+func stop(c *distributorChannels, turn int, component string, err error) {
+	c.events <- ErrorEvent{turn, Error, component, err.Error(), false, time.Now()}
+	c.events <- StateChange{turn, Quitting, time.Now()}
+}
+
+// validateParams checks the parameters make sense before anything is loaded or sent to the broker.
+func validateParams(p Params) error {
+	switch {
+	case p.ImageWidth < 1 || p.ImageHeight < 1:
+		return fmt.Errorf("image size %dx%d isn't positive", p.ImageWidth, p.ImageHeight)
+	case p.Turns < 0:
+		return fmt.Errorf("number of turns %d is negative", p.Turns)
+	case p.Threads < 1:
+		return fmt.Errorf("number of threads %d is less than 1", p.Threads)
+	case p.Preview > 0 && p.PreviewScale < 1:
+		return fmt.Errorf("preview scale %d is less than 1", p.PreviewScale)
+	case p.PausePopulation < 0:
+		return fmt.Errorf("population %d to pause at is negative", p.PausePopulation)
+	}
+	for _, cell := range p.PauseCells {
+		if cell.X < 0 || cell.X >= p.ImageWidth || cell.Y < 0 || cell.Y >= p.ImageHeight {
+			return fmt.Errorf("cell (%d, %d) to pause at is outside the %dx%d world", cell.X, cell.Y, p.ImageWidth, p.ImageHeight)
+		}
+	}
+	if _, _, err := saveFormats(p); err != nil {
+		return err
+	}
+	if p.PausePattern != "" {
+		if _, err := pattern.Parse(p.PausePattern); err != nil {
+			return err
+		}
+	}
+	_, err := kernel.ParseOptions(p.Rule, p.Edge)
+	return err
+}
 
+// readWorld has the IO goroutine read the input image for the size in p.
+func readWorld(p Params, c *distributorChannels) ([][]byte, error) {
 	// Send command to read input.
 	c.ioCommand <- ioInput
 	// Send the filename to read, formatted as "widthxheight".
 	c.ioFilename <- fmt.Sprintf("%d%s%d", p.ImageWidth, "x", p.ImageHeight)
+	// The IO goroutine says whether the image could be read before sending any cells.
+	if err := <-c.ioErrors; err != nil {
+		return nil, err
+	}
 
 	// Create a 2D slice to store the world.
 	world := make([][]uint8, p.ImageHeight)
@@ -47,29 +152,118 @@ func distributor(p Params, c *distributorChannels) {
 			world[i][j] = <-c.ioInput
 		}
 	}
+	return world, nil
+}
 
-	// Connect to the server via RPC.
-	client, err := rpc.Dial("tcp", "127.0.0.1:8030") // Replace with your server's IP and port.
+// reportOwnership fetches the broker's worker assignments and sends them as a WorkerOwnership event.
+// The caller must hold the DistributorChannels mutex.
+func reportOwnership(c *distributorChannels, r *race) error {
+	assignmentsResponse := &stubs.GetAssignmentsResponse{}
+	if err := r.client.Call(stubs.GetAssignmentsHandler, stubs.Empty{}, assignmentsResponse); err != nil {
+		return err
+	}
+	r.owners = assignmentsResponse.Version
+	c.events <- WorkerOwnership{assignmentsResponse.Turn, assignmentsResponse.Assignments, time.Now()}
+	return nil
+}
+
+// reportCycle asks the broker whether the world has entered a cycle and, the first time it has, sends a CycleDetected event.
+// The caller must hold the DistributorChannels mutex.
+func reportCycle(c *distributorChannels, r *race) error {
+	hashResponse := &stubs.WorldHashResponse{}
+	if err := r.client.Call(stubs.WorldHashHandler, stubs.Empty{}, hashResponse); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if hashResponse.Period > 0 && !r.cycle {
+		r.cycle = true
+		c.events <- CycleDetected{hashResponse.CycleTurn, hashResponse.Period, time.Now()}
+	}
+	return nil
+}
+
+// distributor divides the work between workers and interacts with other goroutines. Once ctx is done the run
+// is quit as if q had been pressed.
+func distributor(ctx context.Context, p Params, c *distributorChannels) {
+	// The only place the events channel is closed. Any goroutine started below that sends events has returned by
+	// the time this runs, so nothing can send on it afterwards.
+	defer close(c.events)
+
+	err := validateParams(p)
+	if err == nil {
+		err = CheckFeatures(p, BrokerEngine)
+	}
 	if err != nil {
-		log.Fatal("Error connecting to server:", err)
+		stop(c, 0, "params", err)
+		return
+	}
+
+	world, err := readWorld(p, c)
+	if err != nil {
+		stop(c, 0, "io", err)
+		return
+	}
+
+	// Preview the run locally at low resolution before taking up the cluster with it, and don't send a seed
+	// that dies out or settles down unless asked to.
+	if p.Preview > 0 {
+		opts, _ := kernel.ParseOptions(p.Rule, p.Edge)
+		opts.Seed = p.Seed
+		result := preview(world, p.PreviewScale, p.Preview, p.Threads, opts)
+		result.Submitted = p.PreviewAlways || result.Interesting()
+		result.Emitted = time.Now()
+		c.events <- result
+		if !result.Submitted {
+			c.events <- StateChange{0, Quitting, time.Now()}
+			return
+		}
+	}
+
+	// Connect to the server via RPC. Every call but EvolveWorld gives up after p.RPCTimeout.
+	client, err := dialBroker(BrokerAddress, p.RPCTimeout)
+	if err != nil {
+		stop(c, 0, "broker", fmt.Errorf("couldn't connect to the broker: %v", err))
+		return
 	}
 
 	empty := stubs.Empty{}
+	acquireResponse := &stubs.AcquireResponse{}
+	// Take control of the broker. The returned epoch fences off any client that controlled it before.
+	err = client.Call(stubs.AcquireHandler, empty, acquireResponse)
+	if err != nil {
+		stop(c, 0, "broker", fmt.Errorf("couldn't take control of the broker: %v", err))
+		return
+	}
+	control := stubs.ControlRequest{Epoch: acquireResponse.Epoch}
+
 	continueResponse := &stubs.GetContinueResponse{}
 	// Call RPC method to check if there is a saved state to continue from.
 	err = client.Call(stubs.GetContinueHandler, empty, continueResponse)
-
-	// Fault tolerance: if the server has been quit before, assign the world to be the world stored in the broker.
-	if continueResponse.Continue {
-		world = continueResponse.World
+	if err != nil {
+		// Start from the input image rather than give up.
+		c.events <- ErrorEvent{0, Warning, "broker", fmt.Sprintf("couldn't check for a run to continue: %v", err), true, time.Now()}
+	} else if continueResponse.Continue {
+		// Fault tolerance: if the server has been quit before, assign the world to be the world stored in the broker.
+		world = continueResponse.World.Rows()
 		fmt.Printf("Continuing From Turn %d\n", continueResponse.Turn)
+		// The continued run keeps the labels it was started with, unless this client gives it new ones.
+		if len(p.Labels) == 0 && len(continueResponse.Labels) > 0 {
+			p.Labels = continueResponse.Labels
+			fmt.Printf("Labels: %v\n", util.Labels(p.Labels))
+		}
 	}
 
-	// Send CellFlipped events for any initial live cells in the world.
-	for i := range world {
-		for j := range world[i] {
-			if world[i][j] == 255 {
-				c.events <- CellFlipped{0, util.Cell{j, i}}
+	// Send the initial live cells in the world, all in one event if the GUI draws them at once.
+	loaded := time.Now()
+	if p.InitialBoard {
+		c.events <- InitialBoard{0, aliveCellsOf(world), loaded}
+	} else {
+		for i := range world {
+			for j := range world[i] {
+				if world[i][j] == util.Alive {
+					c.events <- CellFlipped{0, util.Cell{X: j, Y: i}, loaded}
+				}
 			}
 		}
 	}
@@ -80,183 +274,609 @@ func distributor(p Params, c *distributorChannels) {
 
 	// Prepare request to send to server for evolving the world.
 	evolveRequest := stubs.EvolveWorldRequest{
-		World:       world,
+		World:       slab.FromRows(world),
 		Width:       p.ImageWidth,
 		Height:      p.ImageHeight,
 		Turn:        p.Turns,
 		Threads:     p.Threads,
 		ImageWidth:  p.ImageWidth,
 		ImageHeight: p.ImageHeight,
+		Epoch:       control.Epoch,
+		Rule:        p.Rule,
+		Edge:        p.Edge,
+		Seed:        p.Seed,
+		Labels:      p.Labels,
+	}
+	if p.UploadChunk > 0 && p.ImageWidth*p.ImageHeight > p.UploadChunk && !continueResponse.Continue {
+		// Sent whole, a world this big could make a message too large to be practical.
+		id, err := golclient.UploadWorld(client, control.Epoch, evolveRequest.World, p.UploadChunk)
+		if err != nil {
+			stop(c, turn, "broker", fmt.Errorf("couldn't upload the world to the broker: %v", err))
+			return
+		}
+		evolveRequest.World, evolveRequest.Upload = slab.World{}, id
+	}
+	if p.PausePopulation > 0 || p.PauseSteady || len(p.PauseCells) > 0 || p.PausePattern != "" {
+		autoPause := stubs.AutoPauseRequest{Epoch: control.Epoch, Population: p.PausePopulation, Steady: p.PauseSteady, Cells: p.PauseCells}
+		// The pattern was checked by validateParams, and none leaves it empty.
+		autoPause.Pattern, _ = pattern.Parse(p.PausePattern)
+		if err := client.Call(stubs.AutoPauseHandler, autoPause, &stubs.Empty{}); err != nil {
+			// The run can still be paused by hand.
+			c.events <- ErrorEvent{0, Warning, "broker", fmt.Sprintf("couldn't set the triggers to pause the run: %v", err), true, time.Now()}
+		}
 	}
 	evolveResponse := &stubs.EvolveResponse{}
 
 	// Create a separate world variable for the goroutine to avoid data races.
 	goWorld := world
-	done := false
+	// Whether the distributor or the key press goroutine has started ending the run, so only one of them does.
+	// Protected by the DistributorChannels mutex.
+	ending := false
+
+	// Cancelled by the key press goroutine when it ends the run, so the distributor stops waiting for EvolveWorld.
+	run, endRun := context.WithCancel(ctx)
+	defer endRun()
+
+	// The heartbeat and key press goroutines send events until view is done. The distributor ends view and waits
+	// for both to return before it sends any more events itself, or returns and closes the events channel.
+	view, endView := context.WithCancel(run)
+	var live sync.WaitGroup
+	joinLive := func() {
+		endView()
+		live.Wait()
+	}
+	defer joinLive()
+	live.Add(2)
+
+	// Renew the lease from a goroutine of its own, so the broker doesn't presume this client partitioned while
+	// the key press goroutine is blocked waiting for the run to be unpaused.
+	go func() {
+		defer live.Done()
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-view.Done():
+				return
+			case <-ticker.C:
+				err := client.Call(stubs.HeartbeatHandler, control, &stubs.Empty{})
+				if err != nil && err.Error() == stubs.ErrLeaseReleased.Error() {
+					// The run has finished or been quit, so there is no lease left to renew.
+					return
+				}
+				c.mu.Lock()
+				// If the lease has really been lost, EvolveWorld fails and the distributor ends the run.
+				warn(c, &r, stubs.HeartbeatHandler, "renew control of the broker", err)
+				c.mu.Unlock()
+			}
+		}
+	}()
+
 	// Goroutine that handles SDL live view, alive cells count, and key presses.
 	go func() {
+		defer live.Done()
 		ticker := time.NewTicker(2 * time.Second)       // Ticker for alive cell count (every 2 seconds).
 		tickSDL := time.NewTicker(5 * time.Millisecond) // Ticker for SDL live view updates.
-		goDone := done                                  // Local copy to avoid sending on a closed channel.
+		var err error                                   // Separate from the distributor's, which holds the result of EvolveWorld.
 		defer ticker.Stop()
 		defer tickSDL.Stop()
-		for {
-			empty := stubs.Empty{}
-			if goDone {
+
+		// Rule and edge mode the run has been switched to with 'r', kept for saving the state on quitting.
+		rule, edge := p.Rule, p.Edge
+
+		// quit tells the broker to stop the run, saves the world and ends the run, leaving the distributor to close the
+		// events channel once this goroutine has returned. The distributor is told first, so it stops waiting for
+		// EvolveWorld, which the broker ends as soon as it is told.
+		quit := func(handler, what string) {
+			c.mu.Lock()
+			if ending {
+				// The run has already finished.
+				c.mu.Unlock()
+				return
+			}
+			ending = true
+			c.mu.Unlock()
+			endRun()
+			err = client.Call(handler, control, &stubs.Empty{})
+			c.mu.Lock()
+			warn(c, &r, handler, what, err)
+			// StateChange event to indicate quitting and save a PGM image.
+			c.events <- StateChange{r.currentTurn(), Quitting, time.Now()}
+			c.mu.Unlock()
+			err = savePGMImage(c, goWorld, p) // Function to save the current state as a PGM image.
+			c.mu.Lock()
+			reportSave(c, r.currentTurn(), err)
+			// The state is saved as well, so the run can be picked up again with the broker's -resume, under the rule
+			// it had been switched to.
+			state := p
+			state.Rule, state.Edge = rule, edge
+			reportSave(c, r.currentTurn(), saveState(goWorld, r.currentTurn(), state))
+			c.mu.Unlock()
+		}
+
+		// Turn the broker has been asked to run until before pausing, or 0 if it hasn't.
+		target := 0
+
+		// runUntil reads the turn sent after 'g' and asks the broker to run until it, resuming the run if paused.
+		// It reports whether the broker agreed.
+		runUntil := func() bool {
+			turn, ok := readTurn(view, c.keyPresses)
+			if !ok {
+				return false
+			}
+			err = client.Call(stubs.RunUntilHandler, stubs.RunUntilRequest{Epoch: control.Epoch, Turn: turn}, &stubs.Empty{})
+			c.mu.Lock()
+			warn(c, &r, stubs.RunUntilHandler, fmt.Sprintf("run until turn %d", turn), err)
+			c.mu.Unlock()
+			if err != nil {
+				return false
+			}
+			target = turn
+			fmt.Printf("Running until turn %d\n", turn)
+			return true
+		}
+
+		// changeRule reads the rule or edge mode sent after 'r' and asks the broker to switch the run to it from the
+		// next turn on. A typing mistake or a refusal is reported every time, since the user is waiting to see it.
+		changeRule := func() {
+			text, ok := readLine(view, c.keyPresses)
+			if !ok {
+				return
+			}
+			newRule, newEdge, err := splitRuleChange(text)
+			if err != nil {
+				c.mu.Lock()
+				c.events <- ErrorEvent{r.currentTurn(), Warning, "input", err.Error(), true, time.Now()}
+				c.mu.Unlock()
+				return
+			}
+			req := stubs.SetRuleRequest{Epoch: control.Epoch, Rule: newRule, Edge: newEdge}
+			if err := client.Call(stubs.SetRuleHandler, req, &stubs.Empty{}); err != nil {
+				c.mu.Lock()
+				c.events <- ErrorEvent{r.currentTurn(), Warning, "broker", fmt.Sprintf("couldn't switch to %q: %v", text, err), true, time.Now()}
+				c.mu.Unlock()
+				return
+			}
+			if newRule != "" {
+				rule = newRule
+			}
+			if newEdge != "" {
+				edge = newEdge
+			}
+			fmt.Printf("Switching to %q at the next turn\n", text)
+		}
+
+		// pollFlipped fetches the cells flipped since the last poll and sends them to the GUI, each turn followed by a
+		// TurnComplete. The caller must hold the DistributorChannels mutex.
+		pollFlipped := func() (*stubs.GetBrokerCellFlippedResponse, error) {
+			cellFlippedResponse := &stubs.GetBrokerCellFlippedResponse{}
+			// Get the array of cell flipped events from the broker via RPC, timing the round trip so the GUI can
+			// tell lag from the network apart from lag drawing the turns.
+			fetched := time.Now()
+			err := client.Call(stubs.GetBrokerCellFlippedHandler, stubs.Empty{}, cellFlippedResponse)
+			polled, network := time.Now(), time.Since(fetched)
+			warn(c, &r, stubs.GetBrokerCellFlippedHandler, "fetch flipped cells", err)
+			// A poll that failed or timed out is skipped, and the next one tries again.
+			var cellUpdates []stubs.FlippedEvent
+			if err == nil {
+				cellUpdates = cellFlippedResponse.FlippedEvents
+			}
+			// Tell the GUI when the broker starts splitting the world between its workers differently.
+			if err == nil && cellFlippedResponse.AssignmentVersion != r.owners {
+				warn(c, &r, stubs.GetAssignmentsHandler, "fetch worker assignments", reportOwnership(c, &r))
+			}
+			// The queue may span several turns, so a TurnComplete is sent each time the turn changes.
+			for i := range cellUpdates {
+				if i > 0 && cellUpdates[i].CompletedTurns != cellUpdates[i-1].CompletedTurns {
+					c.events <- TurnComplete{CompletedTurns: cellUpdates[i-1].CompletedTurns, Network: network, Emitted: polled}
+				}
+				// Send CellFlipped events to the events channel.
+				c.events <- CellFlipped{cellUpdates[i].CompletedTurns, cellUpdates[i].Cell, polled}
+			}
+			// After sending all CellFlipped events for the last turn, send a TurnComplete event.
+			if len(cellUpdates) != 0 {
+				c.events <- TurnComplete{CompletedTurns: cellUpdates[len(cellUpdates)-1].CompletedTurns, Network: network, Emitted: polled}
+			}
+			// The broker sends the whole world instead of the flips once the live view has fallen too far behind.
+			if err == nil && cellFlippedResponse.Keyframe.Cells != nil {
+				turn := cellFlippedResponse.Turn
+				c.events <- LiveViewShed{turn, cellFlippedResponse.Skipped, aliveCellsOf(cellFlippedResponse.Keyframe.Rows()), polled}
+				c.events <- TurnComplete{CompletedTurns: turn, Network: network, Emitted: polled}
+			}
+			return cellFlippedResponse, err
+		}
+
+		// setCells reads the cells sent after 'c' and asks the broker to set them, then fetches their flips so the GUI
+		// draws them while the run is still paused. A refusal, e.g. because the run isn't paused, is reported every
+		// time, since the user is waiting to see the cells appear.
+		setCells := func() {
+			text, ok := readLine(view, c.keyPresses)
+			if !ok {
+				return
+			}
+			cells, alive, err := parseCells(text)
+			if err != nil {
+				c.mu.Lock()
+				c.events <- ErrorEvent{r.currentTurn(), Warning, "input", err.Error(), true, time.Now()}
+				c.mu.Unlock()
 				return
 			}
+			res := &stubs.SetCellsResponse{}
+			err = client.Call(stubs.SetCellsHandler, stubs.SetCellsRequest{Epoch: control.Epoch, Cells: cells, Alive: alive}, res)
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if err != nil {
+				c.events <- ErrorEvent{r.currentTurn(), Warning, "broker", fmt.Sprintf("couldn't set %d cells: %v", len(cells), err), true, time.Now()}
+				return
+			}
+			if flipped, err := pollFlipped(); err == nil {
+				r.setTurn(flipped.Turn)
+			}
+			fmt.Printf("Set %d cells %s at turn %d\n", res.Changed, strings.Fields(text)[0], res.Turn)
+		}
+
+		// holdPaused waits while the broker is paused until 'p' resumes the run or 'g' runs it on to another turn.
+		// A change of rule asked for with 'r' meanwhile is applied once the run resumes, while cells drawn with 'c'
+		// are set straight away.
+		holdPaused := func() {
+			fmt.Printf("Current turn %d being processed\n", r.currentTurn())
+			for paused := true; paused; { // Loop until 'p' is pressed again.
+				select {
+				case key := <-c.keyPresses:
+					switch key {
+					case 'p':
+						paused = false
+					case 'g':
+						if runUntil() {
+							// The broker has resumed the run itself.
+							c.events <- StateChange{r.currentTurn(), Executing, time.Now()}
+							return
+						}
+					case 'r':
+						changeRule()
+					case 'c':
+						setCells()
+					}
+				case <-view.Done():
+					paused = false // The broker can't quit while paused, so resume before quitting.
+				}
+				if !paused {
+					// Unlock broker mutex.
+					err = client.Call(stubs.UnpauseHandler, control, &stubs.Empty{})
+					c.mu.Lock()
+					warn(c, &r, stubs.UnpauseHandler, "resume the broker", err)
+					c.mu.Unlock()
+				}
+			}
+			// StateChange event to indicate execution after pausing.
+			c.events <- StateChange{r.currentTurn(), Executing, time.Now()}
+		}
+
+		for {
+			empty := stubs.Empty{}
 			select {
+			// The distributor has ended the run itself, or the caller has given up on it.
+			case <-view.Done():
+				if ctx.Err() != nil {
+					quit(stubs.QuitHandler, "tell the broker to quit")
+				}
+				return
 			// If a tick is received from the tickSDL channel, update SDL view.
 			case <-tickSDL.C: // SDL Live View.
 				// Lock the DistributorChannels mutex while sending events.
 				c.mu.Lock()
-				cellFlippedResponse := &stubs.GetBrokerCellFlippedResponse{}
-				// Get the array of cell flipped events from the broker via RPC.
-				err = client.Call(stubs.GetBrokerCellFlippedHandler, empty, cellFlippedResponse)
-				cellUpdates := cellFlippedResponse.FlippedEvents
-				if len(cellUpdates) != 0 {
-					for i := range cellUpdates {
-						if !done { // Further validation to check if channel is closed.
-							// Send CellFlipped events to the events channel.
-							c.events <- CellFlipped{cellUpdates[i].CompletedTurns, cellUpdates[i].Cell}
-						}
-					}
-					// After sending all CellFlipped events for the turn, send a TurnComplete event.
-					if !done { // Check if channel is closed.
-						c.events <- TurnComplete{CompletedTurns: cellUpdates[0].CompletedTurns}
-					}
-				}
+				cellFlippedResponse, err := pollFlipped()
+				// The broker pauses by itself once it reaches the turn 'g' asked for, or a trigger goes off, with
+				// every flip up to it sent.
+				reached := err == nil && target > 0 && cellFlippedResponse.Turn >= target
+				triggered := err == nil && cellFlippedResponse.Paused != ""
 				c.mu.Unlock() // Unlock the DistributorChannels mutex.
+				if triggered {
+					fmt.Printf("Paused at turn %d: %s\n", cellFlippedResponse.Turn, cellFlippedResponse.Paused)
+				}
+				if reached || triggered {
+					target = 0
+					r.setTurn(cellFlippedResponse.Turn)
+					c.events <- StateChange{r.currentTurn(), Paused, time.Now()}
+					holdPaused()
+				}
 			// If a tick is received from the ticker channel, output AliveCellsCount.
 			case <-ticker.C:
 				c.mu.Lock() // Lock DistributorChannels mutex.
 				aliveCellsCountResponse := &stubs.AliveCellsCountResponse{}
 				// RPC call to get alive cells count from the broker.
 				err = client.Call(stubs.AliveCellsCountHandler, empty, aliveCellsCountResponse)
-				if err != nil {
-					log.Fatal("call error : ", err)
-					return
-				}
-				// Get responses from RPC.
-				numberAliveCells := aliveCellsCountResponse.AliveCellsCount
-				r.turn = aliveCellsCountResponse.CompletedTurns
-				if !done { // Check if channel is closed.
+				warn(c, &r, stubs.AliveCellsCountHandler, "count alive cells", err)
+				if err == nil {
+					// Get responses from RPC.
+					numberAliveCells := aliveCellsCountResponse.AliveCellsCount
+					r.setTurn(aliveCellsCountResponse.CompletedTurns)
 					// Send AliveCellsCount event with responses.
-					c.events <- AliveCellsCount{r.turn, numberAliveCells}
+					c.events <- AliveCellsCount{r.currentTurn(), numberAliveCells, time.Now()}
 				}
+				// Report if the world has started repeating itself.
+				warn(c, &r, stubs.WorldHashHandler, "check for a cycle", reportCycle(c, &r))
 				c.mu.Unlock() // Unlock DistributorChannels mutex.
 			// Check for keypress events.
 			case command := <-c.keyPresses:
 				// React based on the keypress command.
 				empty := stubs.Empty{}
 				emptyResponse := &stubs.Empty{}
-				getGlobal := &stubs.GetGlobalResponse{}
-				// RPC call to get the current world and turn from the broker.
-				err = client.Call(stubs.GetGlobalHandler, empty, getGlobal)
-				if err != nil {
-					log.Fatal("call error : ", err)
-					return
+				// Sharded saves are written by the workers, so the world needn't be fetched for them.
+				if command != 's' || !p.Shards {
+					getGlobal := &stubs.GetGlobalResponse{}
+					// RPC call to get the current world and turn from the broker.
+					err = client.Call(stubs.GetGlobalHandler, empty, getGlobal)
+					c.mu.Lock()
+					warn(c, &r, stubs.GetGlobalHandler, "fetch the world, so using the last one fetched", err)
+					c.mu.Unlock()
+					if err == nil {
+						// Update local variables with responses.
+						goWorld = getGlobal.World.Rows()
+						r.setTurn(getGlobal.Turn)
+					}
 				}
-				// Update local variables with responses.
-				goWorld = getGlobal.World
-				r.turn = getGlobal.Turns
 
 				switch command {
 				case 's': // 's' key is pressed.
 					// StateChange event to indicate execution and save a PGM image.
 					c.mu.Lock()
-					c.events <- StateChange{r.turn, Executing}
+					c.events <- StateChange{r.currentTurn(), Executing, time.Now()}
 					c.mu.Unlock()
-					savePGMImage(c, goWorld, p) // Function to save the current state as a PGM image.
-
-				case 'q': // 'q' key is pressed.
-					// StateChange event to indicate quitting and save a PGM image.
-					err = client.Call(stubs.QuitHandler, empty, emptyResponse)
+					if p.Shards {
+						err = saveShards(client, p)
+					} else {
+						err = savePGMImage(c, goWorld, p) // Function to save the current state as a PGM image.
+					}
 					c.mu.Lock()
-					c.events <- StateChange{r.turn, Quitting}
+					reportSave(c, r.currentTurn(), err)
 					c.mu.Unlock()
-					savePGMImage(c, goWorld, p) // Function to save the current state as a PGM image.
-					close(c.events)             // Close the events channel.
-					done = true                 // Update boolean to know that channel is closed.
-					return                      // Exit goroutine.
+
+				case 'q': // 'q' key is pressed.
+					quit(stubs.QuitHandler, "tell the broker to quit")
+					return // Exit goroutine.
 
 				case 'k': // 'k' key is pressed.
 					// RPC call to kill the server.
-					err = client.Call(stubs.KillServerHandler, empty, emptyResponse)
-					c.mu.Lock()
-					// StateChange event to indicate quitting and save a PGM image.
-					c.events <- StateChange{r.turn, Quitting}
-					c.mu.Unlock()
-					savePGMImage(c, goWorld, p) // Function to save the current state as a PGM image.
-					close(c.events)             // Close the events channel.
-					done = true                 // Update boolean to know that channel is closed.
-					return                      // Exit goroutine.
+					quit(stubs.KillServerHandler, "kill the broker")
+					return // Exit goroutine.
+
+				case 'g': // 'g' key is pressed, followed by the digits of a turn and a newline.
+					runUntil()
+
+				case 'r': // 'r' key is pressed, followed by a rule or edge mode and a newline.
+					changeRule()
+
+				case 'c': // Cells drawn in the GUI, followed by whether they are alive and where, and a newline.
+					setCells() // Refused by the broker, as cells can only be set while paused.
 
 				case 'p': // 'p' key is pressed.
 					// Pause the simulation.
-					c.events <- StateChange{r.turn, Paused}
-					// Lock the broker mutex so nothing can be changed or accessed during pause.
-					err = client.Call(stubs.PauseHandler, empty, emptyResponse)
-					fmt.Printf("Current turn %d being processed\n", r.turn)
-					for { // Enter an infinite loop which only breaks after 'p' is pressed again.
-						if <-c.keyPresses == 'p' { // Waits for another 'p' key press.
-							// Unlock broker mutex.
-							err = client.Call(stubs.UnpauseHandler, empty, emptyResponse)
-							break
+					target = 0 // Resuming from this pause runs on to the end, not to a turn asked for earlier.
+					// The broker finishes the turn in progress and publishes it before pausing, unless asked to lock its
+					// mutex so nothing can be changed or accessed during pause.
+					pause := stubs.PauseHandler
+					if p.HardPause {
+						pause = stubs.HardPauseHandler
+					}
+					err = client.Call(pause, control, emptyResponse)
+					c.mu.Lock()
+					warn(c, &r, pause, "pause the broker", err)
+					if err == nil && !p.HardPause {
+						// Fetch the flips of the turns completed since the last poll, so the GUI has drawn exactly the
+						// turn paused at, rather than a frame behind it, when told the run is paused.
+						if res, err := pollFlipped(); err == nil {
+							r.setTurn(res.Turn)
 						}
 					}
-					// StateChange event to indicate execution after pausing.
-					c.events <- StateChange{r.turn, Executing}
-				}
-			default: // No events.
-				if r.turn == p.Turns {
-					return
+					c.mu.Unlock()
+					c.events <- StateChange{r.currentTurn(), Paused, time.Now()}
+					holdPaused()
 				}
 			}
 		}
 	}()
 
 	// Make RPC to start iterating each turn and evolving the world.
-	err = client.Call(stubs.EvolveWorldHandler, evolveRequest, evolveResponse)
+	evolve := client.Go(stubs.EvolveWorldHandler, evolveRequest, evolveResponse, make(chan *rpc.Call, 1))
+	select {
+	case <-evolve.Done:
+		err = evolve.Error
+	case <-run.Done():
+		// The run was quit, killed or cancelled, and the key press goroutine is ending it.
+		return
+	}
+	c.mu.Lock()
+	quitting := ending // The goroutine has already started ending the run because 'q' or 'k' was pressed.
+	ending = true
+	c.mu.Unlock()
+	joinLive()
+	if quitting {
+		return
+	}
 	if err != nil {
-		log.Fatal("call error : ", err)
+		stop(c, r.currentTurn(), "broker", fmt.Errorf("the run failed: %v", err))
+		return
 	}
 	// Update world and turn with the response from the server.
-	world = evolveResponse.World
+	world = evolveResponse.World.Rows()
 	turn = evolveResponse.Turn
 
 	// Prepare request to calculate alive cells for the final turn.
 	aliveCellsRequest := stubs.CalculateAliveCellsRequest{
-		World: world,
+		World: evolveResponse.World,
 	}
 	aliveCellsResponse := &stubs.CalculateAliveCellsResponse{}
 
 	// Retrieve alive cells for the FinalTurnComplete event.
+	var aliveCells []util.Cell
 	err = client.Call(stubs.AliveCellsHandler, aliveCellsRequest, aliveCellsResponse)
-	if err != nil {
-		log.Fatal("call error : ", err)
+	c.mu.Lock()
+	if err == nil {
+		aliveCells = aliveCellsResponse.AliveCells
+	} else {
+		// The final world has already been returned, so its alive cells can be found here instead.
+		warn(c, &r, stubs.AliveCellsHandler, "calculate alive cells, so finding them locally", err)
+		aliveCells = aliveCellsOf(world)
 	}
-	aliveCells := aliveCellsResponse.AliveCells
+
+	// Report a cycle the ticker didn't get the chance to, e.g. on short runs.
+	warn(c, &r, stubs.WorldHashHandler, "check for a cycle", reportCycle(c, &r))
+	c.mu.Unlock()
 
 	// Report the final state using FinalTurnCompleteEvent.
-	c.events <- FinalTurnComplete{turn, aliveCells}
-	savePGMImage(c, world, p) // Save the final world.
+	c.events <- FinalTurnComplete{turn, aliveCells, time.Now()}
+	err = savePGMImage(c, world, p) // Save the final world.
+	c.mu.Lock()
+	reportSave(c, turn, err)
+	c.mu.Unlock()
 
 	// Make sure that the IO has finished any output before exiting.
 	c.ioCommand <- ioCheckIdle
 	<-c.ioIdle
 
 	// Send Quitting StateChange event.
-	c.events <- StateChange{turn, Quitting}
+	c.events <- StateChange{turn, Quitting, time.Now()}
+}
 
-	// Close the events channel to stop the SDL goroutine gracefully.
-	close(c.events)
-	done = true // Update boolean to indicate channel is closed.
+// readTurn reads the digits of a turn sent as key presses after 'g', up to the newline that ends them. It
+// reports false if anything else comes first or ctx is done.
+func readTurn(ctx context.Context, keyPresses <-chan rune) (int, bool) {
+	turn, digits := 0, 0
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, false
+		case key := <-keyPresses:
+			switch {
+			case key == '\n':
+				return turn, digits > 0
+			case key >= '0' && key <= '9' && digits < 9:
+				turn = 10*turn + int(key-'0')
+				digits++
+			default:
+				return 0, false
+			}
+		}
+	}
+}
+
+// readLine reads the text sent as key presses after 'r', up to the newline that ends it. It reports false if ctx
+// is done first.
+func readLine(ctx context.Context, keyPresses <-chan rune) (string, bool) {
+	var line []rune
+	for {
+		select {
+		case <-ctx.Done():
+			return "", false
+		case key := <-keyPresses:
+			if key == '\n' {
+				return string(line), true
+			}
+			line = append(line, key)
+		}
+	}
+}
 
+// splitRuleChange splits the text typed after 'r' into a rule and an edge mode, either of which may be left out,
+// e.g. "B36/S23", "dead" or "B36/S23 dead". Each is checked here, so a typing mistake isn't sent to the broker.
+func splitRuleChange(text string) (rule, edge string, err error) {
+	for _, field := range strings.Fields(text) {
+		if _, err := kernel.ParseEdge(field); err == nil && edge == "" {
+			edge = field
+			continue
+		}
+		if rule != "" {
+			return "", "", fmt.Errorf("%q isn't a rule and an edge mode", text)
+		}
+		if _, err := kernel.ParseRule(field); err != nil {
+			return "", "", err
+		}
+		rule = field
+	}
+	if rule == "" && edge == "" {
+		return "", "", errors.New("no rule or edge mode was given")
+	}
+	return rule, edge, nil
+}
+
+// parseCells parses the text sent after 'c', "alive" or "dead" followed by the cells to set to that state as x,y
+// pairs separated by spaces, e.g. "alive 3,4 4,4 5,4".
+func parseCells(text string) (cells []util.Cell, alive bool, err error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || fields[0] != "alive" && fields[0] != "dead" {
+		return nil, false, fmt.Errorf("%q doesn't start with alive or dead", text)
+	}
+	for _, field := range fields[1:] {
+		var cell util.Cell
+		if _, err := fmt.Sscanf(field, "%d,%d", &cell.X, &cell.Y); err != nil {
+			return nil, false, fmt.Errorf("%q isn't a cell given as x,y", field)
+		}
+		cells = append(cells, cell)
+	}
+	if len(cells) == 0 {
+		return nil, false, errors.New("no cells were given")
+	}
+	return cells, fields[0] == "alive", nil
+}
+
+// aliveCellsOf returns the alive cells in the world.
+func aliveCellsOf(world [][]byte) []util.Cell {
+	aliveCells := []util.Cell{}
+	for y := range world {
+		for x := range world[y] {
+			if world[y][x] == util.Alive {
+				aliveCells = append(aliveCells, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return aliveCells
+}
+
+// saveShards has the broker save the current world as one image per worker slice, written by the workers
+// themselves, and prints where the manifest listing them went.
+func saveShards(client *brokerClient, p Params) error {
+	dir := p.OutDir
+	// Workers run elsewhere, so a local directory has to be given in full, and be shared with them, to mean the
+	// same place on every machine.
+	if !strings.Contains(dir, "://") {
+		absolute, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		dir = absolute
+	}
+	res := &stubs.SaveShardsResponse{}
+	if err := client.Call(stubs.SaveShardsHandler, stubs.SaveShardsRequest{Dir: dir}, res); err != nil {
+		return fmt.Errorf("couldn't save shards: %v", err)
+	}
+	written := 0
+	for _, shard := range res.Shards {
+		if shard.Worker >= 0 {
+			written++
+		}
+	}
+	fmt.Printf("Saved turn %d as %d shards (%d written by workers), listed in %s\n", res.Turn, len(res.Shards), written, res.Manifest)
+	return nil
+}
+
+// saveState saves the world and the turn, rule, edges, seed and labels it belongs to in the golsnap format, as
+// <width>x<height>x<turn>.golsnap beside the images, returning an error if it couldn't be written.
+func saveState(world [][]byte, turn int, p Params) error {
+	data, err := golsnap.Encode(golsnap.State{Turn: turn, Seed: p.Seed, Rule: p.Rule, Edge: p.Edge, World: slab.FromRows(world),
+		Labels: p.Labels})
+	if err != nil {
+		return fmt.Errorf("couldn't save turn %d: %v", turn, err)
+	}
+	filename := fmt.Sprintf("%dx%dx%d%s", p.ImageWidth, p.ImageHeight, turn, golsnap.Extension)
+	if err := storage.Put(storage.Join(outputDir(p), filename), data); err != nil {
+		return fmt.Errorf("couldn't save %s: %v", filename, err)
+	}
+	return nil
 }
 
-// savePGMImage saves the current world state as a PGM image.
-func savePGMImage(c *distributorChannels, world [][]byte, p Params) {
+// savePGMImage saves the current world state as a PGM image, returning an error if it couldn't be written.
+func savePGMImage(c *distributorChannels, world [][]byte, p Params) error {
 	c.ioCommand <- ioOutput
 	c.ioFilename <- fmt.Sprintf("%dx%dx%d", p.ImageWidth, p.ImageHeight, p.Turns)
 	// Iterate over the world and send each cell's value to the ioOutput channel for writing the PGM image.
@@ -265,4 +885,5 @@ func savePGMImage(c *distributorChannels, world [][]byte, p Params) {
 			c.ioOutput <- world[i][j] // Send the current cell value to the output channel.
 		}
 	}
+	return <-c.ioErrors
 }
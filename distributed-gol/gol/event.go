@@ -2,10 +2,14 @@ package gol
 
 import (
 	"fmt"
+	"time"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/stubs"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
 // Event represents any Game of Life event that needs to be communicated to the user.
+// Every event records when it was Emitted, so the GUI can measure how long events take to be shown.
 type Event interface {
 	// Stringer allows each event to be printed by the GUI
 	fmt.Stringer
@@ -19,6 +23,7 @@ type Event interface {
 type AliveCellsCount struct { // implements Event
 	CompletedTurns int
 	CellsCount     int
+	Emitted        time.Time // When the event was sent.
 }
 
 // ImageOutputComplete is an Event notifying the user about the completion of output.
@@ -26,6 +31,7 @@ type AliveCellsCount struct { // implements Event
 type ImageOutputComplete struct { // implements Event
 	CompletedTurns int
 	Filename       string
+	Emitted        time.Time // When the event was sent.
 }
 
 // State represents a change in the state of execution.
@@ -42,6 +48,7 @@ const (
 type StateChange struct { // implements Event
 	CompletedTurns int
 	NewState       State
+	Emitted        time.Time // When the event was sent.
 }
 
 // CellFlipped is an Event notifying the GUI about a change of state of a single cell.
@@ -50,6 +57,16 @@ type StateChange struct { // implements Event
 type CellFlipped struct { // implements Event
 	CompletedTurns int
 	Cell           util.Cell
+	Emitted        time.Time // When the event was sent.
+}
+
+// InitialBoard is an Event giving the GUI every cell alive in the world the run starts from, at once. It is sent
+// instead of the turn 0 CellFlipped events when Params.InitialBoard is set, so the window can draw a dense world in
+// one pass.
+type InitialBoard struct { // implements Event
+	CompletedTurns int
+	Alive          []util.Cell
+	Emitted        time.Time // When the event was sent.
 }
 
 // TurnComplete is an Event notifying the GUI about turn completion.
@@ -57,6 +74,77 @@ type CellFlipped struct { // implements Event
 // All CellFlipped events must be sent *before* TurnComplete.
 type TurnComplete struct { // implements Event
 	CompletedTurns int
+	Network        time.Duration // How long fetching the turn's flipped cells from the broker took.
+	Emitted        time.Time     // When the event was sent.
+}
+
+// LiveViewShed is an Event giving the GUI every cell alive at CompletedTurns, sent in place of the CellFlipped
+// events since the last poll when the live view had fallen so far behind that the broker sent the whole world
+// instead. The GUI redraws the world from it, and the Skipped turns before it are never shown.
+type LiveViewShed struct { // implements Event
+	CompletedTurns int
+	Skipped        int
+	Alive          []util.Cell
+	Emitted        time.Time // When the event was sent.
+}
+
+// CycleDetected is an Event notifying the user that the world has returned to a state it was in earlier in the run.
+// From then on the simulation repeats every Period turns. This Event is sent once per run.
+type CycleDetected struct { // implements Event
+	CompletedTurns int
+	Period         int
+	Emitted        time.Time // When the event was sent.
+}
+
+// WorkerOwnership is an Event notifying the GUI about which worker computes each region of the world.
+// This Event is sent at the start of a run and whenever the broker changes how it splits the world.
+type WorkerOwnership struct { // implements Event
+	CompletedTurns int
+	Assignments    []stubs.Assignment
+	Emitted        time.Time // When the event was sent.
+}
+
+// PreviewComplete is an Event reporting what running the first turns of the world at low resolution on the
+// client showed. It is sent once, before the world is sent to the broker, if Params.Preview is set.
+type PreviewComplete struct { // implements Event
+	Turns      int       // Turns previewed, fewer than asked for if the preview died out or repeated itself first.
+	Scale      int       // Each cell of the preview stands for a Scale x Scale block of the world.
+	Population []int     // Alive cells of the preview at the start and after each turn.
+	Period     int       // Length of the cycle the preview entered, 1 for a still life, or 0 if it didn't enter one.
+	Submitted  bool      // Whether the run is sent to the broker afterwards.
+	Emitted    time.Time // When the event was sent.
+}
+
+// CompareTurn is an Event carrying the worlds two engines computed for the same turn of a comparison run, and how
+// long each took, so they can be shown side by side. The worlds aren't changed after the event is sent.
+type CompareTurn struct { // implements Event
+	CompletedTurns int
+	Engines        [2]kernel.Algorithm
+	Worlds         [2][][]byte
+	Took           [2]time.Duration // How long each engine took to compute this turn.
+	Differ         int              // Cells the two worlds disagree on.
+	Emitted        time.Time        // When the event was sent.
+}
+
+// Severity says how badly an ErrorEvent affects the run.
+type Severity int
+
+const (
+	Warning Severity = iota // Something the user asked for, or a periodic update, didn't happen.
+	Error                   // Part of the run failed, e.g. a snapshot wasn't saved or the broker was lost.
+)
+
+// ErrorEvent is an Event notifying the user that something went wrong, instead of the client exiting.
+// Component names the part that failed, such as "io", "broker" or "params". If Recoverable is false the run
+// has ended: the distributor sends StateChange{Quitting} and closes the events channel straight afterwards,
+// without a FinalTurnComplete.
+type ErrorEvent struct { // implements Event
+	CompletedTurns int
+	Severity       Severity
+	Component      string
+	Message        string
+	Recoverable    bool
+	Emitted        time.Time // When the event was sent.
 }
 
 // FinalTurnComplete is an Event notifying the testing framework about the new world state after execution finished.
@@ -65,6 +153,7 @@ type TurnComplete struct { // implements Event
 type FinalTurnComplete struct {
 	CompletedTurns int
 	Alive          []util.Cell
+	Emitted        time.Time // When the event was sent.
 }
 
 // String methods allow the different types of Events and States to be printed.
@@ -82,6 +171,17 @@ func (state State) String() string {
 	}
 }
 
+func (severity Severity) String() string {
+	switch severity {
+	case Warning:
+		return "Warning"
+	case Error:
+		return "Error"
+	default:
+		return "Incorrect Severity"
+	}
+}
+
 func (event StateChange) String() string {
 	return fmt.Sprintf("%v", event.NewState)
 }
@@ -106,6 +206,74 @@ func (event ImageOutputComplete) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event CycleDetected) String() string {
+	return fmt.Sprintf("Cycle detected with period %v", event.Period)
+}
+
+func (event CycleDetected) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event PreviewComplete) String() string {
+	first, last := event.Population[0], event.Population[len(event.Population)-1]
+	var outcome string
+	switch {
+	case last == 0:
+		outcome = "died out"
+	case event.Period == 1:
+		outcome = "became still"
+	case event.Period > 1:
+		outcome = fmt.Sprintf("entered a cycle of period %d", event.Period)
+	default:
+		outcome = fmt.Sprintf("was still changing, %d alive cells from %d", last, first)
+	}
+	action := "submitting the full run"
+	if !event.Submitted {
+		action = "not submitting the full run"
+	}
+	return fmt.Sprintf("Preview at 1/%d scale %s after %d turns, %s", event.Scale, outcome, event.Turns, action)
+}
+
+func (event PreviewComplete) GetCompletedTurns() int {
+	return 0
+}
+
+func (event CompareTurn) String() string {
+	return fmt.Sprintf("%v %v, %v %v, %d cells differ", event.Engines[0], event.Took[0].Round(time.Microsecond),
+		event.Engines[1], event.Took[1].Round(time.Microsecond), event.Differ)
+}
+
+func (event CompareTurn) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event ErrorEvent) String() string {
+	if event.Recoverable {
+		return fmt.Sprintf("%v (%v): %v", event.Severity, event.Component, event.Message)
+	}
+	return fmt.Sprintf("%v (%v): %v - stopping", event.Severity, event.Component, event.Message)
+}
+
+func (event ErrorEvent) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event LiveViewShed) String() string {
+	return fmt.Sprintf("Live view fell behind, so %d turns were skipped", event.Skipped)
+}
+
+func (event LiveViewShed) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event WorkerOwnership) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event WorkerOwnership) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event CellFlipped) String() string {
 	return fmt.Sprintf("")
 }
@@ -114,6 +282,14 @@ func (event CellFlipped) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event InitialBoard) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event InitialBoard) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event TurnComplete) String() string {
 	return fmt.Sprintf("")
 }
@@ -130,6 +306,52 @@ func (event FinalTurnComplete) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+// stamp returns a copy of an event with Emitted set to at.
+func stamp(event Event, at time.Time) Event {
+	switch e := event.(type) {
+	case AliveCellsCount:
+		e.Emitted = at
+		return e
+	case ImageOutputComplete:
+		e.Emitted = at
+		return e
+	case StateChange:
+		e.Emitted = at
+		return e
+	case CellFlipped:
+		e.Emitted = at
+		return e
+	case InitialBoard:
+		e.Emitted = at
+		return e
+	case TurnComplete:
+		e.Emitted = at
+		return e
+	case LiveViewShed:
+		e.Emitted = at
+		return e
+	case CycleDetected:
+		e.Emitted = at
+		return e
+	case WorkerOwnership:
+		e.Emitted = at
+		return e
+	case PreviewComplete:
+		e.Emitted = at
+		return e
+	case CompareTurn:
+		e.Emitted = at
+		return e
+	case ErrorEvent:
+		e.Emitted = at
+		return e
+	case FinalTurnComplete:
+		e.Emitted = at
+		return e
+	}
+	return event
+}
+
 // This might all seem like weird syntax to you...
 // You have however seen something similar to it before in first year.
 
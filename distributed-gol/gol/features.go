@@ -0,0 +1,122 @@
+package gol
+
+import (
+	"fmt"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+)
+
+// Engines a run can be sent to, as named in an UnsupportedFeature.
+const (
+	BrokerEngine  = "the broker"      // Run sends the run to the broker and its workers.
+	CompareEngine = "the compare run" // RunCompare evolves the world with two kernels on the client.
+)
+
+// UnsupportedFeature is returned when a run asks for something the engine it is sent to can't honour, rather than
+// the setting being quietly ignored. It lists the engines that can.
+type UnsupportedFeature struct {
+	Engine    string   // Engine that can't honour the feature, e.g. "the compare run" or "the bitsliced kernel".
+	Feature   string   // What was asked for, e.g. "hard pausing".
+	Setting   string   // The setting that asked for it, as given on the command line, e.g. "-hardPause".
+	Supported []string // Engines that can honour it.
+}
+
+func (e UnsupportedFeature) Error() string {
+	supported := e.Supported[len(e.Supported)-1]
+	if len(e.Supported) > 1 {
+		supported = strings.Join(e.Supported[:len(e.Supported)-1], ", ") + " or " + supported
+	}
+	return fmt.Sprintf("%s can't honour %s, asked for with %s: only %s can", e.Engine, e.Feature, e.Setting, supported)
+}
+
+// features says which engines honour each setting that not every engine does. runs are the engines a run can be
+// sent to; kernels, if any are given, are the kernels that honour it too, any others falling back to another.
+var features = []struct {
+	name    string
+	setting func(p Params) string // The setting asking for the feature, or "" if p doesn't.
+	runs    []string
+	kernels []kernel.Algorithm
+}{
+	{"stochastic rules", func(p Params) string {
+		if rule, err := kernel.ParseRule(p.Rule); err == nil && rule.Stochastic() {
+			return "-rule " + p.Rule
+		}
+		return ""
+	}, []string{BrokerEngine, CompareEngine}, []kernel.Algorithm{kernel.ByteWise}},
+	{"hard pausing", func(p Params) string { return flagIf(p.HardPause, "-hardPause") },
+		[]string{BrokerEngine}, nil},
+	{"saving a shard per worker", func(p Params) string { return flagIf(p.Shards, "-shards") },
+		[]string{BrokerEngine}, nil},
+	{"previews", func(p Params) string { return flagIf(p.Preview > 0, fmt.Sprintf("-preview %d", p.Preview)) },
+		[]string{BrokerEngine}, nil},
+	{"chunked uploads", func(p Params) string {
+		return flagIf(p.UploadChunk > 0, fmt.Sprintf("-uploadChunk %d", p.UploadChunk))
+	}, []string{BrokerEngine}, nil},
+	{"pausing at a population", func(p Params) string {
+		return flagIf(p.PausePopulation > 0, fmt.Sprintf("-pausePopulation %d", p.PausePopulation))
+	}, []string{BrokerEngine}, nil},
+	{"pausing once the run settles", func(p Params) string { return flagIf(p.PauseSteady, "-pauseSteady") },
+		[]string{BrokerEngine}, nil},
+	{"pausing when cells change", func(p Params) string { return flagIf(len(p.PauseCells) > 0, "-pauseCells") },
+		[]string{BrokerEngine}, nil},
+	{"pausing when a pattern appears", func(p Params) string {
+		return flagIf(p.PausePattern != "", "-pausePattern "+p.PausePattern)
+	}, []string{BrokerEngine}, nil},
+}
+
+// flagIf returns setting if it was asked for, and "" if not.
+func flagIf(asked bool, setting string) string {
+	if asked {
+		return setting
+	}
+	return ""
+}
+
+// CheckFeatures returns an UnsupportedFeature for the first setting in p that engine, or any of the kernels it
+// evolves the world with, can't honour, and nil if they honour them all. A kernel that can't is named with the
+// kernels that can, as a bit-sliced or wide one would otherwise quietly fall back to counting byte by byte.
+func CheckFeatures(p Params, engine string, kernels ...kernel.Algorithm) error {
+	for _, f := range features {
+		setting := f.setting(p)
+		if setting == "" {
+			continue
+		}
+		if !contains(f.runs, engine) {
+			return UnsupportedFeature{engine, f.name, setting, f.runs}
+		}
+		for _, k := range kernels {
+			if f.kernels != nil && !containsKernel(f.kernels, k) {
+				supported := make([]string, len(f.kernels))
+				for i, s := range f.kernels {
+					supported[i] = kernelEngine(s)
+				}
+				return UnsupportedFeature{kernelEngine(k), f.name, setting, supported}
+			}
+		}
+	}
+	return nil
+}
+
+// kernelEngine names a kernel as an engine.
+func kernelEngine(k kernel.Algorithm) string {
+	return fmt.Sprintf("the %v kernel", k)
+}
+
+func contains(engines []string, engine string) bool {
+	for _, e := range engines {
+		if e == engine {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKernel(kernels []kernel.Algorithm, k kernel.Algorithm) bool {
+	for _, c := range kernels {
+		if c == k {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +1,67 @@
 package gol
 
+import (
+	"context"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// BrokerAddress is the address of the broker runs are sent to. Replace with your server's IP and port.
+var BrokerAddress = "127.0.0.1:8030"
+
 // Params provides the details of how to run the Game of Life and which image to load.
 type Params struct {
 	Turns       int
 	Threads     int
 	ImageWidth  int
 	ImageHeight int
+	InDir       string  // Directory or s3:// or gs:// bucket URL input images are read from. Defaults to "images".
+	OutDir      string  // Directory or s3:// or gs:// bucket URL output images are written to. Defaults to "out".
+	Threshold   float64 // Fraction of an input image's maxval at or above which a pixel is alive. Defaults to 0.5.
+	Rule        string  // Rule in B/S notation, e.g. "B36/S23" for HighLife. Defaults to Life, "B3/S23".
+	Edge        string  // "torus" to wrap around the edges of the world or "dead" for dead cells beyond them. Defaults to torus.
+	Seed        int64   // Seed of a stochastic rule's chances. The same seed replays the same run.
+	HardPause   bool    // Pause by locking the broker's mutex, blocking its reads too, rather than holding the run between turns.
+	Shards      bool    // Save with s as one image per worker slice, written by the workers to OutDir, rather than one from the client.
+	Format      string  // Formats images are saved in, separated by commas: "pgm", "npy" for NumPy, or both. Defaults to pgm.
+	// Key/value tags, e.g. experiment, author and notes, attached to the run and saved in its checkpoints and
+	// golsnap saves, so results from many experiments can be told apart.
+	Labels map[string]string
+	// Send the starting world, or the one the run continues from, as one InitialBoard event rather than a
+	// CellFlipped for each alive cell.
+	InitialBoard bool
+	// Turns to run locally on a downsampled copy of the world before sending it to the broker, or 0 not to.
+	// The run is only sent if the preview is still changing at the end, unless PreviewAlways is set.
+	Preview       int
+	PreviewScale  int  // Each cell of the preview stands for a PreviewScale x PreviewScale block of the world.
+	PreviewAlways bool // Send the run to the broker whatever the preview shows.
+	// How long to wait for the broker to answer any call but EvolveWorld, which lasts the run, or 0 to wait forever.
+	// A call that times out is reported with a recoverable ErrorEvent, and the live view skips that poll.
+	RPCTimeout time.Duration
+	// Most cells sent to the broker in one call. A bigger world is uploaded in chunks of this many cells before
+	// the run starts, rather than in the EvolveWorld request. 0 always sends it whole.
+	UploadChunk int
+	// Triggers that make the broker pause the run by itself, reporting why, as 'g' does once it reaches its turn.
+	PausePopulation int         // Pause when the number of alive cells rises above or falls back to this. 0 doesn't.
+	PauseSteady     bool        // Pause when the run settles into a still life or an oscillator.
+	PauseCells      []util.Cell // Pause when any of these cells changes.
+	PausePattern    string      // Pause when this pattern, in Parse's notation, appears in any orientation.
 }
 
 // Run starts the processing of Game of Life. It should initialise channels and goroutines.
 func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
+	RunContext(context.Background(), p, events, keyPresses)
+}
+
+// RunContext is Run, quitting the run as if q had been pressed once ctx is done.
+func RunContext(ctx context.Context, p Params, events chan<- Event, keyPresses <-chan rune) {
+	print(p.Threads)
+	distributor(ctx, p, startChannels(p, events, keyPresses))
+}
+
+// startChannels starts the IO goroutine and returns the channels the distributor talks to it and the user over.
+func startChannels(p Params, events chan<- Event, keyPresses <-chan rune) *distributorChannels {
 
 	// TODO: Put the missing channels in here.
 
@@ -18,8 +70,7 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 	ioFilename := make(chan string)
 	ioOutput := make(chan uint8)
 	ioInput := make(chan uint8)
-
-	print(p.Threads)
+	ioErrors := make(chan error)
 
 	ioChannels := ioChannels{
 		command:  ioCommand,
@@ -27,19 +78,19 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 		filename: ioFilename,
 		output:   ioOutput,
 		input:    ioInput,
+		errors:   ioErrors,
 	}
 
 	go startIo(p, ioChannels)
 
-	distributorChannels := distributorChannels{
+	return &distributorChannels{
 		events:     events,
 		ioCommand:  ioCommand,
 		ioIdle:     ioIdle,
 		ioFilename: ioFilename,
 		ioOutput:   ioOutput,
 		ioInput:    ioInput,
+		ioErrors:   ioErrors,
 		keyPresses: keyPresses,
 	}
-
-	distributor(p, &distributorChannels)
 }
@@ -1,11 +1,13 @@
 package gol
 
 import (
+	"bytes"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"strconv"
 	"strings"
+	"uk.ac.bris.cs/gameoflife/golnpy"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/storage"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
@@ -16,6 +18,7 @@ type ioChannels struct {
 	filename <-chan string
 	output   <-chan uint8
 	input    chan<- uint8
+	errors   chan<- error // Result of every ioInput and ioOutput command, nil if it succeeded.
 }
 
 // ioState is the internal ioState of the io goroutine.
@@ -38,16 +41,34 @@ const (
 	ioCheckIdle
 )
 
+// inDir returns the directory or bucket URL input images are read from.
+func (io *ioState) inDir() string {
+	if io.params.InDir == "" {
+		return "images"
+	}
+	return io.params.InDir
+}
+
+// outDir returns the directory or bucket URL output images are written to.
+func (io *ioState) outDir() string {
+	return outputDir(io.params)
+}
+
+// outputDir is the directory or bucket images and saved states are written to.
+func outputDir(p Params) string {
+	if p.OutDir == "" {
+		return "out"
+	}
+	return p.OutDir
+}
+
 // writePgmImage receives an array of bytes and writes it to a pgm file.
 func (io *ioState) writePgmImage() {
-	_ = os.Mkdir("out", os.ModePerm)
-
 	// Request a filename from the distributor.
 	filename := <-io.channels.filename
 
-	file, ioError := os.Create("out/" + filename + ".pgm")
-	util.Check(ioError)
-	defer file.Close()
+	// Build the whole image in memory, so it can be uploaded in one go if the output is in a bucket.
+	file := &bytes.Buffer{}
 
 	_, _ = file.WriteString("P5\n")
 	//_, _ = file.WriteString("# PGM file writer by pnmmodules (https://github.com/owainkenwayucl/pnmmodules).\n")
@@ -55,7 +76,7 @@ func (io *ioState) writePgmImage() {
 	_, _ = file.WriteString(" ")
 	_, _ = file.WriteString(strconv.Itoa(io.params.ImageHeight))
 	_, _ = file.WriteString("\n")
-	_, _ = file.WriteString(strconv.Itoa(255))
+	_, _ = file.WriteString(strconv.Itoa(int(util.Alive)))
 	_, _ = file.WriteString("\n")
 
 	world := make([][]byte, io.params.ImageHeight)
@@ -74,16 +95,45 @@ func (io *ioState) writePgmImage() {
 	}
 
 	for y := 0; y < io.params.ImageHeight; y++ {
-		for x := 0; x < io.params.ImageWidth; x++ {
-			_, ioError = file.Write([]byte{world[y][x]})
-			util.Check(ioError)
-		}
+		_, _ = file.Write(world[y])
 	}
 
-	ioError = file.Sync()
-	util.Check(ioError)
+	pgm, npy, _ := saveFormats(io.params) // Checked by validateParams.
+	if pgm {
+		ioError := storage.Put(storage.Join(io.outDir(), filename+".pgm"), file.Bytes())
+		if ioError != nil {
+			io.channels.errors <- fmt.Errorf("couldn't save %s: %v", filename, ioError)
+			return
+		}
+	}
+	if npy {
+		ioError := storage.Put(storage.Join(io.outDir(), filename+golnpy.Extension), golnpy.Encode(slab.FromRows(world)))
+		if ioError != nil {
+			io.channels.errors <- fmt.Errorf("couldn't save %s%s: %v", filename, golnpy.Extension, ioError)
+			return
+		}
+	}
 
 	fmt.Println("File", filename, "output done!")
+	io.channels.errors <- nil
+}
+
+// saveFormats reports which formats images are saved in, PGM unless p.Format says otherwise.
+func saveFormats(p Params) (pgm, npy bool, err error) {
+	if p.Format == "" {
+		return true, false, nil
+	}
+	for _, format := range strings.Split(p.Format, ",") {
+		switch strings.ToLower(strings.TrimSpace(format)) {
+		case "pgm":
+			pgm = true
+		case "npy":
+			npy = true
+		default:
+			return false, false, fmt.Errorf("image format %q isn't pgm or npy", format)
+		}
+	}
+	return pgm, npy, nil
 }
 
 // readPgmImage opens a pgm file and sends its data as an array of bytes.
@@ -92,32 +142,26 @@ func (io *ioState) readPgmImage() {
 	// Request a filename from the distributor.
 	filename := <-io.channels.filename
 
-	data, ioError := ioutil.ReadFile("images/" + filename + ".pgm")
-	util.Check(ioError)
-
-	fields := strings.Fields(string(data))
-
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
-	}
-
-	width, _ := strconv.Atoi(fields[1])
-	if width != io.params.ImageWidth {
-		panic("Incorrect width")
+	data, ioError := storage.Get(storage.Join(io.inDir(), filename+".pgm"))
+	if ioError != nil {
+		io.channels.errors <- fmt.Errorf("couldn't read %s: %v", filename, ioError)
+		return
 	}
 
-	height, _ := strconv.Atoi(fields[2])
-	if height != io.params.ImageHeight {
-		panic("Incorrect height")
+	// Normalise the pixels to alive and dead cells, whatever the image's maxval.
+	width, height, image, err := util.ParsePgm(data, io.params.Threshold)
+	if err != nil {
+		io.channels.errors <- fmt.Errorf("%s: %v", filename, err)
+		return
 	}
-
-	maxval, _ := strconv.Atoi(fields[3])
-	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
+	if width != io.params.ImageWidth || height != io.params.ImageHeight {
+		io.channels.errors <- fmt.Errorf("%s is %dx%d, not %dx%d",
+			filename, width, height, io.params.ImageWidth, io.params.ImageHeight)
+		return
 	}
 
-	image := []byte(fields[4])
-
+	// The image is good, so the distributor can start reading cells.
+	io.channels.errors <- nil
 	for _, b := range image {
 		io.channels.input <- b
 	}
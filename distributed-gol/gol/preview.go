@@ -0,0 +1,82 @@
+package gol
+
+import (
+	"hash/fnv"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// previewMinSize is the fewest cells a side of the preview board is shrunk to, however large the scale. Smaller
+// boards are dominated by their edges.
+const previewMinSize = 16
+
+// Interesting reports whether the preview was still changing when it finished. A seed that dies out or settles
+// down at low resolution is unlikely to be worth the cluster's time at full resolution.
+func (event PreviewComplete) Interesting() bool {
+	return event.Population[len(event.Population)-1] > 0 && event.Period == 0
+}
+
+// downsample shrinks the world by scale in each direction, keeping the top-left cell of each block. Unlike
+// counting the alive cells of each block, this keeps the density of a random soup, which is what mostly decides
+// how it evolves.
+func downsample(world [][]byte, scale int) [][]byte {
+	height, width := len(world)/scale, len(world[0])/scale
+	small := make([][]byte, height)
+	for y := range small {
+		small[y] = make([]byte, width)
+		for x := range small[y] {
+			small[y][x] = world[y*scale][x*scale]
+		}
+	}
+	return small
+}
+
+// preview runs up to turns turns of the world shrunk by scale locally with opts, stopping early if it dies out or
+// repeats a state. The scale is lowered if it would leave the preview too small to say anything.
+func preview(world [][]byte, scale, turns, threads int, opts kernel.Options) PreviewComplete {
+	for scale > 1 && (len(world)/scale < previewMinSize || len(world[0])/scale < previewMinSize) {
+		scale--
+	}
+	small := downsample(world, scale)
+	height, width := len(small), len(small[0])
+	chunk := (height + threads - 1) / threads
+
+	result := PreviewComplete{Scale: scale, Population: []int{countAlive(small)}}
+	seen := map[uint64]int{hashWorld(small): 0}
+	for turn := 1; turn <= turns && result.Population[turn-1] > 0; turn++ {
+		opts.Turn = turn - 1
+		small = kernel.NextStateWith(small, width, height, 0, height, chunk, opts)
+		result.Turns = turn
+		result.Population = append(result.Population, countAlive(small))
+		hash := hashWorld(small)
+		if at, ok := seen[hash]; ok {
+			result.Period = turn - at
+			break
+		}
+		seen[hash] = turn
+	}
+	return result
+}
+
+// countAlive counts the alive cells of a world.
+func countAlive(world [][]byte) int {
+	alive := 0
+	for _, row := range world {
+		for _, cell := range row {
+			if cell == util.Alive {
+				alive++
+			}
+		}
+	}
+	return alive
+}
+
+// hashWorld hashes every cell of a world, for spotting the preview repeating itself.
+func hashWorld(world [][]byte) uint64 {
+	h := fnv.New64a()
+	for _, row := range world {
+		h.Write(row)
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,59 @@
+package gol
+
+import (
+	"fmt"
+	"net/rpc"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/memnet"
+)
+
+// brokerClient is the client's connection to the broker, which gives up on a call once it has taken longer than
+// timeout, so a broker that hangs is reported rather than hanging the client with it. A timeout of 0 waits as long
+// as the call takes, as net/rpc does. EvolveWorld lasts the whole run, so it is made with Go and never times out.
+type brokerClient struct {
+	*rpc.Client
+	timeout time.Duration
+}
+
+// timeoutError is returned by a call the broker didn't answer in time.
+type timeoutError struct {
+	method  string
+	timeout time.Duration
+}
+
+func (e timeoutError) Error() string {
+	return fmt.Sprintf("%s got no reply within %v", e.method, e.timeout)
+}
+
+// Timeout reports that the error is a timeout, as net.Error does.
+func (e timeoutError) Timeout() bool {
+	return true
+}
+
+// dialBroker connects to the broker at address, giving up after timeout if it isn't 0. The broker may be in this
+// process, at an in-memory address.
+func dialBroker(address string, timeout time.Duration) (*brokerClient, error) {
+	conn, err := memnet.DialTimeout(address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &brokerClient{rpc.NewClient(conn), timeout}, nil
+}
+
+// Call calls the named method and waits for its reply, or returns a timeoutError once the timeout has passed. The
+// reply may still be decoded into reply after a timeout, so it mustn't be read unless Call returned nil.
+func (b *brokerClient) Call(method string, args, reply interface{}) error {
+	if b.timeout <= 0 {
+		return b.Client.Call(method, args, reply)
+	}
+	call := b.Go(method, args, reply, make(chan *rpc.Call, 1))
+	timer := time.NewTimer(b.timeout)
+	defer timer.Stop()
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-timer.C:
+		return timeoutError{method, b.timeout}
+	}
+}
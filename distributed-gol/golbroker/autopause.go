@@ -0,0 +1,149 @@
+package golbroker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/pattern"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// triggers are the conditions set with AutoPause that soft pause a run by themselves. They are replaced as a whole
+// rather than changed, apart from the state of the world after the latest turn, which only the evolution loop
+// touches once they have been set.
+type triggers struct {
+	population int
+	steady     bool
+	cells      map[util.Cell]bool
+	patterns   []slab.World // Every orientation of the pattern.
+
+	wrap    bool // Whether patterns may straddle the edges, as they do on a torus.
+	above   bool // Whether the population was above the threshold after the latest turn.
+	settled bool // Whether the run had already settled when the latest turn was checked.
+	present bool // Whether the pattern was in the world after the latest turn.
+}
+
+// start notes the state of the world the run is starting or carrying on from, so only changes from it set off a
+// trigger.
+func (t *triggers) start(world slab.World, period int, edge kernel.Edge) {
+	t.wrap = edge == kernel.Torus
+	t.above = countAlive(world) > t.population
+	t.settled = period > 0
+	_, t.present = t.find(world)
+}
+
+// find returns where a pattern first appears in the world, in any orientation, reporting false if none does.
+func (t *triggers) find(world slab.World) (util.Cell, bool) {
+	if len(t.patterns) == 0 {
+		return util.Cell{}, false
+	}
+	board := pattern.Pack(world, t.wrap) // Packed once for every orientation.
+	for _, p := range t.patterns {
+		if found := board.Find(p, 1); len(found) > 0 {
+			return found[0], true
+		}
+	}
+	return util.Cell{}, false
+}
+
+// check returns why the turn just completed should pause the run, or "" if it shouldn't, and notes the state of the
+// world for the next turn.
+func (t *triggers) check(world slab.World, period, alive int, flipped []util.Cell) string {
+	var reasons []string
+	if above := alive > t.population; t.population > 0 && above != t.above {
+		t.above = above
+		if above {
+			reasons = append(reasons, fmt.Sprintf("population rose above %d", t.population))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("population fell to %d", alive))
+		}
+	}
+	if t.steady && period > 0 && !t.settled {
+		t.settled = true
+		if period == 1 {
+			reasons = append(reasons, "settled into a still life")
+		} else {
+			reasons = append(reasons, fmt.Sprintf("settled into an oscillator of period %d", period))
+		}
+	}
+	for _, cell := range flipped {
+		if t.cells[cell] {
+			reasons = append(reasons, fmt.Sprintf("cell (%d, %d) changed", cell.X, cell.Y))
+			break // One is enough to tell the user where to look.
+		}
+	}
+	if len(t.patterns) > 0 {
+		at, present := t.find(world)
+		if present && !t.present {
+			reasons = append(reasons, fmt.Sprintf("the pattern appeared at (%d, %d)", at.X, at.Y))
+		}
+		t.present = present
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// AutoPause sets the triggers that soft pause the run by themselves, replacing any set before. They are compared
+// with the latest generation, so a population already above the threshold or a pattern already there doesn't
+// pause the run until it crosses back or appears again.
+func (b *Broker) AutoPause(req stubs.AutoPauseRequest, res *stubs.Empty) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	if !req.Pattern.Valid() {
+		return errors.New("the pattern has fewer cells than its size")
+	}
+	t := &triggers{population: req.Population, steady: req.Steady}
+	if len(req.Cells) > 0 {
+		t.cells = make(map[util.Cell]bool, len(req.Cells))
+		for _, cell := range req.Cells {
+			t.cells[cell] = true
+		}
+	}
+	if req.Pattern.Width > 0 && req.Pattern.Height > 0 {
+		t.patterns = pattern.Orientations(req.Pattern)
+	}
+
+	// Mu keeps the evolution loop from checking the old triggers against the world while the new ones are set up.
+	b.Mu.Lock()
+	defer b.Mu.Unlock()
+	edge, _ := kernel.ParseEdge(b.Edge)
+	t.start(b.World, b.Period, edge)
+	b.FenceMu.Lock()
+	b.Triggers = t
+	b.FenceMu.Unlock()
+	return
+}
+
+// restartTriggers compares the triggers with the world a run is starting from. It must be called with Mu held.
+func (b *Broker) restartTriggers(edge kernel.Edge) {
+	b.FenceMu.Lock()
+	t := b.Triggers
+	b.FenceMu.Unlock()
+	if t != nil {
+		t.start(b.World, b.Period, edge)
+	}
+}
+
+// pauseOnTrigger soft pauses the run if the turn just completed sets off one of the triggers set with AutoPause.
+// It must be called with Mu held, once the turn has been recorded.
+func (b *Broker) pauseOnTrigger(alive int, flipped []util.Cell) {
+	b.FenceMu.Lock()
+	t := b.Triggers
+	b.FenceMu.Unlock()
+	if t == nil {
+		return
+	}
+	reason := t.check(b.World, b.Period, alive, flipped)
+	if reason == "" {
+		return
+	}
+	fmt.Printf("Pausing at turn %d: %s\n", b.Turn, reason)
+	b.FenceMu.Lock()
+	defer b.FenceMu.Unlock()
+	b.PauseReason = reason
+	b.softPause()
+}
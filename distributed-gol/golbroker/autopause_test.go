@@ -0,0 +1,104 @@
+package golbroker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/pattern"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestTriggers follows three cells in an L turning into a block: the population rises above 3, a block appears,
+// and the next turn repeats it. Each trigger must go off once, on the turn it happens.
+func TestTriggers(t *testing.T) {
+	world := slab.New(6, 6)
+	for _, cell := range []util.Cell{{X: 2, Y: 2}, {X: 3, Y: 2}, {X: 2, Y: 3}} {
+		world.Set(cell.X, cell.Y, util.Alive)
+	}
+	block, _ := pattern.Parse("..../.OO./.OO./....")
+	tr := &triggers{population: 3, steady: true, cells: map[util.Cell]bool{{X: 0, Y: 0}: true}, patterns: pattern.Orientations(block)}
+	tr.start(world, 0, kernel.Torus)
+
+	next, _ := kernel.Next(context.Background(), world, 0, 6, 6, kernel.Defaults)
+	flipped := flippedInSlice(world, next, 0)
+	reason := tr.check(next, 0, countAlive(next), flipped)
+	if !strings.Contains(reason, "population rose above 3") || !strings.Contains(reason, "pattern appeared at (1, 1)") || strings.Contains(reason, "settled") {
+		t.Errorf("turn 1 gave %q, expected the population to rise and the block to appear", reason)
+	}
+	if reason := tr.check(next, 1, countAlive(next), nil); reason != "settled into a still life" {
+		t.Errorf("turn 2 gave %q, expected the run to settle", reason)
+	}
+	if reason := tr.check(next, 1, countAlive(next), nil); reason != "" {
+		t.Errorf("turn 3 gave %q, expected nothing new", reason)
+	}
+	if reason := tr.check(slab.New(6, 6), 1, 0, []util.Cell{{X: 2, Y: 2}, {X: 0, Y: 0}}); reason != "population fell to 0, cell (0, 0) changed" {
+		t.Errorf("the block dying gave %q", reason)
+	}
+}
+
+// TestAutoPause checks the run pauses itself on the turn a watched cell changes, telling the client why until it
+// is resumed, and that taking the trigger away lets the run carry on.
+func TestAutoPause(t *testing.T) {
+	const size = 16
+	watched := util.Cell{X: 4, Y: 5}
+	// Work out when the glider first changes the watched cell.
+	world, changed := gliderWorld(size), 0
+	for turn := 1; changed == 0; turn++ {
+		next, _ := kernel.Next(context.Background(), world, 0, size, size, kernel.Defaults)
+		if next.At(watched.X, watched.Y) != world.At(watched.X, watched.Y) {
+			changed = turn
+		}
+		world = next
+	}
+
+	b := &Broker{Lease: time.Minute}
+	epoch := acquire(t, b)
+	control := stubs.ControlRequest{Epoch: epoch}
+	if err := b.AutoPause(stubs.AutoPauseRequest{Epoch: epoch, Cells: []util.Cell{watched}}, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		req := stubs.EvolveWorldRequest{World: gliderWorld(size), Turn: 1 << 30, ImageWidth: size, ImageHeight: size, Epoch: epoch}
+		done <- b.EvolveWorld(req, &stubs.EvolveResponse{})
+	}()
+	waitForTurn(t, b, changed)
+	time.Sleep(20 * time.Millisecond)
+
+	res := &stubs.GetBrokerCellFlippedResponse{}
+	if err := b.GetCellFlipped(stubs.Empty{}, res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Turn != changed || res.Paused != "cell (4, 5) changed" {
+		t.Fatalf("the run is at turn %d, paused for %q, expected to pause at turn %d as the cell changed", res.Turn, res.Paused, changed)
+	}
+
+	// The glider changes the cell again as it moves on, so the trigger is taken away before resuming.
+	if err := b.AutoPause(stubs.AutoPauseRequest{Epoch: epoch}, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Unpause(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTurn(t, b, changed+4*size)
+	res = &stubs.GetBrokerCellFlippedResponse{}
+	if err := b.GetCellFlipped(stubs.Empty{}, res); err != nil || res.Paused != "" {
+		t.Errorf("still told the run paused itself, %q, after resuming: %v", res.Paused, err)
+	}
+	if err := b.QuitServer(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("quitting left EvolveWorld waiting")
+	}
+}
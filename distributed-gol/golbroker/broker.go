@@ -0,0 +1,1416 @@
+// Package golbroker is the broker, which hands each turn of a run out to its workers and answers the client. The
+// engine command runs it on its own, and Serve runs it alongside the client and workers in one process.
+package golbroker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/rpc"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"uk.ac.bris.cs/gameoflife/audit"
+	"uk.ac.bris.cs/gameoflife/chaos"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/health"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/storage"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/tracing"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Global kill channel used to signal the broker to quit.
+var kill = make(chan bool)
+
+// errStaleEpoch is returned to a client whose fencing epoch has been superseded by another client.
+var errStaleEpoch = errors.New("stale epoch: another client has taken control of the broker")
+
+// errLeaseHeld is returned when a client tries to take control while another client's lease is still live.
+var errLeaseHeld = errors.New("broker is controlled by another client")
+
+// Broker struct represents the broker in the distributed Game of Life simulation.
+// It holds the current state of the world, the list of connected workers, and synchronisation primitives.
+type Broker struct {
+	World         slab.World           // Current state of the world.
+	Turn          int                  // Current turn number.
+	Mu            sync.Mutex           // Mutex to protect shared resources.
+	Quit          bool                 // Flag to indicate if the simulation should quit.
+	Workers       []*workerConn        // List of connected worker clients. Protected by WorkersMu once serving.
+	WorkersMu     sync.Mutex           // Mutex protecting Workers, which RegisterWorker adds to while runs read it.
+	Local         bool                 // Compute every turn on the broker even if workers are connected (-engine=local).
+	Algorithm     kernel.Algorithm     // How the broker counts neighbours when it computes turns itself (-kernel).
+	Cell          util.Cell            // A cell in the world (not used in this snippet).
+	TurnDone      bool                 // Flag to indicate if a turn has been completed.
+	CellUpdates   []util.Cell          // List of cells that have been updated.
+	FlippedEvents []stubs.FlippedEvent // Cells that have changed state since the client last polled.
+	Shed          bool                 // Send a client that has fallen behind the whole world rather than every flip (-shed).
+	Shedding      bool                 // Flips are dropped until the client next polls, which gets the whole world instead. Protected by Mu.
+	Polled        int                  // Turn the client's view was brought up to by its latest poll. Protected by Mu.
+	Continue      bool                 // Flag for fault tolerance, indicates if the simulation should continue from a saved state.
+	Epoch         int                  // Fencing token of the client currently in control.
+	LeaseExpiry   time.Time            // Time after which the controlling client is presumed partitioned.
+	Lease         time.Duration        // How long a client may go without a heartbeat before losing control.
+	Paused        bool                 // Flag to indicate Mu is held by a hard pause.
+	SoftPaused    bool                 // Flag to indicate the evolution loop is held between turns by a soft pause. Protected by FenceMu.
+	resume        chan struct{}        // Closed when a soft pause is lifted. Protected by FenceMu.
+	Target        int                  // Turn after which the loop soft pauses by itself (see RunUntil), or 0. Protected by FenceMu.
+	Triggers      *triggers            // Conditions that soft pause the run by themselves (see AutoPause), or nil. Protected by FenceMu.
+	PauseReason   string               // Why a trigger soft paused the run, until it is resumed. Protected by FenceMu.
+	RuleChange    *ruleChange          // Rule or edge mode to switch to at the next turn boundary (see SetRule), or nil. Protected by FenceMu.
+	FenceMu       sync.Mutex           // Mutex protecting the fencing fields, separate from Mu so it works while paused.
+	Running       sync.Mutex           // Held for the duration of EvolveWorld so only one evolution loop runs at a time.
+	Seed          int64                // Seed of the current world's stochastic rule, kept when the run is continued.
+	Rule          string               // Rule the current world is evolving under, saved with its checkpoints.
+	Edge          string               // Edge mode the current world is evolving under, saved with its checkpoints.
+	Labels        map[string]string    // Key/value tags of the current run, saved with its checkpoints. Never modified, only replaced.
+	Hash          uint64               // Hash of the current world.
+	Seen          map[uint64]int       // Turn at which each state of this run was first seen.
+	Repeated      int                  // Number of turns that produced a state seen earlier in the run.
+	Period        int                  // Length of the cycle the simulation has entered, or 0 if none has been found.
+	CycleTurn     int                  // Turn at which the first repeated state was reached.
+	Assignments   []stubs.Assignment   // Region of the world each worker computed in the latest turn.
+	AssignmentVer int                  // Incremented whenever Assignments changes, so clients know to fetch them again.
+	Stats         stats                // Figures shown on the dashboard.
+	Logs          workerLogs           // Combined log of the lines workers send with Log.
+	snapshot      atomic.Value         // Latest *worldSnapshot, published at turn boundaries for the read RPCs.
+	runs          int                  // Number of evolutions started, so workers can tell the slices of each apart. Protected by Mu.
+	cancelRun     context.CancelFunc   // Cancels the latest evolution, or does nothing once it has finished. Protected by FenceMu.
+	Jobs          jobQueue             // Runs submitted to be carried out one after another.
+	JobDir        string               // Directory each job's checkpoints and final image are saved under.
+	JobCheckpoint time.Duration        // How often a running job is checkpointed, or 0 for only at the end.
+	Replicas      *replicator          // Where checkpoints are copied once saved, or nil for nowhere.
+	ReplicaDir    string               // Directory checkpoints replicated from other brokers are saved in, or "" to refuse them.
+	Retention     retention            // Which earlier checkpoints are kept besides the latest.
+	Checkpoints   checkpointIndex      // Checkpoints saved or loaded so far, for ListCheckpoints.
+	KeyframeEvery int                  // Every how many checkpoints at a path one is saved whole, the rest as deltas against it. 0 or 1 saves all whole.
+	Uploads       uploads              // World being uploaded in chunks for the next run.
+	Observers     observers            // What each remote observer was last sent, for Observe.
+	Audit         *audit.Log           // Hash chain of every turn, rule change and edit (-audit), or nil for none.
+}
+
+// audit reports an error writing the -audit log, which is only ever returned once, without stopping the run.
+func (b *Broker) audit(err error) {
+	if err != nil {
+		fmt.Printf("Warning: couldn't write the audit log, so the rest of the run goes unrecorded: %v\n", err)
+	}
+}
+
+// worldSnapshot is a generation of the world together with the turn it belongs to.
+// A world is never modified once it has been published: each turn assembles a new one, so readers
+// holding a snapshot always see a single, complete generation without taking the broker's mutex.
+type worldSnapshot struct {
+	World  slab.World
+	Turn   int
+	Seed   int64  // Seed of the run's stochastic rule.
+	Rule   string // Rule and edge mode of the run.
+	Edge   string
+	Labels map[string]string // Key/value tags of the run.
+}
+
+// publish makes the current world and turn visible to the read RPCs. It must be called with Mu held,
+// only once the world is complete, and the world must not be modified afterwards.
+func (b *Broker) publish() {
+	b.snapshot.Store(&worldSnapshot{World: b.World, Turn: b.Turn, Seed: b.Seed, Rule: b.Rule, Edge: b.Edge, Labels: b.Labels})
+}
+
+// current returns the latest published generation.
+func (b *Broker) current() *worldSnapshot {
+	if snapshot, ok := b.snapshot.Load().(*worldSnapshot); ok {
+		return snapshot
+	}
+	return &worldSnapshot{}
+}
+
+// maxTrackedStates bounds the memory used for cycle detection on long runs that never repeat.
+const maxTrackedStates = 1 << 22
+
+// ReadFileLines reads the worker addresses from a file, line by line.
+func ReadFileLines(filePath string) []string {
+
+	// Open the file containing worker addresses.
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close() // Ensure the file is closed after reading.
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+
+	// Read each line of the file.
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Split the line into individual elements based on spaces.
+		elements := strings.Fields(line)
+		lines = append(lines, elements...)
+	}
+
+	// Check for any scanning errors.
+	if err := scanner.Err(); err != nil {
+		return nil
+	}
+
+	return lines
+}
+
+// ScanForWorkers scans a range of ports on host to discover active workers, returning their clients and addresses.
+func ScanForWorkers(host string, startPort, endPort int) ([]*workerConn, []string) {
+	var workers []*workerConn
+	var addresses []string
+	for port := startPort; port <= endPort; port++ {
+		address := net.JoinHostPort(host, strconv.Itoa(port))
+		client, err := dialWorker(address)
+		if err == nil {
+			workers = append(workers, client)
+			addresses = append(addresses, address)
+			fmt.Printf("Connected to worker on %s\n", address)
+		} else {
+			fmt.Printf("Failed to connect to worker on %s: %v\n", address, err)
+		}
+	}
+	return workers, addresses
+}
+
+// sliceResult is a worker's slice of the next world along with the cells in it that changed state.
+type sliceResult struct {
+	World     slab.World    // Rows of the next world computed by the worker.
+	Flipped   []util.Cell   // Cells in the slice that differ from the previous world.
+	Count     int           // Number of flipped cells in the slice.
+	RowHashes []uint64      // Hash of each row in the slice.
+	Alive     int           // Number of alive cells in the slice.
+	Latency   time.Duration // Round trip time of the worker's RPC.
+	Job       int           // Run the slice was computed for.
+	Turn      int           // Turn of the world the slice was computed from.
+	StartRow  int           // First row of the slice.
+}
+
+// belongsTo reports whether a slice is the one assigned for a turn of a run, so it may be committed.
+func (s sliceResult) belongsTo(job, turn int, assignment stubs.Assignment) bool {
+	return s.Job == job && s.Turn == turn && s.StartRow == assignment.StartRow && s.World.Height == assignment.EndRow-assignment.StartRow
+}
+
+// assemble builds the next world from the slices of a turn, in the order they were assigned. The board and the
+// flipped cells and row hashes are allocated once at their full size, and every slice is copied into its own part of
+// them at the same time, so a big world isn't copied one slice after another.
+func assemble(width, height int, slices []sliceResult) (slab.World, []util.Cell, []uint64) {
+	world := slab.New(width, height)
+	flips, hashes := make([]int, len(slices)+1), make([]int, len(slices)+1) // Where each slice's flips and hashes go.
+	for i, slice := range slices {
+		flips[i+1] = flips[i] + len(slice.Flipped)
+		hashes[i+1] = hashes[i] + len(slice.RowHashes)
+	}
+	flipped := make([]util.Cell, flips[len(slices)])
+	rowHashes := make([]uint64, hashes[len(slices)])
+
+	var wg sync.WaitGroup
+	for i, slice := range slices {
+		wg.Add(1)
+		go func(i int, slice sliceResult) {
+			defer wg.Done()
+			world.CopyRows(slice.StartRow, slice.World)
+			copy(flipped[flips[i]:], slice.Flipped)
+			copy(rowHashes[hashes[i]:], slice.RowHashes)
+		}(i, slice)
+	}
+	wg.Wait()
+	return world, flipped, rowHashes
+}
+
+// maxQueuedFlips is how many flipped cell events may be queued for the client before they are coalesced.
+// Coalescing leaves at most one event per cell, so allowing twice that keeps the cost of coalescing small.
+func maxQueuedFlips(p gol.Params) int {
+	if limit := 2 * p.ImageWidth * p.ImageHeight; limit > 1<<20 {
+		return limit
+	}
+	return 1 << 20
+}
+
+// coalesceFlips replaces a queue of flipped cell events with one event, tagged with the given turn, for every
+// cell that flipped an odd number of times. Replaying them leaves the client's world the same as replaying
+// the whole queue would, but the turns in between are skipped.
+func coalesceFlips(events []stubs.FlippedEvent, turn int) []stubs.FlippedEvent {
+	odd := make(map[util.Cell]bool)
+	for _, event := range events {
+		odd[event.Cell] = !odd[event.Cell]
+	}
+	coalesced := make([]stubs.FlippedEvent, 0, len(odd))
+	for _, event := range events {
+		if odd[event.Cell] {
+			coalesced = append(coalesced, stubs.FlippedEvent{CompletedTurns: turn, Cell: event.Cell})
+			odd[event.Cell] = false // Each cell only once.
+		}
+	}
+	return coalesced
+}
+
+// assignRows splits the rows of the world evenly between the given number of workers.
+func assignRows(p gol.Params, threads int) []stubs.Assignment {
+	// Calculate the number of rows each worker should process.
+	var heightDiff = float32(p.ImageHeight) / float32(threads)
+
+	assignments := make([]stubs.Assignment, threads)
+	for id := range assignments {
+		// Determine the start and end rows for this worker.
+		startRow := int(float32(id) * heightDiff)
+		endRow := int(float32(id+1) * heightDiff)
+
+		// Ensure that EndRow does not exceed the total number of rows.
+		if endRow > p.ImageHeight {
+			endRow = p.ImageHeight
+		}
+		assignments[id] = stubs.Assignment{Worker: id, StartRow: startRow, EndRow: endRow, StartCol: 0, EndCol: p.ImageWidth}
+	}
+	return assignments
+}
+
+// sameAssignments reports whether two sets of assignments hand out the world identically.
+func sameAssignments(a, b []stubs.Assignment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// failedWorkers holds the connections to workers whose calls have failed, so each is only reported once.
+var failedWorkers sync.Map
+
+// worker function sends a portion of the world to a worker client for processing. If ctx is done first the
+// worker is told to stop and nothing is sent on results.
+func worker(ctx context.Context, job, turn int, world slab.World, results chan<- sliceResult, p gol.Params, opts kernel.Options, conn *workerConn, assignment stubs.Assignment) {
+	startRow, endRow := assignment.StartRow, assignment.EndRow
+	ctx, span := tracing.StartClient(ctx, "CalculateWorld")
+	defer span.End()
+	span.SetInt("worker", assignment.Worker)
+	span.SetInt("rows", endRow-startRow)
+
+	// Create a request object with the portion of the world this worker will process.
+	worldReq := stubs.WorldReq{
+		World:    world,
+		StartRow: startRow,
+		EndRow:   endRow,
+		Width:    p.ImageWidth,
+		Height:   p.ImageHeight,
+		Rule:     opts.Rule.String(),
+		Edge:     opts.Edge.String(),
+		Job:      job,
+		Turn:     turn,
+		Seed:     opts.Seed,
+		Trace:    tracing.Header(ctx),
+	}
+
+	// Prepare a response object to receive the processed world.
+	worldRes := &stubs.WorldRes{}
+
+	// Call the worker's WorldHandler function to evolve the world, timing it for the dashboard.
+	// If the connection has broken the slice is sent again over a new one, as the worker may only have
+	// dropped off the network for a moment.
+	start := time.Now()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var client *rpc.Client
+		if client, err = conn.get(); err != nil {
+			break
+		}
+		call := client.Go(stubs.WorldHandler, worldReq, worldRes, make(chan *rpc.Call, 1))
+		select {
+		case <-call.Done:
+		case <-ctx.Done():
+			span.Fail(ctx.Err())
+			// Nobody is waiting for the slice any more, so don't leave the worker computing it. The reply doesn't matter.
+			client.Go(stubs.CancelHandler, stubs.CancelReq{Job: job}, &stubs.Empty{}, make(chan *rpc.Call, 1))
+			return
+		}
+		if err = call.Error; !isBroken(err) {
+			break
+		}
+		conn.broken(client)
+	}
+	if err != nil {
+		span.Fail(err)
+		if ctx.Err() != nil {
+			return // The worker was cancelled along with the turn.
+		}
+		// The collector waits for every slice while holding the mutex, so a slice must always arrive:
+		// compute it on the broker rather than leave the run hanging on a dead worker.
+		// A dead worker fails every turn, so it is only reported the first time.
+		if _, reported := failedWorkers.LoadOrStore(conn, true); !reported {
+			fmt.Printf("Warning: a worker failed on rows %d-%d, computing its slices on the broker: %v\n", startRow, endRow, err)
+		}
+		computeLocally(ctx, job, turn, world, results, p, opts, assignment)
+		return
+	}
+
+	// A reply for another turn or other rows, e.g. a straggler's from before, would corrupt the board if it
+	// were committed, so it is discarded and the slice computed on the broker instead, like a failed call.
+	if worldRes.Job != job || worldRes.Turn != turn || worldRes.StartRow != startRow || worldRes.EndRow != endRow ||
+		!worldRes.World.Valid() || worldRes.World.Width != p.ImageWidth || worldRes.World.Height != endRow-startRow {
+		span.Fail(fmt.Errorf("stale slice of turn %d rows %d-%d", worldRes.Turn, worldRes.StartRow, worldRes.EndRow))
+		if _, reported := failedWorkers.LoadOrStore(conn, true); !reported {
+			fmt.Printf("Warning: discarded a worker's slice of turn %d rows %d-%d sent for turn %d rows %d-%d, computing its slices on the broker\n",
+				worldRes.Turn, worldRes.StartRow, worldRes.EndRow, turn, startRow, endRow)
+		}
+		computeLocally(ctx, job, turn, world, results, p, opts, assignment)
+		return
+	}
+	// The slice's cells were corrupted on the way, e.g. by a flaky link, which would carry on into every later
+	// generation unnoticed. Corruption is rare and passing, so it is reported every time rather than once.
+	if !worldRes.Intact() {
+		span.Fail(fmt.Errorf("corrupt slice of turn %d rows %d-%d", turn, startRow, endRow))
+		fmt.Printf("Warning: discarded a corrupt slice of turn %d rows %d-%d from %s, computing it on the broker\n",
+			turn, startRow, endRow, conn.Address)
+		computeLocally(ctx, job, turn, world, results, p, opts, assignment)
+		return
+	}
+	failedWorkers.Delete(conn)
+
+	// Send the resulting world slice back through the results channel.
+	slice := newSliceResult(world, worldRes.World, startRow, time.Since(start))
+	slice.Job, slice.Turn = job, turn
+	results <- slice
+}
+
+// localChunkSize is the number of rows each goroutine computes when the broker computes turns itself.
+const localChunkSize = 16
+
+// computeLocally computes a slice of the world on the broker itself, with the same kernel the workers use,
+// so a run can still go ahead when no workers can be reached. If ctx is done first nothing is sent on results.
+func computeLocally(ctx context.Context, job, turn int, world slab.World, results chan<- sliceResult, p gol.Params, opts kernel.Options, assignment stubs.Assignment) {
+	ctx, span := tracing.Start(ctx, "computeLocally")
+	defer span.End()
+	span.SetInt("rows", assignment.EndRow-assignment.StartRow)
+	start := time.Now()
+	rows, err := kernel.Next(ctx, world, assignment.StartRow, assignment.EndRow, localChunkSize, opts)
+	if err != nil {
+		return
+	}
+	slice := newSliceResult(world, rows, assignment.StartRow, time.Since(start))
+	slice.Job, slice.Turn = job, turn
+	results <- slice
+}
+
+// newSliceResult describes a computed slice, diffing it against the rows it replaces. This happens in parallel
+// with the other slices, so the broker never has to scan the whole world for changes while holding the mutex.
+func newSliceResult(world, slice slab.World, startRow int, latency time.Duration) sliceResult {
+	flipped := flippedInSlice(world, slice, startRow)
+	return sliceResult{
+		StartRow:  startRow,
+		World:     slice,
+		Flipped:   flipped,
+		Count:     len(flipped),
+		RowHashes: hashRows(slice),
+		Alive:     countAlive(slice),
+		Latency:   latency,
+	}
+}
+
+// countAlive returns the number of alive cells in a slice of the world.
+func countAlive(world slab.World) int {
+	count := 0
+	for y := 0; y < world.Height; y++ {
+		for _, cell := range world.Row(y) {
+			if cell == util.Alive {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// hashRows returns the FNV-1a hash of each row.
+// Hashing by row keeps the world hash independent of how many workers the world was split between.
+func hashRows(world slab.World) []uint64 {
+	hashes := make([]uint64, world.Height)
+	for y := range hashes {
+		h := fnv.New64a()
+		h.Write(world.Row(y))
+		hashes[y] = h.Sum64()
+	}
+	return hashes
+}
+
+// combineHashes folds the row hashes of a world, in order, into a single hash.
+func combineHashes(rowHashes []uint64) uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, rowHash := range rowHashes {
+		for i := range buf {
+			buf[i] = byte(rowHash >> (8 * uint(i)))
+		}
+		h.Write(buf)
+	}
+	return h.Sum64()
+}
+
+// recordState notes that the current turn produced a world with the given hash, detecting when the run enters a cycle.
+// The caller must hold Mu.
+func (b *Broker) recordState(hash uint64) {
+	b.Hash = hash
+	if first, ok := b.Seen[hash]; ok {
+		b.Repeated++
+		if b.Period == 0 {
+			// The first repeat closes the cycle: every later state is a repeat too.
+			b.Period = b.Turn - first
+			b.CycleTurn = b.Turn
+		}
+		return
+	}
+	if len(b.Seen) < maxTrackedStates {
+		b.Seen[hash] = b.Turn
+	}
+}
+
+// resetStates forgets the states of the previous run and starts again from the current world.
+// The caller must hold Mu, as WorldHash reads the states under it.
+func (b *Broker) resetStates() {
+	b.Seen = make(map[uint64]int)
+	b.Repeated = 0
+	b.Period = 0
+	b.CycleTurn = 0
+	b.recordState(combineHashes(hashRows(b.World)))
+}
+
+// flippedInSlice compares a computed slice with the rows of the previous world it replaces, starting at startRow.
+func flippedInSlice(world, slice slab.World, startRow int) []util.Cell {
+	var flipped []util.Cell
+	for i := 0; i < slice.Height; i++ {
+		row, previous := slice.Row(i), world.Row(startRow+i)
+		for j := range row {
+			if row[j] != previous[j] {
+				flipped = append(flipped, util.Cell{X: j, Y: startRow + i})
+			}
+		}
+	}
+	return flipped
+}
+
+func worldSize(world slab.World) {
+	nonEmptyCount := 0
+	for y := 0; y < world.Height; y++ {
+		for _, cell := range world.Row(y) {
+			if cell != util.Dead {
+				nonEmptyCount++
+			}
+		}
+	}
+	fmt.Printf("Number of non-empty cells: %d\n", nonEmptyCount)
+}
+
+// Acquire hands control of the broker to a new client and returns its fencing epoch.
+// While another client holds a live lease the request is refused, so two clients never drive the same world.
+// Once the lease lapses the old client is presumed partitioned: a new epoch is issued, anything the old
+// client left behind (a running evolution or a hard pause) is cleared, and its later RPCs are rejected.
+func (b *Broker) Acquire(req stubs.Empty, res *stubs.AcquireResponse) (err error) {
+	b.FenceMu.Lock()
+	if time.Now().Before(b.LeaseExpiry) {
+		b.FenceMu.Unlock()
+		return errLeaseHeld
+	}
+	takeover := !b.LeaseExpiry.IsZero() // The previous client vanished without quitting.
+	b.Epoch++
+	b.LeaseExpiry = time.Now().Add(b.Lease)
+	res.Epoch = b.Epoch
+	// Lift the stale client's pause so the evolution loop can observe the new epoch.
+	b.unpause()
+	b.Triggers = nil   // They were set for the stale client's run.
+	b.RuleChange = nil // As was any change of rule still waiting for a turn boundary.
+	b.cancel()
+	b.FenceMu.Unlock()
+
+	// Wait for a stale evolution loop to notice the new epoch and stop.
+	b.Running.Lock()
+	b.Running.Unlock()
+
+	if takeover {
+		// Fault tolerance: the new client continues from where the partitioned client left off.
+		b.Mu.Lock()
+		b.Continue = true
+		b.Mu.Unlock()
+	}
+	return
+}
+
+// Heartbeat renews the lease of the controlling client.
+func (b *Broker) Heartbeat(req stubs.ControlRequest, res *stubs.Empty) (err error) {
+	return b.checkEpoch(req.Epoch)
+}
+
+// checkEpoch rejects requests from superseded clients, or from a client whose lease has been released, and renews
+// the lease of the current one.
+func (b *Broker) checkEpoch(epoch int) error {
+	b.FenceMu.Lock()
+	defer b.FenceMu.Unlock()
+	if epoch != b.Epoch {
+		return errStaleEpoch
+	}
+	// A released lease stays released, so a late heartbeat from a finished client can't lock out the next one.
+	if b.LeaseExpiry.IsZero() {
+		return stubs.ErrLeaseReleased
+	}
+	b.LeaseExpiry = time.Now().Add(b.Lease)
+	return nil
+}
+
+// currentEpoch returns the epoch of the client currently in control.
+func (b *Broker) currentEpoch() int {
+	b.FenceMu.Lock()
+	defer b.FenceMu.Unlock()
+	return b.Epoch
+}
+
+// cancel abandons the turn in progress, if any, so the evolution loop stops without waiting for its slices.
+// The caller must hold FenceMu.
+func (b *Broker) cancel() {
+	if b.cancelRun != nil {
+		b.cancelRun()
+	}
+}
+
+// release gives up the lease so another client can take control straight away.
+func (b *Broker) release() {
+	b.FenceMu.Lock()
+	defer b.FenceMu.Unlock()
+	b.LeaseExpiry = time.Time{}
+}
+
+// EvolveWorld handles the evolution of the world by distributing work to connected workers.
+func (b *Broker) EvolveWorld(req stubs.EvolveWorldRequest, res *stubs.EvolveResponse) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	opts, err := kernel.ParseOptions(req.Rule, req.Edge)
+	if err != nil {
+		b.release() // The client can't run with these options, so another may take over.
+		return
+	}
+	opts.Algorithm = b.Algorithm // Workers count neighbours with their own -kernel.
+	if req.Upload != 0 {
+		// A world too big for one message was sent beforehand with BeginUpload, AppendUpload and CommitUpload.
+		if req.World, err = b.Uploads.take(req.Upload); err != nil {
+			return
+		}
+	}
+	b.Running.Lock()
+	defer b.Running.Unlock()
+	b.Stats.setRunning(true)
+	defer b.Stats.setRunning(false)
+
+	// Quitting or a takeover cancels ctx, which stops the turn in progress on the broker and the workers alike.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.FenceMu.Lock()
+	b.cancelRun = cancel
+	b.FenceMu.Unlock()
+
+	// Fault tolerance: If not continuing from a saved state, initialise the world from the request.
+	b.Mu.Lock()
+	b.Quit = false // Reset the quit flag at the start of a new simulation run.
+	b.runs++
+	job := b.runs
+	if !b.Continue {
+		b.World = req.World.Clone()
+		b.Turn = 0
+		b.Seed = req.Seed
+	} else if b.Rule != "" && (b.Rule != opts.Rule.String() || b.Edge != opts.Edge.String()) {
+		fmt.Printf("Warning: continuing turn %d, saved under %s with %s edges, under %s with %s edges\n",
+			b.Turn, b.Rule, b.Edge, opts.Rule, opts.Edge)
+	}
+	b.Rule, b.Edge = opts.Rule.String(), opts.Edge.String()
+	if !b.Continue || len(req.Labels) > 0 {
+		b.Labels = req.Labels
+	}
+	opts.Seed = b.Seed // Continuing with the seed the run started with makes the same choices as never stopping.
+	b.publish()
+	b.audit(b.Audit.Begin(b.World, b.Turn, b.Rule, b.Edge, b.Seed))
+	// The client renders the starting world itself, so any flips left over from a previous run are stale.
+	b.FlippedEvents = nil
+	b.Shedding, b.Polled = false, b.Turn
+	// A continuing run keeps the states it has already visited, so a cycle spanning the quit is still found.
+	if !b.Continue || b.Seen == nil {
+		b.resetStates()
+	}
+	b.restartTriggers(opts.Edge)
+	b.Mu.Unlock()
+
+	// Without any workers the broker computes every turn itself, which is slower but keeps a demo running.
+	workers := b.fleet()
+	if len(workers) == 0 && !b.Local {
+		fmt.Println("Warning: no workers are reachable, so the broker is computing every turn itself")
+	}
+
+	// Extract parameters from the request.
+	p := gol.Params{
+		Turns:       req.Turn,
+		Threads:     req.Threads,
+		ImageWidth:  req.ImageWidth,
+		ImageHeight: req.ImageHeight,
+	}
+
+	// Execute the Game of Life simulation for the specified number of turns.
+	for {
+		b.Mu.Lock() // Lock the mutex to prevent concurrent access to global variables.
+		// Checked under Mu, which QuitServer holds to set Quit.
+		if b.Turn >= p.Turns || b.Quit {
+			b.Mu.Unlock()
+			break
+		}
+
+		// A soft pause holds the loop here, between turns, with the last turn published and Mu free for readers.
+		b.pauseAtTarget()
+		if !b.waitWhilePaused(ctx) {
+			b.Mu.Unlock()
+			break
+		}
+
+		// Split-brain prevention: stop as soon as another client has taken control.
+		if b.currentEpoch() != req.Epoch {
+			b.Mu.Unlock()
+			return errStaleEpoch
+		}
+		b.applyRuleChange(&opts) // Only ever between turns, so no turn is computed under a mix of rules.
+
+		// Each turn is a trace of its own, with a span for every slice computed, so a slow one stands out.
+		turnCtx, span := tracing.Start(ctx, "turn")
+		span.SetInt("turn", b.Turn+1)
+		span.SetInt("workers", len(workers))
+
+		threads := len(workers) // Number of available workers.
+		if threads == 0 {
+			threads = 1 // The broker computes the whole world as one slice.
+		}
+		// Channels to receive results from workers, buffered so a slice that arrives after the turn has been
+		// cancelled doesn't leave its goroutine blocked forever.
+		results := make([]chan sliceResult, threads)
+
+		opts.Turn = b.Turn // A stochastic rule's chances differ each turn.
+
+		// Work out which rows each worker computes, and publish the split if it has changed.
+		assignments := assignRows(p, threads)
+		if !sameAssignments(assignments, b.Assignments) {
+			b.Assignments = assignments
+			b.AssignmentVer++
+		}
+
+		// Distribute work to each worker.
+		for id, workerClient := range workers {
+			results[id] = make(chan sliceResult, 1)
+			go worker(turnCtx, job, b.Turn, b.World, results[id], p, opts, workerClient, assignments[id]) // Concurrent call to each worker.
+		}
+		if len(workers) == 0 {
+			results[0] = make(chan sliceResult, 1)
+			go computeLocally(turnCtx, job, b.Turn, b.World, results[0], p, opts, assignments[0])
+		}
+
+		// The turn is committed in two phases: first every slice is collected and checked to belong to this
+		// turn, and only once all of them have arrived is the new world assembled from them.
+		slices := make([]sliceResult, threads)
+		cancelled := false
+		for i := 0; i < threads && !cancelled; i++ {
+			select {
+			case slices[i] = <-results[i]:
+			case <-ctx.Done():
+				cancelled = true
+				continue
+			}
+			if !slices[i].belongsTo(job, b.Turn, assignments[i]) {
+				// Never commit a slice of another turn: compute this one again rather than corrupt the board.
+				fmt.Printf("Warning: discarded a stale slice of turn %d for rows %d-%d of turn %d\n", slices[i].Turn, assignments[i].StartRow, assignments[i].EndRow, b.Turn)
+				retry := make(chan sliceResult, 1)
+				computeLocally(turnCtx, job, b.Turn, b.World, retry, p, opts, assignments[i])
+				select {
+				case slices[i] = <-retry:
+				default:
+					cancelled = true // Nothing is sent once ctx is done.
+				}
+			}
+		}
+
+		if cancelled {
+			// The turn is dropped, so the world stays at the last complete one for the next client.
+			span.Fail(ctx.Err())
+			span.End()
+			b.Mu.Unlock()
+			break
+		}
+
+		// Assemble the new world state from the slices along with its flipped cells.
+		newWorld, flipped, rowHashes := assemble(p.ImageWidth, p.ImageHeight, slices)
+		alive := 0
+		latencies := make([]time.Duration, threads)
+		rows := make([]int, threads)
+		flips := make([]int, threads)
+		for i, slice := range slices {
+			alive += slice.Alive
+			latencies[i] = slice.Latency
+			rows[i] = slice.World.Height
+			flips[i] = slice.Count
+		}
+
+		b.World = newWorld // Update the global world state.
+		b.Turn++           // Increment the turn counter.
+		b.publish()        // Only now is the new generation complete, so only now may readers see it.
+		b.audit(b.Audit.Turn(b.World))
+		b.recordState(combineHashes(rowHashes))
+		b.Stats.recordTurn(b.Turn, alive, latencies, rows, flips)
+		b.pauseOnTrigger(alive, flipped)
+
+		b.queueFlips(p, flipped)
+		b.TurnDone = true // Indicate that a turn has been completed.
+		span.SetInt("alive", alive)
+		span.End()
+		b.Mu.Unlock() // Unlock the mutex.
+	}
+
+	// A takeover cancels the run as well as changing the epoch, and the lease now belongs to the new client.
+	if b.currentEpoch() != req.Epoch {
+		return errStaleEpoch
+	}
+
+	// The run is over, so the next client may take control straight away.
+	b.release()
+
+	// Prepare the response with the final world state and turn number.
+	res.World = b.World
+	res.Turn = b.Turn
+	return
+}
+
+// fleet returns the workers turns are split between, which is none if the broker computes every turn itself.
+func (b *Broker) fleet() []*workerConn {
+	if b.Local {
+		return nil
+	}
+	return b.workers()
+}
+
+// gobSize estimates the bytes gob takes to encode rows of a slab world. Each nonzero field is its field number
+// followed by its value, an integer being zigzagged, and the cells are a length followed by the cells themselves.
+func gobSize(rows, width int) int64 {
+	size := int64(2 * (1 + uvarintSize(2*width))) // Width and Stride.
+	if rows > 0 {
+		cells := rows * width
+		size += int64(1+uvarintSize(2*rows)) + int64(1+uvarintSize(cells)+cells)
+	}
+	return size + 1 // The struct ends with a zero byte.
+}
+
+// uvarintSize returns the bytes gob takes to encode n as an unsigned integer.
+func uvarintSize(n int) int {
+	if n < 128 {
+		return 1
+	}
+	size := 1
+	for ; n > 0; n >>= 8 {
+		size++
+	}
+	return size
+}
+
+// Plan works out how the broker would carry out a run with its current workers, without starting it or needing
+// the lease, so a configuration can be checked before an expensive run: which rows each worker would compute,
+// how much would be sent over the network and how much memory the broker and each worker would need.
+func (b *Broker) Plan(req stubs.PlanRequest, res *stubs.PlanResponse) (err error) {
+	if req.ImageWidth <= 0 || req.ImageHeight <= 0 {
+		return fmt.Errorf("cannot plan a %dx%d world", req.ImageWidth, req.ImageHeight)
+	}
+	if _, err = kernel.ParseOptions(req.Rule, req.Edge); err != nil {
+		return
+	}
+	p := gol.Params{Turns: req.Turns, ImageWidth: req.ImageWidth, ImageHeight: req.ImageHeight}
+
+	// Split the world exactly as EvolveWorld would.
+	res.Workers = len(b.fleet())
+	res.Kernel = b.Algorithm.String()
+	threads := res.Workers
+	if threads == 0 {
+		threads = 1
+	}
+	res.Assignments = assignRows(p, threads)
+
+	cells := int64(p.ImageWidth) * int64(p.ImageHeight)
+	world := gobSize(p.ImageHeight, p.ImageWidth)
+	for _, assignment := range res.Assignments {
+		slice := gobSize(assignment.EndRow-assignment.StartRow, p.ImageWidth)
+		if res.Workers > 0 {
+			res.SentPerTurn += world
+			res.ReceivedPerTurn += slice
+			// A worker decodes the world, computes its slice and encodes it to send back.
+			res.WorkerMemory = append(res.WorkerMemory, cells+2*slice)
+		}
+	}
+	res.Transfer = int64(p.Turns) * (res.SentPerTurn + res.ReceivedPerTurn)
+
+	// The broker keeps the current world, the slices coming back and the new world assembled from them, encodes
+	// the world for each worker, and queues flipped cells for the client until they are coalesced.
+	const flip = 3 * 8 // A flipped cell event is three 64-bit ints.
+	res.BrokerMemory = 3*cells + res.SentPerTurn + int64(maxQueuedFlips(p))*flip
+	return
+}
+
+// CalculateAliveCells calculates the positions of all alive cells in the latest published world.
+func (b *Broker) CalculateAliveCells(req stubs.Empty, res *stubs.CalculateAliveCellsResponse) (err error) {
+	world := b.current().World
+
+	aliveCells := []util.Cell{}
+	for i := 0; i < world.Height; i++ { // Iterate over each row.
+		for j, cell := range world.Row(i) { // Iterate over each cell in the row.
+			if cell == util.Alive { // Check if the cell is alive.
+				aliveCells = append(aliveCells, util.Cell{X: j, Y: i})
+			}
+		}
+	}
+	// Return the list of alive cells.
+	res.AliveCells = aliveCells
+	return
+}
+
+// AliveCellsCount returns the number of alive cells and the turn number of the latest published world.
+func (b *Broker) AliveCellsCount(req stubs.Empty, res *stubs.AliveCellsCountResponse) (err error) {
+	_, span := tracing.Start(context.Background(), "AliveCellsCount")
+	defer span.End()
+	snapshot := b.current()
+
+	// Populate the response with the alive cells count and completed turns.
+	res.AliveCellsCount = countAlive(snapshot.World)
+	res.CompletedTurns = snapshot.Turn
+	return
+}
+
+// GetGlobal returns the latest published world state and its turn number.
+func (b *Broker) GetGlobal(req stubs.Empty, res *stubs.GetGlobalResponse) (err error) {
+	_, span := tracing.Start(context.Background(), "GetGlobal")
+	defer span.End()
+	snapshot := b.current()
+	res.World = snapshot.World
+	res.Turn = snapshot.Turn
+	return
+}
+
+// QuitServer sets the flags to indicate that the simulation should quit, keeping the current world for the next client.
+func (b *Broker) QuitServer(req stubs.ControlRequest, res *stubs.Empty) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	// Stop the turn in progress rather than wait for every worker to send back its slice.
+	b.FenceMu.Lock()
+	b.cancel()
+	b.unpause() // Lift any pause, so the loop sees it has been cancelled and Mu can be taken below.
+	b.FenceMu.Unlock()
+	b.Mu.Lock()
+	defer b.Mu.Unlock()
+	b.Continue = true // Enable fault tolerance to continue from this state.
+	b.Quit = true     // Set the quit flag to stop the simulation.
+	b.release()       // The client is leaving, so the next one may take over immediately.
+	return
+}
+
+// Pause stops the simulation once the turn in progress has finished and been published, returning when it has.
+// The read RPCs carry on working, so the client can still fetch the flipped cells and save the paused world.
+func (b *Broker) Pause(req stubs.ControlRequest, res *stubs.Empty) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	b.FenceMu.Lock()
+	if b.Paused {
+		// Already paused harder than this, and Mu can't be taken below.
+		b.FenceMu.Unlock()
+		return
+	}
+	b.softPause()
+	b.FenceMu.Unlock()
+
+	// The evolution loop holds Mu for a whole turn and checks for a pause before starting the next, so once Mu
+	// is free the turn in progress is finished and no other will start.
+	b.Mu.Lock()
+	b.Mu.Unlock()
+	return
+}
+
+// softPause holds the evolution loop before its next turn, if it isn't held already. It must be called with
+// FenceMu held.
+func (b *Broker) softPause() {
+	if !b.SoftPaused {
+		b.SoftPaused = true
+		b.resume = make(chan struct{})
+		b.Stats.setPaused(true)
+	}
+}
+
+// RunUntil resumes the simulation, if it is paused, and soft pauses it again once the given turn has been
+// completed, returning straight away. A turn the run has already completed is refused.
+func (b *Broker) RunUntil(req stubs.RunUntilRequest, res *stubs.Empty) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	// The published turn is read without Mu, which a hard pause holds.
+	if turn := b.current().Turn; req.Turn <= turn {
+		return fmt.Errorf("turn %d has already been completed, the run is at turn %d", req.Turn, turn)
+	}
+	b.FenceMu.Lock()
+	defer b.FenceMu.Unlock()
+	b.unpause()
+	b.Target = req.Turn
+	return
+}
+
+// pauseAtTarget soft pauses the simulation once it has completed the turn given to RunUntil. It must be called
+// with Mu held.
+func (b *Broker) pauseAtTarget() {
+	b.FenceMu.Lock()
+	defer b.FenceMu.Unlock()
+	if b.Target > 0 && b.Turn >= b.Target {
+		b.Target = 0
+		b.softPause()
+	}
+}
+
+// waitWhilePaused holds the evolution loop while the simulation is soft paused, freeing Mu in the meantime. It
+// must be called with Mu held, and returns with it held, reporting false if ctx was done first.
+func (b *Broker) waitWhilePaused(ctx context.Context) bool {
+	for {
+		b.FenceMu.Lock()
+		paused, resume := b.SoftPaused, b.resume
+		b.FenceMu.Unlock()
+		if !paused {
+			return true
+		}
+		b.Mu.Unlock()
+		select {
+		case <-resume:
+		case <-ctx.Done():
+		}
+		b.Mu.Lock()
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+}
+
+// HardPause locks the mutex to pause the simulation by preventing access to global variables. Unlike Pause,
+// the read RPCs that need the mutex wait until the simulation is resumed.
+func (b *Broker) HardPause(req stubs.ControlRequest, res *stubs.Empty) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	b.Mu.Lock()
+	b.FenceMu.Lock()
+	defer b.FenceMu.Unlock()
+	if req.Epoch != b.Epoch {
+		// Another client took over while this one was waiting for the lock.
+		b.Mu.Unlock()
+		return errStaleEpoch
+	}
+	b.Paused = true
+	b.Stats.setPaused(true)
+	return
+}
+
+// Unpause unlocks the mutex to resume the simulation.
+func (b *Broker) Unpause(req stubs.ControlRequest, res *stubs.Empty) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	b.FenceMu.Lock()
+	defer b.FenceMu.Unlock()
+	b.unpause()
+	return
+}
+
+// unpause lifts a soft or hard pause, if either is in place, along with any pause still to come from RunUntil,
+// and forgets which trigger paused the run.
+// It must be called with FenceMu held.
+func (b *Broker) unpause() {
+	b.Target = 0
+	b.PauseReason = ""
+	if b.Paused {
+		b.Paused = false
+		b.Mu.Unlock()
+	}
+	if b.SoftPaused {
+		b.SoftPaused = false
+		close(b.resume)
+	}
+	b.Stats.setPaused(false)
+}
+
+// KillServer terminates the simulation and signals connected workers to shut down.
+func (b *Broker) KillServer(req stubs.ControlRequest, res *stubs.Empty) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+
+	// Prepare an empty request and response for the RPC calls.
+	emptyReq := stubs.Empty{}
+	emptyRes := stubs.Empty{}
+
+	// Notify each worker to shut down and close the client connections.
+	for _, client := range b.workers() {
+		err = client.Call(stubs.KillHandler, emptyReq, &emptyRes)
+		client.Close()
+	}
+
+	b.Quit = true // Set the quit flag.
+	kill <- true  // Signal the kill channel to exit the program.
+	return
+}
+
+// SaveShards saves the current world to req.Dir as one PGM image per worker slice, each written by the worker
+// that computed it, along with a manifest listing them, so a snapshot of a huge world is written from every worker
+// at once rather than gathered and written in one place. Slices a worker can't save, e.g. because the broker
+// computed them itself, are saved by the broker.
+func (b *Broker) SaveShards(req stubs.SaveShardsRequest, res *stubs.SaveShardsResponse) (err error) {
+	// Holding Mu stops another turn replacing the workers' latest slices while they save them.
+	b.Mu.Lock()
+	defer b.Mu.Unlock()
+	world, turn, job := b.World, b.Turn, b.runs
+	if world.Height == 0 {
+		return errors.New("there is no world to save")
+	}
+	width, height := world.Width, world.Height
+
+	// The world is split as it was for the latest turn, so each worker has the slice it is asked for.
+	workers := b.fleet()
+	threads := len(workers)
+	if threads == 0 {
+		threads = 1
+	}
+	assignments := assignRows(gol.Params{ImageWidth: width, ImageHeight: height}, threads)
+
+	name := fmt.Sprintf("%dx%dx%d", width, height, turn)
+	res.Turn = turn
+	res.Shards = make([]stubs.Shard, len(assignments))
+	errs := make([]error, len(assignments))
+	var wg sync.WaitGroup
+	for i, a := range assignments {
+		res.Shards[i] = stubs.Shard{
+			StartRow: a.StartRow,
+			EndRow:   a.EndRow,
+			File:     fmt.Sprintf("%s.rows-%d-%d.pgm", name, a.StartRow, a.EndRow),
+			Worker:   -1,
+		}
+		wg.Add(1)
+		go func(i int, shard *stubs.Shard) {
+			defer wg.Done()
+			path := storage.Join(req.Dir, shard.File)
+			if turn > 0 && i < len(workers) {
+				// The slice of the latest turn is the one the worker computed from the turn before.
+				saveReq := stubs.SaveSliceReq{Job: job, Turn: turn - 1, StartRow: shard.StartRow, EndRow: shard.EndRow, Path: path}
+				err := workers[i].Call(stubs.SaveSliceHandler, saveReq, &stubs.Empty{})
+				if err == nil {
+					shard.Worker = i
+					return
+				}
+				fmt.Printf("Warning: worker %d couldn't save rows %d-%d, saving them on the broker: %v\n", i, shard.StartRow, shard.EndRow, err)
+			}
+			errs[i] = storage.Put(path, util.FormatPgm(world.Slice(shard.StartRow, shard.EndRow).Rows()))
+		}(i, &res.Shards[i])
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	res.Manifest = storage.Join(req.Dir, name+".shards")
+	return storage.Put(res.Manifest, shardManifest(width, height, turn, res.Shards))
+}
+
+// shardManifest lists the images a world was saved as, one "rows <start> <end> <file>" line per image, with
+// the rows a half-open range and the file relative to the manifest.
+func shardManifest(width, height, turn int, shards []stubs.Shard) []byte {
+	manifest := &bytes.Buffer{}
+	fmt.Fprintf(manifest, "# %dx%d world at turn %d, saved as one PGM image per range of rows\n", width, height, turn)
+	fmt.Fprintf(manifest, "width %d\nheight %d\nturn %d\n", width, height, turn)
+	for _, shard := range shards {
+		fmt.Fprintf(manifest, "rows %d %d %s\n", shard.StartRow, shard.EndRow, shard.File)
+	}
+	return manifest.Bytes()
+}
+
+// GetTurnDone returns TurnDone (SDL live view), and the current turn, sets TurnDone back to false
+func (b *Broker) GetTurnDone(req stubs.Empty, res *stubs.GetTurnDoneResponse) (err error) {
+	b.Mu.Lock()
+	defer b.Mu.Unlock()
+	res.TurnDone = b.TurnDone
+	res.Turn = b.Turn
+	b.TurnDone = false
+	return
+}
+
+// GetContinue returns the current world state, turn number, and fault tolerance flag.
+func (b *Broker) GetContinue(req stubs.Empty, res *stubs.GetContinueResponse) (err error) {
+	b.Mu.Lock()
+	defer b.Mu.Unlock()
+	snapshot := b.current()
+	res.World = snapshot.World
+	res.Turn = snapshot.Turn
+	res.Labels = snapshot.Labels
+	res.Continue = b.Continue
+	return
+}
+
+// GetCellFlipped returns the flipped cells queued since the last call, in turn order, and clears the queue. A client
+// that fell too far behind gets the whole world in Keyframe instead.
+func (b *Broker) GetCellFlipped(req stubs.Empty, res *stubs.GetBrokerCellFlippedResponse) (err error) {
+	// The span includes waiting for Mu, which the evolution loop holds for a whole turn.
+	_, span := tracing.Start(context.Background(), "GetCellFlipped")
+	defer span.End()
+	b.Mu.Lock()
+	defer b.Mu.Unlock()
+	span.SetInt("flips", len(b.FlippedEvents))
+
+	res.FlippedEvents = b.FlippedEvents // Return the queued flipped events.
+	b.FlippedEvents = nil               // Start a new queue for the next poll.
+	b.sendKeyframe(res)
+	res.AssignmentVersion = b.AssignmentVer
+	res.Turn = b.Turn
+	b.FenceMu.Lock()
+	res.Paused = b.PauseReason
+	b.FenceMu.Unlock()
+	return
+}
+
+// GetAssignments returns the region of the world each worker computed in the latest turn, for the ownership overlay.
+func (b *Broker) GetAssignments(req stubs.Empty, res *stubs.GetAssignmentsResponse) (err error) {
+	b.Mu.Lock()
+	defer b.Mu.Unlock()
+	res.Assignments = b.Assignments
+	res.Version = b.AssignmentVer
+	res.Turn = b.Turn
+	return
+}
+
+// WorldHash returns the hash of the current world along with the distinct and repeated state counts for this run.
+// Once a state repeats, Period holds the length of the cycle the simulation has entered.
+func (b *Broker) WorldHash(req stubs.Empty, res *stubs.WorldHashResponse) (err error) {
+	_, span := tracing.Start(context.Background(), "WorldHash")
+	defer span.End()
+	b.Mu.Lock()
+	defer b.Mu.Unlock()
+	res.Hash = b.Hash
+	res.Turn = b.Turn
+	res.Distinct = len(b.Seen)
+	res.Repeated = b.Repeated
+	res.Period = b.Period
+	res.CycleTurn = b.CycleTurn
+	return
+}
+
+// Main initialises the broker from the command line arguments args, sets up RPC connections, and listens for
+// incoming requests. command is what it was started as, e.g. engine or serve-broker, for its usage message.
+// It is the whole of go run ./engine, and of the serve-broker subcommand.
+func Main(command string, args []string) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	pAddr := flags.String("port", "8030", "Port to listen on")
+	bind := flags.String("bind", "", "Address of the interface to listen on, e.g. 10.0.0.5, or empty for all interfaces")
+	workerHost := flags.String("workerHost", "localhost", "Host to scan for workers on, or - to scan for none and wait for workers started with -advertise to register")
+	startPort := flags.Int("startPort", 8040, "Starting port for worker scanning")
+	endPort := flags.Int("endPort", 8050, "Ending port for worker scanning")
+	lease := flags.Duration("lease", 10*time.Second, "How long a client may go without a heartbeat before another client may take over")
+	dashboard := flags.String("dashboard", "", "Serve a statistics dashboard on this address, e.g. :8081")
+	workerLog := flags.String("workerLog", "workers.log", "File the log lines sent by workers started with -brokerAddr are appended to, or - for standard output")
+	engine := flags.String("engine", "workers", "Where turns are computed: workers, falling back to the broker if none are reachable, or local to always compute them on the broker")
+	algorithm := flags.String("kernel", "auto", "How the broker counts neighbours when it computes turns itself: bytes, bitsliced to count 64 cells at a time, wide to pack them eight at a time as well, or auto for wide on arm64 and bytes elsewhere")
+	heapWarn := flags.Uint64("heapWarn", 2048, "Log a warning when the heap grows past this many MiB, or 0 for no warnings")
+	heapLimit := flags.Uint64("heapLimit", 0, "Checkpoint and restart the broker when the heap stays past this many MiB after garbage collection, or 0 for no limit")
+	watchdog := flags.Duration("watchdog", 10*time.Second, "How often the heap is sampled for -heapWarn and -heapLimit")
+	checkpointPath := flags.String("checkpoint", "broker.checkpoint", "File the current generation is saved to before a restart")
+	resume := flags.Bool("resume", false, "Load the generation saved in -checkpoint, for the next client to continue from")
+	otlp := flags.String("otlp", "", "Send OpenTelemetry spans of every turn, worker call and client poll to this OTLP/HTTP collector, e.g. http://localhost:4318")
+	jobDir := flags.String("jobDir", "jobs", "Directory each queued job's checkpoints and final image are saved under, in a subdirectory per job")
+	jobCheckpoint := flags.Duration("jobCheckpoint", 5*time.Minute, "How often a queued job's run is checkpointed, or 0 for only at the end")
+	replicate := flags.String("replicate", "", "Comma-separated destinations every checkpoint is copied to in the background: directories, s3:// or gs:// buckets, or standby brokers as broker://host:port")
+	keepCheckpoints := flags.Int("keepCheckpoints", 1, "How many of the newest checkpoints of a run to keep, compressed, in a .history directory beside its checkpoint file")
+	keepHourly := flags.Int("keepHourly", 0, "Also keep the newest checkpoint of each of this many past hours in the history")
+	keyframeEvery := flags.Int("keyframeEvery", 1, "Save every this many checkpoints of a run whole, and those in between as the cells changed since, to write less for boards that change slowly, or 1 to save all whole")
+	checkpointDisk := flags.Int64("checkpointDisk", 0, "Most MiB each checkpoint history may use, deleting the oldest checkpoints past it, or 0 for no limit")
+	replicaDir := flags.String("replicaDir", "", "Directory checkpoints replicated from other brokers are saved in, making this broker a standby, or empty to refuse them")
+	shed := flags.Bool("shed", true, "Send a client whose live view falls behind the whole world instead of the cells flipped since it last polled, once they outnumber the world's cells")
+	selfTest := flags.Bool("selftest", false, "Check the broker's kernel and every worker found compute turns correctly, printing PASS or FAIL for each check, and exit")
+	healthAddr := flags.String("health", "", "Serve /healthz and /readyz on this address, e.g. :8082, for orchestrators and scripts to wait for the broker to be ready")
+	staleAfter := flags.Duration("staleAfter", 30*time.Second, "How long a run may go without finishing a turn before /readyz reports the broker not ready")
+	chaosSpec := flags.String("chaos", "", "Inject faults into the connections to workers, decided by a seed so they recur run after run, e.g. seed=7,latency=20ms,drop=0.01")
+	auditPath := flags.String("audit", "", "Append a SHA-256 hash chain of every turn to this file, for the replay command to check a run's final state really came from its starting state, or empty for none")
+	config := flags.String("config", "", "Read any flags not given on the command line from a YAML or TOML config file, using its top-level and [broker] settings")
+	flags.Parse(args)
+
+	// Fill in anything not given on the command line from the config file.
+	if *config != "" {
+		if err := util.ApplyConfig(flags, *config, "broker"); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if *engine != "workers" && *engine != "local" {
+		fmt.Printf("Unknown engine %q: use workers or local\n", *engine)
+		os.Exit(1)
+	}
+	kernelAlgorithm, err := kernel.ParseAlgorithm(*algorithm)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *otlp != "" {
+		host, _ := os.Hostname()
+		tracing.Enable(*otlp, "gol-broker", host+":"+*pAddr)
+	}
+
+	// Set up the faults before any worker is dialled, so every connection to one gets them.
+	if *chaosSpec != "" {
+		config, err := chaos.Parse(*chaosSpec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		faults = chaos.New(config, "broker "+*pAddr)
+		fmt.Printf("Injecting faults into the connections to workers: %v\n", config)
+	}
+
+	// Goroutine to handle the kill signal and exit the program.
+	go func() {
+		for {
+			if <-kill {
+				tracing.Flush()
+				os.Exit(1)
+			}
+		}
+	}()
+
+	// Set up client connections to workers.
+
+	//var workers []*rpc.Client
+	//workerPorts := ReadFileLines("workers.txt") // Read worker addresses from a file.
+	//for _, detail := range workerPorts {
+	//	client, err := rpc.Dial("tcp", detail)
+	//	if err == nil {
+	//		workers = append(workers, client)
+	//		fmt.Printf("Worker connected on: %v\n", detail)
+	//	}
+	//}
+
+	var workers []*workerConn
+	var addresses []string
+	if *workerHost != "-" {
+		workers, addresses = ScanForWorkers(*workerHost, *startPort, *endPort)
+	}
+	if len(workers) == 0 && *engine == "workers" && *workerHost != "-" {
+		fmt.Printf("Warning: no workers found on %s ports %d-%d, so turns will be computed on the broker until workers register\n", *workerHost, *startPort, *endPort)
+	}
+	broker := &Broker{Workers: workers, Local: *engine == "local", Algorithm: kernelAlgorithm, Continue: false, Lease: *lease,
+		JobDir: *jobDir, JobCheckpoint: *jobCheckpoint, ReplicaDir: *replicaDir, Shed: *shed, KeyframeEvery: *keyframeEvery,
+		Retention: retention{Last: *keepCheckpoints, Hourly: *keepHourly, MaxBytes: *checkpointDisk * util.MiB}}
+	broker.Stats.setWorkers(addresses)
+	if *replicate != "" {
+		broker.Replicas = newReplicator(strings.Split(*replicate, ","), &broker.Stats)
+	}
+
+	// Check the broker and its workers before taking any clients, exiting with status 1 if any are wrong.
+	if *selfTest {
+		if !broker.selfTest(os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Answer health checks from now on, so an orchestrator sees the broker alive but not ready while it starts up.
+	var listening health.Flag
+	if *healthAddr != "" {
+		go health.Serve(*healthAddr, broker.readiness(&listening, *staleAfter), os.Stdout)
+	}
+
+	// Pick up where a restart left off.
+	if *resume {
+		turn, err := broker.loadCheckpoint(*checkpointPath)
+		if err != nil {
+			fmt.Printf("Error loading checkpoint: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Resumed from %s at turn %d\n", *checkpointPath, turn)
+	}
+
+	// Watch the heap, so a slow leak in a long run is caught before the machine runs out of memory.
+	// Past the limit the broker saves the current generation and restarts itself with -resume, and the
+	// next client continues from that generation.
+	heapWatchdog := &util.HeapWatchdog{Interval: *watchdog, Warn: *heapWarn * util.MiB, Limit: *heapLimit * util.MiB, Log: os.Stdout}
+	heapWatchdog.OnLimit = func(heap uint64) {
+		turn, err := broker.saveCheckpoint(*checkpointPath)
+		if err != nil {
+			fmt.Printf("Error saving checkpoint, carrying on without restarting: %s\n", err)
+			return
+		}
+		fmt.Printf("Saved turn %d to %s, restarting\n", turn, *checkpointPath)
+		args := []string{}
+		if !*resume {
+			args = append(args, "-resume")
+		}
+		if err := util.Restart(args...); err != nil {
+			fmt.Printf("Error restarting: %s\n", err)
+		}
+	}
+	go heapWatchdog.Run()
+
+	// Collect the log lines workers send into one file.
+	if *workerLog == "-" {
+		broker.Logs.out = os.Stdout
+	} else {
+		file, err := os.OpenFile(*workerLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Printf("Error opening worker log: %s\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		broker.Logs.out = file
+	}
+
+	// Chain every turn of every run onto the audit log, for replay -audit to check afterwards.
+	if *auditPath != "" {
+		file, err := os.OpenFile(*auditPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Printf("Error opening audit log: %s\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		broker.Audit = audit.NewLog(file)
+	}
+
+	// Serve the dashboard alongside the RPC server.
+	if *dashboard != "" {
+		go serveDashboard(*dashboard, &broker.Stats)
+	}
+
+	// Work through submitted jobs whenever no client is in control.
+	go broker.runJobs()
+
+	// Register the Broker type with the RPC server.
+	rpc.Register(broker)
+
+	// Start listening for incoming RPC connections.
+	listener, err := net.Listen("tcp", net.JoinHostPort(*bind, *pAddr))
+	if err != nil {
+		fmt.Printf("Error starting listener: %s\n", err)
+		os.Exit(1)
+	}
+	defer listener.Close()
+	listening.Set()
+
+	// Accept incoming RPC connections.
+	rpc.Accept(listener)
+}
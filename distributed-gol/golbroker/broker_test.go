@@ -0,0 +1,514 @@
+package golbroker
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/audit"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// acquire takes control of the broker and fails the test if it is refused.
+func acquire(t *testing.T, b *Broker) int {
+	res := &stubs.AcquireResponse{}
+	if err := b.Acquire(stubs.Empty{}, res); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	return res.Epoch
+}
+
+// TestAcquireRefusedWhileLeaseLive checks a second client cannot take over while the first is still heartbeating.
+func TestAcquireRefusedWhileLeaseLive(t *testing.T) {
+	b := &Broker{Lease: time.Minute}
+	first := acquire(t, b)
+
+	if err := b.Acquire(stubs.Empty{}, &stubs.AcquireResponse{}); err != errLeaseHeld {
+		t.Fatalf("second Acquire returned %v, expected %v", err, errLeaseHeld)
+	}
+	if err := b.Heartbeat(stubs.ControlRequest{Epoch: first}, &stubs.Empty{}); err != nil {
+		t.Fatalf("first client's heartbeat was rejected: %v", err)
+	}
+}
+
+// TestPartitionedClientIsFenced simulates a client that stops heartbeating, is replaced, and later comes back.
+func TestPartitionedClientIsFenced(t *testing.T) {
+	b := &Broker{Lease: 20 * time.Millisecond}
+	stale := acquire(t, b)
+
+	// The first client is partitioned: its lease runs out without a heartbeat.
+	time.Sleep(40 * time.Millisecond)
+	current := acquire(t, b)
+	if current <= stale {
+		t.Fatalf("takeover epoch %d is not newer than stale epoch %d", current, stale)
+	}
+	if !b.Continue {
+		t.Fatal("takeover should continue from the partitioned client's world")
+	}
+
+	// When the partition heals, every control RPC from the old client must be rejected.
+	staleReq := stubs.ControlRequest{Epoch: stale}
+	if err := b.Heartbeat(staleReq, &stubs.Empty{}); err != errStaleEpoch {
+		t.Fatalf("stale Heartbeat returned %v, expected %v", err, errStaleEpoch)
+	}
+	if err := b.Pause(staleReq, &stubs.Empty{}); err != errStaleEpoch {
+		t.Fatalf("stale Pause returned %v, expected %v", err, errStaleEpoch)
+	}
+	if err := b.QuitServer(staleReq, &stubs.Empty{}); err != errStaleEpoch {
+		t.Fatalf("stale QuitServer returned %v, expected %v", err, errStaleEpoch)
+	}
+	evolve := stubs.EvolveWorldRequest{Turn: 10, Epoch: stale}
+	if err := b.EvolveWorld(evolve, &stubs.EvolveResponse{}); err != errStaleEpoch {
+		t.Fatalf("stale EvolveWorld returned %v, expected %v", err, errStaleEpoch)
+	}
+
+	// The new client is unaffected.
+	if err := b.Heartbeat(stubs.ControlRequest{Epoch: current}, &stubs.Empty{}); err != nil {
+		t.Fatalf("current client's heartbeat was rejected: %v", err)
+	}
+}
+
+// TestTakeoverStopsStaleEvolution checks that an evolution loop driven by a partitioned client stops at the next turn.
+func TestTakeoverStopsStaleEvolution(t *testing.T) {
+	b := &Broker{Lease: 20 * time.Millisecond}
+	stale := acquire(t, b)
+
+	done := make(chan error)
+	go func() {
+		evolve := stubs.EvolveWorldRequest{Turn: 1 << 30, Epoch: stale}
+		done <- b.EvolveWorld(evolve, &stubs.EvolveResponse{})
+	}()
+
+	time.Sleep(40 * time.Millisecond)
+	acquire(t, b)
+
+	select {
+	case err := <-done:
+		if err != errStaleEpoch {
+			t.Fatalf("stale EvolveWorld returned %v, expected %v", err, errStaleEpoch)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stale evolution loop kept running after takeover")
+	}
+}
+
+// TestTakeoverLiftsStalePause checks a hard pause left behind by a partitioned client doesn't wedge the broker.
+func TestTakeoverLiftsStalePause(t *testing.T) {
+	pauses := map[string]func(*Broker, stubs.ControlRequest, *stubs.Empty) error{
+		"soft": (*Broker).Pause,
+		"hard": (*Broker).HardPause,
+	}
+	for name, pause := range pauses {
+		b := &Broker{Lease: 20 * time.Millisecond}
+		stale := acquire(t, b)
+		if err := pause(b, stubs.ControlRequest{Epoch: stale}, &stubs.Empty{}); err != nil {
+			t.Fatalf("%s pause failed: %v", name, err)
+		}
+
+		time.Sleep(40 * time.Millisecond)
+		acquire(t, b)
+
+		unlocked := make(chan bool)
+		go func() {
+			b.Mu.Lock()
+			b.Mu.Unlock()
+			unlocked <- true
+		}()
+		select {
+		case <-unlocked:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("broker is still %s paused after takeover", name)
+		}
+		if b.SoftPaused {
+			t.Errorf("broker is still %s paused after takeover", name)
+		}
+	}
+}
+
+// waitForTurn polls the published world until it reaches at least the given turn, failing the test if it doesn't.
+func waitForTurn(t *testing.T, b *Broker, turn int) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if current := b.current(); current.Turn >= turn {
+			return current.Turn
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("turn %d was never reached", turn)
+	return 0
+}
+
+// TestSoftPause checks a soft pause stops the run between turns while leaving the reads that need the mutex
+// working, and that the run resumes and can be quit while paused.
+func TestSoftPause(t *testing.T) {
+	b := &Broker{Lease: time.Minute}
+	epoch := acquire(t, b)
+	control := stubs.ControlRequest{Epoch: epoch}
+	done := make(chan error)
+	go func() {
+		req := stubs.EvolveWorldRequest{World: gliderWorld(16), Turn: 1 << 30, ImageWidth: 16, ImageHeight: 16, Epoch: epoch}
+		done <- b.EvolveWorld(req, &stubs.EvolveResponse{})
+	}()
+	waitForTurn(t, b, 1)
+
+	if err := b.Pause(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	paused := b.current().Turn
+	reads := make(chan error)
+	go func() {
+		if err := b.GetCellFlipped(stubs.Empty{}, &stubs.GetBrokerCellFlippedResponse{}); err != nil {
+			reads <- err
+			return
+		}
+		res := &stubs.WorldHashResponse{}
+		reads <- b.WorldHash(stubs.Empty{}, res)
+	}()
+	select {
+	case err := <-reads:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reads were blocked by a soft pause")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if turn := b.current().Turn; turn != paused {
+		t.Fatalf("paused at turn %d, but turn %d was published during the pause", paused, turn)
+	}
+
+	if err := b.Unpause(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTurn(t, b, paused+1)
+
+	if err := b.Pause(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.QuitServer(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("quitting while paused left EvolveWorld waiting")
+	}
+}
+
+// TestRunUntil checks the run pauses by itself once it reaches the turn asked for, resuming from a pause to get
+// there, and that a turn already completed is refused.
+func TestRunUntil(t *testing.T) {
+	b := &Broker{Lease: time.Minute}
+	epoch := acquire(t, b)
+	control := stubs.ControlRequest{Epoch: epoch}
+	done := make(chan error)
+	go func() {
+		req := stubs.EvolveWorldRequest{World: gliderWorld(16), Turn: 1 << 30, ImageWidth: 16, ImageHeight: 16, Epoch: epoch}
+		done <- b.EvolveWorld(req, &stubs.EvolveResponse{})
+	}()
+	waitForTurn(t, b, 1)
+	if err := b.Pause(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := b.current().Turn + 50
+	if err := b.RunUntil(stubs.RunUntilRequest{Epoch: epoch, Turn: target}, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTurn(t, b, target)
+	time.Sleep(20 * time.Millisecond)
+	if turn := b.current().Turn; turn != target {
+		t.Fatalf("asked to run until turn %d, but the run carried on to turn %d", target, turn)
+	}
+	if err := b.RunUntil(stubs.RunUntilRequest{Epoch: epoch, Turn: target}, &stubs.Empty{}); err == nil {
+		t.Fatalf("running until turn %d was accepted at turn %d", target, target)
+	}
+
+	// Resuming carries on past the target.
+	if err := b.Unpause(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTurn(t, b, target+1)
+	if err := b.QuitServer(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("quitting left EvolveWorld waiting")
+	}
+}
+
+// TestHeartbeatAfterReleaseKeepsBrokerFree checks a late heartbeat from a finished client is rejected and doesn't
+// lock out the next one.
+func TestHeartbeatAfterReleaseKeepsBrokerFree(t *testing.T) {
+	b := &Broker{Lease: time.Minute}
+	first := acquire(t, b)
+	b.release()
+
+	if err := b.Heartbeat(stubs.ControlRequest{Epoch: first}, &stubs.Empty{}); err != stubs.ErrLeaseReleased {
+		t.Fatalf("heartbeat after release returned %v, expected %v", err, stubs.ErrLeaseReleased)
+	}
+	if err := b.Pause(stubs.ControlRequest{Epoch: first}, &stubs.Empty{}); err != stubs.ErrLeaseReleased {
+		t.Fatalf("pause after release returned %v, expected %v", err, stubs.ErrLeaseReleased)
+	}
+	acquire(t, b)
+}
+
+// TestCycleDetection feeds the broker the states of a period-2 oscillator after a one-turn transient.
+func TestCycleDetection(t *testing.T) {
+	b := &Broker{World: slab.New(2, 2)}
+	b.resetStates()
+	for _, hash := range []uint64{1, 2, 3, 2, 3, 2} {
+		b.Turn++
+		b.recordState(hash)
+	}
+
+	res := &stubs.WorldHashResponse{}
+	if err := b.WorldHash(stubs.Empty{}, res); err != nil {
+		t.Fatalf("WorldHash failed: %v", err)
+	}
+	if res.Period != 2 || res.CycleTurn != 4 {
+		t.Errorf("found period %d at turn %d, expected period 2 at turn 4", res.Period, res.CycleTurn)
+	}
+	if res.Distinct != 4 || res.Repeated != 3 {
+		t.Errorf("counted %d distinct and %d repeated states, expected 4 and 3", res.Distinct, res.Repeated)
+	}
+	if res.Hash != 2 {
+		t.Errorf("current hash is %d, expected 2", res.Hash)
+	}
+}
+
+// TestAudit checks a run and its continuation after a quit are chained into the audit log as one run, ending at
+// the world the broker finished with.
+func TestAudit(t *testing.T) {
+	var log bytes.Buffer
+	b := &Broker{Lease: time.Minute, Audit: audit.NewLog(&log)}
+	for _, turns := range []int{3, 5} {
+		epoch := acquire(t, b)
+		req := stubs.EvolveWorldRequest{World: gliderWorld(8), Turn: turns, ImageWidth: 8, ImageHeight: 8, Epoch: epoch}
+		if err := b.EvolveWorld(req, &stubs.EvolveResponse{}); err != nil {
+			t.Fatal(err)
+		}
+		b.Continue = true // As after QuitServer.
+	}
+
+	entries, err := audit.Read(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := audit.Start(gliderWorld(8), 0, "B3/S23", "torus", 0)
+	if len(entries) != 6 || entries[0] != last {
+		t.Fatalf("logged %v, expected the start and 5 turns", entries)
+	}
+	for _, e := range entries[1:] {
+		if e.Kind != "turn" || e.Turn != last.Turn+1 {
+			t.Errorf("logged %v after %v", e, last)
+		}
+		last = e
+	}
+	if last.World != audit.WorldSum(b.World) {
+		t.Error("the last turn logged isn't the world the run finished with")
+	}
+}
+
+// TestAssignRows checks every row is owned by exactly one worker, for worker counts that don't divide the height.
+func TestAssignRows(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16}
+	for threads := 1; threads <= 16; threads++ {
+		next := 0
+		for id, a := range assignRows(p, threads) {
+			if a.Worker != id || a.StartRow != next || a.EndRow < a.StartRow || a.EndCol != p.ImageWidth {
+				t.Fatalf("%d workers: worker %d was assigned %+v", threads, id, a)
+			}
+			next = a.EndRow
+		}
+		if next != p.ImageHeight {
+			t.Errorf("%d workers: rows from %d onwards are unowned", threads, next)
+		}
+	}
+}
+
+// TestAssemble checks a world assembled from its slices at once has every slice's rows in place, with the flipped
+// cells and row hashes in the order the slices were assigned, however the world is split.
+func TestAssemble(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16}
+	expected := slab.New(p.ImageWidth, p.ImageHeight)
+	for y := 0; y < p.ImageHeight; y++ {
+		expected.Set(y, y, util.Alive)
+	}
+	for threads := 1; threads <= 16; threads++ {
+		assignments := assignRows(p, threads)
+		slices := make([]sliceResult, threads)
+		for i, a := range assignments {
+			slices[i] = sliceResult{World: expected.Slice(a.StartRow, a.EndRow).Clone(), StartRow: a.StartRow}
+			for y := a.StartRow; y < a.EndRow; y++ {
+				slices[i].Flipped = append(slices[i].Flipped, util.Cell{X: y, Y: y})
+				slices[i].RowHashes = append(slices[i].RowHashes, uint64(y))
+			}
+		}
+		world, flipped, rowHashes := assemble(p.ImageWidth, p.ImageHeight, slices)
+		if !bytes.Equal(world.Cells, expected.Cells) {
+			t.Errorf("%d workers: assembled %v, expected the diagonal", threads, world.Cells)
+		}
+		if len(flipped) != p.ImageHeight || len(rowHashes) != p.ImageHeight {
+			t.Fatalf("%d workers: got %d flips and %d row hashes, expected %d of each", threads, len(flipped), len(rowHashes), p.ImageHeight)
+		}
+		for y := range flipped {
+			if flipped[y] != (util.Cell{X: y, Y: y}) || rowHashes[y] != uint64(y) {
+				t.Errorf("%d workers: row %d has flip %v and hash %d", threads, y, flipped[y], rowHashes[y])
+			}
+		}
+	}
+}
+
+// BenchmarkAssemble times assembling a 5120x5120 world from the slices of 8 workers.
+func BenchmarkAssemble(b *testing.B) {
+	p := gol.Params{ImageWidth: 5120, ImageHeight: 5120}
+	slices := make([]sliceResult, 8)
+	for i, a := range assignRows(p, len(slices)) {
+		slices[i] = sliceResult{World: slab.New(p.ImageWidth, a.EndRow-a.StartRow), StartRow: a.StartRow}
+	}
+	b.SetBytes(int64(p.ImageWidth * p.ImageHeight))
+	for i := 0; i < b.N; i++ {
+		assemble(p.ImageWidth, p.ImageHeight, slices)
+	}
+}
+
+// TestPlan checks a plan splits the world as a run would, counts the whole world sent to every worker, and
+// estimates the size of an encoded world to within gob's headers.
+func TestPlan(t *testing.T) {
+	b := &Broker{Workers: make([]*workerConn, 3), Lease: time.Minute}
+	req := stubs.PlanRequest{ImageWidth: 300, ImageHeight: 200, Turns: 10}
+	res := &stubs.PlanResponse{}
+	if err := b.Plan(req, res); err != nil {
+		t.Fatal(err)
+	}
+	p := gol.Params{ImageWidth: 300, ImageHeight: 200}
+	if res.Workers != 3 || !sameAssignments(res.Assignments, assignRows(p, 3)) || len(res.WorkerMemory) != 3 {
+		t.Errorf("planned %d workers with %+v, expected the rows split three ways", res.Workers, res.Assignments)
+	}
+
+	// A connection describes the type once, so the world is measured the second time it is sent.
+	var encoded bytes.Buffer
+	encoder := gob.NewEncoder(&encoded)
+	for i := 0; i < 2; i++ {
+		encoded.Reset()
+		if err := encoder.Encode(slab.New(300, 200)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only gob's message headers are left out.
+	if sent := res.SentPerTurn / 3; sent > int64(encoded.Len()) || sent < int64(encoded.Len()-64) {
+		t.Errorf("estimated %d bytes to send the world, gob took %d", sent, encoded.Len())
+	}
+	if res.Transfer != 10*(res.SentPerTurn+res.ReceivedPerTurn) {
+		t.Errorf("%d bytes transferred over 10 turns of %d sent and %d received", res.Transfer, res.SentPerTurn, res.ReceivedPerTurn)
+	}
+
+	// A broker computing every turn itself sends nothing.
+	b.Local = true
+	res = &stubs.PlanResponse{}
+	if err := b.Plan(req, res); err != nil || res.Workers != 0 || res.SentPerTurn != 0 || res.BrokerMemory == 0 {
+		t.Errorf("local plan was %+v, %v", res, err)
+	}
+
+	for _, bad := range []stubs.PlanRequest{{ImageWidth: 0, ImageHeight: 16}, {ImageWidth: 16, ImageHeight: 16, Rule: "nonsense"}} {
+		if err := b.Plan(bad, &stubs.PlanResponse{}); err == nil {
+			t.Errorf("planned %+v", bad)
+		}
+	}
+}
+
+// TestCoalesceFlips checks a coalesced queue keeps exactly the cells that flipped an odd number of times.
+func TestCoalesceFlips(t *testing.T) {
+	a, b, c := util.Cell{X: 1, Y: 1}, util.Cell{X: 2, Y: 1}, util.Cell{X: 3, Y: 1}
+	var events []stubs.FlippedEvent
+	for turn, cells := range [][]util.Cell{{a, b, c}, {a, c}, {c}} {
+		for _, cell := range cells {
+			events = append(events, stubs.FlippedEvent{CompletedTurns: turn + 1, Cell: cell})
+		}
+	}
+
+	coalesced := coalesceFlips(events, 3)
+	expected := []stubs.FlippedEvent{{CompletedTurns: 3, Cell: b}, {CompletedTurns: 3, Cell: c}}
+	if len(coalesced) != len(expected) {
+		t.Fatalf("coalesced to %v, expected %v", coalesced, expected)
+	}
+	for i := range expected {
+		if coalesced[i] != expected[i] {
+			t.Fatalf("coalesced to %v, expected %v", coalesced, expected)
+		}
+	}
+}
+
+// hungWorker is a stand-in for a worker whose slices take far longer than a test, until the broker cancels them.
+type hungWorker struct {
+	started   chan bool // Receives a value each time a slice is started.
+	cancelled chan int  // Receives the job of each Cancel.
+	stop      chan bool // Closed by the first Cancel.
+}
+
+func (w *hungWorker) CalculateWorld(req *stubs.WorldReq, res *stubs.WorldRes) (err error) {
+	w.started <- true
+	<-w.stop
+	return errors.New("cancelled")
+}
+
+func (w *hungWorker) Cancel(req *stubs.CancelReq, res *stubs.Empty) (err error) {
+	w.cancelled <- req.Job
+	close(w.stop)
+	return
+}
+
+// TestQuitCancelsTurn checks quitting mid-turn stops the run straight away, rather than when the workers reply,
+// leaving the world at the last complete turn, and tells the workers to stop too.
+func TestQuitCancelsTurn(t *testing.T) {
+	worker := &hungWorker{started: make(chan bool, 1), cancelled: make(chan int, 1), stop: make(chan bool)}
+	client, stop := serveWorker(t, worker)
+	defer stop()
+	b := &Broker{Workers: []*workerConn{client}, Lease: time.Minute}
+	epoch := acquire(t, b)
+
+	done := make(chan error)
+	res := &stubs.EvolveResponse{}
+	go func() {
+		done <- b.EvolveWorld(stubs.EvolveWorldRequest{World: gliderWorld(8), Turn: 100, ImageWidth: 8, ImageHeight: 8, Epoch: epoch}, res)
+	}()
+	<-worker.started
+	if err := b.QuitServer(stubs.ControlRequest{Epoch: epoch}, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("EvolveWorld carried on waiting for the worker after the run was quit")
+	}
+	if res.Turn != 0 || countAlive(res.World) != 5 {
+		t.Errorf("quitting during turn 1 left turn %d with %d alive cells, expected the glider at turn 0", res.Turn, countAlive(res.World))
+	}
+
+	select {
+	case job := <-worker.cancelled:
+		if job != 1 {
+			t.Errorf("the worker was told to cancel job %d, expected 1", job)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the worker wasn't told to stop its slice")
+	}
+}
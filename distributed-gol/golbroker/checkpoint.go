@@ -0,0 +1,153 @@
+package golbroker
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"uk.ac.bris.cs/gameoflife/golsnap"
+	"uk.ac.bris.cs/gameoflife/slab"
+)
+
+// legacyCheckpoint is how checkpoints were saved before the golsnap format, still loaded so they can be resumed.
+type legacyCheckpoint struct {
+	Turn  int
+	World [][]byte
+	Seed  int64 // Seed of a stochastic rule, so the continued run makes the same choices.
+}
+
+// saveCheckpoint writes the latest published generation to path and returns its turn. It goes through a temporary file, so a crash
+// part way through never leaves a truncated checkpoint behind. The snapshot is used rather than b.World, as
+// the mutex may be held for a long time while the run is paused. Once saved, the checkpoint is copied to any
+// -replicate destinations, and kept in the path's history if the retention policy keeps one, in the background.
+// With KeyframeEvery past 1, only every KeyframeEvery-th checkpoint at path is written there whole, and those in
+// between are written beside it as deltas against it, unless a delta would be no smaller. Replicas and the
+// history still get every checkpoint whole.
+func (b *Broker) saveCheckpoint(path string) (int, error) {
+	snapshot := b.current()
+	state := golsnap.State{Turn: snapshot.Turn, Seed: snapshot.Seed, Rule: snapshot.Rule, Edge: snapshot.Edge,
+		World: snapshot.World, Labels: snapshot.Labels}
+	keyframe, data, err := golsnap.NewKeyframe(state)
+	if err != nil {
+		return 0, err
+	}
+	var delta []byte
+	if base, ok := b.Checkpoints.deltaBase(path, b.KeyframeEvery); ok {
+		if delta, err = base.EncodeDelta(state); err != nil || len(delta) >= len(data) {
+			delta = nil
+		}
+	}
+	if delta != nil {
+		err = writeFile(deltaPath(path), delta)
+	} else if err = writeFile(path, data); err == nil {
+		// The delta of the last keyframe no longer applies. Were it left behind by a crash, loading would spot that.
+		os.Remove(deltaPath(path))
+		b.Checkpoints.keyframeSaved(path, keyframe)
+	}
+	if err != nil {
+		return 0, err
+	}
+	b.Replicas.replicate(replicaName(path), data, snapshot.Turn)
+	b.Checkpoints.note(path, snapshot.Turn, snapshot.Labels)
+	if b.Retention.keepsHistory() {
+		b.Checkpoints.pending.Add(1)
+		go b.archiveCheckpoint(path, data, snapshot.Turn)
+	}
+	return snapshot.Turn, nil
+}
+
+// deltaPath is the file the latest delta against the keyframe at path is saved in.
+func deltaPath(path string) string {
+	return path + ".delta"
+}
+
+// writeFile writes data to path through a temporary file, so a crash part way through leaves the old file whole.
+func writeFile(path string, data []byte) error {
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// loadCheckpoint restores a generation saved by saveCheckpoint, or by a client pressing q, for the next client to
+// continue from. The gzipped copies kept in a checkpoint's history, and checkpoints saved before the golsnap
+// format, can be loaded too.
+func (b *Broker) loadCheckpoint(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	in := bufio.NewReader(file)
+	var data io.Reader = in
+	if magic, err := in.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		if data, err = gzip.NewReader(in); err != nil {
+			return 0, err
+		}
+	}
+	contents, err := ioutil.ReadAll(data)
+	if err != nil {
+		return 0, err
+	}
+	var saved golsnap.State
+	if golsnap.Is(contents) {
+		if saved, err = loadKeyframe(path, contents); err != nil {
+			return 0, err
+		}
+	} else {
+		var legacy legacyCheckpoint
+		if err := gob.NewDecoder(bytes.NewReader(contents)).Decode(&legacy); err != nil {
+			return 0, err
+		}
+		saved = golsnap.State{Turn: legacy.Turn, Seed: legacy.Seed, World: slab.FromRows(legacy.World)}
+	}
+	b.Checkpoints.note(path, saved.Turn, saved.Labels)
+
+	b.Mu.Lock()
+	defer b.Mu.Unlock()
+	b.World = saved.World
+	b.Turn = saved.Turn
+	b.Seed = saved.Seed
+	b.Rule, b.Edge = saved.Rule, saved.Edge
+	b.Labels = saved.Labels
+	b.Continue = true
+	b.publish()
+	return saved.Turn, nil
+}
+
+// loadKeyframe decodes the checkpoint saved whole at path, and applies the delta saved beside it, if any, to get the
+// latest checkpoint. A delta against an older keyframe, left behind by a crash just after a newer one was saved,
+// is ignored, as the keyframe is newer.
+func loadKeyframe(path string, contents []byte) (golsnap.State, error) {
+	keyframe, err := golsnap.DecodeKeyframe(contents)
+	if err != nil {
+		return golsnap.State{}, err
+	}
+	delta, err := ioutil.ReadFile(deltaPath(path))
+	if os.IsNotExist(err) {
+		return keyframe.State, nil
+	} else if err != nil {
+		return golsnap.State{}, err
+	}
+	saved, err := keyframe.ApplyDelta(delta)
+	if err == golsnap.ErrOtherKeyframe {
+		fmt.Printf("Warning: ignoring %s, which was saved against an older checkpoint than %s\n", deltaPath(path), path)
+		return keyframe.State, nil
+	} else if err != nil {
+		return golsnap.State{}, fmt.Errorf("%s: %v", deltaPath(path), err)
+	}
+	return saved, nil
+}
@@ -0,0 +1,158 @@
+package golbroker
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestCheckpointResume evolves a world, checkpoints it, and checks a fresh broker restored from the checkpoint
+// hands the same generation to the next client to continue from.
+func TestCheckpointResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "broker.checkpoint")
+
+	const size, turns = 16, 10
+	b := &Broker{Lease: time.Minute}
+	epoch := acquire(t, b)
+	evolved := &stubs.EvolveResponse{}
+	req := stubs.EvolveWorldRequest{World: gliderWorld(size), Turn: turns, ImageWidth: size, ImageHeight: size, Epoch: epoch}
+	if err := b.EvolveWorld(req, evolved); err != nil {
+		t.Fatal(err)
+	}
+	if turn, err := b.saveCheckpoint(path); err != nil || turn != turns {
+		t.Fatalf("saveCheckpoint saved turn %d, expected %d: %v", turn, turns, err)
+	}
+
+	restarted := &Broker{Lease: time.Minute}
+	turn, err := restarted.loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint failed: %v", err)
+	}
+	if turn != turns {
+		t.Errorf("resumed at turn %d, expected %d", turn, turns)
+	}
+	res := &stubs.GetContinueResponse{}
+	if err := restarted.GetContinue(stubs.Empty{}, res); err != nil {
+		t.Fatal(err)
+	}
+	if !res.Continue || res.Turn != turns {
+		t.Fatalf("the next client is told continue=%v from turn %d, expected to continue from %d", res.Continue, res.Turn, turns)
+	}
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if res.World.At(j, i) != evolved.World.At(j, i) {
+				t.Fatalf("cell (%d, %d) differs after resuming", j, i)
+			}
+		}
+	}
+}
+
+// TestStochasticResume checks a stochastic run continued from a checkpoint makes the same choices as a run that
+// never stopped, even when the next client asks for another seed.
+func TestStochasticResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "broker.checkpoint")
+
+	// A soup rather than a glider, so plenty of cells are left to chance every turn.
+	const size, turns = 32, 20
+	soup := gliderWorld(size)
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if random.Intn(3) == 0 {
+				soup.Set(j, i, util.Alive)
+			}
+		}
+	}
+	evolve := func(b *Broker, turns int, seed int64) slab.World {
+		res := &stubs.EvolveResponse{}
+		req := stubs.EvolveWorldRequest{World: soup, Turn: turns, ImageWidth: size, ImageHeight: size,
+			Epoch: acquire(t, b), Rule: "B36/S23,B3=0.8,S2=0.9", Seed: seed}
+		if err := b.EvolveWorld(req, res); err != nil {
+			t.Fatal(err)
+		}
+		return res.World
+	}
+	uninterrupted := evolve(&Broker{Lease: time.Minute}, turns, 7)
+
+	first := &Broker{Lease: time.Minute}
+	evolve(first, turns/2, 7)
+	if _, err := first.saveCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	restarted := &Broker{Lease: time.Minute}
+	if _, err := restarted.loadCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	resumed := evolve(restarted, turns, 99)
+
+	if countAlive(uninterrupted) == 0 {
+		t.Fatal("the soup died out, so the seed made no difference")
+	}
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if resumed.At(j, i) != uninterrupted.At(j, i) {
+				t.Fatalf("cell (%d, %d) differs after resuming", j, i)
+			}
+		}
+	}
+}
+
+// TestCheckpointRule checks a checkpoint keeps the rule and edges of the run it was saved from, so a resumed
+// broker knows them, and that checkpoints saved before the golsnap format still load.
+func TestCheckpointRule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "broker.checkpoint")
+
+	const size, turns = 16, 4
+	b := &Broker{Lease: time.Minute}
+	req := stubs.EvolveWorldRequest{World: gliderWorld(size), Turn: turns, ImageWidth: size, ImageHeight: size,
+		Rule: "B36/S23", Edge: "dead", Epoch: acquire(t, b)}
+	if err := b.EvolveWorld(req, &stubs.EvolveResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.saveCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	restarted := &Broker{Lease: time.Minute}
+	if _, err := restarted.loadCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.Rule != "B36/S23" || restarted.Edge != "dead" {
+		t.Errorf("resumed under %s with %s edges, expected B36/S23 with dead edges", restarted.Rule, restarted.Edge)
+	}
+
+	var legacy bytes.Buffer
+	if err := gob.NewEncoder(&legacy).Encode(legacyCheckpoint{Turn: 7, World: gliderWorld(size).Rows(), Seed: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, legacy.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := &Broker{Lease: time.Minute}
+	if turn, err := old.loadCheckpoint(path); err != nil || turn != 7 || old.Seed != 3 {
+		t.Errorf("loaded an old checkpoint at turn %d with seed %d, expected turn 7 and seed 3: %v", turn, old.Seed, err)
+	}
+}
@@ -0,0 +1,70 @@
+package golbroker
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// corruptingWorker is an in-process stand-in for a worker behind a flaky link: every third slice has a cell flipped
+// after it was sealed, as a bit flipped on the way would look once decoded.
+type corruptingWorker struct {
+	lifeWorker
+	mu        sync.Mutex
+	slices    int
+	corrupted int
+}
+
+func (w *corruptingWorker) CalculateWorld(req *stubs.WorldReq, res *stubs.WorldRes) (err error) {
+	if err = w.lifeWorker.CalculateWorld(req, res); err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.slices++
+	if w.slices%3 == 0 {
+		w.corrupted++
+		res.World.Cells[0] ^= 0xFF
+	}
+	return
+}
+
+// TestCorruptSlice checks slices that don't match their checksum are computed again on the broker rather than
+// committed, so the board stays correct alongside a worker corrupting them.
+func TestCorruptSlice(t *testing.T) {
+	healthy, stopHealthy := serveWorker(t, &lifeWorker{})
+	defer stopHealthy()
+	corrupting := &corruptingWorker{}
+	flaky, stopFlaky := serveWorker(t, corrupting)
+	defer stopFlaky()
+
+	b := &Broker{Workers: []*workerConn{healthy, flaky}, Lease: time.Minute}
+	evolveGliderRoundTrip(t, b, 16)
+	if corrupting.corrupted == 0 {
+		t.Error("no slices were corrupted, so the checksum wasn't tested")
+	}
+}
+
+// TestSeal checks a sealed slice is intact until one of its cells changes.
+func TestSeal(t *testing.T) {
+	res := &stubs.WorldRes{World: gliderWorld(8)}
+	if res.Intact() {
+		t.Error("an unsealed slice of a glider is intact")
+	}
+	res.Seal()
+	if !res.Intact() {
+		t.Fatal("a sealed slice isn't intact")
+	}
+	res.World.Set(7, 7, 0xFF)
+	if res.Intact() {
+		t.Error("a slice changed after sealing is still intact")
+	}
+	empty := &stubs.WorldRes{World: slab.New(0, 0)}
+	empty.Seal()
+	if !empty.Intact() {
+		t.Error("an empty sealed slice isn't intact")
+	}
+}
@@ -0,0 +1,288 @@
+package golbroker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// workerStats describes one worker on the dashboard.
+type workerStats struct {
+	Address   string  // Address the broker connected to the worker on.
+	LatencyMs float64 // Round trip time of the worker's latest slice, in milliseconds.
+	Rows      int     // Number of rows the worker computed in the latest turn.
+	Flipped   int     // Number of cells that changed state in the worker's rows in the latest turn.
+	Calls     int     // Number of slices the worker has computed since the broker started.
+}
+
+// replicaStats describes on the dashboard one destination checkpoints are replicated to.
+type replicaStats struct {
+	Destination string
+	Turn        int       // Turn of the latest checkpoint copied there, or -1 if none has been.
+	Copied      time.Time // When the latest checkpoint was copied there.
+	Failures    int       // Number of copies that have failed since the broker started.
+	Error       string    // Why the latest copy failed, or "" if it succeeded.
+}
+
+// dashboardStats is the snapshot of the broker served to the dashboard.
+type dashboardStats struct {
+	Turn       int
+	Alive      int
+	GensPerSec float64
+	Paused     bool
+	Workers    []workerStats
+	Replicas   []replicaStats
+}
+
+// stats keeps the figures shown on the dashboard. It has its own mutex, separate from the broker's,
+// so the dashboard keeps working while a client has the broker paused.
+type stats struct {
+	mu          sync.Mutex
+	current     dashboardStats
+	lastTurnAt  time.Time // When the latest turn finished.
+	windowStart time.Time // Start of the window the generation rate is measured over.
+	windowTurn  int       // Turn at the start of the window.
+	running     bool      // Whether a run is in progress.
+	runStarted  time.Time // When the run in progress started.
+}
+
+// rateWindow is how long turns are counted for before the generation rate is updated.
+const rateWindow = time.Second
+
+// setWorkers records the addresses of the connected workers.
+func (s *stats) setWorkers(addresses []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.Workers = make([]workerStats, len(addresses))
+	for i, address := range addresses {
+		s.current.Workers[i].Address = address
+	}
+}
+
+// addWorker records the address of a worker connected after the others, which becomes the last worker.
+func (s *stats) addWorker(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.Workers = append(s.current.Workers, workerStats{Address: address})
+}
+
+// workerAddress returns the address of the i-th worker.
+func (s *stats) workerAddress(i int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i < len(s.current.Workers) {
+		return s.current.Workers[i].Address
+	}
+	return "unknown address"
+}
+
+// setReplicas records the destinations checkpoints are replicated to.
+func (s *stats) setReplicas(destinations []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.Replicas = make([]replicaStats, len(destinations))
+	for i, destination := range destinations {
+		s.current.Replicas[i] = replicaStats{Destination: destination, Turn: -1}
+	}
+}
+
+// recordReplica records the outcome of copying the checkpoint of a turn to the i-th replica destination.
+func (s *stats) recordReplica(i, turn int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i >= len(s.current.Replicas) {
+		return
+	}
+	r := &s.current.Replicas[i]
+	if err != nil {
+		r.Failures++
+		r.Error = err.Error()
+		return
+	}
+	r.Turn, r.Copied, r.Error = turn, time.Now(), ""
+}
+
+// recordTurn updates the figures after a turn. latencies, rows and flips are indexed by worker.
+func (s *stats) recordTurn(turn, alive int, latencies []time.Duration, rows, flips []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.current.Turn = turn
+	s.current.Alive = alive
+	s.lastTurnAt = now
+
+	// Measure the rate over a window rather than per turn, so it doesn't jitter with every turn's timing.
+	if s.windowStart.IsZero() || turn < s.windowTurn {
+		s.windowStart, s.windowTurn = now, turn
+	} else if elapsed := now.Sub(s.windowStart); elapsed >= rateWindow {
+		s.current.GensPerSec = float64(turn-s.windowTurn) / elapsed.Seconds()
+		s.windowStart, s.windowTurn = now, turn
+	}
+
+	for i := range latencies {
+		if i >= len(s.current.Workers) {
+			break
+		}
+		w := &s.current.Workers[i]
+		w.LatencyMs = float64(latencies[i]) / float64(time.Millisecond)
+		w.Rows = rows[i]
+		w.Flipped = flips[i]
+		w.Calls++
+	}
+}
+
+// setPaused records whether a client has the broker paused.
+func (s *stats) setPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.Paused = paused
+}
+
+// setRunning records whether a run is in progress.
+func (s *stats) setRunning(running bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = running
+	if running {
+		s.runStarted = time.Now()
+	}
+}
+
+// stalled returns why the run in progress counts as stuck, if it hasn't finished a turn within after, or nil if
+// it hasn't, it is paused or there is no run.
+func (s *stats) stalled(after time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running || s.current.Paused {
+		return nil
+	}
+	last := s.lastTurnAt
+	if s.runStarted.After(last) {
+		last = s.runStarted
+	}
+	if since := time.Since(last); since > after {
+		return fmt.Errorf("no turn finished for %v", since.Round(time.Second))
+	}
+	return nil
+}
+
+// snapshot returns a copy of the current figures.
+func (s *stats) snapshot() dashboardStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := s.current
+	snapshot.Workers = append([]workerStats{}, s.current.Workers...)
+	snapshot.Replicas = append([]replicaStats{}, s.current.Replicas...)
+	// Nothing is being generated if no turn has finished for a while.
+	if time.Since(s.lastTurnAt) > 2*rateWindow {
+		snapshot.GensPerSec = 0
+	}
+	return snapshot
+}
+
+// serveDashboard serves the dashboard on the given address, e.g. ":8081".
+func serveDashboard(address string, s *stats) {
+	fmt.Printf("Dashboard on http://%s/\n", address)
+	if err := http.ListenAndServe(address, dashboardHandler(s)); err != nil {
+		fmt.Println("Error serving dashboard:", err)
+	}
+}
+
+// dashboardHandler serves the dashboard page and the JSON it polls.
+func dashboardHandler(s *stats) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, dashboardPage)
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.snapshot())
+	})
+	return mux
+}
+
+// dashboardPage polls /stats twice a second and renders it. It has no external dependencies.
+const dashboardPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Game of Life broker</title>
+<style>
+body { font-family: sans-serif; margin: 2em; background: #111; color: #eee; }
+.figures { display: flex; gap: 2em; margin-bottom: 2em; }
+.figure { background: #222; padding: 1em 1.5em; border-radius: 6px; }
+.figure .value { font-size: 2em; font-variant-numeric: tabular-nums; }
+.figure .label { color: #999; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 0.4em 1em; text-align: right; font-variant-numeric: tabular-nums; }
+th:first-child, td:first-child { text-align: left; }
+th { color: #999; border-bottom: 1px solid #444; }
+</style>
+</head>
+<body>
+<h1>Game of Life broker <span id="state"></span></h1>
+<div class="figures">
+<div class="figure"><div class="value" id="turn">-</div><div class="label">Turn</div></div>
+<div class="figure"><div class="value" id="rate">-</div><div class="label">Generations/sec</div></div>
+<div class="figure"><div class="value" id="alive">-</div><div class="label">Alive cells</div></div>
+</div>
+<table>
+<thead><tr><th>Worker</th><th>Latency (ms)</th><th>Rows</th><th>Flipped cells</th><th>Slices computed</th></tr></thead>
+<tbody id="workers"></tbody>
+</table>
+<table id="replicaTable" hidden>
+<thead><tr><th>Checkpoint replica</th><th>Turn</th><th>Copied</th><th>Failures</th><th>Latest error</th></tr></thead>
+<tbody id="replicas"></tbody>
+</table>
+<script>
+function cell(row, text) {
+	var td = document.createElement("td");
+	td.textContent = text;
+	row.appendChild(td);
+}
+function refresh() {
+	fetch("/stats").then(function (r) { return r.json(); }).then(function (s) {
+		document.getElementById("turn").textContent = s.Turn;
+		document.getElementById("rate").textContent = s.GensPerSec.toFixed(1);
+		document.getElementById("alive").textContent = s.Alive;
+		document.getElementById("state").textContent = s.Paused ? "(paused)" : "";
+		var body = document.getElementById("workers");
+		body.innerHTML = "";
+		(s.Workers || []).forEach(function (w) {
+			var row = document.createElement("tr");
+			cell(row, w.Address);
+			cell(row, w.LatencyMs.toFixed(2));
+			cell(row, w.Rows);
+			cell(row, w.Flipped);
+			cell(row, w.Calls);
+			body.appendChild(row);
+		});
+		var replicas = document.getElementById("replicas");
+		replicas.innerHTML = "";
+		document.getElementById("replicaTable").hidden = !(s.Replicas || []).length;
+		(s.Replicas || []).forEach(function (r) {
+			var row = document.createElement("tr");
+			cell(row, r.Destination);
+			cell(row, r.Turn < 0 ? "-" : r.Turn);
+			cell(row, r.Turn < 0 ? "never" : new Date(r.Copied).toLocaleTimeString());
+			cell(row, r.Failures);
+			cell(row, r.Error);
+			replicas.appendChild(row);
+		});
+	}).catch(function () {
+		document.getElementById("state").textContent = "(unreachable)";
+	});
+}
+refresh();
+setInterval(refresh, 500);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,31 @@
+package golbroker
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestDashboardStats checks the figures recorded after each turn are served as JSON.
+func TestDashboardStats(t *testing.T) {
+	s := &stats{}
+	s.setWorkers([]string{"localhost:8040", "localhost:8041"})
+	s.recordTurn(1, 10, []time.Duration{2 * time.Millisecond, 4 * time.Millisecond}, []int{8, 8}, []int{3, 5})
+	s.setPaused(true)
+
+	recorder := httptest.NewRecorder()
+	dashboardHandler(s).ServeHTTP(recorder, httptest.NewRequest("GET", "/stats", nil))
+	var got dashboardStats
+	if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatalf("couldn't decode /stats: %v", err)
+	}
+
+	if got.Turn != 1 || got.Alive != 10 || !got.Paused {
+		t.Errorf("got turn %d, %d alive and paused %v, expected turn 1, 10 alive and paused", got.Turn, got.Alive, got.Paused)
+	}
+	if len(got.Workers) != 2 || got.Workers[1].Address != "localhost:8041" || got.Workers[1].LatencyMs != 4 ||
+		got.Workers[1].Flipped != 5 || got.Workers[1].Calls != 1 {
+		t.Errorf("unexpected worker stats %+v", got.Workers)
+	}
+}
@@ -0,0 +1,127 @@
+package golbroker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestDeltaCheckpoints saves a slowly changing run every few turns with a keyframe every third checkpoint, and
+// checks the checkpoints in between only write their changes beside the keyframe, and that a fresh broker restored
+// from the keyframe and its delta gets the latest generation.
+func TestDeltaCheckpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "delta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "broker.checkpoint")
+
+	const size = 64
+	b := &Broker{KeyframeEvery: 3}
+	world := gliderWorld(size)
+	for save := 0; save < 5; save++ {
+		// Another cell is born before each save, so every checkpoint differs from the last.
+		world = world.Clone()
+		world.Set(save, size-1, util.Alive)
+		b.Mu.Lock()
+		b.World, b.Turn = world, 10*save
+		b.publish()
+		b.Mu.Unlock()
+		keyframe, _ := ioutil.ReadFile(path)
+		if _, err := b.saveCheckpoint(path); err != nil {
+			t.Fatal(err)
+		}
+
+		saved, _ := ioutil.ReadFile(path)
+		delta, err := ioutil.ReadFile(deltaPath(path))
+		if whole := save%3 == 0; whole {
+			if !os.IsNotExist(err) {
+				t.Errorf("checkpoint %d was saved whole, but the delta of the keyframe before it was left behind", save)
+			}
+		} else {
+			if err != nil {
+				t.Fatalf("checkpoint %d wasn't saved as a delta: %v", save, err)
+			}
+			if string(saved) != string(keyframe) {
+				t.Errorf("checkpoint %d was saved as a delta, but its keyframe was written too", save)
+			}
+			if len(delta)*10 > len(saved) {
+				t.Errorf("the delta of checkpoint %d took %d bytes, and its keyframe %d", save, len(delta), len(saved))
+			}
+		}
+
+		restarted := &Broker{}
+		turn, err := restarted.loadCheckpoint(path)
+		if err != nil {
+			t.Fatalf("checkpoint %d couldn't be loaded: %v", save, err)
+		}
+		if turn != 10*save || !restarted.World.Equal(world) {
+			t.Fatalf("checkpoint %d loaded as turn %d, expected turn %d with the world saved", save, turn, 10*save)
+		}
+	}
+}
+
+// TestStaleDelta checks a delta left behind by a crash just after a newer keyframe was saved is ignored, and that
+// a checkpoint whose delta would be no smaller than the world is saved whole.
+func TestStaleDelta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "delta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "broker.checkpoint")
+
+	const size = 16
+	b := &Broker{KeyframeEvery: 10}
+	publish := func(world [][]byte, turn int) {
+		b.Mu.Lock()
+		b.World, b.Turn = gliderWorld(size), turn
+		for y, row := range world {
+			copy(b.World.Row(y), row)
+		}
+		b.publish()
+		b.Mu.Unlock()
+	}
+	publish(nil, 1)
+	if _, err := b.saveCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	publish(nil, 2)
+	if _, err := b.saveCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := ioutil.ReadFile(deltaPath(path))
+	if err != nil {
+		t.Fatalf("the second checkpoint wasn't saved as a delta: %v", err)
+	}
+
+	// Every cell changes, so the delta would be bigger than the world saved whole.
+	full := make([][]byte, size)
+	for y := range full {
+		full[y] = make([]byte, size)
+		for x := range full[y] {
+			if b.current().World.At(x, y) != util.Alive {
+				full[y][x] = util.Alive
+			}
+		}
+	}
+	publish(full, 3)
+	if _, err := b.saveCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(deltaPath(path)); !os.IsNotExist(err) {
+		t.Fatal("a checkpoint changing every cell was saved as a delta")
+	}
+
+	if err := ioutil.WriteFile(deltaPath(path), stale, 0644); err != nil {
+		t.Fatal(err)
+	}
+	restarted := &Broker{}
+	if turn, err := restarted.loadCheckpoint(path); err != nil || turn != 3 {
+		t.Fatalf("loaded turn %d (%v) beside a stale delta, expected the keyframe's turn 3", turn, err)
+	}
+}
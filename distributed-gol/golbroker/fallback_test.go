@@ -0,0 +1,212 @@
+package golbroker
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/rpc"
+	"sync"
+	"testing"
+	"time"
+	"uk.ac.bris.cs/gameoflife/chaos"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/memnet"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// gliderWorld returns a size x size world holding a glider, which moves one cell down and to the right
+// every four turns, wrapping around the edges, so after 4*size turns the world is back where it started.
+func gliderWorld(size int) slab.World {
+	world := slab.New(size, size)
+	glider := []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	for _, cell := range glider {
+		world.Set(cell.X, cell.Y, util.Alive)
+	}
+	return world
+}
+
+// evolveGliderRoundTrip sends the glider all the way round the world through the broker and checks it comes back
+// unchanged. The run must finish within the timeout, so a broker stuck waiting for a slice fails rather than hangs.
+func evolveGliderRoundTrip(t *testing.T, b *Broker, size int) {
+	t.Helper()
+	epoch := acquire(t, b)
+	world := gliderWorld(size)
+	turns := 4 * size
+	res := &stubs.EvolveResponse{}
+	req := stubs.EvolveWorldRequest{World: world, Turn: turns, ImageWidth: size, ImageHeight: size, Epoch: epoch}
+
+	done := make(chan error)
+	go func() { done <- b.EvolveWorld(req, res) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("EvolveWorld never finished")
+	}
+
+	if res.Turn != turns {
+		t.Errorf("evolved %d turns, expected %d", res.Turn, turns)
+	}
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if res.World.At(j, i) != world.At(j, i) {
+				t.Fatalf("cell (%d, %d) differs after the glider went all the way round", j, i)
+			}
+		}
+	}
+}
+
+// TestLocalFallback checks the broker still evolves the world correctly when it has no workers.
+func TestLocalFallback(t *testing.T) {
+	evolveGliderRoundTrip(t, &Broker{Lease: time.Minute}, 16)
+}
+
+// lifeWorker is an in-process stand-in for a worker that computes its slice with the shared kernel.
+type lifeWorker struct{}
+
+func (w *lifeWorker) CalculateWorld(req *stubs.WorldReq, res *stubs.WorldRes) (err error) {
+	res.World, err = kernel.Next(context.Background(), req.World, req.StartRow, req.EndRow, localChunkSize, kernel.Defaults)
+	res.Job, res.Turn, res.StartRow, res.EndRow = req.Job, req.Turn, req.StartRow, req.EndRow
+	res.Seal()
+	return
+}
+
+// unreachableWorker returns a client for a worker that has gone away: its connection has been closed
+// and nothing listens on its address any more, so every call fails.
+func unreachableWorker(t *testing.T) *workerConn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := rpc.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	listener.Close()
+	return newWorkerConn(listener.Addr().String(), client)
+}
+
+// TestUnreachableWorker checks a worker that fails mid-run has its slice computed by the broker instead,
+// rather than leaving the broker waiting for it forever.
+func TestUnreachableWorker(t *testing.T) {
+	healthy, stop := serveWorker(t, &lifeWorker{})
+	defer stop()
+
+	b := &Broker{Workers: []*workerConn{healthy, unreachableWorker(t)}, Lease: time.Minute}
+	evolveGliderRoundTrip(t, b, 16)
+}
+
+// serveWorker serves an in-process stand-in for a worker, returning a client for it and a function that stops it.
+func serveWorker(t *testing.T, ops interface{}) (*workerConn, func()) {
+	address, stop, err := memnet.ServeRPC("WorldOps", ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := dialWorker(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, func() {
+		client.Close()
+		stop()
+	}
+}
+
+// staleWorker is an in-process stand-in for a straggling worker: after its first slice it answers each request
+// with its answer to the one before, tagged as such, as a late reply from the previous turn would look.
+type staleWorker struct {
+	mu       sync.Mutex
+	previous *stubs.WorldReq
+}
+
+func (w *staleWorker) CalculateWorld(req *stubs.WorldReq, res *stubs.WorldRes) (err error) {
+	w.mu.Lock()
+	answer := req
+	if w.previous != nil {
+		answer = w.previous
+	}
+	w.previous = req
+	w.mu.Unlock()
+	return (&lifeWorker{}).CalculateWorld(answer, res)
+}
+
+// TestStaleSlice checks slices answering an earlier turn are discarded rather than committed, so the board
+// stays correct alongside a straggler.
+func TestStaleSlice(t *testing.T) {
+	healthy, stopHealthy := serveWorker(t, &lifeWorker{})
+	defer stopHealthy()
+	stale, stopStale := serveWorker(t, &staleWorker{})
+	defer stopStale()
+
+	b := &Broker{Workers: []*workerConn{healthy, stale}, Lease: time.Minute}
+	evolveGliderRoundTrip(t, b, 16)
+}
+
+// delayedWorker is an in-process stand-in for a worker that takes a random time over each slice, so slices
+// arrive in any order.
+type delayedWorker struct {
+	lifeWorker
+}
+
+func (w *delayedWorker) CalculateWorld(req *stubs.WorldReq, res *stubs.WorldRes) (err error) {
+	time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+	return w.lifeWorker.CalculateWorld(req, res)
+}
+
+// TestDelayedWorkers checks the world is assembled correctly however late each worker's slice arrives.
+func TestDelayedWorkers(t *testing.T) {
+	var workers []*workerConn
+	for i := 0; i < 4; i++ {
+		client, stop := serveWorker(t, &delayedWorker{})
+		defer stop()
+		workers = append(workers, client)
+	}
+	evolveGliderRoundTrip(t, &Broker{Workers: workers, Lease: time.Minute}, 16)
+}
+
+// TestChaos checks a run whose connections to its workers are slowed and dropped by -chaos still evolves the world
+// correctly, the broker redialling the workers or computing their slices itself.
+func TestChaos(t *testing.T) {
+	faults = chaos.New(chaos.Config{Seed: 1, Latency: time.Millisecond, Drop: 0.2}, "broker")
+	defer func() { faults = nil }()
+	var workers []*workerConn
+	for i := 0; i < 4; i++ {
+		client, stop := serveWorker(t, &lifeWorker{})
+		defer stop()
+		workers = append(workers, client)
+	}
+	evolveGliderRoundTrip(t, &Broker{Workers: workers, Lease: time.Minute}, 16)
+}
+
+// TestBelongsTo checks only a slice tagged with the run, turn and rows being committed belongs to the turn.
+func TestBelongsTo(t *testing.T) {
+	assignment := stubs.Assignment{StartRow: 4, EndRow: 6}
+	slice := sliceResult{World: slab.New(2, 2), Job: 1, Turn: 7, StartRow: 4}
+	if !slice.belongsTo(1, 7, assignment) {
+		t.Error("slice tagged for the turn doesn't belong to it")
+	}
+	tests := []struct {
+		job, turn int
+		rows      stubs.Assignment
+	}{
+		{2, 7, assignment},
+		{1, 6, assignment},
+		{1, 8, assignment},
+		{1, 7, stubs.Assignment{StartRow: 6, EndRow: 8}},
+		{1, 7, stubs.Assignment{StartRow: 4, EndRow: 7}},
+	}
+	for _, test := range tests {
+		if slice.belongsTo(test.job, test.turn, test.rows) {
+			t.Errorf("slice of run 1 turn 7 rows 4-6 belongs to run %d turn %d rows %d-%d", test.job, test.turn, test.rows.StartRow, test.rows.EndRow)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package golbroker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/health"
+)
+
+// readiness returns the checks the broker's /readyz makes: its RPC listener is up, it has workers to split turns
+// between unless -engine=local, and a run in progress has finished a turn within staleAfter.
+func (b *Broker) readiness(listening *health.Flag, staleAfter time.Duration) []health.Check {
+	return []health.Check{
+		listening.Check("listener", "not yet accepting RPC connections"),
+		{Name: "workers", Run: func() error {
+			if !b.Local && len(b.workers()) == 0 {
+				return errors.New("no workers connected, so turns are computed on the broker")
+			}
+			return nil
+		}},
+		{Name: "turns", Run: func() error {
+			if err := b.Stats.stalled(staleAfter); err != nil {
+				return fmt.Errorf("%v, more than -staleAfter %v", err, staleAfter)
+			}
+			return nil
+		}},
+	}
+}
@@ -0,0 +1,60 @@
+package golbroker
+
+import (
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/health"
+)
+
+// failing returns the names of the checks that fail.
+func failing(checks []health.Check) []string {
+	var names []string
+	for _, c := range checks {
+		if c.Run() != nil {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// TestBrokerReadiness checks the broker is only ready once listening with workers, and stops being ready when a
+// run goes too long without a turn unless it is paused.
+func TestBrokerReadiness(t *testing.T) {
+	b := &Broker{}
+	var listening health.Flag
+	checks := b.readiness(&listening, 50*time.Millisecond)
+	if names := failing(checks); len(names) != 2 || names[0] != "listener" || names[1] != "workers" {
+		t.Errorf("got failing checks %v, expected listener and workers", names)
+	}
+
+	listening.Set()
+	b.Workers = []*workerConn{nil}
+	if names := failing(checks); len(names) != 0 {
+		t.Errorf("got failing checks %v, expected none", names)
+	}
+
+	b.Stats.setRunning(true)
+	b.Stats.recordTurn(1, 0, nil, nil, nil)
+	if names := failing(checks); len(names) != 0 {
+		t.Errorf("got failing checks %v just after a turn, expected none", names)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if names := failing(checks); len(names) != 1 || names[0] != "turns" {
+		t.Errorf("got failing checks %v with no recent turn, expected turns", names)
+	}
+	b.Stats.setPaused(true)
+	if names := failing(checks); len(names) != 0 {
+		t.Errorf("got failing checks %v while paused, expected none", names)
+	}
+	b.Stats.setPaused(false)
+	b.Stats.setRunning(false)
+	if names := failing(checks); len(names) != 0 {
+		t.Errorf("got failing checks %v with no run, expected none", names)
+	}
+
+	b.Workers, b.Local = nil, true
+	if names := failing(checks); len(names) != 0 {
+		t.Errorf("got failing checks %v computing locally, expected none", names)
+	}
+}
@@ -0,0 +1,246 @@
+package golbroker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"uk.ac.bris.cs/gameoflife/storage"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// jobRetry is how often a queued job tries to take control of the broker while a client holds it.
+const jobRetry = time.Second
+
+// jobQueue holds the jobs submitted to the broker, which runs them one at a time, in the order they were
+// submitted, whenever no client controls it, so a backlog of experiments can be left running overnight.
+type jobQueue struct {
+	mu   sync.Mutex
+	jobs []*queuedJob
+	wake chan struct{} // Signalled when a job is submitted.
+}
+
+// queuedJob is a job and how far it has got.
+type queuedJob struct {
+	spec   stubs.JobSpec // Dropped once the job has started, so its starting world can be freed.
+	status stubs.JobStatus
+}
+
+// SubmitJob adds a run to the end of the job queue and returns its ID, which its status can be fetched with.
+func (b *Broker) SubmitJob(req stubs.SubmitJobRequest, res *stubs.SubmitJobResponse) (err error) {
+	spec := req.Job
+	if spec.World.Width == 0 || spec.World.Height == 0 || !spec.World.Valid() {
+		return errors.New("the job has no starting world")
+	}
+	if spec.Turns < 0 {
+		return fmt.Errorf("the job asks for %d turns", spec.Turns)
+	}
+
+	q := &b.Jobs
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, j := range q.jobs {
+		if j.status.State == stubs.JobQueued || j.status.State == stubs.JobRunning {
+			res.Position++
+		}
+	}
+	res.ID = len(q.jobs) + 1
+	q.jobs = append(q.jobs, &queuedJob{spec: spec, status: stubs.JobStatus{
+		ID:        res.ID,
+		Name:      spec.Name,
+		State:     stubs.JobQueued,
+		Width:     spec.World.Width,
+		Height:    spec.World.Height,
+		Turns:     spec.Turns,
+		Seed:      spec.Seed,
+		Submitted: time.Now(),
+		Labels:    spec.Labels,
+	}})
+	select {
+	case q.wakeup() <- struct{}{}:
+	default:
+	}
+	return
+}
+
+// wakeup returns the channel the runner waits on for new jobs. The caller must hold mu.
+func (q *jobQueue) wakeup() chan struct{} {
+	if q.wake == nil {
+		q.wake = make(chan struct{}, 1)
+	}
+	return q.wake
+}
+
+// ListJobs returns the status of every job submitted since the broker started, oldest first.
+func (b *Broker) ListJobs(req stubs.Empty, res *stubs.ListJobsResponse) (err error) {
+	q := &b.Jobs
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, j := range q.jobs {
+		res.Jobs = append(res.Jobs, b.jobStatus(j))
+	}
+	return
+}
+
+// GetJobStatus returns the status of one job.
+func (b *Broker) GetJobStatus(req stubs.JobStatusRequest, res *stubs.JobStatusResponse) (err error) {
+	q := &b.Jobs
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if req.ID < 1 || req.ID > len(q.jobs) {
+		return fmt.Errorf("there is no job %d", req.ID)
+	}
+	res.Job = b.jobStatus(q.jobs[req.ID-1])
+	return
+}
+
+// jobStatus returns the status of a job, with the turn a running job has reached. The caller must hold the
+// queue's mu.
+func (b *Broker) jobStatus(j *queuedJob) stubs.JobStatus {
+	status := j.status
+	if status.State == stubs.JobRunning {
+		status.Turn = b.current().Turn
+	}
+	return status
+}
+
+// next returns the oldest job still queued, waiting for one to be submitted if there are none.
+func (q *jobQueue) next() *queuedJob {
+	for {
+		q.mu.Lock()
+		wake := q.wakeup()
+		for _, j := range q.jobs {
+			if j.status.State == stubs.JobQueued {
+				q.mu.Unlock()
+				return j
+			}
+		}
+		q.mu.Unlock()
+		<-wake
+	}
+}
+
+// update changes a job's status under the queue's mu.
+func (q *jobQueue) update(j *queuedJob, change func(status *stubs.JobStatus)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change(&j.status)
+}
+
+// runJobs runs queued jobs one after another for as long as the broker runs.
+func (b *Broker) runJobs() {
+	for {
+		b.runJob(b.Jobs.next())
+	}
+}
+
+// runJob takes control of the broker as a client would, waiting for any client in control to finish, and
+// evolves the job's world, keeping the lease alive and checkpointing the run every JobCheckpoint as it goes.
+// The final world is saved as an image in the job's directory under JobDir.
+func (b *Broker) runJob(j *queuedJob) {
+	var epoch int
+	for {
+		acquired := &stubs.AcquireResponse{}
+		if err := b.Acquire(stubs.Empty{}, acquired); err == nil {
+			epoch = acquired.Epoch
+			break
+		}
+		time.Sleep(jobRetry)
+	}
+	// A job always starts from its own world, never from where a client left off.
+	b.Mu.Lock()
+	b.Continue = false
+	b.Mu.Unlock()
+
+	dir := filepath.Join(b.JobDir, fmt.Sprintf("job-%d", j.status.ID))
+	checkpoint := filepath.Join(dir, "checkpoint")
+	var spec stubs.JobSpec
+	b.Jobs.update(j, func(status *stubs.JobStatus) {
+		spec, j.spec = j.spec, stubs.JobSpec{}
+		status.State = stubs.JobRunning
+		status.Started = time.Now()
+		status.Checkpoint = checkpoint
+	})
+	fmt.Printf("Starting job %d %q: %d turns of a %dx%d world\n", j.status.ID, spec.Name, spec.Turns, j.status.Width, j.status.Height)
+
+	err := os.MkdirAll(dir, os.ModePerm)
+	res := &stubs.EvolveResponse{}
+	if err == nil {
+		stop := make(chan struct{})
+		go b.tendJob(epoch, checkpoint, stop)
+		req := stubs.EvolveWorldRequest{
+			World:       spec.World,
+			Turn:        spec.Turns,
+			ImageWidth:  j.status.Width,
+			ImageHeight: j.status.Height,
+			Epoch:       epoch,
+			Rule:        spec.Rule,
+			Edge:        spec.Edge,
+			Seed:        spec.Seed,
+			Labels:      spec.Labels,
+		}
+		err = b.EvolveWorld(req, res)
+		close(stop)
+	} else {
+		b.release() // The job never started, so the lease isn't given up by EvolveWorld.
+	}
+	if err != nil {
+		fmt.Printf("Job %d failed: %v\n", j.status.ID, err)
+		b.Jobs.update(j, func(status *stubs.JobStatus) {
+			status.State = stubs.JobFailed
+			status.Turn = b.current().Turn
+			status.Error = err.Error()
+			status.Finished = time.Now()
+		})
+		return
+	}
+
+	// Keep the final world, both as an image and as a checkpoint the broker can be resumed from.
+	output := filepath.Join(dir, fmt.Sprintf("%dx%dx%d.pgm", j.status.Width, j.status.Height, res.Turn))
+	err = storage.Put(output, util.FormatPgm(res.World.Rows()))
+	if _, checkpointErr := b.saveCheckpoint(checkpoint); err == nil {
+		err = checkpointErr
+	}
+	b.Mu.Lock()
+	period := b.Period
+	b.Mu.Unlock()
+	b.Jobs.update(j, func(status *stubs.JobStatus) {
+		status.State = stubs.JobDone
+		status.Turn = res.Turn
+		status.Alive = countAlive(res.World)
+		status.Period = period
+		status.Output = output
+		status.Finished = time.Now()
+		if err != nil {
+			status.Error = fmt.Sprintf("couldn't save the result: %v", err)
+		}
+	})
+	fmt.Printf("Finished job %d at turn %d, saved to %s\n", j.status.ID, res.Turn, output)
+}
+
+// tendJob renews a running job's lease and saves a checkpoint of it every JobCheckpoint, until stop is closed.
+func (b *Broker) tendJob(epoch int, checkpoint string, stop <-chan struct{}) {
+	heartbeat := time.NewTicker(b.Lease / 3)
+	defer heartbeat.Stop()
+	var save <-chan time.Time
+	if b.JobCheckpoint > 0 {
+		ticker := time.NewTicker(b.JobCheckpoint)
+		defer ticker.Stop()
+		save = ticker.C
+	}
+	for {
+		select {
+		case <-heartbeat.C:
+			b.Heartbeat(stubs.ControlRequest{Epoch: epoch}, &stubs.Empty{})
+		case <-save:
+			if _, err := b.saveCheckpoint(checkpoint); err != nil {
+				fmt.Printf("Warning: couldn't checkpoint the job: %v\n", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
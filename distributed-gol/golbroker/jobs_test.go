@@ -0,0 +1,94 @@
+package golbroker
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// waitForJobs polls the broker until none of its jobs are queued or running, failing the test if that takes too long.
+func waitForJobs(t *testing.T, b *Broker) []stubs.JobStatus {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		res := &stubs.ListJobsResponse{}
+		if err := b.ListJobs(stubs.Empty{}, res); err != nil {
+			t.Fatal(err)
+		}
+		finished := true
+		for _, job := range res.Jobs {
+			finished = finished && (job.State == stubs.JobDone || job.State == stubs.JobFailed)
+		}
+		if finished {
+			return res.Jobs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("the jobs never finished")
+	return nil
+}
+
+// TestJobQueue checks jobs wait for the client in control to finish, then run one after another in the order
+// they were submitted, each saving its final world.
+func TestJobQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b := &Broker{Lease: time.Minute, JobDir: dir}
+	go b.runJobs()
+
+	// A client in control holds the jobs back until it releases the broker.
+	acquire(t, b)
+	for _, turns := range []int{32, 3} {
+		res := &stubs.SubmitJobResponse{}
+		if err := b.SubmitJob(stubs.SubmitJobRequest{Job: stubs.JobSpec{World: gliderWorld(8), Turns: turns}}, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	status := &stubs.JobStatusResponse{}
+	if err := b.GetJobStatus(stubs.JobStatusRequest{ID: 1}, status); err != nil || status.Job.State != stubs.JobQueued {
+		t.Fatalf("job 1 is %q (%v) while a client controls the broker, expected it to stay queued", status.Job.State, err)
+	}
+	b.release()
+
+	jobs := waitForJobs(t, b)
+	if len(jobs) != 2 {
+		t.Fatalf("got %d jobs, expected 2", len(jobs))
+	}
+	for _, job := range jobs {
+		if job.State != stubs.JobDone || job.Turn != job.Turns || job.Error != "" {
+			t.Fatalf("job %d is %s at turn %d of %d (%s), expected it done", job.ID, job.State, job.Turn, job.Turns, job.Error)
+		}
+	}
+	if !jobs[0].Finished.Before(jobs[1].Started) && !jobs[0].Finished.Equal(jobs[1].Started) {
+		t.Error("job 2 started before job 1 had finished")
+	}
+
+	// The glider is back where it started after 32 turns of an 8x8 torus.
+	data, err := ioutil.ReadFile(jobs[0].Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, cells, err := util.ParsePgm(data, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cells, gliderWorld(8).Cells) || jobs[0].Alive != 5 {
+		t.Errorf("job 1 saved a world with %d alive cells, expected the glider where it started", jobs[0].Alive)
+	}
+	if _, err := os.Stat(jobs[1].Checkpoint); err != nil {
+		t.Errorf("job 2 left no checkpoint: %v", err)
+	}
+
+	if err := b.GetJobStatus(stubs.JobStatusRequest{ID: 3}, status); err == nil {
+		t.Error("expected an error for a job that was never submitted")
+	}
+}
@@ -0,0 +1,99 @@
+package golbroker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestRunLabels checks a run's labels are saved in its checkpoint, listed with it, handed back with the run to
+// continue from a restarted broker, and kept when the continuing client gives none of its own.
+func TestRunLabels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "broker.checkpoint")
+	labels := map[string]string{"experiment": "gliders", "author": "kim"}
+
+	b := &Broker{Lease: time.Minute}
+	epoch := acquire(t, b)
+	req := stubs.EvolveWorldRequest{World: gliderWorld(8), Turn: 4, ImageWidth: 8, ImageHeight: 8, Epoch: epoch, Labels: labels}
+	if err := b.EvolveWorld(req, &stubs.EvolveResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.saveCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := &Broker{Lease: time.Minute}
+	if _, err := restarted.loadCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	checkpoints := &stubs.ListCheckpointsResponse{}
+	if err := restarted.ListCheckpoints(stubs.Empty{}, checkpoints); err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints.Checkpoints) != 1 || util.Labels(checkpoints.Checkpoints[0].Labels).String() != "author=kim experiment=gliders" {
+		t.Errorf("listed checkpoints %+v, expected one labelled author=kim experiment=gliders", checkpoints.Checkpoints)
+	}
+	continued := &stubs.GetContinueResponse{}
+	if err := restarted.GetContinue(stubs.Empty{}, continued); err != nil {
+		t.Fatal(err)
+	}
+	if util.Labels(continued.Labels).String() != "author=kim experiment=gliders" {
+		t.Errorf("the run to continue has labels %v, expected author=kim experiment=gliders", continued.Labels)
+	}
+
+	// Continuing without labels keeps the run's, and new ones replace them.
+	epoch = acquire(t, restarted)
+	req = stubs.EvolveWorldRequest{Turn: 8, ImageWidth: 8, ImageHeight: 8, Epoch: epoch}
+	if err := restarted.EvolveWorld(req, &stubs.EvolveResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := util.Labels(restarted.current().Labels).String(); got != "author=kim experiment=gliders" {
+		t.Errorf("continuing without labels left %q, expected the run's own", got)
+	}
+	epoch = acquire(t, restarted)
+	req = stubs.EvolveWorldRequest{Turn: 12, ImageWidth: 8, ImageHeight: 8, Epoch: epoch, Labels: map[string]string{"experiment": "guns"}}
+	if err := restarted.EvolveWorld(req, &stubs.EvolveResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := util.Labels(restarted.current().Labels).String(); got != "experiment=guns" {
+		t.Errorf("continuing with new labels left %q, expected experiment=guns", got)
+	}
+}
+
+// TestJobLabels checks a job's labels are kept with its status and saved in its checkpoint.
+func TestJobLabels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b := &Broker{Lease: time.Minute, JobDir: dir}
+	go b.runJobs()
+
+	spec := stubs.JobSpec{World: gliderWorld(8), Turns: 3, Labels: map[string]string{"notes": "overnight run"}}
+	if err := b.SubmitJob(stubs.SubmitJobRequest{Job: spec}, &stubs.SubmitJobResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	jobs := waitForJobs(t, b)
+	if len(jobs) != 1 || jobs[0].Labels["notes"] != "overnight run" {
+		t.Fatalf("got jobs %+v, expected one labelled notes=overnight run", jobs)
+	}
+
+	restarted := &Broker{Lease: time.Minute}
+	if _, err := restarted.loadCheckpoint(jobs[0].Checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.Labels["notes"] != "overnight run" {
+		t.Errorf("the job's checkpoint has labels %v, expected notes=overnight run", restarted.Labels)
+	}
+}
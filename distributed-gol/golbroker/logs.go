@@ -0,0 +1,46 @@
+package golbroker
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// workerLogs writes the log lines workers send to the broker to one combined log, each tagged with the worker
+// it came from. It has its own mutex, separate from the broker's, so workers can still log while the broker is paused.
+type workerLogs struct {
+	mu  sync.Mutex
+	out io.Writer // Combined log, or nil to discard the lines.
+}
+
+// logTimeFormat is how the time a worker wrote each line is shown in the combined log.
+const logTimeFormat = "2006/01/02 15:04:05.000"
+
+// write adds a worker's lines to the combined log. Lines from one batch are kept together.
+func (l *workerLogs) write(worker string, lines []stubs.LogLine) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.out == nil {
+		return nil
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(l.out, "%s [%s] %s\n", line.Time.Format(logTimeFormat), worker, line.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toStdout reports whether the combined log is written to standard output, where the broker's own messages go.
+func (l *workerLogs) toStdout() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.out == os.Stdout
+}
+
+// Log receives log lines from a worker started with -brokerAddr and writes them to the combined worker log.
+func (b *Broker) Log(req stubs.LogRequest, res *stubs.Empty) (err error) {
+	return b.Logs.write(req.Worker, req.Lines)
+}
@@ -0,0 +1,26 @@
+package golbroker
+
+import (
+	"bytes"
+	"testing"
+	"time"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestWorkerLogs checks the lines workers send are written to the combined log with their time and worker.
+func TestWorkerLogs(t *testing.T) {
+	var out bytes.Buffer
+	b := &Broker{}
+	b.Logs.out = &out
+
+	at := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	req := stubs.LogRequest{Worker: "node-3:8042", Lines: []stubs.LogLine{{Time: at, Text: "Listening on port 8042"}}}
+	if err := b.Log(req, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "2024/03/01 12:30:00.000 [node-3:8042] Listening on port 8042\n"
+	if out.String() != expected {
+		t.Errorf("combined log is %q, expected %q", out.String(), expected)
+	}
+}
@@ -0,0 +1,45 @@
+package golbroker
+
+import (
+	"context"
+	"errors"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/pattern"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/tracing"
+)
+
+// MatchPattern returns where a pattern appears in the latest published generation, searching the packed board
+// rather than comparing a cell at a time. Like the other read RPCs it needs no epoch and works while paused.
+func (b *Broker) MatchPattern(req stubs.MatchPatternRequest, res *stubs.MatchPatternResponse) (err error) {
+	_, span := tracing.Start(context.Background(), "MatchPattern")
+	defer span.End()
+	if req.Pattern.Width < 1 || req.Pattern.Height < 1 || !req.Pattern.Valid() {
+		return errors.New("the pattern has no cells, or fewer than its size")
+	}
+	patterns := []slab.World{req.Pattern}
+	if req.AnyOrientation {
+		patterns = pattern.Orientations(req.Pattern)
+	}
+
+	snapshot := b.current()
+	// Patterns only straddle the edges of a world that wraps around them.
+	edge, _ := kernel.ParseEdge(snapshot.Edge)
+	board := pattern.Pack(snapshot.World, edge == kernel.Torus)
+	res.Turn = snapshot.Turn
+	for orientation, p := range patterns {
+		limit := 0
+		if req.Limit > 0 {
+			limit = req.Limit - len(res.Matches)
+		}
+		for _, cell := range board.Find(p, limit) {
+			res.Matches = append(res.Matches, stubs.PatternMatch{Cell: cell, Orientation: orientation})
+		}
+		if req.Limit > 0 && len(res.Matches) >= req.Limit {
+			break
+		}
+	}
+	return
+}
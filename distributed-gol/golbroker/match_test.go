@@ -0,0 +1,55 @@
+package golbroker
+
+import (
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/pattern"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestMatchPattern checks a glider and its mirror image are found in the published world, the mirror image only
+// when asked for any orientation, and that the limit and an empty pattern are respected.
+func TestMatchPattern(t *testing.T) {
+	glider, _ := pattern.Parse(".O./..O/OOO")
+	mirrored, _ := pattern.Parse(".O./O../OOO")
+	world := gliderWorld(16)
+	for y := 0; y < mirrored.Height; y++ {
+		for x := 0; x < mirrored.Width; x++ {
+			world.Set(8+x, 8+y, mirrored.At(x, y))
+		}
+	}
+	b := &Broker{World: world, Turn: 7}
+	b.publish()
+
+	match := func(req stubs.MatchPatternRequest) []stubs.PatternMatch {
+		t.Helper()
+		res := &stubs.MatchPatternResponse{}
+		if err := b.MatchPattern(req, res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Turn != 7 {
+			t.Errorf("matched against turn %d, expected 7", res.Turn)
+		}
+		return res.Matches
+	}
+
+	if got := match(stubs.MatchPatternRequest{Pattern: glider}); !reflect.DeepEqual(got, []stubs.PatternMatch{{Cell: util.Cell{}}}) {
+		t.Errorf("found %v, expected only the glider at (0, 0)", got)
+	}
+	got := match(stubs.MatchPatternRequest{Pattern: glider, AnyOrientation: true})
+	if len(got) != 2 || got[0] != (stubs.PatternMatch{Cell: util.Cell{}}) || got[1].Cell != (util.Cell{X: 8, Y: 8}) {
+		t.Fatalf("found %v in any orientation, expected the glider at (0, 0) and its mirror image at (8, 8)", got)
+	}
+	if o := pattern.Orientations(glider)[got[1].Orientation]; !o.Equal(mirrored) {
+		t.Errorf("orientation %d of the glider is %v, not its mirror image", got[1].Orientation, o.Rows())
+	}
+	if got := match(stubs.MatchPatternRequest{Pattern: glider, AnyOrientation: true, Limit: 1}); len(got) != 1 {
+		t.Errorf("found %v with a limit of 1", got)
+	}
+	if err := b.MatchPattern(stubs.MatchPatternRequest{Pattern: slab.World{}}, &stubs.MatchPatternResponse{}); err == nil {
+		t.Error("matched an empty pattern")
+	}
+}
@@ -0,0 +1,143 @@
+package golbroker
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// observerExpiry is how long an observer may go without polling before the world it was last sent is forgotten.
+// It then starts again from a keyframe, as a new observer would.
+const observerExpiry = time.Minute
+
+// observers remembers the world each remote observer was last sent whole or brought up to with deltas, so the
+// next delta can be worked out against it if the observer says it received it. Keeping it costs nothing, as a published world is never modified. It
+// has its own mutex, so observers never wait for a turn to finish.
+type observers struct {
+	mu   sync.Mutex
+	next int // ID of the latest observer.
+	seen map[int]observed
+}
+
+// observed is what an observer was last sent.
+type observed struct {
+	world  slab.World // World the observer was sent, or empty after a density grid.
+	turn   int        // Turn of world.
+	polled time.Time
+}
+
+// Observe sends a remote observer the latest published generation in the form that best fits its budget: the
+// cells changed since its last poll if that fits and is smaller than the whole world, otherwise the whole world if
+// that fits, and otherwise a density grid coarse enough to fit. The changed cells are only sent if the observer
+// holds the world it was last sent, going by its turn; if that response was lost, it is sent the whole world
+// again. Like the other read RPCs it needs no epoch and works while paused.
+func (b *Broker) Observe(req stubs.ObserveRequest, res *stubs.ObserveResponse) (err error) {
+	snapshot := b.current()
+	world := snapshot.World
+	o := &b.Observers
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	for id, seen := range o.seen {
+		if now.Sub(seen.polled) > observerExpiry {
+			delete(o.seen, id)
+		}
+	}
+	if o.seen == nil {
+		o.seen = make(map[int]observed)
+	}
+	last, ok := o.seen[req.Observer]
+	if !ok {
+		o.next++
+		req.Observer = o.next
+	}
+	res.Observer, res.Turn = req.Observer, snapshot.Turn
+
+	keyframe := int(gobSize(world.Height, world.Width))
+	limit := keyframe
+	if req.Budget > 0 && req.Budget < limit {
+		limit = req.Budget
+	}
+	base := last.world
+	if req.Held != last.turn {
+		base = slab.World{} // The observer never got the last world it was sent.
+	}
+	if flips, size, ok := delta(base, world, limit); ok {
+		res.Form, res.Flips, res.Size = "delta", flips, size
+	} else if req.Budget == 0 || keyframe <= req.Budget {
+		res.Form, res.Keyframe, res.Size = "keyframe", world, keyframe
+	} else {
+		res.Form = "density"
+		res.Density, res.Scale = density(world, req.Budget)
+		res.Size = int(gobSize(res.Density.Height, res.Density.Width))
+		world = slab.World{}
+	}
+	o.seen[req.Observer] = observed{world, snapshot.Turn, now}
+	return
+}
+
+// delta returns the cells that differ between two worlds of the same size and the bytes they take to send, or
+// false if the worlds differ in size or the cells would take more than limit bytes.
+func delta(from, to slab.World, limit int) ([]util.Cell, int, bool) {
+	if from.Cells == nil || from.Width != to.Width || from.Height != to.Height {
+		return nil, 0, false
+	}
+	// Each cell is sent as its X and Y, field number and all, and the struct's closing zero byte.
+	cellSize := 3 + uvarintSize(2*to.Width) + uvarintSize(2*to.Height)
+	size := 1 + uvarintSize(to.Width*to.Height)
+	var flips []util.Cell
+	for y := 0; y < to.Height; y++ {
+		a, b := from.Row(y), to.Row(y)
+		if bytes.Equal(a, b) {
+			continue
+		}
+		for x := range b {
+			if a[x] != b[x] {
+				flips = append(flips, util.Cell{X: x, Y: y})
+				if size += cellSize; size > limit {
+					return nil, 0, false
+				}
+			}
+		}
+	}
+	return flips, size, true
+}
+
+// density returns the finest grid of a world that fits in budget bytes, halving its resolution until it does,
+// along with the number of cells along each side of the block each of its cells stands for. A grid of a single
+// cell is returned however small the budget.
+func density(world slab.World, budget int) (slab.World, int) {
+	scale := 2
+	for ; scale < world.Width || scale < world.Height; scale *= 2 {
+		if gobSize((world.Height+scale-1)/scale, (world.Width+scale-1)/scale) <= int64(budget) {
+			break
+		}
+	}
+	grid := slab.New((world.Width+scale-1)/scale, (world.Height+scale-1)/scale)
+	alive := make([]int, grid.Width*grid.Height)
+	for y := 0; y < world.Height; y++ {
+		for x, cell := range world.Row(y) {
+			if cell == util.Alive {
+				alive[y/scale*grid.Width+x/scale]++
+			}
+		}
+	}
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			// Blocks on the right and bottom edges may be cut short by the edge of the world.
+			w, h := scale, scale
+			if w > world.Width-x*scale {
+				w = world.Width - x*scale
+			}
+			if h > world.Height-y*scale {
+				h = world.Height - y*scale
+			}
+			grid.Set(x, y, byte(alive[y*grid.Width+x]*255/(w*h)))
+		}
+	}
+	return grid, scale
+}
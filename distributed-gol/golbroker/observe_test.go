@@ -0,0 +1,70 @@
+package golbroker
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestObserve checks an observer is sent the whole world first and then only the cells changed since, that one
+// which didn't get the last world it was sent is sent the whole world again, and that one whose budget won't fit
+// the whole world is sent a density grid instead, followed by a keyframe once it fits again.
+func TestObserve(t *testing.T) {
+	b := &Broker{}
+	b.World = slab.New(16, 16)
+	b.publish()
+	observe := func(observer, budget, held int) stubs.ObserveResponse {
+		t.Helper()
+		res := stubs.ObserveResponse{}
+		if err := b.Observe(stubs.ObserveRequest{Observer: observer, Budget: budget, Held: held}, &res); err != nil {
+			t.Fatal(err)
+		}
+		if budget > 0 && res.Size > budget {
+			t.Errorf("sent a %s of %d bytes over a budget of %d", res.Form, res.Size, budget)
+		}
+		return res
+	}
+
+	first := observe(0, 0, -1)
+	if first.Form != "keyframe" || first.Keyframe.Width != 16 || first.Observer == 0 {
+		t.Fatalf("a new observer was sent a %s as observer %d, expected a keyframe and an ID", first.Form, first.Observer)
+	}
+	world := b.World.Clone()
+	for _, cell := range []util.Cell{{X: 10, Y: 10}, {X: 11, Y: 10}, {X: 10, Y: 11}, {X: 11, Y: 11}} {
+		world.Set(cell.X, cell.Y, util.Alive)
+	}
+	b.World, b.Turn = world, 1
+	b.publish()
+	if res := observe(first.Observer, 0, 0); res.Form != "delta" || len(res.Flips) != 4 || res.Turn != 1 {
+		t.Errorf("the block was sent as a %s of %v at turn %d, expected a delta of its 4 cells at turn 1", res.Form, res.Flips, res.Turn)
+	}
+
+	// The delta to turn 1 was lost, so the observer still holds turn 0 when turn 2 is published.
+	world = b.World.Clone()
+	world.Set(12, 12, util.Alive)
+	b.World, b.Turn = world, 2
+	b.publish()
+	if res := observe(first.Observer, 0, 0); res.Form != "keyframe" || countAlive(res.Keyframe) != 5 {
+		t.Errorf("an observer holding turn 0 was sent a %s of turn %d, expected a keyframe", res.Form, res.Turn)
+	}
+	if res := observe(first.Observer, 0, 2); res.Form != "delta" || len(res.Flips) != 0 {
+		t.Errorf("an observer holding turn 2 was sent a %s of %v, expected an empty delta", res.Form, res.Flips)
+	}
+
+	// A 16x16 world takes a few hundred bytes whole, so a new observer with a budget of 100 gets an 8x8 grid.
+	grid := observe(0, 100, -1)
+	if grid.Form != "density" || grid.Scale != 2 || grid.Density.Width != 8 || grid.Density.At(5, 5) != 255 || grid.Density.At(0, 0) != 0 {
+		t.Errorf("sent a %s at scale %d of %v, expected an 8x8 density grid with the block in one cell", grid.Form, grid.Scale, grid.Density)
+	}
+	if res := observe(grid.Observer, 10, -1); res.Form != "density" || res.Density.Width != 1 || res.Density.At(0, 0) != 255*5/256 {
+		t.Errorf("sent a %s of %v over a tiny budget, expected the whole world as one cell", res.Form, res.Density)
+	}
+	if res := observe(grid.Observer, 0, -1); res.Form != "keyframe" || countAlive(res.Keyframe) != 5 {
+		t.Errorf("sent a %s after a density grid, expected a keyframe", res.Form)
+	}
+	if res := observe(999, 0, -1); res.Form != "keyframe" || res.Observer == 999 {
+		t.Errorf("an unknown observer was sent a %s as observer %d, expected a keyframe and a new ID", res.Form, res.Observer)
+	}
+}
@@ -0,0 +1,55 @@
+package golbroker
+
+import (
+	"errors"
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// workers returns the workers connected so far, whether found by ScanForWorkers or added by RegisterWorker.
+func (b *Broker) workers() []*workerConn {
+	b.WorkersMu.Lock()
+	defer b.WorkersMu.Unlock()
+	return b.Workers
+}
+
+// RegisterWorker connects to a worker started with -advertise, e.g. on another host of a cloud deployment, and adds
+// it to the workers turns are split between. A run already in progress carries on with the workers it started with,
+// so the new one gets slices from the next run. A worker registering an address already connected, e.g. after it
+// restarts, is left as it is.
+func (b *Broker) RegisterWorker(req stubs.RegisterWorkerRequest, res *stubs.Empty) (err error) {
+	if req.Address == "" {
+		return errors.New("a worker must register the address it can be reached at")
+	}
+	if b.connected(req.Address) {
+		return nil
+	}
+	worker, err := dialWorker(req.Address)
+	if err != nil {
+		return fmt.Errorf("couldn't reach the worker at %s: %v", req.Address, err)
+	}
+	b.WorkersMu.Lock()
+	defer b.WorkersMu.Unlock()
+	for _, connected := range b.Workers {
+		if connected.Address == req.Address {
+			worker.Close() // Registered twice at once, and the other call won.
+			return nil
+		}
+	}
+	// Appended to a copy, so a run holding the old slice never sees it change.
+	b.Workers = append(b.Workers[:len(b.Workers):len(b.Workers)], worker)
+	b.Stats.addWorker(req.Address)
+	fmt.Printf("Worker registered from %s\n", req.Address)
+	return nil
+}
+
+// connected reports whether a worker at address is already connected.
+func (b *Broker) connected(address string) bool {
+	for _, worker := range b.workers() {
+		if worker.Address == address {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package golbroker
+
+import (
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/memnet"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestRegisterWorker checks a worker registering over RPC is added to the broker's workers once, however often it
+// registers, and is given slices of the next run.
+func TestRegisterWorker(t *testing.T) {
+	address, stopWorker, err := memnet.ServeRPC("WorldOps", &lifeWorker{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopWorker()
+	b := &Broker{Lease: time.Minute}
+	client, stop := serveBroker(t, b)
+	defer stop()
+
+	for i := 0; i < 2; i++ {
+		if err := client.Call(stubs.RegisterWorkerHandler, stubs.RegisterWorkerRequest{Address: address}, &stubs.Empty{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if workers := b.workers(); len(workers) != 1 || workers[0].Address != address {
+		t.Fatalf("got %d workers after registering the same one twice, expected just it", len(workers))
+	}
+	for _, bad := range []string{"", "mem:nowhere"} {
+		if err := client.Call(stubs.RegisterWorkerHandler, stubs.RegisterWorkerRequest{Address: bad}, &stubs.Empty{}); err == nil {
+			t.Errorf("registering %q succeeded, expected an error", bad)
+		}
+	}
+
+	evolveGliderRoundTrip(t, b, 16)
+	if got := b.Stats.snapshot(); len(got.Workers) != 1 || got.Workers[0].Address != address || got.Workers[0].Calls == 0 {
+		t.Errorf("unexpected worker stats %+v, expected the registered worker to have computed slices", got.Workers)
+	}
+}
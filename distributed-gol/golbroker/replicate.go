@@ -0,0 +1,167 @@
+package golbroker
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/storage"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// standbyPrefix marks a replica destination that is another broker, e.g. broker://10.0.0.7:8030, rather than a
+// directory or a bucket.
+const standbyPrefix = "broker://"
+
+// standbyTimeout bounds how long copying a checkpoint to a standby broker may take.
+const standbyTimeout = time.Minute
+
+// replica is a checkpoint waiting to be copied.
+type replica struct {
+	name string // Where the checkpoint goes, relative to the destination.
+	data []byte
+	turn int
+}
+
+// replicaTarget is one destination checkpoints are copied to. Only the latest checkpoint of each name is kept
+// waiting, so a slow destination falls behind by whole checkpoints rather than queueing every one of them.
+type replicaTarget struct {
+	destination string
+	mu          sync.Mutex
+	pending     map[string]replica // Latest checkpoint of each name not copied yet.
+	wake        chan struct{}      // Signalled when a checkpoint is added to pending.
+}
+
+// replicator copies every checkpoint the broker saves to other destinations in the background (-replicate), so
+// losing the disk the checkpoint is on doesn't lose the run. Each destination has its own goroutine, so one that
+// is slow or down holds up neither the others nor the run, and how each is doing is shown on the dashboard.
+type replicator struct {
+	targets []*replicaTarget
+	stats   *stats
+	copy    func(destination, name string, data []byte) error // Copies a checkpoint; copyReplica outside tests.
+}
+
+// newReplicator starts copying checkpoints to each destination: a local directory, an s3:// or gs:// bucket, or
+// a standby broker given as broker://host:port.
+func newReplicator(destinations []string, s *stats) *replicator {
+	r := &replicator{stats: s, copy: copyReplica}
+	var trimmed []string
+	for _, destination := range destinations {
+		if destination = strings.TrimSpace(destination); destination != "" {
+			trimmed = append(trimmed, destination)
+		}
+	}
+	s.setReplicas(trimmed)
+	for i, destination := range trimmed {
+		target := &replicaTarget{destination: destination, pending: make(map[string]replica), wake: make(chan struct{}, 1)}
+		r.targets = append(r.targets, target)
+		go r.run(i, target)
+	}
+	return r
+}
+
+// replicate queues a checkpoint to be copied to every destination, replacing any older one of the same name
+// still waiting. It returns straight away. A nil replicator does nothing, so it can be called unconditionally.
+func (r *replicator) replicate(name string, data []byte, turn int) {
+	if r == nil {
+		return
+	}
+	for _, target := range r.targets {
+		target.mu.Lock()
+		target.pending[name] = replica{name: name, data: data, turn: turn}
+		target.mu.Unlock()
+		select {
+		case target.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run copies the checkpoints queued for a destination, one at a time, recording each outcome in the stats.
+func (r *replicator) run(i int, target *replicaTarget) {
+	for range target.wake {
+		for {
+			target.mu.Lock()
+			var next replica
+			found := false
+			for name, pending := range target.pending {
+				next, found = pending, true
+				delete(target.pending, name)
+				break
+			}
+			target.mu.Unlock()
+			if !found {
+				break
+			}
+			err := r.copy(target.destination, next.name, next.data)
+			if err != nil {
+				fmt.Printf("Warning: couldn't replicate %s to %s: %v\n", next.name, target.destination, err)
+			}
+			r.stats.recordReplica(i, next.turn, err)
+		}
+	}
+}
+
+// copyReplica copies a checkpoint to a destination.
+func copyReplica(destination, name string, data []byte) error {
+	if strings.HasPrefix(destination, standbyPrefix) {
+		return sendReplica(strings.TrimPrefix(destination, standbyPrefix), name, data)
+	}
+	if strings.Contains(destination, "://") {
+		return storage.Put(storage.Join(destination, name), data)
+	}
+	return writeFileAtomic(filepath.Join(destination, filepath.FromSlash(name)), data)
+}
+
+// sendReplica hands a checkpoint to the standby broker at address, which saves it under its -replicaDir.
+func sendReplica(address, name string, data []byte) error {
+	conn, err := net.DialTimeout("tcp", address, standbyTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(standbyTimeout))
+	client := rpc.NewClient(conn)
+	defer client.Close()
+	return client.Call(stubs.StoreReplicaHandler, stubs.StoreReplicaRequest{Name: name, Data: data}, &stubs.Empty{})
+}
+
+// writeFileAtomic writes a file through a temporary file, so a crash part way through never leaves a truncated
+// copy in place of a good one.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// replicaName is the name a checkpoint saved at path is replicated under: the path itself if it is relative,
+// e.g. jobs/job-3/checkpoint, or just the file name if not, so it always lands inside the destination.
+func replicaName(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// StoreReplica saves a checkpoint another broker replicates to this one (-replicaDir), so a standby broker holds
+// a copy of the run it can be started from with -resume -checkpoint=<replicaDir>/<name>.
+func (b *Broker) StoreReplica(req stubs.StoreReplicaRequest, res *stubs.Empty) (err error) {
+	if b.ReplicaDir == "" {
+		return errors.New("this broker doesn't accept replicas: start it with -replicaDir")
+	}
+	name := filepath.Clean(filepath.FromSlash(req.Name))
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("replica name %q is outside the replica directory", req.Name)
+	}
+	return writeFileAtomic(filepath.Join(b.ReplicaDir, name), req.Data)
+}
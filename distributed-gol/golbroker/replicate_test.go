@@ -0,0 +1,151 @@
+package golbroker
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// serveStandby serves a broker that accepts replicas into dir, returning its address and a function that stops it.
+func serveStandby(t *testing.T, dir string) (string, func()) {
+	server := rpc.NewServer()
+	if err := server.Register(&Broker{ReplicaDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Accept(listener)
+	return listener.Addr().String(), func() { listener.Close() }
+}
+
+// waitForReplicas waits until every replica destination has had a copy made or fail, and returns their stats.
+func waitForReplicas(t *testing.T, s *stats) []replicaStats {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		replicas := s.snapshot().Replicas
+		done := true
+		for _, r := range replicas {
+			if r.Turn < 0 && r.Failures == 0 {
+				done = false
+			}
+		}
+		if done {
+			return replicas
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replication didn't finish: %+v", replicas)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestReplicateCheckpoint checks a saved checkpoint is copied to a local directory and a standby broker, that
+// the standby can be resumed from its copy, and that a destination that can't be written to is reported.
+func TestReplicateCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replicas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	standby, stop := serveStandby(t, filepath.Join(dir, "standby"))
+	defer stop()
+	// A file can't be used as a directory, so copies there fail.
+	blocked := filepath.Join(dir, "blocked")
+	if err := ioutil.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Broker{}
+	b.Replicas = newReplicator([]string{filepath.Join(dir, "mirror"), " broker://" + standby, blocked}, &b.Stats)
+	b.World = slab.FromRows([][]byte{{0, 255, 0}, {0, 255, 0}, {0, 255, 0}})
+	b.Turn = 42
+	b.publish()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("runs", os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.saveCheckpoint(filepath.Join("runs", "broker.checkpoint")); err != nil {
+		t.Fatal(err)
+	}
+
+	replicas := waitForReplicas(t, &b.Stats)
+	if len(replicas) != 3 {
+		t.Fatalf("%d replica destinations, expected 3", len(replicas))
+	}
+	original, _ := ioutil.ReadFile(filepath.Join(dir, "runs", "broker.checkpoint"))
+	for i, path := range []string{filepath.Join(dir, "mirror", "runs", "broker.checkpoint"), filepath.Join(dir, "standby", "runs", "broker.checkpoint")} {
+		if replicas[i].Turn != 42 || replicas[i].Error != "" {
+			t.Errorf("%s: turn %d, error %q, expected turn 42 copied", replicas[i].Destination, replicas[i].Turn, replicas[i].Error)
+		}
+		if copied, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(copied, original) {
+			t.Errorf("%s isn't a copy of the checkpoint: %v", path, err)
+		}
+	}
+	if replicas[2].Failures != 1 || replicas[2].Error == "" || replicas[2].Turn != -1 {
+		t.Errorf("unwritable destination reported as %+v, expected one failure", replicas[2])
+	}
+
+	resumed := &Broker{}
+	if turn, err := resumed.loadCheckpoint(filepath.Join(dir, "standby", "runs", "broker.checkpoint")); err != nil || turn != 42 {
+		t.Errorf("standby resumed at turn %d (%v), expected 42", turn, err)
+	}
+}
+
+// TestReplicatorKeepsLatest checks a destination that falls behind only copies the latest checkpoint of each name.
+func TestReplicatorKeepsLatest(t *testing.T) {
+	var s stats
+	release := make(chan struct{})
+	copied := make(chan int, 10)
+	r := &replicator{stats: &s, copy: func(destination, name string, data []byte) error {
+		<-release
+		copied <- int(data[0])
+		return nil
+	}}
+	s.setReplicas([]string{"slow"})
+	target := &replicaTarget{destination: "slow", pending: make(map[string]replica), wake: make(chan struct{}, 1)}
+	r.targets = []*replicaTarget{target}
+
+	// Three checkpoints are saved before the destination gets round to any of them.
+	for turn := 1; turn <= 3; turn++ {
+		r.replicate("broker.checkpoint", []byte{byte(turn)}, turn)
+	}
+	go r.run(0, target)
+	close(release)
+	if got := <-copied; got != 3 {
+		t.Errorf("copied checkpoint %d first, expected only the latest, 3", got)
+	}
+	select {
+	case got := <-copied:
+		t.Errorf("copied checkpoint %d as well, expected the older ones to be dropped", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestStoreReplicaOutsideDir checks a standby refuses replicas named outside its replica directory, and all
+// replicas if it hasn't been given one.
+func TestStoreReplicaOutsideDir(t *testing.T) {
+	b := &Broker{ReplicaDir: "replicas"}
+	for _, name := range []string{"../escape", "/etc/passwd", "a/../../escape"} {
+		if err := b.StoreReplica(stubs.StoreReplicaRequest{Name: name, Data: []byte{1}}, &stubs.Empty{}); err == nil {
+			t.Errorf("stored a replica named %q", name)
+		}
+	}
+	err := (&Broker{}).StoreReplica(stubs.StoreReplicaRequest{Name: "broker.checkpoint"}, &stubs.Empty{})
+	if err == nil {
+		t.Error("stored a replica on a broker without -replicaDir")
+	}
+}
@@ -0,0 +1,220 @@
+package golbroker
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/golsnap"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// retention says which of a run's earlier checkpoints are kept (-keepCheckpoints, -keepHourly, -checkpointDisk).
+// Every checkpoint saved at a path is also kept, compressed, in the path's history directory, which is pruned to
+// the policy after each save, so a long run can be resumed from further back than its latest checkpoint without
+// filling the disk. With the zero value, or Last of 1 and no hours, no history is kept, as before.
+type retention struct {
+	Last     int   // Newest checkpoints kept.
+	Hourly   int   // Hours back for which the newest checkpoint saved in each hour is kept as well.
+	MaxBytes int64 // Most disk a history may use, or 0 for no limit. The newest checkpoint is kept whatever its size.
+}
+
+// keepsHistory reports whether the policy keeps any checkpoint but the latest.
+func (r retention) keepsHistory() bool {
+	return r.Last > 1 || r.Hourly > 0
+}
+
+// keep reports which of a history's checkpoints, newest first, the policy keeps at the given time.
+func (r retention) keep(history []stubs.CheckpointInfo, now time.Time) []bool {
+	kept := make([]bool, len(history))
+	hours := make(map[time.Time]bool)
+	for i, c := range history {
+		hour := c.Saved.Truncate(time.Hour)
+		if i < r.Last || i == 0 {
+			kept[i] = true
+		}
+		if r.Hourly > 0 && !hours[hour] && now.Sub(hour) < time.Duration(r.Hourly)*time.Hour {
+			hours[hour] = true
+			kept[i] = true
+		}
+	}
+	if r.MaxBytes > 0 {
+		var used int64
+		for i, c := range history {
+			if !kept[i] {
+				continue
+			}
+			if i > 0 && used+c.Bytes > r.MaxBytes {
+				kept[i] = false
+				continue
+			}
+			used += c.Bytes
+		}
+	}
+	return kept
+}
+
+// checkpointIndex remembers every checkpoint path the broker has saved to or loaded from, and the turn and labels
+// last saved there, for ListCheckpoints, along with the keyframe last saved whole at each path, for deltas.
+type checkpointIndex struct {
+	mu        sync.Mutex
+	turns     map[string]int
+	labels    map[string]map[string]string
+	keyframes map[string]golsnap.Keyframe
+	deltas    map[string]int // Deltas saved at each path since its keyframe.
+	// Held while a history is being written and pruned, so passes in the background don't overlap.
+	archiving sync.Mutex
+	pending   sync.WaitGroup // Passes not finished yet, for tests to wait for.
+}
+
+// note records that path now holds the given turn of a run with the given labels.
+func (x *checkpointIndex) note(path string, turn int, labels map[string]string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.turns == nil {
+		x.turns = make(map[string]int)
+		x.labels = make(map[string]map[string]string)
+	}
+	x.turns[path] = turn
+	x.labels[path] = labels
+}
+
+// deltaBase returns the keyframe last saved whole at path for the checkpoint about to be saved there to be saved as
+// a delta against, or false if the checkpoint is due to be saved whole, as every every-th one is.
+func (x *checkpointIndex) deltaBase(path string, every int) (golsnap.Keyframe, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	keyframe, ok := x.keyframes[path]
+	if !ok || x.deltas[path]+1 >= every {
+		return golsnap.Keyframe{}, false
+	}
+	x.deltas[path]++
+	return keyframe, true
+}
+
+// keyframeSaved records that keyframe has just been saved whole at path.
+func (x *checkpointIndex) keyframeSaved(path string, keyframe golsnap.Keyframe) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.keyframes == nil {
+		x.keyframes = make(map[string]golsnap.Keyframe)
+		x.deltas = make(map[string]int)
+	}
+	x.keyframes[path] = keyframe
+	x.deltas[path] = 0
+}
+
+// historyDir is the directory earlier checkpoints saved at path are kept in.
+func historyDir(path string) string {
+	return path + ".history"
+}
+
+// archiveCheckpoint keeps a compressed copy of the checkpoint just saved at path in its history directory, then
+// deletes whatever the retention policy no longer keeps there. It runs in the background, after saveCheckpoint.
+func (b *Broker) archiveCheckpoint(path string, data []byte, turn int) {
+	defer b.Checkpoints.pending.Done()
+	b.Checkpoints.archiving.Lock()
+	defer b.Checkpoints.archiving.Unlock()
+
+	dir := historyDir(path)
+	if err := writeCompressed(filepath.Join(dir, fmt.Sprintf("turn-%012d.gz", turn)), data); err != nil {
+		fmt.Printf("Warning: couldn't keep turn %d in %s: %v\n", turn, dir, err)
+		return
+	}
+	history, err := listHistory(dir)
+	if err != nil {
+		fmt.Printf("Warning: couldn't prune %s: %v\n", dir, err)
+		return
+	}
+	for i, keep := range b.Retention.keep(history, time.Now()) {
+		if !keep {
+			if err := os.Remove(history[i].Path); err != nil {
+				fmt.Printf("Warning: couldn't delete %s: %v\n", history[i].Path, err)
+			}
+		}
+	}
+}
+
+// writeCompressed gzips data into filename, through a temporary file so a reader never sees half of it.
+func writeCompressed(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return err
+	}
+	compressed := gzip.NewWriter(file)
+	_, err = compressed.Write(data)
+	if closeErr := compressed.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filename + ".tmp")
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// listHistory lists the checkpoints kept in a history directory, newest first.
+func listHistory(dir string) ([]stubs.CheckpointInfo, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []stubs.CheckpointInfo
+	for _, entry := range entries {
+		var turn int
+		if !strings.HasSuffix(entry.Name(), ".gz") {
+			continue
+		}
+		if _, err := fmt.Sscanf(entry.Name(), "turn-%d.gz", &turn); err != nil {
+			continue
+		}
+		history = append(history, stubs.CheckpointInfo{Path: filepath.Join(dir, entry.Name()), Turn: turn,
+			Saved: entry.ModTime(), Bytes: entry.Size(), Compressed: true})
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].Turn > history[j].Turn })
+	return history, nil
+}
+
+// ListCheckpoints lists every checkpoint the broker has saved or resumed from, each followed by the earlier ones
+// kept in its history, newest first. Any of them can be passed to -resume -checkpoint.
+func (b *Broker) ListCheckpoints(req stubs.Empty, res *stubs.ListCheckpointsResponse) (err error) {
+	b.Checkpoints.mu.Lock()
+	paths := make([]string, 0, len(b.Checkpoints.turns))
+	turns := make(map[string]int, len(b.Checkpoints.turns))
+	labels := make(map[string]map[string]string, len(b.Checkpoints.labels))
+	for path, turn := range b.Checkpoints.turns {
+		paths = append(paths, path)
+		turns[path] = turn
+		labels[path] = b.Checkpoints.labels[path]
+	}
+	b.Checkpoints.mu.Unlock()
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			res.Checkpoints = append(res.Checkpoints, stubs.CheckpointInfo{Path: path, Turn: turns[path],
+				Saved: info.ModTime(), Bytes: info.Size(), Labels: labels[path]})
+		}
+		history, err := listHistory(historyDir(path))
+		if err != nil {
+			return err
+		}
+		res.Checkpoints = append(res.Checkpoints, history...)
+	}
+	return nil
+}
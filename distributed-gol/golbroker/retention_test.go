@@ -0,0 +1,83 @@
+package golbroker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestRetentionKeep checks the policy keeps the newest checkpoints, the newest of each recent hour and no more
+// than the disk allowed, and always the newest of all.
+func TestRetentionKeep(t *testing.T) {
+	now := time.Date(2024, time.January, 31, 12, 30, 0, 0, time.UTC)
+	// Six checkpoints, newest first, saved every 40 minutes and each 100 bytes.
+	var history []stubs.CheckpointInfo
+	for i := 0; i < 6; i++ {
+		history = append(history, stubs.CheckpointInfo{Turn: 600 - 100*i, Saved: now.Add(-time.Duration(40*i) * time.Minute), Bytes: 100})
+	}
+	tests := []struct {
+		name     string
+		policy   retention
+		expected []bool
+	}{
+		{"latest only", retention{Last: 1}, []bool{true, false, false, false, false, false}},
+		{"none asked for", retention{}, []bool{true, false, false, false, false, false}},
+		{"last 3", retention{Last: 3}, []bool{true, true, true, false, false, false}},
+		// Saved at 12:30, 11:50, 11:10, 10:30, 9:50 and 9:10: the newest of the hours 12, 11 and 10.
+		{"hourly", retention{Last: 1, Hourly: 3}, []bool{true, true, false, true, false, false}},
+		{"disk", retention{Last: 6, MaxBytes: 250}, []bool{true, true, false, false, false, false}},
+		{"newest over the disk", retention{Last: 2, MaxBytes: 50}, []bool{true, false, false, false, false, false}},
+	}
+	for _, test := range tests {
+		if kept := test.policy.keep(history, now); !reflect.DeepEqual(kept, test.expected) {
+			t.Errorf("%s: kept %v, expected %v", test.name, kept, test.expected)
+		}
+	}
+}
+
+// TestCheckpointHistory saves several turns of a run with a history of two kept, and checks the two newest are
+// listed, compressed, alongside the latest checkpoint, and that a broker can be resumed from one of them.
+func TestCheckpointHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "broker.checkpoint")
+
+	const size = 16
+	b := &Broker{Lease: time.Minute, Retention: retention{Last: 2}}
+	for _, turns := range []int{5, 10, 15, 20} {
+		req := stubs.EvolveWorldRequest{World: gliderWorld(size), Turn: turns, ImageWidth: size, ImageHeight: size, Epoch: acquire(t, b)}
+		if err := b.EvolveWorld(req, &stubs.EvolveResponse{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.saveCheckpoint(path); err != nil {
+			t.Fatal(err)
+		}
+		b.Checkpoints.pending.Wait()
+	}
+
+	res := &stubs.ListCheckpointsResponse{}
+	if err := b.ListCheckpoints(stubs.Empty{}, res); err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for _, c := range res.Checkpoints {
+		listed = append(listed, fmt.Sprintf("%d %v", c.Turn, c.Compressed))
+	}
+	if expected := []string{"20 false", "20 true", "15 true"}; !reflect.DeepEqual(listed, expected) {
+		t.Fatalf("listed %v, expected %v", listed, expected)
+	}
+
+	restarted := &Broker{Lease: time.Minute}
+	if turn, err := restarted.loadCheckpoint(res.Checkpoints[2].Path); err != nil || turn != 15 {
+		t.Fatalf("resumed from %s at turn %d, expected 15: %v", res.Checkpoints[2].Path, turn, err)
+	}
+}
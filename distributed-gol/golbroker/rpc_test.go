@@ -0,0 +1,207 @@
+package golbroker
+
+import (
+	"net/rpc"
+	"sync"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/memnet"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// serveBroker serves b in memory as the broker binary would, returning a client connected to it and a function that
+// stops it, so its handlers are tested as a client calls them, with every request and reply encoded on the way.
+func serveBroker(t *testing.T, b *Broker) (*rpc.Client, func()) {
+	address, stop, err := memnet.ServeRPC("Broker", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := memnet.DialRPC(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, func() {
+		client.Close()
+		stop()
+	}
+}
+
+// startGlider acquires b through client and starts a glider evolving for ever, returning the epoch and the call,
+// which is done once the run has been quit.
+func startGlider(t *testing.T, b *Broker, client *rpc.Client) (int, *rpc.Call) {
+	acquired := &stubs.AcquireResponse{}
+	if err := client.Call(stubs.AcquireHandler, stubs.Empty{}, acquired); err != nil {
+		t.Fatal(err)
+	}
+	req := stubs.EvolveWorldRequest{World: gliderWorld(16), Turn: 1 << 30, ImageWidth: 16, ImageHeight: 16, Epoch: acquired.Epoch}
+	evolve := client.Go(stubs.EvolveWorldHandler, req, &stubs.EvolveResponse{}, nil)
+	waitForTurn(t, b, 1)
+	return acquired.Epoch, evolve
+}
+
+// controlStep is a control RPC made by the client in control, or by the one before it if stale.
+type controlStep struct {
+	handler string
+	stale   bool
+	running bool // Whether the run should be moving on afterwards.
+}
+
+// TestControlOverRPC drives a run through sequences of pauses, resumes and quits over RPC, checking after each step
+// that the run is held or moving on as it should be, and that once quit it has stopped and left its world for the
+// next client to continue from.
+func TestControlOverRPC(t *testing.T) {
+	pause := controlStep{handler: stubs.PauseHandler}
+	hardPause := controlStep{handler: stubs.HardPauseHandler}
+	unpause := controlStep{handler: stubs.UnpauseHandler, running: true}
+	tests := []struct {
+		name  string
+		steps []controlStep
+	}{
+		{"quit", nil},
+		{"pause", []controlStep{pause}},
+		{"hard pause", []controlStep{hardPause}},
+		{"pause and resume", []controlStep{pause, unpause}},
+		{"hard pause and resume", []controlStep{hardPause, unpause}},
+		{"pause twice", []controlStep{pause, pause, unpause}},
+		{"pause during a hard pause", []controlStep{hardPause, pause, unpause}},
+		{"hard pause during a pause", []controlStep{pause, hardPause, unpause}},
+		{"stale controls", []controlStep{
+			{handler: stubs.PauseHandler, stale: true, running: true},
+			{handler: stubs.HardPauseHandler, stale: true, running: true},
+			{handler: stubs.QuitHandler, stale: true, running: true},
+			pause,
+			{handler: stubs.UnpauseHandler, stale: true},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := &Broker{Lease: time.Minute}
+			client, stop := serveBroker(t, b)
+			defer stop()
+			epoch, evolve := startGlider(t, b, client)
+
+			for i, step := range test.steps {
+				req := stubs.ControlRequest{Epoch: epoch}
+				if step.stale {
+					req.Epoch--
+				}
+				err := client.Call(step.handler, req, &stubs.Empty{})
+				if step.stale && (err == nil || err.Error() != errStaleEpoch.Error()) {
+					t.Fatalf("step %d: stale %s returned %v, expected %v", i, step.handler, err, errStaleEpoch)
+				} else if !step.stale && err != nil {
+					t.Fatalf("step %d: %s failed: %v", i, step.handler, err)
+				}
+				turn := b.current().Turn
+				if step.running {
+					waitForTurn(t, b, turn+1)
+					continue
+				}
+				time.Sleep(20 * time.Millisecond)
+				if now := b.current().Turn; now != turn {
+					t.Fatalf("step %d: paused by %s at turn %d, but the run carried on to turn %d", i, step.handler, turn, now)
+				}
+			}
+
+			if err := client.Call(stubs.QuitHandler, stubs.ControlRequest{Epoch: epoch}, &stubs.Empty{}); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-evolve.Done:
+				if evolve.Error != nil {
+					t.Fatal(evolve.Error)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("quitting left EvolveWorld running")
+			}
+			final := evolve.Reply.(*stubs.EvolveResponse)
+
+			continued := &stubs.GetContinueResponse{}
+			if err := client.Call(stubs.GetContinueHandler, stubs.Empty{}, continued); err != nil {
+				t.Fatal(err)
+			}
+			if !continued.Continue {
+				t.Error("the next client isn't offered the quit run's world to continue from")
+			}
+			if continued.Turn != final.Turn {
+				t.Errorf("the next client would continue from turn %d, but the run was quit at turn %d", continued.Turn, final.Turn)
+			}
+			// Quitting gives up the lease, so the next client can take over straight away.
+			if err := client.Call(stubs.AcquireHandler, stubs.Empty{}, &stubs.AcquireResponse{}); err != nil {
+				t.Errorf("the next client couldn't take over: %v", err)
+			}
+		})
+	}
+}
+
+// TestConcurrentControl has several goroutines pausing and resuming a run over RPC while others read from it, and
+// checks none of the calls is left waiting and the run still resumes and quits afterwards. Run it with -race.
+func TestConcurrentControl(t *testing.T) {
+	const callers, rounds = 8, 20
+	b := &Broker{Lease: time.Minute}
+	client, stop := serveBroker(t, b)
+	defer stop()
+	epoch, evolve := startGlider(t, b, client)
+	control := stubs.ControlRequest{Epoch: epoch}
+
+	pauses := []string{stubs.PauseHandler, stubs.HardPauseHandler}
+	reads := []struct {
+		handler string
+		res     func() interface{}
+	}{
+		{stubs.GetBrokerCellFlippedHandler, func() interface{} { return &stubs.GetBrokerCellFlippedResponse{} }},
+		{stubs.AliveCellsCountHandler, func() interface{} { return &stubs.AliveCellsCountResponse{} }},
+		{stubs.GetTurnDoneHandler, func() interface{} { return &stubs.GetTurnDoneResponse{} }},
+		{stubs.WorldHashHandler, func() interface{} { return &stubs.WorldHashResponse{} }},
+	}
+	errs := make(chan error, 3*callers*rounds) // Two calls a round from each pausing goroutine and one from each reading.
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(2)
+		// Each pause is followed by a resume from the same caller, so however the calls interleave the last one
+		// leaves the run resumed.
+		go func(i int) {
+			defer wg.Done()
+			for round := 0; round < rounds; round++ {
+				errs <- client.Call(pauses[(i+round)%len(pauses)], control, &stubs.Empty{})
+				errs <- client.Call(stubs.UnpauseHandler, control, &stubs.Empty{})
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for round := 0; round < rounds; round++ {
+				read := reads[(i+round)%len(reads)]
+				errs <- client.Call(read.handler, stubs.Empty{}, read.res())
+			}
+		}(i)
+	}
+	finished := make(chan bool)
+	go func() {
+		wg.Wait()
+		close(errs)
+		finished <- true
+	}()
+	select {
+	case <-finished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("concurrent calls were left waiting")
+	}
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitForTurn(t, b, b.current().Turn+1)
+	if err := client.Call(stubs.QuitHandler, control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-evolve.Done:
+		if evolve.Error != nil {
+			t.Fatal(evolve.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("quitting left EvolveWorld running")
+	}
+}
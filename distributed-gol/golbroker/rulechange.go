@@ -0,0 +1,96 @@
+package golbroker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// ruleChange is a rule or edge mode asked for with SetRule, waiting for the evolution loop to reach a turn
+// boundary. A nil field leaves the run's current one alone.
+type ruleChange struct {
+	rule *kernel.Rule
+	edge *kernel.Edge
+}
+
+// SetRule changes the rule or edge mode of the run from the next turn boundary on, so the turn in progress is
+// still finished under the old ones. Asking again before the boundary is reached replaces whichever of the two
+// is given again. A paused run switches when it is resumed.
+func (b *Broker) SetRule(req stubs.SetRuleRequest, res *stubs.Empty) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	if req.Rule == "" && req.Edge == "" {
+		return errors.New("neither a rule nor an edge mode was given")
+	}
+	change := ruleChange{}
+	if req.Rule != "" {
+		rule, err := kernel.ParseRule(req.Rule)
+		if err != nil {
+			return err
+		}
+		change.rule = &rule
+	}
+	if req.Edge != "" {
+		edge, err := kernel.ParseEdge(req.Edge)
+		if err != nil {
+			return err
+		}
+		change.edge = &edge
+	}
+
+	b.FenceMu.Lock()
+	defer b.FenceMu.Unlock()
+	if pending := b.RuleChange; pending != nil {
+		if change.rule == nil {
+			change.rule = pending.rule
+		}
+		if change.edge == nil {
+			change.edge = pending.edge
+		}
+	}
+	b.RuleChange = &change
+	return
+}
+
+// applyRuleChange switches opts to the rule or edge mode asked for with SetRule, if any, and records the switch in
+// the broker's log. States seen under the old rule say nothing about the new one, so cycle detection starts again
+// from the current world. It must be called with Mu held, between turns.
+func (b *Broker) applyRuleChange(opts *kernel.Options) {
+	b.FenceMu.Lock()
+	change := b.RuleChange
+	b.RuleChange = nil
+	b.FenceMu.Unlock()
+	if change == nil {
+		return
+	}
+	from := fmt.Sprintf("%s with %s edges", opts.Rule, opts.Edge)
+	if change.rule != nil {
+		opts.Rule = *change.rule
+	}
+	if change.edge != nil {
+		opts.Edge = *change.edge
+	}
+	to := fmt.Sprintf("%s with %s edges", opts.Rule, opts.Edge)
+	if to == from {
+		return
+	}
+
+	b.Rule, b.Edge = opts.Rule.String(), opts.Edge.String()
+	b.publish() // Checkpoints and clients continuing the run pick up the new rule from here.
+	b.audit(b.Audit.Rule(b.Rule, b.Edge))
+	b.resetStates()
+	b.restartTriggers(opts.Edge)
+
+	text := fmt.Sprintf("Turn %d: changed from %s to %s", b.Turn, from, to)
+	if !b.Logs.toStdout() {
+		fmt.Println(text)
+	}
+	// The combined worker log gets the line too, so the change can be lined up with what the workers did either side.
+	if err := b.Logs.write("broker", []stubs.LogLine{{Time: time.Now(), Text: text}}); err != nil {
+		fmt.Printf("Warning: couldn't log the change of rule: %v\n", err)
+	}
+}
@@ -0,0 +1,69 @@
+package golbroker
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestSetRule switches a paused run to a rule under which every cell dies, given in two parts, and checks it takes
+// effect on the next turn, is published with the run and is written to the combined log.
+func TestSetRule(t *testing.T) {
+	var log bytes.Buffer
+	b := &Broker{Lease: time.Minute}
+	b.Logs.out = &log
+	epoch := acquire(t, b)
+	control := stubs.ControlRequest{Epoch: epoch}
+	done := make(chan error)
+	go func() {
+		req := stubs.EvolveWorldRequest{World: gliderWorld(16), Turn: 1 << 30, ImageWidth: 16, ImageHeight: 16, Epoch: epoch}
+		done <- b.EvolveWorld(req, &stubs.EvolveResponse{})
+	}()
+	waitForTurn(t, b, 1)
+	if err := b.Pause(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	paused := b.current().Turn
+
+	for _, bad := range []stubs.SetRuleRequest{{Epoch: epoch}, {Epoch: epoch, Rule: "B3/S9"}, {Epoch: epoch, Edge: "klein"}, {Epoch: epoch + 1, Rule: "B/S"}} {
+		if err := b.SetRule(bad, &stubs.Empty{}); err == nil {
+			t.Errorf("%+v was accepted", bad)
+		}
+	}
+	if err := b.SetRule(stubs.SetRuleRequest{Epoch: epoch, Rule: "B/S"}, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetRule(stubs.SetRuleRequest{Epoch: epoch, Edge: "dead"}, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.current(); s.Turn != paused || s.Rule != "B3/S23" || countAlive(s.World) != 5 {
+		t.Fatalf("the paused run changed to %s at turn %d with %d alive before being resumed", s.Rule, s.Turn, countAlive(s.World))
+	}
+
+	if err := b.RunUntil(stubs.RunUntilRequest{Epoch: epoch, Turn: paused + 1}, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTurn(t, b, paused+1)
+	if s := b.current(); s.Rule != "B/S" || s.Edge != "dead" || countAlive(s.World) != 0 {
+		t.Errorf("turn %d ran under %s with %s edges and left %d alive, expected B/S with dead edges to kill the glider",
+			s.Turn, s.Rule, s.Edge, countAlive(s.World))
+	}
+	expected := fmt.Sprintf("[broker] Turn %d: changed from B3/S23 with torus edges to B/S with dead edges\n", paused)
+	if !strings.HasSuffix(log.String(), expected) {
+		t.Errorf("combined log is %q, expected it to end with %q", log.String(), expected)
+	}
+
+	if err := b.Unpause(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.QuitServer(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
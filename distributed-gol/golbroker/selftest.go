@@ -0,0 +1,54 @@
+package golbroker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"uk.ac.bris.cs/gameoflife/selftest"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// SelfTest checks the broker's own kernel and every worker it is connected to give the right answers, as
+// -selftest does, so a client can validate the whole deployment.
+func (b *Broker) SelfTest(req stubs.Empty, res *stubs.SelfTestResponse) (err error) {
+	report := &bytes.Buffer{}
+	res.Passed = b.selfTest(report)
+	res.Report = report.String()
+	return
+}
+
+// selfTest writes a PASS or FAIL line for each check of the broker's kernel and of each worker to out, and
+// returns whether all of them passed.
+func (b *Broker) selfTest(out io.Writer) bool {
+	passed := selftest.Report(out, fmt.Sprintf("broker (%v kernel)", b.Algorithm), selftest.Run(selftest.Kernel(b.Algorithm)))
+	workers := b.workers()
+	if len(workers) == 0 {
+		fmt.Fprintln(out, "No workers to check")
+	}
+	for i, client := range workers {
+		what := fmt.Sprintf("worker %d (%s)", i, b.Stats.workerAddress(i))
+		passed = selftest.Report(out, what, selftest.Run(workerEvolver(client))) && passed
+	}
+	return passed
+}
+
+// workerEvolver evolves worlds by asking a worker for every row of each turn, as the slices of stubs.SelfTestJob.
+func workerEvolver(client *workerConn) selftest.Evolver {
+	return func(world [][]byte, turns int) ([][]byte, error) {
+		current := slab.FromRows(world)
+		height, width := current.Height, current.Width
+		for turn := 0; turn < turns; turn++ {
+			req := stubs.WorldReq{World: current, Width: width, Height: height, StartRow: 0, EndRow: height, Job: stubs.SelfTestJob, Turn: turn}
+			res := &stubs.WorldRes{}
+			if err := client.Call(stubs.WorldHandler, req, res); err != nil {
+				return nil, err
+			}
+			if !res.Intact() {
+				return nil, fmt.Errorf("turn %d came back corrupt: checksum doesn't match", turn+1)
+			}
+			current = res.World
+		}
+		return current.Rows(), nil
+	}
+}
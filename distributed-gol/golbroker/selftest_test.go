@@ -0,0 +1,37 @@
+package golbroker
+
+import (
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestSelfTest checks the self-test passes the broker and a correct worker, and picks out a worker that
+// computes slices wrongly.
+func TestSelfTest(t *testing.T) {
+	correct, stopCorrect := serveWorker(t, &lifeWorker{})
+	defer stopCorrect()
+	wrong, stopWrong := serveWorker(t, &countingWorker{})
+	defer stopWrong()
+	b := &Broker{Workers: []*workerConn{correct, wrong}}
+	b.Stats.setWorkers([]string{"correct:8040", "wrong:8041"})
+
+	res := &stubs.SelfTestResponse{}
+	if err := b.SelfTest(stubs.Empty{}, res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Passed {
+		t.Errorf("the self-test passed with a worker that computes slices wrongly:\n%s", res.Report)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(res.Report), "\n") {
+		if !strings.Contains(line, "wrong:8041") && !strings.HasPrefix(line, "PASS") {
+			t.Errorf("expected only the wrong worker's checks to fail, got %q", line)
+		}
+	}
+	if !strings.Contains(res.Report, "PASS broker (bytes kernel): matches the reference") ||
+		!strings.Contains(res.Report, "PASS worker 0 (correct:8040): matches the reference") ||
+		!strings.Contains(res.Report, "FAIL worker 1 (wrong:8041): blinker oscillates") {
+		t.Errorf("expected the broker and worker 0 to pass and worker 1 to fail, got:\n%s", res.Report)
+	}
+}
@@ -0,0 +1,43 @@
+package golbroker
+
+import (
+	"net"
+	"net/rpc"
+	"time"
+)
+
+// Serve runs a broker with the defaults of go run ./engine on l, splitting turns between the workers at the given
+// addresses, until l is closed. Unlike Main it registers the broker with an RPC server of its own and leaves the
+// process alone when killed, just closing l, so it can share the process with the client and its workers, e.g. on
+// a memnet listener.
+func Serve(l net.Listener, workerAddresses []string) error {
+	var workers []*workerConn
+	for _, address := range workerAddresses {
+		worker, err := dialWorker(address)
+		if err != nil {
+			return err
+		}
+		workers = append(workers, worker)
+	}
+	b := &Broker{Workers: workers, Lease: 10 * time.Second, Shed: true, JobDir: "jobs", JobCheckpoint: 5 * time.Minute,
+		Retention: retention{Last: 1}}
+	b.Stats.setWorkers(workerAddresses)
+
+	server := rpc.NewServer()
+	if err := server.Register(b); err != nil {
+		return err
+	}
+	// KillServer waits for the kill to be taken, which Main does by exiting.
+	go func() {
+		<-kill
+		l.Close()
+	}()
+	// Accepting by hand rather than with server.Accept, which logs the error a closed listener gives.
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return nil
+		}
+		go server.ServeConn(conn)
+	}
+}
@@ -0,0 +1,71 @@
+package golbroker
+
+import (
+	"errors"
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// SetCells sets cells of a soft paused run alive or dead, e.g. ones drawn in the window, and queues those that changed
+// as flips of the turn paused at, so the live view draws them on its next poll. The edited world is published
+// straight away, so it is what is saved or counted while paused and what the next turn is computed from. The states
+// seen so far say nothing about the edited world, so cycle detection and the triggers start again from it.
+func (b *Broker) SetCells(req stubs.SetCellsRequest, res *stubs.SetCellsResponse) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	b.FenceMu.Lock()
+	hard := b.Paused
+	b.FenceMu.Unlock()
+	if hard {
+		// Mu is held until the run is resumed, so the world can't be changed.
+		return errors.New("cells can't be set while the run is hard paused")
+	}
+
+	// A soft paused loop is held between turns with Mu free, and can't carry on while it is held here.
+	b.Mu.Lock()
+	defer b.Mu.Unlock()
+	b.FenceMu.Lock()
+	paused := b.SoftPaused
+	b.FenceMu.Unlock()
+	if !paused {
+		return errors.New("cells can only be set while the run is paused")
+	}
+	for _, cell := range req.Cells {
+		if cell.X < 0 || cell.Y < 0 || cell.X >= b.World.Width || cell.Y >= b.World.Height {
+			return fmt.Errorf("cell (%d, %d) is outside the %dx%d world", cell.X, cell.Y, b.World.Width, b.World.Height)
+		}
+	}
+
+	state := util.Dead
+	if req.Alive {
+		state = util.Alive
+	}
+	// A published world is never modified, so the edit is made to a copy.
+	world := b.World.Clone()
+	var flipped []util.Cell
+	for _, cell := range req.Cells {
+		if world.At(cell.X, cell.Y) != state {
+			world.Set(cell.X, cell.Y, state)
+			flipped = append(flipped, cell)
+		}
+	}
+	res.Turn, res.Changed = b.Turn, len(flipped)
+	if len(flipped) == 0 {
+		return
+	}
+
+	b.World = world
+	b.publish()
+	b.audit(b.Audit.Edit(world))
+	b.queueFlips(gol.Params{ImageWidth: world.Width, ImageHeight: world.Height}, flipped)
+	b.resetStates()
+	if edge, err := kernel.ParseEdge(b.Edge); err == nil {
+		b.restartTriggers(edge)
+	}
+	return
+}
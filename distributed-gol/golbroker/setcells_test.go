@@ -0,0 +1,75 @@
+package golbroker
+
+import (
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestSetCells draws a block on a paused run of an empty world, checking it is refused while the run is going, published
+// straight away with its flips queued for the live view, and evolved from once the run is resumed.
+func TestSetCells(t *testing.T) {
+	b := &Broker{Lease: time.Minute}
+	epoch := acquire(t, b)
+	control := stubs.ControlRequest{Epoch: epoch}
+	done := make(chan error)
+	go func() {
+		req := stubs.EvolveWorldRequest{World: slab.New(16, 16), Turn: 1 << 30, ImageWidth: 16, ImageHeight: 16, Epoch: epoch}
+		done <- b.EvolveWorld(req, &stubs.EvolveResponse{})
+	}()
+	waitForTurn(t, b, 1)
+	block := []util.Cell{{X: 10, Y: 10}, {X: 11, Y: 10}, {X: 10, Y: 11}, {X: 11, Y: 11}}
+	if err := b.SetCells(stubs.SetCellsRequest{Epoch: epoch, Cells: block, Alive: true}, &stubs.SetCellsResponse{}); err == nil {
+		t.Error("cells were set while the run was going")
+	}
+	if err := b.Pause(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	paused := b.current().Turn
+	b.GetCellFlipped(stubs.Empty{}, &stubs.GetBrokerCellFlippedResponse{})
+
+	for _, bad := range []stubs.SetCellsRequest{{Epoch: epoch + 1, Cells: block}, {Epoch: epoch, Cells: []util.Cell{{X: 16, Y: 0}}}} {
+		if err := b.SetCells(bad, &stubs.SetCellsResponse{}); err == nil {
+			t.Errorf("%+v was accepted", bad)
+		}
+	}
+	res := &stubs.SetCellsResponse{}
+	// One cell of the block is drawn twice, as overlapping strokes of a brush do.
+	if err := b.SetCells(stubs.SetCellsRequest{Epoch: epoch, Cells: append(block, block[0]), Alive: true}, res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Turn != paused || res.Changed != 4 {
+		t.Errorf("changed %d cells at turn %d, expected 4 at turn %d", res.Changed, res.Turn, paused)
+	}
+	if s := b.current(); s.Turn != paused || countAlive(s.World) != 4 {
+		t.Errorf("turn %d was published with %d alive, expected turn %d with the block", s.Turn, countAlive(s.World), paused)
+	}
+	flipped := &stubs.GetBrokerCellFlippedResponse{}
+	if err := b.GetCellFlipped(stubs.Empty{}, flipped); err != nil {
+		t.Fatal(err)
+	}
+	if len(flipped.FlippedEvents) != 4 || flipped.FlippedEvents[0].CompletedTurns != paused {
+		t.Errorf("the live view was sent %v, expected the block's 4 cells flipped at turn %d", flipped.FlippedEvents, paused)
+	}
+
+	if err := b.RunUntil(stubs.RunUntilRequest{Epoch: epoch, Turn: paused + 1}, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTurn(t, b, paused+1)
+	if s := b.current(); s.Turn != paused+1 || countAlive(s.World) != 4 || s.World.At(10, 10) != util.Alive {
+		t.Errorf("turn %d has %d alive, expected the block to still be there at turn %d", s.Turn, countAlive(s.World), paused+1)
+	}
+
+	if err := b.Unpause(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.QuitServer(control, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
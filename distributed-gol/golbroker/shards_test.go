@@ -0,0 +1,107 @@
+package golbroker
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// savingWorker is a lifeWorker that keeps its last slice and saves it when asked, as the real worker does.
+type savingWorker struct {
+	lifeWorker
+	mu     sync.Mutex
+	latest stubs.WorldRes
+}
+
+func (w *savingWorker) CalculateWorld(req *stubs.WorldReq, res *stubs.WorldRes) (err error) {
+	w.lifeWorker.CalculateWorld(req, res)
+	w.mu.Lock()
+	w.latest = *res
+	w.mu.Unlock()
+	return
+}
+
+func (w *savingWorker) SaveSlice(req *stubs.SaveSliceReq, res *stubs.Empty) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.latest.Job != req.Job || w.latest.Turn != req.Turn || w.latest.StartRow != req.StartRow || w.latest.EndRow != req.EndRow {
+		return fmt.Errorf("asked for rows %d-%d of turn %d, but kept rows %d-%d of turn %d",
+			req.StartRow, req.EndRow, req.Turn, w.latest.StartRow, w.latest.EndRow, w.latest.Turn)
+	}
+	return ioutil.WriteFile(req.Path, util.FormatPgm(w.latest.World.Rows()), 0644)
+}
+
+// TestSaveShards checks a world saved as shards is written by the workers that computed each slice, with the
+// broker saving the slice of a worker that can't, and that the shards listed in the manifest make up the world.
+func TestSaveShards(t *testing.T) {
+	saving, stopSaving := serveWorker(t, &savingWorker{})
+	defer stopSaving()
+	plain, stopPlain := serveWorker(t, &lifeWorker{})
+	defer stopPlain()
+	b := &Broker{Workers: []*workerConn{saving, plain}, Lease: time.Minute}
+	epoch := acquire(t, b)
+	req := stubs.EvolveWorldRequest{World: gliderWorld(16), Turn: 5, ImageWidth: 16, ImageHeight: 16, Epoch: epoch}
+	if err := b.EvolveWorld(req, &stubs.EvolveResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "shards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	res := &stubs.SaveShardsResponse{}
+	if err := b.SaveShards(stubs.SaveShardsRequest{Dir: dir}, res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Turn != 5 || len(res.Shards) != 2 {
+		t.Fatalf("saved turn %d as %d shards, expected turn 5 as 2", res.Turn, len(res.Shards))
+	}
+	if res.Shards[0].Worker != 0 || res.Shards[1].Worker != -1 {
+		t.Errorf("shards were saved by %d and %d, expected worker 0 and the broker", res.Shards[0].Worker, res.Shards[1].Worker)
+	}
+
+	// Reassemble the world from the manifest's shards.
+	manifest, err := os.Open(res.Manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manifest.Close()
+	var world [][]byte
+	scanner := bufio.NewScanner(manifest)
+	for scanner.Scan() {
+		var start, end int
+		var file string
+		if _, err := fmt.Sscanf(scanner.Text(), "rows %d %d %s", &start, &end, &file); err != nil {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		width, height, cells, err := util.ParsePgm(data, 0)
+		if err != nil || width != 16 || height != end-start {
+			t.Fatalf("%s is %dx%d (%v), expected 16x%d", file, width, height, err, end-start)
+		}
+		for i := 0; i < height; i++ {
+			world = append(world, cells[i*width:(i+1)*width])
+		}
+	}
+	if len(world) != b.World.Height {
+		t.Fatalf("the shards hold %d rows, expected %d", len(world), b.World.Height)
+	}
+	for i := range world {
+		if !bytes.Equal(world[i], b.World.Row(i)) {
+			t.Fatalf("row %d of the shards differs from the world", i)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package golbroker
+
+import (
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// queueFlips queues the cells flipped by the turn just completed for the live view, tagged with the turn. A client
+// on a slow link can fall behind until the queue holds more flips than the world has cells, at which point sending
+// the world itself is cheaper, so with Shed set the flips are dropped from then until the client next polls and it
+// gets a keyframe instead. Dropping them costs the evolution loop nothing, however far behind the client is. It
+// must be called with Mu held.
+func (b *Broker) queueFlips(p gol.Params, flipped []util.Cell) {
+	if b.Shedding {
+		return
+	}
+	for _, cell := range flipped {
+		b.FlippedEvents = append(b.FlippedEvents, stubs.FlippedEvent{CompletedTurns: b.Turn, Cell: cell})
+	}
+	if b.Shed && len(b.FlippedEvents) > p.ImageWidth*p.ImageHeight {
+		b.Shedding = true
+		b.FlippedEvents = nil
+		return
+	}
+	// Without a client polling, e.g. with -noVis, the queue would grow every turn until the broker ran out
+	// of memory, so past a limit it is cut down to each cell's net change since the last poll.
+	if len(b.FlippedEvents) > maxQueuedFlips(p) {
+		b.FlippedEvents = coalesceFlips(b.FlippedEvents, b.Turn)
+	}
+}
+
+// sendKeyframe puts the whole world in a poll's response if the flips since the last poll were shed, and notes
+// the turn the client is now up to. It must be called with Mu held.
+func (b *Broker) sendKeyframe(res *stubs.GetBrokerCellFlippedResponse) {
+	if b.Shedding {
+		b.Shedding = false
+		res.Keyframe = b.World // Shared, as a world is never modified once the turn it belongs to is complete.
+		res.Skipped = b.Turn - b.Polled
+	}
+	b.Polled = b.Turn
+}
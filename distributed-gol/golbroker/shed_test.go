@@ -0,0 +1,63 @@
+package golbroker
+
+import (
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestShedFlips runs a glider without polling for its flips until they outnumber the world's cells, and checks the
+// next poll gets the world and how many turns it skipped rather than the flips, after which flips are sent again.
+func TestShedFlips(t *testing.T) {
+	const size, turns = 8, 40 // A glider flips several cells a turn, well over 64 in 40 turns.
+	b := &Broker{Lease: time.Minute, Shed: true}
+	epoch := acquire(t, b)
+	req := stubs.EvolveWorldRequest{World: gliderWorld(size), Turn: turns, ImageWidth: size, ImageHeight: size, Epoch: epoch}
+	if err := b.EvolveWorld(req, &stubs.EvolveResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.FlippedEvents) != 0 {
+		t.Errorf("%d flips were queued for a client that fell behind", len(b.FlippedEvents))
+	}
+
+	res := &stubs.GetBrokerCellFlippedResponse{}
+	if err := b.GetCellFlipped(stubs.Empty{}, res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.FlippedEvents) != 0 || !res.Keyframe.Equal(b.World) || res.Skipped != turns {
+		t.Errorf("got %d flips and a keyframe of %d alive skipping %d turns, expected the world at turn %d alone",
+			len(res.FlippedEvents), countAlive(res.Keyframe), res.Skipped, turns)
+	}
+
+	// Caught up, so the next turns' flips are queued again.
+	b.Turn++
+	b.queueFlips(gol.Params{ImageWidth: size, ImageHeight: size}, []util.Cell{{X: 1, Y: 1}})
+	res = &stubs.GetBrokerCellFlippedResponse{}
+	if err := b.GetCellFlipped(stubs.Empty{}, res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.FlippedEvents) != 1 || res.Keyframe.Cells != nil {
+		t.Errorf("got %d flips and a keyframe of %d cells after catching up, expected the one flip", len(res.FlippedEvents), len(res.Keyframe.Cells))
+	}
+}
+
+// TestNoShed checks a broker told not to shed keeps queueing every flip, however far behind the client is.
+func TestNoShed(t *testing.T) {
+	const size, turns = 8, 40
+	b := &Broker{Lease: time.Minute}
+	epoch := acquire(t, b)
+	req := stubs.EvolveWorldRequest{World: gliderWorld(size), Turn: turns, ImageWidth: size, ImageHeight: size, Epoch: epoch}
+	if err := b.EvolveWorld(req, &stubs.EvolveResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	res := &stubs.GetBrokerCellFlippedResponse{}
+	if err := b.GetCellFlipped(stubs.Empty{}, res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.FlippedEvents) <= size*size || res.Keyframe.Cells != nil {
+		t.Errorf("got %d flips and a keyframe of %d cells, expected every flip", len(res.FlippedEvents), len(res.Keyframe.Cells))
+	}
+}
@@ -0,0 +1,107 @@
+package golbroker
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// countingWorker is an in-process stand-in for a worker that adds one to every cell of its slice.
+// Starting from an empty world, every cell of generation t is t mod 256, so a world mixing rows
+// from two generations is easy to spot.
+type countingWorker struct{}
+
+func (w *countingWorker) CalculateWorld(req *stubs.WorldReq, res *stubs.WorldRes) (err error) {
+	res.World = slab.New(req.Width, req.EndRow-req.StartRow)
+	for i := 0; i < res.World.Height; i++ {
+		for j, cell := range req.World.Row(req.StartRow + i) {
+			res.World.Set(j, i, cell+1)
+		}
+	}
+	res.Job, res.Turn, res.StartRow, res.EndRow = req.Job, req.Turn, req.StartRow, req.EndRow
+	res.Seal()
+	// Give the readers a chance to run in the middle of a turn.
+	runtime.Gosched()
+	return
+}
+
+// startCountingWorkers serves n counting workers in memory and returns clients connected to them.
+func startCountingWorkers(t *testing.T, n int) []*workerConn {
+	var clients []*workerConn
+	for i := 0; i < n; i++ {
+		client, _ := serveWorker(t, &countingWorker{})
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// TestSnapshotIsolation hammers the read RPCs during an evolution and checks each sees one whole generation.
+func TestSnapshotIsolation(t *testing.T) {
+	const size, turns = 32, 200
+	b := &Broker{Workers: startCountingWorkers(t, 4), Lease: time.Minute}
+	epoch := acquire(t, b)
+
+	evolve := stubs.EvolveWorldRequest{World: slab.New(size, size), Turn: turns, ImageWidth: size, ImageHeight: size, Epoch: epoch}
+
+	done := make(chan bool)
+	go func() {
+		if err := b.EvolveWorld(evolve, &stubs.EvolveResponse{}); err != nil {
+			t.Errorf("EvolveWorld failed: %v", err)
+		}
+		close(done)
+	}()
+
+	// checkGeneration fails the test unless every cell belongs to the given turn.
+	checkGeneration := func(generation slab.World, turn int) {
+		world := generation.Rows()
+		for i := range world {
+			for j := range world[i] {
+				if world[i][j] != byte(turn) {
+					t.Errorf("world for turn %d has cell (%d, %d) from turn %d", turn, j, i, world[i][j])
+					return
+				}
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lastTurn := 0
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				res := &stubs.GetGlobalResponse{}
+				if err := b.GetGlobal(stubs.Empty{}, res); err != nil {
+					t.Errorf("GetGlobal failed: %v", err)
+					return
+				}
+				if res.Turn < lastTurn {
+					t.Errorf("GetGlobal went back from turn %d to %d", lastTurn, res.Turn)
+				}
+				lastTurn = res.Turn
+				if res.World.Cells != nil {
+					checkGeneration(res.World, res.Turn)
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+	wg.Wait()
+
+	res := &stubs.GetGlobalResponse{}
+	b.GetGlobal(stubs.Empty{}, res)
+	if res.Turn != turns {
+		t.Fatalf("final snapshot is for turn %d, expected %d", res.Turn, turns)
+	}
+	checkGeneration(res.World, res.Turn)
+}
@@ -0,0 +1,111 @@
+package golbroker
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// uploads holds the world a client is sending in chunks, until EvolveWorld starts from it. Only one is kept: only
+// the client in control may upload and it starts one run at a time, so an abandoned upload is dropped by the next
+// rather than left taking up memory. It has its own mutex, so a chunk can arrive while the broker is paused.
+type uploads struct {
+	mu      sync.Mutex
+	next    int // ID of the latest upload.
+	world   slab.World
+	filled  int  // Cells received so far.
+	done    bool // Whether the upload has been committed.
+	pending bool // Whether there is an upload that hasn't been evolved yet.
+}
+
+// find returns an error unless id is the upload in progress. The caller must hold mu.
+func (u *uploads) find(id int) error {
+	if !u.pending || id != u.next {
+		return fmt.Errorf("upload %d isn't in progress", id)
+	}
+	return nil
+}
+
+// take hands over a committed upload's world, which can only be done once.
+func (u *uploads) take(id int) (slab.World, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := u.find(id); err != nil {
+		return slab.World{}, err
+	}
+	if !u.done {
+		return slab.World{}, fmt.Errorf("upload %d hasn't been committed", id)
+	}
+	world := u.world
+	u.world, u.pending = slab.World{}, false
+	return world, nil
+}
+
+// BeginUpload starts receiving a world in chunks, discarding any earlier upload, and returns its ID.
+func (b *Broker) BeginUpload(req stubs.BeginUploadRequest, res *stubs.BeginUploadResponse) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	if req.Width <= 0 || req.Height <= 0 {
+		return fmt.Errorf("cannot upload a %dx%d world", req.Width, req.Height)
+	}
+	u := &b.Uploads
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.next++
+	u.world, u.filled, u.done, u.pending = slab.New(req.Width, req.Height), 0, false, true
+	res.ID = u.next
+	return
+}
+
+// AppendUpload adds a chunk to the upload in progress. Chunks must arrive in order, and one whose checksum doesn't
+// match is refused, so the client can send it again.
+func (b *Broker) AppendUpload(req stubs.AppendUploadRequest, res *stubs.Empty) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	u := &b.Uploads
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err = u.find(req.ID); err != nil {
+		return
+	}
+	switch {
+	case u.done:
+		return fmt.Errorf("upload %d has already been committed", req.ID)
+	case crc32.ChecksumIEEE(req.Cells) != req.Checksum:
+		return fmt.Errorf("chunk at cell %d of upload %d is corrupt: checksum doesn't match", req.Offset, req.ID)
+	case req.Offset != u.filled:
+		return fmt.Errorf("chunk of upload %d starts at cell %d, expected %d", req.ID, req.Offset, u.filled)
+	case u.filled+len(req.Cells) > len(u.world.Cells):
+		return fmt.Errorf("upload %d has more cells than its %dx%d world", req.ID, u.world.Width, u.world.Height)
+	}
+	u.filled += copy(u.world.Cells[u.filled:], req.Cells)
+	return
+}
+
+// CommitUpload checks every cell of an upload has arrived intact, after which EvolveWorld can start from it.
+// An upload that doesn't match its checksum is discarded, as it can't be told which chunk was wrong.
+func (b *Broker) CommitUpload(req stubs.CommitUploadRequest, res *stubs.Empty) (err error) {
+	if err = b.checkEpoch(req.Epoch); err != nil {
+		return
+	}
+	u := &b.Uploads
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err = u.find(req.ID); err != nil {
+		return
+	}
+	if u.filled != len(u.world.Cells) {
+		return fmt.Errorf("upload %d has %d of its %d cells", req.ID, u.filled, len(u.world.Cells))
+	}
+	if crc32.ChecksumIEEE(u.world.Cells) != req.Checksum {
+		u.world, u.pending = slab.World{}, false
+		return errors.New("upload is corrupt: checksum of the world doesn't match")
+	}
+	u.done = true
+	return
+}
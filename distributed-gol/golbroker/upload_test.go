@@ -0,0 +1,73 @@
+package golbroker
+
+import (
+	"hash/crc32"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestUpload checks a world uploaded in chunks can be evolved once committed, and that chunks out of order or
+// damaged, and uploads missing cells or never committed, are refused.
+func TestUpload(t *testing.T) {
+	const size = 16
+	b := &Broker{Lease: time.Minute}
+	epoch := acquire(t, b)
+	world := gliderWorld(size)
+
+	begin := func() int {
+		res := &stubs.BeginUploadResponse{}
+		if err := b.BeginUpload(stubs.BeginUploadRequest{Epoch: epoch, Width: size, Height: size}, res); err != nil {
+			t.Fatal(err)
+		}
+		return res.ID
+	}
+	appendCells := func(id, offset int, cells []byte) error {
+		req := stubs.AppendUploadRequest{Epoch: epoch, ID: id, Offset: offset, Cells: cells, Checksum: crc32.ChecksumIEEE(cells)}
+		return b.AppendUpload(req, &stubs.Empty{})
+	}
+	commit := func(id int) error {
+		return b.CommitUpload(stubs.CommitUploadRequest{Epoch: epoch, ID: id, Checksum: crc32.ChecksumIEEE(world.Cells)}, &stubs.Empty{})
+	}
+
+	id := begin()
+	half := len(world.Cells) / 2
+	if err := appendCells(id, half, world.Cells[half:]); err == nil {
+		t.Error("a chunk out of order was accepted")
+	}
+	if err := appendCells(id, 0, world.Cells[:half]); err != nil {
+		t.Fatal(err)
+	}
+	if err := commit(id); err == nil {
+		t.Error("an upload missing half its cells was committed")
+	}
+	damaged := stubs.AppendUploadRequest{Epoch: epoch, ID: id, Offset: half, Cells: world.Cells[half:], Checksum: 1}
+	if err := b.AppendUpload(damaged, &stubs.Empty{}); err == nil {
+		t.Error("a chunk that doesn't match its checksum was accepted")
+	}
+	if err := b.EvolveWorld(stubs.EvolveWorldRequest{Upload: id, Epoch: epoch}, &stubs.EvolveResponse{}); err == nil {
+		t.Error("EvolveWorld started from an upload that hadn't been committed")
+	}
+	if err := appendCells(id, half, world.Cells[half:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := commit(id); err != nil {
+		t.Fatal(err)
+	}
+
+	// The glider goes all the way round the world, so the run ends where the upload started.
+	req := stubs.EvolveWorldRequest{Upload: id, Turn: 4 * size, ImageWidth: size, ImageHeight: size, Epoch: epoch}
+	res := &stubs.EvolveResponse{}
+	if err := b.EvolveWorld(req, res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Turn != 4*size || !res.World.Equal(world) {
+		t.Errorf("evolved the upload to turn %d with %d alive cells, expected the glider back where it started", res.Turn, countAlive(res.World))
+	}
+
+	epoch = acquire(t, b)
+	if err := b.EvolveWorld(stubs.EvolveWorldRequest{Upload: id, Epoch: epoch}, &stubs.EvolveResponse{}); err == nil {
+		t.Error("an upload was evolved twice")
+	}
+}
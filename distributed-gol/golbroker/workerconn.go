@@ -0,0 +1,132 @@
+package golbroker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/chaos"
+	"uk.ac.bris.cs/gameoflife/memnet"
+)
+
+// dialTimeout bounds how long dialling a worker may take, so a worker whose host has vanished can't hold up a turn.
+const dialTimeout = 2 * time.Second
+
+// redialBackoff is how long the broker waits after failing to reach a worker before dialling it again. Meanwhile
+// its slices are computed on the broker, rather than every turn waiting for a dial that is likely to fail.
+const redialBackoff = time.Second
+
+// faults are injected into every connection to a worker with -chaos, or nil for none.
+var faults *chaos.Faults
+
+// errDisconnected is returned for a worker whose connection has broken and can't be dialled again yet.
+var errDisconnected = errors.New("worker is disconnected")
+
+// workerConn is the broker's connection to one worker. A connection that breaks, e.g. in a network blip, is
+// dropped and the worker dialled again at its address the next time it is needed, so a worker that comes back
+// rejoins the run rather than being lost to it for good.
+type workerConn struct {
+	Address string // Where the worker listens, or "" if it can't be dialled again.
+
+	mu      sync.Mutex
+	client  *rpc.Client // Current connection, or nil once it has broken.
+	retryAt time.Time   // Time before which the worker isn't dialled again after a failed dial.
+}
+
+// newWorkerConn wraps a connection to the worker at address.
+func newWorkerConn(address string, client *rpc.Client) *workerConn {
+	return &workerConn{Address: address, client: client}
+}
+
+// dialWorker connects to the worker at address.
+func dialWorker(address string) (*workerConn, error) {
+	client, err := dialRPC(address)
+	if err != nil {
+		return nil, err
+	}
+	return newWorkerConn(address, client), nil
+}
+
+// dialRPC opens an RPC connection to address, giving up after dialTimeout.
+func dialRPC(address string) (*rpc.Client, error) {
+	conn, err := memnet.DialTimeout(address, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.NewClient(faults.Wrap(conn, address)), nil
+}
+
+// get returns the current connection to the worker, dialling it again if the last one broke.
+func (w *workerConn) get() (*rpc.Client, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.client != nil {
+		return w.client, nil
+	}
+	if w.Address == "" || time.Now().Before(w.retryAt) {
+		return nil, errDisconnected
+	}
+	client, err := dialRPC(w.Address)
+	if err != nil {
+		w.retryAt = time.Now().Add(redialBackoff)
+		return nil, err
+	}
+	fmt.Printf("Reconnected to worker on %s\n", w.Address)
+	w.client = client
+	return client, nil
+}
+
+// broken drops client, a connection to the worker that has failed, so the next call dials the worker again.
+// A connection that has already been replaced is left alone, as another call may have reconnected first.
+func (w *workerConn) broken(client *rpc.Client) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.client == client {
+		w.client.Close()
+		w.client = nil
+	}
+}
+
+// Call calls the worker, and if the connection turns out to be broken calls it once more over a new one.
+// Only requests that are safe to repeat may be sent this way.
+func (w *workerConn) Call(serviceMethod string, args interface{}, reply interface{}) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var client *rpc.Client
+		if client, err = w.get(); err != nil {
+			return err
+		}
+		if err = client.Call(serviceMethod, args, reply); !isBroken(err) {
+			return err
+		}
+		w.broken(client)
+	}
+	return err
+}
+
+// Close closes the connection to the worker, if it has one.
+func (w *workerConn) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.client == nil {
+		return nil
+	}
+	err := w.client.Close()
+	w.client = nil
+	return err
+}
+
+// isBroken reports whether an RPC failed because its connection broke, rather than being refused by the worker.
+func isBroken(err error) bool {
+	switch err.(type) {
+	case nil, rpc.ServerError:
+		return false
+	case net.Error:
+		return true
+	}
+	return err == rpc.ErrShutdown || err == io.EOF || err == io.ErrUnexpectedEOF
+}
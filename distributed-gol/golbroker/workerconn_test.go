@@ -0,0 +1,173 @@
+package golbroker
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"testing"
+	"time"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// flakyListener remembers the connections it accepts, so a test can cut them all as a network blip would.
+type flakyListener struct {
+	net.Listener
+	mu       sync.Mutex
+	conns    []net.Conn
+	accepted int
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.mu.Lock()
+		l.conns = append(l.conns, conn)
+		l.accepted++
+		l.mu.Unlock()
+	}
+	return conn, err
+}
+
+// blip closes every connection accepted so far, leaving the listener up for the broker to dial again.
+func (l *flakyListener) blip() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+// serveFlakyWorker serves ops like serveWorker, over a listener whose connections the test can cut.
+func serveFlakyWorker(t *testing.T, ops interface{}) (*workerConn, *flakyListener) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("WorldOps", ops); err != nil {
+		t.Fatal(err)
+	}
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := &flakyListener{Listener: inner}
+	go server.Accept(listener)
+	conn, err := dialWorker(inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, listener
+}
+
+// TestReconnect checks a call over a connection cut by a blip is made again over a new one.
+func TestReconnect(t *testing.T) {
+	conn, listener := serveFlakyWorker(t, &lifeWorker{})
+	defer listener.Close()
+	defer conn.Close()
+
+	req := stubs.WorldReq{World: gliderWorld(8), StartRow: 0, EndRow: 8, Width: 8, Height: 8}
+	if err := conn.Call(stubs.WorldHandler, req, &stubs.WorldRes{}); err != nil {
+		t.Fatal(err)
+	}
+	listener.blip()
+	res := &stubs.WorldRes{}
+	if err := conn.Call(stubs.WorldHandler, req, res); err != nil {
+		t.Fatalf("the call after a blip failed: %v", err)
+	}
+	if res.World.Height != 8 {
+		t.Errorf("got %d rows after reconnecting, expected 8", res.World.Height)
+	}
+	listener.mu.Lock()
+	defer listener.mu.Unlock()
+	if listener.accepted != 2 {
+		t.Errorf("the worker accepted %d connections, expected a second after the blip", listener.accepted)
+	}
+}
+
+// blippingWorker is an in-process stand-in for a worker on a flaky network: once, part way through a run, its
+// connection is cut while it computes a slice, so the reply never reaches the broker.
+type blippingWorker struct {
+	lifeWorker
+	listener *flakyListener
+	blipTurn int
+
+	mu     sync.Mutex
+	served map[int]int // Number of times each turn's slice was computed.
+}
+
+func (w *blippingWorker) CalculateWorld(req *stubs.WorldReq, res *stubs.WorldRes) (err error) {
+	w.mu.Lock()
+	w.served[req.Turn]++
+	first := w.served[req.Turn] == 1
+	w.mu.Unlock()
+	if req.Turn == w.blipTurn && first {
+		w.listener.blip()
+	}
+	return w.lifeWorker.CalculateWorld(req, res)
+}
+
+// TestReconnectMidRun checks the slice in flight when a worker's connection breaks is sent again once the broker
+// reconnects, so the worker carries on computing its slices rather than the broker taking them over for good.
+func TestReconnectMidRun(t *testing.T) {
+	const size, blipTurn = 16, 5
+	worker := &blippingWorker{blipTurn: blipTurn, served: make(map[int]int)}
+	conn, listener := serveFlakyWorker(t, worker)
+	defer listener.Close()
+	defer conn.Close()
+	worker.listener = listener
+
+	evolveGliderRoundTrip(t, &Broker{Workers: []*workerConn{conn}, Lease: time.Minute}, size)
+
+	worker.mu.Lock()
+	defer worker.mu.Unlock()
+	for turn := 0; turn < 4*size; turn++ {
+		expected := 1
+		if turn == blipTurn {
+			expected = 2 // Once before the blip and again after reconnecting.
+		}
+		if worker.served[turn] != expected {
+			t.Errorf("the worker computed turn %d's slice %d times, expected %d", turn, worker.served[turn], expected)
+		}
+	}
+}
+
+// TestRedialBackoff checks a worker that can't be dialled again isn't dialled on every call.
+func TestRedialBackoff(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := newWorkerConn(listener.Addr().String(), nil)
+	listener.Close()
+
+	if _, err := conn.get(); err == nil || err == errDisconnected {
+		t.Errorf("got %v dialling a worker that has gone, expected the dial's error", err)
+	}
+	if _, err := conn.get(); err != errDisconnected {
+		t.Errorf("got %v straight after a failed dial, expected %v", err, errDisconnected)
+	}
+	if _, err := newWorkerConn("", nil).get(); err != errDisconnected {
+		t.Errorf("got %v for a worker with no address, expected %v", err, errDisconnected)
+	}
+}
+
+// TestIsBroken checks only errors from the connection, not ones the worker returns, count as broken.
+func TestIsBroken(t *testing.T) {
+	tests := []struct {
+		err    error
+		broken bool
+	}{
+		{nil, false},
+		{rpc.ServerError("no such job"), false},
+		{errors.New("gob: type mismatch"), false},
+		{rpc.ErrShutdown, true},
+		{io.EOF, true},
+		{io.ErrUnexpectedEOF, true},
+		{&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, true},
+	}
+	for _, test := range tests {
+		if got := isBroken(test.err); got != test.broken {
+			t.Errorf("isBroken(%v) = %v, expected %v", test.err, got, test.broken)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package golcli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/affinity"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/storage"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Bench times each kernel evolving an input image on this machine, without the broker or any workers, so a
+// machine's kernels can be compared before choosing the broker's and workers' -kernel. With -cpus each kernel is
+// timed again with its goroutines pinned to those CPUs, as a worker's -cpus would, to see whether pinning pays off.
+//
+//	go run . bench -w 512 -h 512 -turns 100 -kernels bytes,bitsliced,wide
+//	go run . bench -w 5120 -h 5120 -turns 20 -cpus 0-15
+func Bench(command string, args []string) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	width := flags.Int("w", 512, "Specify the width of the image. Defaults to 512.")
+	height := flags.Int("h", 512, "Specify the height of the image. Defaults to 512.")
+	turns := flags.Int("turns", 100, "Specify the number of turns to time. Defaults to 100.")
+	threads := flags.Int("t", 8, "Specify the number of chunks each turn is split into, as a worker's goroutines would. Defaults to 8.")
+	inDir := flags.String("inDir", "images", "Directory or s3:// or gs:// bucket URL the input image is read from. Defaults to images.")
+	rule := flags.String("rule", "", "Specify the rule in B/S notation. Defaults to Life, B3/S23.")
+	kernels := flags.String("kernels", "bytes,bitsliced,wide", "Comma-separated kernels to time.")
+	cpus := flags.String("cpus", "", "Also time each kernel with its goroutines pinned to these CPUs in turn, e.g. 0-15. Linux only.")
+	flags.Parse(args)
+
+	opts, err := kernel.ParseOptions(*rule, "")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	var algorithms []kernel.Algorithm
+	for _, name := range strings.Split(*kernels, ",") {
+		algorithm, err := kernel.ParseAlgorithm(strings.TrimSpace(name))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		algorithms = append(algorithms, algorithm)
+	}
+	if *turns < 1 || *threads < 1 {
+		fmt.Println("-turns and -t must be at least 1")
+		os.Exit(2)
+	}
+	pinned, err := affinity.Parse(*cpus)
+	if err == nil {
+		err = pinned.Check()
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	world, err := readImage(*inDir, *width, *height)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	for _, algorithm := range algorithms {
+		opts.Algorithm, opts.CPUs = algorithm, nil
+		took, err := bench(world, *turns, *threads, opts)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("%-10v %s\n", algorithm, benchResult(took, *turns, *width**height))
+		if pinned == nil {
+			continue
+		}
+
+		opts.CPUs = pinned
+		pinnedTook, err := bench(world, *turns, *threads, opts)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		change := fmt.Sprintf("%.1f%% faster", 100*(took.Seconds()/pinnedTook.Seconds()-1))
+		if pinnedTook > took {
+			change = fmt.Sprintf("%.1f%% slower", 100*(pinnedTook.Seconds()/took.Seconds()-1))
+		}
+		fmt.Printf("%-10v %s pinned to CPUs %v, %s\n", "", benchResult(pinnedTook, *turns, *width**height), pinned, change)
+	}
+}
+
+// benchResult describes evolving a board of cells cells for turns turns in took.
+func benchResult(took time.Duration, turns, cells int) string {
+	perTurn := took / time.Duration(turns)
+	return fmt.Sprintf("%d turns in %v: %v a turn, %.1f million cells a second", turns,
+		took.Round(time.Millisecond), perTurn.Round(time.Microsecond), float64(cells)*float64(turns)/took.Seconds()/1e6)
+}
+
+// bench returns how long evolving world for turns turns takes, split into chunks as threads goroutines would.
+func bench(world slab.World, turns, threads int, opts kernel.Options) (time.Duration, error) {
+	chunk := (world.Height + threads - 1) / threads
+	start := time.Now()
+	for turn := 0; turn < turns; turn++ {
+		opts.Turn = turn
+		next, err := kernel.Next(context.Background(), world, 0, world.Height, chunk, opts)
+		if err != nil {
+			return 0, err
+		}
+		world = next
+	}
+	return time.Since(start), nil
+}
+
+// readImage reads the input image for a width x height world from dir, as the client does.
+func readImage(dir string, width, height int) (slab.World, error) {
+	path := storage.Join(dir, fmt.Sprintf("%dx%d.pgm", width, height))
+	data, err := storage.Get(path)
+	if err != nil {
+		return slab.World{}, err
+	}
+	w, h, cells, err := util.ParsePgm(data, util.DefaultThreshold)
+	if err != nil {
+		return slab.World{}, fmt.Errorf("%s: %v", path, err)
+	}
+	if w != width || h != height {
+		return slab.World{}, fmt.Errorf("%s is %dx%d, not %dx%d", path, w, h, width, height)
+	}
+	return slab.World{Width: w, Height: h, Stride: w, Cells: cells}, nil
+}
@@ -0,0 +1,60 @@
+// Package golcli is the command framework of the client program, whose subcommands run the client, serve a broker
+// or a worker, benchmark the kernels, replay a saved run, compare snapshots and start a broker with its workers on
+// this machine. Dispatch picks the subcommand to run, each taking its own flags, and the subcommands with nowhere
+// else to live are here too. The separate engine, worker and goldiff commands remain as thin wrappers around the
+// same code.
+//
+//	go run . run -t 8 -turns 1000
+//	go run . serve-broker -port 8030
+//	go run . serve-worker -port 8040
+package golcli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Command is a subcommand of a program. Run is given the command's full name, e.g. "gameoflife run", for its usage
+// message, and the arguments after the subcommand, and exits the program itself if it fails.
+type Command struct {
+	Name    string
+	Summary string // One line for the list of commands.
+	Run     func(command string, args []string)
+}
+
+// Dispatch runs the command args[0] names. With no arguments, or with a flag first, it runs fallback with all of
+// args, so the flags given before there were subcommands still work. "help" lists the commands.
+func Dispatch(program string, commands []Command, fallback string, args []string) {
+	name := fallback
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage(os.Stdout, program, commands, fallback)
+		return
+	}
+	for _, c := range commands {
+		if c.Name == name {
+			c.Run(program+" "+c.Name, args)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n", name)
+	usage(os.Stderr, program, commands, fallback)
+	os.Exit(2)
+}
+
+// usage lists the commands with their summaries.
+func usage(out io.Writer, program string, commands []Command, fallback string) {
+	fmt.Fprintf(out, "Usage: %s <command> [flags]\n\nCommands:\n", program)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", c.Name, c.Summary)
+	}
+	w.Flush()
+	fmt.Fprintf(out, "\nWithout a command, or with flags first, %s is %s %s. Pass -h after a command for its flags.\n",
+		program, program, fallback)
+}
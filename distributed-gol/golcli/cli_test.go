@@ -0,0 +1,152 @@
+package golcli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/audit"
+	"uk.ac.bris.cs/gameoflife/golsnap"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestDispatch checks a subcommand is run with the arguments after its name, and the fallback with all of them when
+// they start with a flag or there are none, as they did before there were subcommands.
+func TestDispatch(t *testing.T) {
+	var ran string
+	var got []string
+	record := func(command string, args []string) {
+		ran, got = command, args
+	}
+	commands := []Command{{Name: "run", Run: record}, {Name: "bench", Run: record}}
+	for _, test := range []struct {
+		args     []string
+		ran      string
+		expected []string
+	}{
+		{[]string{"bench", "-turns", "5"}, "gol bench", []string{"-turns", "5"}},
+		{[]string{"-t", "8", "-noVis"}, "gol run", []string{"-t", "8", "-noVis"}},
+		{nil, "gol run", nil},
+	} {
+		ran, got = "", nil
+		Dispatch("gol", commands, "run", test.args)
+		if ran != test.ran || len(got) != len(test.expected) || len(got) > 0 && !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q ran %q with %q, expected %q with %q", test.args, ran, got, test.ran, test.expected)
+		}
+	}
+}
+
+// TestReplay checks a saved run is carried on from the turn it was saved at, and a turn before that is refused.
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	blinker := slab.FromRows([][]byte{{0, 0, 0, 0, 0}, {0, 0, 0, 0, 0}, {0, 255, 255, 255, 0}, {0, 0, 0, 0, 0}, {0, 0, 0, 0, 0}})
+	data, err := golsnap.Encode(golsnap.State{Turn: 10, Rule: "B3/S23", Edge: "dead", World: blinker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "run"+golsnap.Extension)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := replay(path, 11, 2, kernel.ByteWise)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Turn != 11 || state.World.At(2, 1) == 0 || state.World.At(1, 2) != 0 || countAlive(state.World) != 3 {
+		t.Errorf("replayed to turn %d with %v, expected the blinker upright at turn 11", state.Turn, state.World.Rows())
+	}
+	if state, err = replay(path, 12, 2, kernel.BitSliced); err != nil || !state.World.Equal(blinker) {
+		t.Errorf("replaying two turns gave %v (%v), expected the blinker back as it was", state.World.Rows(), err)
+	}
+	if _, err := replay(path, 9, 2, kernel.ByteWise); err == nil {
+		t.Error("replayed to a turn before the run was saved")
+	}
+}
+
+// TestReplayAudited checks a run logged from an image is verified turn by turn up to where it ends, and that
+// altering any turn of the log is caught.
+func TestReplayAudited(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	blinker := slab.FromRows([][]byte{{0, 0, 0, 0, 0}, {0, 0, 0, 0, 0}, {0, 255, 255, 255, 0}, {0, 0, 0, 0, 0}, {0, 0, 0, 0, 0}})
+	image := filepath.Join(dir, "5x5.pgm")
+	if err := ioutil.WriteFile(image, util.FormatPgm(blinker.Rows()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var log strings.Builder
+	l := audit.NewLog(&log)
+	l.Begin(slab.New(5, 5), 0, "B3/S23", "torus", 0) // Another run, which the replay must skip.
+	l.Begin(blinker, 0, "B3/S23", "dead", 0)
+	state := golsnap.State{Rule: "B3/S23", Edge: "dead", World: blinker}
+	opts, _ := replayOptions(state, kernel.ByteWise)
+	for state.Turn < 4 {
+		if err := step(&state, opts, 1); err != nil {
+			t.Fatal(err)
+		}
+		l.Turn(state.World)
+	}
+
+	path := filepath.Join(dir, "audit.log")
+	write := func(text string) {
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(log.String())
+	if replayed, from, err := replayAudited(image, path, 0, 2, kernel.BitSliced); err != nil || from != 0 || replayed.Turn != 4 || !replayed.World.Equal(blinker) {
+		t.Errorf("verified turns %d-%d (%v), expected 0-4 ending with the blinker as it started", from, replayed.Turn, err)
+	}
+	if replayed, _, err := replayAudited(image, path, 3, 2, kernel.ByteWise); err != nil || replayed.Turn != 3 {
+		t.Errorf("verified up to turn %d (%v), expected to stop at turn 3", replayed.Turn, err)
+	}
+	if _, _, err := replayAudited(image, path, 5, 2, kernel.ByteWise); err == nil {
+		t.Error("verified up to turn 5 of a run logged up to turn 4")
+	}
+
+	lines := strings.Split(log.String(), "\n")
+	forged := audit.Next(audit.Start(blinker, 0, "B3/S23", "dead", 0), blinker) // Turn 1 claimed to leave the blinker as it was.
+	unchained := strings.Fields(lines[3])
+	unchained[3] = strings.Repeat("0", 64) // Turn 2 with the right world, but not chained to turn 1.
+	for i, text := range []string{forged.String(), strings.Join(unchained, " ")} {
+		altered := append([]string(nil), lines...)
+		altered[2+i] = text
+		write(strings.Join(altered, "\n"))
+		if _, _, err := replayAudited(image, path, 0, 2, kernel.ByteWise); err == nil {
+			t.Errorf("verified the run with line %d altered to %q", 3+i, text)
+		}
+	}
+}
+
+// TestDeployCommands checks every worker gets a port of its own, which the broker scans, and is waited for.
+func TestDeployCommands(t *testing.T) {
+	commands := deployCommands("gol", 3, 8030, 8040, "bytes", "")
+	if len(commands) != 4 {
+		t.Fatalf("got %d commands, expected 3 workers and the broker", len(commands))
+	}
+	for i, c := range commands[:3] {
+		if c.args[1] != "serve-worker" || c.listens != "localhost:"+c.args[3] || c.args[3] != []string{"8040", "8041", "8042"}[i] {
+			t.Errorf("worker %d is %q listening on %q", i, c.args, c.listens)
+		}
+	}
+	if broker := strings.Join(commands[3].args, " "); broker != "gol serve-broker -port 8030 -workerHost localhost -startPort 8040 -endPort 8042 -kernel bytes" {
+		t.Errorf("the broker is started with %q", broker)
+	}
+	for _, c := range deployCommands("gol", 2, 8030, 8040, "bytes", "seed=7,drop=0.01") {
+		if flags := strings.Join(c.args[len(c.args)-2:], " "); flags != "-chaos seed=7,drop=0.01" {
+			t.Errorf("%s is started with %q, expected it to end with the -chaos spec", c.name, c.args)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package golcli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Deploy starts a broker and its workers on this machine as processes of the program it is part of, with the
+// serve-worker and serve-broker subcommands: the workers on consecutive ports, and the broker once all of them
+// are listening, so its scan finds every one. Each line they print is prefixed with which process printed it.
+// Ctrl+C stops them all, as does any of them exiting, except a worker with -chaos, which may crash on purpose.
+//
+//	go run . deploy -workers 4
+func Deploy(command string, args []string) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	workers := flags.Int("workers", 4, "Number of workers to start")
+	port := flags.Int("port", 8030, "Port the broker listens on")
+	startPort := flags.Int("startPort", 8040, "Port the first worker listens on, the rest on the ports after it")
+	algorithm := flags.String("kernel", "auto", "-kernel of the broker and the workers")
+	wait := flags.Duration("wait", time.Minute, "How long to wait for each worker to start listening, which it does once it has calibrated")
+	chaosSpec := flags.String("chaos", "", "-chaos of the broker and the workers, e.g. seed=7,latency=20ms,drop=0.01,crash=0.001, each process drawing its faults differently from the seed")
+	dryRun := flags.Bool("dryRun", false, "Print the commands that would be run, without running them")
+	flags.Parse(args)
+	if *workers < 1 {
+		fmt.Println("-workers must be at least 1")
+		os.Exit(2)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	commands := deployCommands(executable, *workers, *port, *startPort, *algorithm, *chaosSpec)
+	if *dryRun {
+		for _, c := range commands {
+			fmt.Println(strings.Join(c.args, " "))
+		}
+		return
+	}
+
+	var processes []*exec.Cmd
+	stop := func() {
+		for _, p := range processes {
+			p.Process.Kill()
+		}
+	}
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	exited := make(chan string, len(commands))
+	var output sync.Mutex // Held while a line is printed, so lines from different processes don't interleave.
+	for _, c := range commands {
+		p := exec.Command(c.args[0], c.args[1:]...)
+		pipe, err := p.StdoutPipe()
+		if err == nil {
+			p.Stderr = p.Stdout
+			err = p.Start()
+		}
+		if err != nil {
+			fmt.Printf("Error starting %s: %v\n", c.name, err)
+			stop()
+			os.Exit(1)
+		}
+		processes = append(processes, p)
+		go func(name string) {
+			prefixLines(pipe, name, &output)
+			p.Wait()
+			exited <- name
+		}(c.name)
+		if c.listens != "" {
+			if err := waitForListener(c.listens, *wait, exited); err != nil {
+				fmt.Printf("%s %v\n", c.name, err)
+				stop()
+				os.Exit(1)
+			}
+		}
+	}
+
+	for {
+		select {
+		case name := <-exited:
+			// A worker crashing is what -chaos is for, and the broker carries on without it.
+			if *chaosSpec != "" && name != commands[len(commands)-1].name {
+				fmt.Printf("%s exited, carrying on without it\n", name)
+				continue
+			}
+			fmt.Printf("%s exited, stopping the rest\n", name)
+		case <-interrupts:
+		}
+		stop()
+		return
+	}
+}
+
+// deployed is a process Deploy starts.
+type deployed struct {
+	name    string
+	args    []string
+	listens string // Address to wait for it to listen on before starting the next, or "" not to wait.
+}
+
+// deployCommands returns the processes Deploy starts, in order: the workers, then the broker, all with -chaos
+// chaosSpec unless it is empty.
+func deployCommands(executable string, workers, port, startPort int, algorithm, chaosSpec string) []deployed {
+	var faults []string
+	if chaosSpec != "" {
+		faults = []string{"-chaos", chaosSpec}
+	}
+	var commands []deployed
+	for i := 0; i < workers; i++ {
+		p := strconv.Itoa(startPort + i)
+		commands = append(commands, deployed{"[worker " + p + "]",
+			append([]string{executable, "serve-worker", "-port", p, "-kernel", algorithm}, faults...), net.JoinHostPort("localhost", p)})
+	}
+	return append(commands, deployed{"[broker " + strconv.Itoa(port) + "]", append([]string{executable, "serve-broker",
+		"-port", strconv.Itoa(port), "-workerHost", "localhost", "-startPort", strconv.Itoa(startPort),
+		"-endPort", strconv.Itoa(startPort + workers - 1), "-kernel", algorithm}, faults...), ""})
+}
+
+// waitForListener waits up to timeout for something to accept connections at address, giving up early if one of
+// the processes exits.
+func waitForListener(address string, timeout time.Duration, exited <-chan string) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("wasn't listening on %s after %v", address, timeout)
+		}
+		select {
+		case name := <-exited:
+			return fmt.Errorf("didn't start: %s exited", name)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// prefixLines prints each line read from r with prefix before it, until r is closed.
+func prefixLines(r io.Reader, prefix string, output *sync.Mutex) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		output.Lock()
+		fmt.Println(prefix, scanner.Text())
+		output.Unlock()
+	}
+}
@@ -0,0 +1,154 @@
+package golcli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"uk.ac.bris.cs/gameoflife/golsnap"
+	"uk.ac.bris.cs/gameoflife/storage"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Colours used for each kind of cell in the overlay.
+var (
+	addedColour   = color.RGBA{R: 0x00, G: 0xE0, B: 0x00, A: 0xFF} // Alive only in the second snapshot.
+	removedColour = color.RGBA{R: 0xE0, G: 0x00, B: 0x00, A: 0xFF} // Alive only in the first snapshot.
+	sameColour    = color.RGBA{R: 0x60, G: 0x60, B: 0x60, A: 0xFF} // Alive in both.
+	deadColour    = color.RGBA{A: 0xFF}
+)
+
+// snapshot is a world read from a PGM file or a saved state.
+type snapshot struct {
+	Width, Height int
+	Cells         []byte // Row by row, util.Alive or util.Dead.
+}
+
+// readSnapshot reads a PGM file or a saved state (.golsnap) from the local disk or a bucket.
+func readSnapshot(path string) (snapshot, error) {
+	data, err := storage.Get(path)
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	if golsnap.Is(data) {
+		state, err := golsnap.Decode(data)
+		if err != nil {
+			return snapshot{}, fmt.Errorf("%s: %v", path, err)
+		}
+		world := state.World.Clone() // Packs the rows one after another, as the cells of a snapshot are.
+		return snapshot{Width: world.Width, Height: world.Height, Cells: world.Cells}, nil
+	}
+
+	width, height, cells, err := util.ParsePgm(data, util.DefaultThreshold)
+	if err != nil {
+		return snapshot{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return snapshot{Width: width, Height: height, Cells: cells}, nil
+}
+
+// diff summarises how two snapshots of the same size differ.
+type diff struct {
+	Added, Removed, Same int             // Number of cells of each kind.
+	Bounds               image.Rectangle // Smallest rectangle containing every changed cell, empty if none changed.
+}
+
+// compare overlays two snapshots, returning the differences and the overlay with scale x scale pixels per cell.
+func compare(first, second snapshot, scale int) (diff, *image.RGBA, error) {
+	if first.Width != second.Width || first.Height != second.Height {
+		return diff{}, nil, fmt.Errorf("snapshots are %dx%d and %dx%d, so can't be compared",
+			first.Width, first.Height, second.Width, second.Height)
+	}
+
+	var d diff
+	overlay := image.NewRGBA(image.Rect(0, 0, first.Width*scale, first.Height*scale))
+	for y := 0; y < first.Height; y++ {
+		for x := 0; x < first.Width; x++ {
+			before := first.Cells[y*first.Width+x] != 0
+			after := second.Cells[y*second.Width+x] != 0
+			colour := deadColour
+			switch {
+			case before && after:
+				colour = sameColour
+				d.Same++
+			case after:
+				colour = addedColour
+				d.Added++
+			case before:
+				colour = removedColour
+				d.Removed++
+			}
+			if before != after {
+				d.Bounds = d.Bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+			for i := 0; i < scale*scale; i++ {
+				overlay.SetRGBA(x*scale+i%scale, y*scale+i/scale, colour)
+			}
+		}
+	}
+	return d, overlay, nil
+}
+
+// Diff compares two snapshots of a world, such as the output of a distributed run and of a reference run for the
+// same turn, and draws them overlaid: cells alive only in the second snapshot in green, cells alive only in the
+// first in red, and cells alive in both in grey. It exits with status 1 if the snapshots differ. It is the whole
+// of go run ./goldiff, and of the diff subcommand.
+//
+//	go run ./goldiff -out diff.png check/images/512x512x100.pgm out/512x512x100.pgm
+func Diff(command string, args []string) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	out := flags.String("out", "diff.png", "File or s3:// or gs:// URL to write the overlay to, as a PNG")
+	scale := flags.Int("scale", 4, "Pixels along each side of a cell in the overlay")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] reference.pgm other.pgm\n", command)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 || *scale < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	d, err := diffFiles(flags.Arg(0), flags.Arg(1), *out, *scale)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	fmt.Printf("%d added (green), %d removed (red), %d alive in both (grey)\n", d.Added, d.Removed, d.Same)
+	if d.Bounds.Empty() {
+		fmt.Println("The snapshots are identical")
+		return
+	}
+	fmt.Printf("Changes are between (%d, %d) and (%d, %d); overlay written to %s\n",
+		d.Bounds.Min.X, d.Bounds.Min.Y, d.Bounds.Max.X-1, d.Bounds.Max.Y-1, *out)
+	os.Exit(1)
+}
+
+// diffFiles compares the snapshots at the two paths and writes the overlay to out.
+func diffFiles(firstPath, secondPath, out string, scale int) (diff, error) {
+	first, err := readSnapshot(firstPath)
+	if err != nil {
+		return diff{}, err
+	}
+	second, err := readSnapshot(secondPath)
+	if err != nil {
+		return diff{}, err
+	}
+	d, overlay, err := compare(first, second, scale)
+	if err != nil {
+		return diff{}, err
+	}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, overlay); err != nil {
+		return diff{}, err
+	}
+	if err := storage.Put(out, encoded.Bytes()); err != nil {
+		return diff{}, fmt.Errorf("couldn't write overlay: %v", err)
+	}
+	return d, nil
+}
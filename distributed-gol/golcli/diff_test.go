@@ -0,0 +1,62 @@
+package golcli
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCompare checks cells are counted and coloured by whether they were added, removed or kept.
+func TestCompare(t *testing.T) {
+	first := snapshot{Width: 3, Height: 2, Cells: []byte{255, 255, 0, 0, 0, 0}}
+	second := snapshot{Width: 3, Height: 2, Cells: []byte{255, 0, 0, 0, 0, 255}}
+
+	d, overlay, err := compare(first, second, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Added != 1 || d.Removed != 1 || d.Same != 1 {
+		t.Errorf("got %d added, %d removed and %d kept, expected one of each", d.Added, d.Removed, d.Same)
+	}
+	if d.Bounds != image.Rect(1, 0, 3, 2) {
+		t.Errorf("changes bounded by %v, expected (1,0)-(3,2)", d.Bounds)
+	}
+	if overlay.Bounds() != image.Rect(0, 0, 6, 4) {
+		t.Errorf("overlay is %v, expected 6x4 pixels", overlay.Bounds())
+	}
+	for _, pixel := range []struct {
+		x, y   int
+		colour interface{}
+	}{{1, 1, sameColour}, {3, 0, removedColour}, {5, 3, addedColour}, {0, 3, deadColour}} {
+		if got := overlay.RGBAAt(pixel.x, pixel.y); got != pixel.colour {
+			t.Errorf("pixel (%d, %d) is %v, expected %v", pixel.x, pixel.y, got, pixel.colour)
+		}
+	}
+
+	if _, _, err := compare(first, snapshot{Width: 2, Height: 3, Cells: make([]byte, 6)}, 1); err == nil {
+		t.Error("expected an error comparing snapshots of different sizes")
+	}
+}
+
+// TestReadSnapshot checks snapshots are read with their pixels normalised to alive and dead cells.
+func TestReadSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goldiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "2x2.pgm")
+	if err := ioutil.WriteFile(path, []byte("P5\n2 2\n255\n\x00\xff\x0a\xc0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := readSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Width != 2 || s.Height != 2 || string(s.Cells) != "\x00\xff\x00\xff" {
+		t.Errorf("read %dx%d %q", s.Width, s.Height, s.Cells)
+	}
+}
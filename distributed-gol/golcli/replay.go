@@ -0,0 +1,243 @@
+package golcli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/audit"
+	"uk.ac.bris.cs/gameoflife/golsnap"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/storage"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Replay carries on a saved run (.golsnap), such as a q save or a broker's checkpoint, on this machine up to a
+// later turn, under the rule, edges and seed it was saved with, so a stochastic run makes the same choices it did
+// on the broker. The result is written as a PGM image, or as a .golsnap if -out ends with that, for goldiff to
+// compare with what the broker produced. With -audit it checks every turn against the hash chain a broker run with
+// -audit logged from the saved state, or from the image the run started from, and fails at the first that doesn't
+// match, so a final state can be proved to have come from the starting state in the number of turns claimed.
+//
+//	go run . replay -turn 1000 -out replayed.pgm out/run.golsnap
+//	go run . replay -audit audit.log images/512x512.pgm
+func Replay(command string, args []string) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	turn := flags.Int("turn", 0, "Turn to replay up to, or 0 for the turn the run was saved at")
+	out := flags.String("out", "replay.pgm", "File or s3:// or gs:// URL to write the world at -turn to, as a PGM image or a .golsnap")
+	threads := flags.Int("t", 8, "Number of chunks each turn is split into")
+	algorithm := flags.String("kernel", "bytes", "How neighbours are counted: bytes, bitsliced, wide or auto")
+	auditLog := flags.String("audit", "", "Audit log of a broker run with -audit to check each turn against, replaying up to -turn or, if 0, the end of the run in it; the saved run may then be the PGM image the run started from")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] saved.golsnap\n", command)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 || *threads < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	kernelAlgorithm, err := kernel.ParseAlgorithm(*algorithm)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	var state golsnap.State
+	if *auditLog != "" {
+		var from int
+		if state, from, err = replayAudited(flags.Arg(0), *auditLog, *turn, *threads, kernelAlgorithm); err == nil {
+			fmt.Printf("Verified turns %d-%d against %s\n", from, state.Turn, *auditLog)
+		}
+	} else {
+		state, err = replay(flags.Arg(0), *turn, *threads, kernelAlgorithm)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	data := util.FormatPgm(state.World.Rows())
+	if strings.HasSuffix(*out, golsnap.Extension) {
+		if data, err = golsnap.Encode(state); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if err := storage.Put(*out, data); err != nil {
+		fmt.Printf("couldn't write %s: %v\n", *out, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Replayed to turn %d under %s with %s edges: %d alive, written to %s\n",
+		state.Turn, state.Rule, state.Edge, countAlive(state.World), *out)
+}
+
+// replay reads the saved run at path and evolves it up to turn.
+func replay(path string, turn, threads int, algorithm kernel.Algorithm) (golsnap.State, error) {
+	state, err := readState(path, false)
+	if err != nil {
+		return golsnap.State{}, err
+	}
+	opts, err := replayOptions(state, algorithm)
+	if err != nil {
+		return golsnap.State{}, err
+	}
+	if turn == 0 {
+		turn = state.Turn
+	}
+	if turn < state.Turn {
+		return golsnap.State{}, fmt.Errorf("%s was saved at turn %d, after turn %d", path, state.Turn, turn)
+	}
+	for state.Turn < turn {
+		if err = step(&state, opts, threads); err != nil {
+			return golsnap.State{}, err
+		}
+	}
+	return state, nil
+}
+
+// replayAudited reads the saved run at path, or the PGM image a run started from, and evolves it along the latest
+// run in the audit log at logPath that starts from it, up to turn or, if turn is 0, the end of that run, checking
+// each entry of the log against the chain it recomputes. A run started from an image is under the rule, edges and
+// seed the log gives. It returns the state replayed to and the turn the log's run starts at, or the first entry
+// that doesn't match.
+func replayAudited(path, logPath string, turn, threads int, algorithm kernel.Algorithm) (golsnap.State, int, error) {
+	saved, err := readState(path, true)
+	if err != nil {
+		return golsnap.State{}, 0, err
+	}
+	data, err := storage.Get(logPath)
+	if err != nil {
+		return golsnap.State{}, 0, err
+	}
+	entries, err := audit.Read(bytes.NewReader(data))
+	if err != nil {
+		return golsnap.State{}, 0, fmt.Errorf("%s: %v", logPath, err)
+	}
+	var state golsnap.State
+	var last audit.Entry
+	first := -1
+	for i, e := range entries {
+		if e.Kind != "start" {
+			continue
+		}
+		candidate := saved
+		if candidate.Rule == "" {
+			candidate.Rule, candidate.Edge, candidate.Seed = e.Rule, e.Edge, e.Seed
+		}
+		if start := audit.Start(candidate.World, candidate.Turn, candidate.Rule, candidate.Edge, candidate.Seed); start.Sum == e.Sum {
+			state, last, first = candidate, start, i
+		}
+	}
+	if first < 0 {
+		return golsnap.State{}, 0, fmt.Errorf("%s has no run starting from %s at turn %d", logPath, path, saved.Turn)
+	}
+	opts, err := replayOptions(state, algorithm)
+	if err != nil {
+		return golsnap.State{}, 0, err
+	}
+
+	from := state.Turn
+entries:
+	for _, e := range entries[first+1:] {
+		var next audit.Entry
+		switch e.Kind {
+		case "start":
+			// Starting again from where the run had got to, as a broker restarted from a checkpoint does, carries
+			// the run on. Anything else is another run.
+			next = audit.Start(state.World, state.Turn, state.Rule, state.Edge, state.Seed)
+			if next.Sum != e.Sum {
+				break entries
+			}
+		case "turn":
+			if turn != 0 && state.Turn >= turn {
+				break entries
+			}
+			if err := step(&state, opts, threads); err != nil {
+				return golsnap.State{}, 0, err
+			}
+			next = audit.Next(last, state.World)
+			if next.World != e.World {
+				return golsnap.State{}, 0, fmt.Errorf("turn %d in %s isn't what turn %d evolves into", e.Turn, logPath, e.Turn-1)
+			}
+		case "rule":
+			changed, err := kernel.ParseOptions(e.Rule, e.Edge)
+			if err != nil {
+				return golsnap.State{}, 0, fmt.Errorf("%s: %v", logPath, err)
+			}
+			opts.Rule, opts.Edge = changed.Rule, changed.Edge
+			state.Rule, state.Edge = e.Rule, e.Edge
+			next = audit.Rule(last, e.Rule, e.Edge)
+		case "edit":
+			return golsnap.State{}, 0, fmt.Errorf("cells were set by hand at turn %d, which %s doesn't record, so the run can't be checked past it",
+				e.Turn, logPath)
+		}
+		if next.Sum != e.Sum || next.Turn != e.Turn {
+			return golsnap.State{}, 0, fmt.Errorf("the chain in %s breaks at turn %d: the log has been altered", logPath, e.Turn)
+		}
+		last = next
+	}
+	if turn != 0 && state.Turn < turn {
+		return golsnap.State{}, 0, fmt.Errorf("the run in %s ends at turn %d, before turn %d", logPath, state.Turn, turn)
+	}
+	return state, from, nil
+}
+
+// readState reads the saved run at path or, if image is set, a PGM image there as a world at turn 0 with no rule.
+func readState(path string, image bool) (golsnap.State, error) {
+	data, err := storage.Get(path)
+	if err != nil {
+		return golsnap.State{}, err
+	}
+	if !golsnap.Is(data) {
+		if !image {
+			return golsnap.State{}, fmt.Errorf("%s isn't a saved run (%s)", path, golsnap.Extension)
+		}
+		width, height, cells, err := util.ParsePgm(data, util.DefaultThreshold)
+		if err != nil {
+			return golsnap.State{}, fmt.Errorf("%s is neither a saved run (%s) nor an image: %v", path, golsnap.Extension, err)
+		}
+		return golsnap.State{World: slab.World{Width: width, Height: height, Stride: width, Cells: cells}}, nil
+	}
+	state, err := golsnap.Decode(data)
+	if err != nil {
+		return golsnap.State{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return state, nil
+}
+
+// replayOptions returns the options state evolves under, counting neighbours with algorithm.
+func replayOptions(state golsnap.State, algorithm kernel.Algorithm) (kernel.Options, error) {
+	opts, err := kernel.ParseOptions(state.Rule, state.Edge)
+	opts.Algorithm, opts.Seed = algorithm, state.Seed
+	return opts, err
+}
+
+// step evolves state by one turn, split into threads chunks.
+func step(state *golsnap.State, opts kernel.Options, threads int) error {
+	opts.Turn = state.Turn // A stochastic rule's chances differ each turn, as on the broker.
+	chunk := (state.World.Height + threads - 1) / threads
+	world, err := kernel.Next(context.Background(), state.World, 0, state.World.Height, chunk, opts)
+	if err != nil {
+		return err
+	}
+	state.World = world
+	state.Turn++
+	return nil
+}
+
+// countAlive returns the number of alive cells in a world.
+func countAlive(world slab.World) int {
+	alive := 0
+	for y := 0; y < world.Height; y++ {
+		for _, cell := range world.Row(y) {
+			if cell == util.Alive {
+				alive++
+			}
+		}
+	}
+	return alive
+}
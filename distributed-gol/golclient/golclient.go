@@ -0,0 +1,450 @@
+// Package golclient drives a Game of Life broker from other Go programs. It wraps the broker's RPCs in typed
+// methods that take a context, so callers don't copy handler names from stubs or manage an rpc.Client,
+// lease heartbeats and reconnection themselves.
+package golclient
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/memnet"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Client is a connection to a broker. Its methods may be called from several goroutines at once.
+type Client struct {
+	// Timeout bounds each call other than Evolve, which lasts as long as the run. 0 means no timeout.
+	Timeout time.Duration
+	// Retries is how many times a read that failed because the connection broke is retried on a new one.
+	// Control calls such as Pause are never retried, as the broker may have acted on the first attempt.
+	Retries int
+	// RetryDelay is the wait before the first retry. It doubles with each retry after that.
+	RetryDelay time.Duration
+	// HeartbeatInterval is how often Evolve renews the lease while the run goes on. It must be well within
+	// the broker's -lease, or another client may take over.
+	HeartbeatInterval time.Duration
+	// ChunkSize is the most cells Evolve sends in one call. A bigger starting world is uploaded in chunks of
+	// this many cells first. 0 means the world is always sent whole.
+	ChunkSize int
+
+	address string
+	mu      sync.Mutex
+	conn    *rpc.Client // Current connection, or nil if it broke and hasn't been replaced yet.
+	epoch   int         // Fencing epoch from the last Acquire, or 0 if control hasn't been taken.
+}
+
+// Snapshot is a generation of the world and the turn it was reached at.
+type Snapshot struct {
+	World slab.World
+	Turn  int
+}
+
+// Run describes the evolution Evolve asks the broker for.
+type Run struct {
+	World   slab.World // Starting world. Ignored if the broker continues a previous run.
+	Turns   int        // Turn to evolve up to.
+	Threads int
+	Rule    string            // Rule in B/S notation, e.g. "B36/S23". Empty for Conway's Life.
+	Edge    string            // "torus" or "dead". Empty for a torus.
+	Labels  map[string]string // Key/value tags saved with the run's checkpoints.
+}
+
+// Update is a batch of cells flipped since the previous one, in turn order. A subscriber that fell too far behind
+// gets the whole world at Turn in Keyframe instead, with Skipped turns since the previous Update. The last Update
+// sent by Subscribe before it closes its channel has Err set if it stopped because of an error rather than its
+// context.
+type Update struct {
+	Flips    []stubs.FlippedEvent
+	Keyframe slab.World
+	Turn     int
+	Skipped  int
+	Err      error
+}
+
+// Dial connects to the broker at address, e.g. "127.0.0.1:8030", with the default timeouts and retries.
+func Dial(ctx context.Context, address string) (*Client, error) {
+	c := &Client{
+		Timeout:           10 * time.Second,
+		Retries:           3,
+		RetryDelay:        100 * time.Millisecond,
+		HeartbeatInterval: 2 * time.Second,
+		ChunkSize:         DefaultChunkSize,
+		address:           address,
+	}
+	if _, err := c.connection(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes the connection to the broker.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// connection returns the current connection, dialling a new one if the last one broke.
+func (c *Client) connection(ctx context.Context) (*rpc.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		return c.conn, nil
+	}
+	conn, err := memnet.DialContext(ctx, c.address)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = rpc.NewClient(conn)
+	return c.conn, nil
+}
+
+// drop forgets a connection that broke, so the next call dials a new one.
+func (c *Client) drop(conn *rpc.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == conn {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// broken reports whether err means the connection failed, rather than the broker returning an error.
+func broken(err error) bool {
+	if err == rpc.ErrShutdown || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// call makes one RPC, giving up when ctx is done. The reply is only safe to read if call returns nil.
+func (c *Client) call(ctx context.Context, method string, req, res interface{}) error {
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return err
+	}
+	call := conn.Go(method, req, res, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if broken(call.Error) {
+			c.drop(conn)
+		}
+		return call.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// control makes a call within Timeout that is not retried.
+func (c *Client) control(ctx context.Context, method string, req, res interface{}) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	return c.call(ctx, method, req, res)
+}
+
+// read makes a call within Timeout, retrying on a new connection if the old one broke.
+func (c *Client) read(ctx context.Context, method string, req, res interface{}) error {
+	delay := c.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := c.control(ctx, method, req, res)
+		if err == nil || !broken(err) || attempt >= c.Retries {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// request returns the fencing epoch to send with a control call.
+func (c *Client) request() stubs.ControlRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return stubs.ControlRequest{Epoch: c.epoch}
+}
+
+// Acquire takes control of the broker. Evolve acquires it if this hasn't been done already.
+func (c *Client) Acquire(ctx context.Context) error {
+	res := &stubs.AcquireResponse{}
+	if err := c.control(ctx, stubs.AcquireHandler, stubs.Empty{}, res); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.epoch = res.Epoch
+	c.mu.Unlock()
+	return nil
+}
+
+// Heartbeat renews the lease on the broker.
+func (c *Client) Heartbeat(ctx context.Context) error {
+	return c.read(ctx, stubs.HeartbeatHandler, c.request(), &stubs.Empty{})
+}
+
+// Evolve runs the world up to run.Turns on the broker and returns the final generation. While it runs the lease
+// is renewed every HeartbeatInterval. If ctx is done first, the broker is told to quit the run, so it doesn't
+// carry on computing for nobody, and the next client can continue from where it stopped.
+func (c *Client) Evolve(ctx context.Context, run Run) (Snapshot, error) {
+	c.mu.Lock()
+	acquired := c.epoch != 0
+	c.mu.Unlock()
+	if !acquired {
+		if err := c.Acquire(ctx); err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	req := stubs.EvolveWorldRequest{
+		World:       run.World,
+		Width:       run.World.Width,
+		Height:      run.World.Height,
+		ImageWidth:  run.World.Width,
+		ImageHeight: run.World.Height,
+		Turn:        run.Turns,
+		Threads:     run.Threads,
+		Epoch:       c.request().Epoch,
+		Rule:        run.Rule,
+		Edge:        run.Edge,
+		Labels:      run.Labels,
+	}
+	if c.ChunkSize > 0 && run.World.Width*run.World.Height > c.ChunkSize {
+		id, err := c.Upload(ctx, run.World)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		req.World, req.Upload = slab.World{}, id
+	}
+
+	stopHeartbeat := make(chan struct{})
+	defer close(stopHeartbeat)
+	go func() {
+		ticker := time.NewTicker(c.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopHeartbeat:
+				return
+			case <-ticker.C:
+				// A lost lease makes EvolveWorld fail, which is where it is reported.
+				c.Heartbeat(ctx)
+			}
+		}
+	}()
+
+	res := &stubs.EvolveResponse{}
+	if err := c.call(ctx, stubs.EvolveWorldHandler, req, res); err != nil {
+		if ctx.Err() != nil {
+			quitCtx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+			defer cancel()
+			c.Quit(quitCtx)
+		}
+		return Snapshot{}, err
+	}
+	return Snapshot{World: res.World, Turn: res.Turn}, nil
+}
+
+// Pause stops the run after the current turn, until Unpause is called.
+func (c *Client) Pause(ctx context.Context) error {
+	return c.control(ctx, stubs.PauseHandler, c.request(), &stubs.Empty{})
+}
+
+// HardPause stops the run by locking the broker's mutex, so reads that need it wait until Unpause as well.
+func (c *Client) HardPause(ctx context.Context) error {
+	return c.control(ctx, stubs.HardPauseHandler, c.request(), &stubs.Empty{})
+}
+
+// Unpause resumes a paused run.
+func (c *Client) Unpause(ctx context.Context) error {
+	return c.control(ctx, stubs.UnpauseHandler, c.request(), &stubs.Empty{})
+}
+
+// Quit stops the run and releases control of the broker. The next client continues from where it stopped.
+func (c *Client) Quit(ctx context.Context) error {
+	return c.control(ctx, stubs.QuitHandler, c.request(), &stubs.Empty{})
+}
+
+// SetRule switches the run to another rule or edge mode from the next turn on. An empty rule or edge keeps the
+// current one.
+func (c *Client) SetRule(ctx context.Context, rule, edge string) error {
+	return c.control(ctx, stubs.SetRuleHandler, stubs.SetRuleRequest{Epoch: c.request().Epoch, Rule: rule, Edge: edge}, &stubs.Empty{})
+}
+
+// SetCells sets cells of the paused run alive or dead, returning how many weren't already. The run carries on from the
+// edited world once resumed.
+func (c *Client) SetCells(ctx context.Context, cells []util.Cell, alive bool) (int, error) {
+	res := &stubs.SetCellsResponse{}
+	err := c.control(ctx, stubs.SetCellsHandler, stubs.SetCellsRequest{Epoch: c.request().Epoch, Cells: cells, Alive: alive}, res)
+	return res.Changed, err
+}
+
+// Kill shuts down the broker and its workers.
+func (c *Client) Kill(ctx context.Context) error {
+	err := c.control(ctx, stubs.KillServerHandler, c.request(), &stubs.Empty{})
+	if broken(err) {
+		// The broker may exit before it has replied.
+		return nil
+	}
+	return err
+}
+
+// Snapshot returns the latest complete generation. It can be called by any client, whether or not it controls
+// the broker.
+func (c *Client) Snapshot(ctx context.Context) (Snapshot, error) {
+	res := &stubs.GetGlobalResponse{}
+	if err := c.read(ctx, stubs.GetGlobalHandler, stubs.Empty{}, res); err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{World: res.World, Turn: res.Turn}, nil
+}
+
+// AliveCount returns the number of alive cells in the latest generation and its turn.
+func (c *Client) AliveCount(ctx context.Context) (count, turn int, err error) {
+	res := &stubs.AliveCellsCountResponse{}
+	if err := c.read(ctx, stubs.AliveCellsCountHandler, stubs.Empty{}, res); err != nil {
+		return 0, 0, err
+	}
+	return res.AliveCellsCount, res.CompletedTurns, nil
+}
+
+// AliveCells returns the alive cells of the latest generation.
+func (c *Client) AliveCells(ctx context.Context) ([]util.Cell, error) {
+	res := &stubs.CalculateAliveCellsResponse{}
+	if err := c.read(ctx, stubs.AliveCellsHandler, stubs.Empty{}, res); err != nil {
+		return nil, err
+	}
+	return res.AliveCells, nil
+}
+
+// Plan asks the broker how it would carry out a run with its current workers, without starting it.
+func (c *Client) Plan(ctx context.Context, req stubs.PlanRequest) (stubs.PlanResponse, error) {
+	res := &stubs.PlanResponse{}
+	if err := c.read(ctx, stubs.PlanHandler, req, res); err != nil {
+		return stubs.PlanResponse{}, err
+	}
+	return *res, nil
+}
+
+// Hash returns the hash of the latest generation and what cycle detection has found so far.
+func (c *Client) Hash(ctx context.Context) (stubs.WorldHashResponse, error) {
+	res := &stubs.WorldHashResponse{}
+	if err := c.read(ctx, stubs.WorldHashHandler, stubs.Empty{}, res); err != nil {
+		return stubs.WorldHashResponse{}, err
+	}
+	return *res, nil
+}
+
+// MatchPattern returns where p appears in the latest generation, in any orientation if anyOrientation is set, and
+// its turn. At most limit matches are returned, or all of them if limit is 0.
+func (c *Client) MatchPattern(ctx context.Context, p slab.World, anyOrientation bool, limit int) ([]stubs.PatternMatch, int, error) {
+	res := &stubs.MatchPatternResponse{}
+	req := stubs.MatchPatternRequest{Pattern: p, AnyOrientation: anyOrientation, Limit: limit}
+	if err := c.read(ctx, stubs.MatchPatternHandler, req, res); err != nil {
+		return nil, 0, err
+	}
+	return res.Matches, res.Turn, nil
+}
+
+// SelfTest has the broker check that its own kernel and every worker compute turns correctly.
+func (c *Client) SelfTest(ctx context.Context) (stubs.SelfTestResponse, error) {
+	res := &stubs.SelfTestResponse{}
+	if err := c.read(ctx, stubs.SelfTestHandler, stubs.Empty{}, res); err != nil {
+		return stubs.SelfTestResponse{}, err
+	}
+	return *res, nil
+}
+
+// Checkpoints lists the checkpoints the broker has saved or resumed from, and the earlier ones it has kept.
+func (c *Client) Checkpoints(ctx context.Context) ([]stubs.CheckpointInfo, error) {
+	res := &stubs.ListCheckpointsResponse{}
+	if err := c.read(ctx, stubs.ListCheckpointsHandler, stubs.Empty{}, res); err != nil {
+		return nil, err
+	}
+	return res.Checkpoints, nil
+}
+
+// SubmitJob queues a run on the broker, which carries it out once every job before it has finished and no
+// client controls the broker, and returns the job's ID. It isn't retried, so a job is never queued twice.
+func (c *Client) SubmitJob(ctx context.Context, job stubs.JobSpec) (int, error) {
+	res := &stubs.SubmitJobResponse{}
+	if err := c.control(ctx, stubs.SubmitJobHandler, stubs.SubmitJobRequest{Job: job}, res); err != nil {
+		return 0, err
+	}
+	return res.ID, nil
+}
+
+// Jobs returns the status of every job submitted to the broker since it started, oldest first.
+func (c *Client) Jobs(ctx context.Context) ([]stubs.JobStatus, error) {
+	res := &stubs.ListJobsResponse{}
+	if err := c.read(ctx, stubs.ListJobsHandler, stubs.Empty{}, res); err != nil {
+		return nil, err
+	}
+	return res.Jobs, nil
+}
+
+// JobStatus returns the status of the job with the given ID.
+func (c *Client) JobStatus(ctx context.Context, id int) (stubs.JobStatus, error) {
+	res := &stubs.JobStatusResponse{}
+	if err := c.read(ctx, stubs.GetJobStatusHandler, stubs.JobStatusRequest{ID: id}, res); err != nil {
+		return stubs.JobStatus{}, err
+	}
+	return res.Job, nil
+}
+
+// Subscribe polls the broker every interval for the cells flipped since the last poll and sends each non-empty
+// batch on the returned channel, until ctx is done or a poll fails. The broker hands each flip out once, so
+// there should be only one subscriber per broker. Polls time out while the run is paused, and are then simply
+// tried again.
+func (c *Client) Subscribe(ctx context.Context, interval time.Duration) <-chan Update {
+	updates := make(chan Update)
+	go func() {
+		defer close(updates)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			res := &stubs.GetBrokerCellFlippedResponse{}
+			err := c.read(ctx, stubs.GetBrokerCellFlippedHandler, stubs.Empty{}, res)
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err == context.DeadlineExceeded:
+				continue
+			case err != nil:
+				select {
+				case updates <- Update{Err: err}:
+				case <-ctx.Done():
+				}
+				return
+			case len(res.FlippedEvents) == 0 && res.Keyframe.Cells == nil:
+				continue
+			}
+			select {
+			case updates <- Update{Flips: res.FlippedEvents, Keyframe: res.Keyframe, Turn: res.Turn, Skipped: res.Skipped}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates
+}
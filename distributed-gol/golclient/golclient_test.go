@@ -0,0 +1,302 @@
+package golclient
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// fakeBroker stands in for the broker, recording the calls made to it.
+type fakeBroker struct {
+	mu         sync.Mutex
+	heartbeats int
+	quit       chan bool // Closed by QuitServer, which ends a blocked EvolveWorld.
+	quits      int
+	flips      [][]stubs.FlippedEvent  // Batches handed out by successive GetCellFlipped calls.
+	views      []stubs.ObserveResponse // Handed out by successive Observe calls, the last one repeatedly.
+	budgets    []int                   // Budgets Observe was called with.
+	held       []int                   // Turns the observer held each time Observe was called.
+}
+
+func (b *fakeBroker) Acquire(req stubs.Empty, res *stubs.AcquireResponse) (err error) {
+	res.Epoch = 7
+	return
+}
+
+func (b *fakeBroker) Heartbeat(req stubs.ControlRequest, res *stubs.Empty) (err error) {
+	b.mu.Lock()
+	b.heartbeats++
+	b.mu.Unlock()
+	return
+}
+
+// EvolveWorld runs until the run is quit, like one far too long to finish in a test.
+func (b *fakeBroker) EvolveWorld(req stubs.EvolveWorldRequest, res *stubs.EvolveResponse) (err error) {
+	<-b.quit
+	return
+}
+
+func (b *fakeBroker) QuitServer(req stubs.ControlRequest, res *stubs.Empty) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if req.Epoch == 7 && b.quits == 0 {
+		close(b.quit)
+	}
+	b.quits++
+	return
+}
+
+// Pause never returns, like a broker that has hung.
+func (b *fakeBroker) Pause(req stubs.ControlRequest, res *stubs.Empty) (err error) {
+	select {}
+}
+
+func (b *fakeBroker) GetGlobal(req stubs.Empty, res *stubs.GetGlobalResponse) (err error) {
+	res.World = slab.FromRows([][]byte{{0, 255}, {255, 0}})
+	res.Turn = 42
+	return
+}
+
+func (b *fakeBroker) GetCellFlipped(req stubs.Empty, res *stubs.GetBrokerCellFlippedResponse) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.flips) > 0 {
+		res.FlippedEvents, b.flips = b.flips[0], b.flips[1:]
+	}
+	return
+}
+
+func (b *fakeBroker) Observe(req stubs.ObserveRequest, res *stubs.ObserveResponse) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.budgets = append(b.budgets, req.Budget)
+	b.held = append(b.held, req.Held)
+	*res = b.views[0]
+	if len(b.views) > 1 {
+		b.views = b.views[1:]
+	}
+	return
+}
+
+// serve starts the fake broker on a free port, returning its address and a function that stops it. The first
+// dropConnections connections are closed as soon as they are accepted, as if the broker had restarted.
+func serve(t *testing.T, broker *fakeBroker, dropConnections int) (string, func()) {
+	t.Helper()
+	broker.quit = make(chan bool)
+	server := rpc.NewServer()
+	if err := server.RegisterName("Broker", broker); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for accepted := 0; ; accepted++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if accepted < dropConnections {
+				conn.Close()
+				continue
+			}
+			go server.ServeConn(conn)
+		}
+	}()
+	return listener.Addr().String(), func() { listener.Close() }
+}
+
+// dial connects to the fake broker with timeouts short enough for tests.
+func dial(t *testing.T, address string) *Client {
+	t.Helper()
+	c, err := Dial(context.Background(), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Timeout = time.Second
+	c.RetryDelay = time.Millisecond
+	return c
+}
+
+// TestRetry checks a read survives the connection it was made on being closed by the broker.
+func TestRetry(t *testing.T) {
+	address, stop := serve(t, &fakeBroker{}, 1)
+	defer stop()
+	c := dial(t, address)
+	defer c.Close()
+	snapshot, err := c.Snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Turn != 42 || snapshot.World.Height != 2 || snapshot.World.At(1, 0) != 255 {
+		t.Errorf("got snapshot %+v, expected the broker's world at turn 42", snapshot)
+	}
+
+	c.Retries = 0
+	c.Close()
+	address, stop = serve(t, &fakeBroker{}, 1)
+	defer stop()
+	c.address = address
+	if _, err := c.Snapshot(context.Background()); err == nil {
+		t.Error("a read succeeded without retrying on a connection the broker had closed")
+	}
+}
+
+// TestTimeout checks a call to a broker that never replies gives up after Timeout.
+func TestTimeout(t *testing.T) {
+	address, stop := serve(t, &fakeBroker{}, 0)
+	defer stop()
+	c := dial(t, address)
+	defer c.Close()
+	c.Timeout = 50 * time.Millisecond
+	start := time.Now()
+	if err := c.Pause(context.Background()); err != context.DeadlineExceeded {
+		t.Fatalf("got %v from a broker that never replies, expected %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Pause took %v to time out", elapsed)
+	}
+}
+
+// TestEvolveCancel checks Evolve renews the lease while the run goes on, and quits the run on the broker when
+// its context is cancelled.
+func TestEvolveCancel(t *testing.T) {
+	broker := &fakeBroker{}
+	address, stop := serve(t, broker, 0)
+	defer stop()
+	c := dial(t, address)
+	defer c.Close()
+	c.HeartbeatInterval = 10 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Evolve(ctx, Run{World: slab.New(2, 2), Turns: 1000000, Threads: 1})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("got %v from a cancelled run, expected %v", err, context.DeadlineExceeded)
+	}
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if broker.quits != 1 {
+		t.Errorf("the run was quit %d times, expected once", broker.quits)
+	}
+	if broker.heartbeats == 0 {
+		t.Error("the lease wasn't renewed while the run went on")
+	}
+}
+
+// TestSubscribe checks every batch of flips reaches the subscriber in order, and the channel closes with
+// the context.
+func TestSubscribe(t *testing.T) {
+	broker := &fakeBroker{flips: [][]stubs.FlippedEvent{
+		{{CompletedTurns: 1, Cell: util.Cell{X: 1, Y: 2}}},
+		{},
+		{{CompletedTurns: 2, Cell: util.Cell{X: 3, Y: 4}}, {CompletedTurns: 2, Cell: util.Cell{X: 5, Y: 6}}},
+	}}
+	address, stop := serve(t, broker, 0)
+	defer stop()
+	c := dial(t, address)
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := c.Subscribe(ctx, time.Millisecond)
+
+	var flips []stubs.FlippedEvent
+	for len(flips) < 3 {
+		update := <-updates
+		if update.Err != nil {
+			t.Fatal(update.Err)
+		}
+		flips = append(flips, update.Flips...)
+	}
+	if flips[0].Cell != (util.Cell{X: 1, Y: 2}) || flips[2].Cell != (util.Cell{X: 5, Y: 6}) {
+		t.Errorf("got flips %v, expected them in the order the broker handed them out", flips)
+	}
+
+	cancel()
+	for update := range updates {
+		if update.Err != nil || len(update.Flips) > 0 {
+			t.Errorf("got %+v after the subscription was cancelled", update)
+		}
+	}
+}
+
+// TestObserve checks an observer's copy of the world is kept up to date from keyframes and deltas without changing
+// views already sent, a poll that finds nothing new sends nothing, and each poll asks for a bandwidth's worth of
+// bytes.
+func TestObserve(t *testing.T) {
+	keyframe := slab.FromRows([][]byte{{0, 255}, {255, 0}})
+	broker := &fakeBroker{views: []stubs.ObserveResponse{
+		{Observer: 3, Turn: 1, Form: "keyframe", Keyframe: keyframe},
+		{Observer: 3, Turn: 2, Form: "delta", Flips: []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}}},
+		{Observer: 3, Turn: 2, Form: "delta"},
+		{Observer: 3, Turn: 3, Form: "density", Density: slab.FromRows([][]byte{{127}}), Scale: 2},
+	}}
+	address, stop := serve(t, broker, 0)
+	defer stop()
+	c := dial(t, address)
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	views := c.Observe(ctx, 10*time.Millisecond, 16000)
+
+	var got []View
+	for len(got) < 3 {
+		view := <-views
+		if view.Err != nil {
+			t.Fatal(view.Err)
+		}
+		got = append(got, view)
+	}
+	if !reflect.DeepEqual(got[0].World.Cells, []byte{0, 255, 255, 0}) || got[0].Turn != 1 {
+		t.Errorf("the keyframe gave %+v", got[0])
+	}
+	if !reflect.DeepEqual(got[1].World.Cells, []byte{255, 0, 255, 0}) || got[1].Turn != 2 {
+		t.Errorf("the delta gave %+v, expected the top row flipped", got[1])
+	}
+	if got[0].World.Cells[0] != 0 {
+		t.Error("applying the delta changed the keyframe's view")
+	}
+	if got[2].Form != "density" || got[2].Turn != 3 || got[2].World.Cells != nil || got[2].Density.At(0, 0) != 127 {
+		t.Errorf("the density grid gave %+v, expected it without a world", got[2])
+	}
+
+	// Wait for a poll after the density grid.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		broker.mu.Lock()
+		if len(broker.held) >= 5 || time.Now().After(deadline) {
+			break
+		}
+		broker.mu.Unlock()
+	}
+	defer broker.mu.Unlock()
+	// 16 kbps is 2000 bytes a second, or 20 every 10ms.
+	if broker.budgets[0] != 20 {
+		t.Errorf("polled with a budget of %d bytes, expected 20", broker.budgets[0])
+	}
+	// Each poll says which turn the observer holds, and that it holds none before the keyframe and after a density grid.
+	if len(broker.held) < 5 || !reflect.DeepEqual(broker.held[:5], []int{-1, 1, 2, 2, -1}) {
+		t.Errorf("polled holding turns %v, expected [-1 1 2 2 -1] first", broker.held)
+	}
+}
+
+// TestParseBandwidth checks bandwidths are read in bits per second, whatever the case of the unit.
+func TestParseBandwidth(t *testing.T) {
+	for s, expected := range map[string]int64{"2Mbps": 2000000, "1.5 kbps": 1500, "64000": 64000, "1GBPS": 1000000000} {
+		if got, err := ParseBandwidth(s); err != nil || got != expected {
+			t.Errorf("%q is %d bits per second (%v), expected %d", s, got, err, expected)
+		}
+	}
+	for _, bad := range []string{"", "fast", "-1Mbps", "2 MB"} {
+		if _, err := ParseBandwidth(bad); err == nil {
+			t.Errorf("%q was accepted", bad)
+		}
+	}
+}
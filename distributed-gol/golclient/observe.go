@@ -0,0 +1,127 @@
+package golclient
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// View is a generation of the world as seen by an observer. Form says how the broker sent it: "keyframe" or
+// "delta", after which World is the observer's copy of the whole world at Turn, or "density", when not even the
+// whole world fits the budget and World is empty, with Density holding how many of each Scale x Scale block of
+// cells are alive, from 0 to 255. The last View sent by Observe before it closes its channel has Err set if it
+// stopped because of an error rather than its context.
+type View struct {
+	World   slab.World
+	Density slab.World
+	Scale   int
+	Turn    int
+	Form    string
+	Size    int // Estimated bytes the broker sent for this view.
+	Err     error
+}
+
+// ParseBandwidth reads a bandwidth such as "2Mbps", "500kbps" or "64000bps" and returns it in bits per second.
+// A bare number is bits per second too.
+func ParseBandwidth(s string) (int64, error) {
+	number, scale := strings.TrimSpace(s), 1.0
+	for _, unit := range []struct {
+		suffix string
+		scale  float64
+	}{{"gbps", 1e9}, {"mbps", 1e6}, {"kbps", 1e3}, {"bps", 1}} {
+		if strings.HasSuffix(strings.ToLower(number), unit.suffix) {
+			number, scale = strings.TrimSpace(number[:len(number)-len(unit.suffix)]), unit.scale
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bandwidth %q isn't of the form 2Mbps", s)
+	}
+	return int64(n * scale), nil
+}
+
+// Observe polls the broker every interval for the latest generation as a remote observer, which never holds up
+// the run or takes flips from Subscribe, so any number may watch at once. bandwidth is the most the observer's
+// link should carry, in bits per second, or 0 for no limit: each poll asks for no more than it carries in an
+// interval, and the broker sends the cells changed since the previous poll, the whole world or a density grid,
+// whichever fits. A View is sent for the first poll and for every later one that finds a new turn or form, until
+// ctx is done or a poll fails.
+func (c *Client) Observe(ctx context.Context, interval time.Duration, bandwidth int64) <-chan View {
+	views := make(chan View)
+	budget := int(float64(bandwidth) / 8 * interval.Seconds())
+	if bandwidth > 0 && budget < 1 {
+		budget = 1 // 0 would ask for no limit.
+	}
+	go func() {
+		defer close(views)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var view View
+		observer := 0
+		for {
+			res := &stubs.ObserveResponse{}
+			// Say which world deltas can be applied to, so a lost response is made up for with a keyframe.
+			held := -1
+			if view.World.Cells != nil {
+				held = view.Turn
+			}
+			err := c.read(ctx, stubs.ObserveHandler, stubs.ObserveRequest{Observer: observer, Budget: budget, Held: held}, res)
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				select {
+				case views <- View{Err: err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			observer = res.Observer
+			last := view
+			view = nextView(view, res)
+			// A poll can find the same turn, e.g. while the run is paused, or one so slow the view hasn't changed.
+			if view.Turn != last.Turn || view.Form != last.Form || last.Form == "" {
+				select {
+				case views <- view:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return views
+}
+
+// nextView applies a poll's response to the previous view. A delta flips the cells of a copy of the previous
+// world, so a View already sent is never modified.
+func nextView(last View, res *stubs.ObserveResponse) View {
+	view := View{Turn: res.Turn, Form: res.Form, Size: res.Size}
+	switch res.Form {
+	case "keyframe":
+		view.World = res.Keyframe
+	case "delta":
+		view.World = last.World
+		if len(res.Flips) > 0 {
+			view.World = last.World.Clone()
+		}
+		for _, cell := range res.Flips {
+			view.World.Set(cell.X, cell.Y, util.Alive^view.World.At(cell.X, cell.Y))
+		}
+	case "density":
+		view.Density, view.Scale = res.Density, res.Scale
+	}
+	return view
+}
@@ -0,0 +1,65 @@
+package golclient
+
+import (
+	"context"
+	"hash/crc32"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// DefaultChunkSize is the ChunkSize Dial sets: 16 MiB of cells, a 4096x4096 world, per call.
+const DefaultChunkSize = 16 << 20
+
+// Caller makes an RPC and waits for its reply, as *rpc.Client does.
+type Caller interface {
+	Call(serviceMethod string, args interface{}, reply interface{}) error
+}
+
+// callerFunc lets a function be used as a Caller.
+type callerFunc func(serviceMethod string, args interface{}, reply interface{}) error
+
+func (f callerFunc) Call(serviceMethod string, args interface{}, reply interface{}) error {
+	return f(serviceMethod, args, reply)
+}
+
+// UploadWorld sends world to the broker in chunks of at most chunkSize cells, or in one if chunkSize is 0, for
+// the client holding epoch. It returns the ID to start the run from with EvolveWorldRequest.Upload.
+func UploadWorld(broker Caller, epoch int, world slab.World, chunkSize int) (int, error) {
+	if world.Stride != world.Width {
+		world = world.Clone() // The cells are sent row after row with nothing in between.
+	}
+	cells := world.Cells[:world.Width*world.Height]
+	if chunkSize <= 0 {
+		chunkSize = len(cells)
+	}
+
+	begin := &stubs.BeginUploadResponse{}
+	err := broker.Call(stubs.BeginUploadHandler, stubs.BeginUploadRequest{Epoch: epoch, Width: world.Width, Height: world.Height}, begin)
+	if err != nil {
+		return 0, err
+	}
+	for offset := 0; offset < len(cells); offset += chunkSize {
+		end := offset + chunkSize
+		if end > len(cells) {
+			end = len(cells)
+		}
+		chunk := cells[offset:end]
+		req := stubs.AppendUploadRequest{Epoch: epoch, ID: begin.ID, Offset: offset, Cells: chunk, Checksum: crc32.ChecksumIEEE(chunk)}
+		if err := broker.Call(stubs.AppendUploadHandler, req, &stubs.Empty{}); err != nil {
+			return 0, err
+		}
+	}
+	commit := stubs.CommitUploadRequest{Epoch: epoch, ID: begin.ID, Checksum: crc32.ChecksumIEEE(cells)}
+	if err := broker.Call(stubs.CommitUploadHandler, commit, &stubs.Empty{}); err != nil {
+		return 0, err
+	}
+	return begin.ID, nil
+}
+
+// Upload sends world to the broker in chunks of ChunkSize cells, for a run to start from, and returns its ID.
+// Evolve uploads a starting world bigger than ChunkSize itself. Each chunk must arrive within Timeout.
+func (c *Client) Upload(ctx context.Context, world slab.World) (int, error) {
+	call := func(method string, req, res interface{}) error { return c.control(ctx, method, req, res) }
+	return UploadWorld(callerFunc(call), c.request().Epoch, world, c.ChunkSize)
+}
@@ -0,0 +1,65 @@
+package golclient
+
+import (
+	"bytes"
+	"hash/crc32"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// uploadRecorder is a Caller that records the calls of an upload.
+type uploadRecorder struct {
+	begin  stubs.BeginUploadRequest
+	chunks []stubs.AppendUploadRequest
+	commit stubs.CommitUploadRequest
+}
+
+func (r *uploadRecorder) Call(method string, args interface{}, reply interface{}) error {
+	switch method {
+	case stubs.BeginUploadHandler:
+		r.begin = args.(stubs.BeginUploadRequest)
+		reply.(*stubs.BeginUploadResponse).ID = 3
+	case stubs.AppendUploadHandler:
+		r.chunks = append(r.chunks, args.(stubs.AppendUploadRequest))
+	case stubs.CommitUploadHandler:
+		r.commit = args.(stubs.CommitUploadRequest)
+	}
+	return nil
+}
+
+// TestUploadWorld checks a world is sent packed, in order, in chunks of at most the chunk size, each with its
+// checksum, and committed with the checksum of the whole world.
+func TestUploadWorld(t *testing.T) {
+	// Rows of 3 cells out of rows of 4, so the world has to be packed before it is sent.
+	world := slab.World{Width: 3, Height: 3, Stride: 4, Cells: []byte{1, 2, 3, 0, 4, 5, 6, 0, 7, 8, 9}}
+	packed := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	r := &uploadRecorder{}
+	id, err := UploadWorld(r, 5, world, 4)
+	if err != nil || id != 3 {
+		t.Fatalf("got upload %d, %v, expected 3", id, err)
+	}
+	if r.begin != (stubs.BeginUploadRequest{Epoch: 5, Width: 3, Height: 3}) {
+		t.Errorf("began with %+v", r.begin)
+	}
+
+	var sent []byte
+	for i, chunk := range r.chunks {
+		if chunk.ID != 3 || chunk.Epoch != 5 || chunk.Offset != len(sent) || len(chunk.Cells) > 4 || chunk.Checksum != crc32.ChecksumIEEE(chunk.Cells) {
+			t.Errorf("chunk %d is %+v", i, chunk)
+		}
+		sent = append(sent, chunk.Cells...)
+	}
+	if len(r.chunks) != 3 || !bytes.Equal(sent, packed) {
+		t.Errorf("sent %v in %d chunks, expected %v in 3", sent, len(r.chunks), packed)
+	}
+	if r.commit.ID != 3 || r.commit.Checksum != crc32.ChecksumIEEE(packed) {
+		t.Errorf("committed with %+v", r.commit)
+	}
+
+	r = &uploadRecorder{}
+	if _, err := UploadWorld(r, 5, world, 0); err != nil || len(r.chunks) != 1 {
+		t.Errorf("sent %d chunks with no chunk size, expected the world in one: %v", len(r.chunks), err)
+	}
+}
@@ -0,0 +1,17 @@
+// goldiff compares two snapshots of a world, such as the output of a distributed run and of a reference run
+// for the same turn, and draws them overlaid: cells alive only in the second snapshot in green, cells alive
+// only in the first in red, and cells alive in both in grey. It exits with status 1 if the snapshots differ.
+// It is the same as the diff subcommand of the client, go run . diff.
+//
+//	go run ./goldiff -out diff.png check/images/512x512x100.pgm out/512x512x100.pgm
+package main
+
+import (
+	"os"
+
+	"uk.ac.bris.cs/gameoflife/golcli"
+)
+
+func main() {
+	golcli.Diff("goldiff", os.Args[1:])
+}
@@ -0,0 +1,196 @@
+// goljobs queues runs on a broker, which carries them out one after another whenever no client controls it,
+// and reports how they are getting on, so a backlog of experiments can be left to run overnight.
+//
+//	go run ./goljobs submit -turns 10000 -seed 7 images/512x512.pgm
+//	go run ./goljobs list
+//	go run ./goljobs status 3
+//	go run ./goljobs checkpoints
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"uk.ac.bris.cs/gameoflife/golclient"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/storage"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+func main() {
+	broker := flag.String("broker", "127.0.0.1:8030", "Address of the broker to queue jobs on")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintln(out, "Usage: goljobs [flags] submit [submit flags] image.pgm ...")
+		fmt.Fprintln(out, "       goljobs [flags] list")
+		fmt.Fprintln(out, "       goljobs [flags] status <id>")
+		fmt.Fprintln(out, "       goljobs [flags] checkpoints")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	client, err := golclient.Dial(ctx, *broker)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	switch flag.Arg(0) {
+	case "submit":
+		err = submit(ctx, client, flag.Args()[1:])
+	case "list":
+		var jobs []stubs.JobStatus
+		if jobs, err = client.Jobs(ctx); err == nil {
+			printJobs(jobs)
+		}
+	case "status":
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		id, convErr := strconv.Atoi(flag.Arg(1))
+		if convErr != nil {
+			fmt.Printf("Bad job ID %q\n", flag.Arg(1))
+			os.Exit(2)
+		}
+		var job stubs.JobStatus
+		if job, err = client.JobStatus(ctx, id); err == nil {
+			printJob(job)
+		}
+	case "checkpoints":
+		var checkpoints []stubs.CheckpointInfo
+		if checkpoints, err = client.Checkpoints(ctx); err == nil {
+			printCheckpoints(checkpoints)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// submit queues a job for each image named in args, all with the settings given in args before them.
+func submit(ctx context.Context, client *golclient.Client, args []string) error {
+	flags := flag.NewFlagSet("submit", flag.ExitOnError)
+	turns := flags.Int("turns", 100, "Turn to evolve each world up to")
+	rule := flags.String("rule", "B3/S23", "Rule in B/S notation, optionally with chances, e.g. B3/S23,S2=0.95")
+	edge := flags.String("edge", "torus", "What lies beyond the edges of the world: torus or dead")
+	seed := flags.Int64("seed", 0, "Seed of a stochastic rule's chances, or 0 for a different random seed per job")
+	name := flags.String("name", "", "Label for the jobs in listings. Defaults to the image's name and the seed")
+	threshold := flags.Float64("threshold", util.DefaultThreshold, "Fraction of an image's maxval at or above which a pixel is alive")
+	var labels util.Labels
+	flags.Var(&labels, "label", "Tag the jobs with key=value, e.g. experiment=gliders. Repeat for more")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("submit needs at least one image, e.g. images/512x512.pgm")
+	}
+
+	for _, path := range flags.Args() {
+		data, err := storage.Get(path)
+		if err != nil {
+			return err
+		}
+		width, height, cells, err := util.ParsePgm(data, *threshold)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		// The image's cells are already row after row, as a slab holds them.
+		world := slab.World{Width: width, Height: height, Stride: width, Cells: cells}
+
+		job := stubs.JobSpec{Name: *name, World: world, Turns: *turns, Rule: *rule, Edge: *edge, Seed: util.RunSeed(*seed), Labels: labels}
+		if job.Name == "" {
+			job.Name = fmt.Sprintf("%s seed %d", strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), job.Seed)
+		}
+		id, err := client.SubmitJob(ctx, job)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Queued job %d: %s, %d turns\n", id, job.Name, job.Turns)
+	}
+	return nil
+}
+
+// printJobs prints one line per job.
+func printJobs(jobs []stubs.JobStatus) {
+	if len(jobs) == 0 {
+		fmt.Println("No jobs have been submitted")
+		return
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tNAME\tSTATE\tSIZE\tTURN\tALIVE\tOUTPUT\tLABELS")
+	for _, job := range jobs {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%dx%d\t%d/%d\t%s\t%s\t%v\n", job.ID, job.Name, job.State, job.Width, job.Height,
+			job.Turn, job.Turns, alive(job), job.Output, util.Labels(job.Labels))
+	}
+	table.Flush()
+}
+
+// printJob prints everything known about a job.
+func printJob(job stubs.JobStatus) {
+	fmt.Printf("Job %d: %s\n", job.ID, job.Name)
+	fmt.Printf("State: %s\n", job.State)
+	fmt.Printf("World: %dx%d, seed %d\n", job.Width, job.Height, job.Seed)
+	if len(job.Labels) > 0 {
+		fmt.Printf("Labels: %v\n", util.Labels(job.Labels))
+	}
+	fmt.Printf("Turn: %d of %d\n", job.Turn, job.Turns)
+	fmt.Printf("Submitted: %s\n", job.Submitted.Format(time.RFC3339))
+	if !job.Started.IsZero() {
+		fmt.Printf("Started: %s\n", job.Started.Format(time.RFC3339))
+	}
+	if !job.Finished.IsZero() {
+		fmt.Printf("Finished: %s (took %v)\n", job.Finished.Format(time.RFC3339), job.Finished.Sub(job.Started).Round(time.Second))
+	}
+	if job.State == stubs.JobDone {
+		fmt.Printf("Alive cells: %s\n", alive(job))
+		if job.Period > 0 {
+			fmt.Printf("Cycle: period %d\n", job.Period)
+		}
+		fmt.Printf("Output: %s\n", job.Output)
+	}
+	if job.Checkpoint != "" {
+		fmt.Printf("Checkpoint: %s\n", job.Checkpoint)
+	}
+	if job.Error != "" {
+		fmt.Printf("Error: %s\n", job.Error)
+	}
+}
+
+// printCheckpoints prints one line per checkpoint the broker can be resumed from.
+func printCheckpoints(checkpoints []stubs.CheckpointInfo) {
+	if len(checkpoints) == 0 {
+		fmt.Println("The broker hasn't saved any checkpoints")
+		return
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TURN\tSAVED\tSIZE\tPATH\tLABELS")
+	for _, c := range checkpoints {
+		fmt.Fprintf(table, "%d\t%s\t%.1f MiB\t%s\t%v\n", c.Turn, c.Saved.Format(time.RFC3339), float64(c.Bytes)/float64(util.MiB), c.Path,
+			util.Labels(c.Labels))
+	}
+	table.Flush()
+}
+
+// alive returns a finished job's count of alive cells, or a dash while it isn't known yet.
+func alive(job stubs.JobStatus) string {
+	if job.State != stubs.JobDone {
+		return "-"
+	}
+	return strconv.Itoa(job.Alive)
+}
@@ -0,0 +1,114 @@
+// Package golnpy reads and writes worlds as NumPy .npy arrays, so a saved board can be loaded straight into Python
+// with numpy.load rather than by parsing a PGM. A world is stored as a C-ordered uint8 array of shape
+// (height, width) holding 1 for an alive cell and 0 for a dead one, so arr.sum() is the population.
+//
+// Version 1.0 of the format is written:
+//
+//	magic   "\x93NUMPY"
+//	version 1, 0
+//	length  uint16 little-endian, the length of the header
+//	header  a Python dict literal giving the dtype, order and shape, padded with spaces and ended by a newline so
+//	        the cells start at a multiple of 64 bytes
+//	cells   height rows of width bytes
+package golnpy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Extension is the file extension arrays are saved with.
+const Extension = ".npy"
+
+var magic = []byte("\x93NUMPY")
+
+// alignment is the multiple of bytes the header is padded to, which NumPy itself uses so the cells can be mapped
+// into memory aligned.
+const alignment = 64
+
+// Encode returns a world as a .npy array.
+func Encode(world slab.World) []byte {
+	header := fmt.Sprintf("{'descr': '|u1', 'fortran_order': False, 'shape': (%d, %d), }", world.Height, world.Width)
+	// Magic, version and length come before the header, and the newline ends it.
+	prefix := len(magic) + 2 + 2
+	if pad := (alignment - (prefix+len(header)+1)%alignment) % alignment; pad > 0 {
+		header += string(bytes.Repeat([]byte{' '}, pad))
+	}
+	header += "\n"
+
+	data := make([]byte, 0, prefix+len(header)+world.Width*world.Height)
+	data = append(data, magic...)
+	data = append(data, 1, 0)
+	data = append(data, byte(len(header)), byte(len(header)>>8))
+	data = append(data, header...)
+	for _, row := range world.Rows() {
+		for _, cell := range row {
+			if cell == util.Alive {
+				data = append(data, 1)
+			} else {
+				data = append(data, 0)
+			}
+		}
+	}
+	return data
+}
+
+var (
+	descrPattern = regexp.MustCompile(`'descr':\s*'([^']*)'`)
+	orderPattern = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	shapePattern = regexp.MustCompile(`'shape':\s*\(\s*(\d+)\s*,\s*(\d+)\s*,?\s*\)`)
+)
+
+// Decode reads a world from a 2-D array of uint8 or bool, such as one written by Encode or numpy.save, taking any
+// cell that isn't 0 to be alive. Versions 1.0 to 3.0 of the format are read.
+func Decode(data []byte) (slab.World, error) {
+	if len(data) < len(magic)+4 || !bytes.Equal(data[:len(magic)], magic) {
+		return slab.World{}, errors.New("not a .npy array")
+	}
+	data = data[len(magic):]
+	var length int
+	switch version := data[0]; version {
+	case 1:
+		length, data = int(binary.LittleEndian.Uint16(data[2:4])), data[4:]
+	case 2, 3:
+		if len(data) < 6 {
+			return slab.World{}, errors.New("the .npy array ends in its header")
+		}
+		length, data = int(binary.LittleEndian.Uint32(data[2:6])), data[6:]
+	default:
+		return slab.World{}, fmt.Errorf("version %d.%d of the .npy format isn't supported", version, data[1])
+	}
+	if length > len(data) {
+		return slab.World{}, errors.New("the .npy array ends in its header")
+	}
+	header, cells := string(data[:length]), data[length:]
+
+	descr, order, shape := descrPattern.FindStringSubmatch(header), orderPattern.FindStringSubmatch(header), shapePattern.FindStringSubmatch(header)
+	switch {
+	case descr == nil || order == nil || shape == nil:
+		return slab.World{}, fmt.Errorf("the .npy header %q isn't for a 2-D array", header)
+	case descr[1] != "|u1" && descr[1] != "u1" && descr[1] != "|b1":
+		return slab.World{}, fmt.Errorf("the .npy array holds %s, expected uint8 or bool", descr[1])
+	case order[1] == "True":
+		return slab.World{}, errors.New("the .npy array is in Fortran order, expected C order")
+	}
+	height, _ := strconv.Atoi(shape[1])
+	width, _ := strconv.Atoi(shape[2])
+	if len(cells) != width*height {
+		return slab.World{}, fmt.Errorf("the .npy array has %d cells, expected %dx%d", len(cells), width, height)
+	}
+	world := slab.New(width, height)
+	for i, cell := range cells {
+		if cell != 0 {
+			world.Cells[i] = util.Alive
+		}
+	}
+	return world, nil
+}
@@ -0,0 +1,62 @@
+package golnpy
+
+import (
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestEncode checks a world is written with the header numpy.save writes for a uint8 array of its shape, and the
+// cells as 0 and 1 after it.
+func TestEncode(t *testing.T) {
+	world := slab.New(3, 2)
+	world.Set(1, 0, util.Alive)
+	world.Set(2, 1, util.Alive)
+	data := Encode(world)
+
+	// numpy.save(f, numpy.zeros((2, 3), numpy.uint8)) writes the same 128 bytes before the cells.
+	header := "{'descr': '|u1', 'fortran_order': False, 'shape': (2, 3), }" + strings.Repeat(" ", 58) + "\n"
+	expected := "\x93NUMPY\x01\x00\x76\x00" + header + "\x00\x01\x00\x00\x00\x01"
+	if string(data) != expected {
+		t.Errorf("encoded as %q, expected %q", data, expected)
+	}
+}
+
+// TestDecode checks worlds come back as they were encoded, including from a version 2.0 header, and that arrays
+// that aren't 2-D uint8 in C order are refused.
+func TestDecode(t *testing.T) {
+	world := slab.New(70, 3)
+	for _, cell := range []util.Cell{{X: 0, Y: 0}, {X: 69, Y: 1}, {X: 35, Y: 2}} {
+		world.Set(cell.X, cell.Y, util.Alive)
+	}
+	data := Encode(world)
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(world) {
+		t.Errorf("decoded %dx%d world with %d cells alive, expected the one encoded", decoded.Width, decoded.Height, len(decoded.Cells))
+	}
+
+	// Version 2.0 has a 4-byte header length.
+	length := len(data) - len(world.Cells) - 10
+	v2 := append([]byte("\x93NUMPY\x02\x00"), byte(length), byte(length>>8), 0, 0)
+	v2 = append(v2, data[10:]...)
+	if decoded, err := Decode(v2); err != nil || !decoded.Equal(world) {
+		t.Errorf("decoding a version 2.0 array failed: %v", err)
+	}
+
+	for name, bad := range map[string]string{
+		"fortran": strings.Replace(string(data), "False", "True", 1),
+		"float":   strings.Replace(string(data), "|u1", "<f8", 1),
+		"3-D":     strings.Replace(string(data), "(3, 70)", "(3, 7, 10)", 1),
+		"short":   string(data[:len(data)-1]),
+		"pgm":     "P5\n3 2\n255\n",
+	} {
+		if _, err := Decode([]byte(bad)); err == nil {
+			t.Errorf("decoded the %s array", name)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package golsnap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// A delta saves a state as the cells that differ from a keyframe, a state saved whole by Encode, so saving a board
+// that changes slowly writes a few bytes rather than the whole board. It is stored big-endian as:
+//
+//	magic    "GOLD"
+//	version  uint16, DeltaVersion when written
+//	base     uint32, the checksum the keyframe's encoding ends with, so a delta is never applied to another one
+//	turn     uint64
+//	seed     int64
+//	edge     uint8, as in a state
+//	rule     uint16 length, then the rule in B/S notation
+//	count    uint32, then count uint32 indices y*width+x, in order, of the cells that differ from the keyframe
+//	labels   as in a state
+//	checksum uint32, CRC-32 (IEEE) of everything before it
+//
+// The world is the keyframe's size.
+
+// DeltaVersion is the version of the delta format written by EncodeDelta, and the newest ApplyDelta reads.
+const DeltaVersion = 1
+
+var deltaMagic = []byte("GOLD")
+
+// deltaHeaderSize is the size of the fixed fields of a delta before the rule.
+const deltaHeaderSize = 4 + 2 + 4 + 8 + 8 + 1 + 2
+
+// ErrOtherKeyframe is returned by ApplyDelta for a delta taken against a different keyframe.
+var ErrOtherKeyframe = errors.New("delta was saved against another keyframe")
+
+// Keyframe is a state saved whole, which deltas are taken against and applied to.
+type Keyframe struct {
+	State
+	Sum uint32 // Checksum the keyframe's encoding ends with, which its deltas name it by.
+}
+
+// NewKeyframe encodes s whole, returning it as a keyframe along with its encoding.
+func NewKeyframe(s State) (Keyframe, []byte, error) {
+	data, err := Encode(s)
+	if err != nil {
+		return Keyframe{}, nil, err
+	}
+	return Keyframe{State: s, Sum: binary.BigEndian.Uint32(data[len(data)-4:])}, data, nil
+}
+
+// DecodeKeyframe reads a state written by Encode, in any version of the format, as a keyframe.
+func DecodeKeyframe(data []byte) (Keyframe, error) {
+	s, err := Decode(data)
+	if err != nil {
+		return Keyframe{}, err
+	}
+	return Keyframe{State: s, Sum: binary.BigEndian.Uint32(data[len(data)-4:])}, nil
+}
+
+// IsDelta reports whether data starts like a delta.
+func IsDelta(data []byte) bool {
+	return bytes.HasPrefix(data, deltaMagic)
+}
+
+// EncodeDelta returns s as a delta against k, whose world must be the same size.
+func (k Keyframe) EncodeDelta(s State) ([]byte, error) {
+	width, height := s.World.Width, s.World.Height
+	if width != k.World.Width || height != k.World.Height {
+		return nil, fmt.Errorf("a %dx%d world can't be saved against a %dx%d keyframe", width, height, k.World.Width, k.World.Height)
+	}
+	opts, err := kernel.ParseOptions(s.Rule, s.Edge)
+	if err != nil {
+		return nil, err
+	}
+	rule := opts.Rule.String()
+	if len(rule) > 0xFFFF {
+		return nil, errors.New("rule is too long to save")
+	}
+
+	var changed []uint32
+	for y := 0; y < height; y++ {
+		row, base := s.World.Row(y), k.World.Row(y)
+		if bytes.Equal(row, base) {
+			continue
+		}
+		for x := range row {
+			if (row[x] == util.Alive) != (base[x] == util.Alive) {
+				changed = append(changed, uint32(y*width+x))
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(deltaHeaderSize + len(rule) + 4 + 4*len(changed) + 4)
+	buf.Write(deltaMagic)
+	for _, field := range []interface{}{uint16(DeltaVersion), k.Sum, uint64(s.Turn), s.Seed, uint8(opts.Edge),
+		uint16(len(rule))} {
+		binary.Write(&buf, binary.BigEndian, field)
+	}
+	buf.WriteString(rule)
+	binary.Write(&buf, binary.BigEndian, uint32(len(changed)))
+	binary.Write(&buf, binary.BigEndian, changed)
+	if err := writeLabels(&buf, s.Labels); err != nil {
+		return nil, err
+	}
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	return buf.Bytes(), nil
+}
+
+// ApplyDelta returns the state saved in a delta written by EncodeDelta against k. A delta taken against another
+// keyframe is refused with ErrOtherKeyframe.
+func (k Keyframe) ApplyDelta(data []byte) (State, error) {
+	if !IsDelta(data) {
+		return State{}, errors.New("not a saved delta")
+	}
+	if len(data) < deltaHeaderSize+4 {
+		return State{}, errors.New("saved delta is truncated")
+	}
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return State{}, errors.New("saved delta is corrupt: checksum doesn't match")
+	}
+	if version := binary.BigEndian.Uint16(body[4:]); version < 1 || version > DeltaVersion {
+		return State{}, fmt.Errorf("saved delta is version %d of the format, but only versions 1 to %d can be read", version, DeltaVersion)
+	}
+	if binary.BigEndian.Uint32(body[6:]) != k.Sum {
+		return State{}, ErrOtherKeyframe
+	}
+	if edge := kernel.Edge(body[26]); edge != kernel.Torus && edge != kernel.DeadEdge {
+		return State{}, fmt.Errorf("saved delta has an unknown edge mode %d", edge)
+	}
+	s := State{
+		Turn: int(binary.BigEndian.Uint64(body[10:])),
+		Seed: int64(binary.BigEndian.Uint64(body[18:])),
+		Edge: kernel.Edge(body[26]).String(),
+	}
+	ruleLength := int(binary.BigEndian.Uint16(body[27:]))
+	rest := body[deltaHeaderSize:]
+	if len(rest) < ruleLength+4 {
+		return State{}, errors.New("saved delta is truncated: too short for its rule")
+	}
+	s.Rule, rest = string(rest[:ruleLength]), rest[ruleLength:]
+	if _, err := kernel.ParseRule(s.Rule); err != nil {
+		return State{}, fmt.Errorf("saved delta has an unreadable rule: %v", err)
+	}
+
+	count := int(binary.BigEndian.Uint32(rest))
+	rest = rest[4:]
+	if len(rest)/4 < count {
+		return State{}, fmt.Errorf("saved delta is truncated: too short for %d cells", count)
+	}
+	width, cells := k.World.Width, k.World.Width*k.World.Height
+	s.World = k.World.Clone()
+	for i := 0; i < count; i++ {
+		cell := int(binary.BigEndian.Uint32(rest[4*i:]))
+		if cell >= cells {
+			return State{}, fmt.Errorf("saved delta changes cell %d of a %dx%d world", cell, width, k.World.Height)
+		}
+		x, y := cell%width, cell/width
+		if s.World.At(x, y) == util.Alive {
+			s.World.Set(x, y, util.Dead)
+		} else {
+			s.World.Set(x, y, util.Alive)
+		}
+	}
+	var err error
+	if s.Labels, err = decodeLabels(rest[4*count:]); err != nil {
+		return State{}, err
+	}
+	return s, nil
+}
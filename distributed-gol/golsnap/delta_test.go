@@ -0,0 +1,86 @@
+package golsnap
+
+import (
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestDelta checks a state saved as a delta against a keyframe comes back as it was, and takes far less space than
+// the state saved whole when few cells have changed.
+func TestDelta(t *testing.T) {
+	keyframe, whole, err := NewKeyframe(State{Turn: 100, World: slab.FromRows(soup(64, 64))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := State{Turn: 150, Seed: 7, Rule: "B36/S23", Edge: "dead", World: keyframe.World.Clone(),
+		Labels: map[string]string{"experiment": "deltas"}}
+	for _, cell := range []util.Cell{{X: 0, Y: 0}, {X: 63, Y: 0}, {X: 31, Y: 40}, {X: 63, Y: 63}} {
+		if later.World.At(cell.X, cell.Y) == util.Alive {
+			later.World.Set(cell.X, cell.Y, util.Dead)
+		} else {
+			later.World.Set(cell.X, cell.Y, util.Alive)
+		}
+	}
+	delta, err := keyframe.EncodeDelta(later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsDelta(delta) || Is(delta) {
+		t.Error("a delta isn't told apart from a state saved whole")
+	}
+	if len(delta)*4 > len(whole) {
+		t.Errorf("a delta of 4 cells took %d bytes, and the whole world %d", len(delta), len(whole))
+	}
+
+	// The keyframe read back from disk applies the delta just as well as the one it was taken against.
+	decoded, err := DecodeKeyframe(whole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decoded.ApplyDelta(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Turn != later.Turn || got.Seed != later.Seed || got.Rule != "B36/S23" || got.Edge != "dead" ||
+		got.Labels["experiment"] != "deltas" {
+		t.Errorf("delta came back as turn %d, seed %d, rule %s, edges %s, labels %v", got.Turn, got.Seed, got.Rule,
+			got.Edge, got.Labels)
+	}
+	if !got.World.Equal(later.World) {
+		t.Error("the world differs after applying the delta")
+	}
+	if !decoded.World.Equal(keyframe.World) {
+		t.Error("applying a delta changed the keyframe")
+	}
+}
+
+// TestDeltaRejects checks a delta is only applied to the keyframe it was taken against, a damaged one is refused,
+// and a world of another size can't be saved against a keyframe at all.
+func TestDeltaRejects(t *testing.T) {
+	keyframe, _, err := NewKeyframe(State{Turn: 1, World: slab.FromRows(soup(16, 16))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := NewKeyframe(State{Turn: 2, World: slab.FromRows(soup(16, 16))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	delta, err := keyframe.EncodeDelta(State{Turn: 3, World: other.World})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.ApplyDelta(delta); err != ErrOtherKeyframe {
+		t.Errorf("applying a delta to another keyframe returned %v, expected %v", err, ErrOtherKeyframe)
+	}
+	flipped := append([]byte(nil), delta...)
+	flipped[len(flipped)/2] ^= 0x10
+	if _, err := keyframe.ApplyDelta(flipped); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("applying a damaged delta returned %v, expected a checksum error", err)
+	}
+	if _, err := keyframe.EncodeDelta(State{World: slab.FromRows(soup(16, 8))}); err == nil {
+		t.Error("a 16x8 world was saved against a 16x16 keyframe")
+	}
+}
@@ -0,0 +1,210 @@
+// Package golsnap reads and writes the state of a run in the versioned binary format the broker's checkpoints
+// and the client's q saves share (.golsnap), so a saved run keeps its rule, edges, turn and seed as well as its
+// cells, and any of them can be resumed from with the broker's -resume.
+//
+// A state is stored big-endian as:
+//
+//	magic    "GOLS"
+//	version  uint16, Version when written
+//	width    uint32
+//	height   uint32
+//	turn     uint64
+//	seed     int64
+//	edge     uint8, 0 for a torus and 1 for dead edges
+//	rule     uint16 length, then the rule in B/S notation
+//	cells    height rows of (width+7)/8 bytes, a bit per cell, the leftmost cell in the lowest bit
+//	labels   since version 2: uint16 count, then for each label, sorted by key, a uint16 length and the key,
+//	         and a uint16 length and the value
+//	checksum uint32, CRC-32 (IEEE) of everything before it
+//
+// A later version may add fields, but only after the ones above, so older versions can be read by newer code.
+package golsnap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Version is the version of the format written by Encode, and the newest Decode reads.
+const Version = 2
+
+// Extension is the file extension states are saved with.
+const Extension = ".golsnap"
+
+var magic = []byte("GOLS")
+
+// headerSize is the size of the fixed fields before the rule.
+const headerSize = 4 + 2 + 4 + 4 + 8 + 8 + 1 + 2
+
+// State is everything needed to carry on a run from where it was saved.
+type State struct {
+	Turn  int
+	Seed  int64  // Seed of a stochastic rule's chances.
+	Rule  string // Rule in B/S notation, saved the way kernel.Rule writes it, so an empty string comes back as B3/S23.
+	Edge  string // "torus" or "dead", saved the way kernel.Edge writes it.
+	World slab.World
+	// Labels the run was tagged with, e.g. its experiment and author. Versions before 2 have none.
+	Labels map[string]string
+}
+
+// Is reports whether data starts like a saved state, as opposed to e.g. a PGM image or an older checkpoint.
+func Is(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Encode returns the state in the current version of the format.
+func Encode(s State) ([]byte, error) {
+	width, height := s.World.Width, s.World.Height
+	opts, err := kernel.ParseOptions(s.Rule, s.Edge)
+	if err != nil {
+		return nil, err
+	}
+	rule := opts.Rule.String()
+	if len(rule) > 0xFFFF {
+		return nil, errors.New("rule is too long to save")
+	}
+
+	rowBytes := (width + 7) / 8
+	var buf bytes.Buffer
+	buf.Grow(headerSize + len(rule) + rowBytes*height + 4)
+	buf.Write(magic)
+	for _, field := range []interface{}{uint16(Version), uint32(width), uint32(height), uint64(s.Turn), s.Seed,
+		uint8(opts.Edge), uint16(len(rule))} {
+		binary.Write(&buf, binary.BigEndian, field)
+	}
+	buf.WriteString(rule)
+	row := make([]byte, rowBytes)
+	for y := 0; y < height; y++ {
+		for i := range row {
+			row[i] = 0
+		}
+		for x, cell := range s.World.Row(y) {
+			if cell == util.Alive {
+				row[x/8] |= 1 << uint(x%8)
+			}
+		}
+		buf.Write(row)
+	}
+	if err := writeLabels(&buf, s.Labels); err != nil {
+		return nil, err
+	}
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	return buf.Bytes(), nil
+}
+
+// writeLabels writes the labels that follow the cells since version 2.
+func writeLabels(buf *bytes.Buffer, labels util.Labels) error {
+	if len(labels) > 0xFFFF {
+		return errors.New("too many labels to save")
+	}
+	binary.Write(buf, binary.BigEndian, uint16(len(labels)))
+	for _, key := range labels.Keys() {
+		for _, text := range []string{key, labels[key]} {
+			if len(text) > 0xFFFF {
+				return fmt.Errorf("label %s is too long to save", key)
+			}
+			binary.Write(buf, binary.BigEndian, uint16(len(text)))
+			buf.WriteString(text)
+		}
+	}
+	return nil
+}
+
+// Decode reads a state written by Encode in this or any earlier version of the format.
+func Decode(data []byte) (State, error) {
+	if !Is(data) {
+		return State{}, errors.New("not a saved state")
+	}
+	if len(data) < headerSize+4 {
+		return State{}, errors.New("saved state is truncated")
+	}
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return State{}, errors.New("saved state is corrupt: checksum doesn't match")
+	}
+
+	version := binary.BigEndian.Uint16(body[4:])
+	if version < 1 || version > Version {
+		return State{}, fmt.Errorf("saved state is version %d of the format, but only versions 1 to %d can be read", version, Version)
+	}
+	width := int(binary.BigEndian.Uint32(body[6:]))
+	height := int(binary.BigEndian.Uint32(body[10:]))
+	if edge := kernel.Edge(body[30]); edge != kernel.Torus && edge != kernel.DeadEdge {
+		return State{}, fmt.Errorf("saved state has an unknown edge mode %d", edge)
+	}
+	s := State{
+		Turn: int(binary.BigEndian.Uint64(body[14:])),
+		Seed: int64(binary.BigEndian.Uint64(body[22:])),
+		Edge: kernel.Edge(body[30]).String(),
+	}
+	ruleLength := int(binary.BigEndian.Uint16(body[31:]))
+	rest := body[headerSize:]
+	rowBytes := (width + 7) / 8
+	if len(rest) < ruleLength+rowBytes*height {
+		return State{}, fmt.Errorf("saved state is truncated: too short for a %dx%d world", width, height)
+	}
+	s.Rule, rest = string(rest[:ruleLength]), rest[ruleLength:]
+	if _, err := kernel.ParseRule(s.Rule); err != nil {
+		return State{}, fmt.Errorf("saved state has an unreadable rule: %v", err)
+	}
+
+	s.World = slab.New(width, height)
+	for y := 0; y < height; y++ {
+		row := rest[y*rowBytes : (y+1)*rowBytes]
+		for x := 0; x < width; x++ {
+			if row[x/8]>>uint(x%8)&1 != 0 {
+				s.World.Set(x, y, util.Alive)
+			}
+		}
+	}
+	if version >= 2 {
+		var err error
+		if s.Labels, err = decodeLabels(rest[rowBytes*height:]); err != nil {
+			return State{}, err
+		}
+	}
+	return s, nil
+}
+
+// decodeLabels reads the labels that follow the cells since version 2.
+func decodeLabels(data []byte) (map[string]string, error) {
+	errTruncated := errors.New("saved state is truncated: too short for its labels")
+	// text reads a uint16 length and that many bytes.
+	text := func() (string, bool) {
+		if len(data) < 2 {
+			return "", false
+		}
+		length := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+length {
+			return "", false
+		}
+		s := string(data[2 : 2+length])
+		data = data[2+length:]
+		return s, true
+	}
+	if len(data) < 2 {
+		return nil, errTruncated
+	}
+	count := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if count == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, count)
+	for i := 0; i < count; i++ {
+		key, ok := text()
+		value, ok2 := text()
+		if !ok || !ok2 {
+			return nil, errTruncated
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
@@ -0,0 +1,135 @@
+package golsnap
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// soup returns a random width x height world.
+func soup(width, height int) [][]byte {
+	random := rand.New(rand.NewSource(int64(width*height + 1)))
+	world := make([][]byte, height)
+	for y := range world {
+		world[y] = make([]byte, width)
+		for x := range world[y] {
+			if random.Intn(2) == 0 {
+				world[y][x] = util.Alive
+			}
+		}
+	}
+	return world
+}
+
+// TestRoundTrip checks a state decodes to what was encoded, for widths that don't fill their last byte, with the
+// rule and edges written the same way the kernel writes them.
+func TestRoundTrip(t *testing.T) {
+	for _, size := range [][2]int{{16, 16}, {13, 7}, {1, 1}, {0, 0}} {
+		state := State{Turn: 123456789, Seed: -42, Rule: "b36/s23", Edge: "DEAD", World: slab.FromRows(soup(size[0], size[1]))}
+		data, err := Encode(state)
+		if err != nil {
+			t.Fatalf("%dx%d: %v", size[0], size[1], err)
+		}
+		if !Is(data) {
+			t.Errorf("%dx%d: Is doesn't recognise an encoded state", size[0], size[1])
+		}
+		got, err := Decode(data)
+		if err != nil {
+			t.Fatalf("%dx%d: %v", size[0], size[1], err)
+		}
+		if got.Turn != state.Turn || got.Seed != state.Seed || got.Rule != "B36/S23" || got.Edge != "dead" {
+			t.Errorf("%dx%d: decoded turn %d, seed %d, rule %s, edges %s, expected %d, %d, B36/S23, dead",
+				size[0], size[1], got.Turn, got.Seed, got.Rule, got.Edge, state.Turn, state.Seed)
+		}
+		if got.World.Width != size[0] || got.World.Height != size[1] {
+			t.Fatalf("%dx%d: decoded a %dx%d world", size[0], size[1], got.World.Width, got.World.Height)
+		}
+		for y := 0; y < size[1]; y++ {
+			if string(got.World.Row(y)) != string(state.World.Row(y)) {
+				t.Fatalf("%dx%d: row %d differs after decoding", size[0], size[1], y)
+			}
+		}
+	}
+}
+
+// TestDecodeRejects checks damaged states, states from a newer version and other files are refused with an
+// error saying why, rather than decoded into a wrong world.
+func TestDecodeRejects(t *testing.T) {
+	data, err := Encode(State{Turn: 5, World: slab.FromRows(soup(16, 16))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)/2] ^= 0x10
+	newer := append([]byte(nil), data...)
+	binary.BigEndian.PutUint16(newer[4:], Version+1)
+	binary.BigEndian.PutUint32(newer[len(newer)-4:], crc32.ChecksumIEEE(newer[:len(newer)-4]))
+
+	for name, test := range map[string]struct {
+		data     []byte
+		expected string
+	}{
+		"flipped bit": {flipped, "checksum"},
+		"truncated":   {data[:len(data)-10], "checksum"},
+		"header only": {data[:headerSize], "truncated"},
+		"newer":       {newer, "version"},
+		"pgm":         {[]byte("P5\n16 16\n255\n"), "not a saved state"},
+	} {
+		if _, err := Decode(test.data); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: got error %v, expected one mentioning %q", name, err, test.expected)
+		}
+	}
+}
+
+// TestLabels checks a state's labels survive encoding, and a version 1 state, from before labels were saved,
+// still decodes without any.
+func TestLabels(t *testing.T) {
+	labels := map[string]string{"experiment": "gliders", "author": "kim", "notes": ""}
+	data, err := Encode(State{Turn: 5, World: slab.FromRows(soup(13, 7)), Labels: labels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Labels) != len(labels) {
+		t.Fatalf("decoded labels %v, expected %v", got.Labels, labels)
+	}
+	for key, value := range labels {
+		if got.Labels[key] != value {
+			t.Errorf("label %s decoded as %q, expected %q", key, got.Labels[key], value)
+		}
+	}
+
+	// A version 1 state ends with the cells, where version 2 has a count of no labels.
+	unlabelled, err := Encode(State{Turn: 5, World: slab.FromRows(soup(13, 7))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := append([]byte(nil), unlabelled[:len(unlabelled)-6]...)
+	binary.BigEndian.PutUint16(v1[4:], 1)
+	v1 = append(v1, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(v1[len(v1)-4:], crc32.ChecksumIEEE(v1[:len(v1)-4]))
+	old, err := Decode(v1)
+	if err != nil {
+		t.Fatalf("couldn't decode a version 1 state: %v", err)
+	}
+	if old.Turn != 5 || old.Labels != nil || string(old.World.Row(6)) != string(soup(13, 7)[6]) {
+		t.Errorf("version 1 state decoded as turn %d with labels %v", old.Turn, old.Labels)
+	}
+
+	// Cutting the labels short is caught even with a checksum to match.
+	cut := append([]byte(nil), data[:len(data)-8]...)
+	cut = append(cut, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(cut[len(cut)-4:], crc32.ChecksumIEEE(cut[:len(cut)-4]))
+	if _, err := Decode(cut); err == nil || !strings.Contains(err.Error(), "labels") {
+		t.Errorf("got error %v decoding cut short labels, expected one about the labels", err)
+	}
+}
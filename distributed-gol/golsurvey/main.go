@@ -0,0 +1,304 @@
+// golsurvey runs many patterns and random soups to completion on this machine and records what became of each,
+// whether it died out, settled into still lifes or oscillators of some period, or was still changing, and how many
+// gliders it sent off, in a results file that later surveys add to and query can search.
+//
+//	go run ./golsurvey run -soups 1000 -size 64
+//	go run ./golsurvey run -turns 5000 '.O./..O/OOO' 'OOO/O../.O.' images/16x16.pgm
+//	go run ./golsurvey query -outcome oscillating -minPeriod 3
+//	go run ./golsurvey query -minGliders 2 -label experiment=soups
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/pattern"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/storage"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+func main() {
+	results := flag.String("results", "survey.jsonl", "File the results are saved in, one JSON object per line")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintln(out, "Usage: golsurvey [flags] run [run flags] [pattern or image.pgm ...]")
+		fmt.Fprintln(out, "       golsurvey [flags] query [query flags]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch flag.Arg(0) {
+	case "run":
+		err = run(*results, flag.Args()[1:])
+	case "query":
+		err = search(*results, flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// experiment is one pattern or soup to classify.
+type experiment struct {
+	name  string
+	seed  int64
+	world slab.World
+}
+
+// run classifies every pattern and image named in args, and -soups random soups, with the settings given in args
+// before them, saving each result as it comes. Experiments already in the results file with the same settings are
+// skipped, so a survey stopped part way through picks up where it left off.
+func run(path string, args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	turns := flags.Int("turns", 10000, "Most turns each pattern is given to settle")
+	size := flags.Int("size", 64, "Width and height of the board patterns are placed in the middle of, and of soups")
+	soups := flags.Int("soups", 0, "Random soups to classify, with seeds counting up from -seed")
+	seed := flags.Int64("seed", 1, "Seed of the first soup")
+	density := flags.Float64("density", 0.375, "Chance of each cell of a soup starting alive")
+	rule := flags.String("rule", "B3/S23", "Rule in B/S notation, optionally with chances, e.g. B3/S23,S2=0.95")
+	edge := flags.String("edge", "dead", "What lies beyond the edges of the board: dead, so escaping gliders die at the edge, or torus")
+	threshold := flags.Float64("threshold", util.DefaultThreshold, "Fraction of an image's maxval at or above which a pixel is alive")
+	threads := flags.Int("threads", runtime.NumCPU(), "Experiments classified at once")
+	var labels util.Labels
+	flags.Var(&labels, "label", "Tag the results with key=value, e.g. experiment=soups. Repeat for more")
+	flags.Parse(args)
+	if flags.NArg() == 0 && *soups == 0 {
+		return fmt.Errorf("run needs patterns, images or -soups, e.g. run -soups 100")
+	}
+	opts, err := kernel.ParseOptions(*rule, *edge)
+	if err != nil {
+		return err
+	}
+	opts.Seed = *seed
+
+	var experiments []experiment
+	for _, arg := range flags.Args() {
+		p, err := readPattern(arg, *threshold)
+		if err != nil {
+			return err
+		}
+		experiments = append(experiments, experiment{name: arg, world: centre(p, *size, *size)})
+	}
+	for i := 0; i < *soups; i++ {
+		experiments = append(experiments, experiment{name: "soup", seed: *seed + int64(i), world: soup(*size, *size, *density, *seed+int64(i))})
+	}
+
+	saved, err := loadResults(path)
+	if err != nil {
+		return err
+	}
+	done := make(map[string]bool)
+	for _, r := range saved {
+		done[r.key()] = true
+	}
+	var todo []experiment
+	for _, e := range experiments {
+		if !done[resultFor(e, *turns, opts).key()] {
+			todo = append(todo, e)
+		}
+	}
+	if skipped := len(experiments) - len(todo); skipped > 0 {
+		fmt.Printf("Skipping %d experiments already in %s\n", skipped, path)
+	}
+
+	// Classify on every thread, saving from this goroutine alone so lines in the file never interleave.
+	queue, out := make(chan experiment), make(chan Result)
+	var wg sync.WaitGroup
+	for i := 0; i < *threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range queue {
+				r := classify(e.world, *turns, opts)
+				r.Name, r.Seed = e.name, e.seed
+				out <- r
+			}
+		}()
+	}
+	go func() {
+		for _, e := range todo {
+			queue <- e
+		}
+		close(queue)
+		wg.Wait()
+		close(out)
+	}()
+	for r := range out {
+		r.Labels, r.Recorded = labels, time.Now()
+		if err := appendResult(path, r); err != nil {
+			return err
+		}
+		fmt.Println(describe(r))
+	}
+	return nil
+}
+
+// resultFor returns the result an experiment would have before it is classified, for looking it up.
+func resultFor(e experiment, turns int, opts kernel.Options) Result {
+	return Result{Name: e.name, Seed: e.seed, Rule: opts.Rule.String(), Edge: opts.Edge.String(), Width: e.world.Width,
+		Height: e.world.Height, MaxTurns: turns}
+}
+
+// readPattern reads a pattern given as rows separated by '/', e.g. "OOO" or ".O./..O/OOO", or from the PGM image at
+// arg if it isn't one.
+func readPattern(arg string, threshold float64) (slab.World, error) {
+	if strings.Trim(arg, "Oo*./") == "" {
+		return pattern.Parse(arg)
+	}
+	data, err := storage.Get(arg)
+	if err != nil {
+		return slab.World{}, err
+	}
+	width, height, cells, err := util.ParsePgm(data, threshold)
+	if err != nil {
+		return slab.World{}, fmt.Errorf("%s: %v", arg, err)
+	}
+	// The image's cells are already row after row, as a slab holds them.
+	return slab.World{Width: width, Height: height, Stride: width, Cells: cells}, nil
+}
+
+// describe says what became of an experiment in a line.
+func describe(r Result) string {
+	name := r.Name
+	if r.Name == "soup" {
+		name = fmt.Sprintf("soup %d", r.Seed)
+	}
+	var outcome string
+	switch r.Outcome {
+	case Extinct:
+		if r.Gliders > 0 {
+			outcome = fmt.Sprintf("left nothing but gliders by turn %d", r.SettledAt)
+		} else {
+			outcome = fmt.Sprintf("died out after %d turns", r.Turns)
+		}
+	case Stable:
+		outcome = fmt.Sprintf("became still at turn %d, %d alive", r.SettledAt, r.Alive)
+	case Oscillating:
+		outcome = fmt.Sprintf("settled at turn %d into period %d, %d alive", r.SettledAt, r.Period, r.Alive)
+	default:
+		outcome = fmt.Sprintf("was still changing after %d turns, %d alive", r.Turns, r.Alive)
+	}
+	if r.Gliders == 1 {
+		outcome += ", 1 glider"
+	} else if r.Gliders > 1 {
+		outcome += fmt.Sprintf(", %d gliders", r.Gliders)
+	}
+	return name + ": " + outcome
+}
+
+// search prints the results selected by the query given in args, and how many of them had each outcome.
+func search(path string, args []string) error {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	var q query
+	flags.StringVar(&q.Outcome, "outcome", "", "Only results with this outcome: extinct, stable, oscillating or unsettled")
+	flags.IntVar(&q.Period, "period", 0, "Only results that settled with this period")
+	flags.IntVar(&q.MinPeriod, "minPeriod", 0, "Only results that settled with at least this period")
+	flags.IntVar(&q.MinGliders, "minGliders", 0, "Only results with at least this many gliders")
+	rule := flags.String("rule", "", "Only results of this rule, in B/S notation")
+	flags.Var(&q.Labels, "label", "Only results tagged key=value. Repeat for more")
+	sortBy := flags.String("sort", "", "Order the results by alive, gliders, period or turns, largest first, rather than as saved")
+	limit := flags.Int("limit", 0, "Print at most this many results, or 0 for all of them")
+	flags.Parse(args)
+	if *rule != "" {
+		r, err := kernel.ParseRule(*rule)
+		if err != nil {
+			return err
+		}
+		q.Rule = r.String()
+	}
+
+	saved, err := loadResults(path)
+	if err != nil {
+		return err
+	}
+	var selected []Result
+	for _, r := range saved {
+		if q.matches(r) {
+			selected = append(selected, r)
+		}
+	}
+	if err := sortResults(selected, *sortBy); err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, r := range selected {
+		counts[r.Outcome]++
+	}
+	if *limit > 0 && len(selected) > *limit {
+		selected = selected[:*limit]
+	}
+	printResults(selected)
+	fmt.Printf("%d extinct, %d stable, %d oscillating, %d unsettled\n", counts[Extinct], counts[Stable], counts[Oscillating], counts[Unsettled])
+	return nil
+}
+
+// sortResults orders results by the given field, largest first, keeping the saved order between equal ones.
+func sortResults(results []Result, by string) error {
+	var field func(r Result) int
+	switch by {
+	case "":
+		return nil
+	case "alive":
+		field = func(r Result) int { return r.Alive }
+	case "gliders":
+		field = func(r Result) int { return r.Gliders }
+	case "period":
+		field = func(r Result) int { return r.Period }
+	case "turns":
+		field = func(r Result) int { return r.Turns }
+	default:
+		return fmt.Errorf("can't sort by %q: use alive, gliders, period or turns", by)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return field(results[i]) > field(results[j]) })
+	return nil
+}
+
+// printResults prints one line per result.
+func printResults(results []Result) {
+	if len(results) == 0 {
+		fmt.Println("No results match")
+		return
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tSEED\tRULE\tEDGE\tSIZE\tOUTCOME\tPERIOD\tSETTLED\tALIVE\tGLIDERS\tLABELS")
+	for _, r := range results {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%dx%d\t%s\t%s\t%s\t%d\t%d\t%v\n", r.Name, orDash(r.Seed), r.Rule, r.Edge, r.Width,
+			r.Height, r.Outcome, orDash(int64(r.Period)), settled(r), r.Alive, r.Gliders, r.Labels)
+	}
+	table.Flush()
+}
+
+// settled returns the turn a result settled or died out at, or a dash if it was still changing.
+func settled(r Result) string {
+	if r.Outcome == Unsettled {
+		return "-"
+	}
+	return fmt.Sprint(r.SettledAt)
+}
+
+// orDash formats n, or a dash if it is 0 and so not known.
+func orDash(n int64) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/pattern"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Outcomes a pattern can be classified with, describing what is left of it apart from any gliders it sent off.
+const (
+	Extinct     = "extinct"     // Every cell died, or nothing but gliders was left.
+	Stable      = "stable"      // Settled into still lifes.
+	Oscillating = "oscillating" // Settled into oscillators repeating every Period turns.
+	Unsettled   = "unsettled"   // Still changing when the turns ran out.
+)
+
+// gliderShapes are the two shapes a glider takes, as rows for pattern.Parse. The other two phases of its period are
+// these in other orientations.
+var gliderShapes = []string{".O./..O/OOO", "O.O/.OO/.O."}
+
+// gliders are every shape of a glider in every orientation, each with a border of dead cells so only gliders
+// standing clear of everything else match.
+var gliders = func() []slab.World {
+	var all []slab.World
+	for _, shape := range gliderShapes {
+		p, _ := pattern.Parse(shape)
+		for _, o := range pattern.Orientations(p) {
+			bordered := slab.New(o.Width+2, o.Height+2)
+			for y := 0; y < o.Height; y++ {
+				copy(bordered.Row(y + 1)[1:], o.Row(y))
+			}
+			all = append(all, bordered)
+		}
+	}
+	return all
+}()
+
+// Result is what came of evolving one pattern or soup, as saved in the results file.
+type Result struct {
+	Name      string      // The pattern as given, the image it was read from, or "soup" for a random one.
+	Seed      int64       `json:",omitempty"` // Seed a soup was made from.
+	Rule      string      // Rule in B/S notation.
+	Edge      string      // What lay beyond the edges: torus or dead.
+	Width     int         // Width of the board evolved on.
+	Height    int         // Height of the board evolved on.
+	MaxTurns  int         // Turns the pattern was given to settle.
+	Turns     int         // Turns evolved before it was found to have settled or died out, or MaxTurns.
+	Outcome   string      // One of Extinct, Stable, Oscillating and Unsettled.
+	Period    int         `json:",omitempty"` // Turns the settled pattern repeats after, 1 for still lifes.
+	SettledAt int         `json:",omitempty"` // First turn of the repeating states.
+	Alive     int         // Alive cells at the last turn evolved, gliders included.
+	Initial   int         // Alive cells at the start.
+	Gliders   int         // Gliders standing clear of the rest at the last turn evolved.
+	Labels    util.Labels `json:",omitempty"`
+	Recorded  time.Time   // When the result was saved.
+}
+
+// key identifies the experiment a result came from, so run skips patterns that already have results.
+func (r Result) key() string {
+	return fmt.Sprintf("%s|%d|%s|%s|%dx%d|%d", r.Name, r.Seed, r.Rule, r.Edge, r.Width, r.Height, r.MaxTurns)
+}
+
+// soup returns a width x height world with each cell alive with the given chance, the same for the same seed.
+func soup(width, height int, density float64, seed int64) slab.World {
+	rng := rand.New(rand.NewSource(seed))
+	world := slab.New(width, height)
+	for i := range world.Cells {
+		if rng.Float64() < density {
+			world.Cells[i] = util.Alive
+		}
+	}
+	return world
+}
+
+// centre places p in the middle of a width x height world of dead cells, or returns p itself if it is no smaller.
+func centre(p slab.World, width, height int) slab.World {
+	if p.Width >= width && p.Height >= height {
+		return p
+	}
+	if width < p.Width {
+		width = p.Width
+	}
+	if height < p.Height {
+		height = p.Height
+	}
+	world := slab.New(width, height)
+	x, y := (width-p.Width)/2, (height-p.Height)/2
+	for j := 0; j < p.Height; j++ {
+		copy(world.Row(y + j)[x:], p.Row(j))
+	}
+	return world
+}
+
+// classify evolves world for up to turns turns with opts and says what became of it. Gliders standing clear of the
+// rest are counted and left out of the states compared for a repeat, so a soup that settles down and sends gliders
+// off is still found to settle. A repeat must then hold for long enough for a glider to cross the whole board, so
+// one still on its way towards debris doesn't pass for having escaped.
+func classify(world slab.World, turns int, opts kernel.Options) Result {
+	r := Result{Rule: opts.Rule.String(), Edge: opts.Edge.String(), Width: world.Width, Height: world.Height, MaxTurns: turns,
+		Outcome: Unsettled}
+	wrap := opts.Edge == kernel.Torus
+	confirm := 4 * world.Width
+	if world.Height > world.Width {
+		confirm = 4 * world.Height
+	}
+
+	r.Initial = countAlive(world)
+	alive, d := r.Initial, withoutGliders(world, wrap)
+	hashes := []uint64{hashWorld(d.world)} // Hash of each turn's world without its gliders.
+	seen := map[uint64]int{hashes[0]: 0}   // Latest turn each hash was seen at.
+	period, from := 0, 0                   // The repeat being confirmed, if period isn't 0.
+	for r.Turns < turns && alive > 0 {
+		opts.Turn = r.Turns
+		world, _ = kernel.Next(context.Background(), world, 0, world.Height, world.Height, opts)
+		r.Turns++
+		alive, d = countAlive(world), withoutGliders(world, wrap)
+		hash := hashWorld(d.world)
+		hashes = append(hashes, hash)
+		if period > 0 && hashes[r.Turns-period] != hash {
+			period = 0 // The repeat was broken, e.g. by a glider hitting the debris.
+		}
+		if at, ok := seen[hash]; ok && period == 0 {
+			period, from = r.Turns-at, at
+		}
+		seen[hash] = r.Turns
+		if period > 0 && r.Turns-from >= period+confirm {
+			r.Outcome, r.Period, r.SettledAt = Oscillating, period, from
+			if period == 1 {
+				r.Outcome = Stable
+			}
+			if countAlive(d.world) == 0 {
+				r.Outcome, r.Period = Extinct, 0 // Nothing but gliders.
+			}
+			break
+		}
+	}
+	r.Alive, r.Gliders = alive, d.gliders
+	if alive == 0 {
+		r.Outcome, r.SettledAt = Extinct, r.Turns
+	}
+	return r
+}
+
+// debris is a world with the gliders standing clear of the rest taken out, and how many there were.
+type debris struct {
+	world   slab.World
+	gliders int
+}
+
+// withoutGliders finds the gliders standing clear of everything else in world and returns a copy without them.
+func withoutGliders(world slab.World, wrap bool) debris {
+	board := pattern.Pack(world, wrap)
+	d := debris{world: world}
+	for _, g := range gliders {
+		for _, at := range board.Find(g, 0) {
+			if d.gliders == 0 {
+				d.world = world.Clone()
+			}
+			d.gliders++
+			for y := 1; y < g.Height-1; y++ {
+				for x := 1; x < g.Width-1; x++ {
+					d.world.Set((at.X+x)%world.Width, (at.Y+y)%world.Height, util.Dead)
+				}
+			}
+		}
+	}
+	return d
+}
+
+// countAlive counts the alive cells of a world.
+func countAlive(world slab.World) int {
+	alive := 0
+	for y := 0; y < world.Height; y++ {
+		for _, cell := range world.Row(y) {
+			if cell == util.Alive {
+				alive++
+			}
+		}
+	}
+	return alive
+}
+
+// hashWorld hashes every cell of a world, for spotting it repeating itself.
+func hashWorld(world slab.World) uint64 {
+	h := fnv.New64a()
+	for y := 0; y < world.Height; y++ {
+		h.Write(world.Row(y))
+	}
+	return h.Sum64()
+}
+
+// loadResults reads every result saved in the results file at path, or none if it doesn't exist yet.
+func loadResults(path string) ([]Result, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var results []Result
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r Result
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		results = append(results, r)
+	}
+	return results, scanner.Err()
+}
+
+// appendResult adds a result to the end of the results file at path, one JSON object per line, so results of
+// surveys run at the same time or stopped part way through are all kept.
+func appendResult(path string, r Result) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// query selects results, with zero values matching anything.
+type query struct {
+	Outcome    string
+	Period     int
+	MinPeriod  int
+	MinGliders int
+	Rule       string // In the form Rule.String gives.
+	Labels     util.Labels
+}
+
+// matches reports whether r is selected by q.
+func (q query) matches(r Result) bool {
+	if q.Outcome != "" && q.Outcome != r.Outcome || q.Period > 0 && q.Period != r.Period || r.Period < q.MinPeriod ||
+		r.Gliders < q.MinGliders {
+		return false
+	}
+	if q.Rule != "" && q.Rule != r.Rule {
+		return false
+	}
+	for key, value := range q.Labels {
+		if r.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
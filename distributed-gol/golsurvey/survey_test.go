@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/pattern"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestClassify checks small patterns whose fate is known are classified as they should be, with a glider counted
+// and left out of what the pattern settled into.
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		pattern string
+		edge    kernel.Edge
+		outcome string
+		period  int
+		alive   int
+		gliders int
+	}{
+		{"O", kernel.Torus, Extinct, 0, 0, 0},
+		{"OO/OO", kernel.Torus, Stable, 1, 4, 0},
+		{"OOO", kernel.Torus, Oscillating, 2, 3, 0},
+		{".O./..O/OOO", kernel.Torus, Extinct, 0, 5, 1},
+		{".O./..O/OOO", kernel.DeadEdge, Stable, 1, 4, 0}, // Stopped by the edge as a block.
+		{"OOO/.O./OOO/.../OOO", kernel.Torus, Unsettled, 0, 0, 0},
+	}
+	for _, test := range tests {
+		p, err := pattern.Parse(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		turns := 1000
+		if test.outcome == Unsettled {
+			turns = 5
+		}
+		opts := kernel.Defaults
+		opts.Edge = test.edge
+		r := classify(centre(p, 16, 16), turns, opts)
+		if r.Outcome != test.outcome || r.Period != test.period || r.Gliders != test.gliders ||
+			test.outcome != Unsettled && r.Alive != test.alive {
+			t.Errorf("%s on a %v board: got %s with period %d, %d alive and %d gliders, expected %s with period %d, %d alive and %d gliders",
+				test.pattern, test.edge, r.Outcome, r.Period, r.Alive, r.Gliders, test.outcome, test.period, test.alive, test.gliders)
+		}
+	}
+}
+
+// TestResults checks results saved one at a time are all read back, and queries select the right ones.
+func TestResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "survey.jsonl")
+	if results, err := loadResults(path); err != nil || len(results) != 0 {
+		t.Fatalf("got %d results and error %v before any were saved, expected none", len(results), err)
+	}
+	saved := []Result{
+		{Name: "soup", Seed: 1, Rule: "B3/S23", Outcome: Oscillating, Period: 2, Gliders: 3, Labels: util.Labels{"experiment": "soups"}},
+		{Name: "soup", Seed: 2, Rule: "B3/S23", Outcome: Stable, Period: 1},
+		{Name: "OOO", Rule: "B36/S23", Outcome: Oscillating, Period: 2},
+	}
+	for _, r := range saved {
+		if err := appendResult(path, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results, err := loadResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(saved) || results[0].key() != saved[0].key() || results[0].Labels["experiment"] != "soups" {
+		t.Fatalf("read back %+v, expected %+v", results, saved)
+	}
+
+	tests := []struct {
+		q       query
+		matches []bool
+	}{
+		{query{}, []bool{true, true, true}},
+		{query{Outcome: Oscillating}, []bool{true, false, true}},
+		{query{MinPeriod: 2, Rule: "B3/S23"}, []bool{true, false, false}},
+		{query{MinGliders: 1}, []bool{true, false, false}},
+		{query{Labels: util.Labels{"experiment": "soups"}}, []bool{true, false, false}},
+	}
+	for _, test := range tests {
+		for i, r := range results {
+			if got := test.q.matches(r); got != test.matches[i] {
+				t.Errorf("query %+v matches result %d: got %v, expected %v", test.q, i, got, test.matches[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,360 @@
+// Package golworker is a worker, which computes the slices of each turn the broker hands it. The worker command
+// runs one on its own, and Serve runs one alongside the broker and client in one process.
+package golworker
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/rpc"
+	"os"
+	"sync"
+	"time"
+	"uk.ac.bris.cs/gameoflife/affinity"
+	"uk.ac.bris.cs/gameoflife/chaos"
+	"uk.ac.bris.cs/gameoflife/health"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/selftest"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/storage"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/tracing"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Global kill channel used to signal the worker to quit.
+var kill = make(chan bool)
+
+// chunkCandidates are the chunk sizes, in rows per goroutine, tried by calibration.
+var chunkCandidates = []int{1, 2, 4, 8, 16, 32, 64}
+
+// calibrationRows is the height of the random board chunk sizes are timed on.
+const calibrationRows = 256
+
+// WorldOps struct provides methods for calculating the next state of the world
+// and for handling termination of the worker process.
+type WorldOps struct {
+	Chunk      int              // Rows per goroutine, or 0 to calibrate the fastest chunk size for each board width.
+	Algorithm  kernel.Algorithm // How neighbours are counted, from -kernel.
+	Faults     *chaos.Faults    // Faults injected with -chaos, or nil for none.
+	CPUs       affinity.CPUs    // CPUs the goroutines computing slices are pinned to, from -cpus, or nil for none.
+	chunkSizes map[int]int      // Calibrated chunk size for each board width seen so far.
+	mu         sync.Mutex       // Mutex protecting chunkSizes.
+	jobs       map[int]*job
+	jobMu      sync.Mutex     // Mutex protecting jobs, separate from mu so calibrating doesn't hold up cancelling.
+	latest     stubs.WorldRes // The last slice computed, kept so it can be saved without sending it to the broker.
+	latestMu   sync.Mutex     // Mutex protecting latest.
+}
+
+// job is a run of the broker's with slices being computed by this worker.
+type job struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	calls  int // Number of its slices being computed; the job is forgotten when this drops to 0.
+}
+
+// CalculateWorld processes a slice of the world assigned to this worker and computes its next state.
+// Only the specified rows (from startRow to endRow) are updated, and the rest remain unchanged.
+func (w *WorldOps) CalculateWorld(req *stubs.WorldReq, res *stubs.WorldRes) (err error) {
+	opts, err := kernel.ParseOptions(req.Rule, req.Edge)
+	if err != nil {
+		return
+	}
+	opts.Algorithm, opts.CPUs = w.Algorithm, w.CPUs
+	opts.Seed, opts.Turn = req.Seed, req.Turn
+	if w.Faults.Crash() {
+		// As abrupt as a real crash: nothing is flushed, and the broker finds the connection gone.
+		fmt.Fprintf(logs, "Crashing on turn %d rows %d-%d, as -chaos decided\n", req.Turn+1, req.StartRow, req.EndRow)
+		os.Exit(2)
+	}
+	ctx, done := w.start(req.Job)
+	defer done()
+	ctx, span := tracing.StartServer(ctx, "CalculateWorld", req.Trace)
+	defer span.End()
+	span.SetInt("turn", req.Turn+1)
+	span.SetInt("rows", req.EndRow-req.StartRow)
+	defer func() { span.Fail(err) }()
+	// Compute the next state for the assigned rows and return the result.
+	res.World, err = kernel.Next(ctx, req.World, req.StartRow, req.EndRow, w.chunkSize(req.World.Width), opts)
+	res.Job, res.Turn, res.StartRow, res.EndRow = req.Job, req.Turn, req.StartRow, req.EndRow
+	res.Seal()
+	if err == nil && req.Job != stubs.SelfTestJob {
+		w.latestMu.Lock()
+		w.latest = *res
+		w.latestMu.Unlock()
+	}
+	return
+}
+
+// SaveSlice saves the last slice computed as a PGM image, if it is the one the broker asks for, so a snapshot
+// of a huge world is written by every worker at once instead of being gathered on one machine.
+func (w *WorldOps) SaveSlice(req *stubs.SaveSliceReq, res *stubs.Empty) (err error) {
+	w.latestMu.Lock()
+	latest := w.latest
+	w.latestMu.Unlock()
+	if latest.World.Cells == nil || latest.Job != req.Job || latest.Turn != req.Turn || latest.StartRow != req.StartRow || latest.EndRow != req.EndRow {
+		return fmt.Errorf("the last slice computed is rows %d-%d of turn %d of run %d, not rows %d-%d of turn %d of run %d",
+			latest.StartRow, latest.EndRow, latest.Turn, latest.Job, req.StartRow, req.EndRow, req.Turn, req.Job)
+	}
+	return storage.Put(req.Path, util.FormatPgm(latest.World.Rows()))
+}
+
+// Cancel stops the slices of a run being computed, because the broker has given up on it, e.g. when the
+// client quits mid-turn. They return an error, which the broker has stopped waiting for.
+func (w *WorldOps) Cancel(req *stubs.CancelReq, res *stubs.Empty) (err error) {
+	w.jobMu.Lock()
+	defer w.jobMu.Unlock()
+	if j, ok := w.jobs[req.Job]; ok {
+		j.cancel()
+	}
+	return
+}
+
+// start returns the context a slice of the given run is computed under, and a function to call once it is done.
+func (w *WorldOps) start(id int) (context.Context, func()) {
+	w.jobMu.Lock()
+	defer w.jobMu.Unlock()
+	if w.jobs == nil {
+		w.jobs = make(map[int]*job)
+	}
+	j, ok := w.jobs[id]
+	if !ok {
+		j = &job{}
+		j.ctx, j.cancel = context.WithCancel(context.Background())
+		w.jobs[id] = j
+	}
+	j.calls++
+	return j.ctx, func() {
+		w.jobMu.Lock()
+		defer w.jobMu.Unlock()
+		if j.calls--; j.calls == 0 {
+			j.cancel()
+			delete(w.jobs, id)
+		}
+	}
+}
+
+// chunkSize returns the number of rows each goroutine should process for boards of the given width,
+// calibrating it the first time the width is seen unless a size was given with -chunk.
+func (w *WorldOps) chunkSize(width int) int {
+	if w.Chunk > 0 {
+		return w.Chunk
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.chunkSizes == nil {
+		w.chunkSizes = make(map[int]int)
+	}
+	if size, ok := w.chunkSizes[width]; ok {
+		return size
+	}
+	size := calibrate(width, kernel.Options{Rule: kernel.Life, Algorithm: w.Algorithm, CPUs: w.CPUs})
+	w.chunkSizes[width] = size
+	return size
+}
+
+// calibrate times every candidate chunk size on a random board of the given width, computed with opts, and returns
+// the fastest. Each candidate gets several runs and keeps its best time, so a single slow run doesn't rule it out.
+func calibrate(width int, opts kernel.Options) int {
+	world := slab.New(width, calibrationRows)
+	for i := range world.Cells {
+		if rand.Intn(4) == 0 {
+			world.Cells[i] = util.Alive
+		}
+	}
+
+	best, bestTime := chunkCandidates[0], time.Duration(-1)
+	for _, size := range chunkCandidates {
+		for run := 0; run < 3; run++ {
+			start := time.Now()
+			kernel.Next(context.Background(), world, 0, calibrationRows, size, opts)
+			if elapsed := time.Since(start); bestTime < 0 || elapsed < bestTime {
+				best, bestTime = size, elapsed
+			}
+		}
+	}
+	fmt.Fprintf(logs, "Calibrated chunk size %d for width %d\n", best, width)
+	return best
+}
+
+// evolve evolves a whole world through CalculateWorld, as the broker's calls would, for the self-test.
+func (w *WorldOps) evolve(rows [][]byte, turns int) ([][]byte, error) {
+	world := slab.FromRows(rows)
+	for turn := 0; turn < turns; turn++ {
+		res := &stubs.WorldRes{}
+		req := &stubs.WorldReq{World: world, Width: world.Width, Height: world.Height, StartRow: 0, EndRow: world.Height, Job: stubs.SelfTestJob, Turn: turn}
+		if err := w.CalculateWorld(req, res); err != nil {
+			return nil, err
+		}
+		world = res.World
+	}
+	return world.Rows(), nil
+}
+
+// KillWorker function sends a signal to the kill channel to terminate the worker process.
+func (w *WorldOps) KillWorker(req *stubs.Empty, res *stubs.Empty) (err error) {
+	kill <- true // Send a true signal to the kill channel.
+	return
+}
+
+// Main runs a worker from the command line arguments args, serving slices to the broker until it is killed.
+// command is what it was started as, e.g. worker or serve-worker, for its usage message. It is the whole of
+// go run ./worker, and of the serve-worker subcommand.
+func Main(command string, args []string) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	// Define a command-line flag for specifying the port number.
+	pAddr := flags.String("port", "8040", "Port to listen on")
+	bind := flags.String("bind", "", "Address of the interface to listen on, e.g. 10.0.0.7, or empty for all interfaces")
+	advertise := flags.String("advertise", "", "Register with the broker at -brokerAddr as reachable at this address, e.g. 10.0.0.7:8040, for a broker that doesn't scan this host's ports")
+	chunk := flags.Int("chunk", 0, "Rows per goroutine, or 0 to calibrate the fastest size for each board width")
+	algorithm := flags.String("kernel", "auto", "How neighbours are counted: bytes, bitsliced to pack rows into words and count 64 cells at a time, wide to pack them eight cells at a time as well, or auto for wide on arm64 and bytes elsewhere")
+	calibrateWidth := flags.Int("calibrateWidth", 512, "Board width to calibrate the chunk size for on startup, or 0 to wait for the first request")
+	brokerAddr := flags.String("brokerAddr", "", "Send log lines to the broker at this address, e.g. 10.0.0.5:8030, as well as printing them")
+	heapWarn := flags.Uint64("heapWarn", 2048, "Log a warning when the heap grows past this many MiB, or 0 for no warnings")
+	heapLimit := flags.Uint64("heapLimit", 0, "Restart the worker when the heap stays past this many MiB after garbage collection, or 0 for no limit")
+	watchdog := flags.Duration("watchdog", 10*time.Second, "How often the heap is sampled for -heapWarn and -heapLimit")
+	otlp := flags.String("otlp", "", "Send OpenTelemetry spans of every slice computed to this OTLP/HTTP collector, e.g. http://localhost:4318")
+	selfTest := flags.Bool("selftest", false, "Check this worker computes slices correctly, printing PASS or FAIL for each check, and exit")
+	healthAddr := flags.String("health", "", "Serve /healthz and /readyz on this address, e.g. :8083, for orchestrators and scripts to wait for the worker to be ready before starting the broker")
+	chaosSpec := flags.String("chaos", "", "Inject faults into the connections from the broker and crash now and then, decided by a seed so they recur run after run, e.g. seed=7,latency=20ms,drop=0.01,crash=0.001")
+	cpus := flags.String("cpus", "", "Pin the goroutines computing each slice to these CPUs in turn, e.g. 0-7 or 0,2,4,6, to keep each chunk on one core of a NUMA machine, or empty to leave them to the scheduler. Linux only")
+	config := flags.String("config", "", "Read any flags not given on the command line from a YAML or TOML config file, using its top-level and [worker] settings")
+	flags.Parse(args) // Parse the flag input from the terminal.
+
+	// Fill in anything not given on the command line from the config file.
+	if *config != "" {
+		if err := util.ApplyConfig(flags, *config, "worker"); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	kernelAlgorithm, err := kernel.ParseAlgorithm(*algorithm)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if *advertise != "" && *brokerAddr == "" {
+		fmt.Println("-advertise needs -brokerAddr, the broker to register with")
+		os.Exit(1)
+	}
+	var faults *chaos.Faults
+	if *chaosSpec != "" {
+		config, err := chaos.Parse(*chaosSpec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		faults = chaos.New(config, "worker "+*pAddr)
+	}
+	pinned, err := affinity.Parse(*cpus)
+	if err == nil {
+		err = pinned.Check()
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Check the slices this machine computes before serving any, exiting with status 1 if any are wrong.
+	if *selfTest {
+		ops := &WorldOps{Chunk: *chunk, Algorithm: kernelAlgorithm, CPUs: pinned}
+		if !selftest.Report(os.Stdout, fmt.Sprintf("worker (%v kernel)", kernelAlgorithm), selftest.Run(ops.evolve)) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *otlp != "" {
+		host, _ := os.Hostname()
+		tracing.Enable(*otlp, "gol-worker", host+":"+*pAddr)
+	}
+
+	// Forward log lines to the broker, tagged with this worker's host and port.
+	forwarder := &logForwarder{}
+	name := *pAddr
+	if *brokerAddr != "" {
+		if host, err := os.Hostname(); err == nil {
+			name = host + ":" + *pAddr
+		}
+		logs = io.MultiWriter(os.Stdout, forwarder)
+		go forwarder.run(*brokerAddr, name)
+	}
+
+	// Answer health checks from now on, so an orchestrator sees the worker alive but not ready while it calibrates.
+	var listening health.Flag
+	if *healthAddr != "" {
+		checks := []health.Check{listening.Check("listener", "not yet accepting RPC connections, e.g. still calibrating")}
+		if *brokerAddr != "" {
+			checks = append(checks, health.Check{Name: "broker", Run: forwarder.connected})
+		}
+		go health.Serve(*healthAddr, checks, logs)
+	}
+
+	// Watch the heap, logging through the forwarder so the warnings reach the broker's log too. A worker keeps
+	// nothing between turns, so past the limit it simply restarts; the broker computes its slices itself once the
+	// connection drops.
+	heapWatchdog := &util.HeapWatchdog{Interval: *watchdog, Warn: *heapWarn * util.MiB, Limit: *heapLimit * util.MiB, Log: logs}
+	heapWatchdog.OnLimit = func(heap uint64) {
+		fmt.Fprintln(logs, "Restarting")
+		if *brokerAddr != "" {
+			forwarder.flush(*brokerAddr, name) // Send the last lines before restarting.
+		}
+		if err := util.Restart(); err != nil {
+			fmt.Fprintln(logs, "Error restarting:", err)
+		}
+	}
+	go heapWatchdog.Run()
+
+	// Initialise the WorldOps struct and register its methods for RPC.
+	ops := &WorldOps{Chunk: *chunk, Algorithm: kernelAlgorithm, Faults: faults, CPUs: pinned}
+	rpc.Register(ops)
+
+	// Calibrate for the most likely board width now, so the first turn isn't slowed down by it.
+	if *chunk <= 0 && *calibrateWidth > 0 {
+		ops.chunkSize(*calibrateWidth)
+	}
+
+	// Goroutine that listens for a kill signal and terminates the worker process.
+	go func() {
+		for { // Infinite loop to continuously check for kill signals.
+			if <-kill { // If a true signal is received, terminate the process.
+				fmt.Fprintln(logs, "Killed by the broker")
+				tracing.Flush()
+				if *brokerAddr != "" {
+					forwarder.flush(*brokerAddr, name) // Send the last lines before exiting.
+				}
+				os.Exit(1)
+			}
+		}
+	}()
+
+	// Set up a TCP listener to accept RPC connections.
+	listener, err := net.Listen("tcp", net.JoinHostPort(*bind, *pAddr))
+	if err != nil { // Handle errors when starting the listener.
+		fmt.Fprintln(logs, "Error starting listener:", err)
+		return
+	}
+	defer listener.Close() // Ensure the listener is closed when the program exits.
+	listening.Set()
+
+	fmt.Fprintln(logs, "Listening on", listener.Addr())
+
+	// Tell the broker where to find this worker, now that it can be reached there.
+	if *advertise != "" {
+		go keepRegistered(*brokerAddr, *advertise)
+	}
+
+	if faults != nil {
+		fmt.Fprintln(logs, "Injecting faults:", *chaosSpec)
+	}
+	if pinned != nil {
+		fmt.Fprintln(logs, "Pinning goroutines to CPUs", pinned)
+	}
+
+	// Accept incoming RPC connections and process them.
+	rpc.Accept(faults.Listen(listener))
+}
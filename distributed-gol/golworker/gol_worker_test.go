@@ -0,0 +1,86 @@
+package golworker
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// TestChunkSizesAgree checks every candidate chunk size computes the same next state, including for slices
+// whose height isn't a multiple of the chunk size.
+func TestChunkSizesAgree(t *testing.T) {
+	world := make([][]byte, 37)
+	for i := range world {
+		world[i] = make([]byte, 23)
+		for j := range world[i] {
+			if (i*7+j*13)%5 == 0 {
+				world[i][j] = 255
+			}
+		}
+	}
+
+	expected := kernel.NextState(world, 23, 37, 3, 34, 1)
+	for _, size := range chunkCandidates {
+		got := kernel.NextState(world, 23, 37, 3, 34, size)
+		for i := range expected {
+			if !bytes.Equal(got[i], expected[i]) {
+				t.Fatalf("chunk size %d computed row %d differently", size, i+3)
+			}
+		}
+	}
+}
+
+// TestChunkSizeCalibration checks a fixed -chunk is used as given and calibration picks one of the candidates once per width.
+func TestChunkSizeCalibration(t *testing.T) {
+	if size := (&WorldOps{Chunk: 7}).chunkSize(64); size != 7 {
+		t.Errorf("fixed chunk size 7 was replaced by %d", size)
+	}
+
+	ops := &WorldOps{}
+	size := ops.chunkSize(64)
+	found := false
+	for _, candidate := range chunkCandidates {
+		found = found || candidate == size
+	}
+	if !found {
+		t.Errorf("calibration picked %d, which isn't a candidate", size)
+	}
+	if len(ops.chunkSizes) != 1 || ops.chunkSize(64) != size {
+		t.Error("calibration wasn't cached for the board width")
+	}
+}
+
+// TestCancel checks cancelling a run stops only its slices, and a run is forgotten once its slices are done.
+func TestCancel(t *testing.T) {
+	ops := &WorldOps{}
+	cancelled, doneCancelled := ops.start(1)
+	other, doneOther := ops.start(2)
+	if err := ops.Cancel(&stubs.CancelReq{Job: 1}, &stubs.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Err() == nil {
+		t.Error("the cancelled run's slice carried on")
+	}
+	if other.Err() != nil {
+		t.Error("cancelling one run stopped another")
+	}
+
+	doneCancelled()
+	doneOther()
+	if len(ops.jobs) != 0 {
+		t.Errorf("%d runs are still remembered after all their slices were done", len(ops.jobs))
+	}
+
+	// A cancelled slice returns an error rather than rows the broker would never read.
+	ctx, done := ops.start(3)
+	ops.Cancel(&stubs.CancelReq{Job: 3}, &stubs.Empty{})
+	req := &stubs.WorldReq{World: slab.New(8, 8), Width: 8, Height: 8, StartRow: 0, EndRow: 8, Job: 3}
+	ops.Chunk = 2
+	if err := ops.CalculateWorld(req, &stubs.WorldRes{}); err != ctx.Err() || err == nil {
+		t.Errorf("got %v from a cancelled slice, expected %v", err, context.Canceled)
+	}
+	done()
+}
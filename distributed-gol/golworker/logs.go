@@ -0,0 +1,122 @@
+package golworker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// logs is where the worker writes its log lines. With -brokerAddr they are also forwarded to the broker.
+var logs io.Writer = os.Stdout
+
+// logInterval is how often forwarded log lines are sent to the broker.
+const logInterval = 500 * time.Millisecond
+
+// maxPendingLines bounds the lines kept while the broker can't be reached. The oldest are dropped first.
+const maxPendingLines = 10000
+
+// logForwarder collects the worker's log lines and sends them to the broker in batches, so the logs of every
+// worker end up in one file on the broker rather than on each worker's machine.
+type logForwarder struct {
+	mu      sync.Mutex
+	pending []stubs.LogLine // Complete lines waiting to be sent.
+	partial string          // Start of a line that hasn't been ended yet.
+	dropped int             // Lines dropped since the last batch because the broker couldn't keep up.
+	sending sync.Mutex      // Held while a batch is sent, so only one flush uses client at a time.
+	client  *rpc.Client     // Connection to the broker, or nil until the next attempt to connect.
+	reached int32           // 1 if the latest batch reached the broker, 0 if it didn't or none has been sent. Atomic.
+}
+
+// Write splits p into lines and queues them. It never fails, so it can't hold up the worker's own logging.
+func (f *logForwarder) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lines := strings.Split(f.partial+string(p), "\n")
+	f.partial = lines[len(lines)-1]
+	now := time.Now()
+	for _, line := range lines[:len(lines)-1] {
+		f.pending = append(f.pending, stubs.LogLine{Time: now, Text: line})
+	}
+	if excess := len(f.pending) - maxPendingLines; excess > 0 {
+		f.pending = append([]stubs.LogLine{}, f.pending[excess:]...)
+		f.dropped += excess
+	}
+	return len(p), nil
+}
+
+// take removes and returns the queued lines, noting any that were dropped.
+func (f *logForwarder) take() []stubs.LogLine {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lines := f.pending
+	if f.dropped > 0 {
+		note := stubs.LogLine{Time: time.Now(), Text: fmt.Sprintf("(%d log lines dropped)", f.dropped)}
+		lines = append([]stubs.LogLine{note}, lines...)
+		f.dropped = 0
+	}
+	f.pending = nil
+	return lines
+}
+
+// requeue puts lines that couldn't be sent back at the front of the queue, to be tried again.
+func (f *logForwarder) requeue(lines []stubs.LogLine) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending = append(lines, f.pending...)
+	if excess := len(f.pending) - maxPendingLines; excess > 0 {
+		f.pending = f.pending[excess:]
+		f.dropped += excess
+	}
+}
+
+// flush sends the queued lines to the broker at the given address, connecting first if necessary.
+// Lines are kept for the next flush if the broker can't be reached.
+func (f *logForwarder) flush(address, name string) {
+	f.sending.Lock()
+	defer f.sending.Unlock()
+	lines := f.take()
+	if len(lines) == 0 {
+		return
+	}
+	if f.client == nil {
+		client, err := rpc.Dial("tcp", address)
+		if err != nil {
+			atomic.StoreInt32(&f.reached, 0)
+			f.requeue(lines)
+			return
+		}
+		f.client = client
+	}
+	req := stubs.LogRequest{Worker: name, Lines: lines}
+	if err := f.client.Call(stubs.LogHandler, req, &stubs.Empty{}); err != nil {
+		// Reconnect next time, in case the broker was restarted.
+		f.client.Close()
+		f.client = nil
+		atomic.StoreInt32(&f.reached, 0)
+		f.requeue(lines)
+		return
+	}
+	atomic.StoreInt32(&f.reached, 1)
+}
+
+// connected returns nil if the latest batch of log lines reached the broker, for the worker's readiness check.
+func (f *logForwarder) connected() error {
+	if atomic.LoadInt32(&f.reached) == 0 {
+		return errors.New("log lines aren't reaching the broker at -brokerAddr")
+	}
+	return nil
+}
+
+// run flushes the queued lines every logInterval. It never returns.
+func (f *logForwarder) run(address, name string) {
+	for range time.Tick(logInterval) {
+		f.flush(address, name)
+	}
+}
@@ -0,0 +1,56 @@
+package golworker
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"testing"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// receivingBroker stands in for the broker's Log RPC and keeps the lines it receives.
+type receivingBroker struct {
+	lines []string
+}
+
+func (b *receivingBroker) Log(req stubs.LogRequest, res *stubs.Empty) (err error) {
+	for _, line := range req.Lines {
+		b.lines = append(b.lines, req.Worker+" "+line.Text)
+	}
+	return
+}
+
+// TestLogForwarding checks log lines reach the broker whole and in order, and are kept while it can't be reached.
+func TestLogForwarding(t *testing.T) {
+	broker := &receivingBroker{}
+	server := rpc.NewServer()
+	if err := server.RegisterName("Broker", broker); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go server.Accept(listener)
+
+	f := &logForwarder{}
+	fmt.Fprint(f, "first\nsec")
+	fmt.Fprintln(f, "ond")
+	fmt.Fprint(f, "unfinished")
+
+	// Nothing is listening on port 1, so the lines have to wait.
+	f.flush("127.0.0.1:1", "worker:8040")
+	if f.connected() == nil {
+		t.Error("reported the broker reachable before any lines reached it")
+	}
+	f.flush(listener.Addr().String(), "worker:8040")
+	if err := f.connected(); err != nil {
+		t.Errorf("reported the broker unreachable after lines reached it: %v", err)
+	}
+
+	expected := []string{"worker:8040 first", "worker:8040 second"}
+	if fmt.Sprint(broker.lines) != fmt.Sprint(expected) {
+		t.Errorf("broker received %q, expected %q", broker.lines, expected)
+	}
+}
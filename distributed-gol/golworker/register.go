@@ -0,0 +1,40 @@
+package golworker
+
+import (
+	"fmt"
+	"net/rpc"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// registerInterval is how often a worker started with -advertise registers with the broker again. Registering an
+// address the broker already has connected does nothing, so this only matters once the broker has restarted.
+const registerInterval = 10 * time.Second
+
+// register asks the broker at brokerAddr to split turns with this worker, reachable at advertise.
+func register(brokerAddr, advertise string) error {
+	client, err := rpc.Dial("tcp", brokerAddr)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Call(stubs.RegisterWorkerHandler, stubs.RegisterWorkerRequest{Address: advertise}, &stubs.Empty{})
+}
+
+// keepRegistered registers with the broker every registerInterval, logging only when that starts or stops working,
+// so a broker started after the worker, or restarted, still finds it. It never returns.
+func keepRegistered(brokerAddr, advertise string) {
+	registered, first := false, true
+	for {
+		err := register(brokerAddr, advertise)
+		switch {
+		case err == nil && !registered:
+			fmt.Fprintf(logs, "Registered with the broker at %s as %s\n", brokerAddr, advertise)
+		case err != nil && (registered || first):
+			fmt.Fprintf(logs, "Couldn't register with the broker at %s, trying again every %v: %v\n", brokerAddr, registerInterval, err)
+		}
+		registered, first = err == nil, false
+		time.Sleep(registerInterval)
+	}
+}
@@ -0,0 +1,29 @@
+package golworker
+
+import (
+	"net"
+	"net/rpc"
+)
+
+// Serve runs a worker with the defaults of go run ./worker on l, until l is closed. Unlike Main it registers the
+// worker with an RPC server of its own and leaves the process alone when killed, just closing l, so several workers
+// can share the process with the broker and the client, e.g. on memnet listeners.
+func Serve(l net.Listener) error {
+	server := rpc.NewServer()
+	if err := server.Register(&WorldOps{}); err != nil {
+		return err
+	}
+	// Each kill the broker sends closes one worker's listener, so killing every worker closes them all.
+	go func() {
+		<-kill
+		l.Close()
+	}()
+	// Accepting by hand rather than with server.Accept, which logs the error a closed listener gives.
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return nil
+		}
+		go server.ServeConn(conn)
+	}
+}
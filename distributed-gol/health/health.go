@@ -0,0 +1,72 @@
+// Package health serves the /healthz and /readyz endpoints the broker and workers answer on -health, so
+// orchestrators and scripts can wait for each part of the system to be able to take work before starting the next.
+package health
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// Check is one condition a node must meet to be ready. Run returns nil if it is met, or why it isn't.
+type Check struct {
+	Name string
+	Run  func() error
+}
+
+// Flag is a condition that becomes true once and stays true, such as the listener being up. It is safe to set
+// from one goroutine while checks read it from others.
+type Flag struct {
+	set int32
+}
+
+// Set marks the condition as met.
+func (f *Flag) Set() {
+	atomic.StoreInt32(&f.set, 1)
+}
+
+// Check returns a check that fails with the given reason until the flag is set.
+func (f *Flag) Check(name, reason string) Check {
+	return Check{Name: name, Run: func() error {
+		if atomic.LoadInt32(&f.set) == 0 {
+			return errors.New(reason)
+		}
+		return nil
+	}}
+}
+
+// Handler answers /healthz with 200 for as long as the process can serve it at all, and /readyz with 200 only if
+// every check passes and 503 otherwise, listing each check on a line of its own, "ok <name>" or
+// "failed <name>: <reason>", so a script can show why a node isn't ready yet.
+func Handler(checks []Check) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		status, lines := http.StatusOK, ""
+		for _, c := range checks {
+			if err := c.Run(); err != nil {
+				status = http.StatusServiceUnavailable
+				lines += fmt.Sprintf("failed %s: %v\n", c.Name, err)
+			} else {
+				lines += fmt.Sprintf("ok %s\n", c.Name)
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		fmt.Fprint(w, lines)
+	})
+	return mux
+}
+
+// Serve serves Handler on the given address, e.g. ":8082", logging to log why if it can't. It only returns then.
+func Serve(address string, checks []Check, log io.Writer) {
+	fmt.Fprintf(log, "Health checks on http://%s/readyz\n", address)
+	if err := http.ListenAndServe(address, Handler(checks)); err != nil {
+		fmt.Fprintln(log, "Error serving health checks:", err)
+	}
+}
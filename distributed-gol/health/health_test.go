@@ -0,0 +1,50 @@
+package health
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// get fetches a path from handler, returning the status and body.
+func get(t *testing.T, handler http.Handler, path string) (int, string) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+	body, err := ioutil.ReadAll(recorder.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recorder.Code, string(body)
+}
+
+// TestReadiness checks /readyz fails, listing why, until every check passes, while /healthz passes throughout.
+func TestReadiness(t *testing.T) {
+	var listening Flag
+	workers := errors.New("none connected")
+	handler := Handler([]Check{
+		listening.Check("listener", "not listening"),
+		{Name: "workers", Run: func() error { return workers }},
+	})
+
+	if status, body := get(t, handler, "/healthz"); status != http.StatusOK || body != "ok\n" {
+		t.Errorf("got /healthz %d %q, expected 200 \"ok\\n\"", status, body)
+	}
+	expected := "failed listener: not listening\nfailed workers: none connected\n"
+	if status, body := get(t, handler, "/readyz"); status != http.StatusServiceUnavailable || body != expected {
+		t.Errorf("got /readyz %d %q, expected 503 %q", status, body, expected)
+	}
+
+	listening.Set()
+	expected = "ok listener\nfailed workers: none connected\n"
+	if status, body := get(t, handler, "/readyz"); status != http.StatusServiceUnavailable || body != expected {
+		t.Errorf("got /readyz %d %q, expected 503 %q", status, body, expected)
+	}
+
+	workers = nil
+	expected = "ok listener\nok workers\n"
+	if status, body := get(t, handler, "/readyz"); status != http.StatusOK || body != expected {
+		t.Errorf("got /readyz %d %q, expected 200 %q", status, body, expected)
+	}
+}
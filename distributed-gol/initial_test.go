@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestInitialBoard checks the client sends the starting world as one InitialBoard event, before anything it
+// fetches from the broker, instead of a CellFlipped for each alive cell.
+func TestInitialBoard(t *testing.T) {
+	broker := &hangingBroker{release: make(chan struct{})}
+	defer close(broker.release)
+	server := rpc.NewServer()
+	if err := server.RegisterName("Broker", broker); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go server.Accept(listener)
+	defer func(address string) { gol.BrokerAddress = address }(gol.BrokerAddress)
+	gol.BrokerAddress = listener.Addr().String()
+
+	out, err := ioutil.TempDir("", "initial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Threads: 1, Turns: 10, OutDir: out, RPCTimeout: 50 * time.Millisecond, InitialBoard: true}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+
+	initial := 0
+	timeout := time.After(5 * time.Second)
+	for open := true; open; {
+		select {
+		case event, ok := <-events:
+			switch e := event.(type) {
+			case gol.InitialBoard:
+				initial++
+				assertEqualBoard(t, e.Alive, readAliveCells("check/images/16x16x0.pgm", 16, 16), p)
+			case gol.CellFlipped:
+				t.Fatalf("CellFlipped for %v sent, expected the starting world in InitialBoard", e.Cell)
+			case gol.TurnComplete:
+				if initial == 0 {
+					t.Fatalf("TurnComplete for turn %d sent before InitialBoard", e.CompletedTurns)
+				}
+			}
+			open = ok
+		case <-timeout:
+			t.Fatal("the run didn't finish")
+		}
+	}
+	if initial != 1 {
+		t.Errorf("InitialBoard sent %d times, expected once", initial)
+	}
+}
@@ -0,0 +1,135 @@
+package kernel
+
+import (
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// wordBits is the number of cells packed into each word of a row.
+const wordBits = 64
+
+// packRow sets bit j%64 of word j/64 of dst for each alive cell j of row. dst must be zero.
+func packRow(dst []uint64, row []byte) {
+	for j, cell := range row {
+		if cell == util.Alive {
+			dst[j/wordBits] |= 1 << uint(j%wordBits)
+		}
+	}
+}
+
+// unpackRow writes the cells packed in src out to row, one byte each.
+func unpackRow(row []byte, src []uint64) {
+	for j := range row {
+		if src[j/wordBits]>>uint(j%wordBits)&1 != 0 {
+			row[j] = util.Alive
+		} else {
+			row[j] = util.Dead
+		}
+	}
+}
+
+// westWord returns word k of the packed row shifted one cell east, so each bit holds its western neighbour.
+func westWord(r []uint64, k, width int, torus bool) uint64 {
+	w := r[k] << 1
+	if k > 0 {
+		w |= r[k-1] >> (wordBits - 1)
+	} else if torus {
+		w |= r[(width-1)/wordBits] >> uint((width-1)%wordBits) & 1
+	}
+	return w
+}
+
+// eastWord returns word k of the packed row shifted one cell west, so each bit holds its eastern neighbour.
+// Bits beyond the width of the row must be zero.
+func eastWord(r []uint64, k, width int, torus bool) uint64 {
+	e := r[k] >> 1
+	if k+1 < len(r) {
+		e |= r[k+1] << (wordBits - 1)
+	} else if torus {
+		e |= (r[0] & 1) << uint((width-1)%wordBits)
+	}
+	return e
+}
+
+// fullAdd adds three one-bit numbers in each of 64 lanes at once, returning the sum and carry bits.
+func fullAdd(a, b, c uint64) (sum, carry uint64) {
+	ab := a ^ b
+	return ab ^ c, a&b | ab&c
+}
+
+// countIs returns a mask of the lanes whose neighbour count, held in binary across s0 (1s) to s3 (8s), is n.
+func countIs(n int, s0, s1, s2, s3 uint64) uint64 {
+	mask := ^uint64(0)
+	for bit, s := range [4]uint64{s0, s1, s2, s3} {
+		if n>>uint(bit)&1 == 0 {
+			s = ^s
+		}
+		mask &= s
+	}
+	return mask
+}
+
+// nextPackedRow computes the next state of a packed row into dst, counting the neighbours of 64 cells at a time
+// with a tree of full adders over the eight shifted neighbour rows.
+func nextPackedRow(dst, above, row, below []uint64, width int, opts Options) {
+	torus := opts.Edge == Torus
+	for k := range row {
+		// The eight neighbours of every cell in the word, one bit each.
+		nw, n, ne := westWord(above, k, width, torus), above[k], eastWord(above, k, width, torus)
+		w, e := westWord(row, k, width, torus), eastWord(row, k, width, torus)
+		sw, s, se := westWord(below, k, width, torus), below[k], eastWord(below, k, width, torus)
+
+		// Add them up in binary: three full adders and a half adder give the 1s and three 2s, which two more
+		// adders fold into 2s, 4s and 8s.
+		sumA, carryA := fullAdd(nw, n, ne)
+		sumB, carryB := fullAdd(w, e, sw)
+		sumC, carryC := s^se, s&se
+		s0, carryD := fullAdd(sumA, sumB, sumC)
+		twos, fours := fullAdd(carryA, carryB, carryC)
+		s1, foursD := twos^carryD, twos&carryD
+		s2, s3 := fours^foursD, fours&foursD
+
+		var born, survives uint64
+		for count := 0; count <= 8; count++ {
+			if opts.Rule.Birth[count] || opts.Rule.Survival[count] {
+				is := countIs(count, s0, s1, s2, s3)
+				if opts.Rule.Birth[count] {
+					born |= is
+				}
+				if opts.Rule.Survival[count] {
+					survives |= is
+				}
+			}
+		}
+		dst[k] = row[k]&survives | ^row[k]&born
+	}
+	// Cells beyond the width may have been born, e.g. under B0, so clear them.
+	if width%wordBits != 0 {
+		dst[len(dst)-1] &= 1<<uint(width%wordBits) - 1
+	}
+}
+
+// nextChunkBitSliced computes rows chunkStart to chunkEnd of nextState, which starts at startRow, by packing
+// them and the rows either side of them into words of 64 cells.
+func nextChunkBitSliced(world, nextState slab.World, startRow, chunkStart, chunkEnd int, opts Options) {
+	width, height := world.Width, world.Height
+	words := (width + wordBits - 1) / wordBits
+	packed := make([][]uint64, chunkEnd-chunkStart+2)
+	for r := range packed {
+		packed[r] = make([]uint64, words)
+		y := chunkStart - 1 + r
+		if opts.Edge == Torus {
+			y = (y + height) % height
+		} else if y < 0 || y >= height {
+			continue // Beyond a dead edge, so left dead.
+		}
+		packRow(packed[r], world.Row(y))
+	}
+
+	next := make([]uint64, words)
+	for i := chunkStart; i < chunkEnd; i++ {
+		r := i - chunkStart + 1
+		nextPackedRow(next, packed[r-1], packed[r], packed[r+1], width, opts)
+		unpackRow(nextState.Row(i-startRow), next)
+	}
+}
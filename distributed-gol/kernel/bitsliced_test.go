@@ -0,0 +1,113 @@
+package kernel
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// randomWorld returns a world of the given size with roughly a third of its cells alive.
+func randomWorld(width, height int, seed int64) [][]byte {
+	random := rand.New(rand.NewSource(seed))
+	w := world(width, height)
+	for y := range w {
+		for x := range w[y] {
+			if random.Intn(3) == 0 {
+				w[y][x] = util.Alive
+			}
+		}
+	}
+	return w
+}
+
+// TestBitSlicedAgrees checks the bit-sliced kernel computes the same next state as the byte-wise one, for widths
+// either side of a word boundary, on both edges, under rules using every neighbour count, and for slices of the world.
+func TestBitSlicedAgrees(t *testing.T) {
+	rules := []string{"B3/S23", "B36/S23", "B0/S8", "B012345678/S", "B/S012345678"}
+	for _, width := range []int{1, 2, 3, 63, 64, 65, 128, 130} {
+		for _, edge := range []Edge{Torus, DeadEdge} {
+			for _, s := range rules {
+				rule, _ := ParseRule(s)
+				w := randomWorld(width, 9, int64(width))
+				bytewise := Options{Rule: rule, Edge: edge}
+				bitsliced := Options{Rule: rule, Edge: edge, Algorithm: BitSliced}
+				for _, rows := range [][2]int{{0, 9}, {0, 1}, {3, 7}, {8, 9}} {
+					expected := NextStateWith(w, width, 9, rows[0], rows[1], 2, bytewise)
+					got := NextStateWith(w, width, 9, rows[0], rows[1], 2, bitsliced)
+					for i := range expected {
+						if !bytes.Equal(got[i], expected[i]) {
+							t.Fatalf("width %d, %s edge, %s: row %d is %v, expected %v", width, edge, rule, rows[0]+i, got[i], expected[i])
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestParseAlgorithm checks kernel names round trip and unknown ones are rejected.
+func TestParseAlgorithm(t *testing.T) {
+	for _, a := range []Algorithm{ByteWise, BitSliced, Wide} {
+		if parsed, err := ParseAlgorithm(a.String()); err != nil || parsed != a {
+			t.Errorf("%s was read as %s, %v", a, parsed, err)
+		}
+	}
+	if parsed, err := ParseAlgorithm("auto"); err != nil || parsed != Native {
+		t.Errorf("auto was read as %s, %v, expected %s", parsed, err, Native)
+	}
+	if _, err := ParseAlgorithm("simd"); err == nil {
+		t.Error("simd was accepted")
+	}
+}
+
+// BenchmarkKernels compares the byte-wise, bit-sliced and wide kernels computing whole turns, packing and
+// unpacking included, with the rows split into chunks of 16 as the broker does. Run it on the machines the workers
+// will run on, e.g. GOARCH=arm64 on Graviton, to choose their -kernel.
+func BenchmarkKernels(b *testing.B) {
+	for _, size := range []int{64, 512, 2048} {
+		w := randomWorld(size, size, 1)
+		for _, algorithm := range []Algorithm{ByteWise, BitSliced, Wide} {
+			opts := Options{Rule: Life, Edge: Torus, Algorithm: algorithm}
+			b.Run(fmt.Sprintf("%s/%dx%d", algorithm, size, size), func(b *testing.B) {
+				b.SetBytes(int64(size * size)) // Reported in cells per second.
+				for i := 0; i < b.N; i++ {
+					NextStateWith(w, size, size, 0, size, 16, opts)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkPackedRow measures counting alone on rows already packed, which is what a world kept packed between
+// turns would pay.
+func BenchmarkPackedRow(b *testing.B) {
+	const width = 2048
+	rows := make([][]uint64, 3)
+	for i, row := range randomWorld(width, 3, 1) {
+		rows[i] = make([]uint64, width/wordBits)
+		packRow(rows[i], row)
+	}
+	next := make([]uint64, width/wordBits)
+	b.SetBytes(width)
+	for i := 0; i < b.N; i++ {
+		nextPackedRow(next, rows[0], rows[1], rows[2], width, Defaults)
+	}
+}
+
+// BenchmarkPackedRowWide is BenchmarkPackedRow for the wide kernel's counting.
+func BenchmarkPackedRowWide(b *testing.B) {
+	const width = 2048
+	rows := make([][]uint64, 3)
+	for i, row := range randomWorld(width, 3, 1) {
+		rows[i] = make([]uint64, width/wordBits)
+		packRowWide(rows[i], row)
+	}
+	next := make([]uint64, width/wordBits)
+	b.SetBytes(width)
+	for i := 0; i < b.N; i++ {
+		nextPackedRowWide(next, rows[0], rows[1], rows[2], width, Defaults)
+	}
+}
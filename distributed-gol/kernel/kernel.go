@@ -0,0 +1,318 @@
+// Package kernel computes Game of Life turns. Workers use it for their slices of the world, and the broker
+// uses it to carry on by itself when no workers can be reached.
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"uk.ac.bris.cs/gameoflife/affinity"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Rule says how many alive neighbours a dead cell needs to be born and an alive cell needs to survive.
+// A stochastic rule also gives the chance of a birth or survival happening for some neighbour counts; a chance
+// of 0 means it always happens, as it does in a deterministic rule.
+type Rule struct {
+	Birth, Survival            [9]bool
+	BirthChance, SurviveChance [9]float64
+}
+
+// Life is Conway's Game of Life, B3/S23.
+var Life = Rule{Birth: [9]bool{3: true}, Survival: [9]bool{2: true, 3: true}}
+
+// ParseRule reads a rule written in B/S notation, such as "B3/S23" for Life or "B36/S23" for HighLife.
+// An empty string is Life. Chances may follow, making the rule stochastic: "B3/S23,S2=0.95" is Life in which
+// cells with two neighbours survive only 95% of the time.
+func ParseRule(s string) (Rule, error) {
+	if s == "" {
+		return Life, nil
+	}
+	chances := strings.Split(strings.ToUpper(s), ",")
+	parts := strings.Split(chances[0], "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "B") || !strings.HasPrefix(parts[1], "S") {
+		return Rule{}, fmt.Errorf("rule %q isn't of the form B3/S23", s)
+	}
+	var r Rule
+	for i, counts := range []*[9]bool{&r.Birth, &r.Survival} {
+		for _, c := range parts[i][1:] {
+			if c < '0' || c > '8' {
+				return Rule{}, fmt.Errorf("rule %q has a neighbour count %q that isn't 0-8", s, c)
+			}
+			counts[c-'0'] = true
+		}
+	}
+
+	for _, chance := range chances[1:] {
+		// Each chance is written like S2=0.95, for a transition the rule already makes.
+		field := strings.SplitN(chance, "=", 2)
+		p, err := 0.0, fmt.Errorf("chance %q isn't of the form S2=0.95", chance)
+		if len(field) == 2 && len(field[0]) == 2 && field[0][1] >= '0' && field[0][1] <= '8' {
+			p, err = strconv.ParseFloat(field[1], 64)
+		}
+		if err != nil || p <= 0 || p > 1 {
+			return Rule{}, fmt.Errorf("rule %q has a chance %q that isn't of the form S2=0.95, between 0 and 1", s, chance)
+		}
+		n := field[0][1] - '0'
+		switch {
+		case field[0][0] == 'B' && r.Birth[n]:
+			r.BirthChance[n] = p
+		case field[0][0] == 'S' && r.Survival[n]:
+			r.SurviveChance[n] = p
+		default:
+			return Rule{}, fmt.Errorf("rule %q gives a chance for %s, which the rule never allows", s, field[0])
+		}
+	}
+	return r, nil
+}
+
+// Stochastic reports whether the rule gives a chance for any of its births or survivals.
+func (r Rule) Stochastic() bool {
+	for n := range r.BirthChance {
+		if r.BirthChance[n] != 0 && r.BirthChance[n] != 1 || r.SurviveChance[n] != 0 && r.SurviveChance[n] != 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) String() string {
+	var b strings.Builder
+	b.WriteString("B")
+	for n, born := range r.Birth {
+		if born {
+			b.WriteByte(byte('0' + n))
+		}
+	}
+	b.WriteString("/S")
+	for n, survives := range r.Survival {
+		if survives {
+			b.WriteByte(byte('0' + n))
+		}
+	}
+	for i, chances := range [2]*[9]float64{&r.BirthChance, &r.SurviveChance} {
+		for n, p := range chances {
+			if p != 0 {
+				fmt.Fprintf(&b, ",%c%d=%s", "BS"[i], n, strconv.FormatFloat(p, 'g', -1, 64))
+			}
+		}
+	}
+	return b.String()
+}
+
+// Edge says what lies beyond the edges of the world.
+type Edge int
+
+const (
+	Torus    Edge = iota // The world wraps around, so cells on one edge neighbour those on the opposite edge.
+	DeadEdge             // Everything outside the world is dead.
+)
+
+// ParseEdge reads "torus" or "dead". An empty string is Torus.
+func ParseEdge(s string) (Edge, error) {
+	switch strings.ToLower(s) {
+	case "", "torus":
+		return Torus, nil
+	case "dead":
+		return DeadEdge, nil
+	}
+	return Torus, fmt.Errorf("edge %q isn't torus or dead", s)
+}
+
+func (e Edge) String() string {
+	if e == DeadEdge {
+		return "dead"
+	}
+	return "torus"
+}
+
+// Algorithm says how neighbours are counted. Every algorithm computes the same next state.
+type Algorithm int
+
+const (
+	ByteWise  Algorithm = iota // Each cell's neighbours are added up one byte at a time.
+	BitSliced                  // Rows are packed into words and the neighbours of 64 cells are counted at once.
+	Wide                       // BitSliced, packing and unpacking eight cells per load, with Life decided a word at a time.
+)
+
+// ParseAlgorithm reads "bytes", "bitsliced", "wide", or "auto" for the Native kernel of the machine. An empty
+// string is ByteWise.
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch strings.ToLower(s) {
+	case "", "bytes":
+		return ByteWise, nil
+	case "bitsliced":
+		return BitSliced, nil
+	case "wide":
+		return Wide, nil
+	case "auto":
+		return Native, nil
+	}
+	return ByteWise, fmt.Errorf("kernel %q isn't bytes, bitsliced, wide or auto", s)
+}
+
+func (a Algorithm) String() string {
+	switch a {
+	case BitSliced:
+		return "bitsliced"
+	case Wide:
+		return "wide"
+	}
+	return "bytes"
+}
+
+// Options choose the rule and edges a world evolves with, and how it is computed. The zero value isn't valid;
+// use Defaults.
+type Options struct {
+	Rule      Rule
+	Edge      Edge
+	Algorithm Algorithm
+	Seed      int64         // Seed of a stochastic rule's chances.
+	Turn      int           // Turn the world being evolved is at, which a stochastic rule's chances depend on.
+	CPUs      affinity.CPUs // CPUs the chunks of Next are pinned to in turn, or nil to leave them to the scheduler.
+}
+
+// Defaults are the options of the original game: Life on a torus, counted byte by byte.
+var Defaults = Options{Rule: Life, Edge: Torus, Algorithm: ByteWise}
+
+// ParseOptions reads the rule and edge as given on the command line. Empty strings are the defaults.
+func ParseOptions(rule, edge string) (Options, error) {
+	r, err := ParseRule(rule)
+	if err != nil {
+		return Options{}, err
+	}
+	e, err := ParseEdge(edge)
+	if err != nil {
+		return Options{}, err
+	}
+	return Options{Rule: r, Edge: e}, nil
+}
+
+// NextState computes the next state of the rows from startRow to endRow of the world, in parallel, with each
+// goroutine computing chunkSize rows, using the Defaults. Only those rows are returned.
+func NextState(world [][]byte, width int, height int, startRow int, endRow int, chunkSize int) [][]byte {
+	return NextStateWith(world, width, height, startRow, endRow, chunkSize, Defaults)
+}
+
+// NextStateWith is NextState with the given rule and edges.
+func NextStateWith(world [][]byte, width int, height int, startRow int, endRow int, chunkSize int, opts Options) [][]byte {
+	nextState, _ := NextStateContext(context.Background(), world, width, height, startRow, endRow, chunkSize, opts)
+	return nextState
+}
+
+// NextStateContext is NextStateWith, giving up once ctx is done, as Next does. It copies the world into a slab and
+// the rows out of one, so code that already has a slab should call Next.
+func NextStateContext(ctx context.Context, world [][]byte, width int, height int, startRow int, endRow int, chunkSize int, opts Options) ([][]byte, error) {
+	nextState, err := Next(ctx, slab.FromRows(world[:height]), startRow, endRow, chunkSize, opts)
+	return nextState.Rows(), err
+}
+
+// Next computes the next state of the rows from startRow to endRow of the world, in parallel, with each goroutine
+// computing chunkSize rows. Only those rows are returned. Each chunk checks ctx before it starts, so a cancelled
+// turn stops after the chunks already being computed rather than finishing the whole slice. It returns ctx.Err()
+// if any chunk was skipped, in which case the rows returned are incomplete.
+func Next(ctx context.Context, world slab.World, startRow int, endRow int, chunkSize int, opts Options) (slab.World, error) {
+	width, height := world.Width, world.Height
+	// Initialise the next state for the given slice of rows.
+	nextState := slab.New(width, endRow-startRow)
+
+	numChunks := (endRow - startRow + chunkSize - 1) / chunkSize
+
+	// Use a WaitGroup to synchronise all goroutines.
+	var wg sync.WaitGroup
+
+	// Launch goroutines to process each chunk in parallel.
+	for chunk := 0; chunk < numChunks; chunk++ {
+		// Calculate the start and end rows for this chunk.
+		chunkStart := startRow + chunk*chunkSize
+		chunkEnd := chunkStart + chunkSize
+		if chunkEnd > endRow {
+			chunkEnd = endRow // Ensure we don't exceed the slice boundary.
+		}
+
+		// Increment the WaitGroup counter for this goroutine.
+		wg.Add(1)
+
+		// Launch a goroutine to process the chunk, pinned to its CPU if there are any to pin to. A chunk that
+		// can't be pinned is computed anyway; the CPUs were checked when they were given.
+		go opts.CPUs.Run(chunk, func(error) {
+			defer wg.Done() // Decrement the counter when the goroutine completes.
+			if ctx.Err() != nil {
+				return // The turn has been cancelled, so nobody wants these rows.
+			}
+			// The bit-sliced kernels decide 64 cells at once, so a stochastic rule is left to the byte-wise one.
+			if opts.Algorithm == BitSliced && !opts.Rule.Stochastic() {
+				nextChunkBitSliced(world, nextState, startRow, chunkStart, chunkEnd, opts)
+				return
+			}
+			if opts.Algorithm == Wide && !opts.Rule.Stochastic() {
+				nextChunkWide(world, nextState, startRow, chunkStart, chunkEnd, opts)
+				return
+			}
+
+			// Compute the next state for rows in this chunk.
+			for i := chunkStart; i < chunkEnd; i++ {
+				out := nextState.Row(i - startRow)
+				if opts.Edge == DeadEdge {
+					for j := 0; j < width; j++ {
+						out[j] = next(opts, world.At(j, i), deadEdgeSum(world, i, j), j, i)
+					}
+					continue
+				}
+				// The rows either side wrap around the top and bottom of the world.
+				above, row, below := world.Row((i+height-1)%height), world.Row(i), world.Row((i+1)%height)
+				for j := 0; j < width; j++ {
+					left, right := (j+width-1)%width, (j+1)%width
+					// Calculate the sum of the states of the 8 neighbouring cells.
+					sum := (int(above[left]) + int(above[j]) + int(above[right]) +
+						int(row[left]) + int(row[right]) +
+						int(below[left]) + int(below[j]) + int(below[right])) / int(util.Alive)
+
+					// Update the cell state based on the rule, B3/S23 for Conway's Game of Life.
+					out[j] = next(opts, row[j], sum, j, i)
+				}
+			}
+		})
+	}
+
+	// Wait for all goroutines to finish.
+	wg.Wait()
+
+	return nextState, ctx.Err()
+}
+
+// next returns the next state of cell (x, y) with the given number of alive neighbours.
+func next(opts Options, cell byte, neighbours, x, y int) byte {
+	rule := opts.Rule
+	chance := rule.BirthChance[neighbours]
+	if cell == util.Alive {
+		if !rule.Survival[neighbours] {
+			return util.Dead
+		}
+		chance = rule.SurviveChance[neighbours]
+	} else if !rule.Birth[neighbours] {
+		return util.Dead
+	}
+	// The chances depend only on the seed, the turn and the cell, so however the world is split up, and wherever
+	// the run is resumed, the same seed makes the same choices.
+	if chance != 0 && util.NewTurnRNG(opts.Seed, opts.Turn).Float64(x, y) >= chance {
+		return util.Dead
+	}
+	return util.Alive
+}
+
+// deadEdgeSum counts the alive neighbours of cell (j, i), treating everything outside the world as dead.
+func deadEdgeSum(world slab.World, i, j int) int {
+	sum := 0
+	for y := i - 1; y <= i+1; y++ {
+		for x := j - 1; x <= j+1; x++ {
+			if (y != i || x != j) && y >= 0 && y < world.Height && x >= 0 && x < world.Width && world.At(x, y) == util.Alive {
+				sum++
+			}
+		}
+	}
+	return sum
+}
@@ -0,0 +1,158 @@
+package kernel
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// world builds a world of the given size with the listed cells alive.
+func world(width, height int, alive ...util.Cell) [][]byte {
+	w := make([][]byte, height)
+	for y := range w {
+		w[y] = make([]byte, width)
+	}
+	for _, cell := range alive {
+		w[cell.Y][cell.X] = util.Alive
+	}
+	return w
+}
+
+// aliveCells counts the alive cells in a world.
+func aliveCells(w [][]byte) int {
+	count := 0
+	for y := range w {
+		for x := range w[y] {
+			if w[y][x] == util.Alive {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// TestParseRule checks rules round trip through B/S notation and malformed ones are rejected.
+func TestParseRule(t *testing.T) {
+	for _, s := range []string{"B3/S23", "B36/S23", "B2/S", "B/S012345678", "B3/S23,S2=0.95", "B36/S23,B6=0.5,S3=0.25"} {
+		r, err := ParseRule(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+		} else if r.String() != s {
+			t.Errorf("%s was read as %s", s, r)
+		}
+	}
+	if r, err := ParseRule(""); err != nil || r != Life {
+		t.Errorf("an empty rule was read as %v, %v rather than Life", r, err)
+	}
+	bad := []string{"B3S23", "S23/B3", "B9/S23", "B3/S2x",
+		"B3/S23,S4=0.5", "B3/S23,S2=0", "B3/S23,S2=1.5", "B3/S23,X2=0.5", "B3/S23,S2", "B3/S23,S=0.5"}
+	for _, s := range bad {
+		if _, err := ParseRule(s); err == nil {
+			t.Errorf("%s was accepted", s)
+		}
+	}
+}
+
+// TestEdges checks a block straddling the corners of a torus is stable, while with dead edges its four cells
+// are isolated and die.
+func TestEdges(t *testing.T) {
+	corners := world(5, 5, util.Cell{X: 0, Y: 0}, util.Cell{X: 4, Y: 0}, util.Cell{X: 0, Y: 4}, util.Cell{X: 4, Y: 4})
+	if alive := aliveCells(NextStateWith(corners, 5, 5, 0, 5, 2, Defaults)); alive != 4 {
+		t.Errorf("on a torus the block has %d alive cells after a turn, expected 4", alive)
+	}
+	dead := Options{Rule: Life, Edge: DeadEdge}
+	if alive := aliveCells(NextStateWith(corners, 5, 5, 0, 5, 2, dead)); alive != 0 {
+		t.Errorf("with dead edges the block has %d alive cells after a turn, expected 0", alive)
+	}
+}
+
+// TestRule checks a dead cell with six neighbours is born under HighLife but not under Life.
+func TestRule(t *testing.T) {
+	six := world(5, 5,
+		util.Cell{X: 1, Y: 1}, util.Cell{X: 2, Y: 1}, util.Cell{X: 3, Y: 1},
+		util.Cell{X: 1, Y: 3}, util.Cell{X: 2, Y: 3}, util.Cell{X: 3, Y: 3})
+	highLife, _ := ParseRule("B36/S23")
+	if next := NextStateWith(six, 5, 5, 2, 3, 1, Options{Rule: highLife}); next[0][2] != util.Alive {
+		t.Error("HighLife didn't bring the cell with six neighbours to life")
+	}
+	if next := NextState(six, 5, 5, 2, 3, 1); next[0][2] != util.Dead {
+		t.Error("Life brought the cell with six neighbours to life")
+	}
+}
+
+// TestStochastic checks a stochastic rule's choices depend only on the seed, the turn and the cell, so however the
+// world is split and counted the same seed gives the same world, and that chances only ever hold back births and
+// survivals, about as often as they should.
+func TestStochastic(t *testing.T) {
+	rule, err := ParseRule("B3/S23,B3=0.5,S2=0.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := randomWorld(64, 64, 1)
+	opts := Options{Rule: rule, Seed: 42, Turn: 7}
+	whole := NextStateWith(w, 64, 64, 0, 64, 64, opts)
+
+	split := append(NextStateWith(w, 64, 64, 0, 20, 3, opts), NextStateWith(w, 64, 64, 20, 64, 16, opts)...)
+	bitsliced := opts
+	bitsliced.Algorithm = BitSliced
+	for name, got := range map[string][][]byte{"split": split, "bit-sliced": NextStateWith(w, 64, 64, 0, 64, 16, bitsliced)} {
+		for y := range whole {
+			if !bytes.Equal(got[y], whole[y]) {
+				t.Fatalf("%s: row %d differs with the same seed and turn", name, y)
+			}
+		}
+	}
+
+	differs := func(other Options) bool {
+		next := NextStateWith(w, 64, 64, 0, 64, 64, other)
+		for y := range whole {
+			if !bytes.Equal(next[y], whole[y]) {
+				return true
+			}
+		}
+		return false
+	}
+	if !differs(Options{Rule: rule, Seed: 43, Turn: 7}) || !differs(Options{Rule: rule, Seed: 42, Turn: 8}) {
+		t.Error("another seed or turn made exactly the same choices")
+	}
+
+	// Every cell alive under the stochastic rule is alive under Life, and about half the births happen.
+	life := NextState(w, 64, 64, 0, 64, 64)
+	births, held := 0, 0
+	for y := range life {
+		for x := range life[y] {
+			if whole[y][x] == util.Alive && life[y][x] != util.Alive {
+				t.Fatalf("(%d, %d) is alive only under the stochastic rule", x, y)
+			}
+			if w[y][x] != util.Alive && life[y][x] == util.Alive {
+				births++
+				if whole[y][x] != util.Alive {
+					held++
+				}
+			}
+		}
+	}
+	if births == 0 || held < births/4 || held > 3*births/4 {
+		t.Errorf("%d of %d births were held back, expected about half", held, births)
+	}
+}
+
+// TestNextStateContext checks a cancelled turn is given up rather than computed, and a live one is computed as usual.
+func TestNextStateContext(t *testing.T) {
+	blinker := world(5, 5, util.Cell{X: 1, Y: 2}, util.Cell{X: 2, Y: 2}, util.Cell{X: 3, Y: 2})
+	next, err := NextStateContext(context.Background(), blinker, 5, 5, 0, 5, 2, Defaults)
+	if err != nil || aliveCells(next) != 3 || next[1][2] != util.Alive {
+		t.Errorf("the blinker didn't turn upright: %v, %v", next, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	next, err = NextStateContext(ctx, blinker, 5, 5, 0, 5, 2, Defaults)
+	if err != context.Canceled {
+		t.Errorf("got %v for a cancelled turn, expected %v", err, context.Canceled)
+	}
+	if aliveCells(next) != 0 {
+		t.Error("a cancelled turn was computed anyway")
+	}
+}
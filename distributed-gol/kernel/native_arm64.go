@@ -0,0 +1,8 @@
+//go:build arm64
+// +build arm64
+
+package kernel
+
+// Native is the kernel -kernel=auto chooses. On arm64 machines such as AWS Graviton, the cheapest way to add
+// workers, the byte-wise loop leaves most of each core idle, so it is the wide kernel.
+const Native = Wide
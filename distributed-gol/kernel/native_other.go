@@ -0,0 +1,7 @@
+//go:build !arm64
+// +build !arm64
+
+package kernel
+
+// Native is the kernel -kernel=auto chooses, which away from arm64 is the byte-wise one, as it always has been.
+const Native = ByteWise
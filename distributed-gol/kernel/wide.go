@@ -0,0 +1,125 @@
+package kernel
+
+import (
+	"encoding/binary"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// gatherBits moves the low bit of each byte of a word into the top byte, the low byte's into the lowest bit: the
+// partial products land on distinct bits, so nothing carries into the top byte but the bits wanted.
+const gatherBits = 0x0102040810204080
+
+// spread holds, for each byte of eight packed cells, the eight cells unpacked, a byte each, in one word.
+var spread = func() (spread [256]uint64) {
+	for packed := range spread {
+		for bit := uint(0); bit < 8; bit++ {
+			if packed>>bit&1 != 0 {
+				spread[packed] |= uint64(util.Alive) << (8 * bit)
+			}
+		}
+	}
+	return
+}()
+
+// packRowWide is packRow taking eight cells at a time: one load of eight cells, a mask of the top bit of each,
+// which Alive has set and Dead hasn't, and a multiply to gather them into a byte.
+func packRowWide(dst []uint64, row []byte) {
+	j := 0
+	for ; j+8 <= len(row); j += 8 {
+		cells := binary.LittleEndian.Uint64(row[j:]) >> 7 & 0x0101010101010101
+		dst[j/wordBits] |= cells * gatherBits >> 56 << uint(j%wordBits)
+	}
+	for ; j < len(row); j++ {
+		if row[j] == util.Alive {
+			dst[j/wordBits] |= 1 << uint(j%wordBits)
+		}
+	}
+}
+
+// unpackRowWide is unpackRow writing eight cells at a time, looked up in spread.
+func unpackRowWide(row []byte, src []uint64) {
+	j := 0
+	for ; j+8 <= len(row); j += 8 {
+		binary.LittleEndian.PutUint64(row[j:], spread[byte(src[j/wordBits]>>uint(j%wordBits))])
+	}
+	for ; j < len(row); j++ {
+		if src[j/wordBits]>>uint(j%wordBits)&1 != 0 {
+			row[j] = util.Alive
+		} else {
+			row[j] = util.Dead
+		}
+	}
+}
+
+// nextPackedRowWide is nextPackedRow with the rule worked out once a row rather than once a word, and Life, which
+// every run uses unless told otherwise, decided with four operations rather than a mask for each count.
+func nextPackedRowWide(dst, above, row, below []uint64, width int, opts Options) {
+	torus := opts.Edge == Torus
+	life := opts.Rule.Birth == Life.Birth && opts.Rule.Survival == Life.Survival
+	var born, survives []int // The neighbour counts at which a cell is born, and at which one survives.
+	for count := 0; count <= 8; count++ {
+		if opts.Rule.Birth[count] {
+			born = append(born, count)
+		}
+		if opts.Rule.Survival[count] {
+			survives = append(survives, count)
+		}
+	}
+	for k := range row {
+		nw, n, ne := westWord(above, k, width, torus), above[k], eastWord(above, k, width, torus)
+		w, e := westWord(row, k, width, torus), eastWord(row, k, width, torus)
+		sw, s, se := westWord(below, k, width, torus), below[k], eastWord(below, k, width, torus)
+
+		sumA, carryA := fullAdd(nw, n, ne)
+		sumB, carryB := fullAdd(w, e, sw)
+		sumC, carryC := s^se, s&se
+		s0, carryD := fullAdd(sumA, sumB, sumC)
+		twos, fours := fullAdd(carryA, carryB, carryC)
+		s1, foursD := twos^carryD, twos&carryD
+		s2, s3 := fours^foursD, fours&foursD
+
+		if life {
+			// Three neighbours, or two and alive: the 2s set, the 4s and 8s clear, and the 1s set unless alive.
+			dst[k] = s1 &^ (s2 | s3) & (s0 | row[k])
+			continue
+		}
+		var bornMask, survivesMask uint64
+		for _, count := range born {
+			bornMask |= countIs(count, s0, s1, s2, s3)
+		}
+		for _, count := range survives {
+			survivesMask |= countIs(count, s0, s1, s2, s3)
+		}
+		dst[k] = row[k]&survivesMask | ^row[k]&bornMask
+	}
+	if width%wordBits != 0 {
+		dst[len(dst)-1] &= 1<<uint(width%wordBits) - 1
+	}
+}
+
+// nextChunkWide is nextChunkBitSliced with the word-wide packing and rule above, which keep a wide core such as
+// Graviton's busy with whole words where the bit-sliced kernel spends most of its time on single cells.
+func nextChunkWide(world, nextState slab.World, startRow, chunkStart, chunkEnd int, opts Options) {
+	width, height := world.Width, world.Height
+	words := (width + wordBits - 1) / wordBits
+	packed := make([][]uint64, chunkEnd-chunkStart+2)
+	for r := range packed {
+		packed[r] = make([]uint64, words)
+		y := chunkStart - 1 + r
+		if opts.Edge == Torus {
+			y = (y + height) % height
+		} else if y < 0 || y >= height {
+			continue // Beyond a dead edge, so left dead.
+		}
+		packRowWide(packed[r], world.Row(y))
+	}
+
+	next := make([]uint64, words)
+	for i := chunkStart; i < chunkEnd; i++ {
+		r := i - chunkStart + 1
+		nextPackedRowWide(next, packed[r-1], packed[r], packed[r+1], width, opts)
+		unpackRowWide(nextState.Row(i-startRow), next)
+	}
+}
@@ -0,0 +1,74 @@
+package kernel
+
+import (
+	"bytes"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/affinity"
+)
+
+// TestWideAgrees checks the wide kernel computes the same next state as the byte-wise one, for widths either side
+// of a byte and a word boundary, on both edges, under Life and rules using every neighbour count.
+func TestWideAgrees(t *testing.T) {
+	rules := []string{"B3/S23", "B36/S23", "B0/S8", "B012345678/S", "B/S012345678"}
+	for _, width := range []int{1, 7, 8, 9, 63, 64, 65, 72, 130} {
+		for _, edge := range []Edge{Torus, DeadEdge} {
+			for _, s := range rules {
+				rule, _ := ParseRule(s)
+				w := randomWorld(width, 9, int64(width))
+				bytewise := Options{Rule: rule, Edge: edge}
+				wide := Options{Rule: rule, Edge: edge, Algorithm: Wide}
+				for _, rows := range [][2]int{{0, 9}, {3, 7}, {8, 9}} {
+					expected := NextStateWith(w, width, 9, rows[0], rows[1], 2, bytewise)
+					got := NextStateWith(w, width, 9, rows[0], rows[1], 2, wide)
+					for i := range expected {
+						if !bytes.Equal(got[i], expected[i]) {
+							t.Fatalf("width %d, %s edge, %s: row %d is %v, expected %v", width, edge, rule, rows[0]+i, got[i], expected[i])
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestPackWide checks packing and unpacking eight cells at a time gives the same words and cells as one at a time.
+func TestPackWide(t *testing.T) {
+	for _, width := range []int{5, 8, 64, 100} {
+		row := randomWorld(width, 1, int64(width))[0]
+		words := (width + wordBits - 1) / wordBits
+		expected, got := make([]uint64, words), make([]uint64, words)
+		packRow(expected, row)
+		packRowWide(got, row)
+		for k := range expected {
+			if got[k] != expected[k] {
+				t.Fatalf("width %d: word %d packed as %x, expected %x", width, k, got[k], expected[k])
+			}
+		}
+		unpacked := make([]byte, width)
+		unpackRowWide(unpacked, got)
+		if !bytes.Equal(unpacked, row) {
+			t.Fatalf("width %d: unpacked %v, expected %v", width, unpacked, row)
+		}
+	}
+}
+
+// TestPinnedAgrees checks every kernel computes the same next state with its chunks pinned to a CPU as without.
+func TestPinnedAgrees(t *testing.T) {
+	cpus := affinity.CPUs{0}
+	if err := cpus.Check(); err != nil {
+		t.Skip(err)
+	}
+	w := randomWorld(100, 40, 1)
+	for _, algorithm := range []Algorithm{ByteWise, BitSliced, Wide} {
+		opts := Options{Rule: Life, Algorithm: algorithm}
+		expected := NextStateWith(w, 100, 40, 0, 40, 3, opts)
+		opts.CPUs = cpus
+		got := NextStateWith(w, 100, 40, 0, 40, 3, opts)
+		for i := range expected {
+			if !bytes.Equal(got[i], expected[i]) {
+				t.Fatalf("%v kernel: row %d is %v pinned, expected %v", algorithm, i, got[i], expected[i])
+			}
+		}
+	}
+}
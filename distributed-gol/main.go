@@ -1,68 +1,466 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
 	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/golbroker"
+	"uk.ac.bris.cs/gameoflife/golcli"
+	"uk.ac.bris.cs/gameoflife/golclient"
+	"uk.ac.bris.cs/gameoflife/golworker"
+	"uk.ac.bris.cs/gameoflife/kernel"
 	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/storage"
+	"uk.ac.bris.cs/gameoflife/stubs"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
-// main is the function called when starting Game of Life with 'go run .'
+// commands are the subcommands of the client program. The engine, worker and goldiff commands run the same code
+// as serve-broker, serve-worker and diff.
+var commands = []golcli.Command{
+	{Name: "run", Summary: "Run the Game of Life on a broker and show it in a window (the default)", Run: run},
+	{Name: "serve-broker", Summary: "Serve as the broker, as go run ./engine does", Run: golbroker.Main},
+	{Name: "serve-worker", Summary: "Serve as a worker, as go run ./worker does", Run: golworker.Main},
+	{Name: "bench", Summary: "Time each kernel evolving an input image on this machine", Run: golcli.Bench},
+	{Name: "replay", Summary: "Carry on a saved run (.golsnap) on this machine up to a later turn", Run: golcli.Replay},
+	{Name: "diff", Summary: "Compare two snapshots and draw them overlaid, as go run ./goldiff does", Run: golcli.Diff},
+	{Name: "deploy", Summary: "Start a broker and its workers on this machine", Run: golcli.Deploy},
+}
+
+// main is the function called when starting Game of Life with 'go run .'. Without a subcommand, or with flags
+// first, it runs the client, as it did before there were subcommands.
 func main() {
-	runtime.LockOSThread()
+	runtime.LockOSThread() // The window must be drawn from the main thread, which run is called on.
+	golcli.Dispatch(filepath.Base(os.Args[0]), commands, "run", os.Args[1:])
+}
+
+// run runs the client from the command line arguments args, sending the run to the broker and showing it.
+func run(command string, args []string) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
 	var params gol.Params
 
-	flag.IntVar(
+	flags.IntVar(
 		&params.Threads,
 		"t",
 		8,
 		"Specify the number of worker threads to use. Defaults to 8.")
 
-	flag.IntVar(
+	flags.IntVar(
 		&params.ImageWidth,
 		"w",
 		512,
 		"Specify the width of the image. Defaults to 512.")
 
-	flag.IntVar(
+	flags.IntVar(
 		&params.ImageHeight,
 		"h",
 		512,
 		"Specify the height of the image. Defaults to 512.")
 
-	flag.IntVar(
+	flags.IntVar(
 		&params.Turns,
 		"turns",
 		10000000000,
 		"Specify the number of turns to process. Defaults to 10000000000.")
 
-	noVis := flag.Bool(
+	flags.StringVar(
+		&params.InDir,
+		"inDir",
+		"images",
+		"Specify the directory, or s3:// or gs:// bucket URL, to read input images from. Defaults to images.")
+
+	flags.StringVar(
+		&params.OutDir,
+		"outDir",
+		"out",
+		"Specify the directory, or s3:// or gs:// bucket URL, to write output images to. Defaults to out.")
+
+	perRun := flags.Bool(
+		"perRun",
+		true,
+		"Write this run's output images to a subdirectory of -outDir named after its start time and seed, so repeated runs don't overwrite each other.")
+
+	seed := flags.Int64(
+		"seed",
+		0,
+		"Specify the seed of a stochastic -rule, also used in the name of this run's output subdirectory. Defaults to a random seed.")
+
+	var labels util.Labels
+	flags.Var(
+		&labels,
+		"label",
+		"Attach a key=value tag to the run, e.g. -label experiment=gliders -label author=kim, saved in its checkpoints. Repeat for more.")
+
+	flags.Float64Var(
+		&params.Threshold,
+		"threshold",
+		0.5,
+		"Specify the fraction of an input image's maxval at or above which a pixel is alive. Defaults to 0.5.")
+
+	flags.StringVar(
+		&params.Rule,
+		"rule",
+		"B3/S23",
+		"Specify the rule in B/S notation, e.g. B36/S23 for HighLife, optionally with chances, e.g. B3/S23,S2=0.95. Defaults to B3/S23, Conway's Game of Life.")
+
+	flags.StringVar(
+		&params.Edge,
+		"edge",
+		"torus",
+		"Specify what lies beyond the edges of the world: torus to wrap around, or dead. Defaults to torus.")
+
+	flags.BoolVar(
+		&params.HardPause,
+		"hardPause",
+		false,
+		"Pause by locking the broker's mutex, which also blocks its reads, rather than holding the run between turns.")
+
+	flags.BoolVar(
+		&params.Shards,
+		"shards",
+		false,
+		"Save with s as one image per worker slice, written by the workers to -outDir, which they must share, plus a manifest listing them.")
+
+	flags.StringVar(
+		&params.Format,
+		"format",
+		"pgm",
+		"Specify the formats images are saved in, separated by commas: pgm, npy for a NumPy array of 0s and 1s that numpy.load reads, or pgm,npy for both. Defaults to pgm.")
+
+	flags.IntVar(
+		&params.Preview,
+		"preview",
+		0,
+		"Specify a number of turns to run locally on a downsampled world first, only sending the run to the broker if it is still changing after them. Defaults to 0, no preview.")
+
+	flags.IntVar(
+		&params.PreviewScale,
+		"previewScale",
+		4,
+		"Specify how many times smaller in each direction the -preview world is. Defaults to 4.")
+
+	flags.BoolVar(
+		&params.PreviewAlways,
+		"previewAlways",
+		false,
+		"Send the run to the broker after the -preview even if the preview died out or settled down.")
+
+	flags.DurationVar(
+		&params.RPCTimeout,
+		"rpcTimeout",
+		30*time.Second,
+		"Specify how long to wait for the broker to answer a call before reporting it and carrying on. 0 waits forever. Defaults to 30s.")
+
+	flags.IntVar(
+		&params.UploadChunk,
+		"uploadChunk",
+		golclient.DefaultChunkSize,
+		"Specify the most cells to send the broker in one call. A bigger world is uploaded in chunks before the run starts. 0 always sends it whole. Defaults to 16777216, a 4096x4096 world.")
+
+	flags.IntVar(
+		&params.PausePopulation,
+		"pausePopulation",
+		0,
+		"Specify a number of alive cells to pause the run at when the population rises above it or falls back to it. Defaults to 0, no pause.")
+
+	flags.BoolVar(
+		&params.PauseSteady,
+		"pauseSteady",
+		false,
+		"Pause the run when it settles into a still life or an oscillator.")
+
+	pauseCells := flags.String(
+		"pauseCells",
+		"",
+		"Specify cells to pause the run at when any of them changes, as x,y pairs separated by ';', e.g. '10,20;30,40'.")
+
+	flags.StringVar(
+		&params.PausePattern,
+		"pausePattern",
+		"",
+		"Specify a pattern to pause the run at when it appears in any orientation, as rows separated by '/' with 'O' alive and '.' dead, e.g. '.O./..O/OOO' for a glider.")
+
+	flags.StringVar(
+		&sdl.StreamAddress,
+		"streamAddr",
+		sdl.StreamAddress,
+		"Specify the address to serve the board to browsers on when SDL is unavailable. Defaults to "+sdl.StreamAddress+".")
+
+	noVis := flags.Bool(
 		"noVis",
 		false,
 		"Disables the SDL window, so there is no visualisation during the tests.")
 
-	flag.Parse()
+	keymap := flags.String(
+		"keymap",
+		"",
+		"Read key bindings from a file with one 'action = key [key ...]' binding per line.")
+
+	keys := flags.String(
+		"keys",
+		"",
+		"Bind keys to actions, e.g. 'kill=ctrl+k,save=f5'. Applied after -keymap.")
+
+	plan := flags.Bool(
+		"plan",
+		false,
+		"Print how the broker would split a run of this size between its workers, and the network traffic and memory it would need, then exit without starting it.")
+
+	selfTest := flags.Bool(
+		"selftest",
+		false,
+		"Ask the broker to check its own kernel and every worker compute turns correctly, print PASS or FAIL for each check, then exit.")
+
+	compare := flags.String(
+		"compare",
+		"",
+		"Evolve the input image with two kernels on the client, e.g. bytes,bitsliced, in lockstep and show them side by side, with any cells they disagree on in red, instead of sending the run to the broker.")
+
+	standalone := flags.Int(
+		"standalone",
+		0,
+		"Start the broker and this many workers inside the client, connected in memory rather than over TCP, instead of using a broker started separately. Defaults to 0, a separate broker.")
+
+	config := flags.String(
+		"config",
+		"",
+		"Read any flags not given on the command line from a YAML or TOML config file, using its top-level and [client] settings.")
+
+	flags.Parse(args)
+
+	if *config != "" {
+		if err := util.ApplyConfig(flags, *config, "client"); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	bindings := sdl.DefaultBindings()
+	if *keymap != "" {
+		if err := bindings.Load(*keymap); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := bindings.Set(*keys); err != nil {
+		log.Fatal(err)
+	}
+
+	if *standalone > 0 {
+		stop, err := startStandalone(*standalone)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer stop()
+		fmt.Printf("Standalone: broker and %d workers in this process\n", *standalone)
+	}
+
+	if *selfTest {
+		passed, err := printSelfTest()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !passed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *plan {
+		if err := printPlan(params); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *pauseCells != "" {
+		var err error
+		if params.PauseCells, err = parseCells(*pauseCells); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var engines [2]kernel.Algorithm
+	if *compare != "" {
+		var err error
+		if engines, err = parseEngines(*compare); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Settings the chosen engine can't honour are refused before anything starts, rather than quietly ignored.
+	err := gol.CheckFeatures(params, gol.BrokerEngine)
+	if *compare != "" {
+		err = gol.CheckFeatures(params, gol.CompareEngine, engines[:]...)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The seed goes in the output directory's name, so a stochastic run can be replayed with -seed.
+	params.Seed = util.RunSeed(*seed)
+	if *perRun {
+		params.OutDir = storage.Join(params.OutDir, util.RunName(time.Now(), params.Seed))
+	}
 
 	fmt.Println("Threads:", params.Threads)
 	fmt.Println("Width:", params.ImageWidth)
 	fmt.Println("Height:", params.ImageHeight)
+	fmt.Println("Output:", params.OutDir)
+	fmt.Println("Seed:", params.Seed)
+	if len(labels) > 0 {
+		params.Labels = labels
+		fmt.Println("Labels:", labels)
+	}
+
+	// The window draws the world the run starts from out of one event, however many cells are alive.
+	params.InitialBoard = true
 
 	keyPresses := make(chan rune, 10)
 	events := make(chan gol.Event, 1000)
 
-	go gol.Run(params, events, keyPresses)
+	// Ctrl+C quits the run as q does, so the broker stops computing it rather than carry on for nobody.
+	// A second Ctrl+C exits straight away.
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		signal.Stop(interrupts)
+		cancel()
+	}()
+
+	if *compare != "" {
+		go gol.RunCompare(ctx, params, engines, events, keyPresses)
+		if !(*noVis) {
+			sdl.RunCompare(params, events, keyPresses, bindings)
+		} else if !printComparison(events) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	go gol.RunContext(ctx, params, events, keyPresses)
 	if !(*noVis) {
-		sdl.Run(params, events, keyPresses)
+		sdl.Run(params, events, keyPresses, bindings)
 	} else {
-		complete := false
-		for !complete {
-			event := <-events
-			switch event.(type) {
-			case gol.FinalTurnComplete:
-				complete = true
+		// The events channel is closed without a FinalTurnComplete if the run fails.
+		failed := false
+		for event := range events {
+			switch e := event.(type) {
+			case gol.ErrorEvent:
+				fmt.Printf("Completed Turns %-8v%v\n", e.CompletedTurns, e)
+				failed = failed || !e.Recoverable
+			case gol.PreviewComplete:
+				fmt.Println(e)
 			}
 		}
+		if failed {
+			os.Exit(1)
+		}
+	}
+}
+
+// parseEngines reads the two kernels to compare, e.g. "bytes,bitsliced".
+func parseEngines(spec string) ([2]kernel.Algorithm, error) {
+	var engines [2]kernel.Algorithm
+	names := strings.Split(spec, ",")
+	if len(names) != 2 {
+		return engines, fmt.Errorf("-compare %q doesn't name two kernels, e.g. bytes,bitsliced", spec)
+	}
+	for i, name := range names {
+		engine, err := kernel.ParseAlgorithm(strings.TrimSpace(name))
+		if err != nil {
+			return engines, err
+		}
+		engines[i] = engine
+	}
+	return engines, nil
+}
+
+// parseCells reads the cells to pause the run at, e.g. "10,20;30,40".
+func parseCells(spec string) ([]util.Cell, error) {
+	var cells []util.Cell
+	for _, pair := range strings.Split(spec, ";") {
+		var cell util.Cell
+		if _, err := fmt.Sscanf(strings.TrimSpace(pair), "%d,%d", &cell.X, &cell.Y); err != nil {
+			return nil, fmt.Errorf("-pauseCells %q has %q, expected x,y", spec, pair)
+		}
+		cells = append(cells, cell)
+	}
+	return cells, nil
+}
+
+// printComparison prints the errors of a comparison run without a window, and reports whether the engines agreed
+// on every turn and the run finished.
+func printComparison(events <-chan gol.Event) bool {
+	agreed := true
+	for event := range events {
+		if e, ok := event.(gol.ErrorEvent); ok {
+			fmt.Printf("Completed Turns %-8v%v\n", e.CompletedTurns, e)
+			agreed = agreed && e.Recoverable && e.Component != "compare"
+		}
+	}
+	return agreed
+}
+
+// printSelfTest has the broker check itself and its workers, prints the result of each check and reports whether
+// they all passed.
+func printSelfTest() (bool, error) {
+	ctx := context.Background()
+	client, err := golclient.Dial(ctx, gol.BrokerAddress)
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+	// Checking every worker takes longer than an ordinary call.
+	client.Timeout = time.Minute
+	res, err := client.SelfTest(ctx)
+	if err != nil {
+		return false, err
+	}
+	fmt.Print(res.Report)
+	return res.Passed, nil
+}
+
+// printPlan asks the broker how it would carry out the run params describe, and prints its answer.
+func printPlan(params gol.Params) error {
+	ctx := context.Background()
+	client, err := golclient.Dial(ctx, gol.BrokerAddress)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	client.Timeout = params.RPCTimeout
+	plan, err := client.Plan(ctx, stubs.PlanRequest{
+		ImageWidth:  params.ImageWidth,
+		ImageHeight: params.ImageHeight,
+		Turns:       params.Turns,
+		Rule:        params.Rule,
+		Edge:        params.Edge,
+	})
+	if err != nil {
+		return err
+	}
+
+	mib := func(bytes int64) string { return fmt.Sprintf("%.1f MiB", float64(bytes)/float64(util.MiB)) }
+	if plan.Workers == 0 {
+		fmt.Printf("The broker would compute every turn itself with the %s kernel.\n", plan.Kernel)
+	} else {
+		fmt.Printf("The world would be split between %d workers:\n", plan.Workers)
+		for i, a := range plan.Assignments {
+			fmt.Printf("  worker %-3d rows %d-%d, needing %s\n", a.Worker, a.StartRow, a.EndRow, mib(plan.WorkerMemory[i]))
+		}
 	}
+	fmt.Printf("Each turn sends %s to the workers and receives %s back, %s over %d turns.\n",
+		mib(plan.SentPerTurn), mib(plan.ReceivedPerTurn), mib(plan.Transfer), params.Turns)
+	fmt.Printf("The broker would need %s.\n", mib(plan.BrokerMemory))
+	return nil
 }
@@ -0,0 +1,170 @@
+// Package memnet connects the client, the broker and its workers over in-memory pipes instead of TCP, so the whole
+// distributed code path can run in one process. Listen takes an address of the form mem:<name>, and dialling that
+// address connects to the listener through a net.Pipe. Any other address is dialled over TCP as usual, so callers
+// can dial through memnet whichever kind of address they were given.
+package memnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scheme starts every in-memory address.
+const scheme = "mem:"
+
+// errClosed is returned by Accept once the listener has been closed.
+var errClosed = errors.New("memnet: listener closed")
+
+var (
+	mu        sync.Mutex
+	listeners = make(map[string]*listener) // Listeners by address, until they are closed.
+	served    int                          // Number of addresses ServeRPC has made up, so each is new.
+)
+
+// listener hands each connection dialled to its address over to Accept.
+type listener struct {
+	address string
+	conns   chan net.Conn // The listener's end of each pipe dialled.
+	closed  chan struct{} // Closed by Close.
+	once    sync.Once
+}
+
+// Is reports whether address is an in-memory one.
+func Is(address string) bool {
+	return strings.HasPrefix(address, scheme)
+}
+
+// Listen listens on an in-memory address, which no other open listener may have.
+func Listen(address string) (net.Listener, error) {
+	if !Is(address) {
+		return nil, fmt.Errorf("%q isn't an in-memory address such as %sbroker", address, scheme)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := listeners[address]; ok {
+		return nil, fmt.Errorf("%s is already being listened on", address)
+	}
+	l := &listener{address: address, conns: make(chan net.Conn), closed: make(chan struct{})}
+	listeners[address] = l
+	return l, nil
+}
+
+// Accept waits for the next connection dialled to the listener.
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, errClosed
+	}
+}
+
+// Close stops the listener, freeing its address. Connections already accepted stay open.
+func (l *listener) Close() error {
+	l.once.Do(func() {
+		mu.Lock()
+		delete(listeners, l.address)
+		mu.Unlock()
+		close(l.closed)
+	})
+	return nil
+}
+
+// Addr returns the listener's address.
+func (l *listener) Addr() net.Addr {
+	return addr(l.address)
+}
+
+// addr is an in-memory address.
+type addr string
+
+func (a addr) Network() string {
+	return "memnet"
+}
+
+func (a addr) String() string {
+	return string(a)
+}
+
+// DialContext connects to address, in memory if it is an in-memory address and over TCP otherwise, giving up when
+// ctx is done.
+func DialContext(ctx context.Context, address string) (net.Conn, error) {
+	if !Is(address) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+	mu.Lock()
+	l, ok := listeners[address]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("dial %s: nothing is listening", address)
+	}
+	client, server := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		err := fmt.Errorf("dial %s: %v", address, errClosed)
+		client.Close()
+		server.Close()
+		return nil, err
+	case <-ctx.Done():
+		client.Close()
+		server.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// DialTimeout is DialContext giving up after timeout, or never if it is 0, as net.DialTimeout does.
+func DialTimeout(address string, timeout time.Duration) (net.Conn, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return DialContext(ctx, address)
+}
+
+// ServeRPC serves the methods of receiver under name, as rpc.RegisterName would, on an in-memory address of its own,
+// so RPC handlers can be tested through net/rpc, encoding and all, without binding a TCP port. It returns the
+// address to dial and a function that stops serving it.
+func ServeRPC(name string, receiver interface{}) (address string, stop func(), err error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName(name, receiver); err != nil {
+		return "", nil, err
+	}
+	mu.Lock()
+	served++
+	address = fmt.Sprintf("%s%s-%d", scheme, name, served)
+	mu.Unlock()
+	l, err := Listen(address)
+	if err != nil {
+		return "", nil, err
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn)
+		}
+	}()
+	return address, func() { l.Close() }, nil
+}
+
+// DialRPC connects an RPC client to address, in memory if it is an in-memory address and over TCP otherwise.
+func DialRPC(address string) (*rpc.Client, error) {
+	conn, err := DialContext(context.Background(), address)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.NewClient(conn), nil
+}
@@ -0,0 +1,84 @@
+package memnet
+
+import (
+	"net/rpc"
+	"testing"
+	"time"
+)
+
+// Echo is an RPC service that answers with what it was sent.
+type Echo struct{}
+
+func (Echo) Say(req string, res *string) error {
+	*res = req
+	return nil
+}
+
+// TestRPC checks an RPC server and client can talk over an in-memory address, and that the address can't be
+// dialled once its listener is closed.
+func TestRPC(t *testing.T) {
+	l, err := Listen("mem:echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen("mem:echo"); err == nil {
+		t.Error("two listeners were given the same address")
+	}
+	server := rpc.NewServer()
+	if err := server.Register(Echo{}); err != nil {
+		t.Fatal(err)
+	}
+	go server.Accept(l)
+
+	conn, err := DialTimeout("mem:echo", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.NewClient(conn)
+	defer client.Close()
+	var reply string
+	if err := client.Call("Echo.Say", "hello", &reply); err != nil || reply != "hello" {
+		t.Errorf("got %q, %v, expected the greeting back", reply, err)
+	}
+
+	l.Close()
+	if _, err := DialTimeout("mem:echo", time.Second); err == nil {
+		t.Error("dialled a closed listener")
+	}
+	if err := client.Call("Echo.Say", "again", &reply); err != nil {
+		t.Errorf("a connection accepted before closing the listener broke: %v", err)
+	}
+	if _, err := Listen("tcp:echo"); err == nil {
+		t.Error("listened in memory on an address that isn't an in-memory one")
+	}
+}
+
+// TestServeRPC checks each receiver ServeRPC serves gets an address of its own, which stops answering once stopped.
+func TestServeRPC(t *testing.T) {
+	first, stopFirst, err := ServeRPC("Echo", Echo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, stopSecond, err := ServeRPC("Echo", Echo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopSecond()
+	if first == second {
+		t.Fatalf("both receivers were served on %s", first)
+	}
+
+	client, err := DialRPC(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var reply string
+	if err := client.Call("Echo.Say", "hello", &reply); err != nil || reply != "hello" {
+		t.Errorf("got %q, %v, expected the greeting back", reply, err)
+	}
+	stopFirst()
+	if _, err := DialRPC(first); err == nil {
+		t.Error("dialled a receiver that had been stopped")
+	}
+}
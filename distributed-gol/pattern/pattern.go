@@ -0,0 +1,177 @@
+// Package pattern finds small patterns, such as a glider, in a world, e.g. so a run can be paused when one appears.
+package pattern
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Parse reads a pattern written as its rows separated by '/', with 'O' or '*' for an alive cell and '.' for a dead
+// one, e.g. ".O./..O/OOO" for a glider. Rows shorter than the longest are padded with dead cells.
+func Parse(s string) (slab.World, error) {
+	rows := strings.Split(strings.TrimSpace(s), "/")
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	if width == 0 {
+		return slab.World{}, fmt.Errorf("pattern %q has no cells", s)
+	}
+	p := slab.New(width, len(rows))
+	for y, row := range rows {
+		for x, c := range row {
+			switch c {
+			case 'O', 'o', '*':
+				p.Set(x, y, util.Alive)
+			case '.':
+			default:
+				return slab.World{}, fmt.Errorf("pattern %q has %q in row %d, expected 'O' or '.'", s, c, y+1)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Orientations returns the distinct rotations and reflections of a pattern, starting with the pattern itself.
+// A glider has 8, a blinker 2 and a block only 1.
+func Orientations(p slab.World) []slab.World {
+	var distinct []slab.World
+	add := func(o slab.World) {
+		for _, d := range distinct {
+			if d.Equal(o) {
+				return
+			}
+		}
+		distinct = append(distinct, o)
+	}
+	for _, o := range []slab.World{p, flip(p)} {
+		for i := 0; i < 4; i++ {
+			add(o)
+			o = rotate(o)
+		}
+	}
+	return distinct
+}
+
+// rotate returns a pattern turned a quarter clockwise.
+func rotate(p slab.World) slab.World {
+	r := slab.New(p.Height, p.Width)
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			r.Set(p.Height-1-y, x, p.At(x, y))
+		}
+	}
+	return r
+}
+
+// flip returns a pattern mirrored left to right.
+func flip(p slab.World) slab.World {
+	f := slab.New(p.Width, p.Height)
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			f.Set(p.Width-1-x, y, p.At(x, y))
+		}
+	}
+	return f
+}
+
+// wordBits is the number of cells packed into each word of a Board's rows.
+const wordBits = 64
+
+// Board is a world packed 64 cells to a word, so a pattern can be compared with 64 places in a row at once.
+// Packing it once lets several patterns, such as every orientation of one, be searched for without packing again.
+type Board struct {
+	width, height int
+	wrap          bool
+	// Each row's cells, one bit each in order from bit 0 of word 0. With wrap a row carries on with all but the
+	// last of its own cells again, so a pattern straddling the edge reads across them as if they were adjacent.
+	rows [][]uint64
+}
+
+// Pack packs a world into a Board. With wrap, patterns may straddle the edges, as on a torus.
+func Pack(world slab.World, wrap bool) *Board {
+	b := &Board{width: world.Width, height: world.Height, wrap: wrap, rows: make([][]uint64, world.Height)}
+	length := world.Width
+	if wrap && length > 0 {
+		length += world.Width - 1
+	}
+	words := (length + wordBits - 1) / wordBits
+	for y := range b.rows {
+		b.rows[y] = make([]uint64, words)
+		row := world.Row(y)
+		for x := 0; x < length; x++ {
+			if row[x%world.Width] != 0 {
+				b.rows[y][x/wordBits] |= 1 << uint(x%wordBits)
+			}
+		}
+	}
+	return b
+}
+
+// bitsAt returns the 64 cells of a packed row starting with cell x, in bits 0 to 63, with any beyond the row dead.
+func bitsAt(row []uint64, x int) uint64 {
+	k, shift := x/wordBits, uint(x%wordBits)
+	if k >= len(row) {
+		return 0
+	}
+	bits := row[k] >> shift
+	if shift > 0 && k+1 < len(row) {
+		bits |= row[k+1] << (wordBits - shift)
+	}
+	return bits
+}
+
+// Find returns the top left corner of each place p appears on the board, with every cell within p's bounds as it
+// is in p. Cells beyond the bounds aren't looked at, so a pattern that must stand alone, such as a blinker rather
+// than part of a longer line, is written with a border of dead cells. At most limit places are returned, in row
+// order, or all of them if limit is 0.
+//
+// Each of p's cells is compared with the cell the same distance from 64 top left corners at once, by ANDing the
+// packed row shifted by that distance, or its complement for a dead cell, into a mask of the corners still matching.
+func (b *Board) Find(p slab.World, limit int) []util.Cell {
+	if p.Width == 0 || p.Height == 0 || p.Width > b.width || p.Height > b.height {
+		return nil
+	}
+	maxX, maxY := b.width-p.Width, b.height-p.Height
+	if b.wrap {
+		maxX, maxY = b.width-1, b.height-1
+	}
+	corners := maxX + 1
+	var found []util.Cell
+	for y := 0; y <= maxY; y++ {
+		for k := 0; k*wordBits < corners; k++ {
+			mask := ^uint64(0)
+			if left := corners - k*wordBits; left < wordBits {
+				mask = 1<<uint(left) - 1
+			}
+			for j := 0; j < p.Height && mask != 0; j++ {
+				row := b.rows[(y+j)%b.height]
+				for i, cell := range p.Row(j) {
+					if cell != 0 {
+						mask &= bitsAt(row, k*wordBits+i)
+					} else {
+						mask &^= bitsAt(row, k*wordBits+i)
+					}
+				}
+			}
+			for ; mask != 0; mask &= mask - 1 {
+				found = append(found, util.Cell{X: k*wordBits + bits.TrailingZeros64(mask), Y: y})
+				if len(found) == limit {
+					return found
+				}
+			}
+		}
+	}
+	return found
+}
+
+// Find returns where p appears in world, as Board.Find does. With wrap, p may also straddle the edges, as on a torus.
+func Find(world, p slab.World, wrap bool, limit int) []util.Cell {
+	return Pack(world, wrap).Find(p, limit)
+}
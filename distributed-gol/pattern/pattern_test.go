@@ -0,0 +1,149 @@
+package pattern
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestParse checks patterns are read row by row, padded with dead cells, and that other characters are refused.
+func TestParse(t *testing.T) {
+	p, err := Parse(".O/..O/OOO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Width != 3 || p.Height != 3 || !reflect.DeepEqual(p.Rows(), [][]byte{{0, 255, 0}, {0, 0, 255}, {255, 255, 255}}) {
+		t.Errorf("got %+v for a glider", p)
+	}
+	for _, bad := range []string{"", ".O/x.."} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("parsed %q", bad)
+		}
+	}
+}
+
+// TestOrientations checks each pattern has as many distinct orientations as its symmetry allows.
+func TestOrientations(t *testing.T) {
+	for s, expected := range map[string]int{".O./..O/OOO": 8, "OOO": 2, "OO/OO": 1, "OO./O.O/.O.": 4} {
+		p, _ := Parse(s)
+		if got := len(Orientations(p)); got != expected {
+			t.Errorf("%s has %d orientations, expected %d", s, got, expected)
+		}
+	}
+}
+
+// TestFind checks a pattern is found only where every cell in its bounds matches, across the edges only with wrap,
+// and that the search stops at the limit.
+func TestFind(t *testing.T) {
+	world := slab.New(8, 6)
+	// One blinker at (1, 1), and another wrapping round from (7, 4).
+	for _, cell := range []util.Cell{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 3, Y: 1}, {X: 7, Y: 4}, {X: 0, Y: 4}, {X: 1, Y: 4}} {
+		world.Set(cell.X, cell.Y, util.Alive)
+	}
+	blinker, _ := Parse("OOO")
+
+	if got := Find(world, blinker, false, 0); !reflect.DeepEqual(got, []util.Cell{{X: 1, Y: 1}}) {
+		t.Errorf("found %v without wrapping, expected (1, 1)", got)
+	}
+	if got := Find(world, blinker, true, 0); !reflect.DeepEqual(got, []util.Cell{{X: 1, Y: 1}, {X: 7, Y: 4}}) {
+		t.Errorf("found %v wrapping, expected (1, 1) and (7, 4)", got)
+	}
+	if got := Find(world, blinker, true, 1); len(got) != 1 {
+		t.Errorf("found %v with a limit of 1", got)
+	}
+
+	// With a dead border, only a blinker standing alone is found, not three of a line of four.
+	world.Set(4, 1, util.Alive)
+	alone, _ := Parse("...../.OOO./.....")
+	if got := Find(world, alone, false, 0); len(got) != 0 {
+		t.Errorf("found %v in a line of four", got)
+	}
+	if got := Find(world, alone, true, 0); !reflect.DeepEqual(got, []util.Cell{{X: 6, Y: 3}}) {
+		t.Errorf("found %v wrapping, expected the blinker at (7, 4) with its border from (6, 3)", got)
+	}
+	if got := Find(world, slab.New(9, 1), true, 0); got != nil {
+		t.Errorf("found %v for a pattern wider than the world", got)
+	}
+}
+
+// randomWorld returns a world of the given size with roughly a third of its cells alive.
+func randomWorld(width, height int, seed int64) slab.World {
+	random := rand.New(rand.NewSource(seed))
+	world := slab.New(width, height)
+	for i := range world.Cells {
+		if random.Intn(3) == 0 {
+			world.Cells[i] = util.Alive
+		}
+	}
+	return world
+}
+
+// findEach looks for p at every corner in turn, one cell at a time, for the packed search to be checked against.
+func findEach(world, p slab.World, wrap bool) []util.Cell {
+	maxX, maxY := world.Width-p.Width, world.Height-p.Height
+	if wrap {
+		maxX, maxY = world.Width-1, world.Height-1
+	}
+	var found []util.Cell
+	for y := 0; y <= maxY; y++ {
+		for x := 0; x <= maxX; x++ {
+			matches := true
+			for j := 0; j < p.Height && matches; j++ {
+				for i := 0; i < p.Width && matches; i++ {
+					matches = world.At((x+i)%world.Width, (y+j)%world.Height) == p.At(i, j)
+				}
+			}
+			if matches {
+				found = append(found, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return found
+}
+
+// TestFindPacked checks the packed search finds the same places as looking at each corner in turn, on worlds
+// whose rows don't fill their last word and, when wrapping, patterns read across a word boundary.
+func TestFindPacked(t *testing.T) {
+	corner, _ := Parse("OO/O.")
+	for _, size := range [][2]int{{5, 4}, {64, 3}, {70, 9}, {130, 17}} {
+		world := randomWorld(size[0], size[1], int64(size[0]))
+		for _, wrap := range []bool{false, true} {
+			board := Pack(world, wrap)
+			for _, p := range Orientations(corner) {
+				if got, expected := board.Find(p, 0), findEach(world, p, wrap); !reflect.DeepEqual(got, expected) {
+					t.Errorf("%dx%d wrap=%v: found %v, expected %v", size[0], size[1], wrap, got, expected)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkFind compares the packed search with looking at each corner in turn, for every orientation of a glider.
+func BenchmarkFind(b *testing.B) {
+	glider, _ := Parse(".O./..O/OOO")
+	orientations := Orientations(glider)
+	for _, size := range []int{64, 512} {
+		world := randomWorld(size, size, 1)
+		b.Run(fmt.Sprintf("packed/%dx%d", size, size), func(b *testing.B) {
+			b.SetBytes(int64(size * size)) // Reported in cells per second.
+			for i := 0; i < b.N; i++ {
+				board := Pack(world, true)
+				for _, p := range orientations {
+					board.Find(p, 0)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("each/%dx%d", size, size), func(b *testing.B) {
+			b.SetBytes(int64(size * size))
+			for i := 0; i < b.N; i++ {
+				for _, p := range orientations {
+					findEach(world, p, true)
+				}
+			}
+		})
+	}
+}
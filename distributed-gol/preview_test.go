@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestPreview checks a preview that settles down is reported with a PreviewComplete event and ends the run
+// without sending it to the broker, which the test doesn't start.
+func TestPreview(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Threads: 2, Turns: 100, Preview: 10000, PreviewScale: 4}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+
+	var received []gol.Event
+	timeout := time.After(5 * time.Second)
+	for open := true; open; {
+		select {
+		case event, ok := <-events:
+			if ok {
+				received = append(received, event)
+			}
+			open = ok
+		case <-timeout:
+			t.Fatalf("events channel wasn't closed, received %v", received)
+		}
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("expected a PreviewComplete and a StateChange, received %v", received)
+	}
+	preview, ok := received[0].(gol.PreviewComplete)
+	if !ok {
+		t.Fatalf("expected a PreviewComplete, received %#v", received[0])
+	}
+	// A 16x16 world is too small to shrink, so the preview is the run itself, which settles into a cycle.
+	if preview.Scale != 1 || preview.Interesting() || preview.Submitted {
+		t.Errorf("expected an unshrunk preview that settled and wasn't submitted, received %v", preview)
+	}
+	if len(preview.Population) != preview.Turns+1 {
+		t.Errorf("got %d populations for %d turns", len(preview.Population), preview.Turns)
+	}
+	if s, ok := received[1].(gol.StateChange); !ok || s.NewState != gol.Quitting {
+		t.Errorf("expected StateChange{Quitting}, received %#v", received[1])
+	}
+}
@@ -0,0 +1,42 @@
+package sdl
+
+import (
+	"fmt"
+	"time"
+)
+
+// boardStatsInterval is how often the title bar is updated with the board's statistics, so they can be read.
+const boardStatsInterval = time.Second
+
+// boardStats is the turn, alive count and speed of the run last put in the title bar, so they show in screenshots
+// and recordings of the window as well as on the console.
+type boardStats struct {
+	shown     time.Time // When the statistics were last put in the title bar.
+	shownTurn int       // Turn shown then, which the speed is measured from.
+	text      string    // The statistics last shown, kept so status messages can be shown alongside them.
+}
+
+// describeBoard describes the board at turn for the title bar, with alive cells and how many turns a second have been
+// completed over elapsed.
+func describeBoard(turn, alive, turns int, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return fmt.Sprintf("turn %d, %d alive", turn, alive)
+	}
+	return fmt.Sprintf("turn %d, %d alive, %.1f gens/s", turn, alive, float64(turns)/elapsed.Seconds())
+}
+
+// TurnCounted puts the turn just shown, the cells alive in it and the turns completed per second in the title bar,
+// at most once every boardStatsInterval, so counting the alive cells costs little even for fast runs.
+func (w *Window) TurnCounted(turn int) {
+	now := time.Now()
+	if !w.board.shown.IsZero() && now.Sub(w.board.shown) < boardStatsInterval {
+		return
+	}
+	var elapsed time.Duration
+	if !w.board.shown.IsZero() && turn >= w.board.shownTurn {
+		elapsed = now.Sub(w.board.shown)
+	}
+	w.board.text = describeBoard(turn, w.CountPixels(), turn-w.board.shownTurn, elapsed)
+	w.board.shown, w.board.shownTurn = now, turn
+	w.showTitle()
+}
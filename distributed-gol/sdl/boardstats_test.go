@@ -0,0 +1,29 @@
+package sdl
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTurnCounted checks the turn and alive count reach the title bar straight away, and the speed once a second
+// has passed to measure it over, while turns in between leave the title alone.
+func TestTurnCounted(t *testing.T) {
+	display := &titleDisplay{}
+	w := &Window{Width: 4, Height: 4, display: display, pixels: make([]byte, 4*4*4)}
+	w.SetPixel(1, 1)
+	w.SetPixel(2, 3)
+	w.TurnCounted(10)
+	if display.title != "GOL GUI - turn 10, 2 alive" {
+		t.Fatalf("title is %q, expected the turn and alive count", display.title)
+	}
+	w.TurnCounted(11)
+	if display.title != "GOL GUI - turn 10, 2 alive" {
+		t.Errorf("title is %q straight after the last update, expected it unchanged", display.title)
+	}
+
+	w.board.shown = w.board.shown.Add(-2 * time.Second) // As if turn 10 was shown two seconds ago.
+	w.TurnCounted(110)
+	if display.title != "GOL GUI - turn 110, 2 alive, 50.0 gens/s" {
+		t.Errorf("title is %q, expected 100 turns over two seconds", display.title)
+	}
+}
@@ -0,0 +1,190 @@
+package sdl
+
+import (
+	"fmt"
+	"sort"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// MouseAction is what the mouse did in a MouseEvent.
+type MouseAction int
+
+const (
+	MouseDown MouseAction = iota // A button was pressed.
+	MouseDrag                    // The pointer moved with a button held.
+	MouseUp                      // The button was released.
+)
+
+// MouseEvent is the mouse pressed, dragged or released over the board, in the window or in a browser watching the
+// stream, at the cell under the pointer, which may be off the board while dragging.
+type MouseEvent struct {
+	X, Y   int
+	Action MouseAction
+	Erase  bool // Whether it is the right button, which draws dead cells, rather than the left, which draws alive ones.
+}
+
+// Shape is how a stroke of the mouse is drawn.
+type Shape int
+
+const (
+	Freehand  Shape = iota // Every cell the pointer passes over.
+	Line                   // A straight line from where the button was pressed to where it was released.
+	Rectangle              // The outline of the rectangle with those two corners.
+)
+
+func (s Shape) String() string {
+	return [...]string{"freehand", "line", "rectangle"}[s]
+}
+
+// brushSizes are the widths of the square brushes the Brush action cycles through.
+var brushSizes = []int{1, 3, 5}
+
+// brush draws cells on the board with the mouse. A stroke is sent to the distributor in one go once the button is
+// released, as 'c', "alive" or "dead", the cells as x,y pairs and a newline, so the broker sets every cell of it
+// at once. The broker only sets cells while the run is paused, and refuses them otherwise.
+type brush struct {
+	size   int         // Index into brushSizes.
+	shape  Shape       // How strokes are drawn.
+	active bool        // Whether a button is held.
+	erase  bool        // Whether the stroke draws dead cells.
+	from   util.Cell   // Cell the stroke started at.
+	last   util.Cell   // Cell the pointer was last seen over.
+	path   []util.Cell // Cells the pointer has passed over in a freehand stroke, before the brush is applied.
+}
+
+// String describes the brush, e.g. for the title bar.
+func (b *brush) String() string {
+	size := brushSizes[b.size]
+	return fmt.Sprintf("brush %dx%d %s (left button draws, right erases, while paused)", size, size, b.shape)
+}
+
+// nextSize switches to the next brush size, going back to the smallest after the largest.
+func (b *brush) nextSize() {
+	b.size = (b.size + 1) % len(brushSizes)
+}
+
+// nextShape switches to the next shape, going back to freehand after the rectangle.
+func (b *brush) nextShape() {
+	b.shape = (b.shape + 1) % (Rectangle + 1)
+}
+
+// mouse follows a stroke as the mouse is pressed, dragged and released, and sends the cells drawn once it is
+// released. While a line or rectangle is dragged out, where it runs from and to is shown in the title bar.
+func (b *brush) mouse(in *input, e MouseEvent) {
+	at := util.Cell{X: e.X, Y: e.Y}
+	switch e.Action {
+	case MouseDown:
+		b.active, b.erase, b.from, b.last, b.path = true, e.Erase, at, at, []util.Cell{at}
+	case MouseDrag:
+		if !b.active {
+			return
+		}
+		if b.shape == Freehand && at != b.last {
+			// The pointer can skip cells when moved quickly, so the gap is filled with a line.
+			b.path = append(b.path, line(b.last, at)[1:]...)
+		}
+		b.last = at
+	case MouseUp:
+		if !b.active {
+			return
+		}
+		b.active, b.last = false, at
+		in.setStatus("")
+		b.send(in, b.cells(int(in.w.Width), int(in.w.Height)))
+		return
+	}
+	if b.shape != Freehand {
+		in.setStatus(fmt.Sprintf("%s from %d,%d to %d,%d", b.shape, b.from.X, b.from.Y, b.last.X, b.last.Y))
+	}
+}
+
+// cells returns the cells of a width x height board the stroke covers, with the brush applied to every point of
+// its shape, in row order without duplicates.
+func (b *brush) cells(width, height int) []util.Cell {
+	var points []util.Cell
+	switch b.shape {
+	case Freehand:
+		points = b.path
+	case Line:
+		points = line(b.from, b.last)
+	case Rectangle:
+		corners := []util.Cell{b.from, {X: b.last.X, Y: b.from.Y}, b.last, {X: b.from.X, Y: b.last.Y}, b.from}
+		for i := 0; i < 4; i++ {
+			points = append(points, line(corners[i], corners[i+1])...)
+		}
+	}
+
+	size := brushSizes[b.size]
+	covered := make(map[util.Cell]bool)
+	for _, point := range points {
+		for y := point.Y - size/2; y <= point.Y+size/2; y++ {
+			for x := point.X - size/2; x <= point.X+size/2; x++ {
+				if x >= 0 && y >= 0 && x < width && y < height {
+					covered[util.Cell{X: x, Y: y}] = true
+				}
+			}
+		}
+	}
+	cells := make([]util.Cell, 0, len(covered))
+	for cell := range covered {
+		cells = append(cells, cell)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		return cells[i].Y < cells[j].Y || cells[i].Y == cells[j].Y && cells[i].X < cells[j].X
+	})
+	return cells
+}
+
+// send sends the distributor the cells of a stroke, if any are on the board.
+func (b *brush) send(in *input, cells []util.Cell) {
+	if len(cells) == 0 {
+		return
+	}
+	state := "alive"
+	if b.erase {
+		state = "dead"
+	}
+	in.keyPresses <- 'c'
+	for _, c := range state {
+		in.keyPresses <- c
+	}
+	for _, cell := range cells {
+		for _, c := range fmt.Sprintf(" %d,%d", cell.X, cell.Y) {
+			in.keyPresses <- c
+		}
+	}
+	in.keyPresses <- '\n'
+}
+
+// line returns the cells on the straight line from one cell to another, both included, using Bresenham's algorithm.
+func line(from, to util.Cell) []util.Cell {
+	dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+	sx, sy := 1, 1
+	if to.X < from.X {
+		sx = -1
+	}
+	if to.Y < from.Y {
+		sy = -1
+	}
+	cells := []util.Cell{from}
+	for at, e := from, dx+dy; at != to; cells = append(cells, at) {
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			at.X += sx
+		}
+		if e2 <= dx {
+			e += dx
+			at.Y += sy
+		}
+	}
+	return cells
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
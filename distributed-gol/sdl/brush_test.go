@@ -0,0 +1,63 @@
+package sdl
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestBrush draws strokes with each shape and size, checking each is sent to the distributor in one go once the
+// button is released, with the brush applied to every point and cells off the board left out.
+func TestBrush(t *testing.T) {
+	display := &titleDisplay{}
+	keyPresses := make(chan rune, 1024)
+	in := &input{w: &Window{Width: 8, Height: 8, display: display}, bindings: DefaultBindings(), keyPresses: keyPresses}
+	sent := func() string {
+		var text strings.Builder
+		for len(keyPresses) > 0 {
+			text.WriteRune(<-keyPresses)
+		}
+		return text.String()
+	}
+	stroke := func(events ...MouseEvent) string {
+		for _, e := range events {
+			in.brush.mouse(in, e)
+		}
+		return sent()
+	}
+
+	tests := []struct {
+		keys     string // Pressed before the stroke, to pick the brush.
+		stroke   []MouseEvent
+		expected string
+	}{
+		// A quick drag skips cells, which are filled in.
+		{"", []MouseEvent{{X: 1, Y: 1, Action: MouseDown}, {X: 4, Y: 1, Action: MouseDrag}, {X: 4, Y: 1, Action: MouseUp}},
+			"calive 1,1 2,1 3,1 4,1\n"},
+		{"", []MouseEvent{{X: 2, Y: 2, Action: MouseDown, Erase: true}, {X: 2, Y: 2, Action: MouseUp}}, "cdead 2,2\n"},
+		// Only where the line ends up counts, not where it was dragged on the way.
+		{"d", []MouseEvent{{X: 0, Y: 0, Action: MouseDown}, {X: 7, Y: 7, Action: MouseDrag}, {X: 3, Y: 0, Action: MouseDrag},
+			{X: 3, Y: 0, Action: MouseUp}}, "calive 0,0 1,0 2,0 3,0\n"},
+		{"d", []MouseEvent{{X: 1, Y: 1, Action: MouseDown}, {X: 3, Y: 3, Action: MouseUp}},
+			"calive 1,1 2,1 3,1 1,2 3,2 1,3 2,3 3,3\n"},
+		// A 3x3 brush at the corner of the board only covers the cells on it.
+		{"bd", []MouseEvent{{X: 0, Y: 0, Action: MouseDown}, {X: 0, Y: 0, Action: MouseUp}}, "calive 0,0 1,0 0,1 1,1\n"},
+		{"", []MouseEvent{{X: -5, Y: -5, Action: MouseDown}, {X: -3, Y: -3, Action: MouseUp}}, ""},
+	}
+	for _, test := range tests {
+		for _, key := range test.keys {
+			in.key(Key{Sym: Keycode(key)})
+		}
+		if got := stroke(test.stroke...); got != test.expected {
+			t.Errorf("%s stroke %v sent %q, expected %q", &in.brush, test.stroke, got, test.expected)
+		}
+	}
+
+	in.key(Key{Sym: 'b'})
+	if in.brush.String() != "brush 5x5 freehand (left button draws, right erases, while paused)" ||
+		display.title != "GOL GUI - "+in.brush.String() {
+		t.Errorf("title is %q after switching to the largest brush", display.title)
+	}
+	if got := stroke(MouseEvent{X: 4, Y: 4, Action: MouseDrag}, MouseEvent{X: 4, Y: 4, Action: MouseUp}); got != "" {
+		t.Errorf("dragging without pressing a button sent %q", got)
+	}
+}
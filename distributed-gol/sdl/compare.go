@@ -0,0 +1,73 @@
+package sdl
+
+import (
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// compareGap is the width in pixels of the grey bar between the two halves of a comparison.
+const compareGap = 4
+
+// RunCompare shows the two worlds of a comparison run side by side in one window until the final turn, the first
+// engine's on the left. A cell the engines disagree on is drawn red in both halves, so a divergence stands out
+// however busy the board is, and the title bar shows how long each engine took over the latest turn.
+func RunCompare(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune, bindings Bindings) {
+	if bindings == nil {
+		bindings = DefaultBindings()
+	}
+	w := NewWindow(int32(2*p.ImageWidth+compareGap), int32(p.ImageHeight))
+	in := &input{w: w, bindings: bindings, keyPresses: keyPresses}
+
+compareLoop:
+	for {
+		in.poll()
+		select {
+		case event, ok := <-events:
+			if !ok {
+				w.Destroy()
+				break compareLoop
+			}
+			switch e := event.(type) {
+			case gol.CompareTurn:
+				w.drawComparison(e.Worlds[0], e.Worlds[1])
+				w.RenderFrame()
+				w.SetStatus(fmt.Sprintf("turn %d - %v", e.CompletedTurns, e))
+			case gol.ErrorEvent:
+				fmt.Printf("Completed Turns %-8v%v\n", e.CompletedTurns, e)
+			case gol.FinalTurnComplete:
+				w.Destroy()
+				break compareLoop
+			}
+		default:
+		}
+	}
+}
+
+// drawComparison draws world a in the left half of the window and b in the right, with the cells they disagree on
+// in red in both, and the gap between them in grey.
+func (w *Window) drawComparison(a, b [][]byte) {
+	width := int(w.Width)
+	offset := (width + compareGap) / 2
+	set := func(x, y int, blue, green, red byte) {
+		// ARGB8888 pixels are stored blue, green, red, alpha.
+		i := 4 * (y*width + x)
+		w.pixels[i], w.pixels[i+1], w.pixels[i+2], w.pixels[i+3] = blue, green, red, 0xFF
+	}
+	for y := range a {
+		for x := range a[y] {
+			var blue, green, red byte
+			if a[y][x] != b[y][x] {
+				red = 0xFF
+			} else if a[y][x] == util.Alive {
+				blue, green, red = 0xFF, 0xFF, 0xFF
+			}
+			set(x, y, blue, green, red)
+			set(offset+x, y, blue, green, red)
+		}
+		for x := len(a[y]); x < offset; x++ {
+			set(x, y, 0x80, 0x80, 0x80)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !cgo
+// +build !cgo
+
+package sdl
+
+// newDisplay returns an MJPEG stream, as SDL needs cgo and this build has none.
+func newDisplay() display {
+	return newStreamDisplay()
+}
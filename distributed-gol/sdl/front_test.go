@@ -0,0 +1,97 @@
+package sdl
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// frameDisplay is a display that keeps every frame presented to it, and its title.
+type frameDisplay struct {
+	titleDisplay
+	frames [][]byte
+}
+
+func (d *frameDisplay) present(pixels []byte) {
+	d.frames = append(d.frames, append([]byte(nil), pixels...))
+}
+
+// TestFrontDisplay checks only the latest frame and title drawn since the last flush are shown, and that the window
+// reusing its buffer after presenting doesn't change the frame waiting to be shown.
+func TestFrontDisplay(t *testing.T) {
+	d := &frameDisplay{}
+	front := newFrontDisplay(d)
+	pixels := []byte{1, 2, 3, 4}
+	front.present(pixels)
+	pixels[0] = 5
+	front.present(pixels)
+	pixels[0] = 6 // Changed after presenting, as the window's own buffers are.
+	front.setTitle("GOL GUI - paused at turn 3")
+	select {
+	case <-front.ready:
+	default:
+		t.Fatal("presenting didn't signal the main thread")
+	}
+
+	front.flush()
+	if len(d.frames) != 1 || d.frames[0][0] != 5 || d.title != "GOL GUI - paused at turn 3" {
+		t.Fatalf("showed frames %v with title %q, expected only the second frame and the title", d.frames, d.title)
+	}
+	front.flush()
+	if len(d.frames) != 1 {
+		t.Errorf("flushing again showed %d frames, expected nothing new", len(d.frames)-1)
+	}
+	front.present([]byte{7, 8, 9, 10})
+	front.flush()
+	if len(d.frames) != 2 || d.frames[1][0] != 7 {
+		t.Errorf("showed frames %v, expected the third frame last", d.frames)
+	}
+}
+
+// TestConsumeBurst types into the turn prompt in the middle of a burst of flipped cells, checking the title bar
+// shows what was typed before the burst has been drained, and the consumer finishes with the window at the final
+// turn.
+func TestConsumeBurst(t *testing.T) {
+	display := &titleDisplay{}
+	w := &Window{Width: 64, Height: 64, display: display, pixels: make([]byte, 64*64*4)}
+	keyPresses := make(chan rune, 32)
+	in := &input{w: w, bindings: DefaultBindings(), keyPresses: keyPresses, ops: make(chan func()), done: make(chan struct{})}
+	events := make(chan gol.Event)
+	go consume(w, events, in.ops, in.done)
+
+	// A burst far longer than it takes to type into the prompt.
+	const flips = 100000
+	var sent int64
+	go func() {
+		for i := 0; i < flips; i++ {
+			events <- gol.CellFlipped{CompletedTurns: 1, Cell: util.Cell{X: i % 64, Y: i / 64 % 64}}
+			atomic.AddInt64(&sent, 1)
+		}
+		events <- gol.FinalTurnComplete{CompletedTurns: 1}
+	}()
+	in.key(Key{Sym: 'g'})
+	in.key(Key{Sym: '7'})
+	// The consumer makes changes in order, so once it has taken this one the title has been set.
+	shown := make(chan string, 1)
+	in.view(func() { shown <- display.title })
+
+	select {
+	case title := <-shown:
+		if title != "GOL GUI - run until turn 7_ (return to run, escape to cancel)" {
+			t.Errorf("title bar shows %q", title)
+		}
+		if atomic.LoadInt64(&sent) >= flips {
+			t.Errorf("the prompt was shown after all %d flips", flips)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the prompt was never shown")
+	}
+	select {
+	case <-in.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the consumer didn't finish at the final turn")
+	}
+}
@@ -0,0 +1,24 @@
+package sdl
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestShowInitialBoard checks the starting world replaces whatever was drawn.
+func TestShowInitialBoard(t *testing.T) {
+	w := &Window{Width: 4, Height: 4, display: &titleDisplay{}, pixels: make([]byte, 4*4*4)}
+	w.SetPixel(3, 3) // Left over from before, so it must be cleared.
+	alive := []util.Cell{{X: 0, Y: 0}, {X: 2, Y: 1}}
+
+	w.ShowInitialBoard(alive)
+	if count := w.CountPixels(); count != len(alive) {
+		t.Fatalf("%d cells drawn, expected %d", count, len(alive))
+	}
+	for _, cell := range alive {
+		if w.pixels[4*(cell.Y*4+cell.X)] != 0xFF {
+			t.Errorf("cell %v isn't drawn", cell)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !cgo
+// +build !cgo
+
+package sdl
+
+// input turns the keys pressed and mouse strokes in a browser watching the stream into actions.
+type input struct {
+	w          *Window
+	bindings   Bindings
+	keyPresses chan<- rune
+	prompt     turnPrompt    // Open while a turn to run until is being typed.
+	rule       rulePrompt    // Open while a rule or edge mode to switch to is being typed.
+	brush      brush         // Draws the cells the mouse is dragged over while paused.
+	ops        chan func()   // Changes to the window for the consumer to make, or nil to make them straight away.
+	done       chan struct{} // Closed once the consumer has finished with the window.
+}
+
+// poll handles the next key pressed or mouse event, reporting false if there wasn't one.
+func (in *input) poll() bool {
+	event := in.w.PollEvent()
+	switch e := event.(type) {
+	case KeyEvent:
+		in.key(e.Key)
+	case MouseEvent:
+		in.brush.mouse(in, e)
+	}
+	return event != nil
+}
@@ -0,0 +1,60 @@
+//go:build cgo
+// +build cgo
+
+package sdl
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// input turns the keys pressed and mouse strokes in the window, or in a browser watching the stream, into actions.
+type input struct {
+	w          *Window
+	bindings   Bindings
+	keyPresses chan<- rune
+	prompt     turnPrompt    // Open while a turn to run until is being typed.
+	rule       rulePrompt    // Open while a rule or edge mode to switch to is being typed.
+	brush      brush         // Draws the cells the mouse is dragged over while paused.
+	ops        chan func()   // Changes to the window for the consumer to make, or nil to make them straight away.
+	done       chan struct{} // Closed once the consumer has finished with the window.
+}
+
+// poll handles the next key pressed or mouse event, reporting false if there wasn't one.
+func (in *input) poll() bool {
+	event := in.w.PollEvent()
+	switch e := event.(type) {
+	case KeyEvent:
+		in.key(e.Key)
+	case MouseEvent:
+		in.brush.mouse(in, e)
+	case *sdl.KeyboardEvent:
+		in.key(Key{Sym: e.Keysym.Sym, Mod: modifiers(e.Keysym.Mod)})
+	case *sdl.MouseButtonEvent:
+		// The renderer's logical size makes SDL give the position in cells rather than in pixels of the window.
+		action := MouseDown
+		if e.Type == sdl.MOUSEBUTTONUP {
+			action = MouseUp
+		}
+		if e.Button == sdl.BUTTON_LEFT || e.Button == sdl.BUTTON_RIGHT {
+			in.brush.mouse(in, MouseEvent{X: int(e.X), Y: int(e.Y), Action: action, Erase: e.Button == sdl.BUTTON_RIGHT})
+		}
+	case *sdl.MouseMotionEvent:
+		if e.State&(sdl.ButtonLMask()|sdl.ButtonRMask()) != 0 {
+			in.brush.mouse(in, MouseEvent{X: int(e.X), Y: int(e.Y), Action: MouseDrag})
+		}
+	}
+	return event != nil
+}
+
+// modifiers converts the modifier state of a keyboard event, ignoring the difference between left and right.
+func modifiers(mod uint16) Modifier {
+	var m Modifier
+	if mod&sdl.KMOD_SHIFT != 0 {
+		m |= Shift
+	}
+	if mod&sdl.KMOD_CTRL != 0 {
+		m |= Ctrl
+	}
+	if mod&sdl.KMOD_ALT != 0 {
+		m |= Alt
+	}
+	return m
+}
@@ -0,0 +1,64 @@
+//go:build !cgo
+// +build !cgo
+
+package sdl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keycode identifies a key. Without SDL the values still match SDL's keycodes, so bindings behave the same.
+type Keycode int32
+
+// Keys without a printable character, numbered as in SDL.
+const (
+	keyRight       Keycode = 0x4000004F
+	keyLeft        Keycode = 0x40000050
+	keyDown        Keycode = 0x40000051
+	keyUp          Keycode = 0x40000052
+	keyKeypadMinus Keycode = 0x40000056
+	keyKeypadPlus  Keycode = 0x40000057
+	keyF1          Keycode = 0x4000003A
+)
+
+// keyNames holds SDL's names for the keys without a printable character that can be bound without SDL.
+var keyNames = map[Keycode]string{
+	'\b':           "Backspace",
+	'\t':           "Tab",
+	'\r':           "Return",
+	0x1B:           "Escape",
+	' ':            "Space",
+	0x7F:           "Delete",
+	keyUp:          "Up",
+	keyDown:        "Down",
+	keyLeft:        "Left",
+	keyRight:       "Right",
+	keyKeypadPlus:  "Keypad +",
+	keyKeypadMinus: "Keypad -",
+}
+
+func init() {
+	// F1 to F12 are numbered consecutively.
+	for i := Keycode(0); i < 12; i++ {
+		keyNames[keyF1+i] = fmt.Sprintf("F%d", i+1)
+	}
+}
+
+// keyFromName returns the key with the given SDL name, or 0 if there is none. Names are not case sensitive.
+func keyFromName(name string) Keycode {
+	for key, keyName := range keyNames {
+		if strings.EqualFold(name, keyName) {
+			return key
+		}
+	}
+	return 0
+}
+
+// keyName returns the SDL name of a key.
+func keyName(key Keycode) string {
+	if name, ok := keyNames[key]; ok {
+		return name
+	}
+	return string(rune(key))
+}
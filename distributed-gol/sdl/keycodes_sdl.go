@@ -0,0 +1,19 @@
+//go:build cgo
+// +build cgo
+
+package sdl
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// Keycode identifies a key. With SDL it is SDL's own keycode, so keyboard events can be looked up directly.
+type Keycode = sdl.Keycode
+
+// keyFromName returns the key with the given SDL name, or 0 if there is none.
+func keyFromName(name string) Keycode {
+	return sdl.GetKeyFromName(name)
+}
+
+// keyName returns the SDL name of a key.
+func keyName(key Keycode) string {
+	return sdl.GetKeyName(key)
+}
@@ -0,0 +1,81 @@
+package sdl
+
+import (
+	"fmt"
+	"time"
+)
+
+// latencySamples is how many of the latest turns shown the latency in the title bar is averaged over.
+const latencySamples = 30
+
+// latencyInterval is how often the title bar is updated with the latency, so it can be read.
+const latencyInterval = time.Second
+
+// latencySample is how long one turn took to reach the screen.
+type latencySample struct {
+	total   time.Duration // From the distributor sending the turn's TurnComplete to its frame being shown.
+	network time.Duration // Fetching the turn's flipped cells from the broker, before the event was sent.
+	render  time.Duration // Drawing and presenting the frame, the part of total spent in the window.
+}
+
+// latency keeps the latest latencySamples samples, so a rolling average can be shown.
+type latency struct {
+	samples []latencySample
+	next    int       // Index of the oldest sample, replaced by the next one once there are latencySamples.
+	shown   time.Time // When the average was last put in the title bar.
+	text    string    // The average last shown, kept so status messages can be shown alongside it.
+}
+
+// add records a sample, replacing the oldest once there are latencySamples of them.
+func (l *latency) add(sample latencySample) {
+	if len(l.samples) < latencySamples {
+		l.samples = append(l.samples, sample)
+		return
+	}
+	l.samples[l.next] = sample
+	l.next = (l.next + 1) % latencySamples
+}
+
+// mean averages the samples kept.
+func (l *latency) mean() latencySample {
+	var sum latencySample
+	for _, sample := range l.samples {
+		sum.total += sample.total
+		sum.network += sample.network
+		sum.render += sample.render
+	}
+	if n := time.Duration(len(l.samples)); n > 0 {
+		sum.total /= n
+		sum.network /= n
+		sum.render /= n
+	}
+	return sum
+}
+
+// String describes a sample for the title bar. A slow broker or network shows up as network time, slow drawing
+// as render time, and the rest of the lag is the client's event queue; an engine that is behind shows up as
+// turns arriving slowly rather than as lag.
+func (s latencySample) String() string {
+	return fmt.Sprintf("lag %s (network %s, render %s)", milliseconds(s.total), milliseconds(s.network), milliseconds(s.render))
+}
+
+// milliseconds formats a duration in milliseconds to one decimal place.
+func milliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+// TurnShown records how long a turn took to reach the screen, given when its TurnComplete event was emitted, how
+// long fetching it from the broker took and how long rendering it took, and shows the average over the latest
+// turns in the title bar about once a second.
+func (w *Window) TurnShown(emitted time.Time, network, render time.Duration) {
+	if emitted.IsZero() {
+		return
+	}
+	now := time.Now()
+	w.latency.add(latencySample{total: network + now.Sub(emitted), network: network, render: render})
+	if now.Sub(w.latency.shown) >= latencyInterval {
+		w.latency.shown = now
+		w.latency.text = w.latency.mean().String()
+		w.showTitle()
+	}
+}
@@ -0,0 +1,40 @@
+package sdl
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLatencyMean checks the average only covers the latest latencySamples turns.
+func TestLatencyMean(t *testing.T) {
+	var l latency
+	for i := 0; i < latencySamples; i++ {
+		l.add(latencySample{total: 100 * time.Millisecond, network: 50 * time.Millisecond, render: 10 * time.Millisecond})
+	}
+	for i := 0; i < latencySamples; i++ {
+		l.add(latencySample{total: 8 * time.Millisecond, network: 4 * time.Millisecond, render: 2 * time.Millisecond})
+	}
+	if s := l.mean().String(); s != "lag 8.0ms (network 4.0ms, render 2.0ms)" {
+		t.Errorf("shown as %q, expected the older samples to have been replaced", s)
+	}
+}
+
+// TestTurnShown checks the latency is shown in the title bar alongside any status message, and that fetching
+// the turn from the broker counts towards it.
+func TestTurnShown(t *testing.T) {
+	display := &titleDisplay{}
+	w := &Window{display: display}
+	w.SetStatus("lost the broker")
+	w.TurnShown(time.Now(), 30*time.Millisecond, 5*time.Millisecond)
+	if !strings.HasPrefix(display.title, "GOL GUI - lost the broker - lag ") || !strings.HasSuffix(display.title, "(network 30.0ms, render 5.0ms)") {
+		t.Fatalf("title is %q, expected the status and the latency", display.title)
+	}
+	if lag := w.latency.samples[0].total; lag < 30*time.Millisecond {
+		t.Errorf("lag is %v, expected it to include the network's 30ms", lag)
+	}
+	w.SetStatus("")
+	if !strings.HasPrefix(display.title, "GOL GUI - lag ") {
+		t.Errorf("title is %q, expected the latency to stay once the status is cleared", display.title)
+	}
+}
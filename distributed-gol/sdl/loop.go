@@ -2,53 +2,153 @@ package sdl
 
 import (
 	"fmt"
-	"github.com/veandco/go-sdl2/sdl"
+	"time"
+
 	"uk.ac.bris.cs/gameoflife/gol"
 )
 
-func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) {
+// inputPollInterval is how long the main thread waits between checks for input while there is no new frame to show.
+const inputPollInterval = 5 * time.Millisecond
+
+// Run shows the world in a window until the final turn, turning key presses into actions with the given
+// bindings. A nil bindings uses DefaultBindings. The calling goroutine, which must be the main thread for SDL, polls
+// for input and shows the frames, while another drains the events and draws them, so neither a burst of flipped
+// cells nor typing into a prompt holds up the other.
+func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune, bindings Bindings) {
+	if bindings == nil {
+		bindings = DefaultBindings()
+	}
 	w := NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+	front := newFrontDisplay(w.display)
+	w.display = front
+	in := &input{w: w, bindings: bindings, keyPresses: keyPresses, ops: make(chan func()), done: make(chan struct{})}
+	// From here on only the consumer touches the window, and the main thread only its display.
+	go consume(w, events, in.ops, in.done)
 
-sdlLoop:
+	poll := time.NewTicker(inputPollInterval)
+	defer poll.Stop()
 	for {
-		event := w.PollEvent()
-		if event != nil {
-			switch e := event.(type) {
-			case *sdl.KeyboardEvent:
-				switch e.Keysym.Sym {
-				case sdl.K_p:
-					keyPresses <- 'p'
-				case sdl.K_s:
-					keyPresses <- 's'
-				case sdl.K_q:
-					keyPresses <- 'q'
-				case sdl.K_k:
-					keyPresses <- 'k'
-				}
-			}
+		for in.poll() {
+			// Handle every key waiting, so a word typed into a prompt arrives at once.
 		}
+		front.flush()
+		select {
+		case <-in.done:
+			front.flush()
+			w.Destroy()
+			return
+		case <-front.ready:
+		case <-poll.C:
+		}
+	}
+}
+
+// consume draws the events in the window until the final turn or until events is closed, making the changes to
+// the window asked for by the input on ops in between. It closes done once it has finished with the window.
+func consume(w *Window, events <-chan gol.Event, ops <-chan func(), done chan<- struct{}) {
+	defer close(done)
+	for {
 		select {
 		case event, ok := <-events:
-			if !ok {
-				w.Destroy()
-				break sdlLoop
-			}
-			switch e := event.(type) {
-			case gol.CellFlipped:
-				w.FlipPixel(e.Cell.X, e.Cell.Y)
-			case gol.TurnComplete:
-				w.RenderFrame()
-			case gol.FinalTurnComplete:
-				w.Destroy()
-				break sdlLoop
-			default:
-				if len(event.String()) > 0 {
-					fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
-				}
+			if !ok || !show(w, event) {
+				return
 			}
-		default:
-			break
+		case op := <-ops:
+			op()
+		}
+	}
+}
+
+// show draws an event in the window, reporting false once the final turn has come.
+func show(w *Window, event gol.Event) bool {
+	switch e := event.(type) {
+	case gol.InitialBoard:
+		w.ShowInitialBoard(e.Alive)
+	case gol.CellFlipped:
+		w.FlipCell(e.Cell.X, e.Cell.Y)
+	case gol.TurnComplete:
+		w.TurnComplete()
+		rendering := time.Now()
+		w.RenderFrame()
+		w.TurnShown(e.Emitted, e.Network, time.Since(rendering))
+		w.TurnCounted(e.CompletedTurns)
+	case gol.LiveViewShed:
+		fmt.Printf("Completed Turns %-8v%v\n", e.CompletedTurns, e)
+		w.ShowInitialBoard(e.Alive)
+	case gol.WorkerOwnership:
+		w.SetOwnership(e.Assignments)
+	case gol.ErrorEvent:
+		// Errors are shown in the title bar until the next one, as well as on the console.
+		fmt.Printf("Completed Turns %-8v%v\n", e.CompletedTurns, e)
+		w.SetStatus(e.String())
+	case gol.StateChange:
+		fmt.Printf("Completed Turns %-8v%v\n", e.CompletedTurns, e)
+		if e.NewState == gol.Paused {
+			w.Paused(e.CompletedTurns)
+		} else {
+			w.Resumed()
+		}
+	case gol.FinalTurnComplete:
+		return false
+	default:
+		if len(event.String()) > 0 {
+			fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
 		}
 	}
+	return true
+}
+
+// key carries out the action bound to a key, if there is one. While a turn to run until or a rule is being typed,
+// keys go to the prompt instead. Key presses for the distributor are sent straight away, while changes to the window
+// are left to the consumer.
+func (in *input) key(key Key) {
+	if in.prompt.open {
+		in.prompt.key(in, key)
+		return
+	}
+	if in.rule.open {
+		in.rule.key(in, key)
+		return
+	}
+	action, ok := in.bindings.Lookup(key)
+	if !ok {
+		return
+	}
+	if key, ok := action.KeyPress(); ok {
+		in.keyPresses <- key
+		return
+	}
+	switch action {
+	case Goto:
+		in.prompt.start(in)
+	case Rule:
+		in.rule.start(in)
+	case Brush:
+		in.brush.nextSize()
+		in.setStatus(in.brush.String())
+	case Draw:
+		in.brush.nextShape()
+		in.setStatus(in.brush.String())
+	default:
+		in.view(func() { perform(in.w, action, in.keyPresses) })
+	}
+}
+
+// view has the consumer make a change to the window between events, as only it may touch the window once Run has
+// started it. Without a consumer, as in the tests and RunCompare, the change is made straight away.
+func (in *input) view(op func()) {
+	if in.ops == nil {
+		op()
+		return
+	}
+	select {
+	case in.ops <- op:
+	case <-in.done:
+		// The window has gone, so there is nothing left to change.
+	}
+}
 
+// setStatus has the consumer put a message in the title bar.
+func (in *input) setStatus(message string) {
+	in.view(func() { in.w.SetStatus(message) })
 }
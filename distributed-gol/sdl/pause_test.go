@@ -0,0 +1,28 @@
+package sdl
+
+import "testing"
+
+// TestPaused checks the title only says the run has paused once the frame shows the whole turn, keeps any status
+// message, and loses the pause on resuming.
+func TestPaused(t *testing.T) {
+	display := &titleDisplay{}
+	w := &Window{Width: 4, Height: 4, display: display, pixels: make([]byte, 4*4*4)}
+	w.SetStatus("worker lost")
+	w.FlipCell(1, 1)
+	w.Paused(7)
+	if display.title != "GOL GUI - worker lost" {
+		t.Fatalf("title is %q part way through turn 7", display.title)
+	}
+	w.TurnComplete()
+	if display.title != "GOL GUI - paused at turn 7 - worker lost" {
+		t.Fatalf("title is %q once turn 7 completed, expected it to be paused there", display.title)
+	}
+	w.Resumed()
+	if display.title != "GOL GUI - worker lost" {
+		t.Errorf("title is %q after resuming", display.title)
+	}
+	w.Paused(9)
+	if display.title != "GOL GUI - paused at turn 9 - worker lost" {
+		t.Errorf("title is %q pausing between turns, expected it straight away", display.title)
+	}
+}
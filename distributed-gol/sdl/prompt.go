@@ -0,0 +1,94 @@
+package sdl
+
+import "strings"
+
+// turnPrompt reads the turn typed after the Goto action, showing it in the window's title bar as it is typed.
+type turnPrompt struct {
+	open   bool
+	digits string
+}
+
+// maxTurnDigits keeps the turn typed well within an int.
+const maxTurnDigits = 9
+
+// start opens the prompt with nothing typed yet.
+func (t *turnPrompt) start(in *input) {
+	t.open = true
+	t.digits = ""
+	t.show(in)
+}
+
+// key handles a key pressed while the prompt is open. Digits are typed and backspace deletes the last one.
+// Return closes the prompt and sends the distributor 'g', the digits typed and a newline, and escape closes it
+// without sending anything. Other keys are ignored, so they don't pause or quit the run by accident.
+func (t *turnPrompt) key(in *input, key Key) {
+	switch {
+	case key.Sym >= '0' && key.Sym <= '9' && len(t.digits) < maxTurnDigits:
+		t.digits += string(rune(key.Sym))
+	case key.Sym == '\b' && len(t.digits) > 0:
+		t.digits = t.digits[:len(t.digits)-1]
+	case key.Sym == '\r' || key.Sym == 0x1B:
+		t.open = false
+		in.setStatus("")
+		if key.Sym == '\r' && t.digits != "" {
+			in.keyPresses <- 'g'
+			for _, digit := range t.digits {
+				in.keyPresses <- digit
+			}
+			in.keyPresses <- '\n'
+		}
+		return
+	}
+	t.show(in)
+}
+
+// show puts what has been typed so far in the title bar.
+func (t *turnPrompt) show(in *input) {
+	in.setStatus("run until turn " + t.digits + "_ (return to run, escape to cancel)")
+}
+
+// rulePrompt reads the rule or edge mode typed after the Rule action, e.g. "b36/s23", "dead" or both separated by a
+// space, showing it in the title bar as it is typed.
+type rulePrompt struct {
+	open bool
+	text string
+}
+
+// maxRuleLength is long enough for a rule with a chance for every transition and an edge mode.
+const maxRuleLength = 80
+
+// start opens the prompt with nothing typed yet.
+func (r *rulePrompt) start(in *input) {
+	r.open = true
+	r.text = ""
+	r.show(in)
+}
+
+// key handles a key pressed while the prompt is open. Printable characters are typed and backspace deletes the
+// last one. Return closes the prompt and sends the distributor 'r', the text typed and a newline, and escape closes
+// it without sending anything.
+func (r *rulePrompt) key(in *input, key Key) {
+	switch {
+	case key.Sym >= ' ' && key.Sym <= '~' && len(r.text) < maxRuleLength:
+		r.text += string(rune(key.Sym))
+	case key.Sym == '\b' && len(r.text) > 0:
+		r.text = r.text[:len(r.text)-1]
+	case key.Sym == '\r' || key.Sym == 0x1B:
+		r.open = false
+		in.setStatus("")
+		if key.Sym == '\r' && strings.TrimSpace(r.text) != "" {
+			in.keyPresses <- 'r'
+			for _, c := range r.text {
+				in.keyPresses <- c
+			}
+			in.keyPresses <- '\n'
+		}
+		return
+	}
+	r.show(in)
+}
+
+// show puts what has been typed so far in the title bar.
+func (r *rulePrompt) show(in *input) {
+	in.setStatus("switch to rule/edges " + r.text + "_ (return to switch at the next turn, escape to cancel)")
+}
@@ -0,0 +1,85 @@
+package sdl
+
+import "testing"
+
+// titleDisplay is a display that only keeps its title.
+type titleDisplay struct {
+	title string
+}
+
+func (d *titleDisplay) open(width, height int) {}
+func (d *titleDisplay) present(pixels []byte)  {}
+func (d *titleDisplay) poll() interface{}      { return nil }
+func (d *titleDisplay) setTitle(title string)  { d.title = title }
+func (d *titleDisplay) close()                 {}
+
+// TestTurnPrompt types turns into the prompt, checking return sends them to the distributor after a 'g' and
+// escape sends nothing.
+func TestTurnPrompt(t *testing.T) {
+	display := &titleDisplay{}
+	keyPresses := make(chan rune, 32)
+	in := &input{w: &Window{display: display}, bindings: DefaultBindings(), keyPresses: keyPresses}
+	press := func(keys ...Keycode) {
+		for _, sym := range keys {
+			in.key(Key{Sym: sym})
+		}
+	}
+
+	press('g', '1', '2', 'p', '\b', '5')
+	if !in.prompt.open || display.title != "GOL GUI - run until turn 15_ (return to run, escape to cancel)" {
+		t.Fatalf("prompt open %v, title %q", in.prompt.open, display.title)
+	}
+	press('\r')
+	if in.prompt.open || display.title != "GOL GUI" {
+		t.Fatalf("return left the prompt open %v, title %q", in.prompt.open, display.title)
+	}
+	sent := ""
+	for len(keyPresses) > 0 {
+		sent += string(<-keyPresses)
+	}
+	if sent != "g15\n" {
+		t.Errorf("sent %q, expected %q", sent, "g15\n")
+	}
+
+	press('g', '3', 0x1B, 'p')
+	if in.prompt.open {
+		t.Fatal("escape left the prompt open")
+	}
+	if key := <-keyPresses; key != 'p' {
+		t.Errorf("sent %q after escape, expected only the p pressed after it", key)
+	}
+}
+
+// TestRulePrompt types a rule and edge mode into the prompt, checking return sends them to the distributor after
+// an 'r', so none of the letters typed pause or quit the run, and a blank rule sends nothing.
+func TestRulePrompt(t *testing.T) {
+	display := &titleDisplay{}
+	keyPresses := make(chan rune, 32)
+	in := &input{w: &Window{display: display}, bindings: DefaultBindings(), keyPresses: keyPresses}
+	press := func(keys ...Keycode) {
+		for _, sym := range keys {
+			in.key(Key{Sym: sym})
+		}
+	}
+
+	press('r', 'b', '3', '6', '/', 's', '2', '3', ' ', 'q', '\b', 'd', 'e', 'a', 'd')
+	if !in.rule.open || display.title != "GOL GUI - switch to rule/edges b36/s23 dead_ (return to switch at the next turn, escape to cancel)" {
+		t.Fatalf("prompt open %v, title %q", in.rule.open, display.title)
+	}
+	press('\r')
+	if in.rule.open || display.title != "GOL GUI" {
+		t.Fatalf("return left the prompt open %v, title %q", in.rule.open, display.title)
+	}
+	sent := ""
+	for len(keyPresses) > 0 {
+		sent += string(<-keyPresses)
+	}
+	if sent != "rb36/s23 dead\n" {
+		t.Errorf("sent %q, expected %q", sent, "rb36/s23 dead\n")
+	}
+
+	press('r', ' ', '\r', 'p')
+	if key := <-keyPresses; key != 'p' || len(keyPresses) > 0 {
+		t.Errorf("sent %q after a blank rule, expected only the p pressed after it", key)
+	}
+}
@@ -0,0 +1,336 @@
+package sdl
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// StreamAddress is where the board is served when SDL can't be used. Open it in a browser to watch the board
+// as an MJPEG stream and to control the simulation from the keyboard and draw on it with the mouse.
+var StreamAddress = "localhost:8090"
+
+// streamFrameInterval limits how often frames are encoded, so a fast simulation isn't slowed down to JPEG speed.
+const streamFrameInterval = time.Second / 30
+
+// streamBoundary separates the frames of the multipart stream.
+const streamBoundary = "golframe"
+
+// KeyEvent is a key pressed in a browser watching the stream.
+type KeyEvent struct {
+	Key Key
+}
+
+// streamDisplay serves frames to browsers as an MJPEG stream, and passes back the keys pressed and mouse strokes
+// made in them.
+// It is pure Go, so it works where SDL2 can't be installed or a build has no cgo.
+type streamDisplay struct {
+	server  *http.Server
+	address string // Address the stream is served on.
+	keys    chan KeyEvent
+	mouse   chan MouseEvent
+	done    chan bool // Closed when the display closes, to stop the encoder.
+	width   int       // Size of the view in pixels.
+	height  int
+
+	mu       sync.Mutex
+	updated  *sync.Cond // Broadcast when a frame has been encoded or the display has closed.
+	pixels   []byte     // Copy of the latest frame presented.
+	dirty    bool       // Whether pixels has changed since the last frame was encoded.
+	frame    []byte     // Latest frame, as a JPEG.
+	frames   int        // Number of frames encoded so far.
+	watchers int        // Number of browsers watching; frames are only encoded while there are some.
+	title    string     // Title the page shows, e.g. with the latest error.
+	closed   bool
+}
+
+func newStreamDisplay() *streamDisplay {
+	d := &streamDisplay{keys: make(chan KeyEvent, 100), mouse: make(chan MouseEvent, 100), done: make(chan bool), title: "GOL GUI"}
+	d.updated = sync.NewCond(&d.mu)
+	return d
+}
+
+// open starts serving the stream on StreamAddress.
+func (d *streamDisplay) open(width, height int) {
+	d.width, d.height = width, height
+	d.pixels = make([]byte, width*height*4)
+
+	listener, err := net.Listen("tcp", StreamAddress)
+	util.Check(err)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.servePage)
+	mux.HandleFunc("/stream", d.serveStream)
+	mux.HandleFunc("/key", d.serveKey)
+	mux.HandleFunc("/mouse", d.serveMouse)
+	mux.HandleFunc("/title", d.serveTitle)
+	d.server = &http.Server{Handler: mux}
+	d.address = listener.Addr().String()
+	go d.server.Serve(listener)
+	go d.encode()
+	fmt.Printf("Watch the board at http://%s/\n", d.address)
+}
+
+// present keeps a copy of the frame for the encoder, so the window can carry on changing its pixels.
+func (d *streamDisplay) present(pixels []byte) {
+	d.mu.Lock()
+	copy(d.pixels, pixels)
+	d.dirty = true
+	d.mu.Unlock()
+}
+
+func (d *streamDisplay) poll() interface{} {
+	select {
+	case key := <-d.keys:
+		return key
+	case mouse := <-d.mouse:
+		return mouse
+	default:
+		return nil
+	}
+}
+
+func (d *streamDisplay) setTitle(title string) {
+	d.mu.Lock()
+	d.title = title
+	d.mu.Unlock()
+}
+
+func (d *streamDisplay) close() {
+	close(d.done)
+	d.mu.Lock()
+	d.closed = true
+	d.updated.Broadcast()
+	d.mu.Unlock()
+	d.server.Close()
+}
+
+// encode turns the latest frame presented into a JPEG every streamFrameInterval while anyone is watching.
+func (d *streamDisplay) encode() {
+	ticker := time.NewTicker(streamFrameInterval)
+	defer ticker.Stop()
+	img := image.NewRGBA(image.Rect(0, 0, d.width, d.height))
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+		}
+
+		d.mu.Lock()
+		if !d.dirty || d.watchers == 0 {
+			d.mu.Unlock()
+			continue
+		}
+		// ARGB8888 pixels are stored blue, green, red, alpha.
+		for i := 0; i < len(d.pixels); i += 4 {
+			img.Pix[i+0] = d.pixels[i+2]
+			img.Pix[i+1] = d.pixels[i+1]
+			img.Pix[i+2] = d.pixels[i+0]
+			img.Pix[i+3] = 0xFF
+		}
+		d.dirty = false
+		d.mu.Unlock()
+
+		var frame bytes.Buffer
+		util.Check(jpeg.Encode(&frame, img, &jpeg.Options{Quality: 90}))
+		d.mu.Lock()
+		d.frame = frame.Bytes()
+		d.frames++
+		d.updated.Broadcast()
+		d.mu.Unlock()
+	}
+}
+
+// serveStream sends every frame encoded from now on as one part of a multipart response, which browsers
+// show as a moving image.
+func (d *streamDisplay) serveStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+streamBoundary)
+	flusher, _ := w.(http.Flusher)
+
+	d.mu.Lock()
+	d.watchers++
+	// A new watcher needs a frame even if nothing has changed, e.g. while paused.
+	d.dirty = true
+	defer func() {
+		d.watchers--
+		d.mu.Unlock()
+	}()
+	seen := d.frames
+	for {
+		for d.frames == seen && !d.closed {
+			d.updated.Wait()
+		}
+		if d.closed {
+			return
+		}
+		frame := d.frame
+		seen = d.frames
+		d.mu.Unlock()
+
+		_, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", streamBoundary, len(frame))
+		if err == nil {
+			_, err = w.Write(frame)
+		}
+		if err == nil {
+			_, err = io.WriteString(w, "\r\n")
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		d.mu.Lock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// serveKey passes a key pressed in the browser to the window. The request is never held up: if the window
+// is behind, the key is dropped.
+func (d *streamDisplay) serveKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "keys must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	key, err := browserKey(r.FormValue("key"), r.FormValue("code"),
+		r.FormValue("ctrl") == "true", r.FormValue("shift") == "true", r.FormValue("alt") == "true")
+	if err != nil {
+		// Modifiers on their own, and keys that can't be bound, do nothing.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	select {
+	case d.keys <- KeyEvent{Key: key}:
+	default:
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mouseActions maps the actions the page posts to /mouse to those of a MouseEvent.
+var mouseActions = map[string]MouseAction{"down": MouseDown, "drag": MouseDrag, "up": MouseUp}
+
+// serveMouse passes the mouse pressed, dragged or released over the board in the browser to the window, at the
+// cell the page worked out was under the pointer. Like a key, it is dropped if the window is behind.
+func (d *streamDisplay) serveMouse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "mouse events must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	action, ok := mouseActions[r.FormValue("action")]
+	x, xErr := strconv.Atoi(r.FormValue("x"))
+	y, yErr := strconv.Atoi(r.FormValue("y"))
+	if !ok || xErr != nil || yErr != nil {
+		http.Error(w, "expected an action of down, drag or up and the x and y of a cell", http.StatusBadRequest)
+		return
+	}
+	select {
+	case d.mouse <- MouseEvent{X: x, Y: y, Action: action, Erase: r.FormValue("erase") == "true"}:
+	default:
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// browserKeyNames maps the names browsers give keys without a printable character to those ParseKey expects.
+var browserKeyNames = map[string]string{
+	"ArrowUp":    "up",
+	"ArrowDown":  "down",
+	"ArrowLeft":  "left",
+	"ArrowRight": "right",
+	" ":          "space",
+	"Enter":      "return",
+	"Escape":     "escape",
+	"Tab":        "tab",
+	"Backspace":  "backspace",
+	"Delete":     "delete",
+}
+
+// browserKey converts a browser's KeyboardEvent, given by its key and code properties and modifiers,
+// into a Key. The keypad's + and - have their own codes, as they are bound separately.
+func browserKey(key, code string, ctrl, shift, alt bool) (Key, error) {
+	name, ok := browserKeyNames[key]
+	switch {
+	case code == "NumpadAdd":
+		name = "keypad +"
+	case code == "NumpadSubtract":
+		name = "keypad -"
+	case !ok:
+		name = strings.ToLower(key)
+	}
+	if ctrl {
+		name = "ctrl+" + name
+	}
+	if shift {
+		name = "shift+" + name
+	}
+	if alt {
+		name = "alt+" + name
+	}
+	return ParseKey(name)
+}
+
+// serveTitle returns the title, which the page polls so errors show up in the browser's title bar.
+func (d *streamDisplay) serveTitle(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	title := d.title
+	d.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, title)
+}
+
+// streamPage shows the stream scaled to the browser window, sends every key pressed to /key and every mouse
+// stroke over the board to /mouse, in cells, and keeps the title up to date.
+const streamPage = `<!DOCTYPE html>
+<html>
+<head>
+<title>GOL GUI</title>
+<style>
+body { margin: 0; background: #000; }
+img { width: 100vw; height: 100vh; object-fit: contain; image-rendering: pixelated; }
+</style>
+</head>
+<body>
+<img src="/stream" alt="Game of Life">
+<script>
+document.addEventListener('keydown', function (e) {
+	fetch('/key', {method: 'POST', body: new URLSearchParams({
+		key: e.key, code: e.code, ctrl: e.ctrlKey, shift: e.shiftKey, alt: e.altKey
+	})});
+	e.preventDefault();
+});
+var board = document.querySelector('img'), held = false;
+// The board is scaled to fit the page and centred in it, so the pointer is mapped back to the cell under it.
+function mouse(action, e) {
+	var rect = board.getBoundingClientRect();
+	var scale = Math.min(rect.width / board.naturalWidth, rect.height / board.naturalHeight);
+	var left = rect.left + (rect.width - board.naturalWidth * scale) / 2;
+	var top = rect.top + (rect.height - board.naturalHeight * scale) / 2;
+	fetch('/mouse', {method: 'POST', body: new URLSearchParams({
+		action: action, x: Math.floor((e.clientX - left) / scale), y: Math.floor((e.clientY - top) / scale),
+		erase: e.button === 2
+	})});
+}
+board.addEventListener('mousedown', function (e) { held = true; mouse('down', e); e.preventDefault(); });
+board.addEventListener('contextmenu', function (e) { e.preventDefault(); });
+document.addEventListener('mousemove', function (e) { if (held) { mouse('drag', e); } });
+document.addEventListener('mouseup', function (e) { if (held) { held = false; mouse('up', e); } });
+setInterval(function () {
+	fetch('/title').then(function (res) { return res.text(); }).then(function (title) { document.title = title; });
+}, 1000);
+</script>
+</body>
+</html>
+`
+
+func (d *streamDisplay) servePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, streamPage)
+}
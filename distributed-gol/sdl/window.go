@@ -3,68 +3,81 @@ package sdl
 import (
 	"fmt"
 
-	"github.com/veandco/go-sdl2/sdl"
+	"uk.ac.bris.cs/gameoflife/stubs"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
+// display is where a Window's frames are shown: an SDL window, or an MJPEG stream served over HTTP
+// when SDL can't be used.
+type display interface {
+	// open creates the view that frames of the given size in pixels are drawn to.
+	open(width, height int)
+	// present shows a frame of ARGB8888 pixels the size of the view.
+	present(pixels []byte)
+	// poll returns the next input event, or nil if there isn't one.
+	poll() interface{}
+	// setTitle changes the title the view is shown with.
+	setTitle(title string)
+	// close releases the view.
+	close()
+}
+
 type Window struct {
 	Width, Height int32
-	window        *sdl.Window
-	renderer      *sdl.Renderer
-	texture       *sdl.Texture
+	display       display
 	pixels        []byte
+	owners        []int      // Worker responsible for each pixel, or -1 if unknown.
+	overlay       bool       // Whether the worker ownership overlay is shown.
+	tinted        []byte     // Scratch buffer the overlay is drawn into, so pixels always hold the plain world.
+	status        string     // Message shown in the title bar, if any.
+	latency       latency    // How long the latest turns took to reach the screen.
+	board         boardStats // Turn, alive count and speed of the run, for the title bar.
+	paused        string     // Turn the run is paused at, for the title bar, or "" if it isn't paused.
+	flipsPending  bool       // Whether cells have flipped since the latest turn completed.
 }
 
-func filterEvent(e sdl.Event, userdata interface{}) bool {
-	return e.GetType() == sdl.KEYDOWN || e.GetType() == sdl.QUIT
+// ownerColours tints the regions of the world, indexed by worker. Workers beyond the palette reuse its colours.
+var ownerColours = [][3]byte{
+	{0xE6, 0x19, 0x4B}, // Red.
+	{0x3C, 0xB4, 0x4B}, // Green.
+	{0x43, 0x63, 0xD8}, // Blue.
+	{0xFF, 0xE1, 0x19}, // Yellow.
+	{0x91, 0x1E, 0xB4}, // Purple.
+	{0x42, 0xD4, 0xF4}, // Cyan.
+	{0xF5, 0x82, 0x31}, // Orange.
+	{0xF0, 0x32, 0xE6}, // Magenta.
 }
 
 func NewWindow(width, height int32) *Window {
-	err := sdl.Init(sdl.INIT_EVERYTHING)
-	util.Check(err)
-	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, width, height, sdl.WINDOW_SHOWN)
-	util.Check(err)
-	renderer, err := sdl.CreateRenderer(window, -1, sdl.WINDOW_SHOWN)
-	util.Check(err)
-	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "linear")
-	err = renderer.SetLogicalSize(width, height)
-	util.Check(err)
-	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, width, height)
-	util.Check(err)
-
-	sdl.SetEventFilterFunc(filterEvent, nil)
+	d := newDisplay()
+	d.open(int(width), int(height))
 	return &Window{
-		width,
-		height,
-		window,
-		renderer,
-		texture,
-		make([]byte, width*height*4),
+		Width:   width,
+		Height:  height,
+		display: d,
+		pixels:  make([]byte, width*height*4),
 	}
 }
 
 func (w *Window) Destroy() {
-	err := w.texture.Destroy()
-	util.Check(err)
-	err = w.renderer.Destroy()
-	util.Check(err)
-	err = w.window.Destroy()
-	util.Check(err)
-	sdl.Quit()
+	w.display.close()
 }
 
 func (w *Window) RenderFrame() {
-	err := w.texture.Update(nil, w.pixels, int(w.Width*4))
-	util.Check(err)
-	err = w.renderer.Clear()
-	util.Check(err)
-	err = w.renderer.Copy(w.texture, nil, nil)
-	util.Check(err)
-	w.renderer.Present()
+	pixels := w.pixels
+	if w.overlay && w.owners != nil {
+		pixels = w.tintedPixels()
+	}
+	w.display.present(pixels)
 }
 
-func (w *Window) PollEvent() sdl.Event {
-	return sdl.PollEvent()
+// PollEvent returns the next input event, or nil if there isn't one. The tests call it on a nil Window
+// when run with -noVis.
+func (w *Window) PollEvent() interface{} {
+	if w == nil {
+		return nil
+	}
+	return w.display.poll()
 }
 
 func (w *Window) SetPixel(x, y int) {
@@ -102,3 +115,116 @@ func (w *Window) ClearPixels() {
 		w.pixels[i] = 0
 	}
 }
+
+// ShowInitialBoard draws the world a run starts from, or a keyframe of it later on, in one pass, clearing whatever
+// was drawn before, and shows it.
+func (w *Window) ShowInitialBoard(alive []util.Cell) {
+	w.ClearPixels()
+	for _, cell := range alive {
+		if cell.X < 0 || cell.Y < 0 || cell.X >= int(w.Width) || cell.Y >= int(w.Height) {
+			panic(fmt.Sprintf("InitialBoard cell at (%d, %d) is outside the bounds of the window.", cell.X, cell.Y))
+		}
+		w.SetPixel(cell.X, cell.Y)
+	}
+	w.RenderFrame()
+}
+
+// SetStatus shows a message in the window's title bar, or just the title if the message is empty.
+func (w *Window) SetStatus(message string) {
+	w.status = message
+	w.showTitle()
+}
+
+// FlipCell flips a cell of the turn in progress, which isn't shown until the turn is complete.
+func (w *Window) FlipCell(x, y int) {
+	w.FlipPixel(x, y)
+	w.flipsPending = true
+}
+
+// TurnComplete notes that every cell of the latest turn has flipped, so the frame can be shown.
+func (w *Window) TurnComplete() {
+	w.flipsPending = false
+	if w.paused != "" {
+		w.showTitle() // Paused part way through the turn, so the title was waiting for it.
+	}
+}
+
+// Paused shows in the title bar that the run has paused at turn. The frame must be exactly that turn, so while cells
+// have flipped since the latest TurnComplete, leaving the frame part way through a turn, the title waits for the turn
+// to complete rather than label a half-updated frame.
+func (w *Window) Paused(turn int) {
+	w.paused = fmt.Sprintf("paused at turn %d", turn)
+	w.showTitle()
+}
+
+// Resumed takes the pause out of the title bar once the run carries on.
+func (w *Window) Resumed() {
+	if w.paused != "" {
+		w.paused = ""
+		w.showTitle()
+	}
+}
+
+// showTitle puts the pause, the status message, the board's statistics and the latest latency, whichever there are,
+// in the title bar.
+func (w *Window) showTitle() {
+	title := "GOL GUI"
+	paused := w.paused
+	if w.flipsPending {
+		paused = ""
+	}
+	for _, part := range []string{paused, w.status, w.board.text, w.latency.text} {
+		if part != "" {
+			title += " - " + part
+		}
+	}
+	w.display.setTitle(title)
+}
+
+// ToggleOverlay shows or hides the worker ownership overlay.
+func (w *Window) ToggleOverlay() {
+	w.overlay = !w.overlay
+}
+
+// SetOwnership records which worker computes each region of the world.
+func (w *Window) SetOwnership(assignments []stubs.Assignment) {
+	width, height := int(w.Width), int(w.Height)
+	w.owners = make([]int, width*height)
+	for i := range w.owners {
+		w.owners[i] = -1
+	}
+	for _, a := range assignments {
+		for y := a.StartRow; y < a.EndRow && y < height; y++ {
+			for x := a.StartCol; x < a.EndCol && x < width; x++ {
+				w.owners[y*width+x] = a.Worker
+			}
+		}
+	}
+}
+
+// tintedPixels draws the world with every pixel tinted by the colour of the worker that owns it.
+// Dead cells take a dark shade of the colour and alive cells a light one, so the board stays readable.
+func (w *Window) tintedPixels() []byte {
+	if len(w.tinted) != len(w.pixels) {
+		w.tinted = make([]byte, len(w.pixels))
+	}
+	for i, owner := range w.owners {
+		p := 4 * i
+		if owner < 0 {
+			copy(w.tinted[p:p+4], w.pixels[p:p+4])
+			continue
+		}
+		colour := ownerColours[owner%len(ownerColours)]
+		alive := w.pixels[p] == 0xFF
+		// ARGB8888 pixels are stored blue, green, red, alpha.
+		for c := 0; c < 3; c++ {
+			if alive {
+				w.tinted[p+c] = colour[2-c]/2 + 0x80
+			} else {
+				w.tinted[p+c] = colour[2-c] / 4
+			}
+		}
+		w.tinted[p+3] = 0xFF
+	}
+	return w.tinted
+}
@@ -0,0 +1,184 @@
+// Package selftest checks a Game of Life engine gives the right answers, with patterns whose behaviour is known
+// and a comparison against a deliberately simple reference, so a deployment can be validated on a new machine
+// with -selftest rather than the full test suite.
+package selftest
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Evolver evolves a world on a torus under Conway's rules for the given number of turns.
+type Evolver func(world [][]byte, turns int) ([][]byte, error)
+
+// Result is the outcome of one check: nil Err means it passed.
+type Result struct {
+	Name string
+	Err  error
+}
+
+// check is a world, how many turns to evolve it and the world expected at the end.
+type check struct {
+	name     string
+	world    [][]byte
+	turns    int
+	expected [][]byte
+}
+
+// Kernel evolves worlds with the shared kernel, counting neighbours with the given algorithm.
+func Kernel(algorithm kernel.Algorithm) Evolver {
+	opts := kernel.Defaults
+	opts.Algorithm = algorithm
+	return func(world [][]byte, turns int) ([][]byte, error) {
+		height, width := len(world), len(world[0])
+		for turn := 0; turn < turns; turn++ {
+			opts.Turn = turn
+			world = kernel.NextStateWith(world, width, height, 0, height, 8, opts)
+		}
+		return world, nil
+	}
+}
+
+// Run evolves each check's world with evolve and compares the result with what is expected.
+func Run(evolve Evolver) []Result {
+	var results []Result
+	for _, c := range checks() {
+		world, err := evolve(copyWorld(c.world), c.turns)
+		if err == nil {
+			err = compare(world, c.expected)
+		}
+		results = append(results, Result{Name: c.name, Err: err})
+	}
+	return results
+}
+
+// Report prints a PASS or FAIL line for each result, tagged with what was checked, e.g. "worker 10.0.0.5:8040",
+// and returns whether every check passed.
+func Report(out io.Writer, what string, results []Result) bool {
+	passed := true
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(out, "FAIL %s: %s: %v\n", what, r.Name, r.Err)
+			passed = false
+		} else {
+			fmt.Fprintf(out, "PASS %s: %s\n", what, r.Name)
+		}
+	}
+	return passed
+}
+
+// checks returns the worlds checked, each with the answer worked out independently of the engine checked.
+func checks() []check {
+	// A blinker turns from a horizontal line to a vertical one and back.
+	horizontal := world(5, 5, []util.Cell{{X: 1, Y: 2}, {X: 2, Y: 2}, {X: 3, Y: 2}})
+	vertical := world(5, 5, []util.Cell{{X: 2, Y: 1}, {X: 2, Y: 2}, {X: 2, Y: 3}})
+
+	// A glider moves one cell down and to the right every four turns.
+	glider := []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	moved := make([]util.Cell, len(glider))
+	for i, cell := range glider {
+		moved[i] = util.Cell{X: cell.X + 1, Y: cell.Y + 1}
+	}
+
+	// A blinker split across the top-left corner only turns into the same blinker if neighbours wrap around
+	// both edges, and a glider ends up back where it started after crossing both edges.
+	corner := world(6, 6, []util.Cell{{X: 5, Y: 0}, {X: 0, Y: 0}, {X: 1, Y: 0}})
+	cornerTurned := world(6, 6, []util.Cell{{X: 0, Y: 5}, {X: 0, Y: 0}, {X: 0, Y: 1}})
+
+	// A random world, at a size that isn't a multiple of anything a kernel packs or splits rows by.
+	random := make([][]byte, 29)
+	r := rand.New(rand.NewSource(1))
+	for y := range random {
+		random[y] = make([]byte, 37)
+		for x := range random[y] {
+			if r.Intn(3) == 0 {
+				random[y][x] = util.Alive
+			}
+		}
+	}
+
+	return []check{
+		{"blinker oscillates", horizontal, 1, vertical},
+		{"blinker returns", horizontal, 2, horizontal},
+		{"glider translates", world(8, 8, glider), 4, world(8, 8, moved)},
+		{"blinker wraps around the corner", corner, 1, cornerTurned},
+		{"glider wraps around the edges", world(6, 6, glider), 24, world(6, 6, glider)},
+		{"matches the reference", random, 20, reference(random, 20)},
+	}
+}
+
+// world returns an empty width x height world with the given cells alive.
+func world(width, height int, alive []util.Cell) [][]byte {
+	w := make([][]byte, height)
+	for y := range w {
+		w[y] = make([]byte, width)
+	}
+	for _, cell := range alive {
+		w[cell.Y][cell.X] = util.Alive
+	}
+	return w
+}
+
+// reference evolves a world on a torus by counting every cell's neighbours one at a time, as simply as possible.
+func reference(w [][]byte, turns int) [][]byte {
+	height, width := len(w), len(w[0])
+	for turn := 0; turn < turns; turn++ {
+		next := world(width, height, nil)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				neighbours := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if (dx != 0 || dy != 0) && w[(y+dy+height)%height][(x+dx+width)%width] == util.Alive {
+							neighbours++
+						}
+					}
+				}
+				if neighbours == 3 || neighbours == 2 && w[y][x] == util.Alive {
+					next[y][x] = util.Alive
+				}
+			}
+		}
+		w = next
+	}
+	return w
+}
+
+// compare describes the first cell that differs between two worlds, or returns nil if they are the same.
+func compare(got, expected [][]byte) error {
+	if len(got) != len(expected) || len(got) > 0 && len(got[0]) != len(expected[0]) {
+		return fmt.Errorf("got a world of %d rows, expected %dx%d", len(got), len(expected[0]), len(expected))
+	}
+	for y := range expected {
+		if len(got[y]) != len(expected[y]) {
+			return fmt.Errorf("row %d has %d cells, expected %d", y, len(got[y]), len(expected[y]))
+		}
+		for x := range expected[y] {
+			if got[y][x] != expected[y][x] {
+				return fmt.Errorf("cell (%d, %d) is %s, expected %s", x, y, state(got[y][x]), state(expected[y][x]))
+			}
+		}
+	}
+	return nil
+}
+
+// state names a cell's state.
+func state(cell byte) string {
+	if cell == util.Alive {
+		return "alive"
+	}
+	return "dead"
+}
+
+// copyWorld copies a world, so an engine that works in place can't change the check.
+func copyWorld(w [][]byte) [][]byte {
+	c := make([][]byte, len(w))
+	for y := range w {
+		c[y] = append([]byte{}, w[y]...)
+	}
+	return c
+}
@@ -0,0 +1,32 @@
+package selftest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/kernel"
+)
+
+// TestKernels checks both of the kernel's algorithms pass every check.
+func TestKernels(t *testing.T) {
+	for _, algorithm := range []kernel.Algorithm{kernel.ByteWise, kernel.BitSliced} {
+		for _, r := range Run(Kernel(algorithm)) {
+			if r.Err != nil {
+				t.Errorf("%v kernel: %s: %v", algorithm, r.Name, r.Err)
+			}
+		}
+	}
+}
+
+// TestBrokenEngine checks an engine that never changes the world is reported as failing.
+func TestBrokenEngine(t *testing.T) {
+	frozen := func(world [][]byte, turns int) ([][]byte, error) { return world, nil }
+	out := &bytes.Buffer{}
+	if Report(out, "frozen", Run(frozen)) {
+		t.Fatal("an engine that never changes the world passed")
+	}
+	if !strings.Contains(out.String(), "FAIL frozen: blinker oscillates: cell") {
+		t.Errorf("expected the blinker check to fail naming a cell, got:\n%s", out)
+	}
+}
@@ -0,0 +1,110 @@
+// Package slab holds a world as one flat slice of cells, row after row, rather than as a slice per row. Every cell
+// of a turn is then found by arithmetic on one index rather than by first loading its row, a world is copied with
+// a single copy, and gob sends it over RPC as a single byte slice instead of one per row.
+package slab
+
+// World is a Width x Height world of util.Alive and util.Dead cells. Cell (x, y) is Cells[y*Stride+x]; Stride is
+// usually Width, but can be larger for a world whose rows are part of longer ones. The zero World is empty.
+type World struct {
+	Width  int
+	Height int
+	Stride int // Cells from the start of one row to the start of the next.
+	Cells  []byte
+}
+
+// New returns a world of dead cells.
+func New(width, height int) World {
+	return World{Width: width, Height: height, Stride: width, Cells: make([]byte, width*height)}
+}
+
+// FromRows copies a world indexed [row][column] into a slab. Every row must be as long as the first.
+func FromRows(rows [][]byte) World {
+	if len(rows) == 0 {
+		return World{}
+	}
+	w := New(len(rows[0]), len(rows))
+	for y, row := range rows {
+		copy(w.Row(y), row)
+	}
+	return w
+}
+
+// Row returns row y, sharing its cells with the world.
+func (w World) Row(y int) []byte {
+	start := y * w.Stride
+	return w.Cells[start : start+w.Width : start+w.Width]
+}
+
+// Rows returns the world indexed [row][column], for code that expects one. The rows share their cells with the
+// world, so only the slice of rows is allocated.
+func (w World) Rows() [][]byte {
+	rows := make([][]byte, w.Height)
+	for y := range rows {
+		rows[y] = w.Row(y)
+	}
+	return rows
+}
+
+// At returns cell (x, y).
+func (w World) At(x, y int) byte {
+	return w.Cells[y*w.Stride+x]
+}
+
+// Set sets cell (x, y) to state.
+func (w World) Set(x, y int, state byte) {
+	w.Cells[y*w.Stride+x] = state
+}
+
+// Slice returns rows startRow to endRow of the world, sharing their cells with it.
+func (w World) Slice(startRow, endRow int) World {
+	if startRow == endRow {
+		return World{Width: w.Width, Stride: w.Stride}
+	}
+	end := (endRow-1)*w.Stride + w.Width
+	return World{Width: w.Width, Height: endRow - startRow, Stride: w.Stride, Cells: w.Cells[startRow*w.Stride : end : end]}
+}
+
+// Clone returns a copy of the world that shares nothing with it, with rows packed one after another.
+func (w World) Clone() World {
+	c := New(w.Width, w.Height)
+	if w.Stride == w.Width {
+		copy(c.Cells, w.Cells)
+		return c
+	}
+	for y := 0; y < w.Height; y++ {
+		copy(c.Row(y), w.Row(y))
+	}
+	return c
+}
+
+// Equal reports whether two worlds are the same size with the same cells, whatever their strides.
+func (w World) Equal(o World) bool {
+	if w.Width != o.Width || w.Height != o.Height {
+		return false
+	}
+	for y := 0; y < w.Height; y++ {
+		if string(w.Row(y)) != string(o.Row(y)) {
+			return false
+		}
+	}
+	return true
+}
+
+// CopyRows copies the rows of src into the world from row startRow on. Both must be the same width.
+func (w World) CopyRows(startRow int, src World) {
+	if w.Stride == w.Width && src.Stride == src.Width {
+		copy(w.Cells[startRow*w.Stride:], src.Cells[:src.Width*src.Height])
+		return
+	}
+	for y := 0; y < src.Height; y++ {
+		copy(w.Row(startRow+y), src.Row(y))
+	}
+}
+
+// Valid reports whether the world holds enough cells for its size, as one decoded from a peer might not.
+func (w World) Valid() bool {
+	if w.Width < 0 || w.Height < 0 || w.Stride < w.Width {
+		return false
+	}
+	return w.Height == 0 || len(w.Cells) >= (w.Height-1)*w.Stride+w.Width
+}
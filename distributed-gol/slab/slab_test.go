@@ -0,0 +1,80 @@
+package slab
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRows checks a world converted to a slab and back is unchanged, and that rows share the slab's cells.
+func TestRows(t *testing.T) {
+	rows := [][]byte{{0, 255, 0}, {255, 0, 0}}
+	w := FromRows(rows)
+	if w.Width != 3 || w.Height != 2 || w.Stride != 3 || len(w.Cells) != 6 {
+		t.Fatalf("got a %dx%d slab with stride %d and %d cells", w.Width, w.Height, w.Stride, len(w.Cells))
+	}
+	if !reflect.DeepEqual(w.Rows(), rows) {
+		t.Errorf("got rows %v back, expected %v", w.Rows(), rows)
+	}
+	rows[0][0] = 255
+	if w.At(0, 0) != 0 {
+		t.Error("FromRows shares cells with the rows it was given")
+	}
+	w.Rows()[1][2] = 255
+	if w.At(2, 1) != 255 {
+		t.Error("Rows doesn't share cells with the slab")
+	}
+	if (World{}).Rows() == nil || len(FromRows(nil).Rows()) != 0 {
+		t.Error("an empty world doesn't have zero rows")
+	}
+}
+
+// TestSlice checks a slice of rows shares cells with the world and keeps its stride, and that cloning and
+// comparing ignore the stride.
+func TestSlice(t *testing.T) {
+	w := New(4, 5)
+	w.Set(1, 2, 255)
+	s := w.Slice(2, 4)
+	if s.Height != 2 || s.At(1, 0) != 255 || s.Stride != 4 {
+		t.Fatalf("got slice %+v", s)
+	}
+	s.Set(3, 1, 255)
+	if w.At(3, 3) != 255 {
+		t.Error("a slice doesn't share cells with its world")
+	}
+	if e := w.Slice(1, 1); e.Height != 0 || len(e.Cells) != 0 {
+		t.Errorf("got %+v for an empty slice", e)
+	}
+
+	// A world whose rows are the left halves of longer ones.
+	wide := World{Width: 2, Height: 2, Stride: 4, Cells: []byte{255, 0, 9, 9, 0, 255, 9, 9}}
+	c := wide.Clone()
+	if c.Stride != 2 || !reflect.DeepEqual(c.Cells, []byte{255, 0, 0, 255}) {
+		t.Errorf("got clone %+v, expected the rows packed together", c)
+	}
+	if !c.Equal(wide) || c.Equal(New(2, 2)) || c.Equal(New(2, 3)) {
+		t.Error("Equal doesn't compare just the cells")
+	}
+}
+
+// TestCopyRows checks rows are copied into place whatever the strides, and that worlds too short for their size
+// aren't valid.
+func TestCopyRows(t *testing.T) {
+	w := New(2, 3)
+	w.CopyRows(1, FromRows([][]byte{{1, 2}, {3, 4}}))
+	if !reflect.DeepEqual(w.Cells, []byte{0, 0, 1, 2, 3, 4}) {
+		t.Errorf("got %v after copying packed rows", w.Cells)
+	}
+	w.CopyRows(0, World{Width: 2, Height: 1, Stride: 3, Cells: []byte{5, 6, 9}})
+	if !reflect.DeepEqual(w.Cells, []byte{5, 6, 1, 2, 3, 4}) {
+		t.Errorf("got %v after copying a row of a wider world", w.Cells)
+	}
+
+	if !w.Valid() || !(World{}).Valid() || !w.Slice(1, 3).Valid() {
+		t.Error("a world made by the package isn't valid")
+	}
+	for _, bad := range []World{{Width: 2, Height: 3, Stride: 2, Cells: make([]byte, 5)}, {Width: 2, Height: 1, Stride: 1, Cells: make([]byte, 2)}} {
+		if bad.Valid() {
+			t.Errorf("%+v is valid", bad)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/golbroker"
+	"uk.ac.bris.cs/gameoflife/golworker"
+	"uk.ac.bris.cs/gameoflife/memnet"
+)
+
+// standaloneAddress is the in-memory address the broker started by -standalone listens on.
+const standaloneAddress = "mem:broker"
+
+// startStandalone starts a broker and the given number of workers in this process, connected to each other and to
+// the client over memnet rather than TCP, and points the client at the broker. stop closes their listeners.
+func startStandalone(workers int) (stop func(), err error) {
+	var listeners []net.Listener
+	stop = func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+	var addresses []string
+	for i := 0; i < workers; i++ {
+		l, err := memnet.Listen(fmt.Sprintf("%s-worker%d", standaloneAddress, i))
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		listeners = append(listeners, l)
+		addresses = append(addresses, l.Addr().String())
+		go golworker.Serve(l)
+	}
+	l, err := memnet.Listen(standaloneAddress)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	listeners = append(listeners, l)
+	go func() {
+		if err := golbroker.Serve(l, addresses); err != nil {
+			fmt.Printf("Error starting the standalone broker: %v\n", err)
+			l.Close() // The client's calls fail rather than wait for it.
+		}
+	}()
+	gol.BrokerAddress = standaloneAddress
+	return stop, nil
+}
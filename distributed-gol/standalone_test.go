@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestStandalone runs the 64x64 image for 100 turns through a broker and two workers started inside the test, as
+// -standalone starts them, and checks the result against the expected image.
+func TestStandalone(t *testing.T) {
+	address := gol.BrokerAddress
+	defer func() { gol.BrokerAddress = address }()
+	stop, err := startStandalone(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	if _, err := startStandalone(2); err == nil {
+		t.Error("a second standalone broker started at the same address")
+	}
+
+	dir, err := ioutil.TempDir("", "standalone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 2, OutDir: dir}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		case gol.ErrorEvent:
+			t.Errorf("run reported %v", e)
+		}
+	}
+	assertEqualBoard(t, cells, readAliveCells(fmt.Sprintf("check/images/%dx%dx%d.pgm", 64, 64, 100), 64, 64), p)
+}
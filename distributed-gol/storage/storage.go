@@ -0,0 +1,112 @@
+// Package storage reads and writes whole files either on the local disk or in cloud object storage,
+// so runs can take their input images from, and push their snapshots to, a bucket rather than a local directory.
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Store reads and writes whole files.
+type Store interface {
+	// Get returns the contents of the file at path.
+	Get(path string) ([]byte, error)
+	// Put replaces the contents of the file at path, creating it if necessary.
+	Put(path string, data []byte) error
+}
+
+// For returns the store that handles path: S3 for s3://bucket/key, Google Cloud Storage for gs://bucket/key,
+// and the local disk for anything else.
+func For(path string) Store {
+	switch {
+	case strings.HasPrefix(path, "s3://"):
+		// The AWS CLI picks up credentials and region from the usual environment variables and config files.
+		return cliStore{get: []string{"aws", "s3", "cp", "--quiet"}, put: []string{"aws", "s3", "cp", "--quiet"}}
+	case strings.HasPrefix(path, "gs://"):
+		return cliStore{get: []string{"gsutil", "-q", "cp"}, put: []string{"gsutil", "-q", "cp"}}
+	default:
+		return localStore{}
+	}
+}
+
+// Join appends a file name to a directory, which may be a local path or a bucket URL.
+func Join(dir, name string) string {
+	if strings.Contains(dir, "://") {
+		return strings.TrimSuffix(dir, "/") + "/" + name
+	}
+	return filepath.Join(dir, name)
+}
+
+// Get reads the file at path from whichever store handles it.
+func Get(path string) ([]byte, error) {
+	return For(path).Get(path)
+}
+
+// Put writes the file at path to whichever store handles it.
+func Put(path string, data []byte) error {
+	return For(path).Put(path, data)
+}
+
+// localStore keeps files on the local disk.
+type localStore struct{}
+
+func (localStore) Get(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+func (localStore) Put(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// cliStore copies objects to and from a bucket with the provider's command line tool, streaming through stdin and stdout.
+// This keeps the cloud SDKs out of the module; the tool must be installed and authenticated on the machine.
+type cliStore struct {
+	get []string // Command that copies an object, given as an extra argument, followed by "-" to stdout.
+	put []string // Command that copies "-" from stdin to an object given as the final argument.
+}
+
+func (s cliStore) Get(path string) ([]byte, error) {
+	var out bytes.Buffer
+	err := s.run(s.getArgs(path), nil, &out)
+	return out.Bytes(), err
+}
+
+func (s cliStore) Put(path string, data []byte) error {
+	return s.run(s.putArgs(path), bytes.NewReader(data), nil)
+}
+
+// getArgs returns the command line that copies the object at path to stdout.
+func (s cliStore) getArgs(path string) []string {
+	// Copy the base command, so appending never writes into the backing array shared by every Get.
+	return append(append([]string(nil), s.get...), path, "-")
+}
+
+// putArgs returns the command line that copies stdin to the object at path.
+func (s cliStore) putArgs(path string) []string {
+	return append(append([]string(nil), s.put...), "-", path)
+}
+
+// run executes a command, reporting its stderr if it fails.
+func (s cliStore) run(args []string, stdin *bytes.Reader, stdout *bytes.Buffer) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestJoin checks bucket URLs are joined with slashes and local paths with the OS separator.
+func TestJoin(t *testing.T) {
+	tests := map[[2]string]string{
+		{"s3://bucket", "16x16.pgm"}:       "s3://bucket/16x16.pgm",
+		{"gs://bucket/runs/", "16x16.pgm"}: "gs://bucket/runs/16x16.pgm",
+		{"images", "16x16.pgm"}:            filepath.Join("images", "16x16.pgm"),
+	}
+	for args, expected := range tests {
+		if got := Join(args[0], args[1]); got != expected {
+			t.Errorf("Join(%q, %q) = %q, expected %q", args[0], args[1], got, expected)
+		}
+	}
+}
+
+// TestFor checks each kind of path is handled by the right store.
+func TestFor(t *testing.T) {
+	if _, ok := For("s3://bucket/16x16.pgm").(cliStore); !ok {
+		t.Error("s3:// paths should be handled by the AWS CLI")
+	}
+	if _, ok := For("gs://bucket/16x16.pgm").(cliStore); !ok {
+		t.Error("gs:// paths should be handled by gsutil")
+	}
+	if _, ok := For("out/16x16.pgm").(localStore); !ok {
+		t.Error("plain paths should be handled by the local disk")
+	}
+}
+
+// TestLocalRoundTrip checks a file written locally, into a directory that doesn't exist yet, reads back unchanged.
+func TestLocalRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := Join(filepath.Join(dir, "out"), "16x16.pgm")
+	data := []byte("P5\n16 16\n255\n")
+	if err := Put(path, data); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	got, err := Get(path)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("read back %q, expected %q", got, data)
+	}
+}
+
+// TestCLICommands checks the command lines built for each provider's tool.
+func TestCLICommands(t *testing.T) {
+	tests := []struct {
+		path     string
+		get, put string
+	}{
+		{"s3://bucket/16x16.pgm", "aws s3 cp --quiet s3://bucket/16x16.pgm -", "aws s3 cp --quiet - s3://bucket/16x16.pgm"},
+		{"gs://bucket/runs/16x16.pgm", "gsutil -q cp gs://bucket/runs/16x16.pgm -", "gsutil -q cp - gs://bucket/runs/16x16.pgm"},
+	}
+	for _, test := range tests {
+		store := For(test.path).(cliStore)
+		if got := strings.Join(store.getArgs(test.path), " "); got != test.get {
+			t.Errorf("get %s runs %q, expected %q", test.path, got, test.get)
+		}
+		if got := strings.Join(store.putArgs(test.path), " "); got != test.put {
+			t.Errorf("put %s runs %q, expected %q", test.path, got, test.put)
+		}
+		// Building one command line mustn't change the next.
+		store.getArgs("other")
+		if got := strings.Join(store.getArgs(test.path), " "); got != test.get {
+			t.Errorf("get %s runs %q after another get, expected %q", test.path, got, test.get)
+		}
+	}
+}
+
+// TestCLIRoundTrip runs a cliStore against a stand-in for the provider's tool that copies between stdin/stdout
+// and a local file, and checks failures report the tool's stderr.
+func TestCLIRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell to stand in for the provider's tool")
+	}
+	dir, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Like the real tools, the object comes first for a get ("cp object -") and last for a put ("cp - object").
+	store := cliStore{
+		get: []string{"sh", "-c", `cat "$0"`},
+		put: []string{"sh", "-c", `cat > "$1"`},
+	}
+	path := filepath.Join(dir, "16x16.pgm")
+	data := []byte("P5\n16 16\n255\n")
+	if err := store.Put(path, data); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	got, err := store.Get(path)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("read back %q, expected %q", got, data)
+	}
+
+	_, err = store.Get(filepath.Join(dir, "missing.pgm"))
+	if err == nil || !strings.Contains(err.Error(), "missing.pgm") {
+		t.Errorf("expected an error naming the missing object, got %v", err)
+	}
+}
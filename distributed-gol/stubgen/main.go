@@ -0,0 +1,233 @@
+// stubgen generates the Go request and response types and handler names shared by the client, the broker and
+// its workers from the definitions in stubs/stubs.idl, so they are written down once rather than kept in step by
+// hand. It is run by go generate:
+//
+//	go generate ./stubs
+//
+// The definitions are in a small protobuf-like language. A version line numbers the protocol, and is bumped
+// whenever a change stops older binaries understanding the messages. Each service lists its methods with their
+// request and response messages, and each message lists its fields in Go syntax:
+//
+//	version 2
+//	import "uk.ac.bris.cs/gameoflife/util"
+//
+//	service Broker {
+//		rpc CalculateAliveCells(Empty) returns (CalculateAliveCellsResponse) as AliveCells
+//	}
+//
+//	// CalculateAliveCellsResponse is every alive cell of the world.
+//	message CalculateAliveCellsResponse {
+//		AliveCells []util.Cell
+//	}
+//
+// A method's handler name is the method's name followed by Handler, or the name after as followed by Handler.
+// Comments directly above a message, and on its fields, are copied into the generated code.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// definitions is everything declared in an IDL file, in the order it was declared.
+type definitions struct {
+	version  int
+	imports  []string
+	methods  []method
+	messages []message
+}
+
+// method is an RPC method of a service.
+type method struct {
+	service  string
+	name     string
+	request  string
+	response string
+	handler  string // Name of the variable holding the method's name, e.g. "AliveCellsHandler".
+	line     int
+}
+
+// message is a type sent over RPC.
+type message struct {
+	name   string
+	doc    []string // Comment lines directly above the message.
+	fields []string // Lines of the message's body, in Go syntax.
+}
+
+var (
+	versionLine = regexp.MustCompile(`^version\s+(\d+)$`)
+	importLine  = regexp.MustCompile(`^import\s+("[^"]+")$`)
+	serviceLine = regexp.MustCompile(`^service\s+(\w+)\s*\{$`)
+	rpcLine     = regexp.MustCompile(`^rpc\s+(\w+)\s*\(\s*(\w+)\s*\)\s+returns\s+\(\s*(\w+)\s*\)(?:\s+as\s+(\w+))?$`)
+	messageLine = regexp.MustCompile(`^message\s+(\w+)\s*\{$`)
+)
+
+// parse reads the definitions in an IDL file.
+func parse(r io.Reader) (definitions, error) {
+	var defs definitions
+	var doc []string // Comment lines since the last blank line or declaration.
+	var service string
+	var body *message // Message whose fields are being read, if any.
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		fail := func(format string, a ...interface{}) (definitions, error) {
+			return definitions{}, fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, a...))
+		}
+
+		if body != nil {
+			// Fields are Go, so are copied as they are; format.Source checks them later.
+			if text == "}" {
+				defs.messages = append(defs.messages, *body)
+				body = nil
+			} else if text != "" {
+				body.fields = append(body.fields, text)
+			}
+			continue
+		}
+		if service != "" {
+			switch m := rpcLine.FindStringSubmatch(text); {
+			case text == "}":
+				service = ""
+			case text == "" || strings.HasPrefix(text, "//"):
+			case m != nil:
+				handler := m[1]
+				if m[4] != "" {
+					handler = m[4]
+				}
+				defs.methods = append(defs.methods, method{service, m[1], m[2], m[3], handler + "Handler", line})
+			default:
+				return fail("expected rpc Method(Request) returns (Response), got %q", text)
+			}
+			continue
+		}
+
+		switch {
+		case text == "":
+			doc = nil
+		case strings.HasPrefix(text, "//"):
+			doc = append(doc, text)
+		case versionLine.MatchString(text):
+			defs.version, _ = strconv.Atoi(versionLine.FindStringSubmatch(text)[1])
+		case importLine.MatchString(text):
+			defs.imports = append(defs.imports, importLine.FindStringSubmatch(text)[1])
+		case serviceLine.MatchString(text):
+			service = serviceLine.FindStringSubmatch(text)[1]
+			doc = nil
+		case messageLine.MatchString(text):
+			body = &message{name: messageLine.FindStringSubmatch(text)[1], doc: doc}
+			doc = nil
+		default:
+			return fail("expected version, import, service or message, got %q", text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return definitions{}, err
+	}
+	switch {
+	case body != nil:
+		return definitions{}, fmt.Errorf("message %s isn't closed", body.name)
+	case service != "":
+		return definitions{}, fmt.Errorf("service %s isn't closed", service)
+	case defs.version <= 0:
+		return definitions{}, fmt.Errorf("no version given")
+	}
+	return defs, defs.check()
+}
+
+// check makes sure every name is declared once and every method's messages are declared.
+func (defs definitions) check() error {
+	declared := make(map[string]bool)
+	for _, m := range defs.messages {
+		if declared[m.name] {
+			return fmt.Errorf("message %s is declared twice", m.name)
+		}
+		declared[m.name] = true
+	}
+	handlers := make(map[string]bool)
+	for _, m := range defs.methods {
+		if handlers[m.handler] {
+			return fmt.Errorf("line %d: %s is declared twice", m.line, m.handler)
+		}
+		handlers[m.handler] = true
+		for _, name := range []string{m.request, m.response} {
+			if !declared[name] {
+				return fmt.Errorf("line %d: %s.%s uses undeclared message %s", m.line, m.service, m.name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// generate returns the formatted Go source of the definitions, in package stubs.
+func generate(defs definitions, source string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by stubgen from %s. DO NOT EDIT.\n\npackage stubs\n\n", source)
+	if len(defs.imports) > 0 {
+		fmt.Fprintf(&b, "import (\n%s\n)\n\n", strings.Join(defs.imports, "\n"))
+	}
+	fmt.Fprintf(&b, "// Version is the version of the protocol below. Binaries built with different versions can't talk to each other.\n")
+	fmt.Fprintf(&b, "const Version = %d\n\n", defs.version)
+
+	if len(defs.methods) > 0 {
+		fmt.Fprintf(&b, "// Names of the RPC methods, each with the message it takes and the message it replies with.\nvar (\n")
+		for _, m := range defs.methods {
+			fmt.Fprintf(&b, "%s = %q // (%s) returns (%s)\n", m.handler, m.service+"."+m.name, m.request, m.response)
+		}
+		fmt.Fprintf(&b, ")\n")
+	}
+	for _, m := range defs.messages {
+		fmt.Fprintf(&b, "\n")
+		for _, line := range m.doc {
+			fmt.Fprintf(&b, "%s\n", line)
+		}
+		fmt.Fprintf(&b, "type %s struct {\n", m.name)
+		for _, field := range m.fields {
+			fmt.Fprintf(&b, "%s\n", field)
+		}
+		fmt.Fprintf(&b, "}\n")
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code doesn't compile: %v", err)
+	}
+	return src, nil
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: stubgen definitions.idl output.go")
+		os.Exit(2)
+	}
+	in, out := os.Args[1], os.Args[2]
+
+	file, err := os.Open(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defs, err := parse(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", in, err)
+		os.Exit(1)
+	}
+	src, err := generate(defs, in)
+	if err == nil {
+		err = ioutil.WriteFile(out, src, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
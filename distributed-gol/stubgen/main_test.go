@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestGeneratedUpToDate checks stubs_gen.go is what stubs.idl generates, so a change to one without the other
+// is caught.
+func TestGeneratedUpToDate(t *testing.T) {
+	idl, err := ioutil.ReadFile("../stubs/stubs.idl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := parse(bytes.NewReader(idl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate(defs, "stubs.idl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := ioutil.ReadFile("../stubs/stubs_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, generated) {
+		t.Error("stubs/stubs_gen.go is out of date; run go generate ./stubs")
+	}
+}
+
+// TestParse checks handler names, messages and their comments are read from the definitions.
+func TestParse(t *testing.T) {
+	defs, err := parse(strings.NewReader(`version 3
+import "time"
+
+service Broker {
+	// Comments between methods are ignored.
+	rpc Pause(Request) returns (Empty)
+	rpc QuitServer(Request) returns (Empty) as Quit
+}
+
+// Request is a request.
+message Request {
+	At time.Time // When it was sent.
+}
+
+message Empty {
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defs.version != 3 || len(defs.imports) != 1 || len(defs.methods) != 2 || len(defs.messages) != 2 {
+		t.Fatalf("parsed %+v", defs)
+	}
+	if m := defs.methods[1]; m.service != "Broker" || m.name != "QuitServer" || m.handler != "QuitHandler" {
+		t.Errorf("parsed method %+v, expected Broker.QuitServer as QuitHandler", m)
+	}
+	if m := defs.messages[0]; len(m.doc) != 1 || len(m.fields) != 1 || m.fields[0] != "At time.Time // When it was sent." {
+		t.Errorf("parsed message %+v", m)
+	}
+
+	src, err := generate(defs, "test.idl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"const Version = 3", `QuitHandler  = "Broker.QuitServer" // (Request) returns (Empty)`, "// Request is a request.\ntype Request struct"} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("generated code is missing %q:\n%s", expected, src)
+		}
+	}
+}
+
+// TestParseErrors checks mistakes in the definitions are reported rather than generating broken code.
+func TestParseErrors(t *testing.T) {
+	for name, idl := range map[string]string{
+		"no version":          "message Empty {\n}\n",
+		"undeclared message":  "version 1\nservice Broker {\n\trpc Pause(Missing) returns (Empty)\n}\nmessage Empty {\n}\n",
+		"duplicate message":   "version 1\nmessage Empty {\n}\nmessage Empty {\n}\n",
+		"duplicate handler":   "version 1\nservice Broker {\n\trpc Pause(Empty) returns (Empty)\n\trpc Stop(Empty) returns (Empty) as Pause\n}\nmessage Empty {\n}\n",
+		"unclosed message":    "version 1\nmessage Empty {\n",
+		"malformed rpc":       "version 1\nservice Broker {\n\trpc Pause\n}\n",
+		"unknown declaration": "version 1\nenum State {\n}\n",
+	} {
+		if _, err := parse(strings.NewReader(idl)); err == nil {
+			t.Errorf("%s: parsed without an error", name)
+		}
+	}
+
+	defs, err := parse(strings.NewReader("version 1\nmessage Broken {\n\tWorld [][\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := generate(defs, "test.idl"); err == nil {
+		t.Error("generated code from a field that isn't Go")
+	}
+}
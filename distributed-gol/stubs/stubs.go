@@ -1,64 +1,36 @@
+// Package stubs holds what the client, the broker and its workers send each other. The messages and the names of
+// the RPC methods are generated into stubs_gen.go from stubs.idl; this file holds what goes with them.
 package stubs
 
-import "uk.ac.bris.cs/gameoflife/util"
+//go:generate go run ../stubgen stubs.idl stubs_gen.go
 
-var EvolveWorldHandler = "Broker.EvolveWorld"
-var AliveCellsCountHandler = "Broker.AliveCellsCount"
-var AliveCellsHandler = "Broker.CalculateAliveCells"
-var GetGlobalHandler = "Broker.GetGlobal"
-var PauseHandler = "Broker.Pause"
-var UnpauseHandler = "Broker.Unpause"
-var QuitHandler = "Broker.QuitServer"
-var KillServerHandler = "Broker.KillServer"
-var GetBrokerCellFlippedHandler = "Broker.GetCellFlipped"
-var GetTurnDoneHandler = "Broker.GetTurnDone"
-var GetContinueHandler = "Broker.GetContinue"
+import (
+	"errors"
+	"hash/crc32"
+)
 
-type EvolveResponse struct {
-	World [][]byte
-	Turn  int
-}
+// ErrLeaseReleased is returned for control RPCs whose epoch is current but whose run has finished or been quit.
+// Over RPC it arrives as an error with the same message.
+var ErrLeaseReleased = errors.New("lease released: the run has finished")
 
-type EvolveWorldRequest struct {
-	World       [][]byte
-	Width       int
-	Height      int
-	Turn        int
-	Threads     int
-	ImageHeight int
-	ImageWidth  int
-}
-type CalculateAliveCellsRequest struct {
-	World [][]byte
-}
-type CalculateAliveCellsResponse struct {
-	AliveCells []util.Cell
-}
-type AliveCellsCountResponse struct {
-	AliveCellsCount int
-	CompletedTurns  int
-}
-type GetGlobalResponse struct {
-	World [][]byte
-	Turns int
-}
-type Empty struct{}
+// States of a job in the broker's queue.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
 
-type GetBrokerCellFlippedResponse struct {
-	FlippedEvents []FlippedEvent
-}
+// SelfTestJob is the Job of the slices the broker's self-test asks for, which belong to no run.
+const SelfTestJob = 0
 
-type GetTurnDoneResponse struct {
-	TurnDone bool
-	Turn     int
+// Seal sets the checksum of the slice once World holds it, so the broker can tell if it was corrupted on the way.
+func (r *WorldRes) Seal() {
+	r.Checksum = crc32.ChecksumIEEE(r.World.Cells)
 }
 
-type GetContinueResponse struct {
-	Continue bool
-	World    [][]byte
-	Turn     int
-}
-type FlippedEvent struct {
-	CompletedTurns int
-	Cell           util.Cell
+// Intact reports whether the slice's cells still match the checksum it was sealed with. Gob checks the framing of
+// a message but not the bytes inside it, so a bit flipped on a flaky link would otherwise be committed to the board.
+func (r *WorldRes) Intact() bool {
+	return crc32.ChecksumIEEE(r.World.Cells) == r.Checksum
 }
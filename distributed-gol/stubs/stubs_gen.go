@@ -0,0 +1,455 @@
+// Code generated by stubgen from stubs.idl. DO NOT EDIT.
+
+package stubs
+
+import (
+	"time"
+	"uk.ac.bris.cs/gameoflife/slab"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Version is the version of the protocol below. Binaries built with different versions can't talk to each other.
+const Version = 5
+
+// Names of the RPC methods, each with the message it takes and the message it replies with.
+var (
+	EvolveWorldHandler          = "Broker.EvolveWorld"         // (EvolveWorldRequest) returns (EvolveResponse)
+	AliveCellsCountHandler      = "Broker.AliveCellsCount"     // (Empty) returns (AliveCellsCountResponse)
+	AliveCellsHandler           = "Broker.CalculateAliveCells" // (Empty) returns (CalculateAliveCellsResponse)
+	GetGlobalHandler            = "Broker.GetGlobal"           // (Empty) returns (GetGlobalResponse)
+	PauseHandler                = "Broker.Pause"               // (ControlRequest) returns (Empty)
+	HardPauseHandler            = "Broker.HardPause"           // (ControlRequest) returns (Empty)
+	UnpauseHandler              = "Broker.Unpause"             // (ControlRequest) returns (Empty)
+	QuitHandler                 = "Broker.QuitServer"          // (ControlRequest) returns (Empty)
+	KillServerHandler           = "Broker.KillServer"          // (ControlRequest) returns (Empty)
+	GetBrokerCellFlippedHandler = "Broker.GetCellFlipped"      // (Empty) returns (GetBrokerCellFlippedResponse)
+	GetTurnDoneHandler          = "Broker.GetTurnDone"         // (Empty) returns (GetTurnDoneResponse)
+	GetContinueHandler          = "Broker.GetContinue"         // (Empty) returns (GetContinueResponse)
+	AcquireHandler              = "Broker.Acquire"             // (Empty) returns (AcquireResponse)
+	HeartbeatHandler            = "Broker.Heartbeat"           // (ControlRequest) returns (Empty)
+	WorldHashHandler            = "Broker.WorldHash"           // (Empty) returns (WorldHashResponse)
+	GetAssignmentsHandler       = "Broker.GetAssignments"      // (Empty) returns (GetAssignmentsResponse)
+	LogHandler                  = "Broker.Log"                 // (LogRequest) returns (Empty)
+	PlanHandler                 = "Broker.Plan"                // (PlanRequest) returns (PlanResponse)
+	RunUntilHandler             = "Broker.RunUntil"            // (RunUntilRequest) returns (Empty)
+	SaveShardsHandler           = "Broker.SaveShards"          // (SaveShardsRequest) returns (SaveShardsResponse)
+	SubmitJobHandler            = "Broker.SubmitJob"           // (SubmitJobRequest) returns (SubmitJobResponse)
+	ListJobsHandler             = "Broker.ListJobs"            // (Empty) returns (ListJobsResponse)
+	GetJobStatusHandler         = "Broker.GetJobStatus"        // (JobStatusRequest) returns (JobStatusResponse)
+	SelfTestHandler             = "Broker.SelfTest"            // (Empty) returns (SelfTestResponse)
+	StoreReplicaHandler         = "Broker.StoreReplica"        // (StoreReplicaRequest) returns (Empty)
+	ListCheckpointsHandler      = "Broker.ListCheckpoints"     // (Empty) returns (ListCheckpointsResponse)
+	BeginUploadHandler          = "Broker.BeginUpload"         // (BeginUploadRequest) returns (BeginUploadResponse)
+	AppendUploadHandler         = "Broker.AppendUpload"        // (AppendUploadRequest) returns (Empty)
+	CommitUploadHandler         = "Broker.CommitUpload"        // (CommitUploadRequest) returns (Empty)
+	AutoPauseHandler            = "Broker.AutoPause"           // (AutoPauseRequest) returns (Empty)
+	MatchPatternHandler         = "Broker.MatchPattern"        // (MatchPatternRequest) returns (MatchPatternResponse)
+	SetRuleHandler              = "Broker.SetRule"             // (SetRuleRequest) returns (Empty)
+	SetCellsHandler             = "Broker.SetCells"            // (SetCellsRequest) returns (SetCellsResponse)
+	ObserveHandler              = "Broker.Observe"             // (ObserveRequest) returns (ObserveResponse)
+	RegisterWorkerHandler       = "Broker.RegisterWorker"      // (RegisterWorkerRequest) returns (Empty)
+	WorldHandler                = "WorldOps.CalculateWorld"    // (WorldReq) returns (WorldRes)
+	KillHandler                 = "WorldOps.KillWorker"        // (Empty) returns (Empty)
+	CancelHandler               = "WorldOps.Cancel"            // (CancelReq) returns (Empty)
+	SaveSliceHandler            = "WorldOps.SaveSlice"         // (SaveSliceReq) returns (Empty)
+)
+
+type EvolveResponse struct {
+	World slab.World
+	Turn  int
+}
+
+type EvolveWorldRequest struct {
+	World       slab.World
+	Width       int
+	Height      int
+	Turn        int
+	Threads     int
+	ImageHeight int
+	ImageWidth  int
+	Epoch       int
+	Rule        string // Rule in B/S notation, e.g. "B3/S23". Empty for Life.
+	Edge        string // "torus" or "dead". Empty for torus.
+	Seed        int64  // Seed of a stochastic rule's chances. A continued run keeps the seed it started with.
+	Upload      int    // ID of a committed upload to start from instead of World, or 0.
+	// Key/value tags to attach to the run, saved in its checkpoints. A continued run keeps its own unless given any.
+	Labels map[string]string
+}
+
+// BeginUploadRequest starts sending a world to the broker in chunks, for one too big to send whole in an
+// EvolveWorldRequest. Only the client in control may upload, and starting an upload discards any earlier one.
+type BeginUploadRequest struct {
+	Epoch  int
+	Width  int
+	Height int
+}
+
+type BeginUploadResponse struct {
+	ID int
+}
+
+// AppendUploadRequest carries the next cells of an upload, row after row, starting at cell Offset of the world.
+type AppendUploadRequest struct {
+	Epoch    int
+	ID       int
+	Offset   int
+	Cells    []byte
+	Checksum uint32 // CRC-32 (IEEE) of Cells.
+}
+
+// CommitUploadRequest finishes an upload, after which EvolveWorld can start from it once.
+type CommitUploadRequest struct {
+	Epoch    int
+	ID       int
+	Checksum uint32 // CRC-32 (IEEE) of every cell of the world.
+}
+
+type CalculateAliveCellsRequest struct {
+	World slab.World
+}
+
+type CalculateAliveCellsResponse struct {
+	AliveCells []util.Cell
+}
+
+type AliveCellsCountResponse struct {
+	AliveCellsCount int
+	CompletedTurns  int
+}
+
+type GetGlobalResponse struct {
+	World slab.World
+	Turn  int
+}
+
+type Empty struct {
+}
+
+type GetBrokerCellFlippedResponse struct {
+	FlippedEvents     []FlippedEvent
+	AssignmentVersion int // Changes whenever the broker hands the world out to its workers differently.
+	Turn              int // Latest completed turn, whether or not it flipped any cells.
+	// Why the run paused itself at Turn on a trigger set with AutoPause, or "" if it didn't. Cleared by Unpause.
+	Paused string
+	// The whole world at Turn, sent instead of FlippedEvents when the client fell so far behind that they outnumbered
+	// its cells, or empty. Skipped is how many turns it covers since the last poll.
+	Keyframe slab.World
+	Skipped  int
+}
+
+type GetTurnDoneResponse struct {
+	TurnDone bool
+	Turn     int
+}
+
+type GetContinueResponse struct {
+	Continue bool
+	World    slab.World
+	Turn     int
+	Labels   map[string]string // Labels of the run to continue.
+}
+
+type FlippedEvent struct {
+	CompletedTurns int
+	Cell           util.Cell
+}
+
+// ControlRequest carries the fencing epoch of the client issuing a control RPC.
+// The broker rejects requests whose epoch is not the current one.
+type ControlRequest struct {
+	Epoch int
+}
+
+// AutoPauseRequest sets the triggers that soft pause the run by themselves once something interesting happens, so a
+// long run can be left unattended. It replaces any triggers set before, and a zero field sets none. Triggers last
+// until another client takes control.
+type AutoPauseRequest struct {
+	Epoch      int
+	Population int         // Pause when the number of alive cells rises above this or falls back to it or below.
+	Steady     bool        // Pause once the run settles into a still life or oscillator.
+	Cells      []util.Cell // Pause when any of these cells changes state.
+	Pattern    slab.World  // Pause when this pattern appears, in any orientation. See pattern.Find.
+}
+
+// SetRuleRequest changes the rule or edge mode of the run in progress. The turn being computed finishes under the
+// old ones, and the change applies from the next turn on. An empty field keeps the run's current rule or edges.
+type SetRuleRequest struct {
+	Epoch int
+	Rule  string // Rule in B/S notation, e.g. "B36/S23".
+	Edge  string // "torus" or "dead".
+}
+
+// SetCellsRequest sets cells of a soft paused run alive or dead, e.g. ones drawn in the window. The run stays at the
+// turn it paused at, and the next turn is computed from the edited world.
+type SetCellsRequest struct {
+	Epoch int
+	Cells []util.Cell
+	Alive bool // Whether Cells are set alive, or dead.
+}
+
+// SetCellsResponse says how many of the cells were changed, the rest having already been in the state asked for.
+type SetCellsResponse struct {
+	Turn    int
+	Changed int
+}
+
+// ObserveRequest polls the latest generation for a remote observer, which unlike the client in control may fall
+// behind or drop out without holding up the run. Observer is the ID the last poll returned, or 0 for a new observer.
+// Budget is how many bytes this poll's view may take, e.g. a bandwidth of 2 Mbps over the time between polls,
+// or 0 for no limit. Held is the turn of the world the observer holds, from the last keyframe or delta it
+// received, or -1 if it holds none; a delta is only sent against that world, so a lost response is made up for
+// with a keyframe rather than deltas against a world the observer never got.
+type ObserveRequest struct {
+	Observer int
+	Budget   int
+	Held     int
+}
+
+// ObserveResponse is the latest generation in whichever form fits the observer's budget: "delta", the cells that
+// differ from the world the observer holds; "keyframe", the whole world; or "density", a coarser grid in
+// which each cell stands for a Scale x Scale block of the world and holds how many of its cells are alive, from
+// 0 for none to 255 for all. After a density grid the next view that fits is a keyframe, as there is nothing to
+// apply a delta to.
+type ObserveResponse struct {
+	Observer int // ID to send with the next poll.
+	Turn     int
+	Form     string
+	Flips    []util.Cell
+	Keyframe slab.World
+	Density  slab.World
+	Scale    int
+	Size     int // Estimated bytes the view takes to send.
+}
+
+// MatchPatternRequest asks where a small pattern appears in the latest generation. See pattern.Find for what
+// counts as a match.
+type MatchPatternRequest struct {
+	Pattern        slab.World
+	AnyOrientation bool // Also look for the pattern rotated and reflected.
+	Limit          int  // Most matches to return, or 0 for all of them.
+}
+
+// MatchPatternResponse lists the matches found in the generation at Turn, in row order for each orientation.
+type MatchPatternResponse struct {
+	Turn    int
+	Matches []PatternMatch
+}
+
+// PatternMatch is a place a pattern appears, given by its top left corner, and which orientation appeared there.
+type PatternMatch struct {
+	Cell        util.Cell
+	Orientation int // Index into pattern.Orientations of the pattern, 0 for the pattern as sent.
+}
+
+// RunUntilRequest asks the broker to resume the run, if paused, and pause it again once Turn has been completed.
+type RunUntilRequest struct {
+	Epoch int
+	Turn  int
+}
+
+type AcquireResponse struct {
+	Epoch int
+}
+
+// WorldHashResponse describes the current world's hash and how many states the run has visited.
+type WorldHashResponse struct {
+	Hash      uint64
+	Turn      int
+	Distinct  int // Number of distinct states seen this run.
+	Repeated  int // Number of turns that produced a state seen earlier in the run.
+	Period    int // Length of the cycle the simulation has entered, or 0 if no state has repeated.
+	CycleTurn int // Turn at which the first repeated state was reached.
+}
+
+// Assignment describes the region of the world a worker computes.
+// Rows and columns are half-open ranges, so a row-sliced world has StartCol 0 and EndCol equal to its width.
+type Assignment struct {
+	Worker   int // Index of the worker in the broker's worker list.
+	StartRow int
+	EndRow   int
+	StartCol int
+	EndCol   int
+}
+
+// SelfTestResponse is the outcome of the broker's checks of its own kernel and of each worker.
+type SelfTestResponse struct {
+	Report string // A PASS or FAIL line per check.
+	Passed bool
+}
+
+// StoreReplicaRequest is a checkpoint another broker replicates to this one, to be saved under its replica
+// directory as Name, e.g. "jobs/job-3/checkpoint".
+type StoreReplicaRequest struct {
+	Name string
+	Data []byte
+}
+
+// JobSpec describes a run to queue on the broker, which carries it out once no client controls it.
+type JobSpec struct {
+	Name  string     // Label to tell the job apart in listings, e.g. "512x512 seed 7".
+	World slab.World // Starting world.
+	Turns int
+	Rule  string // Rule in B/S notation, e.g. "B3/S23". Empty for Life.
+	Edge  string // "torus" or "dead". Empty for torus.
+	Seed  int64  // Seed of a stochastic rule's chances.
+	// Key/value tags, e.g. experiment and author, kept with the job's status and saved in its checkpoints.
+	Labels map[string]string
+}
+
+type SubmitJobRequest struct {
+	Job JobSpec
+}
+
+type SubmitJobResponse struct {
+	ID       int
+	Position int // Number of jobs queued or running ahead of it.
+}
+
+// JobStatus is how far a queued job has got, and what it found once it is done.
+type JobStatus struct {
+	ID         int
+	Name       string
+	State      string // JobQueued, JobRunning, JobDone or JobFailed.
+	Width      int
+	Height     int
+	Turns      int
+	Seed       int64
+	Turn       int    // Turns completed so far.
+	Alive      int    // Alive cells in the final world, once done.
+	Period     int    // Length of the cycle the world ended up in, or 0 if none was found.
+	Output     string // Image of the final world, once done.
+	Checkpoint string // Checkpoint of the latest generation saved, which the broker can -resume from.
+	Error      string // Why the job failed.
+	Submitted  time.Time
+	Started    time.Time
+	Finished   time.Time
+	Labels     map[string]string
+}
+
+type ListJobsResponse struct {
+	Jobs []JobStatus
+}
+
+type JobStatusRequest struct {
+	ID int
+}
+
+type JobStatusResponse struct {
+	Job JobStatus
+}
+
+// PlanRequest describes a run for the broker to plan without starting it.
+type PlanRequest struct {
+	ImageWidth  int
+	ImageHeight int
+	Turns       int
+	Rule        string // Rule in B/S notation, e.g. "B3/S23". Empty for Life.
+	Edge        string // "torus" or "dead". Empty for torus.
+}
+
+// PlanResponse is how the broker would carry out a run with its current workers. Sizes are estimates in bytes.
+type PlanResponse struct {
+	Workers         int          // Number of workers the world would be split between, or 0 if the broker would compute every turn.
+	Kernel          string       // How the broker counts neighbours in the turns it computes itself.
+	Assignments     []Assignment // Region of the world each worker would compute.
+	SentPerTurn     int64        // Sent to the workers each turn. Every worker is sent the whole world.
+	ReceivedPerTurn int64        // Sent back by the workers each turn.
+	Transfer        int64        // Sent and received over every turn of the run.
+	BrokerMemory    int64        // Heap the broker needs during a turn.
+	WorkerMemory    []int64      // Heap each worker needs during a turn, in the order of Assignments.
+}
+
+// SaveShardsRequest asks the broker to save the current world as one image per worker slice.
+type SaveShardsRequest struct {
+	Dir string // Directory or bucket URL the broker and every worker can write to.
+}
+
+// Shard is one of the images a world was saved as, holding the half-open range of rows StartRow to EndRow.
+type Shard struct {
+	StartRow int
+	EndRow   int
+	File     string // Name of the image in the directory it was saved to.
+	Worker   int    // Index of the worker that saved it, or -1 if the broker did.
+}
+
+type SaveShardsResponse struct {
+	Turn     int
+	Manifest string // Path of the file listing the shards.
+	Shards   []Shard
+}
+
+type GetAssignmentsResponse struct {
+	Assignments []Assignment
+	Version     int
+	Turn        int
+}
+
+// LogLine is a line a worker wrote to its log, and when it was written.
+type LogLine struct {
+	Time time.Time
+	Text string
+}
+
+// LogRequest carries log lines from a worker to the broker.
+type LogRequest struct {
+	Worker string // Name the lines are tagged with in the combined log, e.g. host:port.
+	Lines  []LogLine
+}
+
+// RegisterWorkerRequest asks the broker to split turns with a worker it didn't find scanning its own ports.
+type RegisterWorkerRequest struct {
+	Address string // Address the broker can reach the worker at, e.g. 10.0.0.7:8040, rather than any it binds.
+}
+
+// CheckpointInfo describes a checkpoint file the broker can be resumed from.
+type CheckpointInfo struct {
+	Path       string
+	Turn       int
+	Saved      time.Time
+	Bytes      int64
+	Compressed bool              // Whether it is an earlier checkpoint kept, gzipped, in a history directory.
+	Labels     map[string]string // Labels of the run saved in it.
+}
+
+type ListCheckpointsResponse struct {
+	Checkpoints []CheckpointInfo
+}
+
+type WorldReq struct {
+	World    slab.World
+	Width    int
+	Height   int
+	StartRow int
+	EndRow   int
+	Rule     string // Rule in B/S notation, e.g. "B3/S23". Empty for Life.
+	Edge     string // "torus" or "dead". Empty for torus.
+	Job      int    // Run the slice belongs to, so the broker can cancel it with CancelHandler.
+	Turn     int    // Turn World is at. Together with Job, this tags the slice with the turn it belongs to.
+	Seed     int64  // Seed of a stochastic rule's chances, which also depend on Turn.
+	Trace    string // W3C traceparent of the broker's span for the call, so the worker's span joins its trace.
+}
+
+// WorldRes carries a computed slice back, tagged with the run, turn and rows of the request it answers so the
+// broker can discard a slice that doesn't belong to the turn it is about to commit.
+type WorldRes struct {
+	World    slab.World
+	Job      int
+	Turn     int
+	StartRow int
+	EndRow   int
+	Checksum uint32 // CRC-32 (IEEE) of World's cells, set with Seal by the worker and checked with Intact by the broker.
+}
+
+// CancelReq asks a worker to stop computing every slice of a run the broker has given up on.
+type CancelReq struct {
+	Job int
+}
+
+// SaveSliceReq asks a worker to save the slice it computed for a run's turn, tagged as in WorldReq, to Path.
+type SaveSliceReq struct {
+	Job      int
+	Turn     int
+	StartRow int
+	EndRow   int
+	Path     string // File or bucket URL to save the slice to as a PGM image.
+}
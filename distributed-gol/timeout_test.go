@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/stubs"
+)
+
+// hangingBroker answers enough of the broker's RPCs for a run, but never answers a poll for flipped cells.
+type hangingBroker struct {
+	release chan struct{} // Closed to let the hung polls return at the end of the test.
+}
+
+func (b *hangingBroker) Acquire(req stubs.Empty, res *stubs.AcquireResponse) error {
+	res.Epoch = 1
+	return nil
+}
+
+func (b *hangingBroker) GetContinue(req stubs.Empty, res *stubs.GetContinueResponse) error {
+	return nil
+}
+
+func (b *hangingBroker) GetCellFlipped(req stubs.Empty, res *stubs.GetBrokerCellFlippedResponse) error {
+	<-b.release
+	return nil
+}
+
+// EvolveWorld returns the world unchanged after long enough for the client to have polled several times.
+func (b *hangingBroker) EvolveWorld(req stubs.EvolveWorldRequest, res *stubs.EvolveResponse) error {
+	time.Sleep(500 * time.Millisecond)
+	res.World, res.Turn = req.World, req.Turn
+	return nil
+}
+
+func (b *hangingBroker) CalculateAliveCells(req stubs.CalculateAliveCellsRequest, res *stubs.CalculateAliveCellsResponse) error {
+	return nil
+}
+
+// TestRPCTimeout checks a broker that stops answering the live view's polls is reported with a recoverable
+// ErrorEvent once the timeout passes, and the run still finishes rather than the client hanging.
+func TestRPCTimeout(t *testing.T) {
+	broker := &hangingBroker{release: make(chan struct{})}
+	defer close(broker.release)
+	server := rpc.NewServer()
+	if err := server.RegisterName("Broker", broker); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go server.Accept(listener)
+	defer func(address string) { gol.BrokerAddress = address }(gol.BrokerAddress)
+	gol.BrokerAddress = listener.Addr().String()
+
+	out, err := ioutil.TempDir("", "timeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Threads: 1, Turns: 10, OutDir: out, RPCTimeout: 50 * time.Millisecond}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+
+	timedOut, finished := false, false
+	timeout := time.After(5 * time.Second)
+	for open := true; open; {
+		select {
+		case event, ok := <-events:
+			switch e := event.(type) {
+			case gol.ErrorEvent:
+				if !e.Recoverable {
+					t.Fatalf("expected only recoverable errors, received %v", e)
+				}
+				timedOut = timedOut || strings.Contains(e.Message, stubs.GetBrokerCellFlippedHandler+" got no reply")
+			case gol.FinalTurnComplete:
+				finished = true
+			}
+			open = ok
+		case <-timeout:
+			t.Fatal("the run didn't finish with the broker's polls hanging")
+		}
+	}
+	if !timedOut {
+		t.Error("expected an ErrorEvent saying the poll for flipped cells timed out")
+	}
+	if !finished {
+		t.Error("expected the run to finish with a FinalTurnComplete")
+	}
+}
@@ -0,0 +1,350 @@
+// Package tracing records spans of the broker's and workers' work and sends them to an OpenTelemetry collector
+// over OTLP/HTTP, so a slow turn can be followed to the node and call that held it up. Spans carry their trace
+// across RPCs in a W3C traceparent string, since net/rpc has nowhere else to put it.
+//
+// Until Enable is called spans are nil and cost next to nothing, so code can be instrumented unconditionally.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Span kinds, as numbered by OTLP.
+const (
+	kindInternal = 1
+	kindServer   = 2
+	kindClient   = 3
+)
+
+// Status codes, as numbered by OTLP.
+const statusError = 2
+
+// Span is an operation being timed. A nil Span, as returned while tracing is off, ignores every call.
+type Span struct {
+	exporter   *exporter
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte // All zero for the root of a trace.
+	name       string
+	kind       int
+	start      time.Time
+	attributes []attribute
+	err        string // Why the operation failed, or empty if it didn't.
+}
+
+// spanKey is the context key the current span is stored under.
+type spanKey struct{}
+
+// spanContext identifies a span, possibly in another process, that new spans are started as children of.
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+// active is the *exporter spans are sent to, or holds a nil one while tracing is off.
+var active atomic.Value
+
+// Enable starts sending spans to the OTLP/HTTP collector at endpoint, e.g. http://localhost:4318, tagged with the
+// name of the service and the instance of it this process is, e.g. "worker" and "lab-12:8041". It should be called
+// once, before any spans are started.
+func Enable(endpoint, service, instance string) {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	e := &exporter{
+		url:      url,
+		resource: []attribute{stringAttribute("service.name", service), stringAttribute("service.instance.id", instance)},
+		client:   &http.Client{Timeout: 10 * time.Second},
+		kick:     make(chan struct{}, 1),
+	}
+	active.Store(e)
+	go e.run()
+}
+
+// Flush sends the spans that have ended so far, e.g. before the process exits.
+func Flush() {
+	if e, _ := active.Load().(*exporter); e != nil {
+		e.flush()
+	}
+}
+
+// Start begins a span of the named operation, as a child of the span in ctx if there is one and otherwise as the
+// root of a new trace. The returned context carries the new span, and must be used to start its children.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return start(ctx, name, kindInternal)
+}
+
+// StartClient is Start for a call to another process, whose spans continue the trace from Header.
+func StartClient(ctx context.Context, name string) (context.Context, *Span) {
+	return start(ctx, name, kindClient)
+}
+
+// StartServer is Start for handling a call from another process, continuing the trace of the caller's span
+// described by header, as returned by Header on its side. An empty or malformed header starts a new trace.
+func StartServer(ctx context.Context, name, header string) (context.Context, *Span) {
+	if parent, ok := parseHeader(header); ok {
+		ctx = context.WithValue(ctx, spanKey{}, parent)
+	}
+	return start(ctx, name, kindServer)
+}
+
+func start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	e, _ := active.Load().(*exporter)
+	if e == nil {
+		return ctx, nil
+	}
+	s := &Span{exporter: e, name: name, kind: kind, start: time.Now()}
+	if parent, ok := ctx.Value(spanKey{}).(spanContext); ok {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, spanContext{s.traceID, s.spanID}), s
+}
+
+// Header returns the W3C traceparent of the span in ctx, to send along with an RPC made on its behalf, or an
+// empty string if there is none.
+func Header(ctx context.Context) string {
+	parent, ok := ctx.Value(spanKey{}).(spanContext)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(parent.traceID[:]), hex.EncodeToString(parent.spanID[:]))
+}
+
+// parseHeader reads a traceparent written by Header.
+func parseHeader(header string) (spanContext, bool) {
+	var parent spanContext
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return parent, false
+	}
+	if _, err := hex.Decode(parent.traceID[:], []byte(parts[1])); err != nil {
+		return parent, false
+	}
+	if _, err := hex.Decode(parent.spanID[:], []byte(parts[2])); err != nil {
+		return parent, false
+	}
+	return parent, true
+}
+
+// SetInt records a number describing the operation, e.g. the turn it computed.
+func (s *Span) SetInt(key string, value int) {
+	if s != nil {
+		s.attributes = append(s.attributes, intAttribute(key, int64(value)))
+	}
+}
+
+// SetString records a string describing the operation.
+func (s *Span) SetString(key, value string) {
+	if s != nil {
+		s.attributes = append(s.attributes, stringAttribute(key, value))
+	}
+}
+
+// Fail marks the operation as failed with err, if it isn't nil.
+func (s *Span) Fail(err error) {
+	if s != nil && err != nil {
+		s.err = err.Error()
+	}
+}
+
+// End finishes the span and queues it to be sent. The span must not be used afterwards.
+func (s *Span) End() {
+	if s != nil {
+		s.exporter.add(s.encode(time.Now()))
+	}
+}
+
+// Limits on the spans queued to be sent. Past maxQueued, e.g. while the collector is unreachable, new spans
+// are dropped rather than let the queue grow without bound.
+const (
+	batchSize     = 512
+	maxQueued     = 16384
+	flushInterval = 2 * time.Second
+)
+
+// exporter queues ended spans and sends them to the collector in batches.
+type exporter struct {
+	url      string
+	resource []attribute
+	client   *http.Client
+	kick     chan struct{} // Signalled when a full batch is waiting.
+
+	mu      sync.Mutex
+	queue   []otlpSpan
+	dropped int  // Spans dropped since the last warning.
+	failing bool // Whether the last batch couldn't be sent, so the warning isn't repeated for every batch.
+}
+
+// add queues a span, waking the sender once a full batch is waiting.
+func (e *exporter) add(span otlpSpan) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.queue) >= maxQueued {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, span)
+	if len(e.queue) >= batchSize {
+		select {
+		case e.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run sends the queued spans every flushInterval, or sooner once a batch is full.
+func (e *exporter) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.kick:
+		}
+		e.flush()
+	}
+}
+
+// flush sends everything queued. A batch the collector doesn't accept is dropped, and reported once until a
+// batch is sent again.
+func (e *exporter) flush() {
+	e.mu.Lock()
+	spans, dropped := e.queue, e.dropped
+	e.queue, e.dropped = nil, 0
+	e.mu.Unlock()
+	if dropped > 0 {
+		fmt.Printf("Warning: dropped %d spans the collector couldn't keep up with\n", dropped)
+	}
+
+	for len(spans) > 0 {
+		n := len(spans)
+		if n > batchSize {
+			n = batchSize
+		}
+		err := e.send(spans[:n])
+		spans = spans[n:]
+
+		e.mu.Lock()
+		report := err != nil && !e.failing
+		e.failing = err != nil
+		e.mu.Unlock()
+		if report {
+			fmt.Printf("Warning: couldn't send spans to %s: %v\n", e.url, err)
+		}
+	}
+}
+
+// send posts a batch of spans to the collector.
+func (e *exporter) send(spans []otlpSpan) error {
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: e.resource},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "uk.ac.bris.cs/gameoflife"}, Spans: spans}},
+	}}})
+	if err != nil {
+		return err
+	}
+	res, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("collector replied %s", res.Status)
+	}
+	return nil
+}
+
+// The OTLP/HTTP JSON encoding of spans, of which only the fields used here are declared. IDs are hex and
+// 64-bit integers are decimal strings, as the encoding requires.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []attribute `json:"attributes,omitempty"`
+	Status            otlpStatus  `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type attribute struct {
+	Key   string         `json:"key"`
+	Value attributeValue `json:"value"`
+}
+
+type attributeValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func stringAttribute(key, value string) attribute {
+	return attribute{key, attributeValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int64) attribute {
+	text := strconv.FormatInt(value, 10)
+	return attribute{key, attributeValue{IntValue: &text}}
+}
+
+// encode converts a span ending at end to the form it is sent in.
+func (s *Span) encode(end time.Time) otlpSpan {
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+		Attributes:        s.attributes,
+	}
+	if s.parentID != ([8]byte{}) {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	if s.err != "" {
+		span.Status = otlpStatus{Code: statusError, Message: s.err}
+	}
+	return span
+}
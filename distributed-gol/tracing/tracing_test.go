@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// TestExport sends a trace spanning a call between two processes to a stand-in collector, checking the spans
+// arrive as OTLP/HTTP JSON with the caller's span as the parent of the callee's.
+func TestExport(t *testing.T) {
+	requests := make(chan otlpRequest, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("spans posted to %s as %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var req otlpRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Error(err)
+		}
+		requests <- req
+	}))
+	defer collector.Close()
+
+	Enable(collector.URL, "gol-broker", "test:8030")
+	defer active.Store((*exporter)(nil))
+
+	ctx, turn := Start(context.Background(), "turn")
+	turn.SetInt("turn", 7)
+	callCtx, call := StartClient(ctx, "CalculateWorld")
+	_, server := StartServer(context.Background(), "CalculateWorld", Header(callCtx))
+	server.Fail(errors.New("cancelled"))
+	server.End()
+	call.End()
+	turn.End()
+	Flush()
+
+	req := <-requests
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("expected one resource and scope, got %+v", req)
+	}
+	if attributes := req.ResourceSpans[0].Resource.Attributes; len(attributes) != 2 || *attributes[0].Value.StringValue != "gol-broker" {
+		t.Errorf("resource attributes %+v don't name the service", attributes)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	served, called, root := spans[0], spans[1], spans[2]
+	if root.ParentSpanID != "" || called.ParentSpanID != root.SpanID || served.ParentSpanID != called.SpanID {
+		t.Errorf("spans aren't nested: %+v", spans)
+	}
+	if served.TraceID != root.TraceID || called.TraceID != root.TraceID {
+		t.Errorf("spans belong to different traces: %+v", spans)
+	}
+	if served.Kind != kindServer || called.Kind != kindClient || root.Kind != kindInternal {
+		t.Errorf("span kinds %d, %d, %d", served.Kind, called.Kind, root.Kind)
+	}
+	if served.Status.Code != statusError || served.Status.Message != "cancelled" {
+		t.Errorf("failed span has status %+v", served.Status)
+	}
+	if len(root.Attributes) != 1 || *root.Attributes[0].Value.IntValue != "7" {
+		t.Errorf("root span has attributes %+v", root.Attributes)
+	}
+}
+
+// TestDisabled checks spans are nil, and safe to use, until tracing is enabled.
+func TestDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "turn")
+	if span != nil || Header(ctx) != "" {
+		t.Fatal("a span was started with tracing off")
+	}
+	span.SetInt("turn", 1)
+	span.Fail(errors.New("failed"))
+	span.End()
+}
+
+// TestDroppedWhileUnreachable checks spans are still recorded while the collector fails, and sent once it
+// recovers.
+func TestDroppedWhileUnreachable(t *testing.T) {
+	var failing int32 = 1
+	var received int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var req otlpRequest
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		atomic.AddInt32(&received, int32(len(req.ResourceSpans[0].ScopeSpans[0].Spans)))
+	}))
+	defer collector.Close()
+	Enable(collector.URL+"/v1/traces", "gol-worker", "test:8040")
+	defer active.Store((*exporter)(nil))
+
+	_, span := Start(context.Background(), "lost")
+	span.End()
+	Flush()
+	atomic.StoreInt32(&failing, 0)
+	_, span = Start(context.Background(), "sent")
+	span.End()
+	Flush()
+	if n := atomic.LoadInt32(&received); n != 1 {
+		t.Errorf("collector received %d spans after recovering, expected 1", n)
+	}
+}
+
+// TestParseHeader checks malformed traceparents start a new trace rather than join a bogus one.
+func TestParseHeader(t *testing.T) {
+	for _, header := range []string{"", "00-abc-def-01", "00-" + string(make([]byte, 32)) + "-0123456789abcdef-01"} {
+		if _, ok := parseHeader(header); ok {
+			t.Errorf("accepted %q", header)
+		}
+	}
+	if _, ok := parseHeader("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"); !ok {
+		t.Error("rejected a valid traceparent")
+	}
+}
@@ -0,0 +1,142 @@
+package util
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ApplyConfig reads flag values from a YAML (.yaml, .yml) or TOML (.toml) config file into fs.
+// Flags given on the command line take precedence, so fs must already have been parsed.
+//
+// One file can configure every binary of a run: top-level keys apply to any binary that has a flag with that
+// name, and keys under a section named after the binary (e.g. "broker:" in YAML or "[broker]" in TOML) apply
+// only to that binary. Unknown keys in a binary's own section are reported as errors, as are malformed lines.
+// Only flat key/value pairs are supported; nested sections and lists are not.
+func ApplyConfig(fs *flag.FlagSet, path, section string) error {
+	values, err := readConfig(path, section)
+	if err != nil {
+		return err
+	}
+
+	// Remember which flags were given explicitly so the file doesn't override them.
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for _, v := range values {
+		if fs.Lookup(v.key) == nil {
+			if v.section == "" {
+				continue // Meant for one of the other binaries.
+			}
+			return fmt.Errorf("%s:%d: unknown setting %q for %s", path, v.line, v.key, section)
+		}
+		if explicit[v.key] {
+			continue
+		}
+		if err := fs.Set(v.key, v.value); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, v.line, err)
+		}
+	}
+	return nil
+}
+
+// configValue is a single setting read from a config file.
+type configValue struct {
+	section string // Section the setting was in, or "" for top-level settings.
+	key     string
+	value   string
+	line    int
+}
+
+// readConfig returns the top-level settings of a config file and those in the given section, in file order.
+func readConfig(path, section string) ([]configValue, error) {
+	var separator string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		separator = ":"
+	case ".toml":
+		separator = "="
+	default:
+		return nil, fmt.Errorf("%s: config files must be .yaml, .yml or .toml", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var values []configValue
+	current := ""
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := stripComment(scanner.Text())
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			continue
+		}
+
+		// TOML section header, e.g. [broker].
+		if separator == "=" && strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			current = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			continue
+		}
+
+		i := strings.Index(trimmed, separator)
+		if i <= 0 {
+			return nil, fmt.Errorf("%s:%d: expected key%svalue", path, line, separator)
+		}
+		key := strings.TrimSpace(trimmed[:i])
+		value := unquote(strings.TrimSpace(trimmed[i+1:]))
+
+		if separator == ":" {
+			indented := strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")
+			if !indented {
+				if value == "" {
+					// YAML section header, e.g. broker:
+					current = key
+					continue
+				}
+				current = ""
+			} else if current == "" {
+				return nil, fmt.Errorf("%s:%d: indented setting outside a section", path, line)
+			}
+		}
+
+		if current == "" || current == section {
+			values = append(values, configValue{section: current, key: key, value: value, line: line})
+		}
+	}
+	return values, scanner.Err()
+}
+
+// stripComment removes a trailing # comment that isn't inside quotes.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote removes matching single or double quotes around a value.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
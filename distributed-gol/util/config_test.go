@@ -0,0 +1,100 @@
+package util
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeConfig writes a config file with the given name into a new temporary directory.
+func writeConfig(t *testing.T, name, contents string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// brokerFlags returns a flag set like the broker's, parsed from args.
+func brokerFlags(t *testing.T, args ...string) (*flag.FlagSet, *string, *int) {
+	fs := flag.NewFlagSet("broker", flag.ContinueOnError)
+	port := fs.String("port", "8030", "")
+	endPort := fs.Int("endPort", 8050, "")
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return fs, port, endPort
+}
+
+// TestApplyConfig checks YAML and TOML files set the same flags, and that the command line wins.
+func TestApplyConfig(t *testing.T) {
+	files := map[string]string{
+		"run.yaml": "# Shared settings.\nturns: 100\nendPort: 8043\n\nbroker:\n  port: \"9030\" # Quoted.\n\nworker:\n  port: 9040\n",
+		"run.toml": "# Shared settings.\nturns = 100\nendPort = 8043\n\n[broker]\nport = \"9030\" # Quoted.\n\n[worker]\nport = 9040\n",
+	}
+	for name, contents := range files {
+		path := writeConfig(t, name, contents)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		fs, port, endPort := brokerFlags(t)
+		if err := ApplyConfig(fs, path, "broker"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if *port != "9030" || *endPort != 8043 {
+			t.Errorf("%s: got port %s and endPort %d, expected 9030 and 8043", name, *port, *endPort)
+		}
+
+		fs, port, _ = brokerFlags(t, "-port", "7030")
+		if err := ApplyConfig(fs, path, "broker"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if *port != "7030" {
+			t.Errorf("%s: the config file overrode -port, got %s", name, *port)
+		}
+	}
+}
+
+// TestApplyConfigUnknownSetting checks typos in a binary's own section are reported rather than ignored.
+func TestApplyConfigUnknownSetting(t *testing.T) {
+	path := writeConfig(t, "run.yaml", "broker:\n  prot: 9030\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	fs, _, _ := brokerFlags(t)
+	if err := ApplyConfig(fs, path, "broker"); err == nil {
+		t.Error("expected an error for an unknown broker setting")
+	}
+}
+
+// TestApplyConfigRunOptions checks one file can set the rule and edge for the client and the engine for the
+// broker, and that the broker's section doesn't leak into the client.
+func TestApplyConfigRunOptions(t *testing.T) {
+	path := writeConfig(t, "run.toml", "rule = \"B36/S23\"\nedge = \"dead\"\n\n[broker]\nengine = \"local\"\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	client := flag.NewFlagSet("client", flag.ContinueOnError)
+	rule := client.String("rule", "B3/S23", "")
+	edge := client.String("edge", "torus", "")
+	if err := client.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyConfig(client, path, "client"); err != nil {
+		t.Fatal(err)
+	}
+	if *rule != "B36/S23" || *edge != "dead" {
+		t.Errorf("got rule %s and edge %s, expected B36/S23 and dead", *rule, *edge)
+	}
+
+	fs, _, _ := brokerFlags(t)
+	engine := fs.String("engine", "workers", "")
+	if err := ApplyConfig(fs, path, "broker"); err != nil {
+		t.Fatal(err)
+	}
+	if *engine != "local" {
+		t.Errorf("got engine %s, expected local", *engine)
+	}
+}
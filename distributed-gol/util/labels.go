@@ -0,0 +1,57 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Labels are key/value tags attached to a run, e.g. experiment=gliders author=kim, so its checkpoints and results
+// can be told apart from those of other experiments. As a flag.Value, each -label key=value adds one.
+type Labels map[string]string
+
+// Set adds a label given as key=value, replacing any earlier value for the key.
+func (l *Labels) Set(label string) error {
+	key, value, err := ParseLabel(label)
+	if err != nil {
+		return err
+	}
+	if *l == nil {
+		*l = make(Labels)
+	}
+	(*l)[key] = value
+	return nil
+}
+
+// String returns the labels as key=value pairs separated by spaces, sorted by key.
+func (l Labels) String() string {
+	pairs := make([]string, 0, len(l))
+	for _, key := range l.Keys() {
+		pairs = append(pairs, key+"="+l[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Keys returns the labels' keys in order.
+func (l Labels) Keys() []string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseLabel splits a label given as key=value. The key must not be empty or contain spaces, but the value may
+// be anything, including empty.
+func ParseLabel(label string) (key, value string, err error) {
+	i := strings.Index(label, "=")
+	if i < 0 {
+		return "", "", fmt.Errorf("label %q isn't key=value", label)
+	}
+	key, value = label[:i], label[i+1:]
+	if key == "" || strings.ContainsAny(key, " \t\n") {
+		return "", "", fmt.Errorf("label %q needs a key without spaces before the =", label)
+	}
+	return key, value, nil
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"flag"
+	"testing"
+)
+
+// TestLabels checks repeated -label flags build up the labels, a later value replacing an earlier one for the
+// same key, and malformed labels are refused.
+func TestLabels(t *testing.T) {
+	var labels Labels
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(&labels, "label", "")
+	args := []string{"-label", "experiment=gliders", "-label", "notes=a = b", "-label", "author=kim", "-label", "experiment=guns", "-label", "empty="}
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := labels.String(), "author=kim empty= experiment=guns notes=a = b"; got != expected {
+		t.Errorf("got labels %q, expected %q", got, expected)
+	}
+
+	for _, bad := range []string{"experiment", "=gliders", "my experiment=gliders"} {
+		if err := labels.Set(bad); err == nil {
+			t.Errorf("label %q was accepted", bad)
+		}
+	}
+	if Labels(nil).String() != "" {
+		t.Errorf("no labels printed as %q", Labels(nil).String())
+	}
+}
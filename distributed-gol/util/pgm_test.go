@@ -0,0 +1,52 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestParsePgm checks images in other formats and bit depths load as the board they show.
+func TestParsePgm(t *testing.T) {
+	expected := []byte{Dead, Alive, Alive, Dead}
+	tests := []struct {
+		name      string
+		data      string
+		threshold float64
+	}{
+		{"standard", "P5\n2 2\n255\n\x00\xff\xff\x00", 0},
+		{"whitespace valued pixels", "P5 2 2 255\n\x0a\xff\xc0\x20", 0},
+		{"comments", "P5\n# made by another tool\n2 2 # size\n255\n\x00\xff\xff\x00", 0},
+		{"maxval 1", "P5\n2 2\n1\n\x00\x01\x01\x00", 0},
+		{"16 bit", "P5\n2 2\n65535\n\x00\x00\xff\xff\x80\x00\x7f\xff", 0},
+		{"plain", "P2\n2 2\n15\n0 15\n9 3\n", 0},
+		{"threshold", "P5\n2 2\n255\n\x10\x20\x30\x0f", 0.1},
+	}
+	for _, test := range tests {
+		width, height, cells, err := ParsePgm([]byte(test.data), test.threshold)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if width != 2 || height != 2 || !bytes.Equal(cells, expected) {
+			t.Errorf("%s: got %dx%d %v, expected 2x2 %v", test.name, width, height, cells, expected)
+		}
+	}
+
+	for _, bad := range []string{"", "P6\n2 2\n255\n", "P5\n2 2\n", "P5\n2 2\n255\n\x00", "P5\n0 2\n255\n", "P5\n2 2\n70000\n"} {
+		if _, _, _, err := ParsePgm([]byte(bad), 0); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+// TestFormatPgm checks an image written by FormatPgm reads back as the same cells.
+func TestFormatPgm(t *testing.T) {
+	rows := [][]byte{{Dead, Alive, Dead}, {Alive, Alive, Dead}}
+	width, height, cells, err := ParsePgm(FormatPgm(rows), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 3 || height != 2 || !bytes.Equal(cells, append(append([]byte{}, rows[0]...), rows[1]...)) {
+		t.Errorf("got %dx%d %v, expected 3x2 %v", width, height, cells, rows)
+	}
+}
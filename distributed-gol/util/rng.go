@@ -0,0 +1,60 @@
+package util
+
+import "math/rand"
+
+// TurnRNG hands out random numbers for one turn of a seeded run. Every number is derived from the seed, the turn
+// and the cell it is drawn for alone, never from what was drawn before, so a run makes the same random choices
+// however many threads or workers compute it, in whatever order, and wherever it is resumed from.
+type TurnRNG struct {
+	seed uint64
+	turn uint64
+}
+
+// NewTurnRNG returns the random numbers of the given turn of a run with the given seed.
+func NewTurnRNG(seed int64, turn int) TurnRNG {
+	return TurnRNG{seed: uint64(seed), turn: uint64(turn)}
+}
+
+// Uint64 returns the random number of cell (x, y).
+func (r TurnRNG) Uint64(x, y int) uint64 {
+	return mix(r.seed ^ mix(r.turn^mix(uint64(uint32(y))<<32|uint64(uint32(x)))))
+}
+
+// Float64 returns the random number of cell (x, y) as a float in [0, 1), e.g. to compare with a probability.
+func (r TurnRNG) Float64(x, y int) float64 {
+	return float64(r.Uint64(x, y)>>11) / (1 << 53)
+}
+
+// Rand returns a generator for cell (x, y), for when a cell needs more than one number. It starts from the same
+// state every time it is asked for, so it must be kept rather than asked for again for each number.
+func (r TurnRNG) Rand(x, y int) *rand.Rand {
+	return rand.New(&splitMix{state: r.Uint64(x, y)})
+}
+
+// splitMix is the SplitMix64 generator, a rand.Source64 whose whole state is one word.
+type splitMix struct {
+	state uint64
+}
+
+func (s *splitMix) Uint64() uint64 {
+	// mix adds the increment before finalising, so this is the state advanced by one step.
+	x := mix(s.state)
+	s.state += 0x9E3779B97F4A7C15
+	return x
+}
+
+func (s *splitMix) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+func (s *splitMix) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+// mix is the SplitMix64 finaliser, which spreads every bit of x over the whole result.
+func mix(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ x>>30) * 0xBF58476D1CE4E5B9
+	x = (x ^ x>>27) * 0x94D049BB133111EB
+	return x ^ x>>31
+}
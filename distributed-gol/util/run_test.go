@@ -0,0 +1,22 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRunName checks runs are named after their start time and seed, and a zero seed is replaced.
+func TestRunName(t *testing.T) {
+	start := time.Date(2024, time.January, 31, 15, 45, 2, 0, time.UTC)
+	if name := RunName(start, 7731); name != "20240131-154502-7731" {
+		t.Errorf("RunName = %q, expected 20240131-154502-7731", name)
+	}
+	if seed := RunSeed(42); seed != 42 {
+		t.Errorf("RunSeed(42) = %d, expected the given seed", seed)
+	}
+	for i := 0; i < 100; i++ {
+		if seed := RunSeed(0); seed <= 0 {
+			t.Fatalf("RunSeed(0) = %d, expected a positive seed", seed)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"syscall"
+	"time"
+)
+
+// MiB is the unit heap thresholds are given in on the command line.
+const MiB = 1 << 20
+
+// HeapWatchdog samples the heap every Interval, so a slow leak in a run lasting days is noticed before the
+// machine runs out of memory. It logs a warning when the heap grows past Warn and calls OnLimit when it grows
+// past Limit even after a forced garbage collection.
+type HeapWatchdog struct {
+	Interval time.Duration
+	Warn     uint64            // Heap size in bytes above which a warning is logged, or 0 for no warnings.
+	Limit    uint64            // Heap size in bytes above which OnLimit is called, or 0 for no limit.
+	Log      io.Writer         // Where warnings are written.
+	OnLimit  func(heap uint64) // Called each time the heap grows past Limit.
+
+	heap    func() uint64 // Reads the size of the heap; replaced in tests.
+	warning bool          // Whether the heap was above Warn at the last sample, so each rise is only reported once.
+}
+
+// Run samples the heap until the process exits.
+func (w *HeapWatchdog) Run() {
+	for range time.Tick(w.Interval) {
+		w.check()
+	}
+}
+
+// check takes one sample of the heap and acts on it.
+func (w *HeapWatchdog) check() {
+	heap := w.readHeap()
+	if w.Warn > 0 {
+		switch {
+		case heap > w.Warn && !w.warning:
+			fmt.Fprintf(w.Log, "Warning: the heap has grown to %d MiB, past the %d MiB warning threshold\n", heap/MiB, w.Warn/MiB)
+			w.warning = true
+		case heap <= w.Warn && w.warning:
+			fmt.Fprintf(w.Log, "The heap is back down to %d MiB\n", heap/MiB)
+			w.warning = false
+		}
+	}
+
+	if w.Limit > 0 && heap > w.Limit {
+		// Garbage that hasn't been collected yet isn't a leak, so only act on what a collection leaves behind.
+		debug.FreeOSMemory()
+		if heap = w.readHeap(); heap > w.Limit {
+			fmt.Fprintf(w.Log, "Warning: the heap is %d MiB after garbage collection, past the %d MiB limit\n", heap/MiB, w.Limit/MiB)
+			if w.OnLimit != nil {
+				w.OnLimit(heap)
+			}
+		}
+	}
+}
+
+// readHeap returns the number of bytes allocated on the heap.
+func (w *HeapWatchdog) readHeap() uint64 {
+	if w.heap != nil {
+		return w.heap()
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// Restart replaces the running process with a fresh copy of itself, started with the same arguments followed
+// by extra. Listening sockets are closed on exec, so the new process can listen on the same ports.
+// It only returns if the restart failed.
+func Restart(extra ...string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := append(append([]string{}, os.Args...), extra...)
+	return syscall.Exec(executable, args, os.Environ())
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestHeapWatchdog feeds the watchdog a heap that rises and falls, and checks each crossing of a threshold
+// is reported once and the limit is only acted on while the heap stays above it.
+func TestHeapWatchdog(t *testing.T) {
+	var log bytes.Buffer
+	var heap uint64
+	var limits []uint64
+	w := &HeapWatchdog{
+		Warn:    100 * MiB,
+		Limit:   200 * MiB,
+		Log:     &log,
+		OnLimit: func(heap uint64) { limits = append(limits, heap) },
+		heap:    func() uint64 { return heap },
+	}
+
+	for _, sample := range []uint64{50, 150, 160, 250, 90, 120} {
+		heap = sample * MiB
+		w.check()
+	}
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	expected := []string{
+		"past the 100 MiB warning threshold", // 150
+		"past the 200 MiB limit",             // 250
+		"back down to 90 MiB",                // 90
+		"past the 100 MiB warning threshold", // 120
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d log lines, got:\n%s", len(expected), log.String())
+	}
+	for i := range expected {
+		if !strings.Contains(lines[i], expected[i]) {
+			t.Errorf("line %d is %q, expected it to contain %q", i, lines[i], expected[i])
+		}
+	}
+	if len(limits) != 1 || limits[0] != 250*MiB {
+		t.Errorf("OnLimit called with %v, expected once with 250 MiB", limits)
+	}
+}
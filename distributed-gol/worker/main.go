@@ -0,0 +1,12 @@
+// Command worker is a worker of the distributed Game of Life, run on each machine turns are split between.
+package main
+
+import (
+	"os"
+
+	"uk.ac.bris.cs/gameoflife/golworker"
+)
+
+func main() {
+	golworker.Main("worker", os.Args[1:])
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// arenaRun runs p, returning the final alive cells, the worlds an asynchronous callback kept, counted only once the
+// run is over, and whether any TurnComplete came with a world.
+func arenaRun(p gol.Params) ([]util.Cell, []int, bool) {
+	var kept []gol.ReadOnlyGrid
+	p.OnTurnAsync(func(turn int, world gol.ReadOnlyGrid) {
+		kept = append(kept, world)
+	}, p.Turns)
+
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var final []util.Cell
+	withWorld := false
+	for event := range events {
+		switch e := event.(type) {
+		case gol.TurnComplete:
+			withWorld = withWorld || e.World != nil
+		case gol.FinalTurnComplete:
+			final = e.Alive
+		}
+	}
+
+	counts := make([]int, len(kept))
+	for i, world := range kept {
+		counts[i] = len(world.AliveCells())
+	}
+	return final, counts, withWorld
+}
+
+// TestArena checks a run writing its generations into the arena gives the same results as one allocating them,
+// including with hooks changing the world in place, that worlds queued for a callback aren't written over, and that
+// TurnComplete comes without a world.
+func TestArena(t *testing.T) {
+	for _, threads := range []int{1, 3, 8} {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: threads}
+		p.Hooks = append(p.Hooks, gol.GliderHook(7, 10, 10))
+		t.Run(fmt.Sprintf("%d_threads", threads), func(t *testing.T) {
+			expected, expectedCounts, _ := arenaRun(p)
+			p.Arena, p.HugePages = true, true
+			final, counts, withWorld := arenaRun(p)
+
+			assertEqualBoard(t, final, expected, p)
+			if fmt.Sprint(counts) != fmt.Sprint(expectedCounts) {
+				t.Errorf("the callback kept worlds of %v alive cells, expected %v", counts, expectedCounts)
+			}
+			if withWorld {
+				t.Error("TurnComplete came with a world")
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestBitPacked checks a run on a packed world gives the same results as one on bytes, including with hooks
+// changing the world and worlds kept by a callback, in bands and interleaved, with and without the arena, and that
+// TurnComplete comes with a world unless the arena is used.
+func TestBitPacked(t *testing.T) {
+	for _, threads := range []int{1, 3, 8} {
+		for _, interleave := range []bool{false, true} {
+			for _, arena := range []bool{false, true} {
+				p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: threads}
+				p.Hooks = append(p.Hooks, gol.GliderHook(7, 10, 10))
+				t.Run(fmt.Sprintf("%d_threads_interleave_%v_arena_%v", threads, interleave, arena), func(t *testing.T) {
+					expected, expectedCounts, _ := arenaRun(p)
+					p.BitPacked, p.Interleave, p.Arena = true, interleave, arena
+					final, counts, withWorld := arenaRun(p)
+
+					assertEqualBoard(t, final, expected, p)
+					if fmt.Sprint(counts) != fmt.Sprint(expectedCounts) {
+						t.Errorf("the callback kept worlds of %v alive cells, expected %v", counts, expectedCounts)
+					}
+					if withWorld == arena {
+						t.Errorf("TurnComplete came with a world %t, expected %t", withWorld, !arena)
+					}
+				})
+			}
+		}
+	}
+}
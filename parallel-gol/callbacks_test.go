@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestOnTurn checks synchronous and asynchronous callbacks see every turn in order, and see the final world last.
+func TestOnTurn(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 100, Threads: 4}
+	var syncTurns, asyncTurns []int
+	var lastAlive int
+	p.OnTurn(func(turn int, world gol.ReadOnlyGrid) {
+		syncTurns = append(syncTurns, turn)
+	})
+	p.OnTurnAsync(func(turn int, world gol.ReadOnlyGrid) {
+		asyncTurns = append(asyncTurns, turn)
+		lastAlive = len(world.AliveCells())
+	}, 1)
+
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+
+	for name, turns := range map[string][]int{"synchronous": syncTurns, "asynchronous": asyncTurns} {
+		if len(turns) != p.Turns {
+			t.Errorf("%s callback ran %d times, expected %d", name, len(turns), p.Turns)
+			continue
+		}
+		for i, turn := range turns {
+			if turn != i+1 {
+				t.Errorf("%s callback was called with turn %d, expected %d", name, turn, i+1)
+				break
+			}
+		}
+	}
+	if lastAlive != len(cells) {
+		t.Errorf("final callback saw %d alive cells, expected %d", lastAlive, len(cells))
+	}
+}
+
+// TestEventWorld checks TurnComplete and FinalTurnComplete share the world of their turn, and that a world kept
+// from an earlier turn isn't changed by the turns after it.
+func TestEventWorld(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 50, Threads: 4}
+	callbackWorlds := make(map[int]gol.ReadOnlyGrid)
+	p.OnTurn(func(turn int, world gol.ReadOnlyGrid) {
+		callbackWorlds[turn] = world
+	})
+
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var first gol.ReadOnlyGrid
+	var firstAlive []util.Cell
+	var final gol.FinalTurnComplete
+	for event := range events {
+		switch e := event.(type) {
+		case gol.TurnComplete:
+			if e.World == nil {
+				t.Fatalf("TurnComplete for turn %d has no world", e.CompletedTurns)
+			}
+			if first == nil {
+				first, firstAlive = e.World, e.World.AliveCells()
+			}
+		case gol.FinalTurnComplete:
+			final = e
+		}
+	}
+
+	if final.World == nil || len(final.World.AliveCells()) != len(final.Alive) {
+		t.Fatalf("FinalTurnComplete's world doesn't match its %d alive cells", len(final.Alive))
+	}
+	assertEqualBoard(t, final.World.AliveCells(), callbackWorlds[p.Turns].AliveCells(), p)
+	assertEqualBoard(t, first.AliveCells(), firstAlive, p)
+}
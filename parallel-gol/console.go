@@ -0,0 +1,26 @@
+package main
+
+import (
+	"uk.ac.bris.cs/gameoflife/console"
+	"uk.ac.bris.cs/gameoflife/sdl"
+)
+
+// consoleCommands tells the console about every action and the single characters bound to them without
+// modifiers, so it follows the same bindings as the window.
+func consoleCommands(bindings sdl.Bindings) console.Commands {
+	commands := console.Commands{
+		Actions: make(map[string]rune),
+		Keys:    make(map[string]string),
+		Help:    bindings.String(),
+	}
+	for _, action := range sdl.Actions() {
+		key, _ := action.KeyPress()
+		commands.Actions[string(action)] = key
+	}
+	for key, action := range bindings {
+		if key.Mod == 0 && key.Sym > ' ' && key.Sym < 0x7F {
+			commands.Keys[string(rune(key.Sym))] = string(action)
+		}
+	}
+	return commands
+}
@@ -0,0 +1,68 @@
+// Package console lets the simulation be controlled by typing commands into a terminal. It knows nothing
+// about SDL, so the commands it understands are handed to it as plain names and characters.
+package console
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Commands describes what the console understands.
+type Commands struct {
+	// Actions maps the name of every action to the key press the distributor expects for it,
+	// or 0 if the action only affects the window.
+	Actions map[string]rune
+	// Keys maps single characters bound to an action without modifiers to the action's name.
+	Keys map[string]string
+	// Help is printed when a command isn't recognised.
+	Help string
+}
+
+// Lookup returns the action a command stands for. A command is either the name of an action,
+// such as "pause" or "more", or a single character bound to an action, such as "p".
+func (c Commands) Lookup(command string) (string, bool) {
+	if _, ok := c.Actions[command]; ok {
+		return command, true
+	}
+	if len(command) == 1 {
+		action, ok := c.Keys[command]
+		return action, ok
+	}
+	return "", false
+}
+
+// Run reads commands from in, one per line, and forwards them as key presses, so the simulation
+// can be controlled from a terminal as well as from the SDL window. It returns when in is closed.
+func Run(in io.Reader, keyPresses chan<- rune, commands Commands) {
+	serve(in, os.Stdout, keyPresses, commands, false)
+}
+
+// serve reads commands from in and forwards them as key presses, writing anything it has to say about them
+// to out. With acknowledge set it also replies "ok" and the action to each command forwarded, so a program
+// on the other end gets exactly one line back for every command it sends.
+func serve(in io.Reader, out io.Writer, keyPresses chan<- rune, commands Commands, acknowledge bool) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		command := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if command == "" {
+			continue
+		}
+		action, ok := commands.Lookup(command)
+		if !ok {
+			fmt.Fprintln(out, "Commands are action names or their keys:", commands.Help)
+			continue
+		}
+		key := commands.Actions[action]
+		if key == 0 {
+			fmt.Fprintln(out, "The", action, "action only works in the window")
+			continue
+		}
+		keyPresses <- key
+		if acknowledge {
+			fmt.Fprintln(out, "ok", action)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package console
+
+import (
+	"strings"
+	"testing"
+)
+
+// commands is a small set of actions like the ones the window binds: pause and kill reach the distributor,
+// zoom only affects the window, and kill has been rebound to ctrl+k so "k" is free.
+var commands = Commands{
+	Actions: map[string]rune{"pause": 'p', "kill": 'k', "more": '+', "zoom": 0},
+	Keys:    map[string]string{"p": "pause", "+": "more", "=": "more", "m": "zoom"},
+	Help:    "pause=p,kill=ctrl+k,more=+ =,zoom=m",
+}
+
+// TestLookup checks commands are recognised by action name or by a single bound character.
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		command string
+		action  string
+		ok      bool
+	}{
+		{"pause", "pause", true},
+		{"p", "pause", true},
+		{"kill", "kill", true},
+		{"k", "", false}, // Only bound with ctrl.
+		{"=", "more", true},
+		{"zoom", "zoom", true},
+		{"explode", "", false},
+		{"pp", "", false},
+	}
+	for _, test := range tests {
+		action, ok := commands.Lookup(test.command)
+		if ok != test.ok || action != test.action {
+			t.Errorf("%q: expected %q (%v), got %q (%v)", test.command, test.action, test.ok, action, ok)
+		}
+	}
+}
+
+// TestRun checks the console forwards the key presses of recognised commands, in order, and skips the rest.
+func TestRun(t *testing.T) {
+	keyPresses := make(chan rune, 10)
+	Run(strings.NewReader("  Pause \n\nk\nzoom\nkill\n=\nnonsense\nP\n"), keyPresses, commands)
+	close(keyPresses)
+
+	var got []rune
+	for key := range keyPresses {
+		got = append(got, key)
+	}
+	if expected := []rune{'p', 'k', '+', 'p'}; string(got) != string(expected) {
+		t.Errorf("expected key presses %q, got %q", string(expected), string(got))
+	}
+}
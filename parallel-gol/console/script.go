@@ -0,0 +1,87 @@
+package console
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Step is one command of a script, carried out At long after the script starts.
+type Step struct {
+	At     time.Duration
+	Action string
+	Key    rune // The key press forwarded for the action.
+}
+
+// LoadScript reads the script at path. See ParseScript.
+func LoadScript(path string, commands Commands) ([]Step, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	steps, err := ParseScript(file, commands)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return steps, nil
+}
+
+// ParseScript reads a script of timed commands, one per line, such as "at 10s press 's'" or just "10s s". The time
+// is a Go duration from the start of the script, and the command is anything the terminal accepts, quoted or not.
+// Blank lines and lines starting with # are skipped. The steps are returned in the order they are due, and a
+// command the terminal wouldn't forward, such as one that only works in the window, is an error, so a demo never
+// runs for a minute before finding its script is broken.
+func ParseScript(in io.Reader, commands Commands) ([]Step, error) {
+	var steps []Step
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(strings.ToLower(scanner.Text()))
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == "at" {
+			fields = fields[1:]
+		}
+		if len(fields) == 3 && fields[1] == "press" {
+			fields = append(fields[:1], fields[2])
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a time and a command, such as \"at 10s press 'p'\"", line)
+		}
+		at, err := time.ParseDuration(fields[0])
+		if err != nil || at < 0 {
+			return nil, fmt.Errorf("line %d: %q isn't a time from the start, such as 10s or 1m30s", line, fields[0])
+		}
+		command := strings.Trim(fields[1], `'"`)
+		action, ok := commands.Lookup(command)
+		if !ok {
+			return nil, fmt.Errorf("line %d: %q isn't a command. Commands are action names or their keys: %s", line, command, commands.Help)
+		}
+		key := commands.Actions[action]
+		if key == 0 {
+			return nil, fmt.Errorf("line %d: the %s action only works in the window", line, action)
+		}
+		steps = append(steps, Step{At: at, Action: action, Key: key})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].At < steps[j].At })
+	return steps, nil
+}
+
+// RunScript forwards the key press of each step once it is due, timed from when RunScript is called, so a demo or
+// a regression scenario plays out the same way unattended every time. It returns after the last step.
+func RunScript(steps []Step, keyPresses chan<- rune) {
+	start := time.Now()
+	for _, step := range steps {
+		time.Sleep(step.At - time.Since(start))
+		fmt.Printf("Script: %s at %v\n", step.Action, step.At)
+		keyPresses <- step.Key
+	}
+}
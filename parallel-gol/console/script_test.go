@@ -0,0 +1,64 @@
+package console
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseScript checks the ways a step can be written, that steps come back in the order they are due, and that
+// a script which couldn't be carried out in full is refused.
+func TestParseScript(t *testing.T) {
+	script := `# Pause for five seconds half a minute in.
+at 30s press 'p'
+at 10s press "+"
+35s pause
+at 1m0s press kill
+`
+	steps, err := ParseScript(strings.NewReader(script), commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Step{
+		{At: 10 * time.Second, Action: "more", Key: '+'},
+		{At: 30 * time.Second, Action: "pause", Key: 'p'},
+		{At: 35 * time.Second, Action: "pause", Key: 'p'},
+		{At: time.Minute, Action: "kill", Key: 'k'},
+	}
+	if !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected %+v, got %+v", expected, steps)
+	}
+
+	for _, broken := range []string{
+		"at 10s press",
+		"at soon press p",
+		"at -5s press p",
+		"at 10s press explode",
+		"at 10s press zoom", // Only works in the window.
+		"at 10s press p now",
+	} {
+		if _, err := ParseScript(strings.NewReader(broken), commands); err == nil {
+			t.Errorf("%q was accepted", broken)
+		}
+	}
+}
+
+// TestRunScript checks each step's key press is forwarded in turn, no sooner than it is due.
+func TestRunScript(t *testing.T) {
+	steps := []Step{{At: 0, Key: 'p'}, {At: 20 * time.Millisecond, Key: '+'}, {At: 40 * time.Millisecond, Key: 'p'}}
+	keyPresses := make(chan rune, len(steps))
+	start := time.Now()
+	RunScript(steps, keyPresses)
+	if elapsed := time.Since(start); elapsed < steps[len(steps)-1].At {
+		t.Errorf("the last step was due at %v but the script finished after %v", steps[len(steps)-1].At, elapsed)
+	}
+	close(keyPresses)
+	var got []rune
+	for key := range keyPresses {
+		got = append(got, key)
+	}
+	if expected := []rune{'p', '+', 'p'}; string(got) != string(expected) {
+		t.Errorf("expected key presses %q, got %q", string(expected), string(got))
+	}
+}
@@ -0,0 +1,34 @@
+package console
+
+import (
+	"net"
+	"os"
+)
+
+// Listen accepts connections on a Unix domain socket at path, which also works on Windows 10 and later, and
+// reads the same commands as the terminal from each, so external programs such as a notebook can pause, save or
+// step the simulation without the distributed version's RPC. Every command sent gets one line back: "ok" and
+// the action if it was forwarded, or why it wasn't. A socket left behind by an earlier run is replaced. Closing
+// the listener stops accepting connections and removes the socket.
+func Listen(path string, keyPresses chan<- rune, commands Commands) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn, conn, keyPresses, commands, true)
+			}()
+		}
+	}()
+	return listener, nil
+}
@@ -0,0 +1,68 @@
+package console
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestListen checks commands sent over the control socket are forwarded as key presses, with one reply for
+// each, and that a socket left behind by an earlier run doesn't stop a new one listening.
+func TestListen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gol.sock")
+
+	keyPresses := make(chan rune, 10)
+	stale, err := Listen(path, keyPresses, commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stop accepting without removing the socket, as a run that crashed would.
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := Listen(path, keyPresses, commands)
+	if err != nil {
+		t.Fatalf("couldn't replace a stale socket: %v", err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "pause\nzoom\n\nnonsense\n+\n")
+
+	replies := bufio.NewScanner(conn)
+	expected := []string{
+		"ok pause",
+		"The zoom action only works in the window",
+		"Commands are action names or their keys: " + commands.Help,
+		"ok more",
+	}
+	for _, want := range expected {
+		if !replies.Scan() {
+			t.Fatalf("connection closed, expected %q", want)
+		}
+		if got := replies.Text(); got != want {
+			t.Errorf("replied %q, expected %q", got, want)
+		}
+	}
+	close(keyPresses)
+	var got []rune
+	for key := range keyPresses {
+		got = append(got, key)
+	}
+	if string(got) != "p+" {
+		t.Errorf("expected key presses %q, got %q", "p+", string(got))
+	}
+}
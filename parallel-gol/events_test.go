@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestStrictEvents checks the event order guaranteed by strict mode, and that it doesn't change the result.
+func TestStrictEvents(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 8, StrictEvents: true}
+	expectedAlive := readAliveCells(
+		"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+		p.ImageWidth,
+		p.ImageHeight,
+	)
+
+	events := make(chan gol.Event, 1000)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	final := false
+	lastTurn := -1
+	var flipped []gol.CellFlipped // CellFlipped events since the last TurnComplete.
+	for event := range events {
+		if final {
+			t.Fatalf("%T event sent after FinalTurnComplete", event)
+		}
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			flipped = append(flipped, e)
+		case gol.TurnComplete:
+			if e.CompletedTurns < lastTurn {
+				t.Fatalf("TurnComplete for turn %d sent after turn %d", e.CompletedTurns, lastTurn)
+			}
+			for _, f := range flipped {
+				if f.CompletedTurns > e.CompletedTurns {
+					t.Fatalf("CellFlipped for turn %d sent before TurnComplete for turn %d", f.CompletedTurns, e.CompletedTurns)
+				}
+			}
+			lastTurn = e.CompletedTurns
+			flipped = nil
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+			final = true
+		}
+	}
+	assertEqualBoard(t, cells, expectedAlive, p)
+}
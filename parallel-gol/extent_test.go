@@ -0,0 +1,58 @@
+package main
+
+import (
+	"image"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestPatternExtent checks a run with ExtentEvery sends a PatternExtent every that many turns, on the torus and
+// on the infinite plane, the last counting the cells alive at the end and boxing them all.
+func TestPatternExtent(t *testing.T) {
+	for _, infinite := range []bool{false, true} {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 18, Threads: 4, ExtentEvery: 3, Infinite: infinite}
+		events := make(chan gol.Event, 1000)
+		go gol.Run(p, events, nil)
+
+		var extents []gol.PatternExtent
+		var final []util.Cell
+		for event := range events {
+			switch e := event.(type) {
+			case gol.PatternExtent:
+				extents = append(extents, e)
+			case gol.FinalTurnComplete:
+				final = e.Alive
+			}
+		}
+
+		if len(extents) != p.Turns/p.ExtentEvery {
+			t.Fatalf("infinite %v: received %d PatternExtent events, expected %d", infinite, len(extents), p.Turns/p.ExtentEvery)
+		}
+		for i, e := range extents {
+			if e.CompletedTurns != (i+1)*p.ExtentEvery {
+				t.Errorf("infinite %v: extent %d is for turn %d, expected %d", infinite, i, e.CompletedTurns, (i+1)*p.ExtentEvery)
+			}
+			if e.Alive == 0 || e.Bounds.Empty() {
+				t.Errorf("infinite %v: extent for turn %d has %d alive in %v", infinite, e.CompletedTurns, e.Alive, e.Bounds)
+			}
+		}
+		// The last extent is of the final board, so must hold every cell alive at the end.
+		last := extents[len(extents)-1]
+		if last.Alive != len(final) {
+			t.Errorf("infinite %v: last extent has %d alive, the final board %d", infinite, last.Alive, len(final))
+		}
+		for _, cell := range final {
+			if !infinite && cell.X < last.Bounds.Min.X {
+				cell.X += p.ImageWidth // Boxes across an edge reach past the right or bottom of the world.
+			}
+			if !infinite && cell.Y < last.Bounds.Min.Y {
+				cell.Y += p.ImageHeight
+			}
+			if !(image.Point{X: cell.X, Y: cell.Y}).In(last.Bounds) {
+				t.Errorf("infinite %v: cell %v is outside the last extent %v", infinite, cell, last.Bounds)
+			}
+		}
+	}
+}
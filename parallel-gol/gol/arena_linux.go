@@ -0,0 +1,8 @@
+package gol
+
+import "syscall"
+
+// adviseHugePages asks the kernel to back memory with transparent huge pages.
+func adviseHugePages(memory []byte) error {
+	return syscall.Madvise(memory, syscall.MADV_HUGEPAGE)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package gol
+
+import "errors"
+
+// adviseHugePages reports that huge pages can't be asked for, since only Linux takes the hint.
+func adviseHugePages(memory []byte) error {
+	return errors.New("only supported on Linux")
+}
@@ -0,0 +1,48 @@
+package gol
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// TestGridArena checks the arena lays both generations out one row after another from a huge page boundary, and
+// hands them out in turn.
+func TestGridArena(t *testing.T) {
+	const width, height = 10, 4
+	a := newGridArena(width, height, false)
+	first, second := a.next(), a.next()
+	if &a.next()[0][0] != &first[0][0] || &a.next()[0][0] != &second[0][0] {
+		t.Fatal("the arena didn't alternate between its two generations")
+	}
+	if start := uintptr(unsafe.Pointer(&first[0][0])); start%hugePageSize != 0 {
+		t.Errorf("the arena starts at %#x, expected a multiple of %#x", start, hugePageSize)
+	}
+
+	rows := append(append([][]byte{}, first...), second...)
+	for i, row := range rows {
+		if len(row) != width || cap(row) != width {
+			t.Fatalf("row %d has length %d and capacity %d, expected both to be %d", i, len(row), cap(row), width)
+		}
+		if i > 0 && uintptr(unsafe.Pointer(&row[0])) != uintptr(unsafe.Pointer(&rows[i-1][0]))+width {
+			t.Errorf("row %d doesn't follow on from row %d", i, i-1)
+		}
+	}
+}
+
+// TestRunSummary checks the summary reports the arena and what became of the huge page hint.
+func TestRunSummary(t *testing.T) {
+	a := newGridArena(1024, 1024, false)
+	s := startSummary(a, true)
+	s.turns, s.rowsReused = 3, 3*1024
+	summary := s.String()
+	for _, part := range []string{"3 turns", "grid arena 2.0 MiB", "3072 row allocations saved", "huge pages advised"} {
+		if !strings.Contains(summary, part) {
+			t.Errorf("got summary %q, expected it to include %q", summary, part)
+		}
+	}
+
+	if s := startSummary(nil, false).String(); strings.Contains(s, "arena") {
+		t.Errorf("got summary %q without an arena, expected it not to mention one", s)
+	}
+}
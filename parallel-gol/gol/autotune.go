@@ -0,0 +1,61 @@
+package gol
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// autotuneTurns is the number of warm-up generations timed at each thread count. The fastest of them counts, so
+// a garbage collection or a busy moment on the machine doesn't rule a thread count out.
+const autotuneTurns = 5
+
+// threadCandidates returns the thread counts autotune tries: the powers of two up to twice the number of cores Go
+// runs on, along with the count asked for, never more than there are rows.
+func threadCandidates(p Params) []int {
+	seen := map[int]bool{}
+	var candidates []int
+	add := func(threads int) {
+		if threads >= 1 && threads <= p.ImageHeight && !seen[threads] {
+			seen[threads] = true
+			candidates = append(candidates, threads)
+		}
+	}
+	for threads := 1; threads <= 2*runtime.GOMAXPROCS(0); threads *= 2 {
+		add(threads)
+	}
+	add(p.Threads)
+	sort.Ints(candidates)
+	return candidates
+}
+
+// autotune times a few warm-up generations of world at each candidate thread count and returns the fastest.
+// The warm-up generations are thrown away, so the run itself starts from world as it was.
+func autotune(p Params, world [][]byte) int {
+	best, bestTime := p.Threads, time.Duration(-1)
+	for _, threads := range threadCandidates(p) {
+		p.Threads = threads
+		results := make([]chan sliceResult, threads)
+		for i := range results {
+			results[i] = make(chan sliceResult)
+		}
+
+		current := world
+		fastest := time.Duration(-1)
+		for turn := 0; turn < autotuneTurns; turn++ {
+			start := time.Now()
+			next := nextWorld(p, current, results)
+			if elapsed := time.Since(start); fastest < 0 || elapsed < fastest {
+				fastest = elapsed
+			}
+			current = next
+		}
+
+		if bestTime < 0 || fastest < bestTime {
+			best, bestTime = threads, fastest
+		}
+	}
+	fmt.Printf("Autotuned to %d worker threads (%v per turn)\n", best, bestTime)
+	return best
+}
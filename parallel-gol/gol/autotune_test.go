@@ -0,0 +1,59 @@
+package gol
+
+import (
+	"bytes"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestThreadCandidates checks autotune tries the thread count asked for, and never more threads than rows.
+func TestThreadCandidates(t *testing.T) {
+	candidates := threadCandidates(Params{Threads: 3, ImageHeight: 4})
+	if len(candidates) == 0 || candidates[0] != 1 {
+		t.Fatalf("got candidates %v, expected them to start at 1", candidates)
+	}
+	found := false
+	for i, threads := range candidates {
+		if threads > 4 {
+			t.Errorf("got candidate %d for a board of 4 rows", threads)
+		}
+		if i > 0 && threads <= candidates[i-1] {
+			t.Errorf("got candidates %v, expected them in increasing order without repeats", candidates)
+		}
+		found = found || threads == 3
+	}
+	if !found {
+		t.Errorf("got candidates %v, expected them to include the 3 threads asked for", candidates)
+	}
+}
+
+// TestAutotune checks autotune picks one of its candidates and leaves the starting board as it was.
+func TestAutotune(t *testing.T) {
+	p := Params{Threads: 2, ImageWidth: 16, ImageHeight: 16}
+	world := make([][]byte, p.ImageHeight)
+	for i := range world {
+		world[i] = make([]byte, p.ImageWidth)
+	}
+	for _, cell := range []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}} {
+		world[cell.Y][cell.X] = util.Alive
+	}
+	before := make([][]byte, len(world))
+	for i := range world {
+		before[i] = append([]byte{}, world[i]...)
+	}
+
+	threads := autotune(p, world)
+	found := false
+	for _, candidate := range threadCandidates(p) {
+		found = found || candidate == threads
+	}
+	if !found {
+		t.Errorf("autotune picked %d threads, which isn't one of %v", threads, threadCandidates(p))
+	}
+	for i := range world {
+		if !bytes.Equal(world[i], before[i]) {
+			t.Fatalf("row %d of the board changed while autotuning", i)
+		}
+	}
+}
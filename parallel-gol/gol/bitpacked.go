@@ -0,0 +1,169 @@
+package gol
+
+import (
+	"math/bits"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// packedGrid is a world stored a bit per cell, 64 cells to a uint64 word, each row starting a new word and cell x
+// of a row in bit x%64 of word x/64. Bits past the end of a row are always 0. A 4096x4096 board is 2 MiB packed
+// rather than 16 MiB as bytes, so a band of rows and the rows either side of it stay in cache while it is computed,
+// and the neighbours of 64 cells are counted at once.
+//
+// Like a grid, a packedGrid is never written once it has been handed out, unless it is one of the arena's: each turn
+// is computed into a new one, so it is the ReadOnlyGrid of TurnComplete and the callbacks as it is.
+type packedGrid struct {
+	width, height int
+	words         int      // Words per row.
+	cells         []uint64 // Row after row.
+}
+
+// newPackedGrid returns an empty width x height world.
+func newPackedGrid(width, height int) *packedGrid {
+	words := (width + 63) / 64
+	return &packedGrid{width: width, height: height, words: words, cells: make([]uint64, words*height)}
+}
+
+// packGrid packs a world of util.Alive and util.Dead cells.
+func packGrid(world [][]byte) *packedGrid {
+	g := newPackedGrid(len(world[0]), len(world))
+	g.pack(world)
+	return g
+}
+
+// pack writes a world of util.Alive and util.Dead cells, the same size, over g.
+func (g *packedGrid) pack(world [][]byte) {
+	for y, row := range world {
+		packed := g.row(y)
+		for i := range packed {
+			packed[i] = 0
+		}
+		for x, cell := range row {
+			if cell == util.Alive {
+				packed[x/64] |= 1 << uint(x%64)
+			}
+		}
+	}
+}
+
+// clone returns a copy of g, for keeping once g is to be written over.
+func (g *packedGrid) clone() *packedGrid {
+	c := *g
+	c.cells = append([]uint64{}, g.cells...)
+	return &c
+}
+
+// rows unpacks the world into rows of util.Alive and util.Dead cells.
+func (g *packedGrid) rows() [][]byte {
+	world := newRows(g.height, g.width)
+	for y, row := range world {
+		packed := g.row(y)
+		for x := range row {
+			if packed[x/64]>>uint(x%64)&1 != 0 {
+				row[x] = util.Alive
+			}
+		}
+	}
+	return world
+}
+
+// row returns the words of row y.
+func (g *packedGrid) row(y int) []uint64 {
+	return g.cells[y*g.words : (y+1)*g.words]
+}
+
+func (g *packedGrid) Width() int {
+	return g.width
+}
+
+func (g *packedGrid) Height() int {
+	return g.height
+}
+
+func (g *packedGrid) Alive(x, y int) bool {
+	x, y = (x%g.width+g.width)%g.width, (y%g.height+g.height)%g.height
+	return g.row(y)[x/64]>>uint(x%64)&1 != 0
+}
+
+func (g *packedGrid) AliveCells() []util.Cell {
+	alive := []util.Cell{}
+	for y := 0; y < g.height; y++ {
+		for i, word := range g.row(y) {
+			for ; word != 0; word &= word - 1 {
+				alive = append(alive, util.Cell{X: i*64 + bits.TrailingZeros64(word), Y: y})
+			}
+		}
+	}
+	return alive
+}
+
+// shifted returns word i of row moved along a cell each way: left holds cell x-1 in bit x, and right cell x+1,
+// wrapping around the ends of the row. Bits of the last word past the end of the row may be set in left.
+func (g *packedGrid) shifted(row []uint64, i int) (left, right uint64) {
+	last := len(row) - 1
+	end := uint((g.width - 1) % 64) // Bit of the last word holding the last cell of the row.
+	left, right = row[i]<<1, row[i]>>1
+	if i > 0 {
+		left |= row[i-1] >> 63
+	} else {
+		left |= row[last] >> end & 1
+	}
+	if i < last {
+		right |= row[i+1] << 63
+	} else {
+		right |= (row[0] & 1) << end
+	}
+	return
+}
+
+// nextPackedState computes the next state of rows startRow to endRow of world into the same rows of next, a word
+// at a time, and returns the cells in them that flipped. Each cell's neighbours are added up in three bit planes,
+// the count mod 8, which is enough: a cell with all 8 neighbours alive dies or stays dead as one with none does.
+func nextPackedState(world, next *packedGrid, startRow, endRow int) []util.Cell {
+	var flipped []util.Cell
+	end := ^uint64(0) >> uint(63-(world.width-1)%64) // Bits of the last word inside the row.
+	for y := startRow; y < endRow; y++ {
+		up := world.row((y + world.height - 1) % world.height)
+		row := world.row(y)
+		down := world.row((y + 1) % world.height)
+		out := next.row(y)
+		for i := range row {
+			upLeft, upRight := world.shifted(up, i)
+			left, right := world.shifted(row, i)
+			downLeft, downRight := world.shifted(down, i)
+			var ones, twos, fours uint64
+			for _, neighbours := range [8]uint64{upLeft, up[i], upRight, left, right, downLeft, down[i], downRight} {
+				carry := ones & neighbours
+				ones ^= neighbours
+				fours ^= twos & carry
+				twos ^= carry
+			}
+			// Alive with 3 neighbours, or with 2 if it was alive already.
+			cells := twos &^ fours & (ones | row[i])
+			if i == len(row)-1 {
+				cells &= end
+			}
+			out[i] = cells
+			for changed := cells ^ row[i]; changed != 0; changed &= changed - 1 {
+				flipped = append(flipped, util.Cell{X: i*64 + bits.TrailingZeros64(changed), Y: y})
+			}
+		}
+	}
+	return flipped
+}
+
+// packedWorker computes worker id's band of rows of the next state of world into next, sending back the cells in
+// it that flipped. With p.Interleave it computes every p.Threads-th row, starting at row id, as worker does.
+func packedWorker(id int, p Params, world, next *packedGrid, result chan<- sliceResult) {
+	if p.Interleave {
+		var flipped []util.Cell
+		for row := id; row < p.ImageHeight; row += p.Threads {
+			flipped = append(flipped, nextPackedState(world, next, row, row+1)...)
+		}
+		result <- sliceResult{nil, flipped}
+		return
+	}
+	startRow, endRow := workerRows(id, p)
+	result <- sliceResult{nil, nextPackedState(world, next, startRow, endRow)}
+}
@@ -0,0 +1,125 @@
+package gol
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// randomWorld returns a width x height world with about a third of its cells alive.
+func randomWorld(width, height int, seed int64) [][]byte {
+	world := newRows(height, width)
+	r := rand.New(rand.NewSource(seed))
+	for _, row := range world {
+		for x := range row {
+			if r.Intn(3) == 0 {
+				row[x] = util.Alive
+			}
+		}
+	}
+	return world
+}
+
+// sortCells sorts cells by row, then column.
+func sortCells(cells []util.Cell) {
+	sort.Slice(cells, func(i, j int) bool {
+		return cells[i].Y < cells[j].Y || cells[i].Y == cells[j].Y && cells[i].X < cells[j].X
+	})
+}
+
+// TestPackedNextState checks counting neighbours a word at a time gives the same next world, and the same flipped
+// cells, as counting them a cell at a time, including for rows narrower than a word and rows ending part way
+// through one, where the neighbours wrap around.
+func TestPackedNextState(t *testing.T) {
+	for _, width := range []int{1, 2, 63, 64, 65, 130} {
+		for _, height := range []int{1, 3, 16} {
+			p := Params{ImageWidth: width, ImageHeight: height}
+			world := randomWorld(width, height, int64(width*height))
+			packed := packGrid(world)
+			for turn := 1; turn <= 8; turn++ {
+				next := newRows(height, width)
+				expectedFlipped := calculateNextState(world, next, 0, height, p)
+				packedNext := newPackedGrid(width, height)
+				flipped := nextPackedState(packed, packedNext, 0, height)
+
+				if err := compareWorlds(packedNext.rows(), next); err != nil {
+					t.Fatalf("%dx%d, turn %d: %v", width, height, turn, err)
+				}
+				sortCells(flipped)
+				sortCells(expectedFlipped)
+				if fmt.Sprint(flipped) != fmt.Sprint(expectedFlipped) {
+					t.Fatalf("%dx%d, turn %d: flipped %v, expected %v", width, height, turn, flipped, expectedFlipped)
+				}
+				world, packed = next, packedNext
+			}
+		}
+	}
+}
+
+// TestPackedGrid checks a packed world unpacks to the one it was packed from, and reads as it does.
+func TestPackedGrid(t *testing.T) {
+	world := randomWorld(130, 5, 1)
+	packed := packGrid(world)
+	if err := compareWorlds(packed.rows(), world); err != nil {
+		t.Fatal(err)
+	}
+	if packed.Width() != 130 || packed.Height() != 5 {
+		t.Errorf("packed world is %dx%d, expected 130x5", packed.Width(), packed.Height())
+	}
+	for _, c := range []util.Cell{{X: 0, Y: 0}, {X: 64, Y: 2}, {X: -1, Y: -1}, {X: 130, Y: 5}} {
+		if alive, expected := packed.Alive(c.X, c.Y), grid(world).Alive(c.X, c.Y); alive != expected {
+			t.Errorf("cell (%d, %d) is alive %t, expected %t", c.X, c.Y, alive, expected)
+		}
+	}
+	if fmt.Sprint(packed.AliveCells()) != fmt.Sprint(calculateAliveCells(world)) {
+		t.Error("packed world has different alive cells")
+	}
+}
+
+// BenchmarkBitPacked compares a turn of a 4096x4096 board packed with one as bytes, both with a pool of 8 workers.
+func BenchmarkBitPacked(b *testing.B) {
+	p := Params{Threads: 8, ImageWidth: 4096, ImageHeight: 4096}
+	world := randomWorld(p.ImageWidth, p.ImageHeight, 1)
+	b.Run("bytes", func(b *testing.B) {
+		pool := newWorkerPool(p)
+		defer pool.close()
+		for i := 0; i < b.N; i++ {
+			pool.step(world, nil)
+		}
+	})
+	b.Run("packed", func(b *testing.B) {
+		pool := newWorkerPool(p)
+		defer pool.close()
+		packed := packGrid(world)
+		for i := 0; i < b.N; i++ {
+			pool.stepPacked(packed, newPackedGrid(p.ImageWidth, p.ImageHeight))
+		}
+	})
+}
+
+// TestPackedArena checks packed generations written into the arena are the same as ones allocated each turn, in
+// bands and interleaved.
+func TestPackedArena(t *testing.T) {
+	for _, interleave := range []bool{false, true} {
+		p := Params{Threads: 3, ImageWidth: 130, ImageHeight: 40, Interleave: interleave}
+		pool := newWorkerPool(p)
+		arena := newPackedArena(p.ImageWidth, p.ImageHeight, false)
+		world := packGrid(randomWorld(p.ImageWidth, p.ImageHeight, 1))
+		expected := world
+		for turn := 1; turn <= 6; turn++ {
+			next := arena.nextPacked()
+			pool.stepPacked(world, next)
+			world = next
+			allocated := newPackedGrid(p.ImageWidth, p.ImageHeight)
+			nextPackedState(expected, allocated, 0, p.ImageHeight)
+			expected = allocated
+			if err := compareWorlds(world.rows(), expected.rows()); err != nil {
+				t.Fatalf("interleaved %t, turn %d: %v", interleave, turn, err)
+			}
+		}
+		pool.close()
+	}
+}
@@ -0,0 +1,93 @@
+package gol
+
+import "sync"
+
+// turnCallback is a function registered with OnTurn or OnTurnAsync.
+type turnCallback struct {
+	fn    func(turn int, world ReadOnlyGrid)
+	queue int // Size of the queue between the distributor and an asynchronous callback, or 0 to call it synchronously.
+}
+
+// OnTurn registers a callback that the distributor calls after every turn with the number of completed turns
+// and the new world. The next turn doesn't start until the callback returns. Callbacks aren't called in infinite mode.
+// With Params.Arena the world is written over two turns later, so a callback must copy anything it keeps.
+func (p *Params) OnTurn(fn func(turn int, world ReadOnlyGrid)) {
+	p.callbacks = append(p.callbacks, turnCallback{fn: fn})
+}
+
+// OnTurnAsync registers a callback that runs on its own goroutine, fed turns through a queue of the given size.
+// The simulation runs ahead of a slow callback until the queue is full and then waits for it to catch up.
+// All queued turns are delivered before the events channel is closed.
+func (p *Params) OnTurnAsync(fn func(turn int, world ReadOnlyGrid), queue int) {
+	if queue < 1 {
+		queue = 1
+	}
+	p.callbacks = append(p.callbacks, turnCallback{fn: fn, queue: queue})
+}
+
+// completedTurn is a world queued for an asynchronous callback.
+type completedTurn struct {
+	turn  int
+	world ReadOnlyGrid
+}
+
+// turnDispatcher delivers completed turns to the registered callbacks.
+type turnDispatcher struct {
+	callbacks []turnCallback
+	borrowed  bool                 // Worlds are written over once the turn after next starts, so queued ones must be copies.
+	queues    []chan completedTurn // Queue for each callback, or nil for synchronous callbacks.
+	wg        sync.WaitGroup
+}
+
+// newTurnDispatcher starts a goroutine for each asynchronous callback.
+func newTurnDispatcher(callbacks []turnCallback) *turnDispatcher {
+	d := &turnDispatcher{callbacks: callbacks, queues: make([]chan completedTurn, len(callbacks))}
+	for i, callback := range callbacks {
+		if callback.queue == 0 {
+			continue
+		}
+		d.queues[i] = make(chan completedTurn, callback.queue)
+		d.wg.Add(1)
+		go func(fn func(int, ReadOnlyGrid), queue <-chan completedTurn) {
+			defer d.wg.Done()
+			for t := range queue {
+				fn(t.turn, t.world)
+			}
+		}(callback.fn, d.queues[i])
+	}
+	return d
+}
+
+// dispatch hands a completed turn to every callback.
+func (d *turnDispatcher) dispatch(turn int, world ReadOnlyGrid) {
+	for i, callback := range d.callbacks {
+		if d.queues[i] == nil {
+			callback.fn(turn, world)
+		} else {
+			queued := world
+			if d.borrowed {
+				switch borrowed := world.(type) {
+				case grid:
+					copied := make(grid, len(borrowed))
+					for y := range borrowed {
+						copied[y] = append([]byte{}, borrowed[y]...)
+					}
+					queued = copied
+				case *packedGrid:
+					queued = borrowed.clone()
+				}
+			}
+			d.queues[i] <- completedTurn{turn, queued}
+		}
+	}
+}
+
+// close waits for the asynchronous callbacks to work through their queues.
+func (d *turnDispatcher) close() {
+	for _, queue := range d.queues {
+		if queue != nil {
+			close(queue)
+		}
+	}
+	d.wg.Wait()
+}
@@ -0,0 +1,108 @@
+package gol
+
+import (
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// cellHashes gives each cell, relative to the corner of the pattern's box, a fixed pseudo-random number. The
+// numbers of a pattern's cells are added up to hash it, which doesn't depend on the order the cells are listed in.
+var cellHashes = util.NewTurnRNG(0x5eed, 0)
+
+// sighting is when a pattern with a given hash was last seen, and where its box was.
+type sighting struct {
+	turn   int
+	origin util.Cell
+}
+
+// classifier notices when the alive cells are the same shape as they were a few turns earlier, wherever the
+// shape has moved to: a still life, an oscillator or a spaceship. A shape is hashed canonically, from the cells
+// relative to the corner of its bounding box along with the box's size, so a shape that has moved hashes the same.
+type classifier struct {
+	within        int // Longest period looked for.
+	width, height int // Size of the torus, or 0 on the infinite plane.
+	seen          map[uint64]sighting
+	history       []uint64      // Hash of each of the last within turns, oldest first, so they can be forgotten.
+	reported      PatternPeriod // Last period reported, so it isn't reported again every turn.
+}
+
+// newClassifier returns a classifier looking for periods of up to within turns on a torus of the given size, or
+// on the plane if the size is 0.
+func newClassifier(within, width, height int) *classifier {
+	return &classifier{within: within, width: width, height: height, seen: make(map[uint64]sighting)}
+}
+
+// observe records the alive cells at the end of a turn. It returns a PatternPeriod the first turn they are found
+// to repeat an earlier turn's shape, and again whenever the period or the movement changes.
+func (c *classifier) observe(turn int, cells []util.Cell) (PatternPeriod, bool) {
+	if len(cells) == 0 {
+		c.reported = PatternPeriod{}
+		return PatternPeriod{}, false
+	}
+	extent := measureExtent(turn, cells, c.width, c.height)
+	origin := util.Cell{X: extent.Bounds.Min.X, Y: extent.Bounds.Min.Y}
+	hash := c.hash(cells, origin, extent.Bounds.Dx(), extent.Bounds.Dy())
+
+	earlier, found := c.seen[hash]
+	c.seen[hash] = sighting{turn, origin}
+	c.history = append(c.history, hash)
+	if len(c.history) > c.within {
+		// Forget the oldest turn, unless its shape has been seen again since.
+		oldest := c.history[0]
+		c.history = c.history[1:]
+		if s := c.seen[oldest]; s.turn <= turn-c.within {
+			delete(c.seen, oldest)
+		}
+	}
+
+	if !found {
+		c.reported = PatternPeriod{}
+		return PatternPeriod{}, false
+	}
+	period := PatternPeriod{
+		CompletedTurns: turn,
+		Period:         turn - earlier.turn,
+		Alive:          len(cells),
+		DX:             int(shortest(float64(origin.X-earlier.origin.X), c.width)),
+		DY:             int(shortest(float64(origin.Y-earlier.origin.Y), c.height)),
+	}
+	if period.Period == c.reported.Period && period.DX == c.reported.DX && period.DY == c.reported.DY {
+		return PatternPeriod{}, false
+	}
+	c.reported = period
+	return period, true
+}
+
+// stationary reports whether the shape last seen repeats without moving, as a still life or an oscillator does. A
+// nil classifier, when Params.Classify is off, never sees one.
+func (c *classifier) stationary() bool {
+	return c != nil && c.reported.Period > 0 && c.reported.DX == 0 && c.reported.DY == 0
+}
+
+// Kind returns "still life", "oscillator" or "spaceship".
+func (p PatternPeriod) Kind() string {
+	switch {
+	case p.DX != 0 || p.DY != 0:
+		return "spaceship"
+	case p.Period == 1:
+		return "still life"
+	}
+	return "oscillator"
+}
+
+// Velocity returns how many cells the shape moves each turn in each direction, e.g. 0.25, 0.25 for a glider.
+func (p PatternPeriod) Velocity() (float64, float64) {
+	return float64(p.DX) / float64(p.Period), float64(p.DY) / float64(p.Period)
+}
+
+// hash returns the canonical hash of the cells of a pattern whose box has the given corner and size.
+func (c *classifier) hash(cells []util.Cell, origin util.Cell, width, height int) uint64 {
+	sum := cellHashes.Uint64(-width, -height) // The box's size, at a position no cell can have.
+	for _, cell := range cells {
+		x, y := cell.X-origin.X, cell.Y-origin.Y
+		if c.width > 0 {
+			x, y = (x+c.width)%c.width, (y+c.height)%c.height
+		}
+		sum += cellHashes.Uint64(x, y)
+	}
+	return sum
+}
@@ -0,0 +1,63 @@
+package gol
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestClassifier checks a block, a blinker and a glider crossing the edges of the torus are each reported once,
+// as a still life, an oscillator and a spaceship, with the right period and movement.
+func TestClassifier(t *testing.T) {
+	tests := []struct {
+		name   string
+		cells  []util.Cell
+		kind   string
+		period int
+		dx, dy int
+	}{
+		{"block", []util.Cell{{X: 5, Y: 5}, {X: 6, Y: 5}, {X: 5, Y: 6}, {X: 6, Y: 6}}, "still life", 1, 0, 0},
+		{"blinker", []util.Cell{{X: 15, Y: 8}, {X: 0, Y: 8}, {X: 1, Y: 8}}, "oscillator", 2, 0, 0},
+		{"glider", []util.Cell{{X: 13, Y: 12}, {X: 14, Y: 13}, {X: 12, Y: 14}, {X: 13, Y: 14}, {X: 14, Y: 14}}, "spaceship", 4, 1, 1},
+	}
+	const size = 16
+	p := Params{Threads: 2, ImageWidth: size, ImageHeight: size}
+	results := []chan sliceResult{make(chan sliceResult), make(chan sliceResult)}
+	for _, test := range tests {
+		world := newRows(size, size)
+		for _, cell := range test.cells {
+			world[cell.Y][cell.X] = util.Alive
+		}
+		shapes := newClassifier(8, size, size)
+		var reported []PatternPeriod
+		for turn := 1; turn <= 40; turn++ {
+			world = nextWorld(p, world, results)
+			if period, ok := shapes.observe(turn, calculateAliveCells(world)); ok {
+				reported = append(reported, period)
+			}
+		}
+
+		if len(reported) != 1 {
+			t.Fatalf("%s: reported %v, expected one PatternPeriod", test.name, reported)
+		}
+		e := reported[0]
+		if e.Kind() != test.kind || e.Period != test.period || e.DX != test.dx || e.DY != test.dy {
+			t.Errorf("%s: reported a %s of period %d moving (%d, %d), expected a %s of period %d moving (%d, %d)",
+				test.name, e.Kind(), e.Period, e.DX, e.DY, test.kind, test.period, test.dx, test.dy)
+		}
+		if e.CompletedTurns != 1+test.period {
+			t.Errorf("%s: reported at turn %d, expected %d", test.name, e.CompletedTurns, 1+test.period)
+		}
+	}
+}
+
+// TestClassifierWithin checks a period longer than the classifier looks for isn't reported.
+func TestClassifierWithin(t *testing.T) {
+	blinker := [][]util.Cell{{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}}, {{X: 0, Y: 1}, {X: 1, Y: 1}, {X: 2, Y: 1}}}
+	shapes := newClassifier(1, 0, 0)
+	for turn := 1; turn <= 10; turn++ {
+		if period, ok := shapes.observe(turn, blinker[turn%2]); ok {
+			t.Fatalf("reported %v, longer than the one turn looked for", period)
+		}
+	}
+}
@@ -17,14 +17,46 @@ type distributorChannels struct {
 	keyPresses <-chan rune      // Channel to receive key presses from the GUI.
 }
 
-// worker function computes the next state of a slice of the world.
-func worker(id int, p Params, world [][]byte, result chan<- [][]byte, c distributorChannels, turn int) {
+// sliceResult is the next state of a worker's slice of the world, and the cells in it that flipped.
+type sliceResult struct {
+	rows    [][]byte
+	flipped []util.Cell
+}
+
+// worker function computes the next state of a slice of the world. It writes the slice into the same rows of next
+// if it isn't nil, or into new rows otherwise. With p.Interleave the worker owns every p.Threads-th row, starting
+// at row id, rather than a band of rows; it always writes them into next, and sends back no rows.
+func worker(id int, p Params, world, next [][]byte, result chan<- sliceResult) {
+	if p.Interleave {
+		var flipped []util.Cell
+		for row := id; row < p.ImageHeight; row += p.Threads {
+			flipped = append(flipped, calculateNextState(world, next[row:row+1], row, row+1, p)...)
+		}
+		result <- sliceResult{nil, flipped}
+		return
+	}
+
+	startRow, endRow := workerRows(id, p)
+
+	// Calculate the next state for this worker's slice.
+	var newWorld [][]byte
+	if next != nil {
+		newWorld = next[startRow:endRow]
+	} else {
+		newWorld = newRows(endRow-startRow, p.ImageWidth)
+	}
+	flipped := calculateNextState(world, newWorld, startRow, endRow, p)
+
+	// Send the computed slice and its flipped cells back to the distributor, which leaves sending events to the emitter.
+	result <- sliceResult{newWorld, flipped}
+}
+
+// workerRows returns the band of rows worker id computes, from startRow up to endRow.
+func workerRows(id int, p Params) (startRow, endRow int) {
 	// Calculate the base number of rows per worker and the remainder.
 	rowsPerWorker := p.ImageHeight / p.Threads
 	remainder := p.ImageHeight % p.Threads
 
-	var startRow, endRow int
-
 	if id < remainder {
 		// Workers with id less than remainder get an extra row.
 		startRow = id * (rowsPerWorker + 1)
@@ -34,12 +66,27 @@ func worker(id int, p Params, world [][]byte, result chan<- [][]byte, c distribu
 		startRow = id*rowsPerWorker + remainder
 		endRow = startRow + rowsPerWorker
 	}
+	return
+}
 
-	// Calculate the next state for this worker's slice.
-	newWorld := calculateNextState(world, startRow, endRow, c, turn, p)
-
-	// Send the computed slice back to the distributor.
-	result <- newWorld
+// nextWorld computes the next state of the whole world with p.Threads workers, one result channel each, without
+// the flipped cells the distributor sends on.
+func nextWorld(p Params, world [][]byte, results []chan sliceResult) [][]byte {
+	var rows [][]byte
+	if p.Interleave {
+		rows = newRows(p.ImageHeight, p.ImageWidth)
+	}
+	for i := 0; i < p.Threads; i++ {
+		go worker(i, p, world, rows, results[i])
+	}
+	next := make([][]byte, 0, p.ImageHeight)
+	for i := 0; i < p.Threads; i++ {
+		next = append(next, (<-results[i]).rows...)
+	}
+	if p.Interleave {
+		return rows
+	}
+	return next
 }
 
 // savePGMImage function saves the current state of the world as a PGM image.
@@ -58,6 +105,11 @@ func savePGMImage(c distributorChannels, world [][]byte, p Params) {
 
 // distributor divides the work between workers and interacts with other goroutines.
 func distributor(p Params, c distributorChannels) {
+	checkGeometry(p)
+
+	// Send events from a separate goroutine, so delivering them overlaps with computing the next turn.
+	out := newEmitter(c.events)
+
 	// Signal the IO goroutine to start input operation.
 	c.ioCommand <- ioInput
 	c.ioFilename <- fmt.Sprintf("%d%s%d", p.ImageWidth, "x", p.ImageHeight)
@@ -77,90 +129,227 @@ func distributor(p Params, c distributorChannels) {
 		}
 	}
 
-	// Send CellFlipped events for all initially alive cells.
-	for i := range world {
-		for j := range world[i] {
-			if world[i][j] == 255 {
-				c.events <- CellFlipped{0, util.Cell{j, i}}
-			}
-		}
+	// Send the initially alive cells.
+	out.sendInitial(p, calculateAliveCells(world))
+
+	// The fastest thread count depends on the board and the machine, so try a few before starting.
+	if p.Autotune {
+		p.Threads = autotune(p, world)
 	}
 
-	turn := 0                                    // Initialise the turn counter.
-	quit := false                                // Flag to indicate if the program should quit.
-	resultCh := make([]chan [][]byte, p.Threads) // Channels to receive results from workers.
+	// Packed, the world is a bit per cell, and every generation is computed into a new packed world.
+	var packed *packedGrid
+	if p.BitPacked {
+		packed = packGrid(world)
+	}
 
-	// Initialise result channels for each worker.
-	for i := range resultCh {
-		resultCh[i] = make(chan [][]byte)
+	// With an arena, every generation is written into one of two buffers allocated now, rather than into new rows.
+	var arena *gridArena
+	if p.Arena && packed != nil {
+		arena = newPackedArena(p.ImageWidth, p.ImageHeight, p.HugePages)
+	} else if p.Arena {
+		arena = newGridArena(p.ImageWidth, p.ImageHeight, p.HugePages)
 	}
+	summary := startSummary(arena, p.HugePages)
+
+	turn := 0                   // Initialise the turn counter.
+	quit := false               // Flag to indicate if the program should quit.
+	stepping := false           // Flag to indicate a single step was requested while paused.
+	pausing := false            // Flag to indicate 'p' was pressed, pausing once the turn is complete.
+	workers := newWorkerPool(p) // Worker goroutines, started once and handed every turn.
 
 	// Create a ticker to send AliveCellsCount events every 2 seconds.
 	ticker := time.NewTicker(2 * time.Second)
 
+	var speculated []generation // Turns worked out ahead, to be used instead of stepping.
+	retryAt := 0                // Turn before which speculating isn't tried again after it last failed.
+
+	var shapes *classifier
+	if p.Classify > 0 {
+		shapes = newClassifier(p.Classify, p.ImageWidth, p.ImageHeight)
+	}
+	idling := &idler{after: p.IdleAfter}
+
+	// Start delivering completed turns to any callbacks registered by library users.
+	callbacks := newTurnDispatcher(p.callbacks)
+	callbacks.borrowed = arena != nil
+
 	// Main loop to process each turn.
 	for turn := 0; turn < p.Turns; turn++ {
 		if quit {
 			break // Exit the loop if quit flag is set.
 		}
 
-		// Start worker goroutines to compute the next state in parallel.
-		for i := 0; i < p.Threads; i++ {
-			go worker(i, p, world, resultCh[i], c, turn)
+		var flipped [][]util.Cell
+		if len(speculated) > 0 {
+			// This turn was worked out ahead along with the last.
+			world, flipped = speculated[0].world, [][]util.Cell{speculated[0].flipped}
+			speculated = speculated[1:]
+		} else if packed != nil {
+			// Hand the pool's workers the packed world, each writing its rows of the next into a new one, or into
+			// the arena if there is one.
+			var next *packedGrid
+			if arena != nil {
+				next = arena.nextPacked()
+				summary.rowsReused += p.ImageHeight
+			} else {
+				next = newPackedGrid(p.ImageWidth, p.ImageHeight)
+			}
+			flipped = make([][]util.Cell, 0, p.Threads+1)
+			for _, resultPart := range workers.stepPacked(packed, next) {
+				flipped = append(flipped, resultPart.flipped)
+			}
+			packed = next
+		} else {
+			// Hand the pool's workers the next state to compute in parallel, into the arena if there is one.
+			var next [][]byte
+			if arena != nil {
+				next = arena.next()
+				summary.rowsReused += p.ImageHeight
+			} else if p.Interleave {
+				next = newRows(p.ImageHeight, p.ImageWidth)
+			}
+			results := workers.step(world, next)
+
+			// Assemble the new world state from every worker's slice.
+			flipped = make([][]util.Cell, 0, p.Threads+1)
+			for _, resultPart := range results {
+				newWorld = append(newWorld, resultPart.rows...) // Append the slice to form the new world.
+				flipped = append(flipped, resultPart.flipped)
+			}
+
+			// Update the world with the new state. Interleaved workers wrote theirs straight into next.
+			if p.Interleave {
+				newWorld = next
+			}
+			world = append([][]byte{}, newWorld...)
+			newWorld = [][]byte{} // Reset newWorld for the next turn.
+
+			// With few cells changing, work out the next turns ahead in just the regions that are changing. If one
+			// of them spreads too far, leave it a while before trying again. Turns worked out ahead share rows, which
+			// the arena would write over.
+			if p.Speculate > 0 && arena == nil && turn+1 >= retryAt {
+				ahead := p.Speculate
+				if left := p.Turns - turn - 1; left < ahead {
+					ahead = left
+				}
+				var failed bool
+				speculated, failed = speculate(p, world, flipped, ahead)
+				if failed {
+					retryAt = turn + 1 + p.Speculate
+				}
+			}
+		}
+
+		// Give any scripting hooks that are due a chance to perturb the board. A packed world is unpacked for them,
+		// only on the turns they are due, and packed again in place if they changed it; it hasn't been handed out yet.
+		if packed == nil {
+			if changed := runHooks(p.Hooks, turn+1, world); len(changed) > 0 {
+				flipped = append(flipped, changed)
+			}
+		} else if hooksDue(p.Hooks, turn+1) {
+			rows := packed.rows()
+			if changed := runHooks(p.Hooks, turn+1, rows); len(changed) > 0 {
+				flipped = append(flipped, changed)
+				packed.pack(rows)
+			}
+		}
+
+		// The finished world, packed or not, for everything below that only reads it.
+		var board ReadOnlyGrid = grid(world)
+		if packed != nil {
+			board = packed
 		}
 
-		// Collect results from all workers and assemble the new world state.
-		for i := 0; i < p.Threads; i++ {
-			resultPart := <-resultCh[i]                // Receive the computed slice.
-			newWorld = append(newWorld, resultPart...) // Append the slice to form the new world.
+		// Queue the turn's flipped cells and move on; the emitter sends them while the next turn is computed.
+		out.sendTurn(turn, flipped)
+
+		// Hand the finished world to any per-turn callbacks.
+		callbacks.dispatch(turn+1, board)
+
+		// Say where the pattern has got to, for the GUI to follow it, and whether it has settled into a known shape.
+		if p.ExtentEvery > 0 && (turn+1)%p.ExtentEvery == 0 {
+			out.send(measureExtent(turn+1, board.AliveCells(), p.ImageWidth, p.ImageHeight))
+		}
+		if shapes != nil {
+			if period, ok := shapes.observe(turn+1, board.AliveCells()); ok {
+				out.send(period)
+			}
 		}
 
-		// Update the world with the new state.
-		world = append([][]byte{}, newWorld...)
-		newWorld = [][]byte{} // Reset newWorld for the next turn.
+		// Say when the board has stopped changing, or has started again, e.g. after a hook changed it.
+		if state, ok := idling.observe(countFlipped(flipped) == 0 || shapes.stationary()); ok {
+			out.send(StateChange{CompletedTurns: turn, NewState: state})
+		}
 
-		// Handle events such as key presses and ticker ticks.
-		select {
-		case <-ticker.C:
+		// Handle events such as key presses and ticker ticks. While idle, wait a while for one rather than racing
+		// on through turns that change nothing.
+		command, tick := nextInput(c.keyPresses, ticker.C, idling.wait())
+		if tick {
 			// Send AliveCellsCount event every 2 seconds.
-			c.events <- AliveCellsCount{turn + 1, len(calculateAliveCells(world))}
-		case command := <-c.keyPresses:
-			// Handle key press events.
-			switch command {
-			case 's':
-				// Save the current state as a PGM image.
-				c.events <- StateChange{turn, Executing}
-				savePGMImage(c, world, p)
-			case 'q':
-				// Save the current state and set the quit flag to exit.
-				c.events <- StateChange{turn, Quitting}
-				savePGMImage(c, world, p)
-				quit = true
-				break
-			case 'p':
-				// Pause the execution until 'p' is pressed again.
-				c.events <- StateChange{turn, Paused}
-				fmt.Printf("Current turn %d being processed\n", turn)
-				for {
-					if <-c.keyPresses == 'p' {
-						break // Resume execution when 'p' is pressed again.
-					}
-				}
-				c.events <- StateChange{turn, Executing}
+			out.send(AliveCellsCount{CompletedTurns: turn + 1, CellsCount: len(board.AliveCells())})
+		}
+		if command != 0 {
+			// Any key press wakes the distributor, so the user sees it respond at full speed.
+			if state, ok := idling.wake(); ok {
+				out.send(StateChange{CompletedTurns: turn, NewState: state})
 			}
-		default:
-			// No event; continue processing.
+		}
+		// Handle key press events.
+		switch command {
+		case 's':
+			// Save the current state as a PGM image.
+			out.send(StateChange{CompletedTurns: turn, NewState: Executing})
+			savePGMImage(c, boardRows(world, packed), p)
+		case 'q':
+			// Save the current state and set the quit flag to exit.
+			out.send(StateChange{CompletedTurns: turn, NewState: Quitting})
+			savePGMImage(c, boardRows(world, packed), p)
+			quit = true
+			break
+		case 'p':
+			// Pause the execution until 'p' is pressed again, once the turn is complete.
+			pausing = true
+		case '+', '-':
+			// Resize the worker pool. This is a turn boundary, so the next turn uses the new pool.
+			p.Threads = adjustThreads(p, command)
+			workers.close()
+			workers = newWorkerPool(p)
+			fmt.Printf("Using %d worker threads from turn %d\n", p.Threads, turn+1)
 		}
 
-		// Send TurnComplete event after finishing the turn.
-		c.events <- TurnComplete{CompletedTurns: turn}
+		// Send TurnComplete event after finishing the turn. The arena writes over the world two turns from now,
+		// likely before the event is read, so it isn't sent with one.
+		var completed ReadOnlyGrid
+		if arena == nil {
+			completed = board
+		}
+		out.send(TurnComplete{CompletedTurns: turn, World: completed})
+		summary.turns++
+
+		// Pause only after the TurnComplete, so the GUI has drawn exactly this turn, not part of it, when it is told.
+		// After a single step, stay paused so the new turn can be inspected.
+		if pausing {
+			pausing = false
+			out.send(StateChange{CompletedTurns: turn, NewState: Paused})
+			fmt.Printf("Current turn %d being processed\n", turn)
+			stepping = waitForResume(c, out, turn, nil)
+		} else if stepping {
+			stepping = waitForResume(c, out, turn, nil)
+		}
 	}
 
+	workers.close()
+	world = boardRows(world, packed)
+
+	// Let asynchronous callbacks catch up, so they have all run by the time the events channel closes.
+	callbacks.close()
+
 	// Calculate the final list of alive cells.
 	calculateAliveCells(world)
 
 	// Send FinalTurnComplete event with the list of alive cells.
-	c.events <- FinalTurnComplete{turn, calculateAliveCells(world)}
+	out.send(FinalTurnComplete{CompletedTurns: turn, Alive: calculateAliveCells(world), World: grid(world)})
 
 	// Save the final state as a PGM image.
 	savePGMImage(c, world, p)
@@ -169,61 +358,116 @@ func distributor(p Params, c distributorChannels) {
 	c.ioCommand <- ioCheckIdle
 	<-c.ioIdle
 
+	fmt.Println(summary)
+
 	// Send a StateChange event to indicate the program is quitting.
-	c.events <- StateChange{p.Turns, Quitting}
+	out.send(StateChange{CompletedTurns: p.Turns, NewState: Quitting})
 
-	// Close the events channel to allow the GUI to shut down gracefully.
+	// Wait for the emitter to send everything queued, then close the events channel to allow the GUI to shut down gracefully.
+	out.close()
 	close(c.events)
 }
 
-// calculateNextState computes the next state of a slice of the world grid.
-func calculateNextState(world [][]byte, startRow, endRow int, c distributorChannels, turn int, p Params) [][]byte {
+// adjustThreads returns the thread count after a '+' or '-' key press.
+// There is always at least one worker, and never more workers than rows.
+func adjustThreads(p Params, command rune) int {
+	threads := p.Threads
+	if command == '+' && threads < p.ImageHeight {
+		threads++
+	} else if command == '-' && threads > 1 {
+		threads--
+	}
+	return threads
+}
+
+// waitForResume blocks while paused until 'p' resumes execution or 'n' requests a single step.
+// It reports whether the pause ended with a step, in which case the distributor pauses again after one turn.
+// Any other key press is passed to other, if it isn't nil.
+func waitForResume(c distributorChannels, out *emitter, turn int, other func(rune)) bool {
+	for {
+		switch command := <-c.keyPresses; command {
+		case 'p':
+			// Resume execution when 'p' is pressed again.
+			out.send(StateChange{CompletedTurns: turn, NewState: Executing})
+			return false
+		case 'n':
+			// Run exactly one more turn before pausing again.
+			return true
+		default:
+			if other != nil {
+				other(command)
+			}
+		}
+	}
+}
+
+// boardRows returns the rows of the world: world itself, or packed unpacked if the world is packed.
+func boardRows(world [][]byte, packed *packedGrid) [][]byte {
+	if packed == nil {
+		return world
+	}
+	return packed.rows()
+}
+
+// newRows allocates rows for a slice of the world.
+func newRows(rows, width int) [][]byte {
+	slice := make([][]byte, rows)
+	for i := range slice {
+		slice[i] = make([]byte, width)
+	}
+	return slice
+}
+
+// calculateNextState computes the next state of a slice of the world grid into nextState, which holds the slice's
+// rows, and returns the cells in the slice that flipped.
+func calculateNextState(world, nextState [][]byte, startRow, endRow int, p Params) []util.Cell {
 	height := p.ImageHeight
 	width := p.ImageWidth
 
-	// Initialise the next state slice.
-	nextState := make([][]byte, endRow-startRow)
-	for i := 0; i < endRow-startRow; i++ {
-		nextState[i] = make([]byte, width)
-	}
+	var flipped []util.Cell
 
 	// Iterate over each cell in the assigned slice.
 	for i := startRow; i < endRow; i++ {
 		for j := 0; j < width; j++ {
 			// Calculate the sum of alive neighbouring cells.
-			sum := (int(world[(i+height-1)%height][(j+width-1)%width]) +
-				int(world[(i+height-1)%height][(j+width)%width]) +
-				int(world[(i+height-1)%height][(j+width+1)%width]) +
-				int(world[(i+height)%height][(j+width-1)%width]) +
-				int(world[(i+height)%height][(j+width+1)%width]) +
-				int(world[(i+height+1)%height][(j+width-1)%width]) +
-				int(world[(i+height+1)%height][(j+width)%width]) +
-				int(world[(i+height+1)%height][(j+width+1)%width])) / 255
-
-			// Apply the Game of Life rules.
-			if world[i][j] == 255 { // If the cell is alive.
-				if sum < 2 || sum > 3 {
+			var sum int
+			if p.Geometry == Triangular {
+				sum = triangularSum(world, j, i, width, height)
+			} else {
+				sum = (int(world[(i+height-1)%height][(j+width-1)%width]) +
+					int(world[(i+height-1)%height][(j+width)%width]) +
+					int(world[(i+height-1)%height][(j+width+1)%width]) +
+					int(world[(i+height)%height][(j+width-1)%width]) +
+					int(world[(i+height)%height][(j+width+1)%width]) +
+					int(world[(i+height+1)%height][(j+width-1)%width]) +
+					int(world[(i+height+1)%height][(j+width)%width]) +
+					int(world[(i+height+1)%height][(j+width+1)%width])) / int(util.Alive)
+			}
+
+			// Apply the rules of the board's geometry.
+			if world[i][j] == util.Alive { // If the cell is alive.
+				if !p.Geometry.nextAlive(true, sum) {
 					// Cell dies due to underpopulation or overpopulation.
-					nextState[i-startRow][j] = 0
-					c.events <- CellFlipped{turn, util.Cell{j, i}}
+					nextState[i-startRow][j] = util.Dead
+					flipped = append(flipped, util.Cell{X: j, Y: i})
 				} else {
 					// Cell stays alive.
-					nextState[i-startRow][j] = 255
+					nextState[i-startRow][j] = util.Alive
 				}
 			} else { // If the cell is dead.
-				if sum == 3 {
+				if p.Geometry.nextAlive(false, sum) {
 					// Cell becomes alive due to reproduction.
-					nextState[i-startRow][j] = 255
-					c.events <- CellFlipped{turn, util.Cell{j, i}}
+					nextState[i-startRow][j] = util.Alive
+					flipped = append(flipped, util.Cell{X: j, Y: i})
 				} else {
 					// Cell stays dead.
-					nextState[i-startRow][j] = 0
+					nextState[i-startRow][j] = util.Dead
 				}
 			}
 		}
 	}
 
-	return nextState
+	return flipped
 }
 
 // calculateAliveCells returns a list of coordinates of all alive cells in the world.
@@ -231,7 +475,7 @@ func calculateAliveCells(world [][]byte) []util.Cell {
 	aliveCells := []util.Cell{}
 	for i := range world { // Iterate over rows.
 		for j := range world[i] { // Iterate over columns.
-			if world[i][j] == 255 {
+			if world[i][j] == util.Alive {
 				// Append the cell's coordinates if it is alive.
 				aliveCells = append(aliveCells, util.Cell{j, i})
 			}
@@ -0,0 +1,75 @@
+package gol
+
+import (
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// batch is a group of events queued on an emitter together.
+type batch struct {
+	turn    int
+	flipped [][]util.Cell // Cells to send CellFlipped events for, before events.
+	events  []Event
+	emitted time.Time // When the batch was queued, which its events are stamped with.
+}
+
+// emitter sends events on the distributor's behalf, in the order they were queued. The distributor queues each
+// turn's events in one go and carries on computing the next turn while they are delivered, so sending events
+// for turn T overlaps with computing turn T+1 instead of holding up the workers.
+type emitter struct {
+	queue chan batch
+	done  chan struct{}
+}
+
+// emitterDepth is how many batches may be queued before the distributor waits for the emitter to catch up,
+// which bounds how far computation can get ahead of the events.
+const emitterDepth = 2
+
+// newEmitter starts an emitter that sends to events.
+func newEmitter(events chan<- Event) *emitter {
+	e := &emitter{queue: make(chan batch, emitterDepth), done: make(chan struct{})}
+	go e.run(events)
+	return e
+}
+
+func (e *emitter) run(events chan<- Event) {
+	for b := range e.queue {
+		for _, cells := range b.flipped {
+			for _, cell := range cells {
+				events <- CellFlipped{CompletedTurns: b.turn, Cell: cell, Emitted: b.emitted}
+			}
+		}
+		for _, event := range b.events {
+			events <- stamp(event, b.emitted)
+		}
+	}
+	close(e.done)
+}
+
+// send queues events to be sent after everything queued before them. Events are stamped with when they were
+// queued rather than when they were sent, so time spent waiting for the emitter counts towards their latency.
+func (e *emitter) send(events ...Event) {
+	e.queue <- batch{events: events, emitted: time.Now()}
+}
+
+// sendTurn queues CellFlipped events for the cells flipped in a turn, followed by any other events.
+func (e *emitter) sendTurn(turn int, flipped [][]util.Cell, events ...Event) {
+	e.queue <- batch{turn: turn, flipped: flipped, events: events, emitted: time.Now()}
+}
+
+// sendInitial queues the cells alive on the starting board, as one InitialBoard event if p asks for it and as
+// turn 0 CellFlipped events otherwise.
+func (e *emitter) sendInitial(p Params, alive []util.Cell) {
+	if p.InitialBoard {
+		e.send(InitialBoard{Alive: alive})
+		return
+	}
+	e.sendTurn(0, [][]util.Cell{alive})
+}
+
+// close waits until every queued event has been sent. Nothing may be queued afterwards.
+func (e *emitter) close() {
+	close(e.queue)
+	<-e.done
+}
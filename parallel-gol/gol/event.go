@@ -2,10 +2,13 @@ package gol
 
 import (
 	"fmt"
+	"image"
+	"time"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
 // Event represents any Game of Life event that needs to be communicated to the user.
+// Every event records when it was Emitted, so the GUI can measure how long events take to be shown.
 type Event interface {
 	// Stringer allows each event to be printed by the GUI
 	fmt.Stringer
@@ -19,6 +22,7 @@ type Event interface {
 type AliveCellsCount struct { // implements Event
 	CompletedTurns int
 	CellsCount     int
+	Emitted        time.Time // When the event was sent.
 }
 
 // ImageOutputComplete is an Event notifying the user about the completion of output.
@@ -26,6 +30,7 @@ type AliveCellsCount struct { // implements Event
 type ImageOutputComplete struct { // implements Event
 	CompletedTurns int
 	Filename       string
+	Emitted        time.Time // When the event was sent.
 }
 
 // State represents a change in the state of execution.
@@ -35,6 +40,7 @@ const (
 	Paused State = iota
 	Executing
 	Quitting
+	Idle // Nothing has changed for Params.IdleAfter turns, so turns have slowed down until something does.
 )
 
 // StateChange is an Event notifying the user about the change of state of execution.
@@ -42,6 +48,7 @@ const (
 type StateChange struct { // implements Event
 	CompletedTurns int
 	NewState       State
+	Emitted        time.Time // When the event was sent.
 }
 
 // CellFlipped is an Event notifying the GUI about a change of state of a single cell.
@@ -50,6 +57,17 @@ type StateChange struct { // implements Event
 type CellFlipped struct { // implements Event
 	CompletedTurns int
 	Cell           util.Cell
+	Emitted        time.Time // When the event was sent.
+}
+
+// InitialBoard is an Event giving the GUI every cell alive on the starting board at once. It is sent instead of
+// the turn 0 CellFlipped events when Params.InitialBoard is set, before any other CellFlipped or TurnComplete,
+// so a dense board is drawn in one pass rather than from one event per alive cell. In infinite mode it holds
+// just the cells inside the viewport.
+type InitialBoard struct { // implements Event
+	CompletedTurns int
+	Alive          []util.Cell
+	Emitted        time.Time // When the event was sent.
 }
 
 // TurnComplete is an Event notifying the GUI about turn completion.
@@ -57,6 +75,8 @@ type CellFlipped struct { // implements Event
 // All CellFlipped events must be sent *before* TurnComplete.
 type TurnComplete struct { // implements Event
 	CompletedTurns int
+	World          ReadOnlyGrid // The world at the end of the turn, shared rather than copied. Nil in infinite mode.
+	Emitted        time.Time    // When the event was sent.
 }
 
 // FinalTurnComplete is an Event notifying the testing framework about the new world state after execution finished.
@@ -65,6 +85,42 @@ type TurnComplete struct { // implements Event
 type FinalTurnComplete struct {
 	CompletedTurns int
 	Alive          []util.Cell
+	World          ReadOnlyGrid // The final world, shared rather than copied. Nil in infinite mode.
+	Emitted        time.Time    // When the event was sent.
+}
+
+// PopulationCapped is an Event notifying the user that more cells came alive on the infinite plane than
+// Params.MaxAlive allows. Culled is how many cells were killed to get back under the cap, or 0 if the run paused.
+type PopulationCapped struct { // implements Event
+	CompletedTurns int
+	Alive          int
+	Cap            int
+	Culled         int
+	Emitted        time.Time // When the event was sent.
+}
+
+// PatternExtent is an Event giving where the alive cells are: the smallest box holding them all, with Min
+// inclusive and Max exclusive, and their centre. It is sent every Params.ExtentEvery turns, so the GUI can follow
+// a moving pattern and DriftSince can measure how fast it moves. On the torus a pattern across an edge has a box
+// reaching past the world's right or bottom edge, so coordinates should be taken modulo the world's size; in
+// infinite mode they are plane coordinates. Bounds is empty and the centre (0, 0) when nothing is alive.
+type PatternExtent struct { // implements Event
+	CompletedTurns   int
+	Alive            int
+	Bounds           image.Rectangle
+	CentreX, CentreY float64
+	Emitted          time.Time // When the event was sent.
+}
+
+// PatternPeriod is an Event notifying the user that the alive cells have taken a shape they had Period turns
+// earlier, moved by (DX, DY) cells: a still life, an oscillator or a spaceship. It is sent when Params.Classify
+// is set, the first turn the shape repeats and again whenever its period or movement changes.
+type PatternPeriod struct { // implements Event
+	CompletedTurns int
+	Period         int
+	Alive          int
+	DX, DY         int       // How far the shape moves each period, the shorter way around the torus.
+	Emitted        time.Time // When the event was sent.
 }
 
 // String methods allow the different types of Events and States to be printed.
@@ -77,6 +133,8 @@ func (state State) String() string {
 		return "Executing"
 	case Quitting:
 		return "Quitting"
+	case Idle:
+		return "Idle"
 	default:
 		return "Incorrect State"
 	}
@@ -114,6 +172,14 @@ func (event CellFlipped) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event InitialBoard) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event InitialBoard) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event TurnComplete) String() string {
 	return fmt.Sprintf("")
 }
@@ -130,6 +196,76 @@ func (event FinalTurnComplete) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event PopulationCapped) String() string {
+	if event.Culled > 0 {
+		return fmt.Sprintf("%v cells alive, over the cap of %v, so %v furthest from view were culled", event.Alive, event.Cap, event.Culled)
+	}
+	return fmt.Sprintf("%v cells alive, over the cap of %v, so paused", event.Alive, event.Cap)
+}
+
+func (event PopulationCapped) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event PatternPeriod) String() string {
+	switch event.Kind() {
+	case "still life":
+		return fmt.Sprintf("Still life of %v cells", event.Alive)
+	case "oscillator":
+		return fmt.Sprintf("Oscillator of period %v, %v cells", event.Period, event.Alive)
+	}
+	return fmt.Sprintf("Spaceship of period %v, %v cells, moving (%v, %v) each period", event.Period, event.Alive, event.DX, event.DY)
+}
+
+func (event PatternPeriod) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event PatternExtent) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event PatternExtent) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+// stamp returns a copy of an event with Emitted set to at.
+func stamp(event Event, at time.Time) Event {
+	switch e := event.(type) {
+	case AliveCellsCount:
+		e.Emitted = at
+		return e
+	case ImageOutputComplete:
+		e.Emitted = at
+		return e
+	case StateChange:
+		e.Emitted = at
+		return e
+	case CellFlipped:
+		e.Emitted = at
+		return e
+	case InitialBoard:
+		e.Emitted = at
+		return e
+	case TurnComplete:
+		e.Emitted = at
+		return e
+	case FinalTurnComplete:
+		e.Emitted = at
+		return e
+	case PopulationCapped:
+		e.Emitted = at
+		return e
+	case PatternExtent:
+		e.Emitted = at
+		return e
+	case PatternPeriod:
+		e.Emitted = at
+		return e
+	}
+	return event
+}
+
 // This might all seem like weird syntax to you...
 // You have however seen something similar to it before in first year.
 
@@ -0,0 +1,102 @@
+package gol
+
+import (
+	"math"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// measureExtent works out the PatternExtent of the given alive cells. On a torus of the given size a pattern
+// straddling an edge is measured as one piece: each axis is taken to start just after the widest run of empty
+// columns or rows, so a glider crossing the right edge has a small box reaching past it, rather than one the width
+// of the world. A width and height of 0 measure cells on the unbounded plane of infinite mode.
+func measureExtent(turn int, cells []util.Cell, width, height int) PatternExtent {
+	e := PatternExtent{CompletedTurns: turn, Alive: len(cells)}
+	if len(cells) == 0 {
+		return e
+	}
+	xs, ys := make([]int, len(cells)), make([]int, len(cells))
+	for i, cell := range cells {
+		xs[i], ys[i] = cell.X, cell.Y
+	}
+	e.Bounds.Min.X, e.Bounds.Max.X, e.CentreX = span(xs, width)
+	e.Bounds.Min.Y, e.Bounds.Max.Y, e.CentreY = span(ys, height)
+	return e
+}
+
+// span returns the smallest interval [lo, hi) holding every coordinate, and their mean. With a size, coordinates
+// wrap around it: lo is in [0, size), hi may be past size, and the mean is taken along the interval and then
+// wrapped back into [0, size).
+func span(coords []int, size int) (int, int, float64) {
+	if size <= 0 {
+		lo, hi, sum := math.MaxInt32, math.MinInt32, 0
+		for _, c := range coords {
+			if c < lo {
+				lo = c
+			}
+			if c+1 > hi {
+				hi = c + 1
+			}
+			sum += c
+		}
+		return lo, hi, float64(sum) / float64(len(coords))
+	}
+
+	occupied := make([]bool, size)
+	for _, c := range coords {
+		occupied[c] = true
+	}
+	// Find the widest run of empty coordinates, going round twice so a run across the end is seen whole.
+	gapEnd, gapLength, run := 0, 0, 0
+	for i := 0; i < 2*size; i++ {
+		if occupied[i%size] {
+			run = 0
+			continue
+		}
+		if run++; run > gapLength && run <= size {
+			gapEnd, gapLength = i%size, run
+		}
+	}
+	lo := 0
+	if gapLength > 0 {
+		lo = (gapEnd + 1) % size
+	}
+
+	sum := 0
+	for _, c := range coords {
+		sum += (c - lo + size) % size
+	}
+	mean := float64(lo) + float64(sum)/float64(len(coords))
+	return lo, lo + size - gapLength, math.Mod(mean, float64(size))
+}
+
+// DriftSince returns how far the pattern's centre has moved in each direction per turn since an earlier extent.
+// On a torus of the given size the shorter way around is taken, so a spaceship crossing an edge keeps a steady
+// velocity; a width and height of 0 are for infinite mode. It returns 0, 0 if no turns separate the two.
+func (e PatternExtent) DriftSince(earlier PatternExtent, width, height int) (float64, float64) {
+	turns := float64(e.CompletedTurns - earlier.CompletedTurns)
+	if turns == 0 {
+		return 0, 0
+	}
+	return shortest(e.CentreX-earlier.CentreX, width) / turns, shortest(e.CentreY-earlier.CentreY, height) / turns
+}
+
+// shortest returns the shorter of the two ways round a torus of the given size a distance d can be travelled.
+func shortest(d float64, size int) float64 {
+	if size <= 0 {
+		return d
+	}
+	d = math.Mod(d, float64(size))
+	switch {
+	case d > float64(size)/2:
+		d -= float64(size)
+	case d < -float64(size)/2:
+		d += float64(size)
+	}
+	return d
+}
+
+// Centre returns the cell the pattern's centre lies in.
+func (e PatternExtent) Centre() util.Cell {
+	return util.Cell{X: int(math.Floor(e.CentreX)), Y: int(math.Floor(e.CentreY))}
+}
@@ -0,0 +1,68 @@
+package gol
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestMeasureExtent checks the box and centre of patterns in the middle of the torus, across its edges and on
+// the infinite plane.
+func TestMeasureExtent(t *testing.T) {
+	tests := []struct {
+		name             string
+		cells            []util.Cell
+		size             int
+		bounds           image.Rectangle
+		centreX, centreY float64
+	}{
+		{"nothing", nil, 16, image.Rectangle{}, 0, 0},
+		{"middle", []util.Cell{{X: 4, Y: 5}, {X: 6, Y: 5}, {X: 5, Y: 7}}, 16, image.Rect(4, 5, 7, 8), 5, 17.0 / 3},
+		// A blinker across the right edge is columns 15, 0 and 1, so its box starts at 15.
+		{"right edge", []util.Cell{{X: 15, Y: 3}, {X: 0, Y: 3}, {X: 1, Y: 3}}, 16, image.Rect(15, 3, 18, 4), 0, 3},
+		{"corner", []util.Cell{{X: 15, Y: 15}, {X: 0, Y: 0}}, 16, image.Rect(15, 15, 17, 17), 15.5, 15.5},
+		{"plane", []util.Cell{{X: -20, Y: 3}, {X: 10, Y: -4}}, 0, image.Rect(-20, -4, 11, 4), -5, -0.5},
+	}
+	for _, test := range tests {
+		e := measureExtent(7, test.cells, test.size, test.size)
+		if e.CompletedTurns != 7 || e.Alive != len(test.cells) {
+			t.Errorf("%s: turn %d with %d alive, expected turn 7 with %d", test.name, e.CompletedTurns, e.Alive, len(test.cells))
+		}
+		if e.Bounds != test.bounds {
+			t.Errorf("%s: bounds %v, expected %v", test.name, e.Bounds, test.bounds)
+		}
+		if math.Abs(e.CentreX-test.centreX) > 1e-9 || math.Abs(e.CentreY-test.centreY) > 1e-9 {
+			t.Errorf("%s: centre (%v, %v), expected (%v, %v)", test.name, e.CentreX, e.CentreY, test.centreX, test.centreY)
+		}
+	}
+}
+
+// TestDriftSince checks a glider, which moves a cell diagonally every four turns, drifts at a quarter of a cell
+// a turn, including while it crosses the edges of the torus.
+func TestDriftSince(t *testing.T) {
+	const size = 16
+	world := newRows(size, size)
+	for _, cell := range []util.Cell{{X: 13, Y: 12}, {X: 14, Y: 13}, {X: 12, Y: 14}, {X: 13, Y: 14}, {X: 14, Y: 14}} {
+		world[cell.Y][cell.X] = util.Alive
+	}
+	p := Params{Threads: 2, ImageWidth: size, ImageHeight: size}
+	results := []chan sliceResult{make(chan sliceResult), make(chan sliceResult)}
+
+	earlier := measureExtent(0, calculateAliveCells(world), size, size)
+	for turn := 1; turn <= 40; turn++ {
+		world = nextWorld(p, world, results)
+		if turn%8 != 0 {
+			continue
+		}
+		e := measureExtent(turn, calculateAliveCells(world), size, size)
+		if dx, dy := e.DriftSince(earlier, size, size); math.Abs(dx-0.25) > 1e-9 || math.Abs(dy-0.25) > 1e-9 {
+			t.Errorf("turns %d to %d: drift (%v, %v) a turn, expected (0.25, 0.25)", earlier.CompletedTurns, turn, dx, dy)
+		}
+		if e.Bounds.Dx() != 3 || e.Bounds.Dy() != 3 {
+			t.Errorf("turn %d: bounds %v, expected 3x3", turn, e.Bounds)
+		}
+		earlier = e
+	}
+}
@@ -0,0 +1,102 @@
+package gol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Engines a run can be computed on, as named in an UnsupportedFeature. A run on the torus stores its world in one
+// of the ways that follow them.
+const (
+	TorusEngine    = "the torus"            // distributor evolves a board that wraps around at the edges.
+	InfiniteEngine = "the infinite plane"   // distributeInfinite evolves an unbounded plane in chunks, with Infinite.
+	BytesEngine    = "the byte grid"        // A torus stored a byte per cell in new rows each turn, the default.
+	ArenaEngine    = "the grid arena"       // A torus written into two reused buffers, with Arena.
+	PackedEngine   = "the bit-packed board" // A torus stored a bit per cell, with BitPacked.
+)
+
+// UnsupportedFeature is the error a run is refused with when it asks for something the engine it would run on
+// can't honour, rather than the setting being quietly ignored. It lists the engines that can.
+type UnsupportedFeature struct {
+	Engine    string   // Engine that can't honour the feature, e.g. "the infinite plane" or "the grid arena".
+	Feature   string   // What was asked for, e.g. "speculation".
+	Setting   string   // The setting that asked for it, as given on the command line, e.g. "-speculate 16".
+	Supported []string // Engines that can honour it.
+}
+
+func (e UnsupportedFeature) Error() string {
+	supported := e.Supported[len(e.Supported)-1]
+	if len(e.Supported) > 1 {
+		supported = strings.Join(e.Supported[:len(e.Supported)-1], ", ") + " or " + supported
+	}
+	return fmt.Sprintf("%s can't honour %s, asked for with %s: only %s can", e.Engine, e.Feature, e.Setting, supported)
+}
+
+// features says which engines honour each setting that not every engine does. runs are the engines a run can be
+// computed on; stores, if any are given, are the ways of storing a torus that honour it too.
+var features = []struct {
+	name    string
+	setting func(p Params) string // The setting asking for the feature, or "" if p doesn't.
+	runs    []string
+	stores  []string
+}{
+	{"speculation", func(p Params) string { return flagIf(p.Speculate > 0, fmt.Sprintf("-speculate %d", p.Speculate)) },
+		[]string{TorusEngine}, []string{BytesEngine}},
+	{"triangular cells", func(p Params) string { return flagIf(p.Geometry != Square, "-geometry "+p.Geometry.String()) },
+		[]string{TorusEngine}, []string{BytesEngine, ArenaEngine}},
+	{"hooks", func(p Params) string { return flagIf(len(p.Hooks) > 0, "-glider or -stamp") },
+		[]string{TorusEngine}, nil},
+	{"autotuning", func(p Params) string { return flagIf(p.Autotune, "-autotune") },
+		[]string{TorusEngine}, nil},
+	{"idling", func(p Params) string { return flagIf(p.IdleAfter > 0, fmt.Sprintf("-idle %d", p.IdleAfter)) },
+		[]string{TorusEngine}, nil},
+	{"interleaved rows", func(p Params) string { return flagIf(p.Interleave, "-interleave") },
+		[]string{TorusEngine}, nil},
+	{"a grid arena", func(p Params) string { return flagIf(p.Arena, "-arena") },
+		[]string{TorusEngine}, nil},
+	{"bit packing", func(p Params) string { return flagIf(p.BitPacked, "-bitPacked") },
+		[]string{TorusEngine}, nil},
+}
+
+// flagIf returns setting if it was asked for, and "" if not.
+func flagIf(asked bool, setting string) string {
+	if asked {
+		return setting
+	}
+	return ""
+}
+
+// CheckFeatures returns an UnsupportedFeature for the first setting in p that the engine p runs on, or the way it
+// stores the world, can't honour, and nil if they honour them all.
+func CheckFeatures(p Params) error {
+	run, store := TorusEngine, BytesEngine
+	if p.Infinite {
+		run = InfiniteEngine
+	} else if p.BitPacked {
+		store = PackedEngine
+	} else if p.Arena {
+		store = ArenaEngine
+	}
+	for _, f := range features {
+		setting := f.setting(p)
+		if setting == "" {
+			continue
+		}
+		if !contains(f.runs, run) {
+			return UnsupportedFeature{run, f.name, setting, f.runs}
+		}
+		if run == TorusEngine && f.stores != nil && !contains(f.stores, store) {
+			return UnsupportedFeature{store, f.name, setting, f.stores}
+		}
+	}
+	return nil
+}
+
+func contains(engines []string, engine string) bool {
+	for _, e := range engines {
+		if e == engine {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package gol
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestCheckFeatures checks settings an engine can't honour are refused, naming the engines that can, and that
+// those it can are let through.
+func TestCheckFeatures(t *testing.T) {
+	tests := []struct {
+		p        Params
+		expected error
+	}{
+		{Params{Speculate: 16, Hooks: []Hook{GliderHook(7, 0, 0)}, Geometry: Triangular, Interleave: true}, nil},
+		{Params{Arena: true, BitPacked: true, Interleave: true, Autotune: true, IdleAfter: 5}, nil},
+		{Params{Arena: true, Speculate: 16},
+			UnsupportedFeature{ArenaEngine, "speculation", "-speculate 16", []string{BytesEngine}}},
+		{Params{BitPacked: true, Arena: true, Geometry: Triangular},
+			UnsupportedFeature{PackedEngine, "triangular cells", "-geometry triangular", []string{BytesEngine, ArenaEngine}}},
+		{Params{Infinite: true, Hooks: []Hook{GliderHook(7, 0, 0)}},
+			UnsupportedFeature{InfiniteEngine, "hooks", "-glider or -stamp", []string{TorusEngine}}},
+		{Params{Infinite: true, IdleAfter: 5},
+			UnsupportedFeature{InfiniteEngine, "idling", "-idle 5", []string{TorusEngine}}},
+		{Params{Infinite: true, Autotune: true},
+			UnsupportedFeature{InfiniteEngine, "autotuning", "-autotune", []string{TorusEngine}}},
+		{Params{Infinite: true, BitPacked: true},
+			UnsupportedFeature{InfiniteEngine, "bit packing", "-bitPacked", []string{TorusEngine}}},
+	}
+	for _, test := range tests {
+		if err := CheckFeatures(test.p); fmt.Sprint(err) != fmt.Sprint(test.expected) {
+			t.Errorf("CheckFeatures(%+v) = %v, expected %v", test.p, err, test.expected)
+		}
+	}
+	err := CheckFeatures(Params{BitPacked: true, Geometry: Triangular})
+	expected := "the bit-packed board can't honour triangular cells, asked for with -geometry triangular: only the byte grid or the grid arena can"
+	if _, ok := err.(UnsupportedFeature); !ok || err.Error() != expected {
+		t.Errorf("error is %v, expected UnsupportedFeature %q", err, expected)
+	}
+}
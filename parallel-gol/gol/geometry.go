@@ -0,0 +1,91 @@
+package gol
+
+import (
+	"fmt"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Geometry is the shape of the cells the board is divided into.
+type Geometry int
+
+const (
+	// Square is Conway's Game of Life: square cells with eight neighbours, born with 3 and surviving with 2 or 3.
+	Square Geometry = iota
+	// Triangular divides each row into alternating up and down pointing triangles. Every triangle touches twelve
+	// others (three along its edges and nine at its corners), and the rules are Carter Bays' triangular Life:
+	// born with 4 and surviving with 4, 5 or 6. Cell (x, y) points up when x+y is even, so the board's width
+	// and height must both be even for the pattern to line up where it wraps around.
+	Triangular
+)
+
+// geometryNames are the names used for each geometry on the command line.
+var geometryNames = map[Geometry]string{
+	Square:     "square",
+	Triangular: "triangular",
+}
+
+func (g Geometry) String() string {
+	if name, ok := geometryNames[g]; ok {
+		return name
+	}
+	return fmt.Sprintf("Geometry(%d)", int(g))
+}
+
+// ParseGeometry returns the geometry with the given name, e.g. "triangular".
+func ParseGeometry(name string) (Geometry, error) {
+	for g, n := range geometryNames {
+		if n == name {
+			return g, nil
+		}
+	}
+	return Square, fmt.Errorf("unknown geometry %q, expected square or triangular", name)
+}
+
+// PointsUp reports whether the triangle at (x, y) of a triangular board points up.
+func PointsUp(x, y int) bool {
+	return (x+y)%2 == 0
+}
+
+// triangleNeighbours are the offsets of the twelve neighbours of an up pointing triangle: three in the row above
+// (touching its apex), four in its own row and five in the row below (along its base). A down pointing
+// triangle's neighbours are the same with the rows swapped.
+var triangleNeighbours = [12][2]int{
+	{-1, -1}, {0, -1}, {1, -1},
+	{-2, 0}, {-1, 0}, {1, 0}, {2, 0},
+	{-2, 1}, {-1, 1}, {0, 1}, {1, 1}, {2, 1},
+}
+
+// triangularSum counts the alive neighbours of the triangle at (x, y), wrapping around the edges of the world.
+func triangularSum(world [][]byte, x, y, width, height int) int {
+	flip := 1
+	if !PointsUp(x, y) {
+		flip = -1
+	}
+	sum := 0
+	for _, offset := range triangleNeighbours {
+		row := world[(y+flip*offset[1]+height)%height]
+		sum += int(row[(x+offset[0]+width)%width])
+	}
+	return sum / int(util.Alive)
+}
+
+// nextAlive applies the geometry's rules to a cell with the given number of alive neighbours.
+func (g Geometry) nextAlive(alive bool, sum int) bool {
+	if g == Triangular {
+		if alive {
+			return sum >= 4 && sum <= 6
+		}
+		return sum == 4
+	}
+	if alive {
+		return sum == 2 || sum == 3
+	}
+	return sum == 3
+}
+
+// checkGeometry panics if the board can't be used with the geometry.
+func checkGeometry(p Params) {
+	if p.Geometry == Triangular && (p.ImageWidth%2 != 0 || p.ImageHeight%2 != 0) {
+		panic(fmt.Sprintf("A triangular board must have an even width and height, not %dx%d", p.ImageWidth, p.ImageHeight))
+	}
+}
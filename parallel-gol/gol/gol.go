@@ -2,14 +2,44 @@ package gol
 
 // Params provides the details of how to run the Game of Life and which image to load.
 type Params struct {
-	Turns       int
-	Threads     int
-	ImageWidth  int
-	ImageHeight int
+	Turns        int
+	Threads      int
+	ImageWidth   int
+	ImageHeight  int
+	Infinite     bool     // Run on an unbounded plane instead of a torus; the image size becomes the viewport size.
+	ViewX        int      // Plane x coordinate shown in the left column of the viewport in infinite mode.
+	ViewY        int      // Plane y coordinate shown in the top row of the viewport in infinite mode.
+	ShrinkAfter  int      // Turns a chunk of the infinite plane stays allocated once empty. Zero frees it straight away.
+	MaxAlive     int      // Most cells allowed alive on the infinite plane before the AtCap rule applies. Zero is no cap.
+	AtCap        CapRule  // What happens when the population passes MaxAlive: pause (the default) or cull.
+	Hooks        []Hook   // Functions run every N turns with read/write access to the board. Not in infinite mode.
+	StrictEvents bool     // Pass events through a sequencer that guarantees the order the test suite requires.
+	Geometry     Geometry // Shape of the cells: Square (the default) or Triangular. Not in infinite mode.
+	Threshold    float64  // Fraction of an input image's maxval at or above which a pixel is alive. Defaults to 0.5.
+	OutDir       string   // Directory output images are written to. Defaults to "out".
+	Scene        string   // Scene file the starting board is assembled from, instead of reading the input image.
+	Pattern      string   // RLE file, or HTTP(S) URL of one, placed in the middle of an empty board instead of the input image.
+	PatternCache string   // Directory downloaded patterns are kept in. Empty downloads them every run.
+	Autotune     bool     // Time a few warm-up generations at several thread counts and run with the fastest. Not in infinite mode.
+	Speculate    int      // Turns worked out ahead at once in just the changing regions of a nearly still board. Zero is off.
+	Arena        bool     // Write every generation into one of two reused buffers. TurnComplete then has no World. Not with Speculate.
+	HugePages    bool     // Ask the kernel to back the Arena with transparent huge pages. Linux only.
+	Interleave   bool     // Give worker k rows k, k+Threads, k+2*Threads... rather than a band, to share out a clustered board.
+	BitPacked    bool     // Store the world a bit per cell and count neighbours 64 cells at a time. Square cells only, and not with Speculate.
+	ExtentEvery  int      // Turns between PatternExtent events. Zero sends none.
+	Classify     int      // Longest period to look for still lifes, oscillators and spaceships with, sending PatternPeriod. Zero is off.
+	IdleAfter    int      // Turns without change (or, with Classify, as an oscillator) before idling. Zero is off. Not in infinite mode.
+	InitialBoard bool     // Send the starting board as one InitialBoard event, rather than a CellFlipped for each alive cell.
+
+	callbacks []turnCallback // Registered with OnTurn and OnTurnAsync.
 }
 
 // Run starts the processing of Game of Life. It should initialise channels and goroutines.
 func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
+	// Settings the engine can't honour are refused before anything starts, rather than quietly ignored.
+	if err := CheckFeatures(p); err != nil {
+		panic(err)
+	}
 
 	// TODO: Put the missing channels in here.
 
@@ -31,6 +61,13 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 
 	go startIo(p, ioChannels)
 
+	// In strict mode the distributor sends to the sequencer, which alone sends to and closes the events channel.
+	if p.StrictEvents {
+		sequenced := make(chan Event, cap(events))
+		go sequenceEvents(sequenced, events)
+		events = sequenced
+	}
+
 	distributorChannels := distributorChannels{
 		events:     events,
 		ioCommand:  ioCommand,
@@ -41,5 +78,9 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 		keyPresses: keyPresses,
 	}
 
+	if p.Infinite {
+		distributeInfinite(p, distributorChannels)
+		return
+	}
 	distributor(p, distributorChannels)
 }
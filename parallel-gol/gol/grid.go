@@ -0,0 +1,40 @@
+package gol
+
+import "uk.ac.bris.cs/gameoflife/util"
+
+// ReadOnlyGrid gives callbacks and event consumers read access to the world after a turn without letting them
+// change it, and without the world being copied to hand it to them.
+type ReadOnlyGrid interface {
+	Width() int
+	Height() int
+	// Alive reports whether the cell at (x, y) is alive. Coordinates wrap around the edges of the world.
+	Alive(x, y int) bool
+	// AliveCells returns the coordinates of every alive cell.
+	AliveCells() []util.Cell
+}
+
+// grid is the ReadOnlyGrid view of a world. Grids share the world's rows rather than copying them, which is safe
+// because a world is never written once it has been handed out: the workers write each generation into new rows,
+// and hooks change a generation before anything else sees it. A grid can therefore be kept as long as its holder
+// likes, on any goroutine, while the distributor carries on with later turns.
+type grid [][]byte
+
+func (g grid) Width() int {
+	if len(g) == 0 {
+		return 0
+	}
+	return len(g[0])
+}
+
+func (g grid) Height() int {
+	return len(g)
+}
+
+func (g grid) Alive(x, y int) bool {
+	width, height := g.Width(), g.Height()
+	return g[(y%height+height)%height][(x%width+width)%width] == util.Alive
+}
+
+func (g grid) AliveCells() []util.Cell {
+	return calculateAliveCells(g)
+}
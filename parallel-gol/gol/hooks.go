@@ -0,0 +1,94 @@
+package gol
+
+import (
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Hook is a user-supplied function that runs every Every turns with read/write access to the board.
+// Fn may change cells in place (using util.Alive and util.Dead); the distributor reports any cells
+// it changed as CellFlipped events so the live view stays in step with the world.
+// A hook that changes cells at random should draw from util.NewTurnRNG(seed, turn), so a seeded run
+// perturbs the same cells whatever the number of threads.
+type Hook struct {
+	Every int                            // Number of turns between calls. Hooks with Every <= 0 never run.
+	Fn    func(turn int, world [][]byte) // Called with the number of completed turns and the current world.
+}
+
+// hooksDue reports whether any hook is due after the given number of completed turns.
+func hooksDue(hooks []Hook, completed int) bool {
+	for _, hook := range hooks {
+		if hook.Every > 0 && completed%hook.Every == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runHooks calls every hook that is due after the given number of completed turns and returns the cells they changed.
+func runHooks(hooks []Hook, completed int, world [][]byte) []util.Cell {
+	var before [][]byte
+	for _, hook := range hooks {
+		if hook.Every <= 0 || completed%hook.Every != 0 {
+			continue
+		}
+		// Take a copy of the world the first time a hook is due, so changes can be found afterwards.
+		if before == nil {
+			before = make([][]byte, len(world))
+			for i := range world {
+				before[i] = append([]byte{}, world[i]...)
+			}
+		}
+		hook.Fn(completed, world)
+	}
+	if before == nil {
+		return nil
+	}
+
+	// Find every cell the hooks changed, so CellFlipped events can be sent for them.
+	var changed []util.Cell
+	for i := range world {
+		for j := range world[i] {
+			if world[i][j] != before[i][j] {
+				changed = append(changed, util.Cell{X: j, Y: i})
+			}
+		}
+	}
+	return changed
+}
+
+// GliderHook returns a hook that injects a south-east moving glider with its top-left corner at (x, y)
+// every n turns. Coordinates wrap around the edges of the world.
+func GliderHook(n, x, y int) Hook {
+	glider := []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	return stampHook(n, x, y, glider)
+}
+
+// StampHook returns a hook that stamps a pattern onto the board every n turns, described as a line of a scene
+// file is, e.g. "gosper at 10,10 rot90" or "patterns/pulsar.rle at 60,5 flipx". Coordinates wrap around the
+// edges of the world.
+func StampHook(n int, line string) (Hook, error) {
+	p, err := parsePlacement(line, ".")
+	if err != nil {
+		return Hook{}, err
+	}
+	if len(p.cells) == 0 {
+		return Hook{}, fmt.Errorf("%q stamps no cells", line)
+	}
+	return stampHook(n, p.x, p.y, p.cells), nil
+}
+
+// stampHook returns a hook that sets cells alive every n turns, offset so (0, 0) lands on (x, y).
+func stampHook(n, x, y int, cells []util.Cell) Hook {
+	return Hook{
+		Every: n,
+		Fn: func(turn int, world [][]byte) {
+			height := len(world)
+			for _, cell := range cells {
+				row := world[((y+cell.Y)%height+height)%height]
+				row[((x+cell.X)%len(row)+len(row))%len(row)] = util.Alive
+			}
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package gol
+
+import (
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// idleTurnInterval is how long an idle distributor waits for a key press before going on with the next turn.
+const idleTurnInterval = 250 * time.Millisecond
+
+// idler notices when the board has stopped changing, so that rather than working out the same board as fast as it
+// can, the distributor slows down to a turn every idleTurnInterval until something changes or a key is pressed.
+type idler struct {
+	after  int  // Turns the board must be steady for before idling. Zero never idles.
+	steady int  // Turns the board has been steady for.
+	idle   bool // Whether the distributor is idling.
+}
+
+// observe records whether the board was steady on the turn just completed: no cells flipped, or it is a still life
+// or oscillator. It returns the state to report if the distributor has just started or stopped idling.
+func (d *idler) observe(steady bool) (State, bool) {
+	if !steady {
+		d.steady = 0
+		return d.set(false)
+	}
+	d.steady++
+	return d.set(d.after > 0 && d.steady >= d.after)
+}
+
+// wake stops idling, e.g. because a key was pressed, and starts counting steady turns again. It returns the state
+// to report if the distributor was idling.
+func (d *idler) wake() (State, bool) {
+	d.steady = 0
+	return d.set(false)
+}
+
+// set starts or stops idling, returning the state to report if that changed anything.
+func (d *idler) set(idle bool) (State, bool) {
+	if idle == d.idle {
+		return 0, false
+	}
+	d.idle = idle
+	if idle {
+		return Idle, true
+	}
+	return Executing, true
+}
+
+// wait returns how long to wait for input after a turn: idleTurnInterval when idling, and none otherwise.
+func (d *idler) wait() time.Duration {
+	if d.idle {
+		return idleTurnInterval
+	}
+	return 0
+}
+
+// nextInput returns the next key press, or whether the ticker ticked, waiting for up to wait for one of them. With
+// no wait it returns straight away, with a key press of 0 and no tick if neither was ready.
+func nextInput(keyPresses <-chan rune, ticks <-chan time.Time, wait time.Duration) (rune, bool) {
+	if wait <= 0 {
+		select {
+		case command := <-keyPresses:
+			return command, false
+		case <-ticks:
+			return 0, true
+		default:
+			return 0, false
+		}
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case command := <-keyPresses:
+		return command, false
+	case <-ticks:
+		return 0, true
+	case <-timer.C:
+		return 0, false
+	}
+}
+
+// countFlipped returns how many cells flipped in a turn.
+func countFlipped(flipped [][]util.Cell) int {
+	count := 0
+	for _, cells := range flipped {
+		count += len(cells)
+	}
+	return count
+}
@@ -0,0 +1,65 @@
+package gol
+
+import (
+	"testing"
+	"time"
+)
+
+// TestIdler checks idling starts once the board has been steady for long enough, and stops when it changes or
+// is woken, reporting each change of state once.
+func TestIdler(t *testing.T) {
+	d := &idler{after: 2}
+	if _, ok := d.observe(true); ok || d.wait() != 0 {
+		t.Fatal("idled after one steady turn")
+	}
+	if state, ok := d.observe(true); !ok || state != Idle || d.wait() != idleTurnInterval {
+		t.Fatalf("got %v, %v after two steady turns, expected to start idling", state, ok)
+	}
+	if _, ok := d.observe(true); ok {
+		t.Error("reported idling again")
+	}
+	if state, ok := d.observe(false); !ok || state != Executing || d.wait() != 0 {
+		t.Errorf("got %v, %v once the board changed, expected to stop idling", state, ok)
+	}
+
+	d.observe(true)
+	d.observe(true)
+	if state, ok := d.wake(); !ok || state != Executing {
+		t.Errorf("got %v, %v on waking, expected to stop idling", state, ok)
+	}
+	if _, ok := d.wake(); ok {
+		t.Error("reported waking when already awake")
+	}
+	if _, ok := d.observe(true); ok {
+		t.Error("idled straight after waking, expected the steady turns to be counted again")
+	}
+
+	never := &idler{}
+	for i := 0; i < 10; i++ {
+		if _, ok := never.observe(true); ok {
+			t.Fatal("idled with idling off")
+		}
+	}
+}
+
+// TestNextInput checks input is returned straight away when there is no wait, and otherwise waited for.
+func TestNextInput(t *testing.T) {
+	keyPresses := make(chan rune)
+	ticks := make(chan time.Time)
+	if command, tick := nextInput(keyPresses, ticks, 0); command != 0 || tick {
+		t.Fatalf("got %q, %v with no input, expected nothing", command, tick)
+	}
+
+	start := time.Now()
+	if command, tick := nextInput(keyPresses, ticks, 20*time.Millisecond); command != 0 || tick {
+		t.Fatalf("got %q, %v with no input, expected nothing", command, tick)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("returned after %v, expected to wait", elapsed)
+	}
+
+	go func() { keyPresses <- 'p' }()
+	if command, _ := nextInput(keyPresses, ticks, time.Minute); command != 'p' {
+		t.Errorf("got %q, expected the key press to end the wait", command)
+	}
+}
@@ -0,0 +1,387 @@
+package gol
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Key presses that move the viewport over the plane in infinite mode, each by ViewStep cells. The plane has no
+// edges, so the viewport can be moved as far as the pattern travels.
+const (
+	ViewUp    = '↑'
+	ViewDown  = '↓'
+	ViewLeft  = '←'
+	ViewRight = '→'
+)
+
+// ViewStep is how many cells the viewport moves for each key press.
+const ViewStep = 8
+
+// viewMoves maps the viewport key presses to how far they move the viewport.
+var viewMoves = map[rune]util.Cell{
+	ViewUp:    {X: 0, Y: -ViewStep},
+	ViewDown:  {X: 0, Y: ViewStep},
+	ViewLeft:  {X: -ViewStep, Y: 0},
+	ViewRight: {X: ViewStep, Y: 0},
+}
+
+// chunkSize is the side length of the square chunks the infinite plane is divided into.
+const chunkSize = 64
+
+// chunkCoord identifies a chunk by its position on the plane, measured in chunks.
+type chunkCoord struct {
+	X, Y int
+}
+
+// chunk holds the cells of one chunkSize x chunkSize block of the plane.
+type chunk [chunkSize][chunkSize]byte
+
+// plane is an unbounded world made of chunks. A chunk is only allocated once a live cell appears in it.
+type plane map[chunkCoord]*chunk
+
+// chunkResult is the next state of one chunk together with the cells that flipped in it.
+type chunkResult struct {
+	coord   chunkCoord
+	next    *chunk
+	flipped []util.Cell
+}
+
+// floorDiv divides rounding towards negative infinity, so cell -1 belongs to chunk -1 rather than chunk 0.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// locate returns the chunk containing the cell and the cell's position inside that chunk.
+func locate(x, y int) (chunkCoord, int, int) {
+	cc := chunkCoord{floorDiv(x, chunkSize), floorDiv(y, chunkSize)}
+	return cc, x - cc.X*chunkSize, y - cc.Y*chunkSize
+}
+
+// get returns the state of the cell at plane coordinates (x, y).
+func (pl plane) get(x, y int) byte {
+	cc, lx, ly := locate(x, y)
+	if ch, ok := pl[cc]; ok {
+		return ch[ly][lx]
+	}
+	return 0
+}
+
+// set changes the cell at plane coordinates (x, y), allocating its chunk if needed.
+func (pl plane) set(x, y int, value byte) {
+	cc, lx, ly := locate(x, y)
+	ch, ok := pl[cc]
+	if !ok {
+		ch = &chunk{}
+		pl[cc] = ch
+	}
+	ch[ly][lx] = value
+}
+
+// aliveCells returns the plane coordinates of every live cell.
+func (pl plane) aliveCells() []util.Cell {
+	aliveCells := []util.Cell{}
+	for cc, ch := range pl {
+		for ly := range ch {
+			for lx := range ch[ly] {
+				if ch[ly][lx] == util.Alive {
+					aliveCells = append(aliveCells, util.Cell{X: cc.X*chunkSize + lx, Y: cc.Y*chunkSize + ly})
+				}
+			}
+		}
+	}
+	return aliveCells
+}
+
+// candidates returns every chunk whose cells could be alive next turn: the allocated chunks and their neighbours.
+func (pl plane) candidates() []chunkCoord {
+	seen := make(map[chunkCoord]bool)
+	var coords []chunkCoord
+	for cc := range pl {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				n := chunkCoord{cc.X + dx, cc.Y + dy}
+				if !seen[n] {
+					seen[n] = true
+					coords = append(coords, n)
+				}
+			}
+		}
+	}
+	return coords
+}
+
+// nextChunk computes the next state of the chunk at cc. It returns a nil chunk if no cell in it will be alive.
+func (pl plane) nextChunk(cc chunkCoord) chunkResult {
+	// Look up the 3x3 block of chunks around cc once, rather than once per cell.
+	var around [3][3]*chunk
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			around[dy+1][dx+1] = pl[chunkCoord{cc.X + dx, cc.Y + dy}]
+		}
+	}
+	// cell reads a cell relative to the chunk, where -1 and chunkSize reach into the neighbouring chunks.
+	cell := func(lx, ly int) int {
+		ch := around[(ly+chunkSize)/chunkSize][(lx+chunkSize)/chunkSize]
+		if ch == nil {
+			return 0
+		}
+		return int(ch[(ly+chunkSize)%chunkSize][(lx+chunkSize)%chunkSize])
+	}
+
+	result := chunkResult{coord: cc}
+	var next chunk
+	alive := false
+	for ly := 0; ly < chunkSize; ly++ {
+		for lx := 0; lx < chunkSize; lx++ {
+			sum := (cell(lx-1, ly-1) + cell(lx, ly-1) + cell(lx+1, ly-1) +
+				cell(lx-1, ly) + cell(lx+1, ly) +
+				cell(lx-1, ly+1) + cell(lx, ly+1) + cell(lx+1, ly+1)) / int(util.Alive)
+			current := cell(lx, ly)
+			if sum == 3 || (sum == 2 && current == int(util.Alive)) {
+				next[ly][lx] = util.Alive
+				alive = true
+			}
+			if int(next[ly][lx]) != current {
+				result.flipped = append(result.flipped, util.Cell{X: cc.X*chunkSize + lx, Y: cc.Y*chunkSize + ly})
+			}
+		}
+	}
+	if alive {
+		result.next = &next
+	}
+	return result
+}
+
+// step advances the plane by one turn using the given number of worker goroutines.
+// It returns the new plane and every cell that changed state.
+func (pl plane) step(threads int) (plane, []util.Cell) {
+	coords := pl.candidates()
+	results := make([]chunkResult, len(coords))
+
+	// Each worker takes every threads-th candidate chunk. Workers only read the old plane,
+	// and the new plane is built afterwards, so no map is written concurrently.
+	var wg sync.WaitGroup
+	for id := 0; id < threads; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for i := id; i < len(coords); i += threads {
+				results[i] = pl.nextChunk(coords[i])
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	next := make(plane)
+	var flipped []util.Cell
+	for _, result := range results {
+		if result.next != nil {
+			next[result.coord] = result.next
+		}
+		flipped = append(flipped, result.flipped...)
+	}
+	return next, flipped
+}
+
+// retainEmpty keeps the chunks of prev that have emptied allocated in pl, cleared, until they have been empty for
+// after turns, so a pattern oscillating across a chunk edge doesn't free and reallocate the chunk every turn. Once
+// a chunk has been empty that long it is dropped, so the region computed shrinks as the population contracts and
+// memory follows the live pattern rather than the furthest it ever reached. idle holds how many turns each kept
+// chunk has been empty; the counts for the next turn come back in a new map, as a map never gives back the memory
+// of deleted entries.
+func (pl plane) retainEmpty(prev plane, idle map[chunkCoord]int, after int) map[chunkCoord]int {
+	nextIdle := make(map[chunkCoord]int)
+	for cc, ch := range prev {
+		if _, alive := pl[cc]; alive || idle[cc] >= after {
+			continue
+		}
+		// Nothing reads the previous plane after a step, so its chunk can be cleared and kept.
+		*ch = chunk{}
+		pl[cc] = ch
+		nextIdle[cc] = idle[cc] + 1
+	}
+	return nextIdle
+}
+
+// inView reports whether a plane cell is inside the viewport, and where it appears in the window.
+func inView(p Params, cell util.Cell) (util.Cell, bool) {
+	x, y := cell.X-p.ViewX, cell.Y-p.ViewY
+	return util.Cell{X: x, Y: y}, x >= 0 && y >= 0 && x < p.ImageWidth && y < p.ImageHeight
+}
+
+// viewport copies the part of the plane under the viewport into a world the size of the image.
+func (pl plane) viewport(p Params) [][]byte {
+	world := make([][]byte, p.ImageHeight)
+	for y := range world {
+		world[y] = make([]byte, p.ImageWidth)
+		for x := range world[y] {
+			world[y][x] = pl.get(p.ViewX+x, p.ViewY+y)
+		}
+	}
+	return world
+}
+
+// viewChanges returns the cells that differ between two viewports of the same size.
+func viewChanges(before, after [][]byte) []util.Cell {
+	var changed []util.Cell
+	for y := range before {
+		for x := range before[y] {
+			if before[y][x] != after[y][x] {
+				changed = append(changed, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return changed
+}
+
+// moveView handles a viewport key press, moving the viewport and queuing CellFlipped events for every cell
+// that looks different from its new position. It reports whether command was a viewport key press.
+func (pl plane) moveView(p *Params, out *emitter, turn int, command rune) bool {
+	move, ok := viewMoves[command]
+	if !ok {
+		return false
+	}
+	before := pl.viewport(*p)
+	p.ViewX += move.X
+	p.ViewY += move.Y
+	out.sendTurn(turn, [][]util.Cell{viewChanges(before, pl.viewport(*p))})
+	return true
+}
+
+// inViewCells returns the cells inside the viewport, in viewport coordinates.
+func inViewCells(p Params, cells []util.Cell) []util.Cell {
+	var view []util.Cell
+	for _, cell := range cells {
+		if viewCell, ok := inView(p, cell); ok {
+			view = append(view, viewCell)
+		}
+	}
+	return view
+}
+
+// distributeInfinite runs the Game of Life on an unbounded plane. The input image is placed with its
+// top-left corner at the plane origin, and the viewport (the size of the image) is what gets rendered and saved.
+func distributeInfinite(p Params, c distributorChannels) {
+	out := newEmitter(c.events)
+
+	c.ioCommand <- ioInput
+	c.ioFilename <- fmt.Sprintf("%d%s%d", p.ImageWidth, "x", p.ImageHeight)
+
+	// Read the initial image into the plane.
+	pl := make(plane)
+	for y := 0; y < p.ImageHeight; y++ {
+		for x := 0; x < p.ImageWidth; x++ {
+			if <-c.ioInput == util.Alive {
+				pl.set(x, y, util.Alive)
+			}
+		}
+	}
+
+	// Send the initially alive cells inside the viewport.
+	out.sendInitial(p, inViewCells(p, pl.aliveCells()))
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	turn := 0
+	stepping := false
+	idle := make(map[chunkCoord]int)
+	overCap := false // Whether the population has passed the cap and not come back under it since.
+	var shapes *classifier
+	if p.Classify > 0 {
+		shapes = newClassifier(p.Classify, 0, 0)
+	}
+	// The viewport can still be moved while paused, and the window redrawn to show it.
+	panWhilePaused := func(command rune) {
+		if pl.moveView(&p, out, turn, command) {
+			out.send(TurnComplete{CompletedTurns: turn})
+		}
+	}
+
+	for quit := false; turn < p.Turns && !quit; turn++ {
+		var flipped []util.Cell
+		prev := pl
+		pl, flipped = pl.step(p.Threads)
+		idle = pl.retainEmpty(prev, idle, p.ShrinkAfter)
+
+		// Keep the population under the cap, if there is one, by culling or by pausing once it is passed.
+		var culled []util.Cell
+		var capEvents []Event
+		pauseAtCap, pausing := false, false
+		if p.MaxAlive > 0 {
+			alive := pl.population()
+			switch {
+			case alive <= p.MaxAlive:
+				overCap = false
+			case p.AtCap == CullAtCap:
+				culled = pl.cull(p, p.MaxAlive)
+				capEvents = append(capEvents, PopulationCapped{CompletedTurns: turn + 1, Alive: alive, Cap: p.MaxAlive, Culled: len(culled)})
+			case !overCap:
+				overCap, pauseAtCap = true, true
+				capEvents = append(capEvents, PopulationCapped{CompletedTurns: turn + 1, Alive: alive, Cap: p.MaxAlive})
+			}
+		}
+
+		// Only cells inside the viewport are rendered. Culled cells are flipped back after being computed.
+		out.sendTurn(turn, [][]util.Cell{inViewCells(p, flipped), inViewCells(p, culled)}, capEvents...)
+		if p.ExtentEvery > 0 && (turn+1)%p.ExtentEvery == 0 {
+			out.send(measureExtent(turn+1, pl.aliveCells(), 0, 0))
+		}
+		if shapes != nil {
+			if period, ok := shapes.observe(turn+1, pl.aliveCells()); ok {
+				out.send(period)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+			out.send(AliveCellsCount{CompletedTurns: turn + 1, CellsCount: len(pl.aliveCells())})
+		case command := <-c.keyPresses:
+			switch command {
+			case 's':
+				out.send(StateChange{CompletedTurns: turn, NewState: Executing})
+				savePGMImage(c, pl.viewport(p), p)
+			case 'q':
+				out.send(StateChange{CompletedTurns: turn, NewState: Quitting})
+				savePGMImage(c, pl.viewport(p), p)
+				quit = true
+			case 'p':
+				pausing = true // Once the turn is complete, as in the distributor.
+			default:
+				pl.moveView(&p, out, turn, command)
+			}
+		default:
+		}
+
+		out.send(TurnComplete{CompletedTurns: turn})
+
+		if pausing {
+			out.send(StateChange{CompletedTurns: turn, NewState: Paused})
+			fmt.Printf("Current turn %d being processed\n", turn)
+			stepping = waitForResume(c, out, turn, panWhilePaused)
+		} else if pauseAtCap {
+			out.send(StateChange{CompletedTurns: turn, NewState: Paused})
+			fmt.Printf("Paused at turn %d with the population over the cap\n", turn)
+			stepping = waitForResume(c, out, turn, panWhilePaused)
+		} else if stepping {
+			stepping = waitForResume(c, out, turn, panWhilePaused)
+		}
+	}
+
+	// Report every live cell on the plane, including those outside the viewport.
+	out.send(FinalTurnComplete{CompletedTurns: turn, Alive: pl.aliveCells()})
+	savePGMImage(c, pl.viewport(p), p)
+
+	c.ioCommand <- ioCheckIdle
+	<-c.ioIdle
+
+	out.send(StateChange{CompletedTurns: turn, NewState: Quitting})
+	out.close()
+	close(c.events)
+}
@@ -0,0 +1,181 @@
+package gol
+
+import (
+	"sort"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// glider moves one cell right and one cell down every 4 turns.
+var glider = []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+
+// referenceStep advances a set of live cells by one turn of standard Life on an unbounded plane.
+func referenceStep(alive map[util.Cell]bool) map[util.Cell]bool {
+	counts := make(map[util.Cell]int)
+	for cell := range alive {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx != 0 || dy != 0 {
+					counts[util.Cell{X: cell.X + dx, Y: cell.Y + dy}]++
+				}
+			}
+		}
+	}
+	next := make(map[util.Cell]bool)
+	for cell, count := range counts {
+		if count == 3 || count == 2 && alive[cell] {
+			next[cell] = true
+		}
+	}
+	return next
+}
+
+// translate returns the cells moved by (dx, dy).
+func translate(cells []util.Cell, dx, dy int) []util.Cell {
+	moved := make([]util.Cell, len(cells))
+	for i, cell := range cells {
+		moved[i] = util.Cell{X: cell.X + dx, Y: cell.Y + dy}
+	}
+	return moved
+}
+
+// sortedCells sorts cells by row then column, so boards can be compared.
+func sortedCells(cells []util.Cell) []util.Cell {
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Y != cells[j].Y {
+			return cells[i].Y < cells[j].Y
+		}
+		return cells[i].X < cells[j].X
+	})
+	return cells
+}
+
+// assertCells fails the test unless got and expected hold the same cells.
+func assertCells(t *testing.T, turn int, got, expected []util.Cell) {
+	t.Helper()
+	got, expected = sortedCells(got), sortedCells(expected)
+	if len(got) != len(expected) {
+		t.Fatalf("turn %d: %d cells alive, expected %d: %v", turn, len(got), len(expected), got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("turn %d: alive cells %v, expected %v", turn, got, expected)
+		}
+	}
+}
+
+// TestPlane compares the chunked plane against a reference for patterns placed across chunk boundaries,
+// at negative coordinates, and travelling well beyond the chunks they started in.
+func TestPlane(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells []util.Cell
+		turns int
+	}{
+		// A blinker lying across the vertical boundary between chunks 0 and 1.
+		{"blinker across chunk edge", []util.Cell{{X: 63, Y: 10}, {X: 64, Y: 10}, {X: 65, Y: 10}}, 4},
+		// A block on the corner shared by four chunks, around the origin.
+		{"block on chunk corner", []util.Cell{{X: -1, Y: -1}, {X: 0, Y: -1}, {X: -1, Y: 0}, {X: 0, Y: 0}}, 4},
+		// An R-pentomino at negative coordinates, which spreads over several chunks.
+		{"r-pentomino at negative coordinates",
+			translate([]util.Cell{{X: 1, Y: 0}, {X: 2, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}}, -100, -70), 200},
+		// A glider crossing from chunk (-1, -1) into chunk (0, 0).
+		{"glider into positive chunks", translate(glider, -8, -8), 64},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pl := make(plane)
+			reference := make(map[util.Cell]bool)
+			for _, cell := range test.cells {
+				pl.set(cell.X, cell.Y, util.Alive)
+				reference[cell] = true
+			}
+			for turn := 1; turn <= test.turns; turn++ {
+				pl, _ = pl.step(4)
+				reference = referenceStep(reference)
+				var expected []util.Cell
+				for cell := range reference {
+					expected = append(expected, cell)
+				}
+				assertCells(t, turn, pl.aliveCells(), expected)
+			}
+		})
+	}
+}
+
+// TestGliderTravelsPastChunks runs a glider far beyond the chunk it started in, in both directions, and checks
+// it arrives intact and the chunks it left behind are freed.
+func TestGliderTravelsPastChunks(t *testing.T) {
+	// Turning the glider around makes it travel up and to the left instead.
+	backwards := make([]util.Cell, len(glider))
+	for i, cell := range glider {
+		backwards[i] = util.Cell{X: 2 - cell.X, Y: 2 - cell.Y}
+	}
+	const turns = 4 * 100 // 100 cells, well over a chunk.
+	for _, test := range []struct {
+		name   string
+		cells  []util.Cell
+		offset int
+	}{
+		{"down and right", glider, 100},
+		{"up and left", backwards, -100},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			pl := make(plane)
+			for _, cell := range test.cells {
+				pl.set(cell.X, cell.Y, util.Alive)
+			}
+			for turn := 0; turn < turns; turn++ {
+				pl, _ = pl.step(2)
+			}
+			assertCells(t, turns, pl.aliveCells(), translate(test.cells, test.offset, test.offset))
+			if len(pl) > 4 {
+				t.Errorf("%d chunks are allocated for one glider", len(pl))
+			}
+		})
+	}
+}
+
+// TestMoveView checks moving the viewport redraws exactly the cells that look different from its new position.
+func TestMoveView(t *testing.T) {
+	p := Params{ImageWidth: 16, ImageHeight: 16, ViewX: -4, ViewY: -4}
+	pl := make(plane)
+	for _, cell := range translate(glider, -62, -2) {
+		pl.set(cell.X, cell.Y, util.Alive)
+	}
+
+	events := make(chan Event, 100)
+	out := newEmitter(events)
+	// Eight moves left bring the glider, 60 cells to the left of the origin, into view.
+	board := make(map[util.Cell]bool)
+	for i := 0; i < 8; i++ {
+		if !pl.moveView(&p, out, 0, ViewLeft) {
+			t.Fatal("ViewLeft wasn't treated as a viewport key press")
+		}
+	}
+	if pl.moveView(&p, out, 0, 'x') {
+		t.Error("'x' was treated as a viewport key press")
+	}
+	out.close()
+	close(events)
+	for event := range events {
+		if flipped, ok := event.(CellFlipped); ok {
+			board[flipped.Cell] = !board[flipped.Cell]
+		}
+	}
+
+	if p.ViewX != -4-8*ViewStep || p.ViewY != -4 {
+		t.Fatalf("viewport is at (%d, %d), expected (%d, -4)", p.ViewX, p.ViewY, -4-8*ViewStep)
+	}
+	var shown []util.Cell
+	for cell, alive := range board {
+		if alive {
+			shown = append(shown, cell)
+		}
+	}
+	assertCells(t, 0, shown, inViewCells(p, pl.aliveCells()))
+	if len(shown) != len(glider) {
+		t.Errorf("%d cells shown, expected the whole glider", len(shown))
+	}
+}
@@ -0,0 +1,66 @@
+package gol
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// clusteredWorld returns a 512x512 world whose live cells are all in the top eighth, as when a pattern grows from
+// one corner of the board.
+func clusteredWorld() [][]byte {
+	world := newRows(512, 512)
+	r := rand.New(rand.NewSource(1))
+	for y := 0; y < 64; y++ {
+		for x := range world[y] {
+			if r.Intn(3) == 0 {
+				world[y][x] = util.Alive
+			}
+		}
+	}
+	return world
+}
+
+// TestInterleaveNextWorld checks interleaved rows make the same next world as bands, for any number of threads.
+func TestInterleaveNextWorld(t *testing.T) {
+	world := clusteredWorld()
+	for _, threads := range []int{1, 3, 7, 512} {
+		p := Params{Threads: threads, ImageWidth: 512, ImageHeight: 512}
+		results := make([]chan sliceResult, threads)
+		for i := range results {
+			results[i] = make(chan sliceResult)
+		}
+		expected := nextWorld(p, world, results)
+		p.Interleave = true
+		if err := compareWorlds(nextWorld(p, world, results), expected); err != nil {
+			t.Errorf("%d threads: %v", threads, err)
+		}
+	}
+}
+
+// BenchmarkDecomposition compares bands with interleaved rows on a board whose live cells are clustered in one
+// band. Counting neighbours costs the same wherever the cells are, but finding flipped cells, and appending them,
+// only happens where they change, which bands leave to one thread.
+func BenchmarkDecomposition(b *testing.B) {
+	world := clusteredWorld()
+	for _, interleave := range []bool{false, true} {
+		name := "bands"
+		if interleave {
+			name = "interleaved"
+		}
+		for _, threads := range []int{2, 4, 8} {
+			b.Run(fmt.Sprintf("%s/%d_threads", name, threads), func(b *testing.B) {
+				p := Params{Threads: threads, ImageWidth: 512, ImageHeight: 512, Interleave: interleave}
+				results := make([]chan sliceResult, threads)
+				for i := range results {
+					results[i] = make(chan sliceResult)
+				}
+				for i := 0; i < b.N; i++ {
+					nextWorld(p, world, results)
+				}
+			})
+		}
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
-	"strings"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
@@ -38,14 +38,22 @@ const (
 	ioCheckIdle
 )
 
+// outDir returns the directory output images are written to.
+func (io *ioState) outDir() string {
+	if io.params.OutDir == "" {
+		return "out"
+	}
+	return io.params.OutDir
+}
+
 // writePgmImage receives an array of bytes and writes it to a pgm file.
 func (io *ioState) writePgmImage() {
-	_ = os.Mkdir("out", os.ModePerm)
+	_ = os.MkdirAll(io.outDir(), os.ModePerm)
 
 	// Request a filename from the distributor.
 	filename := <-io.channels.filename
 
-	file, ioError := os.Create("out/" + filename + ".pgm")
+	file, ioError := os.Create(filepath.Join(io.outDir(), filename+".pgm"))
 	util.Check(ioError)
 	defer file.Close()
 
@@ -55,7 +63,7 @@ func (io *ioState) writePgmImage() {
 	_, _ = file.WriteString(" ")
 	_, _ = file.WriteString(strconv.Itoa(io.params.ImageHeight))
 	_, _ = file.WriteString("\n")
-	_, _ = file.WriteString(strconv.Itoa(255))
+	_, _ = file.WriteString(strconv.Itoa(int(util.Alive)))
 	_, _ = file.WriteString("\n")
 
 	world := make([][]byte, io.params.ImageHeight)
@@ -92,32 +100,47 @@ func (io *ioState) readPgmImage() {
 	// Request a filename from the distributor.
 	filename := <-io.channels.filename
 
+	// A scene places patterns on an empty board of the image's size, so there is no image to read.
+	if io.params.Scene != "" {
+		image, err := loadScene(io.params.Scene, io.params.ImageWidth, io.params.ImageHeight)
+		if err != nil {
+			panic(fmt.Sprintf("scene %v", err))
+		}
+		for _, b := range image {
+			io.channels.input <- b
+		}
+		fmt.Println("Scene", io.params.Scene, "input done!")
+		return
+	}
+
+	// A pattern, possibly downloaded, is placed in the middle of an empty board of the image's size.
+	if io.params.Pattern != "" {
+		image, err := loadPattern(io.params.Pattern, io.params.PatternCache, io.params.ImageWidth, io.params.ImageHeight)
+		if err != nil {
+			panic(fmt.Sprintf("pattern %v", err))
+		}
+		for _, b := range image {
+			io.channels.input <- b
+		}
+		fmt.Println("Pattern", io.params.Pattern, "input done!")
+		return
+	}
+
 	data, ioError := ioutil.ReadFile("images/" + filename + ".pgm")
 	util.Check(ioError)
 
-	fields := strings.Fields(string(data))
-
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
+	// Normalise the pixels to alive and dead cells, whatever the image's maxval.
+	width, height, image, err := util.ParsePgm(data, io.params.Threshold)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %v", filename, err))
 	}
-
-	width, _ := strconv.Atoi(fields[1])
 	if width != io.params.ImageWidth {
 		panic("Incorrect width")
 	}
-
-	height, _ := strconv.Atoi(fields[2])
 	if height != io.params.ImageHeight {
 		panic("Incorrect height")
 	}
 
-	maxval, _ := strconv.Atoi(fields[3])
-	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
-	}
-
-	image := []byte(fields[4])
-
 	for _, b := range image {
 		io.channels.input <- b
 	}
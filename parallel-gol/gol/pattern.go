@@ -0,0 +1,140 @@
+package gol
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// patternTimeout is how long downloading a pattern may take before the run gives up on it.
+const patternTimeout = 30 * time.Second
+
+// patternMaxSize is the largest pattern file downloaded. The biggest patterns in the usual catalogues are a few
+// megabytes of RLE.
+const patternMaxSize = 16 << 20
+
+// patternRule matches the rule in an RLE header, e.g. "x = 3, y = 3, rule = B3/S23".
+var patternRule = regexp.MustCompile(`(?m)^x\s*=.*rule\s*=\s*([^\s,]+)`)
+
+// isURL reports whether a pattern source is a web address rather than a file.
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// loadPattern places the pattern in an RLE file, or at an HTTP(S) URL, in the middle of an empty board of the
+// given size. Downloaded patterns are kept in cacheDir, if it isn't empty, so the next run doesn't need the network.
+func loadPattern(source, cacheDir string, width, height int) ([]byte, error) {
+	var data []byte
+	var err error
+	if isURL(source) {
+		data, err = fetchPattern(source, cacheDir)
+	} else {
+		data, err = ioutil.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cells, err := util.ParseRLE(data)
+	if err != nil {
+		return nil, err
+	}
+	if match := patternRule.FindSubmatch(data); match != nil && !strings.EqualFold(string(match[1]), "B3/S23") {
+		fmt.Printf("Warning: %s is for rule %s, but runs under Conway's rules (B3/S23)\n", source, match[1])
+	}
+
+	// Centre the pattern, which has to fit without wrapping around onto itself.
+	cells = util.Normalise(cells)
+	patternWidth, patternHeight := 0, 0
+	for _, cell := range cells {
+		if cell.X >= patternWidth {
+			patternWidth = cell.X + 1
+		}
+		if cell.Y >= patternHeight {
+			patternHeight = cell.Y + 1
+		}
+	}
+	if patternWidth > width || patternHeight > height {
+		return nil, fmt.Errorf("pattern is %dx%d, larger than the %dx%d board", patternWidth, patternHeight, width, height)
+	}
+	left, top := (width-patternWidth)/2, (height-patternHeight)/2
+	board := make([]byte, width*height)
+	for _, cell := range cells {
+		board[(top+cell.Y)*width+left+cell.X] = util.Alive
+	}
+	return board, nil
+}
+
+// fetchPattern returns the pattern file at url, from cacheDir if it was downloaded before. A download is only
+// cached once it parses, so a page of HTML served in place of the pattern isn't kept.
+func fetchPattern(url, cacheDir string) ([]byte, error) {
+	cached := ""
+	if cacheDir != "" {
+		cached = filepath.Join(cacheDir, patternCacheName(url))
+		if data, err := ioutil.ReadFile(cached); err == nil {
+			return data, nil
+		}
+	}
+
+	client := http.Client{Timeout: patternTimeout}
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, response.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, patternMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %v", url, err)
+	}
+	if len(data) > patternMaxSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, patternMaxSize)
+	}
+	if _, err := util.ParseRLE(data); err != nil {
+		return nil, fmt.Errorf("%s isn't an rle pattern: %v", url, err)
+	}
+
+	if cached != "" {
+		if err := cachePattern(cached, data); err != nil {
+			fmt.Printf("Warning: couldn't cache %s: %v\n", url, err)
+		}
+	}
+	return data, nil
+}
+
+// patternCacheName names the cached copy of the pattern at url after the file it downloads, with a hash of the
+// whole URL so patterns of the same name from different places don't collide.
+func patternCacheName(url string) string {
+	sum := sha1.Sum([]byte(url))
+	name := strings.TrimSuffix(path.Base(strings.SplitN(url, "?", 2)[0]), ".rle")
+	if name == "" || name == "." || name == "/" {
+		name = "pattern"
+	}
+	return fmt.Sprintf("%s-%s.rle", name, hex.EncodeToString(sum[:6]))
+}
+
+// cachePattern writes a downloaded pattern to the cache, through a temporary file so a run interrupted part way
+// doesn't leave half a pattern to be read next time.
+func cachePattern(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+	temp := filename + ".tmp"
+	if err := ioutil.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, filename)
+}
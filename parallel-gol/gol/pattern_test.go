@@ -0,0 +1,117 @@
+package gol
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// gliderRLE is a glider as the LifeWiki serves it.
+const gliderRLE = "#N Glider\n#O Richard K. Guy\nx = 3, y = 3, rule = B3/S23\nbob$2bo$3o!\n"
+
+// aliveOn returns the alive cells of a board laid out a row at a time.
+func aliveOn(board []byte, width int) []util.Cell {
+	var cells []util.Cell
+	for i, cell := range board {
+		if cell == util.Alive {
+			cells = append(cells, util.Cell{X: i % width, Y: i / width})
+		}
+	}
+	return cells
+}
+
+// TestLoadPatternURL checks a pattern is downloaded, centred on the board and cached, so a second run reads it
+// without the server.
+func TestLoadPatternURL(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write([]byte(gliderRLE))
+	}))
+	cache, err := ioutil.TempDir("", "patterns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+
+	expected := []util.Cell{{X: 4, Y: 3}, {X: 5, Y: 4}, {X: 3, Y: 5}, {X: 4, Y: 5}, {X: 5, Y: 5}}
+	url := server.URL + "/patterns/glider.rle"
+	board, err := loadPattern(url, cache, 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cells := aliveOn(board, 10); !reflect.DeepEqual(cells, expected) {
+		t.Errorf("got cells %v, expected %v", cells, expected)
+	}
+
+	server.Close()
+	board, err = loadPattern(url, cache, 10, 10)
+	if err != nil {
+		t.Fatalf("loading from the cache: %v", err)
+	}
+	if cells := aliveOn(board, 10); !reflect.DeepEqual(cells, expected) {
+		t.Errorf("got cells %v from the cache, expected %v", cells, expected)
+	}
+	if downloads != 1 {
+		t.Errorf("downloaded the pattern %d times, expected once", downloads)
+	}
+}
+
+// TestLoadPatternErrors checks a missing page, a page that isn't a pattern and a pattern too big for the board are
+// reported, and that nothing but a pattern is cached.
+func TestLoadPatternErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing.rle":
+			http.NotFound(w, r)
+		case "/page.rle":
+			w.Write([]byte("<html>Not a pattern</html>"))
+		default:
+			w.Write([]byte(gliderRLE))
+		}
+	}))
+	defer server.Close()
+	cache, err := ioutil.TempDir("", "patterns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+
+	for _, path := range []string{"/missing.rle", "/page.rle"} {
+		if _, err := loadPattern(server.URL+path, cache, 10, 10); err == nil {
+			t.Errorf("loaded %s, expected an error", path)
+		}
+	}
+	if _, err := loadPattern(server.URL+"/glider.rle", cache, 2, 2); err == nil {
+		t.Error("loaded a 3x3 pattern onto a 2x2 board, expected an error")
+	}
+	files, _ := filepath.Glob(filepath.Join(cache, "*"))
+	if len(files) != 1 {
+		t.Errorf("cached %v, expected just the glider", files)
+	}
+}
+
+// TestLoadPatternFile checks a pattern can be read from a file as well.
+func TestLoadPatternFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "glider*.rle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(gliderRLE)
+	file.Close()
+
+	board, err := loadPattern(file.Name(), "", 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cells := aliveOn(board, 3); !reflect.DeepEqual(cells, glider) {
+		t.Errorf("got cells %v, expected %v", cells, glider)
+	}
+}
@@ -0,0 +1,73 @@
+package gol
+
+import "sync"
+
+// workerPool is p.Threads worker goroutines started once for the whole run, each handed every turn through a
+// channel of its own, rather than a goroutine and a result channel per worker started every turn. On a long run of
+// small boards that start-up is a noticeable share of each turn. A turn is over once every worker has passed the
+// pool's barrier, after which the results are read straight from the pool.
+type workerPool struct {
+	p       Params
+	work    []chan poolTurn // Each worker's next turn.
+	results []sliceResult   // Each worker's slice of the latest turn, written before it passes the barrier.
+	barrier sync.WaitGroup  // Passed by each worker once it has finished the turn.
+}
+
+// poolTurn is the world a turn is computed from, and the rows to write it into as worker does, or with BitPacked
+// the packed world and the one to write it into.
+type poolTurn struct {
+	world, next  [][]byte
+	packed, into *packedGrid
+}
+
+// newWorkerPool starts p.Threads workers, which wait for turns until the pool is closed.
+func newWorkerPool(p Params) *workerPool {
+	pool := &workerPool{p: p, work: make([]chan poolTurn, p.Threads), results: make([]sliceResult, p.Threads)}
+	for id := range pool.work {
+		pool.work[id] = make(chan poolTurn)
+		go pool.run(id)
+	}
+	return pool
+}
+
+// run computes worker id's slice of each turn it is handed.
+func (pool *workerPool) run(id int) {
+	result := make(chan sliceResult, 1)
+	for turn := range pool.work[id] {
+		if turn.packed != nil {
+			packedWorker(id, pool.p, turn.packed, turn.into, result)
+		} else {
+			worker(id, pool.p, turn.world, turn.next, result)
+		}
+		pool.results[id] = <-result
+		pool.barrier.Done()
+	}
+}
+
+// step computes the next state of world, writing into next as worker does, and returns every worker's slice in
+// order once all of them have finished. The slices are only valid until the next step.
+func (pool *workerPool) step(world, next [][]byte) []sliceResult {
+	return pool.hand(poolTurn{world: world, next: next})
+}
+
+// stepPacked computes the next state of a packed world into next, returning every worker's flipped cells as step does.
+func (pool *workerPool) stepPacked(world, next *packedGrid) []sliceResult {
+	return pool.hand(poolTurn{packed: world, into: next})
+}
+
+// hand gives every worker the turn and waits for all of them to pass the barrier.
+func (pool *workerPool) hand(turn poolTurn) []sliceResult {
+	pool.barrier.Add(len(pool.work))
+	for _, work := range pool.work {
+		work <- turn
+	}
+	pool.barrier.Wait()
+	return pool.results
+}
+
+// close stops the workers. The pool can't be used afterwards.
+func (pool *workerPool) close() {
+	for _, work := range pool.work {
+		close(work)
+	}
+}
@@ -0,0 +1,63 @@
+package gol
+
+import "testing"
+
+// TestWorkerPool checks the pool's workers, handed turn after turn, make the same worlds as workers started afresh
+// each turn, in bands and interleaved.
+func TestWorkerPool(t *testing.T) {
+	for _, interleave := range []bool{false, true} {
+		for _, threads := range []int{1, 3, 7} {
+			p := Params{Threads: threads, ImageWidth: 512, ImageHeight: 512, Interleave: interleave}
+			results := make([]chan sliceResult, threads)
+			for i := range results {
+				results[i] = make(chan sliceResult)
+			}
+			pool := newWorkerPool(p)
+			world, expected := clusteredWorld(), clusteredWorld()
+			for turn := 1; turn <= 4; turn++ {
+				var next [][]byte
+				if interleave {
+					next = newRows(p.ImageHeight, p.ImageWidth)
+				}
+				var rows [][]byte
+				for _, result := range pool.step(world, next) {
+					rows = append(rows, result.rows...)
+				}
+				if interleave {
+					rows = next
+				}
+				world, expected = rows, nextWorld(p, expected, results)
+				if err := compareWorlds(world, expected); err != nil {
+					t.Errorf("%d threads, interleaved %t, turn %d: %v", threads, interleave, turn, err)
+				}
+			}
+			pool.close()
+		}
+	}
+}
+
+// BenchmarkWorkerPool compares handing turns to the pool's workers with starting them afresh each turn, on a board
+// small enough for starting them to matter.
+func BenchmarkWorkerPool(b *testing.B) {
+	p := Params{Threads: 8, ImageWidth: 64, ImageHeight: 64}
+	world := clusteredWorld()[:64]
+	for i := range world {
+		world[i] = world[i][:64]
+	}
+	b.Run("per_turn", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			results := make([]chan sliceResult, p.Threads)
+			for i := range results {
+				results[i] = make(chan sliceResult)
+			}
+			nextWorld(p, world, results)
+		}
+	})
+	b.Run("pool", func(b *testing.B) {
+		pool := newWorkerPool(p)
+		defer pool.close()
+		for i := 0; i < b.N; i++ {
+			pool.step(world, nil)
+		}
+	})
+}
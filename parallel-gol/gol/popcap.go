@@ -0,0 +1,105 @@
+package gol
+
+import (
+	"fmt"
+	"sort"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// CapRule is what the infinite plane does when more cells are alive than Params.MaxAlive allows.
+type CapRule int
+
+const (
+	// PauseAtCap pauses the run with a PopulationCapped event, once each time the population grows past the cap,
+	// so the pattern can be looked at, saved or quit before it uses up the machine's memory.
+	PauseAtCap CapRule = iota
+	// CullAtCap frees the chunks furthest from the viewport, killing every cell in them, until the population is
+	// back under the cap, and carries on. The pattern in view is kept for as long as possible.
+	CullAtCap
+)
+
+// capRuleNames are the names used for each rule on the command line.
+var capRuleNames = map[CapRule]string{
+	PauseAtCap: "pause",
+	CullAtCap:  "cull",
+}
+
+func (r CapRule) String() string {
+	if name, ok := capRuleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("CapRule(%d)", int(r))
+}
+
+// ParseCapRule returns the rule with the given name, e.g. "cull".
+func ParseCapRule(name string) (CapRule, error) {
+	for r, n := range capRuleNames {
+		if n == name {
+			return r, nil
+		}
+	}
+	return PauseAtCap, fmt.Errorf("unknown rule at the population cap %q, expected pause or cull", name)
+}
+
+// chunkPopulation counts the live cells in a chunk.
+func chunkPopulation(ch *chunk) int {
+	alive := 0
+	for ly := range ch {
+		for lx := range ch[ly] {
+			if ch[ly][lx] == util.Alive {
+				alive++
+			}
+		}
+	}
+	return alive
+}
+
+// population counts the live cells on the plane, without collecting them as aliveCells does.
+func (pl plane) population() int {
+	alive := 0
+	for _, ch := range pl {
+		alive += chunkPopulation(ch)
+	}
+	return alive
+}
+
+// cull frees the chunks furthest from the centre of the viewport until no more than limit cells are alive, and
+// returns the cells it killed. Whole chunks are freed, so the population can end up well under limit.
+func (pl plane) cull(p Params, limit int) []util.Cell {
+	centreX, centreY := p.ViewX+p.ImageWidth/2, p.ViewY+p.ImageHeight/2
+	distance := func(cc chunkCoord) int {
+		dx, dy := cc.X*chunkSize+chunkSize/2-centreX, cc.Y*chunkSize+chunkSize/2-centreY
+		return dx*dx + dy*dy
+	}
+
+	counts := make(map[chunkCoord]int, len(pl))
+	coords := make([]chunkCoord, 0, len(pl))
+	alive := 0
+	for cc, ch := range pl {
+		counts[cc] = chunkPopulation(ch)
+		coords = append(coords, cc)
+		alive += counts[cc]
+	}
+	// Furthest first, breaking ties by position so the same chunks are culled on every run.
+	sort.Slice(coords, func(i, j int) bool {
+		if di, dj := distance(coords[i]), distance(coords[j]); di != dj {
+			return di > dj
+		}
+		if coords[i].Y != coords[j].Y {
+			return coords[i].Y < coords[j].Y
+		}
+		return coords[i].X < coords[j].X
+	})
+
+	var culled []util.Cell
+	for _, cc := range coords {
+		if alive <= limit {
+			break
+		}
+		culled = append(culled, plane{cc: pl[cc]}.aliveCells()...)
+		alive -= counts[cc]
+		delete(pl, cc)
+	}
+	return culled
+}
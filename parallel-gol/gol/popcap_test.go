@@ -0,0 +1,47 @@
+package gol
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// block is a still life of four cells.
+var block = []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}}
+
+// TestCull checks culling frees the chunks furthest from the view first, and only as many as it needs to.
+func TestCull(t *testing.T) {
+	p := Params{ImageWidth: 32, ImageHeight: 32}
+	pl := make(plane)
+	var near, far []util.Cell
+	near = append(near, translate(glider, 10, 10)...)
+	near = append(near, translate(block, 3*chunkSize, 10)...)
+	far = append(far, translate(block, 10, 8*chunkSize)...)
+	far = append(far, translate(block, -5*chunkSize, -5*chunkSize)...)
+	for _, cell := range append(append([]util.Cell{}, near...), far...) {
+		pl.set(cell.X, cell.Y, util.Alive)
+	}
+
+	culled := pl.cull(p, len(near))
+	assertCells(t, 0, culled, far)
+	assertCells(t, 0, pl.aliveCells(), near)
+	if alive := pl.population(); alive != len(near) {
+		t.Errorf("population is %d after culling, expected %d", alive, len(near))
+	}
+
+	if culled := pl.cull(p, len(near)); len(culled) != 0 {
+		t.Errorf("culled %v from a plane already at the cap", culled)
+	}
+}
+
+// TestParseCapRule checks every rule can be given by the name it prints as, and anything else is refused.
+func TestParseCapRule(t *testing.T) {
+	for _, rule := range []CapRule{PauseAtCap, CullAtCap} {
+		if parsed, err := ParseCapRule(rule.String()); err != nil || parsed != rule {
+			t.Errorf("%q parsed as %v (%v), expected %v", rule.String(), parsed, err, rule)
+		}
+	}
+	if _, err := ParseCapRule("explode"); err == nil {
+		t.Error("an unknown rule was accepted")
+	}
+}
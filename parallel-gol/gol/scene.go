@@ -0,0 +1,120 @@
+package gol
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// patterns are the patterns a scene can place by name, in RLE. Any other name is read from an RLE file.
+var patterns = map[string]string{
+	"block":      "2o$2o!",
+	"beehive":    "b2o$o2bo$b2o!",
+	"blinker":    "3o!",
+	"glider":     "bo$2bo$3o!",
+	"lwss":       "bo2bo$o$o3bo$4o!",
+	"rpentomino": "b2o$2o$bo!",
+	"diehard":    "6bo$2o$bo3b3o!",
+	"acorn":      "bo$3bo$2o2b3o!",
+	"gosper": "24bo$22bobo$12b2o6b2o12b2o$11bo3bo4b2o12b2o$2o8bo5bo3b2o$2o8bo3bob2o4bobo$10bo5bo7bo$" +
+		"11bo3bo$12b2o!",
+}
+
+// placement is one line of a scene: a pattern and where it goes.
+type placement struct {
+	cells []util.Cell // Cells of the pattern after rotating and flipping, with its top-left at (0, 0).
+	x, y  int         // Where the top-left of the pattern goes on the board.
+}
+
+// loadScene assembles a board from a scene file. Each line of the file places a pattern, e.g.
+//
+//	gosper at 10,10 rot90
+//	patterns/pulsar.rle at 60,5 flipx
+//
+// giving the pattern's name or an RLE file (relative to the scene file), the board coordinates of its top-left
+// corner and optionally rot90, rot180 or rot270 (or any multiple of 90, negative for anticlockwise) to turn it
+// clockwise and flipx or flipy to mirror it left to right or top to bottom, applied in the order written. Blank lines and lines starting with # are skipped. Patterns
+// wrap around the edges of the board, and cells alive in any pattern are alive on the board.
+func loadScene(filename string, width, height int) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	board := make([]byte, width*height)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		p, err := parsePlacement(scanner.Text(), filepath.Dir(filename))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+		}
+		for _, cell := range p.cells {
+			x := ((p.x+cell.X)%width + width) % width
+			y := ((p.y+cell.Y)%height + height) % height
+			board[y*width+x] = util.Alive
+		}
+	}
+	return board, scanner.Err()
+}
+
+// parsePlacement reads one line of a scene, loading RLE files relative to dir. A blank or comment line places
+// no cells.
+func parsePlacement(line, dir string) (placement, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return placement{}, nil
+	}
+	if len(fields) < 3 || fields[1] != "at" {
+		return placement{}, fmt.Errorf("expected '<pattern> at <x>,<y>', found %q", line)
+	}
+
+	var p placement
+	coordinates := strings.Split(fields[2], ",")
+	if len(coordinates) != 2 {
+		return placement{}, fmt.Errorf("bad position %q", fields[2])
+	}
+	var errX, errY error
+	p.x, errX = strconv.Atoi(coordinates[0])
+	p.y, errY = strconv.Atoi(coordinates[1])
+	if errX != nil || errY != nil {
+		return placement{}, fmt.Errorf("bad position %q", fields[2])
+	}
+
+	rle, builtIn := patterns[fields[0]]
+	if !builtIn {
+		data, err := ioutil.ReadFile(filepath.Join(dir, fields[0]))
+		if err != nil {
+			return placement{}, fmt.Errorf("%q is neither a known pattern nor a readable rle file: %v", fields[0], err)
+		}
+		rle = string(data)
+	}
+	cells, err := util.ParseRLE([]byte(rle))
+	if err != nil {
+		return placement{}, fmt.Errorf("%s: %v", fields[0], err)
+	}
+	cells = util.Normalise(cells)
+
+	for _, option := range fields[3:] {
+		switch {
+		case option == "flipx":
+			cells = util.Mirror(cells, false)
+		case option == "flipy":
+			cells = util.Mirror(cells, true)
+		case strings.HasPrefix(option, "rot"):
+			degrees, err := strconv.Atoi(option[len("rot"):])
+			if err != nil || degrees%90 != 0 {
+				return placement{}, fmt.Errorf("bad rotation %q, expected a multiple of 90 degrees, e.g. rot90 or rot-90", option)
+			}
+			cells = util.Rotate(cells, degrees/90)
+		default:
+			return placement{}, fmt.Errorf("unknown option %q, expected rot<degrees>, flipx or flipy", option)
+		}
+	}
+	p.cells = cells
+	return p, nil
+}
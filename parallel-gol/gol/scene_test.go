@@ -0,0 +1,147 @@
+package gol
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// sorted returns cells in row order, so patterns can be compared whatever order their cells were listed in.
+func sorted(cells []util.Cell) []util.Cell {
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Y != cells[j].Y {
+			return cells[i].Y < cells[j].Y
+		}
+		return cells[i].X < cells[j].X
+	})
+	return cells
+}
+
+// TestParsePlacement checks each option turns or mirrors the pattern as described, in the order written.
+func TestParsePlacement(t *testing.T) {
+	// The glider heads down and to the right; turning and mirroring it changes where it heads.
+	tests := []struct {
+		line     string
+		expected []util.Cell
+	}{
+		{"glider at 0,0", []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}},
+		{"glider at 0,0 rot90", []util.Cell{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}}},
+		{"glider at 0,0 rot180", []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 2}}},
+		{"glider at 0,0 rot270", []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 0}, {X: 0, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 2}}},
+		{"glider at 0,0 rot-90", []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 0}, {X: 0, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 2}}},
+		{"glider at 0,0 rot450", []util.Cell{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}}},
+		{"glider at 0,0 flipx", []util.Cell{{X: 1, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}},
+		{"glider at 0,0 flipy", []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 1}, {X: 1, Y: 2}}},
+		// Mirroring then turning isn't the same as turning then mirroring.
+		{"glider at 0,0 flipx rot90", []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 2, Y: 1}, {X: 0, Y: 2}}},
+		{"glider at 0,0 rot90 flipx", []util.Cell{{X: 2, Y: 0}, {X: 0, Y: 1}, {X: 2, Y: 1}, {X: 1, Y: 2}, {X: 2, Y: 2}}},
+	}
+	for _, test := range tests {
+		p, err := parsePlacement(test.line, ".")
+		if err != nil {
+			t.Errorf("%s: %v", test.line, err)
+			continue
+		}
+		if got := sorted(p.cells); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: got %v, expected %v", test.line, got, test.expected)
+		}
+	}
+
+	for _, line := range []string{"# a comment", "", "   "} {
+		if p, err := parsePlacement(line, "."); err != nil || len(p.cells) != 0 {
+			t.Errorf("%q placed %v, %v, expected nothing", line, p.cells, err)
+		}
+	}
+	for _, bad := range []string{"glider", "glider 0,0", "glider at 0", "glider at a,0", "glider at 0,0 rot45", "glider at 0,0 rot", "glider at 0,0 spin", "nosuch at 0,0"} {
+		if _, err := parsePlacement(bad, "."); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+// TestLoadScene checks a scene places built-in and file patterns where it says, wrapping around the edges.
+func TestLoadScene(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scene")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "dot.rle"), []byte("x = 1, y = 1\no!\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scene := filepath.Join(dir, "test.scene")
+	lines := []string{
+		"# A blinker, a dot from a file and a block split across the corners.",
+		"blinker at 1,1",
+		"dot.rle at 5,6",
+		"block at 7,7",
+	}
+	if err := ioutil.WriteFile(scene, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	board, err := loadScene(scene, 8, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []util.Cell{{X: 0, Y: 0}, {X: 7, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 1}, {X: 3, Y: 1}, {X: 5, Y: 6}, {X: 0, Y: 7}, {X: 7, Y: 7}}
+	var alive []util.Cell
+	for i, cell := range board {
+		if cell == util.Alive {
+			alive = append(alive, util.Cell{X: i % 8, Y: i / 8})
+		}
+	}
+	if !reflect.DeepEqual(alive, expected) {
+		t.Errorf("got %v alive, expected %v", alive, expected)
+	}
+
+	if err := ioutil.WriteFile(scene, []byte("blinker at 1,1\nglider at 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadScene(scene, 8, 8); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("got %v, expected an error on line 2", err)
+	}
+}
+
+// TestBuiltInPatterns checks every built-in pattern is valid RLE.
+func TestBuiltInPatterns(t *testing.T) {
+	for name, rle := range patterns {
+		if cells, err := util.ParseRLE([]byte(rle)); err != nil || len(cells) == 0 {
+			t.Errorf("%s: %d cells, %v", name, len(cells), err)
+		}
+	}
+	if cells, _ := util.ParseRLE([]byte(patterns["gosper"])); len(cells) != 36 {
+		t.Errorf("gosper gun has %d cells, expected 36", len(cells))
+	}
+}
+
+// TestStampHook checks a stamp puts the pattern where its scene line says, every n turns, wrapping around the
+// edges, and that a line stamping nothing is refused.
+func TestStampHook(t *testing.T) {
+	hook, err := StampHook(5, "blinker at 7,2 rot90")
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := make([][]byte, 4)
+	for i := range world {
+		world[i] = make([]byte, 8)
+	}
+	if changed := runHooks([]Hook{hook}, 3, world); len(changed) != 0 {
+		t.Errorf("stamped %v at turn 3, expected nothing until turn 5", changed)
+	}
+	changed := runHooks([]Hook{hook}, 5, world)
+	expected := []util.Cell{{X: 7, Y: 0}, {X: 7, Y: 2}, {X: 7, Y: 3}}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Errorf("stamped %v, expected %v", changed, expected)
+	}
+
+	if _, err := StampHook(5, "# nothing"); err == nil {
+		t.Error("a line stamping nothing was accepted")
+	}
+}
@@ -0,0 +1,153 @@
+package gol
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// selfTestThreads are the thread counts the self-test splits worlds between, so rows lost or repeated where
+// slices meet show up as well as wrong rules.
+var selfTestThreads = []int{1, 3, 8}
+
+// selfTestCheck is a world, how many turns to evolve it and the world expected at the end, worked out
+// independently of the engine.
+type selfTestCheck struct {
+	name     string
+	world    [][]byte
+	turns    int
+	expected [][]byte
+}
+
+// SelfTest evolves a few patterns whose behaviour is known, and a random world compared against a deliberately
+// simple reference, with the parallel engine at several thread counts. It prints PASS or FAIL for each check to
+// out and returns whether all of them passed, so a machine can be validated without the full test suite.
+func SelfTest(out io.Writer) bool {
+	passed := true
+	for _, threads := range selfTestThreads {
+		for _, c := range selfTestChecks() {
+			height, width := len(c.world), len(c.world[0])
+			p := Params{Threads: threads, ImageWidth: width, ImageHeight: height}
+			results := make([]chan sliceResult, threads)
+			for i := range results {
+				results[i] = make(chan sliceResult)
+			}
+			world := c.world
+			for turn := 0; turn < c.turns; turn++ {
+				world = nextWorld(p, world, results)
+			}
+
+			if err := compareWorlds(world, c.expected); err != nil {
+				fmt.Fprintf(out, "FAIL %d threads: %s: %v\n", threads, c.name, err)
+				passed = false
+			} else {
+				fmt.Fprintf(out, "PASS %d threads: %s\n", threads, c.name)
+			}
+		}
+	}
+	return passed
+}
+
+// selfTestChecks returns the worlds the self-test evolves.
+func selfTestChecks() []selfTestCheck {
+	// A blinker turns from a horizontal line to a vertical one and back.
+	horizontal := worldOf(5, 5, []util.Cell{{X: 1, Y: 2}, {X: 2, Y: 2}, {X: 3, Y: 2}})
+	vertical := worldOf(5, 5, []util.Cell{{X: 2, Y: 1}, {X: 2, Y: 2}, {X: 2, Y: 3}})
+
+	// A glider moves one cell down and to the right every four turns.
+	glider := []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	moved := make([]util.Cell, len(glider))
+	for i, cell := range glider {
+		moved[i] = util.Cell{X: cell.X + 1, Y: cell.Y + 1}
+	}
+
+	// A blinker split across the top-left corner only turns into the same blinker if neighbours wrap around
+	// both edges, and a glider ends up back where it started after crossing both edges.
+	corner := worldOf(6, 6, []util.Cell{{X: 5, Y: 0}, {X: 0, Y: 0}, {X: 1, Y: 0}})
+	cornerTurned := worldOf(6, 6, []util.Cell{{X: 0, Y: 5}, {X: 0, Y: 0}, {X: 0, Y: 1}})
+
+	// A random world, at a size no thread count used divides evenly.
+	random := worldOf(37, 29, nil)
+	r := rand.New(rand.NewSource(1))
+	for y := range random {
+		for x := range random[y] {
+			if r.Intn(3) == 0 {
+				random[y][x] = util.Alive
+			}
+		}
+	}
+
+	return []selfTestCheck{
+		{"blinker oscillates", horizontal, 1, vertical},
+		{"blinker returns", horizontal, 2, horizontal},
+		{"glider translates", worldOf(8, 8, glider), 4, worldOf(8, 8, moved)},
+		{"blinker wraps around the corner", corner, 1, cornerTurned},
+		{"glider wraps around the edges", worldOf(6, 6, glider), 24, worldOf(6, 6, glider)},
+		{"matches the reference", random, 20, referenceLife(random, 20)},
+	}
+}
+
+// worldOf returns an empty width x height world with the given cells alive.
+func worldOf(width, height int, alive []util.Cell) [][]byte {
+	world := make([][]byte, height)
+	for y := range world {
+		world[y] = make([]byte, width)
+	}
+	for _, cell := range alive {
+		world[cell.Y][cell.X] = util.Alive
+	}
+	return world
+}
+
+// referenceLife evolves a world on a torus by counting every cell's neighbours one at a time, as simply as possible.
+func referenceLife(world [][]byte, turns int) [][]byte {
+	height, width := len(world), len(world[0])
+	for turn := 0; turn < turns; turn++ {
+		next := worldOf(width, height, nil)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				neighbours := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if (dx != 0 || dy != 0) && world[(y+dy+height)%height][(x+dx+width)%width] == util.Alive {
+							neighbours++
+						}
+					}
+				}
+				if neighbours == 3 || neighbours == 2 && world[y][x] == util.Alive {
+					next[y][x] = util.Alive
+				}
+			}
+		}
+		world = next
+	}
+	return world
+}
+
+// compareWorlds describes the first cell that differs between two worlds, or returns nil if they are the same.
+func compareWorlds(got, expected [][]byte) error {
+	if len(got) != len(expected) {
+		return fmt.Errorf("got %d rows, expected %d", len(got), len(expected))
+	}
+	for y := range expected {
+		if len(got[y]) != len(expected[y]) {
+			return fmt.Errorf("row %d has %d cells, expected %d", y, len(got[y]), len(expected[y]))
+		}
+		for x := range expected[y] {
+			if got[y][x] != expected[y][x] {
+				return fmt.Errorf("cell (%d, %d) is %s, expected %s", x, y, cellState(got[y][x]), cellState(expected[y][x]))
+			}
+		}
+	}
+	return nil
+}
+
+// cellState names a cell's state.
+func cellState(cell byte) string {
+	if cell == util.Alive {
+		return "alive"
+	}
+	return "dead"
+}
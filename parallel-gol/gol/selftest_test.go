@@ -0,0 +1,32 @@
+package gol
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestSelfTest checks every self-test passes with the parallel engine at every thread count tried.
+func TestSelfTest(t *testing.T) {
+	var out bytes.Buffer
+	if !SelfTest(&out) {
+		t.Errorf("self-test failed:\n%s", out.String())
+	}
+	if lines := strings.Count(out.String(), "PASS"); lines != len(selfTestThreads)*len(selfTestChecks()) {
+		t.Errorf("reported %d passes, expected %d:\n%s", lines, len(selfTestThreads)*len(selfTestChecks()), out.String())
+	}
+}
+
+// TestCompareWorlds checks a world differing in one cell is reported, with where it differs.
+func TestCompareWorlds(t *testing.T) {
+	expected := worldOf(4, 4, nil)
+	got := worldOf(4, 4, nil)
+	if err := compareWorlds(got, expected); err != nil {
+		t.Errorf("identical worlds reported as different: %v", err)
+	}
+	got[2][3] = 255
+	err := compareWorlds(got, expected)
+	if err == nil || !strings.Contains(err.Error(), "(3, 2)") {
+		t.Errorf("got %v, expected cell (3, 2) reported", err)
+	}
+}
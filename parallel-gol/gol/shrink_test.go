@@ -0,0 +1,72 @@
+package gol
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestRetainEmpty checks a chunk that empties stays allocated for ShrinkAfter turns and is then freed, and that
+// one kept alive by a pattern oscillating across its edge is never freed.
+func TestRetainEmpty(t *testing.T) {
+	const after = 3
+	pl := make(plane)
+	// A lone cell dies straight away, leaving its chunk empty.
+	pl.set(10, 10, util.Alive)
+	// A blinker straddling the edge between chunks (1, 0) and (1, 1) keeps both alive on alternate turns.
+	for x := chunkSize + 10; x < chunkSize+13; x++ {
+		pl.set(x, chunkSize, util.Alive)
+	}
+
+	idle := make(map[chunkCoord]int)
+	for turn := 1; turn <= 2*after; turn++ {
+		prev := pl
+		pl, _ = pl.step(4)
+		idle = pl.retainEmpty(prev, idle, after)
+
+		if _, kept := pl[chunkCoord{0, 0}]; kept != (turn <= after) {
+			t.Errorf("turn %d: empty chunk allocated is %v, expected %v", turn, kept, turn <= after)
+		}
+		for _, cc := range []chunkCoord{{1, 0}, {1, 1}} {
+			if _, kept := pl[cc]; !kept {
+				t.Errorf("turn %d: chunk %v the blinker crosses was freed", turn, cc)
+			}
+		}
+		if alive := len(pl.aliveCells()); alive != 3 {
+			t.Errorf("turn %d: %d cells alive, expected the blinker's 3", turn, alive)
+		}
+	}
+	if _, counted := idle[chunkCoord{0, 0}]; counted {
+		t.Error("freed chunk still counted as empty")
+	}
+	if idle[chunkCoord{1, 0}] > 1 {
+		t.Errorf("chunk the blinker crosses counted as empty for %d turns, expected at most 1", idle[chunkCoord{1, 0}])
+	}
+}
+
+// TestRetainEmptyMatchesReference checks keeping empty chunks doesn't change how a pattern evolves.
+func TestRetainEmptyMatchesReference(t *testing.T) {
+	pl := make(plane)
+	alive := make(map[util.Cell]bool)
+	for _, cell := range translate(glider, chunkSize-2, chunkSize-2) {
+		pl.set(cell.X, cell.Y, util.Alive)
+		alive[cell] = true
+	}
+
+	idle := make(map[chunkCoord]int)
+	for turn := 1; turn <= 200; turn++ {
+		prev := pl
+		pl, _ = pl.step(3)
+		idle = pl.retainEmpty(prev, idle, 5)
+		alive = referenceStep(alive)
+
+		var expected []util.Cell
+		for cell := range alive {
+			expected = append(expected, cell)
+		}
+		assertCells(t, turn, pl.aliveCells(), expected)
+	}
+	if len(pl) > 4 {
+		t.Errorf("%d chunks allocated for a glider, expected at most 4", len(pl))
+	}
+}
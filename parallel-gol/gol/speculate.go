@@ -0,0 +1,242 @@
+package gol
+
+import (
+	"sort"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// speculateRate is the largest fraction of the board that may flip in a turn for the turns after it to be
+// worked out ahead. Above it the active regions cover too much of the board to be worth doing separately.
+const speculateRate = 0.001
+
+// speculateMargin is how far around each flipped cell an active region reaches. Oscillators stay well inside it;
+// anything that travels further in the turns worked out ahead, such as a glider, reaches the region's edge and the
+// speculation is thrown away.
+const speculateMargin = 4
+
+// speculateTile is the side of the square tiles flipped cells are gathered into before active regions are worked
+// out.
+const speculateTile = 8
+
+// speculateRegions is the most separate groups of activity worked out ahead. A board with more is changing in too
+// many places for merging their regions to be cheap.
+const speculateRegions = 1024
+
+// generation is one turn worked out ahead: the world at the end of it and the cells that flipped during it.
+type generation struct {
+	world   [][]byte
+	flipped []util.Cell
+}
+
+// region is a rectangle of cells, from (x0, y0) up to but not including (x1, y1).
+type region struct {
+	x0, y0, x1, y1 int
+}
+
+// overlaps reports whether r, grown by a cell on every side, overlaps o, so one's frozen border would lie inside
+// the other.
+func (r region) overlaps(o region) bool {
+	return r.x0-1 < o.x1 && o.x0 < r.x1+1 && r.y0-1 < o.y1 && o.y0 < r.y1+1
+}
+
+// union returns the smallest region holding both r and o.
+func (r region) union(o region) region {
+	if o.x0 < r.x0 {
+		r.x0 = o.x0
+	}
+	if o.y0 < r.y0 {
+		r.y0 = o.y0
+	}
+	if o.x1 > r.x1 {
+		r.x1 = o.x1
+	}
+	if o.y1 > r.y1 {
+		r.y1 = o.y1
+	}
+	return r
+}
+
+// speculate works out up to turns of the turns after world at once, when few enough cells flipped to reach it
+// that everything else on the board is sure to stay as it is. Each active region, the cells within
+// speculateMargin of a flip, is evolved on its own by one of p.Threads goroutines, with the ring of cells around
+// it frozen. That is only right if nothing ever changes at a region's edge, where it would reach the frozen ring
+// or another region, so each region checks its edge every turn. It returns nil if the board is too busy to try,
+// and failed as well if a region's edge changed, in which case the turns must be worked out normally.
+func speculate(p Params, world [][]byte, flipped [][]util.Cell, turns int) (generations []generation, failed bool) {
+	if turns < 1 || p.Geometry != Square || len(p.Hooks) > 0 {
+		return nil, false
+	}
+	var cells []util.Cell
+	for _, part := range flipped {
+		cells = append(cells, part...)
+	}
+	if float64(len(cells)) > speculateRate*float64(p.ImageWidth*p.ImageHeight) {
+		return nil, false
+	}
+
+	regions, ok := activeRegions(p, cells)
+	if !ok {
+		return nil, false
+	}
+
+	// Evolve the regions on their own, a goroutine per thread taking regions in turn.
+	flips := make([][][]util.Cell, len(regions))
+	valid := make([]bool, len(regions))
+	next := make(chan int, len(regions))
+	for i := range regions {
+		next <- i
+	}
+	close(next)
+	var wg sync.WaitGroup
+	for t := 0; t < p.Threads && t < len(regions); t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				flips[i], valid[i] = evolveRegion(p, world, regions[i], turns)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, ok := range valid {
+		if !ok {
+			return nil, true
+		}
+	}
+
+	// Build each turn's world from the last, copying only the rows with flipped cells in them. The flips are put in
+	// the order normal stepping finds them, row by row.
+	generations = make([]generation, turns)
+	current := world
+	for turn := range generations {
+		var turnFlips []util.Cell
+		for i := range regions {
+			turnFlips = append(turnFlips, flips[i][turn]...)
+		}
+		sort.Slice(turnFlips, func(i, j int) bool {
+			a, b := turnFlips[i], turnFlips[j]
+			return a.Y < b.Y || a.Y == b.Y && a.X < b.X
+		})
+		nextWorld := append([][]byte{}, current...)
+		copied := map[int]bool{}
+		for _, cell := range turnFlips {
+			if !copied[cell.Y] {
+				nextWorld[cell.Y] = append([]byte{}, current[cell.Y]...)
+				copied[cell.Y] = true
+			}
+			nextWorld[cell.Y][cell.X] ^= util.Alive
+		}
+		generations[turn] = generation{world: nextWorld, flipped: turnFlips}
+		current = nextWorld
+	}
+	return generations, false
+}
+
+// activeRegions returns rectangles covering every cell within speculateMargin of a flipped cell, merged wherever
+// one's surrounding ring would overlap another. Flipped cells are first gathered into tiles, and tiles close enough
+// for their regions to touch into groups, so a board of scattered oscillators doesn't compare every flip with every
+// other. It returns false if a region would touch the edge of the world, where its ring would wrap around to the
+// other side, or if there are more than speculateRegions groups.
+func activeRegions(p Params, cells []util.Cell) ([]region, bool) {
+	// The region around each tile holding a flip.
+	tiles := map[[2]int]region{}
+	for _, cell := range cells {
+		r := region{cell.X - speculateMargin, cell.Y - speculateMargin, cell.X + speculateMargin + 1, cell.Y + speculateMargin + 1}
+		if r.x0 < 1 || r.y0 < 1 || r.x1 > p.ImageWidth-1 || r.y1 > p.ImageHeight-1 {
+			return nil, false
+		}
+		tile := [2]int{cell.X / speculateTile, cell.Y / speculateTile}
+		if existing, ok := tiles[tile]; ok {
+			r = r.union(existing)
+		}
+		tiles[tile] = r
+	}
+
+	// Group tiles whose regions overlap, looking only at tiles near enough for that to be possible.
+	const reach = (2*speculateMargin+1)/speculateTile + 1
+	var regions []region
+	seen := map[[2]int]bool{}
+	for start := range tiles {
+		if seen[start] {
+			continue
+		}
+		if len(regions) == speculateRegions {
+			return nil, false
+		}
+		seen[start] = true
+		group := tiles[start]
+		queue := [][2]int{start}
+		for len(queue) > 0 {
+			tile := queue[0]
+			queue = queue[1:]
+			for dy := -reach; dy <= reach; dy++ {
+				for dx := -reach; dx <= reach; dx++ {
+					near := [2]int{tile[0] + dx, tile[1] + dy}
+					if r, ok := tiles[near]; ok && !seen[near] && r.overlaps(tiles[tile]) {
+						seen[near] = true
+						group = group.union(r)
+						queue = append(queue, near)
+					}
+				}
+			}
+		}
+		regions = append(regions, group)
+	}
+
+	// A group's bounding rectangle can still reach another's, so merge them until none overlap.
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(regions); i++ {
+			for j := i + 1; j < len(regions); j++ {
+				if regions[i].overlaps(regions[j]) {
+					regions[i] = regions[i].union(regions[j])
+					regions[j] = regions[len(regions)-1]
+					regions = regions[:len(regions)-1]
+					merged = true
+					j--
+				}
+			}
+		}
+	}
+	return regions, true
+}
+
+// evolveRegion evolves the cells of r in world for the given number of turns, reading the ring of cells around it
+// but never changing them, and returns the cells that flipped in each turn. It returns false as soon as a cell on
+// the region's edge flips, since the ring next to it may then have changed too.
+func evolveRegion(p Params, world [][]byte, r region, turns int) ([][]util.Cell, bool) {
+	width, height := r.x1-r.x0, r.y1-r.y0
+
+	// Copy the region and its ring; the ring is copied into both buffers once and left alone.
+	current := make([][]byte, height+2)
+	next := make([][]byte, height+2)
+	for y := range current {
+		current[y] = append([]byte{}, world[r.y0-1+y][r.x0-1:r.x1+1]...)
+		next[y] = append([]byte{}, current[y]...)
+	}
+
+	flips := make([][]util.Cell, turns)
+	for turn := 0; turn < turns; turn++ {
+		for y := 1; y <= height; y++ {
+			for x := 1; x <= width; x++ {
+				sum := (int(current[y-1][x-1]) + int(current[y-1][x]) + int(current[y-1][x+1]) +
+					int(current[y][x-1]) + int(current[y][x+1]) +
+					int(current[y+1][x-1]) + int(current[y+1][x]) + int(current[y+1][x+1])) / int(util.Alive)
+				alive := current[y][x] == util.Alive
+				if p.Geometry.nextAlive(alive, sum) == alive {
+					next[y][x] = current[y][x]
+					continue
+				}
+				if y == 1 || y == height || x == 1 || x == width {
+					return nil, false
+				}
+				next[y][x] = current[y][x] ^ util.Alive
+				flips[turn] = append(flips[turn], util.Cell{X: r.x0 + x - 1, Y: r.y0 + y - 1})
+			}
+		}
+		current, next = next, current
+	}
+	return flips, true
+}
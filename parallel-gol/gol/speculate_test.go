@@ -0,0 +1,115 @@
+package gol
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// speculateWorld returns a 256x256 world with the given patterns alive, each placed with its top-left at an offset.
+func speculateWorld(patterns map[util.Cell][]util.Cell) [][]byte {
+	var alive []util.Cell
+	for at, pattern := range patterns {
+		for _, cell := range pattern {
+			alive = append(alive, util.Cell{X: at.X + cell.X, Y: at.Y + cell.Y})
+		}
+	}
+	return worldOf(256, 256, alive)
+}
+
+var (
+	blinker = []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}}
+	toad    = []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}, {X: 2, Y: 1}}
+)
+
+// stepOnce steps a whole world normally, returning the next world and the cells that flipped.
+func stepOnce(p Params, world [][]byte) ([][]byte, [][]util.Cell) {
+	next := newRows(p.ImageHeight, p.ImageWidth)
+	flipped := calculateNextState(world, next, 0, p.ImageHeight, p)
+	return next, [][]util.Cell{flipped}
+}
+
+// TestSpeculate checks turns worked out ahead for oscillators, two of them close enough to share a region, are the
+// turns normal stepping gives, cell for cell and flip for flip, and that the world they start from is left alone.
+func TestSpeculate(t *testing.T) {
+	p := Params{Threads: 2, ImageWidth: 256, ImageHeight: 256, Speculate: 10}
+	world, flipped := stepOnce(p, speculateWorld(map[util.Cell][]util.Cell{
+		{X: 50, Y: 50}: blinker, {X: 56, Y: 52}: blinker, {X: 150, Y: 100}: toad, {X: 200, Y: 200}: block,
+	}))
+	before := worldOf(256, 256, calculateAliveCells(world))
+
+	generations, failed := speculate(p, world, flipped, p.Speculate)
+	if failed || len(generations) != p.Speculate {
+		t.Fatalf("got %d turns worked out ahead (failed %v), expected %d", len(generations), failed, p.Speculate)
+	}
+	if err := compareWorlds(world, before); err != nil {
+		t.Fatalf("the world speculated from changed: %v", err)
+	}
+	expected := world
+	for i, g := range generations {
+		var expectedFlipped [][]util.Cell
+		expected, expectedFlipped = stepOnce(p, expected)
+		if err := compareWorlds(g.world, expected); err != nil {
+			t.Fatalf("turn %d ahead: %v", i+1, err)
+		}
+		if !reflect.DeepEqual(g.flipped, expectedFlipped[0]) {
+			t.Fatalf("turn %d ahead flipped %v, expected %v", i+1, g.flipped, expectedFlipped[0])
+		}
+	}
+}
+
+// TestSpeculateFails checks a glider, which travels out of its region, makes speculation fail, and that a busy
+// board and a pattern next to the edge of the world aren't speculated at all.
+func TestSpeculateFails(t *testing.T) {
+	p := Params{Threads: 2, ImageWidth: 256, ImageHeight: 256, Speculate: 16}
+	world, flipped := stepOnce(p, speculateWorld(map[util.Cell][]util.Cell{{X: 100, Y: 100}: glider}))
+	if generations, failed := speculate(p, world, flipped, p.Speculate); !failed || generations != nil {
+		t.Errorf("speculating with a glider gave %d turns (failed %v), expected it to fail", len(generations), failed)
+	}
+
+	world, flipped = stepOnce(p, speculateWorld(map[util.Cell][]util.Cell{{X: 250, Y: 100}: blinker}))
+	if generations, failed := speculate(p, world, flipped, p.Speculate); failed || generations != nil {
+		t.Errorf("speculating at the edge gave %d turns (failed %v), expected it not to be tried", len(generations), failed)
+	}
+
+	busy := worldOf(256, 256, nil)
+	r := rand.New(rand.NewSource(1))
+	for y := range busy {
+		for x := range busy[y] {
+			if r.Intn(3) == 0 {
+				busy[y][x] = util.Alive
+			}
+		}
+	}
+	world, flipped = stepOnce(p, busy)
+	if generations, failed := speculate(p, world, flipped, p.Speculate); failed || generations != nil {
+		t.Errorf("speculating on a busy board gave %d turns (failed %v), expected it not to be tried", len(generations), failed)
+	}
+}
+
+// TestActiveRegions checks flips are only put in the same region when one region's ring would lie inside the other.
+func TestActiveRegions(t *testing.T) {
+	p := Params{ImageWidth: 256, ImageHeight: 256}
+	tests := []struct {
+		name    string
+		cells   []util.Cell
+		regions int
+	}{
+		{"rings touch", []util.Cell{{X: 50, Y: 50}, {X: 60, Y: 50}}, 2},
+		{"ring inside the other", []util.Cell{{X: 50, Y: 50}, {X: 59, Y: 50}}, 1},
+		{"diagonal", []util.Cell{{X: 50, Y: 50}, {X: 59, Y: 59}}, 1},
+		{"chain", []util.Cell{{X: 50, Y: 50}, {X: 77, Y: 50}, {X: 59, Y: 50}, {X: 68, Y: 50}}, 1},
+		{"far apart", []util.Cell{{X: 20, Y: 20}, {X: 200, Y: 200}}, 2},
+	}
+	for _, test := range tests {
+		regions, ok := activeRegions(p, test.cells)
+		if !ok || len(regions) != test.regions {
+			t.Errorf("%s: got %v (ok %v), expected %d regions", test.name, regions, ok, test.regions)
+		}
+	}
+	if _, ok := activeRegions(p, []util.Cell{{X: 3, Y: 50}}); ok {
+		t.Error("got regions for a flip next to the edge, expected none")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestHooks checks a hook's changes to the board are kept, and that it only runs on the turns it is due.
+func TestHooks(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 100, Threads: 4}
+	calls := 0
+	p.Hooks = []gol.Hook{{
+		Every: 25,
+		Fn: func(turn int, world [][]byte) {
+			calls++
+			// Clear the board, so nothing can be alive at the end.
+			for i := range world {
+				for j := range world[i] {
+					world[i][j] = 0
+				}
+			}
+		},
+	}}
+
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+
+	if calls != 4 {
+		t.Errorf("hook ran %d times in %d turns, expected 4", calls, p.Turns)
+	}
+	assertEqualBoard(t, cells, []util.Cell{}, p)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestIdle checks a run on a still board goes idle after IdleAfter turns, slowing down, and that a key press
+// wakes it until the board has been still for IdleAfter turns again.
+func TestIdle(t *testing.T) {
+	file, err := ioutil.TempFile("", "block*.rle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("x = 2, y = 2\n2o$2o!\n")
+	file.Close()
+
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 2, Pattern: file.Name(), IdleAfter: 3}
+	events := make(chan gol.Event, 1000)
+	keyPresses := make(chan rune)
+	start := time.Now()
+	go gol.Run(p, events, keyPresses)
+
+	var states []gol.StateChange
+	for event := range events {
+		if e, ok := event.(gol.StateChange); ok && e.NewState != gol.Quitting {
+			states = append(states, e)
+			if len(states) == 1 {
+				// Wake the run with a key that doesn't change the board.
+				go func() { keyPresses <- '+' }()
+			}
+		}
+	}
+
+	expected := []gol.State{gol.Idle, gol.Executing, gol.Idle}
+	if len(states) != len(expected) {
+		t.Fatalf("received state changes %v, expected %v", states, expected)
+	}
+	for i, state := range expected {
+		if states[i].NewState != state {
+			t.Errorf("state change %d is %v, expected %v", i, states[i].NewState, state)
+		}
+	}
+	if states[0].CompletedTurns != p.IdleAfter-1 {
+		t.Errorf("went idle on turn %d, expected %d", states[0].CompletedTurns, p.IdleAfter-1)
+	}
+	if states[2].CompletedTurns-states[1].CompletedTurns != p.IdleAfter {
+		t.Errorf("woken on turn %d and idle again on turn %d, expected %d turns apart",
+			states[1].CompletedTurns, states[2].CompletedTurns, p.IdleAfter)
+	}
+	// Turns after going idle wait for a key press; at least the last few must have waited in full.
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("the run took %v, expected idle turns to slow it down", elapsed)
+	}
+}
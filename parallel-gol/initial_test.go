@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestInitialBoard checks the starting board arrives as one InitialBoard event, before any flips, in place of a
+// CellFlipped for each alive cell, and that a board built from it and the flips of each turn ends up as the final
+// world, which it wouldn't if the starting cells were flipped as well.
+func TestInitialBoard(t *testing.T) {
+	for _, infinite := range []bool{false, true} {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 8, InitialBoard: true, Infinite: infinite}
+		t.Run(fmt.Sprintf("infinite=%v", infinite), func(t *testing.T) {
+			events := make(chan gol.Event, 1000)
+			go gol.Run(p, events, nil)
+
+			board := make(map[util.Cell]bool)
+			initial := false
+			var final []util.Cell
+			for event := range events {
+				switch e := event.(type) {
+				case gol.InitialBoard:
+					if initial {
+						t.Fatal("InitialBoard sent twice")
+					}
+					initial = true
+					expected := readAliveCells(fmt.Sprintf("check/images/%vx%vx0.pgm", p.ImageWidth, p.ImageHeight), p.ImageWidth, p.ImageHeight)
+					assertEqualBoard(t, e.Alive, expected, p)
+					for _, cell := range e.Alive {
+						board[cell] = true
+					}
+				case gol.CellFlipped:
+					if !initial {
+						t.Fatalf("CellFlipped for turn %d sent before InitialBoard", e.CompletedTurns)
+					}
+					board[e.Cell] = !board[e.Cell]
+				case gol.TurnComplete:
+					if !initial {
+						t.Fatalf("TurnComplete for turn %d sent before InitialBoard", e.CompletedTurns)
+					}
+				case gol.FinalTurnComplete:
+					final = e.Alive
+				}
+			}
+
+			var shown []util.Cell
+			for cell, alive := range board {
+				if alive {
+					shown = append(shown, cell)
+				}
+			}
+			if !infinite {
+				// On the plane cells leave the viewport, so only the torus's final world is all shown.
+				assertEqualBoard(t, shown, final, p)
+			}
+		})
+	}
+}
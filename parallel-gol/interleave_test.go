@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestInterleave checks dealing rows out to the threads in turn gives the same board as giving each a band, with
+// and without the arena, including with more threads than divide the rows evenly.
+func TestInterleave(t *testing.T) {
+	for _, threads := range []int{1, 3, 8, 16} {
+		for _, arena := range []bool{false, true} {
+			p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: threads, Interleave: true, Arena: arena}
+			t.Run(fmt.Sprintf("%d_threads_arena_%v", threads, arena), func(t *testing.T) {
+				expectedAlive := readAliveCells(
+					"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+					p.ImageWidth,
+					p.ImageHeight,
+				)
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				var cells []util.Cell
+				for event := range events {
+					switch e := event.(type) {
+					case gol.FinalTurnComplete:
+						cells = e.Alive
+					}
+				}
+				assertEqualBoard(t, cells, expectedAlive, p)
+			})
+		}
+	}
+}
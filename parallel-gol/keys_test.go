@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/sdl"
+)
+
+// TestKeyBindings rebinds keys the way -keys does and checks both the window and the console follow them.
+func TestKeyBindings(t *testing.T) {
+	bindings := sdl.DefaultBindings()
+	if err := bindings.Set("kill=ctrl+k, up=w, left=a, down=s, right=d, save=f5 ctrl+s"); err != nil {
+		t.Fatal(err)
+	}
+
+	key := func(name string) sdl.Key {
+		k, err := sdl.ParseKey(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	tests := []struct {
+		key    string
+		action sdl.Action
+		bound  bool
+	}{
+		{"k", "", false}, // Kill no longer has its default key.
+		{"ctrl+k", sdl.Kill, true},
+		{"s", sdl.Down, true}, // Taken from save.
+		{"ctrl+s", sdl.Save, true},
+		{"f5", sdl.Save, true},
+		{"w", sdl.Up, true},
+		{"p", sdl.Pause, true}, // Untouched defaults remain.
+		{"shift+=", sdl.More, true},
+	}
+	for _, test := range tests {
+		action, ok := bindings.Lookup(key(test.key))
+		if ok != test.bound || action != test.action {
+			t.Errorf("%s: expected %q (bound %v), got %q (bound %v)", test.key, test.action, test.bound, action, ok)
+		}
+	}
+
+	// The console accepts action names and single keys bound without modifiers.
+	commands := consoleCommands(bindings)
+	if action, ok := commands.Lookup("kill"); !ok || action != string(sdl.Kill) {
+		t.Errorf("console command kill: got %q", action)
+	}
+	if _, ok := commands.Lookup("k"); ok {
+		t.Error("console command k should no longer kill")
+	}
+	if action, ok := commands.Lookup("s"); !ok || action != string(sdl.Down) {
+		t.Errorf("console command s: got %q", action)
+	}
+	if commands.Actions[string(sdl.Zoom)] != 0 || commands.Actions[string(sdl.Pause)] != 'p' {
+		t.Errorf("console key presses don't match the distributor's: %v", commands.Actions)
+	}
+
+	for _, spec := range []string{"explode=x", "pause", "pause=", "pause=notakey"} {
+		if err := sdl.DefaultBindings().Set(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
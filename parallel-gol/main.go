@@ -3,9 +3,15 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
 	"runtime"
+	"time"
+	"uk.ac.bris.cs/gameoflife/console"
 	"uk.ac.bris.cs/gameoflife/gol"
 	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
 // main is the function called when starting Game of Life with 'go run .'
@@ -41,6 +47,215 @@ func main() {
 		10000000000,
 		"Specify the number of turns to process. Defaults to 10000000000.")
 
+	flag.BoolVar(
+		&params.Infinite,
+		"infinite",
+		false,
+		"Run on an unbounded plane that grows in 64x64 chunks. The image size becomes the viewport size, which the pan keys move over the plane.")
+
+	flag.IntVar(
+		&params.ViewX,
+		"viewX",
+		0,
+		"Specify the plane x coordinate of the viewport's left edge in infinite mode. Defaults to 0.")
+
+	flag.IntVar(
+		&params.ViewY,
+		"viewY",
+		0,
+		"Specify the plane y coordinate of the viewport's top edge in infinite mode. Defaults to 0.")
+
+	flag.IntVar(
+		&params.ShrinkAfter,
+		"shrinkAfter",
+		8,
+		"Specify how many turns a chunk of the infinite plane must stay empty before it is freed and no longer computed. Defaults to 8.")
+
+	flag.IntVar(
+		&params.MaxAlive,
+		"maxAlive",
+		0,
+		"Specify the most cells allowed alive on the infinite plane before -atCap is taken. Defaults to no cap.")
+
+	atCap := flag.String(
+		"atCap",
+		gol.PauseAtCap.String(),
+		"Specify what happens when the infinite plane's population passes -maxAlive: pause, or cull the chunks furthest from view.")
+
+	gliderEvery := flag.Int(
+		"gliderEvery",
+		0,
+		"Inject a glider at the top-left corner every n turns. Defaults to 0 (never).")
+
+	stamp := flag.String(
+		"stamp",
+		"",
+		"Stamp a pattern onto the board every -stampEvery turns, written as a scene line, e.g. 'gosper at 10,10 rot90 flipx'.")
+
+	stampEvery := flag.Int(
+		"stampEvery",
+		100,
+		"Specify how many turns pass between stamps of -stamp. Defaults to 100.")
+
+	flag.BoolVar(
+		&params.StrictEvents,
+		"strictEvents",
+		false,
+		"Guarantee the exact event order required by the test suite, whatever order events are produced in.")
+
+	flag.StringVar(
+		&params.OutDir,
+		"outDir",
+		"out",
+		"Specify the directory to write output images to. Defaults to out.")
+
+	perRun := flag.Bool(
+		"perRun",
+		true,
+		"Write this run's output images to a subdirectory of -outDir named after its start time and seed, so repeated runs don't overwrite each other.")
+
+	seed := flag.Int64(
+		"seed",
+		0,
+		"Specify the seed in the name of this run's output subdirectory. Defaults to a random seed.")
+
+	flag.Float64Var(
+		&params.Threshold,
+		"threshold",
+		0.5,
+		"Specify the fraction of an input image's maxval at or above which a pixel is alive. Defaults to 0.5.")
+
+	flag.StringVar(
+		&params.Scene,
+		"scene",
+		"",
+		"Assemble the starting board from a scene file, e.g. with the line 'gosper at 10,10 rot90', instead of reading the input image.")
+
+	flag.StringVar(
+		&params.Pattern,
+		"pattern",
+		"",
+		"Start from an RLE pattern in the middle of an empty board, from a file or an http(s) URL such as a LifeWiki pattern, instead of reading the input image.")
+
+	flag.StringVar(
+		&params.PatternCache,
+		"patternCache",
+		defaultPatternCache(),
+		"Specify the directory downloaded -pattern files are kept in, so they are only downloaded once. Empty downloads them every run.")
+
+	geometry := flag.String(
+		"geometry",
+		"square",
+		"Specify the shape of the cells: square (Conway's Game of Life) or triangular. Defaults to square.")
+
+	keymap := flag.String(
+		"keymap",
+		"",
+		"Read key bindings from a file with one 'action = key [key ...]' binding per line.")
+
+	keys := flag.String(
+		"keys",
+		"",
+		"Bind keys to actions, e.g. 'kill=ctrl+k,up=w,left=a,down=s,right=d,save=f5'. Applied after -keymap.")
+
+	flag.StringVar(
+		&sdl.StreamAddress,
+		"streamAddr",
+		sdl.StreamAddress,
+		"Specify the address to serve the board to browsers on when SDL is unavailable. Defaults to "+sdl.StreamAddress+".")
+
+	flag.BoolVar(
+		&sdl.ShowGraph,
+		"graph",
+		sdl.ShowGraph,
+		"Show the population graph along the bottom of the window from the start. Toggle it with g either way.")
+
+	flag.IntVar(
+		&sdl.KeyframeEvery,
+		"keyframeEvery",
+		sdl.KeyframeEvery,
+		"Specify how many turns apart the full keyframes in a recording are, so replay can seek without starting from the beginning. 0 keeps only the first. Defaults to 100.")
+
+	flag.BoolVar(
+		&params.Autotune,
+		"autotune",
+		false,
+		"Time a few warm-up generations at several thread counts before the run and use the fastest instead of -t.")
+
+	flag.IntVar(
+		&params.Speculate,
+		"speculate",
+		0,
+		"Specify how many turns to work out ahead at once, in just the regions that are changing, while few cells change each turn. Defaults to 0 (off).")
+
+	flag.BoolVar(
+		&params.Arena,
+		"arena",
+		false,
+		"Write every generation into one of two buffers allocated at the start, rather than into new rows each turn, for boards of gigabytes.")
+
+	flag.BoolVar(
+		&params.HugePages,
+		"hugePages",
+		false,
+		"Ask the kernel to back the -arena with transparent huge pages, to cut TLB misses on large boards. Linux only.")
+
+	flag.BoolVar(
+		&params.Interleave,
+		"interleave",
+		false,
+		"Deal rows out to the worker threads in turn, rather than giving each a band, so a board with its live cells in one region keeps every thread busy.")
+
+	flag.BoolVar(
+		&params.BitPacked,
+		"bitPacked",
+		false,
+		"Store the board a bit per cell and count neighbours 64 cells at a time, an eighth of the memory, for boards of 4096x4096 and up. Square cells only.")
+
+	flag.IntVar(
+		&params.ExtentEvery,
+		"extent",
+		0,
+		"Specify how many turns apart to report the bounding box and centre of the alive cells, for following the pattern. Defaults to 0 (off), or 1 with -follow.")
+
+	flag.IntVar(
+		&params.Classify,
+		"classify",
+		0,
+		"Specify the longest period to look for when reporting that the board has become a still life, oscillator or spaceship. Defaults to 0 (off).")
+
+	flag.IntVar(
+		&params.IdleAfter,
+		"idle",
+		0,
+		"Specify how many turns the board must stay unchanged, or be an oscillator found by -classify, before turns slow down to a few a second until a key is pressed. Defaults to 0 (off).")
+
+	flag.BoolVar(
+		&sdl.FollowPattern,
+		"follow",
+		sdl.FollowPattern,
+		"Keep the pattern in the middle of the window as it moves, from the start. Toggle it with f either way.")
+
+	control := flag.String(
+		"control",
+		"",
+		"Specify a Unix domain socket to accept console commands on from other programs, one per line. Off by default.")
+
+	script := flag.String(
+		"script",
+		"",
+		"Read a file of timed commands, one such as 'at 10s press p' per line, and carry them out as if typed, so a demo runs unattended.")
+
+	selfTest := flag.Bool(
+		"selftest",
+		false,
+		"Check the engine against patterns whose behaviour is known at several thread counts, then exit, with status 1 if any check fails.")
+
+	replay := flag.String(
+		"replay",
+		"",
+		"Play back a .golrec recording in the window instead of running. + and - change the speed between 0.5x and 4x, n and b step forwards and backwards a turn.")
+
 	noVis := flag.Bool(
 		"noVis",
 		false,
@@ -48,16 +263,100 @@ func main() {
 
 	flag.Parse()
 
+	if *selfTest {
+		if !gol.SelfTest(os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if sdl.FollowPattern && params.ExtentEvery == 0 {
+		params.ExtentEvery = 1
+	}
+
+	if params.Scene != "" && params.Pattern != "" {
+		log.Fatal("-scene and -pattern both give the starting board; pass only one")
+	}
+
+	if *gliderEvery > 0 {
+		params.Hooks = append(params.Hooks, gol.GliderHook(*gliderEvery, 0, 0))
+	}
+	if *stamp != "" {
+		hook, err := gol.StampHook(*stampEvery, *stamp)
+		if err != nil {
+			log.Fatal(err)
+		}
+		params.Hooks = append(params.Hooks, hook)
+	}
+
+	var err error
+	if params.Geometry, err = gol.ParseGeometry(*geometry); err != nil {
+		log.Fatal(err)
+	}
+	if params.AtCap, err = gol.ParseCapRule(*atCap); err != nil {
+		log.Fatal(err)
+	}
+
+	// Settings the engine can't honour are refused before anything starts, rather than quietly ignored.
+	if err := gol.CheckFeatures(params); err != nil {
+		log.Fatal(err)
+	}
+
+	bindings := sdl.DefaultBindings()
+	if *keymap != "" {
+		if err := bindings.Load(*keymap); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := bindings.Set(*keys); err != nil {
+		log.Fatal(err)
+	}
+
+	if *replay != "" {
+		if err := sdl.Replay(*replay, bindings); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *perRun {
+		params.OutDir = filepath.Join(params.OutDir, util.RunName(time.Now(), util.RunSeed(*seed)))
+	}
+
 	fmt.Println("Threads:", params.Threads)
 	fmt.Println("Width:", params.ImageWidth)
 	fmt.Println("Height:", params.ImageHeight)
+	fmt.Println("Output:", params.OutDir)
+
+	// The window draws the starting board from one event, which for a dense board is far quicker than a
+	// CellFlipped for every alive cell.
+	params.InitialBoard = true
+
+	var steps []console.Step
+	if *script != "" {
+		if steps, err = console.LoadScript(*script, consoleCommands(bindings)); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	keyPresses := make(chan rune, 10)
 	events := make(chan gol.Event, 1000)
 
 	go gol.Run(params, events, keyPresses)
+	go console.Run(os.Stdin, keyPresses, consoleCommands(bindings))
+	if *script != "" {
+		go console.RunScript(steps, keyPresses)
+	}
+	if *control != "" {
+		listener, err := console.Listen(*control, keyPresses, consoleCommands(bindings))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer listener.Close()
+		fmt.Println("Control socket:", *control)
+	}
 	if !(*noVis) {
-		sdl.Run(params, events, keyPresses)
+		sdl.Run(params, events, keyPresses, bindings)
 	} else {
 		complete := false
 		for !complete {
@@ -69,3 +368,13 @@ func main() {
 		}
 	}
 }
+
+// defaultPatternCache returns the directory downloaded patterns are kept in unless -patternCache says otherwise:
+// a directory in the user's cache directory, or in the working directory if there isn't one.
+func defaultPatternCache() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join("cache", "patterns")
+	}
+	return filepath.Join(dir, "gameoflife", "patterns")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestPauseAfterTurn checks pausing waits for the turn's TurnComplete, so the GUI has every flip of the turn it is
+// told the run paused at before being told, and that stepping with n completes exactly one more turn.
+func TestPauseAfterTurn(t *testing.T) {
+	file, err := ioutil.TempFile("", "glider*.rle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("x = 3, y = 3\nbo$2bo$3o!\n")
+	file.Close()
+	out, err := ioutil.TempDir("", "pause")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 100, Threads: 2, Pattern: file.Name(), OutDir: out}
+	events := make(chan gol.Event, 1000)
+	keyPresses := make(chan rune, 3)
+	go gol.Run(p, events, keyPresses)
+
+	var last gol.Event // Latest CellFlipped or TurnComplete.
+	var paused []int   // Turns of the TurnComplete events seen while paused.
+	pausing, pausedAt := false, 0
+	for event := range events {
+		switch e := event.(type) {
+		case gol.TurnComplete:
+			if e.CompletedTurns == 5 && !pausing {
+				keyPresses <- 'p'
+			}
+			if pausing {
+				// The step is complete, so resume.
+				paused = append(paused, e.CompletedTurns)
+				keyPresses <- 'p'
+			}
+			last = e
+		case gol.CellFlipped:
+			if pausing && e.CompletedTurns <= pausedAt {
+				t.Fatalf("cell flipped on turn %d after pausing at turn %d", e.CompletedTurns, pausedAt)
+			}
+			last = e
+		case gol.StateChange:
+			switch e.NewState {
+			case gol.Paused:
+				if complete, ok := last.(gol.TurnComplete); !ok || complete.CompletedTurns != e.CompletedTurns {
+					t.Fatalf("paused at turn %d after %v, expected straight after that turn's TurnComplete", e.CompletedTurns, last)
+				}
+				pausing, pausedAt = true, e.CompletedTurns
+				keyPresses <- 'n'
+			case gol.Executing:
+				pausing = false
+			}
+		}
+	}
+	if len(paused) != 1 || paused[0] != pausedAt+1 {
+		t.Errorf("completed turns %v while paused at turn %d, expected a single step", paused, pausedAt)
+	}
+}
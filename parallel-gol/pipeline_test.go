@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestPipelinedEvents checks that, although events are sent while later turns are being computed, every turn's
+// CellFlipped events still arrive before its TurnComplete, and replaying them reproduces the final board.
+func TestPipelinedEvents(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 8}
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+
+	board := make(map[util.Cell]bool)
+	completed := -1
+	var final []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			if e.CompletedTurns <= completed {
+				t.Fatalf("CellFlipped for turn %d arrived after TurnComplete for turn %d", e.CompletedTurns, completed)
+			}
+			board[e.Cell] = !board[e.Cell]
+		case gol.TurnComplete:
+			if e.CompletedTurns != completed+1 {
+				t.Fatalf("TurnComplete for turn %d followed turn %d", e.CompletedTurns, completed)
+			}
+			completed = e.CompletedTurns
+		case gol.FinalTurnComplete:
+			final = e.Alive
+		}
+	}
+
+	var replayed []util.Cell
+	for cell, alive := range board {
+		if alive {
+			replayed = append(replayed, cell)
+		}
+	}
+	assertEqualBoard(t, replayed, final, p)
+}
@@ -0,0 +1,298 @@
+package sdl
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Action is something the user can ask for from the keyboard, whatever key it is bound to.
+type Action string
+
+const (
+	Pause  Action = "pause"
+	Save   Action = "save"
+	Quit   Action = "quit"
+	Kill   Action = "kill"
+	Step   Action = "step"   // Advance a single turn while paused.
+	Back   Action = "back"   // Go back a single turn while replaying a recording.
+	More   Action = "more"   // Add a worker thread.
+	Fewer  Action = "fewer"  // Remove a worker thread.
+	Zoom   Action = "zoom"   // Switch between the downsampled overview and 1:1.
+	Graph  Action = "graph"  // Show or hide the population graph.
+	Record Action = "record" // Start or stop recording the turns shown.
+	Follow Action = "follow" // Start or stop keeping the pattern in the middle of the view.
+	Up     Action = "up"     // Pan the view.
+	Down   Action = "down"
+	Left   Action = "left"
+	Right  Action = "right"
+)
+
+// keyPresses maps the actions handled by the distributor to the key press it expects for them.
+// Actions missing from here only affect the window.
+var keyPresses = map[Action]rune{
+	Pause: 'p',
+	Save:  's',
+	Quit:  'q',
+	Kill:  'k',
+	Step:  'n',
+	Back:  'b',
+	More:  '+',
+	Fewer: '-',
+}
+
+// panStep is how many cells the view moves for each press of a pan key.
+const panStep = 8
+
+// KeyPress returns the key press the distributor expects for the action, or false if the action only affects the window.
+func (a Action) KeyPress() (rune, bool) {
+	key, ok := keyPresses[a]
+	return key, ok
+}
+
+// Actions returns every action that can be bound, in alphabetical order.
+func Actions() []Action {
+	actions := []Action{Pause, Save, Quit, Kill, Step, Back, More, Fewer, Zoom, Graph, Record, Follow, Up, Down, Left, Right}
+	sort.Slice(actions, func(i, j int) bool { return actions[i] < actions[j] })
+	return actions
+}
+
+// Modifier is a set of modifier keys that must be held down for a binding to apply.
+type Modifier uint8
+
+const (
+	Shift Modifier = 1 << iota
+	Ctrl
+	Alt
+)
+
+// modifierNames are the prefixes used for modifiers in bindings, e.g. "ctrl+k".
+var modifierNames = []struct {
+	name     string
+	modifier Modifier
+}{{"ctrl+", Ctrl}, {"shift+", Shift}, {"alt+", Alt}}
+
+// Key is a key together with the modifiers held with it.
+type Key struct {
+	Sym Keycode
+	Mod Modifier
+}
+
+// ParseKey parses a key such as "k", "space", "up", "f5" or "ctrl+k". Names other than single characters
+// are SDL key names and are not case sensitive.
+func ParseKey(name string) (Key, error) {
+	var key Key
+	rest := strings.ToLower(strings.TrimSpace(name))
+	for stripped := true; stripped; {
+		stripped = false
+		for _, m := range modifierNames {
+			// Leave a lone "+" alone, so "ctrl++" binds ctrl and the plus key.
+			if strings.HasPrefix(rest, m.name) && len(rest) > len(m.name) {
+				key.Mod |= m.modifier
+				rest = rest[len(m.name):]
+				stripped = true
+			}
+		}
+	}
+
+	if len(rest) == 1 {
+		// SDL keycodes for printable keys are the characters they type.
+		key.Sym = Keycode(rest[0])
+	} else {
+		key.Sym = keyFromName(rest)
+	}
+	if key.Sym == 0 {
+		return key, fmt.Errorf("unknown key %q", name)
+	}
+	return key, nil
+}
+
+// Bindings maps keys to the actions they trigger. Several keys can trigger the same action, but each key
+// triggers at most one.
+type Bindings map[Key]Action
+
+// DefaultBindings returns the keys used when nothing has been configured: the letters from the coursework
+// specification, '+'/'-' (and '=' so it works without shift), 'm' to zoom, 'g' for the graph, 'r' to record,
+// 'f' to follow the pattern, 'b' to step back through a replay and the arrow keys to pan.
+func DefaultBindings() Bindings {
+	return Bindings{
+		{Sym: 'p'}:            Pause,
+		{Sym: 's'}:            Save,
+		{Sym: 'q'}:            Quit,
+		{Sym: 'k'}:            Kill,
+		{Sym: 'n'}:            Step,
+		{Sym: 'b'}:            Back,
+		{Sym: '+'}:            More,
+		{Sym: '='}:            More,
+		{Sym: keyKeypadPlus}:  More,
+		{Sym: '-'}:            Fewer,
+		{Sym: keyKeypadMinus}: Fewer,
+		{Sym: 'm'}:            Zoom,
+		{Sym: 'g'}:            Graph,
+		{Sym: 'r'}:            Record,
+		{Sym: 'f'}:            Follow,
+		{Sym: keyUp}:          Up,
+		{Sym: keyDown}:        Down,
+		{Sym: keyLeft}:        Left,
+		{Sym: keyRight}:       Right,
+	}
+}
+
+// Lookup returns the action bound to a key press. A binding without shift still applies while shift is held,
+// since some keys (such as '+') need shift on common layouts.
+func (b Bindings) Lookup(key Key) (Action, bool) {
+	if action, ok := b[key]; ok {
+		return action, true
+	}
+	if key.Mod == Shift {
+		action, ok := b[Key{Sym: key.Sym}]
+		return action, ok
+	}
+	return "", false
+}
+
+// Bind makes the given keys the only ones that trigger the action, taking them away from any other action.
+func (b Bindings) Bind(action Action, keys ...Key) {
+	for key, bound := range b {
+		if bound == action {
+			delete(b, key)
+		}
+	}
+	for _, key := range keys {
+		b[key] = action
+	}
+}
+
+// Set applies a comma separated list of bindings such as "kill=ctrl+k,up=w,save=f5 ctrl+s". Each binding
+// replaces all the keys of its action; several keys for one action are separated by spaces.
+func (b Bindings) Set(spec string) error {
+	for _, binding := range strings.Split(spec, ",") {
+		if strings.TrimSpace(binding) == "" {
+			continue
+		}
+		if err := b.apply(binding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load applies the bindings in a file with one "action = key [key ...]" binding per line.
+// Blank lines and lines starting with '#' are ignored.
+func (b Bindings) Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := b.apply(text); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// apply parses and applies a single "action=key [key ...]" binding.
+func (b Bindings) apply(binding string) error {
+	i := strings.Index(binding, "=")
+	if i <= 0 {
+		return fmt.Errorf("expected action=key in %q", binding)
+	}
+	action := Action(strings.ToLower(strings.TrimSpace(binding[:i])))
+	if !action.valid() {
+		return fmt.Errorf("unknown action %q, expected one of %v", action, Actions())
+	}
+
+	var keys []Key
+	for _, name := range strings.Fields(binding[i+1:]) {
+		key, err := ParseKey(name)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no keys given for %s", action)
+	}
+	b.Bind(action, keys...)
+	return nil
+}
+
+// valid reports whether the action is one of the known actions.
+func (a Action) valid() bool {
+	for _, action := range Actions() {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// String lists the bindings, e.g. for a help message.
+func (b Bindings) String() string {
+	keys := make(map[Action][]string)
+	for key, action := range b {
+		keys[action] = append(keys[action], key.String())
+	}
+	var lines []string
+	for _, action := range Actions() {
+		if len(keys[action]) == 0 {
+			continue
+		}
+		sort.Strings(keys[action])
+		lines = append(lines, fmt.Sprintf("%s=%s", action, strings.Join(keys[action], " ")))
+	}
+	return strings.Join(lines, ",")
+}
+
+// String returns a readable name for the key, such as "ctrl+k" or "up".
+func (k Key) String() string {
+	name := ""
+	for _, m := range modifierNames {
+		if k.Mod&m.modifier != 0 {
+			name += m.name
+		}
+	}
+	if k.Sym > ' ' && k.Sym < 0x7F {
+		return name + string(rune(k.Sym))
+	}
+	return name + strings.ToLower(keyName(k.Sym))
+}
+
+// perform carries out an action, either by sending the distributor its key press or by changing the view.
+func perform(w *Window, action Action, keyPresses chan<- rune) {
+	if key, ok := action.KeyPress(); ok {
+		keyPresses <- key
+		return
+	}
+	switch action {
+	case Zoom:
+		// The view is purely visual, so the distributor doesn't need to know.
+		w.ToggleDownsample()
+		w.Redraw()
+	case Graph:
+		w.ToggleGraph()
+	case Record:
+		w.ToggleRecording()
+	case Follow:
+		w.ToggleFollow()
+	case Up:
+		w.Pan(0, -panStep)
+	case Down:
+		w.Pan(0, panStep)
+	case Left:
+		w.Pan(-panStep, 0)
+	case Right:
+		w.Pan(panStep, 0)
+	}
+	w.RenderFrame()
+}
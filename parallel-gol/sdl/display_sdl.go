@@ -0,0 +1,98 @@
+//go:build cgo
+// +build cgo
+
+package sdl
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// sdlDisplay shows frames in an SDL window.
+type sdlDisplay struct {
+	window   *sdl.Window
+	renderer *sdl.Renderer
+	texture  *sdl.Texture
+	width    int // Width of the view in pixels.
+}
+
+// newDisplay returns an SDL display, or an MJPEG stream if SDL can't be initialised, e.g. on a machine
+// without a screen.
+func newDisplay() display {
+	if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
+		fmt.Println("SDL is unavailable, so the board is streamed instead:", err)
+		return newStreamDisplay()
+	}
+	return &sdlDisplay{}
+}
+
+func filterEvent(e sdl.Event, userdata interface{}) bool {
+	switch e.GetType() {
+	case sdl.KEYDOWN, sdl.QUIT, sdl.CONTROLLERBUTTONDOWN, sdl.CONTROLLERAXISMOTION, sdl.CONTROLLERDEVICEADDED, sdl.WINDOWEVENT:
+		return true
+	}
+	return false
+}
+
+func (d *sdlDisplay) bounds() (int32, int32, bool) {
+	bounds, err := sdl.GetDisplayUsableBounds(0)
+	return bounds.W, bounds.H, err == nil
+}
+
+// open creates the window, renderer and texture used to draw a view of the given size in pixels.
+func (d *sdlDisplay) open(width, height int) {
+	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, int32(width), int32(height), sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
+	util.Check(err)
+	renderer, err := sdl.CreateRenderer(window, -1, sdl.WINDOW_SHOWN)
+	util.Check(err)
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "linear")
+	err = renderer.SetLogicalSize(int32(width), int32(height))
+	util.Check(err)
+	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, int32(width), int32(height))
+	util.Check(err)
+	sdl.SetEventFilterFunc(filterEvent, nil)
+	d.window, d.renderer, d.texture, d.width = window, renderer, texture, width
+}
+
+func (d *sdlDisplay) present(pixels []byte) {
+	err := d.texture.Update(nil, pixels, d.width*4)
+	util.Check(err)
+	err = d.renderer.Clear()
+	util.Check(err)
+	err = d.renderer.Copy(d.texture, nil, nil)
+	util.Check(err)
+	d.renderer.Present()
+}
+
+func (d *sdlDisplay) poll() interface{} {
+	// Return a nil interface rather than a nil sdl.Event, so callers can compare the result with nil.
+	event := sdl.PollEvent()
+	if event == nil {
+		return nil
+	}
+	if e, ok := event.(*sdl.WindowEvent); ok {
+		// Uncovering, resizing or restoring the window can lose what was drawn in it.
+		switch e.Event {
+		case sdl.WINDOWEVENT_EXPOSED, sdl.WINDOWEVENT_SIZE_CHANGED, sdl.WINDOWEVENT_RESTORED:
+			return redrawRequest{}
+		}
+		return nil
+	}
+	return event
+}
+
+func (d *sdlDisplay) setTitle(title string) {
+	d.window.SetTitle(title)
+}
+
+func (d *sdlDisplay) close() {
+	err := d.texture.Destroy()
+	util.Check(err)
+	err = d.renderer.Destroy()
+	util.Check(err)
+	err = d.window.Destroy()
+	util.Check(err)
+	sdl.Quit()
+}
@@ -0,0 +1,35 @@
+package sdl
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+)
+
+// TestFollow checks following a pattern puts its centre in the middle of the view, wrapping around the board,
+// and that nothing moves unless following is on.
+func TestFollow(t *testing.T) {
+	const width, height = 64, 32
+	w := &Window{Width: width, Height: height, viewWidth: 16, viewHeight: 8, factor: 1}
+	extent := gol.PatternExtent{Alive: 5, CentreX: 3.5, CentreY: 30}
+
+	w.Follow(extent)
+	if w.offsetX != 0 || w.offsetY != 0 {
+		t.Fatalf("the view moved to (%d, %d) without following", w.offsetX, w.offsetY)
+	}
+
+	w.ToggleFollow()
+	if w.Following() {
+		t.Fatal("started following a run that sends no extents")
+	}
+	w.FollowExtents(true, false)
+	w.ToggleFollow()
+	if !w.Following() {
+		t.Fatal("didn't start following")
+	}
+	w.Follow(extent)
+	// Cell (3, 30) is 8 columns from the left of the view and 4 rows from the top, wrapping around the edges.
+	if w.offsetX != width-5 || w.offsetY != 26 {
+		t.Errorf("the view is at (%d, %d), expected (%d, 26)", w.offsetX, w.offsetY, width-5)
+	}
+}
@@ -0,0 +1,69 @@
+package sdl
+
+import "sync"
+
+// frontDisplay hands what the event consumer draws over to a display that may only be used from the main thread,
+// as SDL's may. present and setTitle just keep the latest frame and title, which the main thread shows with flush,
+// so frames drawn faster than the main thread gets round to them are dropped rather than queued up.
+type frontDisplay struct {
+	display // Used directly for bounds, open, poll and close, which only the main thread calls.
+
+	mu       sync.Mutex
+	frame    []byte        // Latest frame presented, copied as the window goes on changing its buffers.
+	spare    []byte        // Frame last shown, reused for the next one once the main thread is done with it.
+	fresh    bool          // Whether frame hasn't been shown yet.
+	title    string        // Latest title set.
+	retitled bool          // Whether title hasn't been shown yet.
+	ready    chan struct{} // Signalled, without blocking, when there is something for flush to show.
+}
+
+// newFrontDisplay wraps a display so the event consumer can draw to it from any goroutine.
+func newFrontDisplay(d display) *frontDisplay {
+	return &frontDisplay{display: d, ready: make(chan struct{}, 1)}
+}
+
+// present keeps a copy of the frame for the main thread to show.
+func (f *frontDisplay) present(pixels []byte) {
+	f.mu.Lock()
+	f.frame = append(f.frame[:0], pixels...)
+	f.fresh = true
+	f.mu.Unlock()
+	f.signal()
+}
+
+// setTitle keeps the title for the main thread to show.
+func (f *frontDisplay) setTitle(title string) {
+	f.mu.Lock()
+	f.title, f.retitled = title, true
+	f.mu.Unlock()
+	f.signal()
+}
+
+// signal wakes the main thread if it isn't already due to flush.
+func (f *frontDisplay) signal() {
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+}
+
+// flush shows the latest frame and title, if they haven't been shown yet. It must be called from the main thread.
+// The lock is only held to take them, so the consumer can carry on drawing while SDL shows them.
+func (f *frontDisplay) flush() {
+	f.mu.Lock()
+	var frame []byte
+	if f.fresh {
+		frame, f.frame, f.spare = f.frame, f.spare, f.frame
+		f.fresh = false
+	}
+	title, retitled := f.title, f.retitled
+	f.retitled = false
+	f.mu.Unlock()
+
+	if retitled {
+		f.display.setTitle(title)
+	}
+	if frame != nil {
+		f.display.present(frame)
+	}
+}
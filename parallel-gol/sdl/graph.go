@@ -0,0 +1,120 @@
+package sdl
+
+// ShowGraph is whether the population graph is shown when the window opens. It can be toggled with the
+// Graph action either way.
+var ShowGraph = false
+
+// graphHistory is how many turns the graph remembers, enough for a graph as wide as any screen.
+const graphHistory = 4096
+
+// Colours of the graph's lines, as ARGB8888 pixels are stored: blue, green, red.
+var (
+	aliveColour = [3]byte{0xFF, 0xFF, 0xFF}
+	birthColour = [3]byte{0x00, 0xFF, 0x00}
+	deathColour = [3]byte{0x00, 0x00, 0xFF}
+)
+
+// sample is the population at the end of a turn, along with the cells born and died during it.
+type sample struct {
+	alive, births, deaths int
+}
+
+// graph follows the population of the board shown in the window turn by turn, from the cells flipped each turn,
+// and plots it over a strip along the bottom of the window.
+type graph struct {
+	shown   bool
+	alive   int      // Number of cells alive on the board shown.
+	births  int      // Cells born so far this turn.
+	deaths  int      // Cells died so far this turn.
+	samples []sample // One per completed turn, oldest first.
+}
+
+// flipped records a cell flipping. Cells flipped at turn 0 set up the starting board, so they are neither births
+// nor deaths.
+func (g *graph) flipped(alive bool, turn int) {
+	if alive {
+		g.alive++
+	} else {
+		g.alive--
+	}
+	switch {
+	case turn == 0:
+	case alive:
+		g.births++
+	default:
+		g.deaths++
+	}
+}
+
+// record ends a turn, adding its sample to the graph.
+func (g *graph) record() {
+	g.samples = append(g.samples, sample{g.alive, g.births, g.deaths})
+	g.births, g.deaths = 0, 0
+	// Trimming only once twice the history has built up keeps appending cheap.
+	if len(g.samples) >= 2*graphHistory {
+		g.samples = append(g.samples[:0], g.samples[len(g.samples)-graphHistory:]...)
+	}
+}
+
+// draw plots the latest turns over the bottom quarter of a view of ARGB8888 pixels, one turn per column with
+// the latest on the right. The population is drawn in white against its own scale, and births in green and
+// deaths in red against a shared one, so small changes to a large population still show.
+func (g *graph) draw(view []byte, width, height int) {
+	strip := height / 4
+	if strip < 2 {
+		return
+	}
+	top := height - strip
+
+	// Darken the strip so the lines stand out from the board behind them.
+	for i := 4 * top * width; i < len(view); i += 4 {
+		view[i+0] /= 4
+		view[i+1] /= 4
+		view[i+2] /= 4
+	}
+
+	samples := g.samples
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+	maxAlive, maxChange := 1, 1
+	for _, s := range samples {
+		maxAlive = max(maxAlive, s.alive)
+		maxChange = max(maxChange, max(s.births, s.deaths))
+	}
+
+	// Each line is joined up with a vertical run between the previous column's point and this one's.
+	x := width - len(samples)
+	var previous sample
+	for i, s := range samples {
+		if i == 0 {
+			previous = s
+		}
+		plot(view, width, x+i, top, strip, previous.deaths, s.deaths, maxChange, deathColour)
+		plot(view, width, x+i, top, strip, previous.births, s.births, maxChange, birthColour)
+		plot(view, width, x+i, top, strip, previous.alive, s.alive, maxAlive, aliveColour)
+		previous = s
+	}
+}
+
+// plot draws the part of a line in column x of the strip from value from to value to, scaled so that limit
+// reaches the top of the strip.
+func plot(view []byte, width, x, top, strip, from, to, limit int, colour [3]byte) {
+	y0 := top + strip - 1 - from*(strip-1)/limit
+	y1 := top + strip - 1 - to*(strip-1)/limit
+	if y0 > y1 {
+		y0, y1 = y1, y0
+	}
+	for y := y0; y <= y1; y++ {
+		i := 4 * (y*width + x)
+		copy(view[i:i+3], colour[:])
+	}
+}
+
+// max returns the larger of a and b.
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
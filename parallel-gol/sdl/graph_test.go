@@ -0,0 +1,77 @@
+package sdl
+
+import "testing"
+
+// TestGraphRecord checks flips are counted towards the population, births and deaths, except those setting up the
+// starting board, and that old turns are trimmed.
+func TestGraphRecord(t *testing.T) {
+	var g graph
+	for i := 0; i < 5; i++ {
+		g.flipped(true, 0)
+	}
+	g.record()
+	g.flipped(true, 1)
+	g.flipped(false, 1)
+	g.flipped(false, 1)
+	g.record()
+
+	expected := []sample{{5, 0, 0}, {4, 1, 2}}
+	if len(g.samples) != len(expected) {
+		t.Fatalf("%d samples recorded, expected %d", len(g.samples), len(expected))
+	}
+	for i := range expected {
+		if g.samples[i] != expected[i] {
+			t.Errorf("turn %d recorded as %+v, expected %+v", i, g.samples[i], expected[i])
+		}
+	}
+
+	for i := 0; i < 2*graphHistory; i++ {
+		g.record()
+	}
+	if len(g.samples) > 2*graphHistory || len(g.samples) < graphHistory {
+		t.Errorf("%d samples kept, expected between %d and %d", len(g.samples), graphHistory, 2*graphHistory)
+	}
+	if latest := g.samples[len(g.samples)-1]; latest != (sample{4, 0, 0}) {
+		t.Errorf("latest turn recorded as %+v after trimming, expected %+v", latest, sample{4, 0, 0})
+	}
+}
+
+// TestGraphDraw checks the graph is drawn over the bottom quarter of the view only, with the latest turn in the
+// rightmost column.
+func TestGraphDraw(t *testing.T) {
+	const width, height = 8, 32
+	view := make([]byte, 4*width*height)
+	for i := range view {
+		view[i] = 0xFF
+	}
+	g := graph{samples: []sample{{10, 0, 0}, {10, 2, 4}}}
+	g.draw(view, width, height)
+
+	pixel := func(x, y int) [3]byte {
+		i := 4 * (y*width + x)
+		return [3]byte{view[i], view[i+1], view[i+2]}
+	}
+	for y := 0; y < height-height/4; y++ {
+		for x := 0; x < width; x++ {
+			if pixel(x, y) != aliveColour {
+				t.Fatalf("(%d, %d) above the graph was drawn over", x, y)
+			}
+		}
+	}
+	// The population is the largest it has been, so it reaches the top of the strip. Deaths were the largest
+	// change, so they run up to it from zero under the population, and births halfway up over the deaths.
+	top := height - height/4
+	if pixel(width-1, top) != aliveColour {
+		t.Errorf("population not plotted at the top of the latest column, found %v", pixel(width-1, top))
+	}
+	if pixel(width-1, top+1) != deathColour {
+		t.Errorf("deaths not plotted below the population in the latest column, found %v", pixel(width-1, top+1))
+	}
+	if pixel(width-1, height-1) != birthColour {
+		t.Errorf("births not joined up from zero in the latest column, found %v", pixel(width-1, height-1))
+	}
+	// Columns without a turn are only darkened.
+	if dark := (pixel(0, height-1)); dark != [3]byte{0x3F, 0x3F, 0x3F} {
+		t.Errorf("empty column of the graph is %v, expected it darkened", dark)
+	}
+}
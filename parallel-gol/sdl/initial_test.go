@@ -0,0 +1,36 @@
+package sdl
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestShowInitialBoard checks the starting board replaces whatever was drawn, with its block densities and the
+// population graph in step, and the flips of turn 1 are applied on top of it.
+func TestShowInitialBoard(t *testing.T) {
+	w := newTestWindow(&titleDisplay{}, 8, 8, 2)
+	w.SetPixel(7, 7) // Left over from before, so it must be cleared.
+	alive := []util.Cell{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 4, Y: 5}}
+
+	w.ShowInitialBoard(alive)
+	if count := w.CountPixels(); count != len(alive) {
+		t.Fatalf("%d cells drawn, expected %d", count, len(alive))
+	}
+	for _, cell := range alive {
+		if w.pixels[4*(cell.Y*8+cell.X)] != 0xFF {
+			t.Errorf("cell %v isn't drawn", cell)
+		}
+	}
+	if w.density[w.block(0, 0)] != 2 || w.density[w.block(4, 5)] != 1 || w.density[w.block(7, 7)] != 0 {
+		t.Errorf("block densities %v don't match the starting board", w.density)
+	}
+	if w.graph.alive != len(alive) || w.graph.births != 0 {
+		t.Errorf("graph has %d alive and %d births, expected %d alive and no births", w.graph.alive, w.graph.births, len(alive))
+	}
+
+	w.FlipCell(1, 0, 1)
+	if w.CountPixels() != len(alive)-1 || w.graph.alive != len(alive)-1 || w.graph.deaths != 1 {
+		t.Errorf("a death at turn 1 left %d cells drawn and %d in the graph", w.CountPixels(), w.graph.alive)
+	}
+}
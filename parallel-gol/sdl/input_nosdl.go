@@ -0,0 +1,32 @@
+//go:build !cgo
+// +build !cgo
+
+package sdl
+
+// input turns the keys pressed in a browser watching the stream into actions.
+type input struct {
+	w          *Window
+	bindings   Bindings
+	keyPresses chan<- rune
+	ops        chan func()   // Changes to the view for the consumer to make, or nil to make them straight away.
+	done       chan struct{} // Closed once the consumer has finished with the window.
+}
+
+// newInput starts listening for input to the window.
+func newInput(w *Window, bindings Bindings, keyPresses chan<- rune) *input {
+	return &input{w: w, bindings: bindings, keyPresses: keyPresses}
+}
+
+// poll handles the next key pressed or redraw asked for, reporting false if there wasn't one.
+func (in *input) poll() bool {
+	event := in.w.PollEvent()
+	switch e := event.(type) {
+	case KeyEvent:
+		in.key(e.Key)
+	case redrawRequest:
+		in.view(in.w.Redraw)
+	}
+	return event != nil
+}
+
+func (in *input) close() {}
@@ -0,0 +1,163 @@
+//go:build cgo
+// +build cgo
+
+package sdl
+
+import (
+	"math"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// stickDeadZone is the axis value below which stick movement is ignored, so resting sticks don't drift the view.
+const stickDeadZone = 8000
+
+// stickPan is how many cells a fully deflected stick moves the view per interval.
+const stickPan = 4
+
+// panInterval is how often a held stick moves the view.
+const panInterval = 16 * time.Millisecond
+
+// gamepad keeps track of the open game controllers and how far the left stick is pushed.
+type gamepad struct {
+	controllers []*sdl.GameController
+	stickX      int16
+	stickY      int16
+	lastPan     time.Time
+}
+
+// openGamepads opens every game controller that is already plugged in.
+func openGamepads() *gamepad {
+	g := &gamepad{}
+	for i := 0; i < sdl.NumJoysticks(); i++ {
+		g.open(i)
+	}
+	return g
+}
+
+// open starts listening to the controller at the given device index.
+func (g *gamepad) open(index int) {
+	if !sdl.IsGameController(index) {
+		return
+	}
+	if controller := sdl.GameControllerOpen(index); controller != nil {
+		g.controllers = append(g.controllers, controller)
+	}
+}
+
+// close releases all open controllers. It must be called before the window is destroyed.
+func (g *gamepad) close() {
+	for _, controller := range g.controllers {
+		controller.Close()
+	}
+	g.controllers = nil
+}
+
+// pan moves the view in the direction the left stick is held, at most once per panInterval.
+func (g *gamepad) pan(in *input) {
+	if g.stickX == 0 && g.stickY == 0 || time.Since(g.lastPan) < panInterval {
+		return
+	}
+	g.lastPan = time.Now()
+	dx, dy := stickCells(g.stickX), stickCells(g.stickY)
+	in.view(func() {
+		in.w.Pan(dx, dy)
+		in.w.RenderFrame()
+	})
+}
+
+// stickCells converts a stick reading into the number of cells to pan by, scaled so that full deflection moves
+// stickPan cells either way. A stick pushed past the dead zone always moves at least one cell.
+func stickCells(value int16) int {
+	cells := int(value) * stickPan / math.MaxInt16
+	switch {
+	case cells == 0 && value > 0:
+		return 1
+	case cells == 0 && value < 0:
+		return -1
+	}
+	return cells
+}
+
+// axisValue applies the dead zone to a raw stick reading.
+func axisValue(value int16) int16 {
+	if value > -stickDeadZone && value < stickDeadZone {
+		return 0
+	}
+	return value
+}
+
+// input turns the window's events into actions: key presses, from SDL or from a browser watching the stream,
+// and game controllers.
+type input struct {
+	w          *Window
+	bindings   Bindings
+	keyPresses chan<- rune
+	pad        *gamepad
+	ops        chan func()   // Changes to the view for the consumer to make, or nil to make them straight away.
+	done       chan struct{} // Closed once the consumer has finished with the window.
+}
+
+// newInput starts listening for input to the window. Game controllers are only opened when SDL is showing it.
+func newInput(w *Window, bindings Bindings, keyPresses chan<- rune) *input {
+	in := &input{w: w, bindings: bindings, keyPresses: keyPresses, pad: &gamepad{}}
+	if _, ok := w.display.(*sdlDisplay); ok {
+		in.pad = openGamepads()
+	}
+	return in
+}
+
+// poll handles the next input event, reporting false if there wasn't one, and keeps panning while a stick is held.
+func (in *input) poll() bool {
+	event := in.w.PollEvent()
+	switch e := event.(type) {
+	case KeyEvent:
+		in.key(e.Key)
+	case *sdl.KeyboardEvent:
+		in.key(Key{Sym: e.Keysym.Sym, Mod: modifiers(e.Keysym.Mod)})
+	case redrawRequest:
+		in.view(in.w.Redraw)
+	case *sdl.ControllerDeviceEvent:
+		if e.Type == sdl.CONTROLLERDEVICEADDED {
+			in.pad.open(int(e.Which))
+		}
+	case *sdl.ControllerButtonEvent:
+		switch e.Button {
+		case sdl.CONTROLLER_BUTTON_A, sdl.CONTROLLER_BUTTON_START:
+			in.keyPresses <- 'p'
+		case sdl.CONTROLLER_BUTTON_LEFTSHOULDER, sdl.CONTROLLER_BUTTON_RIGHTSHOULDER:
+			// Either bumper steps a single turn while paused.
+			in.keyPresses <- 'n'
+		}
+	case *sdl.ControllerAxisEvent:
+		switch e.Axis {
+		case sdl.CONTROLLER_AXIS_LEFTX:
+			in.pad.stickX = axisValue(e.Value)
+		case sdl.CONTROLLER_AXIS_LEFTY:
+			in.pad.stickY = axisValue(e.Value)
+		}
+	}
+	in.pad.pan(in)
+	return event != nil
+}
+
+// close releases any game controllers. It must be called before the window is destroyed.
+func (in *input) close() {
+	in.pad.close()
+}
+
+// modifiers converts the modifier state of a keyboard event, ignoring the difference between left and right.
+func modifiers(mod uint16) Modifier {
+	var m Modifier
+	if mod&sdl.KMOD_SHIFT != 0 {
+		m |= Shift
+	}
+	if mod&sdl.KMOD_CTRL != 0 {
+		m |= Ctrl
+	}
+	if mod&sdl.KMOD_ALT != 0 {
+		m |= Alt
+	}
+	return m
+}
@@ -0,0 +1,29 @@
+//go:build cgo
+// +build cgo
+
+package sdl
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// Keycode identifies a key. With SDL it is SDL's own keycode, so keyboard events can be looked up directly.
+type Keycode = sdl.Keycode
+
+// The keys without a printable character that are bound by default.
+const (
+	keyUp          = sdl.K_UP
+	keyDown        = sdl.K_DOWN
+	keyLeft        = sdl.K_LEFT
+	keyRight       = sdl.K_RIGHT
+	keyKeypadPlus  = sdl.K_KP_PLUS
+	keyKeypadMinus = sdl.K_KP_MINUS
+)
+
+// keyFromName returns the key with the given SDL name, or 0 if there is none.
+func keyFromName(name string) Keycode {
+	return sdl.GetKeyFromName(name)
+}
+
+// keyName returns the SDL name of a key.
+func keyName(key Keycode) string {
+	return sdl.GetKeyName(key)
+}
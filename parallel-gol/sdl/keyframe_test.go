@@ -0,0 +1,107 @@
+package sdl
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// lifeTurn evolves a torus board of the given width by one turn of Conway's rules.
+func lifeTurn(board []bool, width int) []bool {
+	height := len(board) / width
+	next := make([]bool, len(board))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			neighbours := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if (dx != 0 || dy != 0) && board[(y+dy+height)%height*width+(x+dx+width)%width] {
+						neighbours++
+					}
+				}
+			}
+			next[y*width+x] = neighbours == 3 || neighbours == 2 && board[y*width+x]
+		}
+	}
+	return next
+}
+
+// aliveIn lists the cells alive on a board of the given width, in the order replay gives them.
+func aliveIn(board []bool, width int) []util.Cell {
+	var alive []util.Cell
+	for i, cell := range board {
+		if cell {
+			alive = append(alive, util.Cell{X: i % width, Y: i / width})
+		}
+	}
+	return alive
+}
+
+// TestKeyframes records a glider and checks every turn that is a multiple of the interval is a keyframe of the
+// whole board, and that seeking to any turn gives the same board as playing the recording from the start.
+func TestKeyframes(t *testing.T) {
+	const width, height, every, turns = 8, 8, 4, 13
+	board := make([]bool, width*height)
+	for _, cell := range []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}} {
+		board[cell.Y*width+cell.X] = true
+	}
+	boards := [][]bool{board}
+
+	var out bytes.Buffer
+	r, err := newRecorder(&out, width, height, 0, aliveIn(board, width), every)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for turn := 1; turn <= turns; turn++ {
+		next := lifeTurn(board, width)
+		for i := range next {
+			if next[i] != board[i] {
+				r.flipped(i%width, i/width)
+			}
+		}
+		if err := r.turnComplete(turn); err != nil {
+			t.Fatal(err)
+		}
+		board = next
+		boards = append(boards, board)
+	}
+	if err := r.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	header, frames, err := readRecording(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range frames {
+		if f.Keyframe != (f.Turn%every == 0) {
+			t.Errorf("turn %d was recorded with keyframe %v", f.Turn, f.Keyframe)
+		}
+		if f.Keyframe && !reflect.DeepEqual(f.Cells, aliveIn(boards[f.Turn], width)) {
+			t.Errorf("keyframe at turn %d holds %v, expected %v", f.Turn, f.Cells, aliveIn(boards[f.Turn], width))
+		}
+	}
+
+	replay, err := newReplay(header, frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, last := replay.turns(); first != 0 || last != turns {
+		t.Errorf("replay covers turns %d to %d, expected 0 to %d", first, last, turns)
+	}
+	// Backwards, so every seek has to start again from a keyframe rather than carry on from the last.
+	for turn := turns; turn >= 0; turn-- {
+		if alive := replay.seek(turn); !reflect.DeepEqual(alive, aliveIn(boards[turn], width)) {
+			t.Errorf("seeking to turn %d gave %v, expected %v", turn, alive, aliveIn(boards[turn], width))
+		}
+	}
+	if alive := replay.seek(turns + 10); !reflect.DeepEqual(alive, aliveIn(boards[turns], width)) {
+		t.Errorf("seeking past the end gave %v, expected the last turn", alive)
+	}
+
+	if _, err := newReplay(header, frames[1:]); err == nil {
+		t.Error("a recording not starting with a keyframe was replayed")
+	}
+}
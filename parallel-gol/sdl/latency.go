@@ -0,0 +1,74 @@
+package sdl
+
+import (
+	"fmt"
+	"time"
+)
+
+// latencySamples is how many of the latest turns shown the latency in the title bar is averaged over.
+const latencySamples = 30
+
+// latencyInterval is how often the title bar is updated with the latency, so it can be read.
+const latencyInterval = time.Second
+
+// latencySample is how long one turn took to reach the screen.
+type latencySample struct {
+	total  time.Duration // From the distributor sending the turn's TurnComplete to its frame being shown.
+	render time.Duration // Drawing and presenting the frame, the part of total spent in the window.
+}
+
+// latency keeps the latest latencySamples samples, so a rolling average can be shown.
+type latency struct {
+	samples []latencySample
+	next    int       // Index of the oldest sample, replaced by the next one once there are latencySamples.
+	shown   time.Time // When the average was last put in the title bar.
+}
+
+// add records a sample, replacing the oldest once there are latencySamples of them.
+func (l *latency) add(sample latencySample) {
+	if len(l.samples) < latencySamples {
+		l.samples = append(l.samples, sample)
+		return
+	}
+	l.samples[l.next] = sample
+	l.next = (l.next + 1) % latencySamples
+}
+
+// mean averages the samples kept.
+func (l *latency) mean() latencySample {
+	var sum latencySample
+	for _, sample := range l.samples {
+		sum.total += sample.total
+		sum.render += sample.render
+	}
+	if n := time.Duration(len(l.samples)); n > 0 {
+		sum.total /= n
+		sum.render /= n
+	}
+	return sum
+}
+
+// String describes a sample for the title bar. Time not spent rendering was spent in the engine's event queue,
+// so lag with little render time means the engine is behind, and lag that is mostly render time means drawing is.
+func (s latencySample) String() string {
+	return fmt.Sprintf("lag %s (render %s)", milliseconds(s.total), milliseconds(s.render))
+}
+
+// milliseconds formats a duration in milliseconds to one decimal place.
+func milliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+// TurnShown records how long a turn took to reach the screen, given when its TurnComplete event was emitted and
+// how long rendering it took, and shows the average over the latest turns in the title bar about once a second.
+func (w *Window) TurnShown(emitted time.Time, render time.Duration) {
+	if emitted.IsZero() {
+		return
+	}
+	now := time.Now()
+	w.latency.add(latencySample{total: now.Sub(emitted), render: render})
+	if now.Sub(w.latency.shown) >= latencyInterval && !w.paused { // The title says it is paused until resumed.
+		w.latency.shown = now
+		w.display.setTitle("GOL GUI - " + w.latency.mean().String())
+	}
+}
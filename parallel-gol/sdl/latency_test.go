@@ -0,0 +1,58 @@
+package sdl
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLatencyMean checks the average only covers the latest latencySamples turns.
+func TestLatencyMean(t *testing.T) {
+	var l latency
+	for i := 0; i < latencySamples; i++ {
+		l.add(latencySample{total: 100 * time.Millisecond, render: 10 * time.Millisecond})
+	}
+	if mean := l.mean(); mean.total != 100*time.Millisecond || mean.render != 10*time.Millisecond {
+		t.Errorf("mean of equal samples is %v, expected them", mean)
+	}
+	for i := 0; i < latencySamples; i++ {
+		l.add(latencySample{total: 4 * time.Millisecond, render: 2 * time.Millisecond})
+	}
+	if mean := l.mean(); mean.total != 4*time.Millisecond || mean.render != 2*time.Millisecond {
+		t.Errorf("mean is %v, expected the older samples to have been replaced", mean)
+	}
+	if s := l.mean().String(); s != "lag 4.0ms (render 2.0ms)" {
+		t.Errorf("shown as %q", s)
+	}
+}
+
+// titleDisplay is a display that only remembers its title.
+type titleDisplay struct {
+	title string
+}
+
+func (d *titleDisplay) bounds() (int32, int32, bool) { return 0, 0, false }
+func (d *titleDisplay) open(width, height int)       {}
+func (d *titleDisplay) present(pixels []byte)        {}
+func (d *titleDisplay) poll() interface{}            { return nil }
+func (d *titleDisplay) setTitle(title string)        { d.title = title }
+func (d *titleDisplay) close()                       {}
+
+// TestTurnShown checks the latency is put in the title bar, but not on every turn.
+func TestTurnShown(t *testing.T) {
+	d := &titleDisplay{}
+	w := &Window{display: d}
+	w.TurnShown(time.Now().Add(-20*time.Millisecond), 5*time.Millisecond)
+	if !strings.HasPrefix(d.title, "GOL GUI - lag ") || !strings.HasSuffix(d.title, "(render 5.0ms)") {
+		t.Fatalf("title is %q, expected the latency", d.title)
+	}
+	d.title = ""
+	w.TurnShown(time.Now(), time.Millisecond)
+	if d.title != "" {
+		t.Errorf("title updated to %q straight away, expected it to wait", d.title)
+	}
+	w.TurnShown(time.Time{}, time.Millisecond)
+	if len(w.latency.samples) != 2 {
+		t.Errorf("%d samples kept, expected an event without a time to be ignored", len(w.latency.samples))
+	}
+}
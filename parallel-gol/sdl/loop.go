@@ -2,53 +2,144 @@ package sdl
 
 import (
 	"fmt"
-	"github.com/veandco/go-sdl2/sdl"
+	"time"
+
 	"uk.ac.bris.cs/gameoflife/gol"
 )
 
-func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune) {
-	w := NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+// inputPollInterval is how long the main thread waits between checks for input while there is no new frame to show.
+const inputPollInterval = 5 * time.Millisecond
+
+// Run shows the world in a window until the final turn, turning key presses into actions with the given
+// bindings. A nil bindings uses DefaultBindings. Input is polled and frames are shown on the calling goroutine,
+// which must be the main thread for SDL, while the events are drained on a goroutine of their own, so a burst of
+// events doesn't hold up key presses and handling a key press doesn't hold up the run.
+func Run(p gol.Params, events <-chan gol.Event, keyPresses chan<- rune, bindings Bindings) {
+	if bindings == nil {
+		bindings = DefaultBindings()
+	}
+	var w *Window
+	if p.Geometry == gol.Triangular {
+		w = NewTriangleWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+	} else {
+		w = NewWindow(int32(p.ImageWidth), int32(p.ImageHeight))
+	}
+	if p.Infinite {
+		w.SetPlaneView(keyPresses)
+	}
+	w.RecordTo(recordingDir(p))
+	w.FollowExtents(p.ExtentEvery > 0, FollowPattern)
+	in := newInput(w, bindings, keyPresses)
+
+	// From here on only the consumer touches the window, and the main thread only its display.
+	front := newFrontDisplay(w.display)
+	w.display = front
+	in.ops, in.done = make(chan func()), make(chan struct{})
+	go consume(w, events, in.ops, in.done)
 
-sdlLoop:
+	poll := time.NewTicker(inputPollInterval)
+	defer poll.Stop()
 	for {
-		event := w.PollEvent()
-		if event != nil {
-			switch e := event.(type) {
-			case *sdl.KeyboardEvent:
-				switch e.Keysym.Sym {
-				case sdl.K_p:
-					keyPresses <- 'p'
-				case sdl.K_s:
-					keyPresses <- 's'
-				case sdl.K_q:
-					keyPresses <- 'q'
-				case sdl.K_k:
-					keyPresses <- 'k'
-				}
-			}
+		for in.poll() {
+			// Handle every input event waiting, so a burst of them is dealt with at once.
 		}
+		front.flush()
+		select {
+		case <-in.done:
+			front.flush()
+			in.close()
+			w.Destroy()
+			return
+		case <-front.ready:
+		case <-poll.C:
+		}
+	}
+}
+
+// consume shows the events in the window until the final turn or until events is closed, carrying out the
+// changes to the view asked for by the input on ops in between. It closes done once it has finished with the window.
+func consume(w *Window, events <-chan gol.Event, ops <-chan func(), done chan<- struct{}) {
+	defer close(done)
+	for {
 		select {
 		case event, ok := <-events:
-			if !ok {
-				w.Destroy()
-				break sdlLoop
-			}
-			switch e := event.(type) {
-			case gol.CellFlipped:
-				w.FlipPixel(e.Cell.X, e.Cell.Y)
-			case gol.TurnComplete:
-				w.RenderFrame()
-			case gol.FinalTurnComplete:
-				w.Destroy()
-				break sdlLoop
-			default:
-				if len(event.String()) > 0 {
-					fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
-				}
+			if !ok || !show(w, event) {
+				return
 			}
-		default:
-			break
+		case op := <-ops:
+			op()
+		}
+	}
+}
+
+// show draws an event in the window, reporting false once the final turn has come.
+func show(w *Window, event gol.Event) bool {
+	switch e := event.(type) {
+	case gol.InitialBoard:
+		w.ShowInitialBoard(e.Alive)
+	case gol.CellFlipped:
+		w.FlipCell(e.Cell.X, e.Cell.Y, e.CompletedTurns)
+	case gol.TurnComplete:
+		w.SetBoard(e.World)
+		w.TurnComplete(e.CompletedTurns)
+		rendering := time.Now()
+		w.RenderFrame()
+		w.TurnShown(e.Emitted, time.Since(rendering))
+	case gol.PatternExtent:
+		w.Follow(e)
+	case gol.StateChange:
+		fmt.Printf("Completed Turns %-8v%v\n", e.CompletedTurns, e)
+		if e.NewState == gol.Paused {
+			w.Paused()
+		} else {
+			w.Resumed()
+		}
+	case gol.FinalTurnComplete:
+		return false
+	default:
+		if len(event.String()) > 0 {
+			fmt.Printf("Completed Turns %-8v%v\n", event.GetCompletedTurns(), event)
 		}
 	}
+	return true
+}
+
+// recordingDir returns the directory recordings of a run are saved in, alongside its images.
+func recordingDir(p gol.Params) string {
+	if p.OutDir == "" {
+		return "out"
+	}
+	return p.OutDir
+}
 
+// redrawRequest is returned by a display's poll when the whole frame needs drawing again, e.g. because the
+// window was uncovered.
+type redrawRequest struct{}
+
+// key carries out the action bound to a key, if there is one. Key presses for the distributor are sent straight
+// away, while changes to the view are left to the consumer.
+func (in *input) key(key Key) {
+	action, ok := in.bindings.Lookup(key)
+	if !ok {
+		return
+	}
+	if key, ok := action.KeyPress(); ok {
+		in.keyPresses <- key
+		return
+	}
+	in.view(func() { perform(in.w, action, in.keyPresses) })
+}
+
+// view has the consumer carry out a change to the window between events, as only it may touch the window once Run
+// has started it. Without a consumer, as in the tests, the change is made straight away.
+func (in *input) view(op func()) {
+	if in.ops == nil {
+		op()
+		return
+	}
+	select {
+	case in.ops <- op:
+	case <-in.done:
+		// The window has gone, so there is nothing left to change.
+	}
 }
@@ -0,0 +1,42 @@
+package sdl
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPaused checks the title only says the run has paused once the frame shows a whole turn, follows single steps,
+// isn't overwritten by the latency, and is put back on resuming.
+func TestPaused(t *testing.T) {
+	d := &titleDisplay{}
+	w := newTestWindow(d, 4, 4, 1)
+	w.TurnComplete(3)
+	w.FlipCell(1, 1, 4)
+	w.Paused()
+	if d.title != "" {
+		t.Fatalf("title is %q part way through turn 4", d.title)
+	}
+	w.TurnComplete(4)
+	if d.title != "GOL GUI - paused at turn 4" {
+		t.Fatalf("title is %q once turn 4 completed, expected it to be paused there", d.title)
+	}
+	w.TurnShown(time.Now().Add(-time.Second), time.Millisecond)
+	if d.title != "GOL GUI - paused at turn 4" {
+		t.Errorf("the latency replaced the title with %q while paused", d.title)
+	}
+
+	w.FlipCell(1, 1, 5)
+	w.TurnComplete(5)
+	if d.title != "GOL GUI - paused at turn 5" {
+		t.Errorf("title is %q after stepping to turn 5", d.title)
+	}
+	w.Resumed()
+	if d.title != "GOL GUI" {
+		t.Errorf("title is %q after resuming", d.title)
+	}
+
+	w.Paused()
+	if d.title != "GOL GUI - paused at turn 5" {
+		t.Errorf("title is %q pausing between turns, expected it straight away", d.title)
+	}
+}
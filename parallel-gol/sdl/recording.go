@@ -0,0 +1,261 @@
+package sdl
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// KeyframeEvery is how many turns apart the keyframes of a recording are, so replay can seek to any turn by
+// starting from the keyframe before it rather than from the start. 0 records only the first keyframe.
+var KeyframeEvery = 100
+
+// recordingHeader starts a recording, giving the size of the board it shows.
+type recordingHeader struct {
+	Width, Height int
+}
+
+// frame is one turn of a recording: the cells that flipped during it, or for a keyframe every cell alive at
+// the end of it, so replay can start from there without the turns before.
+type frame struct {
+	Turn     int
+	Keyframe bool
+	Cells    []util.Cell
+}
+
+// recorder writes the turns shown in the window to a file, as a gob stream of a recordingHeader followed by
+// frames, starting with a keyframe of the board when recording started. Every turn that is a multiple of every
+// is recorded as a keyframe too, in place of its flipped cells.
+type recorder struct {
+	file    *os.File
+	buffer  *bufio.Writer
+	encoder *gob.Encoder
+	flips   []util.Cell // Cells flipped so far in the turn being shown.
+	every   int         // Turns between keyframes, or 0 for none after the first.
+	width   int
+	board   []bool // Whether each cell is alive, kept up to date from the flips for the keyframes.
+}
+
+// newRecorder starts recording to w a board of the given size, whose cells alive at turn are alive, with a
+// keyframe every so many turns.
+func newRecorder(w io.Writer, width, height, turn int, alive []util.Cell, every int) (*recorder, error) {
+	r := &recorder{buffer: bufio.NewWriter(w), every: every, width: width}
+	if every > 0 {
+		r.board = make([]bool, width*height)
+		for _, cell := range alive {
+			r.board[cell.Y*width+cell.X] = true
+		}
+	}
+	r.encoder = gob.NewEncoder(r.buffer)
+	if err := r.encoder.Encode(recordingHeader{Width: width, Height: height}); err != nil {
+		return nil, err
+	}
+	if err := r.encoder.Encode(frame{Turn: turn, Keyframe: true, Cells: alive}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// flipped adds a cell to the turn being recorded.
+func (r *recorder) flipped(x, y int) {
+	r.flips = append(r.flips, util.Cell{X: x, Y: y})
+	if r.board != nil {
+		r.board[y*r.width+x] = !r.board[y*r.width+x]
+	}
+}
+
+// turnComplete writes the cells flipped during a turn, or every cell alive at the end of it if it is due a keyframe.
+func (r *recorder) turnComplete(turn int) error {
+	f := frame{Turn: turn, Cells: r.flips}
+	if r.every > 0 && turn%r.every == 0 {
+		f = frame{Turn: turn, Keyframe: true}
+		for i, alive := range r.board {
+			if alive {
+				f.Cells = append(f.Cells, util.Cell{X: i % r.width, Y: i / r.width})
+			}
+		}
+	}
+	err := r.encoder.Encode(f)
+	r.flips = r.flips[:0]
+	return err
+}
+
+// close finishes the recording, and closes its file if it has one.
+func (r *recorder) close() error {
+	err := r.buffer.Flush()
+	if r.file != nil {
+		if closeErr := r.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// readRecording reads a recording back, returning the size of its board and its frames in order.
+func readRecording(in io.Reader) (recordingHeader, []frame, error) {
+	decoder := gob.NewDecoder(bufio.NewReader(in))
+	var header recordingHeader
+	if err := decoder.Decode(&header); err != nil {
+		return header, nil, fmt.Errorf("not a recording: %v", err)
+	}
+	var frames []frame
+	for {
+		var f frame
+		err := decoder.Decode(&f)
+		if err == io.EOF {
+			return header, frames, nil
+		}
+		if err != nil {
+			return header, frames, err
+		}
+		frames = append(frames, f)
+	}
+}
+
+// replay is a recording read back to be played, indexed by its keyframes so it can be started from any turn
+// without going through every turn before it, as a scrub bar needs.
+type replay struct {
+	header    recordingHeader
+	frames    []frame
+	keyframes []int // Index in frames of each keyframe, in order.
+}
+
+// newReplay indexes the frames of a recording, which must start with a keyframe.
+func newReplay(header recordingHeader, frames []frame) (*replay, error) {
+	if len(frames) == 0 || !frames[0].Keyframe {
+		return nil, fmt.Errorf("recording doesn't start with a keyframe")
+	}
+	r := &replay{header: header, frames: frames}
+	for i, f := range frames {
+		if f.Keyframe {
+			r.keyframes = append(r.keyframes, i)
+		}
+	}
+	return r, nil
+}
+
+// turns returns the first and last turns recorded.
+func (r *replay) turns() (first, last int) {
+	return r.frames[0].Turn, r.frames[len(r.frames)-1].Turn
+}
+
+// seek returns the cells alive at the end of a turn, built from the last keyframe at or before it and the cells
+// flipped after that. A turn before the recording starts gives its first keyframe, and one after it ends its last
+// turn.
+func (r *replay) seek(turn int) []util.Cell {
+	// The first keyframe after the turn, so the one before it is where to start.
+	k := sort.Search(len(r.keyframes), func(k int) bool { return r.frames[r.keyframes[k]].Turn > turn })
+	if k == 0 {
+		k = 1
+	}
+	start := r.keyframes[k-1]
+	width := r.header.Width
+	board := make([]bool, width*r.header.Height)
+	for _, cell := range r.frames[start].Cells {
+		board[cell.Y*width+cell.X] = true
+	}
+	for _, f := range r.frames[start+1:] {
+		if f.Turn > turn {
+			break
+		}
+		for _, cell := range f.Cells {
+			board[cell.Y*width+cell.X] = !board[cell.Y*width+cell.X]
+		}
+	}
+
+	var alive []util.Cell
+	for i, cell := range board {
+		if cell {
+			alive = append(alive, util.Cell{X: i % width, Y: i / width})
+		}
+	}
+	return alive
+}
+
+// RecordTo sets the directory the Record action saves recordings in.
+func (w *Window) RecordTo(dir string) {
+	w.recordDir = dir
+}
+
+// Recording reports whether the turns shown are being recorded.
+func (w *Window) Recording() bool {
+	return w.recorder != nil
+}
+
+// ToggleRecording starts recording the turns shown to a new file in the directory given to RecordTo, named after
+// the board and the turn recording started at, or stops the recording in progress.
+func (w *Window) ToggleRecording() {
+	if w.recorder != nil {
+		w.stopRecording()
+		return
+	}
+	path := filepath.Join(w.recordDir, fmt.Sprintf("%dx%d-from-%d.golrec", w.Width, w.Height, w.turn))
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		fmt.Printf("Error recording: %v\n", err)
+		return
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("Error recording: %v\n", err)
+		return
+	}
+	w.recorder, err = newRecorder(file, int(w.Width), int(w.Height), w.turn, w.aliveCells(), KeyframeEvery)
+	if err != nil {
+		file.Close()
+		fmt.Printf("Error recording: %v\n", err)
+		return
+	}
+	w.recorder.file = file
+	fmt.Printf("Recording to %s\n", path)
+}
+
+// stopRecording finishes the recording in progress.
+func (w *Window) stopRecording() {
+	if err := w.recorder.close(); err != nil {
+		fmt.Printf("Error recording: %v\n", err)
+	} else {
+		fmt.Printf("Stopped recording at turn %d\n", w.turn)
+	}
+	w.recorder = nil
+}
+
+// aliveCells returns the cells alive on the board shown.
+func (w *Window) aliveCells() []util.Cell {
+	var cells []util.Cell
+	for y := 0; y < int(w.Height); y++ {
+		for x := 0; x < int(w.Width); x++ {
+			if w.pixels[4*(y*int(w.Width)+x)] == 0xFF {
+				cells = append(cells, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return cells
+}
+
+// drawRecordingDot draws a red dot in the top-right corner of a frame of pixels, to show it is being recorded.
+func drawRecordingDot(pixels []byte, width, height int) {
+	radius := width / 40
+	if height/40 < radius {
+		radius = height / 40
+	}
+	if radius < 3 {
+		radius = 3
+	}
+	centreX, centreY := width-2*radius, 2*radius
+	for y := centreY - radius; y <= centreY+radius; y++ {
+		for x := centreX - radius; x <= centreX+radius; x++ {
+			dx, dy := x-centreX, y-centreY
+			if x < 0 || y < 0 || x >= width || y >= height || dx*dx+dy*dy > radius*radius {
+				continue
+			}
+			i := 4 * (y*width + x)
+			pixels[i+0], pixels[i+1], pixels[i+2], pixels[i+3] = 0x00, 0x00, 0xFF, 0xFF
+		}
+	}
+}
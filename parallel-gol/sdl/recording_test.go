@@ -0,0 +1,83 @@
+package sdl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestToggleRecording checks a recording starts with a keyframe of the board shown, then holds the cells flipped
+// in each turn until it is stopped.
+func TestToggleRecording(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const width, height = 4, 3
+	w := &Window{Width: width, Height: height, pixels: make([]byte, 4*width*height), density: make([]int32, width*height), factor: 1}
+	w.RecordTo(dir)
+	w.FlipCell(1, 1, 0)
+	w.TurnComplete(0)
+
+	w.ToggleRecording()
+	if !w.Recording() {
+		t.Fatal("recording didn't start")
+	}
+	w.FlipCell(2, 1, 1)
+	w.FlipCell(1, 1, 1)
+	w.TurnComplete(1)
+	w.TurnComplete(2)
+	w.ToggleRecording()
+	if w.Recording() {
+		t.Fatal("recording didn't stop")
+	}
+	w.FlipCell(0, 0, 3)
+	w.TurnComplete(3)
+
+	file, err := os.Open(filepath.Join(dir, "4x3-from-0.golrec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	header, frames, err := readRecording(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header != (recordingHeader{Width: width, Height: height}) {
+		t.Errorf("recorded a %dx%d board, expected %dx%d", header.Width, header.Height, width, height)
+	}
+	expected := []frame{
+		{Turn: 0, Keyframe: true, Cells: []util.Cell{{X: 1, Y: 1}}},
+		{Turn: 1, Cells: []util.Cell{{X: 2, Y: 1}, {X: 1, Y: 1}}},
+		{Turn: 2},
+	}
+	if !reflect.DeepEqual(frames, expected) {
+		t.Errorf("recorded %+v, expected %+v", frames, expected)
+	}
+}
+
+// TestRecordingDot checks the recording indicator is drawn in the top-right corner and nowhere else.
+func TestRecordingDot(t *testing.T) {
+	const width, height = 200, 100
+	pixels := make([]byte, 4*width*height)
+	drawRecordingDot(pixels, width, height)
+
+	red := func(x, y int) bool {
+		i := 4 * (y*width + x)
+		return pixels[i] == 0x00 && pixels[i+1] == 0x00 && pixels[i+2] == 0xFF && pixels[i+3] == 0xFF
+	}
+	if !red(width-6, 6) {
+		t.Error("no dot in the top-right corner")
+	}
+	for _, corner := range [][2]int{{0, 0}, {0, height - 1}, {width - 1, height - 1}, {width / 2, height / 2}} {
+		if red(corner[0], corner[1]) {
+			t.Errorf("(%d, %d) is red, expected only the top-right corner to be", corner[0], corner[1])
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package sdl
+
+import (
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// cellBoard is a board holding just the given alive cells.
+type cellBoard struct {
+	width, height int
+	alive         []util.Cell
+}
+
+func (b cellBoard) Width() int  { return b.width }
+func (b cellBoard) Height() int { return b.height }
+func (b cellBoard) Alive(x, y int) bool {
+	for _, cell := range b.alive {
+		if cell.X == x && cell.Y == y {
+			return true
+		}
+	}
+	return false
+}
+func (b cellBoard) AliveCells() []util.Cell { return b.alive }
+
+// newTestWindow returns a window for a board of the given size, drawn downsampled by factor, which presents to d.
+func newTestWindow(d display, width, height, factor int) *Window {
+	viewWidth, viewHeight := width/factor, height/factor
+	return &Window{
+		Width:      int32(width),
+		Height:     int32(height),
+		display:    d,
+		pixels:     make([]byte, width*height*4),
+		view:       make([]byte, viewWidth*viewHeight*4),
+		viewWidth:  viewWidth,
+		viewHeight: viewHeight,
+		factor:     factor,
+		downsample: factor > 1,
+		density:    make([]int32, viewWidth*viewHeight),
+	}
+}
+
+// TestRedraw checks a redraw replaces stale pixels and block densities with those of the latest board.
+func TestRedraw(t *testing.T) {
+	w := newTestWindow(&titleDisplay{}, 8, 8, 2)
+	// A stale frame, e.g. one that missed some flips, with cells the board doesn't have.
+	w.SetPixel(0, 0)
+	w.SetPixel(7, 7)
+	board := cellBoard{8, 8, []util.Cell{{X: 2, Y: 2}, {X: 3, Y: 2}, {X: 3, Y: 3}}}
+	w.SetBoard(board)
+
+	w.Redraw()
+	if count := w.CountPixels(); count != 3 {
+		t.Fatalf("%d cells drawn after the redraw, expected 3", count)
+	}
+	for _, cell := range board.alive {
+		if w.pixels[4*(cell.Y*8+cell.X)] != 0xFF {
+			t.Errorf("cell %v isn't drawn", cell)
+		}
+	}
+	if w.density[w.block(0, 0)] != 0 || w.density[w.block(7, 7)] != 0 || w.density[w.block(2, 2)] != 3 {
+		t.Errorf("block densities %v weren't rebuilt", w.density)
+	}
+
+	// A board of another size can't be drawn, so the frame is left alone.
+	w.SetBoard(cellBoard{4, 4, nil})
+	w.Redraw()
+	if count := w.CountPixels(); count != 3 {
+		t.Errorf("%d cells drawn after redrawing a board of the wrong size, expected 3", count)
+	}
+}
+
+// TestRedrawWaitsForTurn checks a redraw asked for part way through a turn waits until it is complete, as the
+// board then is the one from before the flips so far.
+func TestRedrawWaitsForTurn(t *testing.T) {
+	w := newTestWindow(&titleDisplay{}, 8, 8, 1)
+	w.SetBoard(cellBoard{8, 8, nil})
+	w.FlipCell(4, 4, 1)
+
+	w.Redraw()
+	if count := w.CountPixels(); count != 1 {
+		t.Fatalf("%d cells drawn, expected the redraw to wait rather than undo the flip", count)
+	}
+	w.SetBoard(cellBoard{8, 8, []util.Cell{{X: 4, Y: 4}, {X: 5, Y: 5}}})
+	w.TurnComplete(1)
+	if count := w.CountPixels(); count != 2 {
+		t.Errorf("%d cells drawn at the end of the turn, expected the board's 2", count)
+	}
+}
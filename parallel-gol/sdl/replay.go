@@ -0,0 +1,195 @@
+package sdl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// replayFrameInterval is how often a replay moves on at normal speed.
+const replayFrameInterval = time.Second / 30
+
+// replaySpeeds are the speeds a replay can be played at, as turns per frame. Speeds above one play several
+// recorded turns per frame, and those below one hold each turn for several frames.
+var replaySpeeds = []float64{0.5, 1, 2, 4}
+
+// normalSpeed is the index in replaySpeeds a replay starts at.
+const normalSpeed = 1
+
+// Replay plays the recording at path in a window, as Run shows a run, and waits at its end until it is quit.
+// Pause, step and quit work as they do for a run, the keys for more and fewer worker threads speed playback up
+// and slow it down, and back steps back a turn.
+func Replay(path string, bindings Bindings) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	header, frames, err := readRecording(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	r, err := newReplay(header, frames)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	events := make(chan gol.Event, 1000)
+	keyPresses := make(chan rune, 10)
+	ticker := time.NewTicker(replayFrameInterval)
+	defer ticker.Stop()
+	go play(r, events, keyPresses, ticker.C)
+	// Recording again while replaying saves alongside the recording being played.
+	Run(gol.Params{ImageWidth: header.Width, ImageHeight: header.Height, OutDir: filepath.Dir(path)}, events, keyPresses, bindings)
+	return nil
+}
+
+// player sends the turns of a replay as the events of a run, moving on as tick says and the key presses ask.
+type player struct {
+	replay *replay
+	events chan<- gol.Event
+	pos    int     // Index in the replay's frames of the turn shown.
+	board  []bool  // Whether each cell is alive in the turn shown.
+	speed  int     // Index in replaySpeeds of the speed being played at.
+	owed   float64 // Turns due to be played but not yet, from the speeds below one.
+	paused bool
+}
+
+// play sends the replay's first turn, then the rest as tick and the key presses ask, until quit is pressed.
+// It closes events once it has sent the FinalTurnComplete.
+func play(r *replay, events chan<- gol.Event, keyPresses <-chan rune, tick <-chan time.Time) {
+	first := r.frames[0]
+	p := &player{replay: r, events: events, board: make([]bool, r.header.Width*r.header.Height), speed: normalSpeed}
+	for _, cell := range first.Cells {
+		p.board[cell.Y*r.header.Width+cell.X] = true
+	}
+	events <- gol.InitialBoard{CompletedTurns: first.Turn, Alive: first.Cells}
+	events <- gol.TurnComplete{CompletedTurns: first.Turn}
+
+	for {
+		select {
+		case <-tick:
+			if p.paused {
+				continue
+			}
+			for p.owed += replaySpeeds[p.speed]; p.owed >= 1; p.owed-- {
+				if !p.forward() {
+					// Wait at the end, so it can be stepped back through.
+					p.owed = 0
+					p.pause()
+					break
+				}
+			}
+		case key := <-keyPresses:
+			if !p.press(key) {
+				events <- gol.StateChange{CompletedTurns: p.turn(), NewState: gol.Quitting}
+				events <- gol.FinalTurnComplete{CompletedTurns: p.turn(), Alive: p.alive()}
+				close(events)
+				return
+			}
+		}
+	}
+}
+
+// press carries out a key press, reporting false if it quits the replay.
+func (p *player) press(key rune) bool {
+	switch key {
+	case 'q', 'k':
+		return false
+	case 'p':
+		if p.paused {
+			p.paused = false
+			p.events <- gol.StateChange{CompletedTurns: p.turn(), NewState: gol.Executing}
+		} else {
+			p.pause()
+		}
+	case 'n':
+		// As in a run, a step while playing pauses after it.
+		p.forward()
+		p.pause()
+	case 'b':
+		if p.pos > 0 {
+			p.moveTo(p.pos - 1)
+		}
+		p.pause()
+	case '+', '-':
+		if key == '+' && p.speed < len(replaySpeeds)-1 {
+			p.speed++
+		} else if key == '-' && p.speed > 0 {
+			p.speed--
+		}
+		p.owed = 0
+		fmt.Printf("Replaying at %gx\n", replaySpeeds[p.speed])
+	}
+	return true
+}
+
+// pause stops playback, unless it is already paused.
+func (p *player) pause() {
+	if !p.paused {
+		p.paused = true
+		p.events <- gol.StateChange{CompletedTurns: p.turn(), NewState: gol.Paused}
+	}
+}
+
+// forward moves on a turn, reporting false if the last turn is already shown.
+func (p *player) forward() bool {
+	if p.pos == len(p.replay.frames)-1 {
+		return false
+	}
+	p.moveTo(p.pos + 1)
+	return true
+}
+
+// moveTo shows the turn of the frame at pos. Moving on a turn takes the cells flipped from the frame itself,
+// and any other move seeks to the turn and flips the cells that differ from the turn shown.
+func (p *player) moveTo(pos int) {
+	f := p.replay.frames[pos]
+	flips := f.Cells
+	if pos != p.pos+1 || f.Keyframe {
+		flips = p.differences(p.replay.seek(f.Turn))
+	}
+	width := p.replay.header.Width
+	for _, cell := range flips {
+		p.board[cell.Y*width+cell.X] = !p.board[cell.Y*width+cell.X]
+		p.events <- gol.CellFlipped{CompletedTurns: f.Turn, Cell: cell}
+	}
+	p.events <- gol.TurnComplete{CompletedTurns: f.Turn}
+	p.pos = pos
+}
+
+// differences returns the cells whose state in the turn shown differs from whether they are in alive.
+func (p *player) differences(alive []util.Cell) []util.Cell {
+	width := p.replay.header.Width
+	target := make([]bool, len(p.board))
+	for _, cell := range alive {
+		target[cell.Y*width+cell.X] = true
+	}
+	var flips []util.Cell
+	for i := range target {
+		if target[i] != p.board[i] {
+			flips = append(flips, util.Cell{X: i % width, Y: i / width})
+		}
+	}
+	return flips
+}
+
+// turn returns the turn shown.
+func (p *player) turn() int {
+	return p.replay.frames[p.pos].Turn
+}
+
+// alive returns the cells alive in the turn shown.
+func (p *player) alive() []util.Cell {
+	var alive []util.Cell
+	for i, cell := range p.board {
+		if cell {
+			alive = append(alive, util.Cell{X: i % p.replay.header.Width, Y: i / p.replay.header.Width})
+		}
+	}
+	return alive
+}
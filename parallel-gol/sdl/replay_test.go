@@ -0,0 +1,126 @@
+package sdl
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestPlay replays a recorded glider at each speed, stepping forwards and backwards across a keyframe, and checks
+// each turn is shown with the board it was recorded with.
+func TestPlay(t *testing.T) {
+	const width, height, every, turns = 8, 8, 4, 12
+	board := make([]bool, width*height)
+	for _, cell := range []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}} {
+		board[cell.Y*width+cell.X] = true
+	}
+	boards := [][]bool{board}
+	var out bytes.Buffer
+	r, err := newRecorder(&out, width, height, 0, aliveIn(board, width), every)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for turn := 1; turn <= turns; turn++ {
+		next := lifeTurn(board, width)
+		for i := range next {
+			if next[i] != board[i] {
+				r.flipped(i%width, i/width)
+			}
+		}
+		if err := r.turnComplete(turn); err != nil {
+			t.Fatal(err)
+		}
+		board = next
+		boards = append(boards, board)
+	}
+	if err := r.close(); err != nil {
+		t.Fatal(err)
+	}
+	header, frames, err := readRecording(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := newReplay(header, frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Plenty of room for the events, so the player never waits for them to be read. Ticks and key presses are
+	// unbuffered, so each has been dealt with once the next is taken.
+	events := make(chan gol.Event, 10000)
+	tick := make(chan time.Time)
+	keyPresses := make(chan rune)
+	go play(replay, events, keyPresses, tick)
+
+	shown := make([]bool, width*height)
+	// expect checks the events sent so far show exactly the turns given, each with its recorded board. A key press
+	// that does nothing is taken only once the player has finished with what came before.
+	expect := func(action string, expected ...int) {
+		t.Helper()
+		keyPresses <- 0
+		var got []int
+		for len(events) > 0 {
+			switch e := (<-events).(type) {
+			case gol.InitialBoard:
+				for _, cell := range e.Alive {
+					shown[cell.Y*width+cell.X] = true
+				}
+			case gol.CellFlipped:
+				shown[e.Cell.Y*width+e.Cell.X] = !shown[e.Cell.Y*width+e.Cell.X]
+			case gol.TurnComplete:
+				got = append(got, e.CompletedTurns)
+				if !reflect.DeepEqual(shown, boards[e.CompletedTurns]) {
+					t.Errorf("%s showed turn %d as %v, expected %v", action, e.CompletedTurns,
+						aliveIn(shown, width), aliveIn(boards[e.CompletedTurns], width))
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s showed turns %v, expected %v", action, got, expected)
+		}
+	}
+	press := func(keys string) {
+		for _, key := range keys {
+			keyPresses <- key
+		}
+	}
+
+	tick <- time.Now()
+	expect("starting", 0, 1)
+	press("+")
+	tick <- time.Now()
+	expect("2x", 2, 3)
+	press("+")
+	tick <- time.Now()
+	expect("4x", 4, 5, 6, 7)
+	press("+") // Already as fast as it goes.
+	tick <- time.Now()
+	expect("4x again", 8, 9, 10, 11)
+	press("---")
+	tick <- time.Now()
+	expect("0.5x")
+	tick <- time.Now()
+	expect("0.5x after two frames", 12)
+	tick <- time.Now()
+	tick <- time.Now()
+	expect("after the end")
+	press("bbbbb")
+	expect("stepping back", 11, 10, 9, 8, 7)
+	press("n")
+	expect("stepping forward", 8)
+	press("p")
+	tick <- time.Now()
+	tick <- time.Now()
+	expect("resuming at 0.5x", 9)
+
+	press("q")
+	for event := range events {
+		if final, ok := event.(gol.FinalTurnComplete); ok && !reflect.DeepEqual(final.Alive, aliveIn(boards[9], width)) {
+			t.Errorf("quitting gave %v alive, expected turn 9's %v", final.Alive, aliveIn(boards[9], width))
+		}
+	}
+}
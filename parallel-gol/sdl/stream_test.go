@@ -0,0 +1,125 @@
+package sdl
+
+import (
+	"bufio"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestBrowserKey checks the keys browsers report are converted to the keys bindings are written with.
+func TestBrowserKey(t *testing.T) {
+	tests := []struct {
+		key, code        string
+		ctrl, shift, alt bool
+		expected         string
+	}{
+		{"p", "KeyP", false, false, false, "p"},
+		{"P", "KeyP", false, true, false, "shift+p"},
+		{"k", "KeyK", true, false, false, "ctrl+k"},
+		{"ArrowUp", "ArrowUp", false, false, false, "up"},
+		{"+", "Equal", false, true, false, "shift++"},
+		{"+", "NumpadAdd", false, false, false, "keypad +"},
+		{"F5", "F5", false, false, false, "f5"},
+	}
+	for _, test := range tests {
+		key, err := browserKey(test.key, test.code, test.ctrl, test.shift, test.alt)
+		if err != nil {
+			t.Errorf("%s: %v", test.key, err)
+			continue
+		}
+		expected, err := ParseKey(test.expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != expected {
+			t.Errorf("%s (%s): got %v, expected %v", test.key, test.code, key, expected)
+		}
+	}
+	if _, err := browserKey("Shift", "ShiftLeft", false, true, false); err == nil {
+		t.Error("a modifier on its own should not be a key")
+	}
+}
+
+// TestStream watches a stream the way a browser does, checking frames arrive as JPEGs of the view and
+// that keys pressed in the browser come back out of the display.
+func TestStream(t *testing.T) {
+	defer func(address string) { StreamAddress = address }(StreamAddress)
+	StreamAddress = "127.0.0.1:0"
+	d := newStreamDisplay()
+	d.open(16, 8)
+	defer d.close()
+
+	// A white frame, so it can be told apart from the zeroed pixels before the first present.
+	pixels := make([]byte, 16*8*4)
+	for i := range pixels {
+		pixels[i] = 0xFF
+	}
+	d.present(pixels)
+
+	res, err := http.Get("http://" + d.address + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	mediaType, params, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/x-mixed-replace" {
+		t.Fatalf("stream served as %q", res.Header.Get("Content-Type"))
+	}
+	part, err := multipart.NewReader(bufio.NewReader(res.Body), params["boundary"]).NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err := jpeg.Decode(io.Reader(part))
+	if err != nil {
+		t.Fatalf("frame is not a JPEG: %v", err)
+	}
+	if size := frame.Bounds().Size(); size.X != 16 || size.Y != 8 {
+		t.Errorf("frame is %dx%d, expected 16x8", size.X, size.Y)
+	}
+	if r, g, b, _ := frame.At(8, 4).RGBA(); r < 0xF000 || g < 0xF000 || b < 0xF000 {
+		t.Errorf("frame should be white, got (%x, %x, %x)", r, g, b)
+	}
+
+	form := url.Values{"key": {"p"}, "code": {"KeyP"}, "ctrl": {"false"}, "shift": {"false"}, "alt": {"false"}}
+	if _, err := http.PostForm("http://"+d.address+"/key", form); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if event, ok := d.poll().(KeyEvent); ok {
+			if action, _ := DefaultBindings().Lookup(event.Key); action != Pause {
+				t.Errorf("key pressed in the browser is bound to %q, expected %q", action, Pause)
+			}
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("key pressed in the browser never reached the window")
+}
+
+// TestStreamTitle checks the page can fetch the title the window sets, e.g. with the latency.
+func TestStreamTitle(t *testing.T) {
+	defer func(address string) { StreamAddress = address }(StreamAddress)
+	StreamAddress = "127.0.0.1:0"
+	d := newStreamDisplay()
+	d.open(4, 4)
+	defer d.close()
+
+	d.setTitle("GOL GUI - lag 1.0ms (render 0.5ms)")
+	res, err := http.Get("http://" + d.address + "/title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(text) != "GOL GUI - lag 1.0ms (render 0.5ms)" {
+		t.Errorf("page title is %q, expected the one set", text)
+	}
+}
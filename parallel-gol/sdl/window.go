@@ -3,72 +3,453 @@ package sdl
 import (
 	"fmt"
 
-	"github.com/veandco/go-sdl2/sdl"
+	"uk.ac.bris.cs/gameoflife/gol"
 	"uk.ac.bris.cs/gameoflife/util"
 )
 
+// display is where a Window's frames are shown: an SDL window, or an MJPEG stream served over HTTP
+// when SDL can't be used.
+type display interface {
+	// bounds returns the usable size of the screen in pixels, or false if it isn't known.
+	bounds() (int32, int32, bool)
+	// open creates the view that frames of the given size in pixels are drawn to.
+	open(width, height int)
+	// present shows a frame of ARGB8888 pixels the size of the view.
+	present(pixels []byte)
+	// poll returns the next input event, or nil if there isn't one.
+	poll() interface{}
+	// setTitle changes the title the view is shown with.
+	setTitle(title string)
+	// close releases the view.
+	close()
+}
+
+// FollowPattern is whether the view follows the pattern when the window opens. It can be toggled with the Follow
+// action either way.
+var FollowPattern = false
+
 type Window struct {
-	Width, Height int32
-	window        *sdl.Window
-	renderer      *sdl.Renderer
-	texture       *sdl.Texture
+	Width, Height int32 // Size of the board in cells.
+	display       display
 	pixels        []byte
+	view          []byte           // Scratch buffer holding what is drawn: the panned copy of pixels, or the downsampled board.
+	offsetX       int              // Horizontal pan offset in cells.
+	offsetY       int              // Vertical pan offset in cells.
+	viewWidth     int              // Width of the window in pixels.
+	viewHeight    int              // Height of the window in pixels.
+	factor        int              // Number of cells along each side of the block drawn as one pixel when downsampling.
+	downsample    bool             // Whether the whole board is drawn downsampled, rather than part of it at 1:1.
+	density       []int32          // Number of alive cells in each factor x factor block, kept up to date as cells flip.
+	triangles     bool             // Whether cells are drawn as the alternating triangles of a triangular board.
+	planeView     chan<- rune      // In infinite mode, where panning is sent, as the distributor owns the viewport.
+	graph         graph            // Population over time, drawn along the bottom when shown.
+	turn          int              // Latest turn completed.
+	recorder      *recorder        // Where the turns shown are being recorded, or nil if they aren't.
+	recordDir     string           // Directory recordings are saved in.
+	latency       latency          // How long the latest turns took to reach the screen.
+	following     bool             // Whether each PatternExtent moves the view to centre the pattern.
+	extents       bool             // Whether the run sends PatternExtent events to follow.
+	board         gol.ReadOnlyGrid // World at the end of the latest turn, to redraw from, or nil if it wasn't sent.
+	flipsPending  bool             // Whether cells have flipped since the latest turn completed.
+	redrawDue     bool             // Whether a redraw was asked for while flips were pending.
+	paused        bool             // Whether the run is paused, shown in the title bar with the turn it paused at.
 }
 
-func filterEvent(e sdl.Event, userdata interface{}) bool {
-	return e.GetType() == sdl.KEYDOWN || e.GetType() == sdl.QUIT
-}
+// Each cell of a triangular board is drawn as a triangle triangleHeight pixels tall whose base is twice
+// triangleAdvance pixels wide, so neighbouring triangles in a row overlap by half their width.
+const (
+	triangleAdvance = 4
+	triangleHeight  = 7
+)
 
+// NewWindow opens a window for a board of the given size. Boards larger than the screen are drawn downsampled,
+// with each pixel showing how many cells of a block are alive, until 1:1 mode is toggled on.
 func NewWindow(width, height int32) *Window {
-	err := sdl.Init(sdl.INIT_EVERYTHING)
-	util.Check(err)
-	window, err := sdl.CreateWindow("GOL GUI", sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, width, height, sdl.WINDOW_SHOWN)
-	util.Check(err)
-	renderer, err := sdl.CreateRenderer(window, -1, sdl.WINDOW_SHOWN)
-	util.Check(err)
-	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "linear")
-	err = renderer.SetLogicalSize(width, height)
-	util.Check(err)
-	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STATIC, width, height)
-	util.Check(err)
-
-	sdl.SetEventFilterFunc(filterEvent, nil)
+	d := newDisplay()
+
+	// Shrink the window by a whole factor until it fits on the screen.
+	factor := 1
+	if boundsW, boundsH, ok := d.bounds(); ok && boundsW > 0 && boundsH > 0 {
+		for width > int32(factor)*boundsW || height > int32(factor)*boundsH {
+			factor++
+		}
+	}
+	viewWidth := (int(width) + factor - 1) / factor
+	viewHeight := (int(height) + factor - 1) / factor
+
+	d.open(viewWidth, viewHeight)
+	return &Window{
+		Width:      width,
+		Height:     height,
+		display:    d,
+		pixels:     make([]byte, width*height*4),
+		view:       make([]byte, viewWidth*viewHeight*4),
+		viewWidth:  viewWidth,
+		viewHeight: viewHeight,
+		factor:     factor,
+		downsample: factor > 1,
+		density:    make([]int32, viewWidth*viewHeight),
+		graph:      graph{shown: ShowGraph},
+	}
+}
+
+// NewTriangleWindow opens a window for a triangular board of the given size, drawing each cell as a triangle.
+// Boards larger than the screen are cropped to it rather than downsampled, and can be panned around.
+func NewTriangleWindow(width, height int32) *Window {
+	d := newDisplay()
+
+	// The extra half triangle on the right is the first column wrapping around.
+	viewWidth := (int(width) + 1) * triangleAdvance
+	viewHeight := int(height) * triangleHeight
+	if boundsW, boundsH, ok := d.bounds(); ok && boundsW > 0 && boundsH > 0 {
+		if viewWidth > int(boundsW) {
+			viewWidth = int(boundsW)
+		}
+		if viewHeight > int(boundsH) {
+			viewHeight = int(boundsH)
+		}
+	}
+
+	d.open(viewWidth, viewHeight)
 	return &Window{
-		width,
-		height,
-		window,
-		renderer,
-		texture,
-		make([]byte, width*height*4),
+		Width:      width,
+		Height:     height,
+		display:    d,
+		pixels:     make([]byte, width*height*4),
+		view:       make([]byte, viewWidth*viewHeight*4),
+		viewWidth:  viewWidth,
+		viewHeight: viewHeight,
+		factor:     1,
+		density:    make([]int32, width*height),
+		triangles:  true,
+		graph:      graph{shown: ShowGraph},
 	}
 }
 
 func (w *Window) Destroy() {
-	err := w.texture.Destroy()
-	util.Check(err)
-	err = w.renderer.Destroy()
-	util.Check(err)
-	err = w.window.Destroy()
-	util.Check(err)
-	sdl.Quit()
+	if w.recorder != nil {
+		w.stopRecording()
+	}
+	w.display.close()
 }
 
 func (w *Window) RenderFrame() {
-	err := w.texture.Update(nil, w.pixels, int(w.Width*4))
-	util.Check(err)
-	err = w.renderer.Clear()
-	util.Check(err)
-	err = w.renderer.Copy(w.texture, nil, nil)
-	util.Check(err)
-	w.renderer.Present()
+	pixels := w.pixels
+	if w.triangles {
+		pixels = w.trianglePixels()
+	} else if w.downsample {
+		pixels = w.downsampledPixels()
+	} else if w.offsetX != 0 || w.offsetY != 0 || w.factor > 1 {
+		pixels = w.pannedPixels()
+	}
+	if (w.graph.shown || w.recorder != nil) && len(pixels) > 0 && &pixels[0] == &w.pixels[0] {
+		// Draw over a copy, as pixels holds the board itself.
+		copy(w.view, w.pixels)
+		pixels = w.view
+	}
+	if w.graph.shown {
+		w.graph.draw(pixels, w.viewWidth, w.viewHeight)
+	}
+	if w.recorder != nil {
+		drawRecordingDot(pixels, w.viewWidth, w.viewHeight)
+	}
+	w.display.present(pixels)
+}
+
+// ToggleGraph shows or hides the population graph.
+func (w *Window) ToggleGraph() {
+	w.graph.shown = !w.graph.shown
+}
+
+// TurnComplete ends a turn of the population graph, and of the recording if there is one. A redraw asked for
+// during the turn is done now, once the pixels and the board agree again.
+func (w *Window) TurnComplete(turn int) {
+	w.turn = turn
+	w.flipsPending = false
+	if w.paused {
+		// Paused part way through the turn, or stepping on one turn while paused.
+		w.showPaused()
+	}
+	if w.redrawDue {
+		w.redrawDue = false
+		w.rebuild()
+	}
+	w.graph.record()
+	if w.recorder != nil {
+		if err := w.recorder.turnComplete(turn); err != nil {
+			fmt.Printf("Error recording: %v\n", err)
+			w.stopRecording()
+		}
+	}
+}
+
+// Paused shows in the title bar that the run has paused. The frame must be exactly the turn it paused at, so if cells
+// have flipped since the latest TurnComplete, leaving the frame part way through a turn, the title waits for the
+// turn to complete rather than label a half-updated frame.
+func (w *Window) Paused() {
+	w.paused = true
+	if !w.flipsPending {
+		w.showPaused()
+	}
+}
+
+// Resumed puts the title bar back once the run carries on after a pause.
+func (w *Window) Resumed() {
+	if w.paused {
+		w.paused = false
+		w.display.setTitle("GOL GUI")
+	}
+}
+
+// showPaused puts the latest turn completed in the title bar as the one the run is paused at.
+func (w *Window) showPaused() {
+	w.display.setTitle(fmt.Sprintf("GOL GUI - paused at turn %d", w.turn))
+}
+
+// SetBoard keeps the world at the end of a turn, which Redraw rebuilds the pixels from. It must be called before
+// TurnComplete for the same turn. A nil board, as sent in infinite mode and with an arena, leaves nothing to
+// redraw from.
+func (w *Window) SetBoard(board gol.ReadOnlyGrid) {
+	w.board = board
+}
+
+// ShowInitialBoard draws the starting board from its alive cells in one pass and shows it, rather than flipping
+// them one by one as turn 0 CellFlipped events would. Anything drawn before is cleared.
+func (w *Window) ShowInitialBoard(alive []util.Cell) {
+	w.ClearPixels()
+	for _, cell := range alive {
+		if cell.X < 0 || cell.Y < 0 || cell.X >= int(w.Width) || cell.Y >= int(w.Height) {
+			panic(fmt.Sprintf("InitialBoard cell at (%d, %d) is outside the bounds of the window.", cell.X, cell.Y))
+		}
+		w.SetPixel(cell.X, cell.Y)
+		if w.recorder != nil {
+			w.recorder.flipped(cell.X, cell.Y)
+		}
+	}
+	w.graph.alive = len(alive)
+	w.RenderFrame()
+}
+
+// Redraw rebuilds every pixel from the latest board and shows it, for when the frame built up from flips can't be
+// trusted: the window was uncovered or resized, the zoom changed or a browser started watching. Part way through a
+// turn the pixels are ahead of the board, so the rebuild waits for the turn to complete.
+func (w *Window) Redraw() {
+	if w.flipsPending {
+		w.redrawDue = true
+		return
+	}
+	w.rebuild()
+	w.RenderFrame()
+}
+
+// rebuild clears the pixels and block densities and sets those of the board's alive cells. It does nothing
+// without a board the size of the window.
+func (w *Window) rebuild() {
+	if w.board == nil || w.board.Width() != int(w.Width) || w.board.Height() != int(w.Height) {
+		return
+	}
+	w.ClearPixels()
+	for _, cell := range w.board.AliveCells() {
+		w.SetPixel(cell.X, cell.Y)
+	}
+}
+
+// Pan moves the view by (dx, dy) cells. The board is a torus, so the view wraps around the edges.
+// On an infinite plane the distributor is asked to move its viewport instead; see SetPlaneView.
+func (w *Window) Pan(dx, dy int) {
+	if w.planeView != nil {
+		w.panPlane(dx, dy)
+		return
+	}
+	width, height := int(w.Width), int(w.Height)
+	w.offsetX = ((w.offsetX+dx)%width + width) % width
+	w.offsetY = ((w.offsetY+dy)%height + height) % height
+}
+
+// ToggleFollow starts or stops keeping the pattern in the middle of the view as it moves. Following needs the
+// run to send PatternExtent events, and only works on the torus, as the distributor owns the infinite viewport.
+func (w *Window) ToggleFollow() {
+	switch {
+	case w.planeView != nil:
+		fmt.Println("Following isn't available on the infinite plane")
+	case !w.extents:
+		fmt.Println("Following needs the run to send pattern extents, e.g. with -extent 1")
+	default:
+		w.following = !w.following
+	}
+}
+
+// FollowExtents says whether the run sends PatternExtent events, and starts following them if follow is set.
+func (w *Window) FollowExtents(extents, follow bool) {
+	w.extents = extents
+	w.following = extents && follow && w.planeView == nil
+}
+
+// Following reports whether the view is following the pattern.
+func (w *Window) Following() bool {
+	return w.following
+}
+
+// Follow pans the view so the centre of the pattern is in the middle of it, if the view is following the pattern.
+func (w *Window) Follow(e gol.PatternExtent) {
+	if !w.following || e.Alive == 0 {
+		return
+	}
+	columns, rows := w.viewCells()
+	centre := e.Centre()
+	w.Pan(centre.X-columns/2-w.offsetX, centre.Y-rows/2-w.offsetY)
 }
 
-func (w *Window) PollEvent() sdl.Event {
-	return sdl.PollEvent()
+// viewCells returns how many columns and rows of cells the view shows.
+func (w *Window) viewCells() (int, int) {
+	switch {
+	case w.triangles:
+		return w.viewWidth / triangleAdvance, w.viewHeight / triangleHeight
+	case w.downsample:
+		return w.viewWidth * w.factor, w.viewHeight * w.factor
+	}
+	return w.viewWidth, w.viewHeight
+}
+
+// SetPlaneView makes Pan move the distributor's viewport over an infinite plane, by sending it key presses,
+// rather than wrapping the board around.
+func (w *Window) SetPlaneView(keyPresses chan<- rune) {
+	w.planeView = keyPresses
+}
+
+// panPlane asks the distributor to move its viewport by gol.ViewStep cells in the direction of (dx, dy).
+// If the distributor is busy the request is dropped, rather than holding up the window while a stick is held.
+func (w *Window) panPlane(dx, dy int) {
+	var keys []rune
+	switch {
+	case dx < 0:
+		keys = append(keys, gol.ViewLeft)
+	case dx > 0:
+		keys = append(keys, gol.ViewRight)
+	}
+	switch {
+	case dy < 0:
+		keys = append(keys, gol.ViewUp)
+	case dy > 0:
+		keys = append(keys, gol.ViewDown)
+	}
+	for _, key := range keys {
+		select {
+		case w.planeView <- key:
+		default:
+		}
+	}
+}
+
+// pannedPixels copies pixels into the view buffer so that cell (offsetX, offsetY) is drawn in the top-left corner.
+// When the board is larger than the window, only the part of it that fits is copied.
+func (w *Window) pannedPixels() []byte {
+	height := int(w.Height)
+	rowBytes := int(w.Width) * 4
+	viewBytes := w.viewWidth * 4
+	split := w.offsetX * 4
+	for y := 0; y < w.viewHeight; y++ {
+		src := w.pixels[((y+w.offsetY)%height)*rowBytes:][:rowBytes]
+		dst := w.view[y*viewBytes:][:viewBytes]
+		// Each row is copied in two parts so that columns left of the offset wrap around to the right.
+		n := copy(dst, src[split:])
+		copy(dst[n:], src[:split])
+	}
+	return w.view
+}
+
+// downsampledPixels draws each block of the board as one grey pixel, brighter the more of its cells are alive.
+// The pan offset moves the view by whole blocks, wrapping around the edges like pannedPixels.
+func (w *Window) downsampledPixels() []byte {
+	blockSize := int32(w.factor * w.factor)
+	offsetX, offsetY := w.offsetX/w.factor, w.offsetY/w.factor
+	for y := 0; y < w.viewHeight; y++ {
+		row := ((y + offsetY) % w.viewHeight) * w.viewWidth
+		for x := 0; x < w.viewWidth; x++ {
+			shade := byte(w.density[row+(x+offsetX)%w.viewWidth] * 0xFF / blockSize)
+			i := 4 * (y*w.viewWidth + x)
+			w.view[i+0] = shade
+			w.view[i+1] = shade
+			w.view[i+2] = shade
+			w.view[i+3] = 0xFF
+		}
+	}
+	return w.view
+}
+
+// ToggleDownsample switches between drawing the whole board downsampled and part of it at 1:1.
+// It has no effect when the board fits on the screen.
+func (w *Window) ToggleDownsample() {
+	if w.factor > 1 {
+		w.downsample = !w.downsample
+	}
+}
+
+// block returns the index of the downsampled block containing cell (x, y).
+func (w *Window) block(x, y int) int {
+	blocksPerRow := (int(w.Width) + w.factor - 1) / w.factor
+	return (y/w.factor)*blocksPerRow + x/w.factor
+}
+
+// trianglePixels draws the cells of a triangular board as triangles, with cell (offsetX, offsetY) in the
+// top-left corner. Down pointing triangles are drawn slightly darker so neighbouring alive cells stay distinct,
+// and dead ones slightly lighter than black so the lattice is visible.
+func (w *Window) trianglePixels() []byte {
+	width, height := int(w.Width), int(w.Height)
+	for py := 0; py < w.viewHeight; py++ {
+		y := (py/triangleHeight + w.offsetY) % height
+		// How far down the row the centre of this pixel is, from 0 at the top to 1 at the bottom.
+		down := (float64(py%triangleHeight) + 0.5) / triangleHeight
+		for px := 0; px < w.viewWidth; px++ {
+			// Each column of triangleAdvance pixels is split by a diagonal between the triangle starting
+			// in it and the one that started in the previous column.
+			column := px / triangleAdvance
+			across := (float64(px%triangleAdvance) + 0.5) / triangleAdvance
+			x := (column + w.offsetX) % width
+			var inColumn bool
+			if gol.PointsUp(x, y) {
+				inColumn = across >= 1-down // Left edge runs from bottom-left up to the apex.
+			} else {
+				inColumn = across >= down // Left edge runs from top-left down to the apex.
+			}
+			if !inColumn {
+				x = (x + width - 1) % width
+			}
+
+			var shade byte
+			alive := w.pixels[4*(y*width+x)] == 0xFF
+			switch {
+			case alive && gol.PointsUp(x, y):
+				shade = 0xFF
+			case alive:
+				shade = 0xC0
+			case !gol.PointsUp(x, y):
+				shade = 0x20
+			}
+			i := 4 * (py*w.viewWidth + px)
+			w.view[i+0] = shade
+			w.view[i+1] = shade
+			w.view[i+2] = shade
+			w.view[i+3] = 0xFF
+		}
+	}
+	return w.view
+}
+
+// PollEvent returns the next input event, or nil if there isn't one. The tests call it on a nil Window
+// when run with -noVis.
+func (w *Window) PollEvent() interface{} {
+	if w == nil {
+		return nil
+	}
+	return w.display.poll()
 }
 
 func (w *Window) SetPixel(x, y int) {
 	width := int(w.Width)
+	if w.pixels[4*(y*width+x)] != 0xFF {
+		w.density[w.block(x, y)]++
+	}
 	w.pixels[4*(y*width+x)+0] = 0xFF
 	w.pixels[4*(y*width+x)+1] = 0xFF
 	w.pixels[4*(y*width+x)+2] = 0xFF
@@ -85,11 +466,29 @@ func (w *Window) FlipPixel(x, y int) {
 	w.pixels[4*(y*width+x)+1] = ^w.pixels[4*(y*width+x)+1]
 	w.pixels[4*(y*width+x)+2] = ^w.pixels[4*(y*width+x)+2]
 	w.pixels[4*(y*width+x)+3] = ^w.pixels[4*(y*width+x)+3]
+
+	// Keep the block densities in step, so downsampling never has to rescan the board.
+	if w.pixels[4*(y*width+x)] == 0xFF {
+		w.density[w.block(x, y)]++
+	} else {
+		w.density[w.block(x, y)]--
+	}
+}
+
+// FlipCell flips a cell as FlipPixel does, also counting it towards the population graph. Cells flipped
+// at turn 0 set up the starting board rather than being born.
+func (w *Window) FlipCell(x, y, turn int) {
+	w.FlipPixel(x, y)
+	w.flipsPending = true
+	w.graph.flipped(w.pixels[4*(y*int(w.Width)+x)] == 0xFF, turn)
+	if w.recorder != nil {
+		w.recorder.flipped(x, y)
+	}
 }
 
 func (w *Window) CountPixels() int {
 	count := 0
-	for i := 0; i < int(w.Width) * int(w.Height) * 4; i += 4 {
+	for i := 0; i < int(w.Width)*int(w.Height)*4; i += 4 {
 		if w.pixels[i] == 0xFF {
 			count++
 		}
@@ -101,4 +500,7 @@ func (w *Window) ClearPixels() {
 	for i := range w.pixels {
 		w.pixels[i] = 0
 	}
+	for i := range w.density {
+		w.density[i] = 0
+	}
 }
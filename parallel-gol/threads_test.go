@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/console"
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestLiveThreads changes the number of worker threads mid-run from the console and checks the result is unaffected.
+func TestLiveThreads(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 2}
+	expectedAlive := readAliveCells(
+		"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+		p.ImageWidth,
+		p.ImageHeight,
+	)
+
+	keyPresses := make(chan rune, 10)
+	console.Run(strings.NewReader("+\nmore\n+\n-\n"), keyPresses, consoleCommands(sdl.DefaultBindings()))
+
+	events := make(chan gol.Event)
+	go gol.Run(p, events, keyPresses)
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	if len(keyPresses) != 0 {
+		t.Errorf("%d thread changes were never applied", len(keyPresses))
+	}
+	assertEqualBoard(t, cells, expectedAlive, p)
+}
+
+// TestAutotuneResult checks a run that picks its own thread count still ends with the expected board.
+func TestAutotuneResult(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 3, Autotune: true}
+	expectedAlive := readAliveCells(
+		"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns),
+		p.ImageWidth,
+		p.ImageHeight,
+	)
+
+	events := make(chan gol.Event)
+	go gol.Run(p, events, make(chan rune))
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	assertEqualBoard(t, cells, expectedAlive, p)
+}
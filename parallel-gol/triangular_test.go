@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestTriangular runs a triangular board and compares it with a reference that finds each triangle's
+// neighbours from the corners it shares with them, rather than from a table of offsets.
+func TestTriangular(t *testing.T) {
+	const turns = 20
+	for _, threads := range []int{1, 3, 8} {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: turns, Threads: threads, Geometry: gol.Triangular}
+		t.Run(fmt.Sprintf("%d_threads", threads), func(t *testing.T) {
+			initial := readAliveCells("images/64x64.pgm", p.ImageWidth, p.ImageHeight)
+			expected := referenceTriangular(initial, p.ImageWidth, p.ImageHeight, turns)
+
+			events := make(chan gol.Event)
+			go gol.Run(p, events, nil)
+			var cells []util.Cell
+			for event := range events {
+				switch e := event.(type) {
+				case gol.FinalTurnComplete:
+					cells = e.Alive
+				}
+			}
+			assertEqualBoard(t, cells, expected, p)
+		})
+	}
+}
+
+// referenceTriangular runs triangular Life for the given number of turns. Corners are numbered in units of
+// half a triangle's base across and one row down: an up triangle (x, y) has its apex at (x+1, y) and its base
+// from (x, y+1) to (x+2, y+1), and a down triangle the other way up.
+func referenceTriangular(initial []util.Cell, width, height, turns int) []util.Cell {
+	type corner struct{ x, y int }
+	corners := func(x, y int) []corner {
+		wrap := func(cx, cy int) corner { return corner{cx % width, cy % height} }
+		if (x+y)%2 == 0 {
+			return []corner{wrap(x+1, y), wrap(x, y+1), wrap(x+2, y+1)}
+		}
+		return []corner{wrap(x, y), wrap(x+2, y), wrap(x+1, y+1)}
+	}
+
+	// Find the triangles meeting at every corner, then each triangle's neighbours are the others at its corners.
+	touching := make(map[corner][]util.Cell)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			for _, c := range corners(x, y) {
+				touching[c] = append(touching[c], util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	neighbours := make(map[util.Cell][]util.Cell)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			self := util.Cell{X: x, Y: y}
+			seen := map[util.Cell]bool{self: true}
+			for _, c := range corners(x, y) {
+				for _, other := range touching[c] {
+					if !seen[other] {
+						seen[other] = true
+						neighbours[self] = append(neighbours[self], other)
+					}
+				}
+			}
+		}
+	}
+
+	alive := make(map[util.Cell]bool)
+	for _, cell := range initial {
+		alive[cell] = true
+	}
+	for turn := 0; turn < turns; turn++ {
+		next := make(map[util.Cell]bool)
+		for cell, around := range neighbours {
+			sum := 0
+			for _, other := range around {
+				if alive[other] {
+					sum++
+				}
+			}
+			if sum == 4 || alive[cell] && (sum == 5 || sum == 6) {
+				next[cell] = true
+			}
+		}
+		alive = next
+	}
+
+	cells := []util.Cell{}
+	for cell := range alive {
+		cells = append(cells, cell)
+	}
+	return cells
+}
@@ -0,0 +1,55 @@
+package util
+
+// Rotate turns a pattern the given number of quarter turns clockwise (anticlockwise if negative), keeping the
+// top-left of its bounding box at (0, 0). The cells passed in are left as they are.
+func Rotate(cells []Cell, quarterTurns int) []Cell {
+	turned := make([]Cell, len(cells))
+	for i, cell := range cells {
+		switch (quarterTurns%4 + 4) % 4 {
+		case 0:
+			turned[i] = cell
+		case 1:
+			turned[i] = Cell{X: -cell.Y, Y: cell.X}
+		case 2:
+			turned[i] = Cell{X: -cell.X, Y: -cell.Y}
+		case 3:
+			turned[i] = Cell{X: cell.Y, Y: -cell.X}
+		}
+	}
+	return Normalise(turned)
+}
+
+// Mirror reflects a pattern left to right, or top to bottom if vertical is set, keeping the top-left of its
+// bounding box at (0, 0). The cells passed in are left as they are.
+func Mirror(cells []Cell, vertical bool) []Cell {
+	mirrored := make([]Cell, len(cells))
+	for i, cell := range cells {
+		if vertical {
+			mirrored[i] = Cell{X: cell.X, Y: -cell.Y}
+		} else {
+			mirrored[i] = Cell{X: -cell.X, Y: cell.Y}
+		}
+	}
+	return Normalise(mirrored)
+}
+
+// Normalise moves a pattern in place so the top-left of its bounding box is at (0, 0), and returns it.
+func Normalise(cells []Cell) []Cell {
+	if len(cells) == 0 {
+		return cells
+	}
+	minX, minY := cells[0].X, cells[0].Y
+	for _, cell := range cells {
+		if cell.X < minX {
+			minX = cell.X
+		}
+		if cell.Y < minY {
+			minY = cell.Y
+		}
+	}
+	for i := range cells {
+		cells[i].X -= minX
+		cells[i].Y -= minY
+	}
+	return cells
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// inOrder sorts cells by row, so patterns can be compared whatever order their cells are listed in.
+func inOrder(cells []Cell) []Cell {
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Y != cells[j].Y {
+			return cells[i].Y < cells[j].Y
+		}
+		return cells[i].X < cells[j].X
+	})
+	return cells
+}
+
+// TestRotateMirror checks turning and mirroring an asymmetric pattern against each other.
+func TestRotateMirror(t *testing.T) {
+	// An R-pentomino has no symmetry, so every orientation of it is different.
+	r := []Cell{{X: 1, Y: 0}, {X: 2, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}}
+	original := append([]Cell{}, r...)
+
+	if got := inOrder(Rotate(r, 1)); !reflect.DeepEqual(got, []Cell{{X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 2}}) {
+		t.Errorf("a quarter turn clockwise gave %v", got)
+	}
+	same := []struct {
+		name     string
+		got, exp []Cell
+	}{
+		{"four quarter turns", Rotate(r, 4), r},
+		{"a quarter turn anticlockwise", Rotate(r, -1), Rotate(r, 3)},
+		{"two quarter turns", Rotate(Rotate(r, 1), 1), Rotate(r, 2)},
+		{"a half turn", Rotate(r, 2), Mirror(Mirror(r, false), true)},
+		{"mirroring twice", Mirror(Mirror(r, true), true), r},
+		{"mirroring then turning", Rotate(Mirror(r, false), 1), Mirror(Rotate(r, 1), true)},
+	}
+	for _, test := range same {
+		if got, exp := inOrder(append([]Cell{}, test.got...)), inOrder(append([]Cell{}, test.exp...)); !reflect.DeepEqual(got, exp) {
+			t.Errorf("%s gave %v, expected %v", test.name, got, exp)
+		}
+	}
+	if !reflect.DeepEqual(r, original) {
+		t.Errorf("the pattern passed in was changed to %v", r)
+	}
+	if got := Normalise([]Cell{{X: -3, Y: 5}, {X: 2, Y: 7}}); !reflect.DeepEqual(got, []Cell{{X: 0, Y: 0}, {X: 5, Y: 2}}) {
+		t.Errorf("normalising gave %v", got)
+	}
+}
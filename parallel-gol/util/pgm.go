@@ -0,0 +1,132 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Cell states, as stored in the world and written to PGM images. Dead must stay 0: neighbours are counted by
+// adding up cells and dividing by Alive.
+const (
+	Dead  byte = 0
+	Alive byte = 255
+)
+
+// DefaultThreshold is the fraction of an image's maxval at or above which a pixel is read as alive.
+const DefaultThreshold = 0.5
+
+// ParsePgm reads a PGM image in either the binary (P5) or plain (P2) format, with any maxval from 1 to 65535
+// and comments in the header, and returns its pixels as Alive or Dead cells, row by row. A pixel is alive if
+// it is at least threshold times the maxval; a threshold of 0 uses DefaultThreshold. This way images saved by
+// other tools, e.g. with maxval 1 or anti-aliased greys, load as the board they show rather than as all dead.
+func ParsePgm(data []byte, threshold float64) (width, height int, cells []byte, err error) {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	pos := 0
+	// next returns the next whitespace separated header field, skipping # comments.
+	next := func() (string, error) {
+		for pos < len(data) {
+			if isSpace(data[pos]) {
+				pos++
+			} else if data[pos] == '#' {
+				for pos < len(data) && data[pos] != '\n' {
+					pos++
+				}
+			} else {
+				break
+			}
+		}
+		start := pos
+		for pos < len(data) && !isSpace(data[pos]) && data[pos] != '#' {
+			pos++
+		}
+		if start == pos {
+			return "", fmt.Errorf("incomplete pgm header")
+		}
+		return string(data[start:pos]), nil
+	}
+	number := func(name string, max int) (int, error) {
+		field, err := next()
+		if err != nil {
+			return 0, err
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > max {
+			return 0, fmt.Errorf("bad pgm %s %q", name, field)
+		}
+		return n, nil
+	}
+
+	magic, err := next()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if magic != "P5" && magic != "P2" {
+		return 0, 0, nil, fmt.Errorf("not a pgm file")
+	}
+	if width, err = number("width", 1<<20); err != nil {
+		return 0, 0, nil, err
+	}
+	if height, err = number("height", 1<<20); err != nil {
+		return 0, 0, nil, err
+	}
+	maxval, err := number("maxval", 65535)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	cut := int(threshold*float64(maxval) + 0.5)
+	if cut < 1 {
+		cut = 1 // Pixels of 0 are always dead.
+	}
+
+	cells = make([]byte, width*height)
+	if magic == "P2" {
+		for i := range cells {
+			field, err := next()
+			if err != nil {
+				return 0, 0, nil, fmt.Errorf("expected %d pixels, found %d", len(cells), i)
+			}
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return 0, 0, nil, fmt.Errorf("bad pixel %q", field)
+			}
+			cells[i] = state(value >= cut)
+		}
+		return width, height, cells, nil
+	}
+
+	// A single whitespace byte separates the header from the binary pixels, which may themselves look like
+	// whitespace. Pixels take two bytes, most significant first, when maxval is over 255.
+	pos++
+	size := 1
+	if maxval > 255 {
+		size = 2
+	}
+	if len(data)-pos < len(cells)*size {
+		return 0, 0, nil, fmt.Errorf("expected %d pixels, found %d", len(cells), (len(data)-pos)/size)
+	}
+	for i := range cells {
+		value := int(data[pos])
+		if size == 2 {
+			value = value<<8 | int(data[pos+1])
+		}
+		pos += size
+		cells[i] = state(value >= cut)
+	}
+	return width, height, cells, nil
+}
+
+// state returns Alive or Dead.
+func state(alive bool) byte {
+	if alive {
+		return Alive
+	}
+	return Dead
+}
+
+// isSpace reports whether b is whitespace in a PGM header.
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\v' || b == '\f'
+}
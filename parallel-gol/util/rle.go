@@ -0,0 +1,54 @@
+package util
+
+import "fmt"
+
+// ParseRLE reads a pattern in the run length encoded format used by most pattern collections, e.g. "bo$2bo$3o!"
+// for a glider, and returns its alive cells with the top-left of the pattern at (0, 0). Comment lines starting
+// with # and the "x = ..., y = ..." header are skipped; any state other than b or . counts as alive.
+func ParseRLE(data []byte) ([]Cell, error) {
+	var cells []Cell
+	x, y, run := 0, 0, 0
+	lineStart := true
+	for pos := 0; pos < len(data); pos++ {
+		c := data[pos]
+		// Comments and the header take up whole lines before the pattern itself.
+		if lineStart && (c == '#' || c == 'x') {
+			for pos < len(data) && data[pos] != '\n' {
+				pos++
+			}
+			continue
+		}
+		lineStart = c == '\n'
+
+		switch {
+		case isSpace(c):
+		case c >= '0' && c <= '9':
+			run = 10*run + int(c-'0')
+			if run > 1<<20 {
+				return nil, fmt.Errorf("run of %d cells is too long", run)
+			}
+		case c == '!':
+			return cells, nil
+		default:
+			n := run
+			if n == 0 {
+				n = 1
+			}
+			run = 0
+			switch {
+			case c == '$':
+				x, y = 0, y+n
+			case c == 'b' || c == '.':
+				x += n
+			case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+				for i := 0; i < n; i++ {
+					cells = append(cells, Cell{X: x + i, Y: y})
+				}
+				x += n
+			default:
+				return nil, fmt.Errorf("unexpected %q in rle pattern", c)
+			}
+		}
+	}
+	return nil, fmt.Errorf("rle pattern has no terminating !")
+}
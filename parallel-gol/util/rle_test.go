@@ -0,0 +1,42 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseRLE checks patterns load whichever optional parts of the format they use.
+func TestParseRLE(t *testing.T) {
+	glider := []Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"bare", "bo$2bo$3o!"},
+		{"header and comments", "#N Glider\n#C The smallest spaceship.\nx = 3, y = 3, rule = B3/S23\nbo$2bo$3o!"},
+		{"split across lines", "bo$2b\no$3\no!"},
+		{"dots and trailing dead cells", ".o.$..o$ooo!"},
+		{"blank rows", "bo$2bo$3o$$!"},
+	}
+	for _, test := range tests {
+		cells, err := ParseRLE([]byte(test.data))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(cells, glider) {
+			t.Errorf("%s: got %v, expected %v", test.name, cells, glider)
+		}
+	}
+
+	cells, err := ParseRLE([]byte("o2$o!"))
+	if err != nil || !reflect.DeepEqual(cells, []Cell{{X: 0, Y: 0}, {X: 0, Y: 2}}) {
+		t.Errorf("a run of $ should skip rows, got %v, %v", cells, err)
+	}
+
+	for _, bad := range []string{"", "bo$2bo$3o", "b?o!", "99999999o!"} {
+		if _, err := ParseRLE([]byte(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestTurnRNG checks a cell's numbers depend only on the seed, turn and cell: drawing a board's numbers from
+// several goroutines in any order gives the numbers drawn one cell at a time, and changing any of the three
+// changes them.
+func TestTurnRNG(t *testing.T) {
+	const size = 64
+	rng := NewTurnRNG(7731, 12)
+	expected := make([]float64, size*size)
+	for i := range expected {
+		expected[i] = rng.Float64(i%size, i/size)
+		if expected[i] < 0 || expected[i] >= 1 {
+			t.Fatalf("Float64 = %v, expected a number in [0, 1)", expected[i])
+		}
+	}
+
+	got := make([]float64, size*size)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			// Each goroutine takes every fourth row, from the bottom up, with an RNG of its own.
+			rng := NewTurnRNG(7731, 12)
+			for y := size - 1 - g; y >= 0; y -= 4 {
+				for x := 0; x < size; x++ {
+					got[y*size+x] = rng.Float64(x, y)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("cell (%d, %d) drew %v in parallel, expected %v", i%size, i/size, got[i], expected[i])
+		}
+	}
+
+	others := map[string]TurnRNG{"seed": NewTurnRNG(7732, 12), "turn": NewTurnRNG(7731, 13)}
+	for name, other := range others {
+		if other.Uint64(3, 4) == rng.Uint64(3, 4) {
+			t.Errorf("changing the %s didn't change cell (3, 4)'s number", name)
+		}
+	}
+	if rng.Uint64(3, 4) == rng.Uint64(4, 3) {
+		t.Error("cells (3, 4) and (4, 3) drew the same number")
+	}
+}
+
+// TestTurnRNGRand checks a cell's generator gives the same sequence each time it is asked for.
+func TestTurnRNGRand(t *testing.T) {
+	a, b := NewTurnRNG(1, 2).Rand(5, 6), NewTurnRNG(1, 2).Rand(5, 6)
+	for i := 0; i < 100; i++ {
+		if x, y := a.Intn(1000), b.Intn(1000); x != y {
+			t.Fatalf("number %d: got %d and %d from the same cell", i, x, y)
+		}
+	}
+}
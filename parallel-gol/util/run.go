@@ -0,0 +1,22 @@
+package util
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RunName names a run after the time it started and its seed, e.g. "20240131-154502-7731", so the outputs
+// of repeated runs land in separate directories that sort in the order the runs were started.
+func RunName(start time.Time, seed int64) string {
+	return fmt.Sprintf("%s-%d", start.Format("20060102-150405"), seed)
+}
+
+// RunSeed returns seed, or a random one if seed is 0, so two runs started in the same second still get
+// different names unless they were given the same seed.
+func RunSeed(seed int64) int64 {
+	if seed != 0 {
+		return seed
+	}
+	return rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(10000) + 1
+}